@@ -0,0 +1,182 @@
+package logr
+
+import (
+	"fmt"
+	"time"
+)
+
+// AlertMsg is the message of the records logged for alerts raised by an
+// `AlertRule` with a level.
+const AlertMsg = "log alert"
+
+// AlertRule raises an alert when Threshold records matching Filter occur within
+// Window. See `Alerts`.
+type AlertRule struct {
+	// Name identifies the rule in alerts.
+	Name string
+
+	// Filter selects the records counted by the rule, by level and, if the filter
+	// implements `RecordFilter` such as `ExprFilter`, by content.
+	Filter Filter
+
+	// Threshold is the number of matching records within Window that raises an alert.
+	Threshold int
+
+	// Window is the period over which matching records are counted.
+	Window time.Duration
+
+	// Cooldown is the minimum time between alerts raised by the rule, so a sustained
+	// burst raises a single alert. Defaults to Window.
+	Cooldown time.Duration
+
+	// OnAlert, when not nil, is called on its own goroutine for each alert.
+	OnAlert func(alert Alert)
+
+	// Level, when not nil, causes a record with message `AlertMsg` to be logged at
+	// this level for each alert. Use a custom level isolated via `IsolateLevels` to
+	// route alerts to a notification target only.
+	Level *Level
+}
+
+// Alert describes an alert raised by an `AlertRule`.
+type Alert struct {
+	// Rule is the name of the rule raising the alert.
+	Rule string
+
+	// Count is the number of matching records within the window, i.e. the threshold.
+	Count int
+
+	// Window is the rule's window.
+	Window time.Duration
+
+	// Time is the time stamp of the record that raised the alert.
+	Time time.Time
+
+	// Level and Msg are those of the record that raised the alert.
+	Level Level
+	Msg   string
+}
+
+// Alerts enables lightweight in-process alerting for small deployments without
+// an external alerting stack. Each record passing through the Logr is counted by
+// the rules whose filter matches it; when a rule counts its threshold within its
+// window an alert is raised, invoking the rule's callback and/or logging a record.
+// Records only reach the Logr when their level is enabled by at least one target.
+func Alerts(rules ...AlertRule) Option {
+	return func(l *Logr) error {
+		for i, rule := range rules {
+			if rule.Filter == nil {
+				return fmt.Errorf("alert rule %d: filter cannot be nil", i)
+			}
+			if rule.Threshold < 1 {
+				return fmt.Errorf("alert rule %d: threshold must be at least 1", i)
+			}
+			if rule.Window <= 0 {
+				return fmt.Errorf("alert rule %d: window must be greater than zero", i)
+			}
+			if rule.OnAlert == nil && rule.Level == nil {
+				return fmt.Errorf("alert rule %d: OnAlert or Level must be set", i)
+			}
+			if rule.Cooldown <= 0 {
+				rule.Cooldown = rule.Window
+			}
+			l.options.alertRules = append(l.options.alertRules, rule)
+		}
+		return nil
+	}
+}
+
+// alerter applies `Alerts` rules. It is only accessed by the Logr read loop so
+// needs no locking.
+type alerter struct {
+	rules []*alertState
+}
+
+type alertState struct {
+	AlertRule
+
+	// times is a ring of the time stamps of the last Threshold matching records.
+	times     []time.Time
+	next      int
+	lastAlert time.Time
+}
+
+func newAlerter(rules []AlertRule) *alerter {
+	a := &alerter{rules: make([]*alertState, 0, len(rules))}
+	for _, rule := range rules {
+		a.rules = append(a.rules, &alertState{AlertRule: rule, times: make([]time.Time, 0, rule.Threshold)})
+	}
+	return a
+}
+
+// raisedAlert is an alert raised by a rule.
+type raisedAlert struct {
+	Alert
+	rule *alertState
+}
+
+// observe counts a prepped record against each rule, returning the alerts raised.
+func (a *alerter) observe(rec *LogRec) []raisedAlert {
+	var alerts []raisedAlert
+	for _, rule := range a.rules {
+		if _, enabled := rule.Filter.GetEnabledLevel(rec.level); !enabled {
+			continue
+		}
+		if rf, ok := rule.Filter.(RecordFilter); ok && !rf.IsRecordEnabled(rec) {
+			continue
+		}
+		if rule.add(rec.time) {
+			alerts = append(alerts, raisedAlert{rule: rule, Alert: Alert{
+				Rule:   rule.Name,
+				Count:  rule.Threshold,
+				Window: rule.Window,
+				Time:   rec.time,
+				Level:  rec.level,
+				Msg:    rec.msg,
+			}})
+		}
+	}
+	return alerts
+}
+
+// add records the time stamp of a matching record, returning true if an alert
+// should be raised.
+func (s *alertState) add(t time.Time) bool {
+	var oldest time.Time
+	if len(s.times) < s.Threshold {
+		s.times = append(s.times, t)
+		oldest = s.times[0]
+	} else {
+		s.times[s.next] = t
+		s.next = (s.next + 1) % s.Threshold
+		oldest = s.times[s.next]
+	}
+	if len(s.times) < s.Threshold || t.Sub(oldest) > s.Window {
+		return false
+	}
+	if !s.lastAlert.IsZero() && t.Sub(s.lastAlert) < s.Cooldown {
+		return false
+	}
+	s.lastAlert = t
+	return true
+}
+
+// raiseAlerts invokes the callbacks and logs the records of alerts.
+func (lgr *Logr) raiseAlerts(alerts []raisedAlert) {
+	for _, alert := range alerts {
+		if alert.rule.OnAlert != nil {
+			go alert.rule.OnAlert(alert.Alert)
+		}
+		if alert.rule.Level != nil {
+			fields := []Field{
+				String("alert", alert.Rule),
+				Int("count", alert.Count),
+				Duration("window", alert.Window),
+				String("trigger", alert.Msg),
+			}
+			rec := NewLogRec(*alert.rule.Level, lgr.NewLogger(), AlertMsg, fields, false)
+			rec.prep()
+			lgr.dispatch(rec)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package logr_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlerts(t *testing.T) {
+	var mux sync.Mutex
+	now := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		now = now.Add(d)
+	}
+
+	filter, err := logr.NewExprFilter(`level >= error && fields.subsystem == "db"`)
+	require.NoError(t, err)
+
+	alerts := make(chan logr.Alert, 10)
+	notify := logr.Level{ID: 120, Name: "alert"}
+	lgr, err := logr.New(
+		logr.Clock(func() time.Time {
+			mux.Lock()
+			defer mux.Unlock()
+			return now
+		}),
+		logr.IsolateLevels(notify),
+		logr.Alerts(logr.AlertRule{
+			Name:      "db-errors",
+			Filter:    filter,
+			Threshold: 3,
+			Window:    time.Minute,
+			OnAlert:   func(alert logr.Alert) { alerts <- alert },
+			Level:     &notify,
+		}),
+	)
+	require.NoError(t, err)
+
+	appBuf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(appBuf), "app", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+	notifyBuf := &test.Buffer{}
+	notifyFilter := &logr.CustomFilter{}
+	notifyFilter.Add(notify)
+	err = lgr.AddTarget(targets.NewWriterTarget(notifyBuf), "notify", notifyFilter, &formatters.Plain{DisableTimestamp: true}, 100,
+		logr.AllowIsolatedLevels(notify))
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("subsystem", "db"))
+	logger.Error("query failed")
+	logger.Info("query ok")
+	advance(40 * time.Second)
+	logger.Error("query failed")
+	advance(40 * time.Second)
+	// the first error is now outside the window.
+	logger.Error("query failed")
+	logger.Error("connection lost")
+	// within the cooldown.
+	logger.Error("connection lost")
+	advance(time.Minute)
+	lgr.NewLogger().Error("unrelated")
+	logger.Error("connection lost")
+	logger.Error("connection lost")
+	require.NoError(t, lgr.Shutdown())
+
+	require.Len(t, alerts, 2)
+	alert := <-alerts
+	assert.Equal(t, "db-errors", alert.Rule)
+	assert.Equal(t, 3, alert.Count)
+	assert.Equal(t, "connection lost", alert.Msg)
+	assert.Equal(t, logr.Error, alert.Level)
+
+	assert.Equal(t, 2, strings.Count(notifyBuf.String(), "alert log alert alert=db-errors count=3 window=1m0s trigger=\"connection lost\""), notifyBuf.String())
+	assert.NotContains(t, appBuf.String(), logr.AlertMsg)
+
+	_, err = logr.New(logr.Alerts(logr.AlertRule{Filter: filter, Threshold: 1, Window: time.Second}))
+	assert.Error(t, err)
+}
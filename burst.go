@@ -0,0 +1,189 @@
+package logr
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// BurstMsg is the message of the Warn record output when a burst of records is
+	// detected. See `BurstDetection`.
+	BurstMsg = "log burst detected"
+
+	// burstWarmup is the number of intervals observed before bursts are detected.
+	burstWarmup = 5
+
+	// burstAlpha is the weight of the latest interval in the rolling baseline.
+	burstAlpha = 0.2
+)
+
+// BurstCounterCollector is optionally implemented by a `MetricsCollector` to count
+// the bursts detected via `BurstDetection`.
+type BurstCounterCollector interface {
+	// BurstCounter returns a Counter that will be incremented each time a burst of
+	// records at the named level is detected.
+	BurstCounter(level string) (Counter, error)
+}
+
+// BurstDetection surfaces log storms before queues overflow. The number of records
+// at each level is counted per interval and compared to a rolling baseline, an
+// exponentially weighted average of previous intervals. When an interval's count
+// exceeds the baseline by factor, and is at least minCount, a Warn record with
+// message `BurstMsg` is output with fields containing the level, the rate and
+// baseline rate per second, and the factor. Bursts are also counted by the metrics
+// collector if it implements `BurstCounterCollector`. Detection starts once five
+// intervals have been observed.
+func BurstDetection(interval time.Duration, factor float64, minCount int) Option {
+	return func(l *Logr) error {
+		if interval <= 0 {
+			return errors.New("burst interval must be greater than zero")
+		}
+		if factor <= 1 {
+			return errors.New("burst factor must be greater than 1")
+		}
+		if minCount < 0 {
+			return errors.New("burst min count cannot be negative")
+		}
+		l.options.burst = &burstOptions{interval: interval, factor: factor, minCount: minCount}
+		return nil
+	}
+}
+
+type burstOptions struct {
+	interval time.Duration
+	factor   float64
+	minCount int
+}
+
+// burstDetector applies `BurstDetection`. Counting is only done by the Logr read
+// loop; the mutex guards the collector and counters.
+type burstDetector struct {
+	burstOptions
+	intervalEnd time.Time
+	intervals   int
+	levels      map[LevelID]*burstLevel
+
+	mux       sync.Mutex
+	collector BurstCounterCollector
+	counters  map[string]Counter
+}
+
+type burstLevel struct {
+	level    Level
+	count    int
+	baseline float64
+}
+
+type burst struct {
+	level    Level
+	count    int
+	baseline float64
+}
+
+func newBurstDetector(opts burstOptions) *burstDetector {
+	return &burstDetector{
+		burstOptions: opts,
+		levels:       make(map[LevelID]*burstLevel),
+		counters:     make(map[string]Counter),
+	}
+}
+
+// setCollector replaces the metrics collector, discarding counters from any
+// previous collector.
+func (bd *burstDetector) setCollector(collector MetricsCollector) {
+	bd.mux.Lock()
+	defer bd.mux.Unlock()
+	bd.collector, _ = collector.(BurstCounterCollector)
+	bd.counters = make(map[string]Counter)
+}
+
+// observe counts a prepped record, first returning the bursts detected in any
+// interval that ended before the record's time stamp.
+func (bd *burstDetector) observe(rec *LogRec) []burst {
+	var bursts []burst
+	if bd.intervalEnd.IsZero() {
+		bd.intervalEnd = rec.time.Add(bd.interval)
+	} else if !rec.time.Before(bd.intervalEnd) {
+		bursts = bd.roll(rec.time)
+	}
+
+	bl, ok := bd.levels[rec.level.ID]
+	if !ok {
+		bl = &burstLevel{level: rec.level}
+		bd.levels[rec.level.ID] = bl
+	}
+	bl.count++
+	return bursts
+}
+
+// roll ends the current interval, and any empty intervals up to now, updating the
+// baselines and returning the bursts detected, sorted by level ID.
+func (bd *burstDetector) roll(now time.Time) []burst {
+	var bursts []burst
+	for _, bl := range bd.levels {
+		if bd.intervals >= burstWarmup && bl.count >= bd.minCount && float64(bl.count) > bl.baseline*bd.factor {
+			bursts = append(bursts, burst{level: bl.level, count: bl.count, baseline: bl.baseline})
+		}
+		if bd.intervals == 0 {
+			bl.baseline = float64(bl.count)
+		} else {
+			bl.baseline += burstAlpha * (float64(bl.count) - bl.baseline)
+		}
+	}
+	bd.intervals++
+
+	// intervals without records lower the baselines, up to the warm-up period.
+	missed := int(now.Sub(bd.intervalEnd) / bd.interval)
+	for i := 0; i < missed && i < burstWarmup; i++ {
+		for _, bl := range bd.levels {
+			bl.baseline -= burstAlpha * bl.baseline
+		}
+	}
+	bd.intervals += missed
+	bd.intervalEnd = bd.intervalEnd.Add(time.Duration(missed+1) * bd.interval)
+
+	for _, bl := range bd.levels {
+		bl.count = 0
+	}
+	sort.Slice(bursts, func(i, j int) bool { return bursts[i].level.ID < bursts[j].level.ID })
+	return bursts
+}
+
+func (bd *burstDetector) incCounter(level string) {
+	bd.mux.Lock()
+	defer bd.mux.Unlock()
+	if bd.collector == nil {
+		return
+	}
+	c, ok := bd.counters[level]
+	if !ok {
+		c, _ = bd.collector.BurstCounter(level)
+		bd.counters[level] = c
+	}
+	if c != nil {
+		c.Inc()
+	}
+}
+
+// outputBursts counts and outputs a Warn record for each burst detected.
+func (lgr *Logr) outputBursts(bursts []burst) {
+	if len(bursts) == 0 {
+		return
+	}
+	secs := lgr.burst.interval.Seconds()
+	logger := lgr.NewLogger()
+	for _, b := range bursts {
+		lgr.burst.incCounter(b.level.Name)
+		fields := []Field{
+			String("level", b.level.Name),
+			Float64("rate", float64(b.count)/secs),
+			Float64("baseline", b.baseline/secs),
+			Float64("factor", lgr.burst.factor),
+		}
+		rec := NewLogRec(Warn, logger, BurstMsg, fields, false)
+		rec.prep()
+		lgr.dispatch(rec)
+	}
+}
@@ -0,0 +1,86 @@
+package logr_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBurstDetection(t *testing.T) {
+	collector := test.NewTestMetricsCollector()
+	var mux sync.Mutex
+	now := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		now = now.Add(d)
+	}
+
+	lgr, err := logr.New(
+		logr.Clock(func() time.Time {
+			mux.Lock()
+			defer mux.Unlock()
+			return now
+		}),
+		logr.SetMetricsCollector(collector, 1000),
+		logr.BurstDetection(time.Second, 3, 5),
+	)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "test", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	// bursts during the warm-up are not detected.
+	for i := 0; i < 20; i++ {
+		logger.Info("warm-up")
+	}
+	advance(time.Second)
+	for i := 0; i < 6; i++ {
+		logger.Info("steady")
+		logger.Info("steady")
+		logger.Error("steady")
+		advance(time.Second)
+	}
+	// below the factor.
+	for i := 0; i < 5; i++ {
+		logger.Info("busier")
+	}
+	advance(time.Second)
+	// below the min count.
+	for i := 0; i < 4; i++ {
+		logger.Error("storm")
+	}
+	advance(time.Second)
+	for i := 0; i < 30; i++ {
+		logger.Info("storm")
+	}
+	advance(time.Second)
+	logger.Info("after")
+	require.NoError(t, lgr.Shutdown())
+
+	output := buf.String()
+	assert.Equal(t, 1, strings.Count(output, logr.BurstMsg), output)
+	assert.Contains(t, output, "warn log burst detected level=info rate=30 ")
+	assert.Contains(t, output, " factor=3\n")
+	assert.Equal(t, 1.0, collector.GetBursts("info"))
+	assert.Equal(t, 0.0, collector.GetBursts("error"))
+}
+
+func TestBurstDetectionOptions(t *testing.T) {
+	_, err := logr.New(logr.BurstDetection(0, 3, 5))
+	assert.Error(t, err)
+	_, err = logr.New(logr.BurstDetection(time.Second, 1, 5))
+	assert.Error(t, err)
+	_, err = logr.New(logr.BurstDetection(time.Second, 3, -1))
+	assert.Error(t, err)
+}
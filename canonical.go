@@ -0,0 +1,120 @@
+package logr
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultCanonicalDurationKey is the key of the field containing the time between
+// creating a canonical log line and emitting it.
+const DefaultCanonicalDurationKey = "duration"
+
+type canonicalKey struct{}
+
+// CanonicalLine accumulates the fields of all records logged during a request,
+// or any other unit of work, into a single "canonical" log record emitted when the
+// work ends. One wide record per request is easy to query and aggregate, without
+// joining the many narrow records logged along the way.
+//
+// Fields are merged from records logged via the `XXXCtx` style APIs with a context
+// returned by `ContextWithCanonicalLine`, and via `Add`. When a key is merged more
+// than once the last value wins. As with all records, only records for levels
+// enabled by at least one target are merged. The canonical record is logged at
+// Info level, or at Warn or Error if a merged record was logged at that level.
+//
+// A CanonicalLine is safe for concurrent use.
+type CanonicalLine struct {
+	logger   Logger
+	msg      string
+	suppress bool
+	start    time.Time
+
+	mux     sync.Mutex
+	fields  []Field
+	index   map[string]int
+	level   Level
+	emitted bool
+}
+
+// NewCanonicalLine creates a canonical log line that is emitted via logger with
+// message msg. If suppress is true, records logged with a context carrying the
+// line are merged into it instead of also being logged individually.
+func NewCanonicalLine(logger Logger, msg string, suppress bool) *CanonicalLine {
+	return &CanonicalLine{
+		logger:   logger,
+		msg:      msg,
+		suppress: suppress,
+		start:    time.Now(),
+		index:    make(map[string]int),
+		level:    Info,
+	}
+}
+
+// ContextWithCanonicalLine returns a copy of ctx carrying the canonical log line.
+func ContextWithCanonicalLine(ctx context.Context, cl *CanonicalLine) context.Context {
+	return context.WithValue(ctx, canonicalKey{}, cl)
+}
+
+// CanonicalLineFromContext returns the canonical log line carried by ctx, or nil.
+func CanonicalLineFromContext(ctx context.Context) *CanonicalLine {
+	cl, _ := ctx.Value(canonicalKey{}).(*CanonicalLine)
+	return cl
+}
+
+// Add merges fields into the canonical log line.
+func (cl *CanonicalLine) Add(fields ...Field) {
+	cl.mux.Lock()
+	defer cl.mux.Unlock()
+	cl.addLocked(fields)
+}
+
+func (cl *CanonicalLine) addLocked(fields []Field) {
+	if cl.emitted {
+		return
+	}
+	for _, f := range fields {
+		if i, ok := cl.index[f.Key]; ok {
+			cl.fields[i] = f
+			continue
+		}
+		cl.index[f.Key] = len(cl.fields)
+		cl.fields = append(cl.fields, f)
+	}
+}
+
+// merge adds the fields of a record logged at lvl, returning true if the record
+// should not also be logged individually.
+func (cl *CanonicalLine) merge(lvl Level, fields []Field) bool {
+	cl.mux.Lock()
+	defer cl.mux.Unlock()
+	if cl.emitted {
+		return false
+	}
+	cl.addLocked(fields)
+	if (lvl.ID == Warn.ID || lvl.ID == Error.ID) && lvl.ID < cl.level.ID {
+		cl.level = lvl
+	}
+	return cl.suppress
+}
+
+// Emit logs the canonical record, with a field containing the time since the line
+// was created. Only the first call has any effect; records logged afterwards are
+// logged individually.
+func (cl *CanonicalLine) Emit() {
+	cl.mux.Lock()
+	if cl.emitted {
+		cl.mux.Unlock()
+		return
+	}
+	cl.emitted = true
+	fields := make([]Field, 0, len(cl.fields)+1)
+	fields = append(fields, cl.fields...)
+	level := cl.level
+	cl.mux.Unlock()
+
+	if _, ok := cl.index[DefaultCanonicalDurationKey]; !ok {
+		fields = append(fields, Duration(DefaultCanonicalDurationKey, time.Since(cl.start)))
+	}
+	cl.logger.Log(level, cl.msg, fields...)
+}
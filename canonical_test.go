@@ -0,0 +1,70 @@
+package logr_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalLine(t *testing.T) {
+	newLogr := func(t *testing.T) (*logr.Logr, *test.Buffer) {
+		lgr, err := logr.New()
+		require.NoError(t, err)
+		buf := &test.Buffer{}
+		err = lgr.AddTarget(targets.NewWriterTarget(buf), "canonical", &logr.StdFilter{Lvl: logr.Info},
+			&formatters.Plain{DisableTimestamp: true}, 100)
+		require.NoError(t, err)
+		return lgr, buf
+	}
+
+	t.Run("in addition", func(t *testing.T) {
+		lgr, buf := newLogr(t)
+		logger := lgr.NewLogger()
+
+		cl := logr.NewCanonicalLine(logger, "request done", false)
+		ctx := logr.ContextWithCanonicalLine(context.Background(), cl)
+		cl.Add(logr.String("route", "/users"), logr.Int("rows", 0))
+		logger.InfoCtx(ctx, "query", logr.Int("rows", 12))
+		logger.DebugCtx(ctx, "disabled", logr.Bool("cached", true))
+		logger.WarnCtx(ctx, "slow", logr.Bool("slow", true))
+		cl.Emit()
+		cl.Emit()
+		logger.InfoCtx(ctx, "after")
+		require.NoError(t, lgr.Shutdown())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4)
+		assert.Contains(t, lines[0], "query")
+		assert.Contains(t, lines[1], "slow")
+		assert.True(t, strings.HasPrefix(lines[2], "warn request done"), lines[2])
+		assert.Contains(t, lines[2], "route=/users rows=12 slow=true duration=")
+		assert.NotContains(t, lines[2], "cached")
+		assert.Contains(t, lines[3], "after")
+	})
+
+	t.Run("instead", func(t *testing.T) {
+		lgr, buf := newLogr(t)
+		logger := lgr.NewLogger()
+
+		cl := logr.NewCanonicalLine(logger, "request done", true)
+		ctx := logr.ContextWithCanonicalLine(context.Background(), cl)
+		logger.InfoCtx(ctx, "query", logr.Int("rows", 12))
+		logger.ErrorCtx(ctx, "failed", logr.String("error", "timeout"))
+		logger.Info("not merged")
+		cl.Emit()
+		require.NoError(t, lgr.Shutdown())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "not merged")
+		assert.True(t, strings.HasPrefix(lines[1], "error request done"), lines[1])
+		assert.Contains(t, lines[1], "rows=12 error=timeout duration=")
+	})
+}
@@ -0,0 +1,68 @@
+package logr
+
+import (
+	"time"
+)
+
+// DefaultClockSkewKey is the default field key used to annotate log records
+// affected by a backward wall-clock jump. See `MonotonicTime`.
+const DefaultClockSkewKey = "clock_skew"
+
+// clockMonitor records a monotonic timestamp for each log record and detects
+// backward jumps of the wall clock, such as NTP steps. It is only accessed by
+// the Logr read loop so needs no locking.
+type clockMonitor struct {
+	epoch     time.Time
+	threshold time.Duration
+	key       string
+
+	// offset is the difference between elapsed wall time and elapsed monotonic
+	// time for the last record observed.
+	offset time.Duration
+}
+
+func newClockMonitor(threshold time.Duration, key string) *clockMonitor {
+	return &clockMonitor{epoch: time.Now(), threshold: threshold, key: key}
+}
+
+// observe sets the monotonic timestamp of a prepped record and annotates the record
+// with a field if the wall clock stepped backward since the previous record.
+// Records without a monotonic clock reading, e.g. those created via
+// `Logger.LogWithTime`, are ignored.
+func (c *clockMonitor) observe(rec *LogRec) {
+	if c == nil || !hasMonotonic(rec.time) {
+		return
+	}
+
+	wall := rec.time.Round(0).Sub(c.epoch.Round(0))
+	mono := rec.time.Sub(c.epoch)
+	c.record(rec, wall, mono)
+}
+
+// record sets the monotonic timestamp of the record and checks for a backward jump
+// using the elapsed wall and monotonic time since the epoch.
+func (c *clockMonitor) record(rec *LogRec, wall time.Duration, mono time.Duration) {
+	rec.mux.Lock()
+	rec.mono = mono
+	rec.hasMono = true
+	rec.mux.Unlock()
+
+	// The offset only changes when the wall clock is adjusted, so it does not
+	// matter if records arrive slightly out of order.
+	offset := wall - mono
+	jump := offset - c.offset
+	c.offset = offset
+
+	if c.threshold > 0 && jump < -c.threshold {
+		fields := make([]Field, 0, len(rec.fieldsAll)+1)
+		fields = append(fields, rec.fieldsAll...)
+		fields = append(fields, Duration(c.key, jump))
+		rec.fieldsAll, _ = dedupFields(fields)
+	}
+}
+
+// hasMonotonic returns true if the time includes a monotonic clock reading.
+func hasMonotonic(t time.Time) bool {
+	// Round(0) strips the monotonic clock reading.
+	return t != t.Round(0)
+}
@@ -0,0 +1,62 @@
+package logr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockMonitorSkew(t *testing.T) {
+	c := newClockMonitor(time.Second, DefaultClockSkewKey)
+	newRec := func() *LogRec {
+		return &LogRec{fieldsAll: []Field{String("user", "wiggin")}}
+	}
+
+	rec := newRec()
+	c.record(rec, time.Second, time.Second)
+	mono, ok := rec.Monotonic()
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, mono)
+	assert.Len(t, rec.Fields(), 1)
+
+	// small adjustments and forward jumps are not annotated.
+	rec = newRec()
+	c.record(rec, time.Second*2-time.Millisecond*500, time.Second*2)
+	assert.Len(t, rec.Fields(), 1)
+	rec = newRec()
+	c.record(rec, time.Second*10, time.Second*3)
+	assert.Len(t, rec.Fields(), 1)
+
+	// wall clock stepped back 5s.
+	rec = newRec()
+	c.record(rec, time.Second*6, time.Second*4)
+	require.Len(t, rec.Fields(), 2)
+	assert.Equal(t, DefaultClockSkewKey, rec.Fields()[1].Key)
+	assert.Equal(t, int64(-time.Second*5), rec.Fields()[1].Integer)
+
+	// only the first record after the jump is annotated.
+	rec = newRec()
+	c.record(rec, time.Second*7, time.Second*5)
+	assert.Len(t, rec.Fields(), 1)
+}
+
+func TestClockMonitorObserve(t *testing.T) {
+	lgr, err := New(MonotonicTime(time.Second, ""))
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+	require.NotNil(t, lgr.clock)
+
+	rec := NewLogRec(Info, lgr.NewLogger(), "msg", nil, false)
+	lgr.clock.observe(rec)
+	mono, ok := rec.Monotonic()
+	assert.True(t, ok)
+	assert.True(t, mono > 0)
+
+	// records with an explicit time have no monotonic timestamp.
+	rec = rec.WithTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	lgr.clock.observe(rec)
+	_, ok = rec.Monotonic()
+	assert.False(t, ok)
+}
@@ -0,0 +1,168 @@
+// Command logr-fmt reads JSON or logfmt log records from stdin and renders them
+// in a human friendly, optionally colored, format. It is intended for local
+// development against production logs, e.g.
+//
+//	kubectl logs my-pod | logr-fmt -level warn -omit request_id
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/replay"
+	"github.com/mattermost/logr/v2/targets"
+)
+
+type settings struct {
+	level       string
+	fields      string
+	omit        string
+	where       string
+	noColor     bool
+	delim       string
+	groupKey    string
+	tsFormatIn  string
+	tsFormatOut string
+}
+
+func main() {
+	var s settings
+	flag.StringVar(&s.level, "level", "trace", "minimum level to output (panic, fatal, error, warn, info, debug, trace)")
+	flag.StringVar(&s.fields, "fields", "", "comma separated list of field keys to output; all fields if empty")
+	flag.StringVar(&s.omit, "omit", "", "comma separated list of field keys to omit")
+	flag.StringVar(&s.where, "where", "", "comma separated list of key=value pairs that records must match")
+	flag.BoolVar(&s.noColor, "no-color", false, "disable colored output")
+	flag.StringVar(&s.delim, "delim", " ", "delimiter output between parts of each record")
+	flag.StringVar(&s.groupKey, "group-key", "", "key under which fields were grouped, if any")
+	flag.StringVar(&s.tsFormatIn, "ts-in", logr.DefTimestampFormat, "timestamp format of the input records")
+	flag.StringVar(&s.tsFormatOut, "ts-out", logr.DefTimestampFormat, "timestamp format for output")
+	flag.Parse()
+
+	if err := run(s, os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "logr-fmt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(s settings, in io.Reader, out io.Writer, errOut io.Writer) error {
+	minLevel, ok := logr.GetLevelMapper().Name(s.level)
+	if !ok {
+		return fmt.Errorf("unknown level '%s'", s.level)
+	}
+
+	where := make(map[string]string)
+	for _, pair := range splitList(s.where) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid -where pair '%s'", pair)
+		}
+		where[kv[0]] = kv[1]
+	}
+	keep := toSet(splitList(s.fields))
+	omit := toSet(splitList(s.omit))
+
+	lgr, err := logr.New()
+	if err != nil {
+		return err
+	}
+	formatter := &formatters.Plain{
+		Delim:           s.delim,
+		EnableColor:     !s.noColor,
+		MinLevelLen:     5,
+		TimestampFormat: s.tsFormatOut,
+	}
+	filter := &logr.StdFilter{Lvl: minLevel}
+	if err := lgr.AddTarget(targets.NewWriterTarget(out), "logr-fmt", filter, formatter, 1000); err != nil {
+		return err
+	}
+	logger := lgr.NewLogger()
+
+	def := logr.Info
+	rdr := replay.NewReader(in, replay.Options{
+		TimestampFormat: s.tsFormatIn,
+		KeyGroupFields:  s.groupKey,
+		DefaultLevel:    &def,
+	})
+
+	for {
+		rec, err := rdr.Next()
+		if err == io.EOF {
+			break
+		}
+		var perr *replay.ParseError
+		if errors.As(err, &perr) {
+			fmt.Fprintln(errOut, "logr-fmt: skipping", err)
+			continue
+		}
+		if err != nil {
+			_ = lgr.Shutdown()
+			return err
+		}
+		if !matches(rec, where) {
+			continue
+		}
+		logger.LogWithTime(rec.Time, rec.Level, rec.Msg, selectFields(rec.Fields, keep, omit)...)
+	}
+	return lgr.Shutdown()
+}
+
+func matches(rec *replay.Record, where map[string]string) bool {
+	for k, v := range where {
+		found := false
+		for _, f := range rec.Fields {
+			if f.Key != k {
+				continue
+			}
+			var sb strings.Builder
+			if err := f.ValueString(&sb, nil); err == nil && sb.String() == v {
+				found = true
+			}
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func selectFields(fields []logr.Field, keep, omit map[string]struct{}) []logr.Field {
+	if len(keep) == 0 && len(omit) == 0 {
+		return fields
+	}
+	selected := make([]logr.Field, 0, len(fields))
+	for _, f := range fields {
+		if _, ok := omit[f.Key]; ok {
+			continue
+		}
+		if _, ok := keep[f.Key]; len(keep) != 0 && !ok {
+			continue
+		}
+		selected = append(selected, f)
+	}
+	return selected
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func toSet(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, item := range list {
+		set[item] = struct{}{}
+	}
+	return set
+}
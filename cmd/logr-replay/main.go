@@ -0,0 +1,83 @@
+// Command logr-replay reads NDJSON records produced by the JSON formatter and
+// re-emits them to the targets described by a logr config file.
+//
+//	logr-replay -config targets.json archive1.log archive2.log
+//
+// Records are read from stdin when no files are given.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/config"
+	"github.com/mattermost/logr/v2/replay"
+)
+
+func main() {
+	cfgFile := flag.String("config", "", "logr target config file (JSON)")
+	skip := flag.Bool("skip-invalid", false, "skip records that cannot be parsed")
+	groupKey := flag.String("group-key", "", "key under which fields were grouped, if any")
+	flag.Parse()
+
+	if err := run(*cfgFile, *skip, *groupKey, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "logr-replay:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfgFile string, skip bool, groupKey string, files []string) error {
+	if cfgFile == "" {
+		return fmt.Errorf("missing -config")
+	}
+	b, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	var cfg map[string]config.TargetCfg
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("cannot parse config: %w", err)
+	}
+
+	lgr, err := logr.New()
+	if err != nil {
+		return err
+	}
+	if err := config.ConfigureTargets(lgr, cfg, nil); err != nil {
+		return err
+	}
+
+	opts := replay.Options{SkipInvalid: skip, KeyGroupFields: groupKey}
+	logger := lgr.NewLogger()
+
+	replayOne := func(r io.Reader) error {
+		_, err := replay.Replay(context.Background(), r, logger, opts)
+		return err
+	}
+
+	if len(files) == 0 {
+		err = replayOne(os.Stdin)
+	}
+	for _, name := range files {
+		if err != nil {
+			break
+		}
+		var f *os.File
+		if f, err = os.Open(name); err != nil {
+			break
+		}
+		err = replayOne(f)
+		f.Close()
+	}
+
+	if errShutdown := lgr.Shutdown(); err == nil {
+		err = errShutdown
+	}
+	return err
+}
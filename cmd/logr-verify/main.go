@@ -0,0 +1,104 @@
+// Command logr-verify checks the Ed25519 signatures of records signed by the
+// formatters.Ed25519 post-processor, one record per line.
+//
+//	logr-verify -key signing.pub app.log app.log.1
+//
+// Records are read from stdin when no files are given. The public key file holds
+// the 32 byte key, raw or base64 encoded. Each invalid record is reported with its
+// file and line number, and the exit status is 1 if any record is invalid.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/mattermost/logr/v2/formatters"
+)
+
+func main() {
+	keyFile := flag.String("key", "", "file holding the Ed25519 public key, raw or base64 encoded")
+	sep := flag.String("sep", " ", "separator between each record and its signature")
+	printRecs := flag.Bool("print", false, "print verified records without their signatures")
+	flag.Parse()
+
+	if err := run(*keyFile, *sep, *printRecs, flag.Args(), os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "logr-verify:", err)
+		os.Exit(1)
+	}
+}
+
+func run(keyFile string, sep string, printRecs bool, files []string, out io.Writer, errOut io.Writer) error {
+	if keyFile == "" {
+		return fmt.Errorf("missing -key")
+	}
+	key, err := readPublicKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	var invalid int
+	verifyOne := func(name string, r io.Reader) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			rec, err := formatters.VerifyEd25519(key, scanner.Bytes(), sep)
+			if err != nil {
+				invalid++
+				fmt.Fprintf(errOut, "%s:%d: %v\n", name, line, err)
+				continue
+			}
+			if printRecs {
+				out.Write(rec)
+				fmt.Fprintln(out)
+			}
+		}
+		return scanner.Err()
+	}
+
+	if len(files) == 0 {
+		if err := verifyOne("stdin", os.Stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = verifyOne(name, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d invalid records", invalid)
+	}
+	return nil
+}
+
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == ed25519.PublicKeySize {
+		return ed25519.PublicKey(b), nil
+	}
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%s does not hold a %d byte Ed25519 public key", path, ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
@@ -0,0 +1,94 @@
+package logr
+
+import (
+	"reflect"
+	"time"
+)
+
+// coalescer merges identical consecutive log records received within a window
+// into a single record with a count field. It is only accessed by the Logr
+// read loop so needs no locking.
+type coalescer struct {
+	window time.Duration
+	key    string
+
+	held  *LogRec
+	count int
+	timer *time.Timer
+}
+
+func newCoalescer(window time.Duration, key string) *coalescer {
+	timer := time.NewTimer(window)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	return &coalescer{window: window, key: key, timer: timer}
+}
+
+// expired returns a channel that is signaled when the held record's window
+// elapses, or nil if no record is held.
+func (c *coalescer) expired() <-chan time.Time {
+	if c == nil || c.held == nil {
+		return nil
+	}
+	return c.timer.C
+}
+
+// add returns the records that should be fanned out to targets, if any. A new
+// record is held until either the window elapses or a different record arrives.
+func (c *coalescer) add(rec *LogRec) *LogRec {
+	if c.held != nil && rec.time.Sub(c.held.time) < c.window && isSameRec(c.held, rec) {
+		c.count++
+		return nil
+	}
+
+	out := c.release()
+	c.held = rec
+	c.count = 1
+	c.timer.Reset(c.window)
+	return out
+}
+
+// release returns the held record, if any, with a count field added when more
+// than one record was merged.
+func (c *coalescer) release() *LogRec {
+	if c.held == nil {
+		return nil
+	}
+	if !c.timer.Stop() {
+		select {
+		case <-c.timer.C:
+		default:
+		}
+	}
+
+	rec := c.held
+	if c.count > 1 {
+		fields := make([]Field, 0, len(rec.fieldsAll)+1)
+		fields = append(fields, rec.fieldsAll...)
+		fields = append(fields, Int(c.key, c.count))
+		rec.fieldsAll, _ = dedupFields(fields)
+	}
+	c.held = nil
+	c.count = 0
+	return rec
+}
+
+// isSameRec returns true if the records have the same level, message and fields.
+// Both records must have been prepped.
+func isSameRec(a, b *LogRec) bool {
+	if a.level != b.level || a.msg != b.msg || len(a.fieldsAll) != len(b.fieldsAll) {
+		return false
+	}
+	for i := range a.fieldsAll {
+		fa, fb := a.fieldsAll[i], b.fieldsAll[i]
+		if fa.Key != fb.Key || fa.Type != fb.Type || fa.Integer != fb.Integer ||
+			fa.Float != fb.Float || fa.String != fb.String {
+			return false
+		}
+		if !reflect.DeepEqual(fa.Interface, fb.Interface) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package logr_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesce(t *testing.T) {
+	lgr, err := logr.New(logr.Coalesce(time.Hour, ""))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "coalesceTest", &logr.StdFilter{Lvl: logr.Debug},
+		&formatters.Plain{DisableTimestamp: true, DisableLevel: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("db", "main"))
+	for i := 0; i < 5; i++ {
+		logger.Error("connection refused", logr.Int("port", 5432))
+	}
+	logger.Error("connection refused", logr.Int("port", 5433))
+	logger.Info("connection refused", logr.Int("port", 5433))
+	logger.Info("connection refused", logr.Int("port", 5433))
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		"connection refused db=main port=5432 count=5",
+		"connection refused db=main port=5433",
+		"connection refused db=main port=5433 count=2",
+	}, lines)
+}
+
+func TestCoalesceWindow(t *testing.T) {
+	lgr, err := logr.New(logr.Coalesce(time.Millisecond*50, "repeats"))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "coalesceTest", &logr.StdFilter{Lvl: logr.Debug},
+		&formatters.Plain{DisableTimestamp: true, DisableLevel: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Warn("storm")
+	logger.Warn("storm")
+
+	// held record is released once the window elapses without needing a flush.
+	assert.Eventually(t, func() bool {
+		return strings.TrimSpace(buf.String()) == "storm repeats=2"
+	}, time.Second*5, time.Millisecond*10)
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	_, err = logr.New(logr.Coalesce(0, ""))
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mattermost/logr/v2"
 	"github.com/mattermost/logr/v2/formatters"
@@ -15,16 +16,106 @@ import (
 )
 
 type TargetCfg struct {
-	Type          string          `json:"type"` // one of "console", "file", "tcp", "syslog", "none".
+	Type          string          `json:"type"` // one of "console", "file", "tcp", "syslog", "kinesis", "s3", "parquet", "pubsub", "mqtt", "fluent", "datadog", "clickhouse", "azure_monitor", "redis", "socket", "udp", "mmap", "none".
 	Options       json.RawMessage `json:"options,omitempty"`
-	Format        string          `json:"format"` // one of "json", "plain", "gelf"
+	Format        string          `json:"format"` // one of "json", "plain", "gelf", "avro", "w3c", "ncsa"
 	FormatOptions json.RawMessage `json:"format_options,omitempty"`
 	Levels        []logr.Level    `json:"levels"`
 	MaxQueueSize  int             `json:"maxqueuesize,omitempty"`
+
+	// MaxQueueBytes, when greater than zero, bounds the estimated size of the records
+	// queued for the target. See `logr.MaxQueueBytes`.
+	MaxQueueBytes int `json:"max_queue_bytes,omitempty"`
+
+	// WriteTimeoutMillis, when greater than zero, aborts writes that take longer than
+	// this for targets that support it. See `logr.WriteTimeout`.
+	WriteTimeoutMillis int64 `json:"write_timeout_millis,omitempty"`
+
+	// BlockLevel, when not empty, is the name of a standard level, e.g. "error", at or
+	// above which records are never dropped when the queue is full. See `logr.BlockLevel`.
+	BlockLevel string `json:"block_level,omitempty"`
+
+	// Synchronous writes records on the logging goroutine instead of via the queue.
+	// See `logr.Synchronous`.
+	Synchronous bool `json:"synchronous,omitempty"`
+
+	// MaxRecordAgeMillis, when greater than zero, drops queued records older than this
+	// when dequeued. MaxRecordAgeLevel, when not empty, is the name of the most severe
+	// standard level, e.g. "debug", to which it applies; all levels by default.
+	// See `logr.MaxRecordAge`.
+	MaxRecordAgeMillis int64  `json:"max_record_age_millis,omitempty"`
+	MaxRecordAgeLevel  string `json:"max_record_age_level,omitempty"`
+
+	// AllowIsolatedLevels opts the target in to receiving records at these levels
+	// when isolated via `logr.IsolateLevels`. See `logr.AllowIsolatedLevels`.
+	AllowIsolatedLevels []logr.Level `json:"allow_isolated_levels,omitempty"`
+
+	// Priority delivers queued records by level, most severe first, instead of in
+	// order. See `logr.PriorityQueue`.
+	Priority bool `json:"priority,omitempty"`
+
+	// FlushIntervalMillis, when greater than zero, periodically flushes targets that
+	// buffer records. See `logr.FlushInterval`.
+	FlushIntervalMillis int64 `json:"flush_interval_millis,omitempty"`
+
+	// Group, when not empty, names a group of targets sharing a budget of
+	// GroupMaxQueued queued records. See `logr.InTargetGroup`.
+	Group          string `json:"group,omitempty"`
+	GroupMaxQueued int    `json:"group_max_queued,omitempty"`
+
+	// FieldAllow, when not empty, restricts the fields output by the target to these
+	// keys. FieldDeny excludes these keys. Only one can be set. Both accept "stacktrace"
+	// and "caller". See `logr.FieldAllowList`.
+	FieldAllow []string `json:"field_allow,omitempty"`
+	FieldDeny  []string `json:"field_deny,omitempty"`
+
+	// FieldTransforms rename, hash or drop fields before they are output by the target.
+	FieldTransforms []logr.FieldTransform `json:"field_transforms,omitempty"`
+
+	// OffloadFields, when not nil, moves oversized field values to files. See
+	// `logr.OffloadFields`.
+	OffloadFields *OffloadCfg `json:"offload_fields,omitempty"`
+
+	// Filter, when not empty, names a filter registered via `logr.RegisterFilterFactory`
+	// which is used instead of `Levels`.
+	Filter        string          `json:"filter,omitempty"`
+	FilterOptions json.RawMessage `json:"filter_options,omitempty"`
+
+	// PostProcessors are applied, in order, to the formatted output before it is
+	// written to the target.
+	PostProcessors []PostProcessorCfg `json:"post_processors,omitempty"`
+	// PostDelimiter, when not empty, is appended after post-processing. See `formatters.Chain`.
+	PostDelimiter string `json:"post_delimiter,omitempty"`
+}
+
+// OffloadCfg moves field values larger than MaxSize bytes to files in Dir,
+// replacing them with reference fields. See `targets.FileBlobStore`.
+type OffloadCfg struct {
+	MaxSize int    `json:"max_size"`
+	Dir     string `json:"dir"`
+}
+
+func (oc OffloadCfg) CheckValid() error {
+	if oc.MaxSize <= 0 {
+		return errors.New("max_size must be greater than zero")
+	}
+	if oc.Dir == "" {
+		return errors.New("dir cannot be empty")
+	}
+	return nil
+}
+
+type PostProcessorCfg struct {
+	Type    string          `json:"type"` // one of "gzip", "base64", "hmac", "aes-gcm", "ed25519", "length-prefix"
+	Options json.RawMessage `json:"options,omitempty"`
 }
 
 type ConsoleOptions struct {
 	Out string `json:"out"` // one of "stdout", "stderr"
+
+	// MaxBatchRecords, when greater than one, coalesces queued records into fewer
+	// writes. See `targets.WriterOptions`.
+	MaxBatchRecords int `json:"max_batch_records,omitempty"`
 }
 
 type TargetFactory func(targetType string, options json.RawMessage) (logr.Target, error)
@@ -42,7 +133,9 @@ var removeAll = func(ti logr.TargetInfo) bool { return true }
 // source or can be programmatically created.
 //
 // An optional set of factories can be provided which will be called to create any target
-// types or formatters not built-in.
+// types or formatters not built-in. Factories registered via `logr.RegisterTargetFactory`,
+// `logr.RegisterFormatterFactory` and `logr.RegisterFilterFactory` are also consulted,
+// including when a provided factory returns nil for a type it does not handle.
 //
 // To append log targets to an existing config, use `(*Logr).AddTarget` or
 // `(*Logr).AddTargetFromConfig` instead.
@@ -70,25 +163,112 @@ func ConfigureTargets(lgr *logr.Logr, config map[string]TargetCfg, factories *Fa
 			return fmt.Errorf("error creating formatter for log target %s: %w", name, err)
 		}
 
-		filter := newFilter(tcfg.Levels)
+		if len(tcfg.PostProcessors) != 0 || tcfg.PostDelimiter != "" {
+			chain := formatters.NewChain(formatter)
+			chain.Delimiter = tcfg.PostDelimiter
+			for _, pcfg := range tcfg.PostProcessors {
+				pp, err := newPostProcessor(pcfg.Type, pcfg.Options)
+				if err != nil {
+					return fmt.Errorf("error creating post-processor for log target %s: %w", name, err)
+				}
+				chain.PostProcessors = append(chain.PostProcessors, pp)
+			}
+			formatter = chain
+		}
+
+		filter, err := newFilter(tcfg)
+		if err != nil {
+			return fmt.Errorf("error creating filter for log target %s: %w", name, err)
+		}
 		qSize := tcfg.MaxQueueSize
 		if qSize == 0 {
 			qSize = logr.DefaultMaxQueueSize
 		}
 
-		if err = lgr.AddTarget(target, name, filter, formatter, qSize); err != nil {
+		hostOpts := []logr.TargetOption{logr.WriteTimeout(time.Duration(tcfg.WriteTimeoutMillis) * time.Millisecond)}
+		if tcfg.FieldAllow != nil {
+			hostOpts = append(hostOpts, logr.FieldAllowList(tcfg.FieldAllow...))
+		}
+		if tcfg.FieldDeny != nil {
+			hostOpts = append(hostOpts, logr.FieldDenyList(tcfg.FieldDeny...))
+		}
+		if len(tcfg.FieldTransforms) > 0 {
+			hostOpts = append(hostOpts, logr.FieldTransforms(tcfg.FieldTransforms...))
+		}
+		if tcfg.OffloadFields != nil {
+			if err := tcfg.OffloadFields.CheckValid(); err != nil {
+				return fmt.Errorf("invalid offload_fields for log target %s: %w", name, err)
+			}
+			store, err := targets.NewFileBlobStore(tcfg.OffloadFields.Dir)
+			if err != nil {
+				return fmt.Errorf("error creating blob store for log target %s: %w", name, err)
+			}
+			hostOpts = append(hostOpts, logr.OffloadFields(tcfg.OffloadFields.MaxSize, store))
+		}
+		if tcfg.BlockLevel != "" {
+			level, ok := mappedLevel(tcfg.BlockLevel)
+			if !ok {
+				return fmt.Errorf("invalid block level %q for log target %s", tcfg.BlockLevel, name)
+			}
+			hostOpts = append(hostOpts, logr.BlockLevel(level))
+		}
+		if tcfg.Synchronous {
+			hostOpts = append(hostOpts, logr.Synchronous())
+		}
+		if tcfg.MaxRecordAgeMillis > 0 {
+			level := logr.Panic
+			if tcfg.MaxRecordAgeLevel != "" {
+				var ok bool
+				if level, ok = mappedLevel(tcfg.MaxRecordAgeLevel); !ok {
+					return fmt.Errorf("invalid max record age level %q for log target %s", tcfg.MaxRecordAgeLevel, name)
+				}
+			}
+			hostOpts = append(hostOpts, logr.MaxRecordAge(time.Duration(tcfg.MaxRecordAgeMillis)*time.Millisecond, level))
+		}
+		if len(tcfg.AllowIsolatedLevels) > 0 {
+			hostOpts = append(hostOpts, logr.AllowIsolatedLevels(tcfg.AllowIsolatedLevels...))
+		}
+		if tcfg.Priority {
+			hostOpts = append(hostOpts, logr.PriorityQueue())
+		}
+		if tcfg.FlushIntervalMillis > 0 {
+			hostOpts = append(hostOpts, logr.FlushInterval(time.Duration(tcfg.FlushIntervalMillis)*time.Millisecond))
+		}
+		if tcfg.MaxQueueBytes > 0 {
+			hostOpts = append(hostOpts, logr.MaxQueueBytes(tcfg.MaxQueueBytes))
+		}
+		if tcfg.Group != "" {
+			hostOpts = append(hostOpts, logr.InTargetGroup(tcfg.Group, tcfg.GroupMaxQueued))
+		}
+
+		if err = lgr.AddTarget(target, name, filter, formatter, qSize, hostOpts...); err != nil {
 			return fmt.Errorf("error adding log target %s: %w", name, err)
 		}
 	}
 	return nil
 }
 
-func newFilter(levels []logr.Level) logr.Filter {
+func newFilter(tcfg TargetCfg) (logr.Filter, error) {
+	if tcfg.Filter != "" {
+		factory, ok := logr.GetFilterFactory(tcfg.Filter)
+		if !ok {
+			return nil, fmt.Errorf("filter '%s' is unrecogized", tcfg.Filter)
+		}
+		f, err := factory(tcfg.FilterOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error from filter factory: %w", err)
+		}
+		if f == nil {
+			return nil, fmt.Errorf("filter type '%s' not found", tcfg.Filter)
+		}
+		return f, nil
+	}
+
 	filter := &logr.CustomFilter{}
-	for _, lvl := range levels {
+	for _, lvl := range tcfg.Levels {
 		filter.Add(lvl)
 	}
-	return filter
+	return filter, nil
 }
 
 func newTarget(targetType string, options json.RawMessage, factory TargetFactory) (logr.Target, error) {
@@ -109,7 +289,7 @@ func newTarget(targetType string, options json.RawMessage, factory TargetFactory
 		default:
 			return nil, fmt.Errorf("invalid console target option '%s'", c.Out)
 		}
-		return targets.NewWriterTarget(w), nil
+		return targets.NewWriterTargetWithOptions(w, targets.WriterOptions{MaxBatchRecords: c.MaxBatchRecords}), nil
 	case "file":
 		fo := targets.FileOptions{}
 		if len(options) == 0 {
@@ -146,15 +326,183 @@ func newTarget(targetType string, options json.RawMessage, factory TargetFactory
 			return nil, fmt.Errorf("invalid SysLog target options: %w", err)
 		}
 		return targets.NewSyslogTarget(&so)
+	case "kinesis":
+		ko := targets.KinesisOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing Kinesis target options")
+		}
+		if err := json.Unmarshal(options, &ko); err != nil {
+			return nil, fmt.Errorf("error decoding Kinesis target options: %w", err)
+		}
+		if err := ko.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid Kinesis target options: %w", err)
+		}
+		return targets.NewKinesisTarget(ko)
+	case "s3":
+		so := targets.S3Options{}
+		if len(options) == 0 {
+			return nil, errors.New("missing S3 target options")
+		}
+		if err := json.Unmarshal(options, &so); err != nil {
+			return nil, fmt.Errorf("error decoding S3 target options: %w", err)
+		}
+		if err := so.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid S3 target options: %w", err)
+		}
+		return targets.NewS3Target(so)
+	case "parquet":
+		po := targets.ParquetOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing Parquet target options")
+		}
+		if err := json.Unmarshal(options, &po); err != nil {
+			return nil, fmt.Errorf("error decoding Parquet target options: %w", err)
+		}
+		if err := po.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid Parquet target options: %w", err)
+		}
+		return targets.NewParquetTarget(po)
+	case "pubsub":
+		po := targets.PubSubOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing Pub/Sub target options")
+		}
+		if err := json.Unmarshal(options, &po); err != nil {
+			return nil, fmt.Errorf("error decoding Pub/Sub target options: %w", err)
+		}
+		if err := po.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid Pub/Sub target options: %w", err)
+		}
+		return targets.NewPubSubTarget(po)
+	case "mqtt":
+		mo := targets.MQTTOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing MQTT target options")
+		}
+		if err := json.Unmarshal(options, &mo); err != nil {
+			return nil, fmt.Errorf("error decoding MQTT target options: %w", err)
+		}
+		if err := mo.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid MQTT target options: %w", err)
+		}
+		return targets.NewMQTTTarget(mo)
+	case "fluent":
+		fo := targets.FluentOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing Fluent target options")
+		}
+		if err := json.Unmarshal(options, &fo); err != nil {
+			return nil, fmt.Errorf("error decoding Fluent target options: %w", err)
+		}
+		if err := fo.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid Fluent target options: %w", err)
+		}
+		return targets.NewFluentTarget(fo)
+	case "datadog":
+		do := targets.DatadogOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing Datadog target options")
+		}
+		if err := json.Unmarshal(options, &do); err != nil {
+			return nil, fmt.Errorf("error decoding Datadog target options: %w", err)
+		}
+		if err := do.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid Datadog target options: %w", err)
+		}
+		return targets.NewDatadogTarget(do)
+	case "clickhouse":
+		co := targets.ClickHouseOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing ClickHouse target options")
+		}
+		if err := json.Unmarshal(options, &co); err != nil {
+			return nil, fmt.Errorf("error decoding ClickHouse target options: %w", err)
+		}
+		if err := co.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid ClickHouse target options: %w", err)
+		}
+		return targets.NewClickHouseTarget(co)
+	case "azure_monitor":
+		ao := targets.AzureMonitorOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing Azure Monitor target options")
+		}
+		if err := json.Unmarshal(options, &ao); err != nil {
+			return nil, fmt.Errorf("error decoding Azure Monitor target options: %w", err)
+		}
+		if err := ao.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid Azure Monitor target options: %w", err)
+		}
+		return targets.NewAzureMonitorTarget(ao)
+	case "redis":
+		ro := targets.RedisOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing Redis target options")
+		}
+		if err := json.Unmarshal(options, &ro); err != nil {
+			return nil, fmt.Errorf("error decoding Redis target options: %w", err)
+		}
+		if err := ro.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid Redis target options: %w", err)
+		}
+		return targets.NewRedisTarget(ro)
+	case "socket":
+		lo := targets.LocalSocketOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing socket target options")
+		}
+		if err := json.Unmarshal(options, &lo); err != nil {
+			return nil, fmt.Errorf("error decoding socket target options: %w", err)
+		}
+		if err := lo.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid socket target options: %w", err)
+		}
+		return targets.NewLocalSocketTarget(lo)
+	case "udp":
+		uo := targets.UDPOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing UDP target options")
+		}
+		if err := json.Unmarshal(options, &uo); err != nil {
+			return nil, fmt.Errorf("error decoding UDP target options: %w", err)
+		}
+		if err := uo.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid UDP target options: %w", err)
+		}
+		return targets.NewUDPTarget(uo)
+	case "mmap":
+		mo := targets.MmapOptions{}
+		if len(options) == 0 {
+			return nil, errors.New("missing mmap target options")
+		}
+		if err := json.Unmarshal(options, &mo); err != nil {
+			return nil, fmt.Errorf("error decoding mmap target options: %w", err)
+		}
+		if err := mo.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid mmap target options: %w", err)
+		}
+		return targets.NewMmapTarget(mo)
 	case "none":
 		return nil, nil
 	default:
+		// a factory returning nil does not handle the type; try the registry next.
 		if factory != nil {
 			t, err := factory(targetType, options)
-			if err != nil || t == nil {
+			if err != nil {
 				return nil, fmt.Errorf("error from target factory: %w", err)
 			}
-			return t, nil
+			if t != nil {
+				return t, nil
+			}
+		}
+		if registered, ok := logr.GetTargetFactory(targetType); ok {
+			t, err := registered(options)
+			if err != nil {
+				return nil, fmt.Errorf("error from target factory: %w", err)
+			}
+			if t != nil {
+				return t, nil
+			}
 		}
 	}
 	return nil, fmt.Errorf("target type '%s' is unrecogized", targetType)
@@ -195,15 +543,95 @@ func newFormatter(format string, options json.RawMessage, factory FormatterFacto
 			}
 		}
 		return &g, nil
+	case "avro":
+		a := formatters.Avro{}
+		if len(options) != 0 {
+			if err := json.Unmarshal(options, &a); err != nil {
+				return nil, fmt.Errorf("error decoding Avro formatter options: %w", err)
+			}
+			if err := a.CheckValid(); err != nil {
+				return nil, fmt.Errorf("invalid Avro formatter options: %w", err)
+			}
+		}
+		return &a, nil
+	case "w3c":
+		w := formatters.W3C{}
+		if len(options) == 0 {
+			return nil, errors.New("missing W3C formatter options")
+		}
+		if err := json.Unmarshal(options, &w); err != nil {
+			return nil, fmt.Errorf("error decoding W3C formatter options: %w", err)
+		}
+		if err := w.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid W3C formatter options: %w", err)
+		}
+		return &w, nil
+	case "ncsa":
+		n := formatters.NCSA{}
+		if len(options) != 0 {
+			if err := json.Unmarshal(options, &n); err != nil {
+				return nil, fmt.Errorf("error decoding NCSA formatter options: %w", err)
+			}
+			if err := n.CheckValid(); err != nil {
+				return nil, fmt.Errorf("invalid NCSA formatter options: %w", err)
+			}
+		}
+		return &n, nil
 
 	default:
+		// a factory returning nil does not handle the type; try the registry next.
 		if factory != nil {
 			f, err := factory(format, options)
-			if err != nil || f == nil {
+			if err != nil {
 				return nil, fmt.Errorf("error from formatter factory: %w", err)
 			}
-			return f, nil
+			if f != nil {
+				return f, nil
+			}
+		}
+		if registered, ok := logr.GetFormatterFactory(format); ok {
+			f, err := registered(options)
+			if err != nil {
+				return nil, fmt.Errorf("error from formatter factory: %w", err)
+			}
+			if f != nil {
+				return f, nil
+			}
 		}
 	}
 	return nil, fmt.Errorf("format '%s' is unrecogized", format)
 }
+
+func newPostProcessor(ppType string, options json.RawMessage) (formatters.PostProcessor, error) {
+	var pp interface {
+		formatters.PostProcessor
+		CheckValid() error
+	}
+
+	switch strings.ToLower(ppType) {
+	case "gzip":
+		pp = &formatters.Gzip{}
+	case "base64":
+		pp = &formatters.Base64{}
+	case "hmac":
+		pp = &formatters.HMAC{}
+	case "aes-gcm":
+		pp = &formatters.AESGCM{}
+	case "ed25519":
+		pp = &formatters.Ed25519{}
+	case "length-prefix":
+		pp = &formatters.LengthPrefix{}
+	default:
+		return nil, fmt.Errorf("post-processor type '%s' is unrecogized", ppType)
+	}
+
+	if len(options) != 0 {
+		if err := json.Unmarshal(options, pp); err != nil {
+			return nil, fmt.Errorf("error decoding %s post-processor options: %w", ppType, err)
+		}
+	}
+	if err := pp.CheckValid(); err != nil {
+		return nil, fmt.Errorf("invalid %s post-processor options: %w", ppType, err)
+	}
+	return pp, nil
+}
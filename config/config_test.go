@@ -99,6 +99,131 @@ func TestConfigureCustomTarget(t *testing.T) {
 	assert.Contains(t, buf.String(), "mode")
 }
 
+func TestConfigureRegisteredFactories(t *testing.T) {
+	buf := &test.Buffer{}
+
+	err := logr.RegisterTargetFactory("registered_target", func(options json.RawMessage) (logr.Target, error) {
+		return targets.NewWriterTarget(buf), nil
+	})
+	require.NoError(t, err)
+	err = logr.RegisterFormatterFactory("registered_format", func(options json.RawMessage) (logr.Formatter, error) {
+		return &formatters.Plain{Delim: " ~ "}, nil
+	})
+	require.NoError(t, err)
+	err = logr.RegisterFilterFactory("registered_filter", func(options json.RawMessage) (logr.Filter, error) {
+		var opts struct {
+			Level string `json:"level"`
+		}
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, err
+		}
+		if opts.Level != "warn" {
+			return nil, fmt.Errorf("unexpected level %s", opts.Level)
+		}
+		return &logr.StdFilter{Lvl: logr.Warn}, nil
+	})
+	require.NoError(t, err)
+
+	str := `{ "sample-registered": {
+        "type": "registered_target",
+        "format": "registered_format",
+        "filter": "registered_filter",
+        "filter_options": {"level": "warn"}
+    } }`
+
+	var cfg map[string]TargetCfg
+	err = json.Unmarshal([]byte(str), &cfg)
+	require.NoError(t, err)
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	err = ConfigureTargets(lgr, cfg, nil)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("not logged")
+	logger.Warn("registered warning")
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	assert.NotContains(t, buf.String(), "not logged")
+	assert.Contains(t, buf.String(), "warn ~ ")
+	assert.Contains(t, buf.String(), "registered warning")
+}
+
+func TestConfigureNilFilter(t *testing.T) {
+	err := logr.RegisterFilterFactory("nil_filter", func(options json.RawMessage) (logr.Filter, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	cfg := map[string]TargetCfg{
+		"nil-filter": {Type: "console", Format: "plain", Filter: "nil_filter"},
+	}
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+
+	err = ConfigureTargets(lgr, cfg, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "filter type 'nil_filter' not found")
+	assert.NotContains(t, err.Error(), "%!w")
+}
+
+func TestConfigureNilFactories(t *testing.T) {
+	buf := &test.Buffer{}
+	err := logr.RegisterTargetFactory("fallback_target", func(options json.RawMessage) (logr.Target, error) {
+		return targets.NewWriterTarget(buf), nil
+	})
+	require.NoError(t, err)
+	err = logr.RegisterFormatterFactory("fallback_format", func(options json.RawMessage) (logr.Formatter, error) {
+		return &formatters.Plain{Delim: " | ", DisableTimestamp: true}, nil
+	})
+	require.NoError(t, err)
+
+	// factories that do not handle a type defer to the registry.
+	factories := &Factories{
+		TargetFactory: func(targetType string, options json.RawMessage) (logr.Target, error) {
+			return nil, nil
+		},
+		FormatterFactory: func(format string, options json.RawMessage) (logr.Formatter, error) {
+			return nil, nil
+		},
+	}
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	cfg := map[string]TargetCfg{
+		"fallback": {Type: "fallback_target", Format: "fallback_format", Levels: []logr.Level{logr.Info}},
+	}
+	require.NoError(t, ConfigureTargets(lgr, cfg, factories))
+	lgr.NewLogger().Info("registered")
+	require.NoError(t, lgr.Flush())
+	assert.Contains(t, buf.String(), "info | registered")
+
+	cfg = map[string]TargetCfg{
+		"unknown": {Type: "unknown_target", Format: "plain", Levels: []logr.Level{logr.Info}},
+	}
+	err = ConfigureTargets(lgr, cfg, factories)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "target type 'unknown_target' is unrecogized")
+	assert.NotContains(t, err.Error(), "%!w")
+
+	cfg = map[string]TargetCfg{
+		"unknown": {Type: "console", Format: "unknown_format", Levels: []logr.Level{logr.Info}},
+	}
+	err = ConfigureTargets(lgr, cfg, factories)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "format 'unknown_format' is unrecogized")
+	assert.NotContains(t, err.Error(), "%!w")
+
+	require.NoError(t, lgr.Shutdown())
+}
+
 func makeCustomTargetFactory(w io.Writer) TargetFactory {
 	return func(targetType string, options json.RawMessage) (logr.Target, error) {
 		if targetType != "my_custom_target" {
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+)
+
+// Log styles for `NewForKubernetes`. See `KubernetesOptions.Style`.
+const (
+	// KubernetesStyleGCP outputs `severity`, `message` and `time` keys as expected by
+	// Google Cloud Logging, with severities such as "WARNING".
+	KubernetesStyleGCP = "gcp"
+
+	// KubernetesStyleECS outputs `log.level`, `message` and `@timestamp` keys as
+	// expected by Elastic Common Schema.
+	KubernetesStyleECS = "ecs"
+)
+
+const (
+	// KubernetesDefaultQueueSize is the default size of the Logr and target queues
+	// created by `NewForKubernetes`.
+	KubernetesDefaultQueueSize = 4096
+
+	// KubernetesLevelEnv is the environment variable consulted for the minimum level
+	// when `KubernetesOptions.Level` is empty.
+	KubernetesLevelEnv = "LOG_LEVEL"
+)
+
+// kubernetesPodEnv maps the environment variables typically populated via the
+// downward API to the keys of the fields added to every log record.
+var kubernetesPodEnv = []struct {
+	env string
+	key string
+}{
+	{"POD_NAME", "k8s.pod.name"},
+	{"POD_NAMESPACE", "k8s.namespace.name"},
+	{"POD_IP", "k8s.pod.ip"},
+	{"NODE_NAME", "k8s.node.name"},
+	{"CONTAINER_NAME", "k8s.container.name"},
+}
+
+// gcpSeverities maps standard level names to Google Cloud Logging severities.
+var gcpSeverities = map[string]string{
+	logr.Trace.Name: "DEBUG",
+	logr.Debug.Name: "DEBUG",
+	logr.Info.Name:  "INFO",
+	logr.Warn.Name:  "WARNING",
+	logr.Error.Name: "ERROR",
+	logr.Fatal.Name: "CRITICAL",
+	logr.Panic.Name: "EMERGENCY",
+}
+
+// KubernetesOptions provides parameters for `NewForKubernetes`.
+type KubernetesOptions struct {
+	// Style determines the keys and level values of the JSON output; one of
+	// KubernetesStyleGCP or KubernetesStyleECS. Defaults to KubernetesStyleGCP.
+	Style string
+
+	// Level is the name of the minimum level output, e.g. "debug". Defaults to the
+	// value of the LOG_LEVEL environment variable, or "info" if not set.
+	Level string
+
+	// QueueSize is the size of the Logr and target queues. Defaults to
+	// KubernetesDefaultQueueSize.
+	QueueSize int
+
+	// Out is where log records are written. Defaults to os.Stdout.
+	Out io.Writer
+}
+
+// NewForKubernetes creates a Logr configured for containers running in Kubernetes:
+// JSON written to stdout, with keys and level values understood by the log
+// aggregator selected via `KubernetesOptions.Style`, and larger queues than the
+// defaults to absorb bursts. Pod metadata exposed via the downward API environment
+// variables POD_NAME, POD_NAMESPACE, POD_IP, NODE_NAME and CONTAINER_NAME is added
+// to every log record. Additional options are applied after the preset's options.
+func NewForKubernetes(options KubernetesOptions, opts ...logr.Option) (*logr.Logr, error) {
+	formatter, err := kubernetesFormatter(options.Style)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := resolveLevel(options.Level, KubernetesLevelEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	qSize := options.QueueSize
+	if qSize == 0 {
+		qSize = KubernetesDefaultQueueSize
+	}
+	out := options.Out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	preset := []logr.Option{logr.MaxQueueSize(qSize)}
+	if fields := kubernetesPodFields(); len(fields) > 0 {
+		preset = append(preset, logr.Enrichers(nil, func(logr.Level) []logr.Field { return fields }))
+	}
+
+	filter := &logr.StdFilter{Lvl: level, Stacktrace: logr.Error}
+	return newPreset(out, "kubernetes", filter, formatter, qSize, append(preset, opts...))
+}
+
+func kubernetesFormatter(style string) (*formatters.JSON, error) {
+	switch strings.ToLower(style) {
+	case KubernetesStyleGCP, "":
+		return &formatters.JSON{
+			KeyTimestamp:    "time",
+			TimestampFormat: "2006-01-02T15:04:05.000000000Z07:00",
+			KeyLevel:        "severity",
+			LevelNames:      gcpSeverities,
+			KeyMsg:          "message",
+		}, nil
+	case KubernetesStyleECS:
+		return &formatters.JSON{
+			KeyTimestamp:     "@timestamp",
+			TimestampFormat:  "2006-01-02T15:04:05.000Z07:00",
+			KeyLevel:         "log.level",
+			KeyMsg:           "message",
+			KeyStacktrace:    "error.stack_trace",
+			KeyFrameFunction: "function",
+			KeyFrameFile:     "file",
+			KeyFrameLine:     "line",
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid style %q", style)
+}
+
+// kubernetesPodFields returns fields for the pod metadata found in the environment.
+func kubernetesPodFields() []logr.Field {
+	var fields []logr.Field
+	for _, pe := range kubernetesPodEnv {
+		if val := os.Getenv(pe.env); val != "" {
+			fields = append(fields, logr.String(pe.key, val))
+		}
+	}
+	return fields
+}
+
+// resolveLevel returns the level mapped to by the name, or by the environment
+// variable if name is empty, or Info if neither is set.
+func resolveLevel(name string, env string) (logr.Level, error) {
+	if name == "" {
+		name = os.Getenv(env)
+	}
+	if name == "" {
+		return logr.Info, nil
+	}
+	level, ok := mappedLevel(name)
+	if !ok {
+		return logr.Level{}, fmt.Errorf("invalid level %q", name)
+	}
+	return level, nil
+}
+
+// mappedLevel returns the level mapped to by the name, ignoring case, using the
+// shared `logr.LevelMapper`.
+func mappedLevel(name string) (logr.Level, bool) {
+	return logr.GetLevelMapper().Name(name)
+}
+
+var stdLevels = []logr.Level{logr.Panic, logr.Fatal, logr.Error, logr.Warn, logr.Info, logr.Debug, logr.Trace}
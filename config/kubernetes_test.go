@@ -0,0 +1,56 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewForKubernetes(t *testing.T) {
+	t.Setenv("POD_NAME", "api-7d9f")
+	t.Setenv("POD_NAMESPACE", "prod")
+	t.Setenv(KubernetesLevelEnv, "warn")
+
+	tests := []struct {
+		style    string
+		keyTime  string
+		keyLevel string
+		warn     string
+	}{
+		{style: "", keyTime: "time", keyLevel: "severity", warn: "WARNING"},
+		{style: KubernetesStyleECS, keyTime: "@timestamp", keyLevel: "log.level", warn: "warn"},
+	}
+
+	for _, tt := range tests {
+		buf := &test.Buffer{}
+		lgr, err := NewForKubernetes(KubernetesOptions{Style: tt.style, Out: buf})
+		require.NoError(t, err)
+
+		logger := lgr.NewLogger()
+		logger.Info("not output")
+		logger.Warn("disk almost full", logr.Int("pct", 93))
+		require.NoError(t, lgr.Shutdown())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 1, tt.style)
+
+		var rec map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+		assert.Contains(t, rec, tt.keyTime)
+		assert.Equal(t, tt.warn, rec[tt.keyLevel])
+		assert.Equal(t, "disk almost full", rec["message"])
+		assert.Equal(t, "api-7d9f", rec["k8s.pod.name"])
+		assert.Equal(t, "prod", rec["k8s.namespace.name"])
+		assert.NotContains(t, rec, "k8s.node.name")
+	}
+
+	_, err := NewForKubernetes(KubernetesOptions{Style: "splunk"})
+	assert.Error(t, err)
+	_, err = NewForKubernetes(KubernetesOptions{Level: "verbose"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+)
+
+// NewDevelopment creates a Logr with defaults suited to development: colored plain
+// text written to stdout, including the caller, with Debug enabled and stack traces
+// for Warn and more severe levels. Additional options are applied after the preset's
+// options.
+func NewDevelopment(opts ...logr.Option) (*logr.Logr, error) {
+	formatter := &formatters.Plain{EnableColor: true, EnableCaller: true, MinLevelLen: 5}
+	filter := &logr.StdFilter{Lvl: logr.Debug, Stacktrace: logr.Warn}
+	return newPreset(os.Stdout, "development", filter, formatter, logr.DefaultMaxQueueSize, opts)
+}
+
+// NewProduction creates a Logr with defaults suited to production: JSON written to
+// stdout with Info enabled and stack traces for Error and more severe levels.
+// Repetitive records are sampled, logging the first 100 records per second with a
+// given level and message and every 100th thereafter. Additional options are applied
+// after the preset's options.
+func NewProduction(opts ...logr.Option) (*logr.Logr, error) {
+	opts = append([]logr.Option{logr.Sampling(time.Second, 100, 100)}, opts...)
+	filter := &logr.StdFilter{Lvl: logr.Info, Stacktrace: logr.Error}
+	return newPreset(os.Stdout, "production", filter, &formatters.JSON{}, logr.DefaultMaxQueueSize, opts)
+}
+
+// newPreset creates a Logr with a single writer target.
+func newPreset(w io.Writer, name string, filter logr.Filter, formatter logr.Formatter, qSize int, opts []logr.Option) (*logr.Logr, error) {
+	lgr, err := logr.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := lgr.AddTarget(targets.NewWriterTarget(w), name, filter, formatter, qSize); err != nil {
+		_ = lgr.Shutdown()
+		return nil, err
+	}
+	return lgr, nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDevelopment(t *testing.T) {
+	lgr, err := NewDevelopment()
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+
+	assert.True(t, lgr.IsLevelEnabled(logr.Debug).Enabled)
+	assert.False(t, lgr.IsLevelEnabled(logr.Trace).Enabled)
+	assert.True(t, lgr.IsLevelEnabled(logr.Warn).Stacktrace)
+}
+
+func TestNewProduction(t *testing.T) {
+	lgr, err := NewProduction(logr.MaxQueueSize(10))
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+
+	assert.False(t, lgr.IsLevelEnabled(logr.Debug).Enabled)
+	assert.True(t, lgr.IsLevelEnabled(logr.Info).Enabled)
+	assert.False(t, lgr.IsLevelEnabled(logr.Warn).Stacktrace)
+	assert.True(t, lgr.IsLevelEnabled(logr.Error).Stacktrace)
+	assert.Equal(t, 10, lgr.StatsSnapshot().QueueCapacity)
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+)
+
+// Platforms for `NewForServerless`. See `ServerlessOptions.Platform`.
+const (
+	// ServerlessAWSLambda outputs the JSON format used by AWS Lambda's advanced
+	// logging controls: `timestamp`, `level` (e.g. "WARN") and `message` keys.
+	ServerlessAWSLambda = "aws_lambda"
+
+	// ServerlessGCP outputs the JSON format understood by Google Cloud Logging, for
+	// Cloud Functions and Cloud Run. See `KubernetesStyleGCP`.
+	ServerlessGCP = "gcp"
+)
+
+const (
+	// ServerlessDefaultQueueSize is the default size of the Logr and target queues
+	// created by `NewForServerless`.
+	ServerlessDefaultQueueSize = 100
+
+	// ServerlessFlushTimeout is the flush timeout used by `EndInvocation` when the
+	// invocation context has no deadline.
+	ServerlessFlushTimeout = time.Second * 2
+)
+
+// lambdaLevels maps standard level names to AWS Lambda log levels.
+var lambdaLevels = map[string]string{
+	logr.Trace.Name: "TRACE",
+	logr.Debug.Name: "DEBUG",
+	logr.Info.Name:  "INFO",
+	logr.Warn.Name:  "WARN",
+	logr.Error.Name: "ERROR",
+	logr.Fatal.Name: "FATAL",
+	logr.Panic.Name: "FATAL",
+}
+
+// ServerlessOptions provides parameters for `NewForServerless`.
+type ServerlessOptions struct {
+	// Platform determines the JSON output format; one of ServerlessAWSLambda or
+	// ServerlessGCP. Defaults to ServerlessAWSLambda.
+	Platform string
+
+	// Level is the name of the minimum level output, e.g. "debug". Defaults to the
+	// level configured for the function via AWS_LAMBDA_LOG_LEVEL for AWS Lambda, or
+	// LOG_LEVEL otherwise, or "info" if not set.
+	Level string
+
+	// QueueSize is the size of the Logr and target queues. Defaults to
+	// ServerlessDefaultQueueSize.
+	QueueSize int
+
+	// Out is where log records are written. Defaults to os.Stdout.
+	Out io.Writer
+}
+
+// NewForServerless creates a Logr configured for serverless functions: JSON written
+// to stdout in the platform's structured format, and small queues since there is little
+// to gain from buffering in a function that handles one invocation at a time. Call
+// `EndInvocation` before returning from each invocation so that no log records are
+// lost when the sandbox is frozen. Additional options are applied after the preset's
+// options.
+func NewForServerless(options ServerlessOptions, opts ...logr.Option) (*logr.Logr, error) {
+	var formatter *formatters.JSON
+	levelEnv := KubernetesLevelEnv
+
+	switch strings.ToLower(options.Platform) {
+	case ServerlessAWSLambda, "":
+		formatter = &formatters.JSON{
+			KeyTimestamp:    "timestamp",
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+			LevelNames:      lambdaLevels,
+			KeyMsg:          "message",
+		}
+		levelEnv = "AWS_LAMBDA_LOG_LEVEL"
+	case ServerlessGCP:
+		formatter, _ = kubernetesFormatter(KubernetesStyleGCP)
+	default:
+		return nil, fmt.Errorf("invalid platform %q", options.Platform)
+	}
+
+	level, err := resolveLevel(options.Level, levelEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	qSize := options.QueueSize
+	if qSize == 0 {
+		qSize = ServerlessDefaultQueueSize
+	}
+	out := options.Out
+	if out == nil {
+		out = os.Stdout
+	}
+
+	filter := &logr.StdFilter{Lvl: level, Stacktrace: logr.Error}
+	opts = append([]logr.Option{logr.MaxQueueSize(qSize)}, opts...)
+	return newPreset(out, "serverless", filter, formatter, qSize, opts)
+}
+
+// EndInvocation synchronously flushes all queued log records. Call it, typically via
+// `defer`, before returning from each invocation of a serverless function, since the
+// platform may freeze the sandbox as soon as the handler returns, stranding records
+// still queued. The flush is bounded by the deadline of the invocation context, or
+// `ServerlessFlushTimeout` if it has none; cancellation of the context is ignored so
+// records are still flushed for canceled invocations.
+func EndInvocation(ctx context.Context, lgr *logr.Logr) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(ServerlessFlushTimeout)
+	}
+	fctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	return lgr.FlushWithTimeout(fctx)
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewForServerless(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_LOG_LEVEL", "DEBUG")
+
+	buf := &test.Buffer{}
+	lgr, err := NewForServerless(ServerlessOptions{Out: buf})
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+
+	handler := func(ctx context.Context) {
+		defer func() { require.NoError(t, EndInvocation(ctx, lgr)) }()
+		lgr.NewLogger().Debug("handling event", logr.String("request_id", "c6af9ac6"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handler(ctx)
+
+	// the record is written by the time the handler returns.
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "DEBUG", rec["level"])
+	assert.Equal(t, "handling event", rec["message"])
+	assert.Equal(t, "c6af9ac6", rec["request_id"])
+	assert.Contains(t, rec, "timestamp")
+
+	_, err = NewForServerless(ServerlessOptions{Platform: "openwhisk"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,638 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+)
+
+// ValidationError describes a problem found in a JSON configuration and where it is.
+type ValidationError struct {
+	Line   int    // line number, starting at 1
+	Column int    // column in bytes, starting at 1
+	Path   string // path to the offending value, e.g. "sample-file.options.max_size"
+	Msg    string
+}
+
+func (ve *ValidationError) Error() string {
+	if ve.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", ve.Line, ve.Column, ve.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", ve.Line, ve.Column, ve.Path, ve.Msg)
+}
+
+// ValidationErrors is the list of problems returned by `Validate`, in the order
+// they appear in the configuration.
+type ValidationErrors []*ValidationError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(ve))
+	for _, e := range ve {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// builtinTargetOptions maps the built-in target types to their options.
+var builtinTargetOptions = map[string]func() interface{}{
+	"console":       func() interface{} { return &ConsoleOptions{} },
+	"file":          func() interface{} { return &targets.FileOptions{} },
+	"tcp":           func() interface{} { return &targets.TcpOptions{} },
+	"syslog":        func() interface{} { return &targets.SyslogOptions{} },
+	"kinesis":       func() interface{} { return &targets.KinesisOptions{} },
+	"s3":            func() interface{} { return &targets.S3Options{} },
+	"parquet":       func() interface{} { return &targets.ParquetOptions{} },
+	"pubsub":        func() interface{} { return &targets.PubSubOptions{} },
+	"mqtt":          func() interface{} { return &targets.MQTTOptions{} },
+	"fluent":        func() interface{} { return &targets.FluentOptions{} },
+	"datadog":       func() interface{} { return &targets.DatadogOptions{} },
+	"clickhouse":    func() interface{} { return &targets.ClickHouseOptions{} },
+	"azure_monitor": func() interface{} { return &targets.AzureMonitorOptions{} },
+	"redis":         func() interface{} { return &targets.RedisOptions{} },
+	"socket":        func() interface{} { return &targets.LocalSocketOptions{} },
+	"udp":           func() interface{} { return &targets.UDPOptions{} },
+	"mmap":          func() interface{} { return &targets.MmapOptions{} },
+	"none":          nil,
+}
+
+// builtinFormatOptions maps the built-in formats to their options.
+var builtinFormatOptions = map[string]func() interface{}{
+	"json":  func() interface{} { return &formatters.JSON{} },
+	"plain": func() interface{} { return &formatters.Plain{} },
+	"gelf":  func() interface{} { return &formatters.Gelf{} },
+	"avro":  func() interface{} { return &formatters.Avro{} },
+	"w3c":   func() interface{} { return &formatters.W3C{} },
+	"ncsa":  func() interface{} { return &formatters.NCSA{} },
+}
+
+// builtinPostProcessors maps the built-in post-processor types to their options.
+var builtinPostProcessors = map[string]func() interface{}{
+	"gzip":          func() interface{} { return &formatters.Gzip{} },
+	"base64":        func() interface{} { return &formatters.Base64{} },
+	"hmac":          func() interface{} { return &formatters.HMAC{} },
+	"aes-gcm":       func() interface{} { return &formatters.AESGCM{} },
+	"ed25519":       func() interface{} { return &formatters.Ed25519{} },
+	"length-prefix": func() interface{} { return &formatters.LengthPrefix{} },
+}
+
+// Validate checks a JSON configuration, as accepted by `ConfigureTargets` once decoded,
+// without creating any targets. Problems such as unknown fields, unknown target types or
+// formats, bad level names and invalid options are reported as `ValidationErrors` with
+// the line and column of each, plus a suggestion where a likely intended value exists.
+//
+// Target types, formats and filters registered via `logr.RegisterTargetFactory` etc. are
+// recognized. If the factories that will be passed to `ConfigureTargets` include a target
+// or formatter factory then unrecognized target types or formats are not reported.
+func Validate(data []byte, factories *Factories) error {
+	v := &validator{data: data}
+	if factories != nil {
+		v.factories = *factories
+	}
+
+	root, err := parseJSON(data)
+	if err != nil {
+		v.addf(err.offset, "", "%s", err.msg)
+		return v.errs
+	}
+
+	if !v.expectObject(root, "") {
+		return v.errs
+	}
+	for _, m := range root.members {
+		v.checkTarget(m.key, m.node)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+type validator struct {
+	data      []byte
+	factories Factories
+	errs      ValidationErrors
+}
+
+// addf adds an error for the value at the offset.
+func (v *validator) addf(offset int64, path string, format string, args ...interface{}) {
+	if offset > int64(len(v.data)) {
+		offset = int64(len(v.data))
+	}
+	line := 1 + bytes.Count(v.data[:offset], []byte{'\n'})
+	col := int(offset) + 1
+	if i := bytes.LastIndexByte(v.data[:offset], '\n'); i >= 0 {
+		col = int(offset) - i
+	}
+	v.errs = append(v.errs, &ValidationError{Line: line, Column: col, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) expectObject(n *jsonNode, path string) bool {
+	if n.kind != kindObject {
+		v.addf(n.start, path, "expected an object")
+		return false
+	}
+	return true
+}
+
+func (v *validator) expectString(n *jsonNode, path string) (string, bool) {
+	s, ok := n.value.(string)
+	if n.kind != kindScalar || !ok {
+		v.addf(n.start, path, "expected a string")
+		return "", false
+	}
+	return s, true
+}
+
+func (v *validator) checkTarget(name string, n *jsonNode) {
+	if !v.expectObject(n, name) {
+		return
+	}
+	v.checkKeys(n, name, reflect.TypeOf(TargetCfg{}))
+	decoded := v.checkDecode(n, name, &TargetCfg{})
+
+	targetType := ""
+	if tn := n.member("type"); tn == nil {
+		v.addf(n.start, name, `missing field "type"`)
+	} else if s, ok := v.expectString(tn, name+".type"); ok {
+		targetType = strings.ToLower(s)
+		v.checkTargetType(targetType, tn, name+".type")
+	}
+	if targetType == "none" {
+		return
+	}
+
+	if newOptions, ok := builtinTargetOptions[targetType]; ok {
+		path := name + ".options"
+		on := n.member("options")
+		switch {
+		case on != nil:
+			v.checkOptions(on, path, newOptions())
+		case targetType != "console":
+			v.addf(n.start, name, `missing field "options" required by target type %q`, targetType)
+		}
+	}
+
+	v.checkFormat(n, name)
+	if decoded {
+		v.checkLevels(n, name)
+	}
+
+	if fn := n.member("filter"); fn != nil {
+		if s, ok := v.expectString(fn, name+".filter"); ok && s != "" {
+			if _, ok := logr.GetFilterFactory(s); !ok {
+				v.addf(fn.start, name+".filter", "unknown filter %q; filters must be registered via logr.RegisterFilterFactory", s)
+			}
+		}
+	}
+
+	if bn := n.member("block_level"); bn != nil {
+		if s, ok := v.expectString(bn, name+".block_level"); ok && s != "" {
+			if _, ok := mappedLevel(s); !ok {
+				v.addf(bn.start, name+".block_level", "unknown level name %q%s", s, suggest(strings.ToLower(s), stdLevelNames()))
+			}
+		}
+	}
+
+	if n.member("field_allow") != nil && n.member("field_deny") != nil {
+		v.addf(n.member("field_deny").start, name+".field_deny", `only one of "field_allow" and "field_deny" can be set`)
+	}
+
+	if tn := n.member("field_transforms"); decoded && tn != nil && tn.kind == kindArray {
+		for i, item := range tn.items {
+			v.checkOptions(item, fmt.Sprintf("%s.field_transforms[%d]", name, i), &logr.FieldTransform{})
+		}
+	}
+
+	if on := n.member("offload_fields"); decoded && on != nil && on.kind == kindObject {
+		v.checkOptions(on, name+".offload_fields", &OffloadCfg{})
+	}
+
+	if pn := n.member("post_processors"); pn != nil && pn.kind == kindArray {
+		for i, item := range pn.items {
+			v.checkPostProcessor(item, fmt.Sprintf("%s.post_processors[%d]", name, i))
+		}
+	}
+}
+
+func (v *validator) checkTargetType(targetType string, n *jsonNode, path string) {
+	if _, ok := builtinTargetOptions[targetType]; ok {
+		return
+	}
+	if _, ok := logr.GetTargetFactory(targetType); ok || v.factories.TargetFactory != nil {
+		return
+	}
+	v.addf(n.start, path, "unknown target type %q%s", targetType, suggest(targetType, mapKeys(builtinTargetOptions)))
+}
+
+func (v *validator) checkFormat(n *jsonNode, name string) {
+	path := name + ".format"
+	fn := n.member("format")
+	if fn == nil {
+		v.addf(n.start, name, `missing field "format"`)
+		return
+	}
+	s, ok := v.expectString(fn, path)
+	if !ok {
+		return
+	}
+	format := strings.ToLower(s)
+
+	newOptions, builtin := builtinFormatOptions[format]
+	if !builtin {
+		if _, ok := logr.GetFormatterFactory(format); !ok && v.factories.FormatterFactory == nil {
+			v.addf(fn.start, path, "unknown format %q%s", format, suggest(format, mapKeys(builtinFormatOptions)))
+		}
+		return
+	}
+	if on := n.member("format_options"); on != nil {
+		v.checkOptions(on, name+".format_options", newOptions())
+	}
+}
+
+func (v *validator) checkLevels(n *jsonNode, name string) {
+	ln := n.member("levels")
+	if ln == nil || ln.kind != kindArray {
+		return
+	}
+
+	stdNames := stdLevelNames()
+
+	for i, item := range ln.items {
+		path := fmt.Sprintf("%s.levels[%d]", name, i)
+		v.checkKeys(item, path, reflect.TypeOf(logr.Level{}))
+
+		var lvl logr.Level
+		_ = json.Unmarshal(v.data[item.start:item.end], &lvl) // type errors already reported
+		if lvl.Name == "" {
+			v.addf(item.start, path, `missing field "name"`)
+			continue
+		}
+		nameStart := item.member("name").start
+		idStart := item.start
+		if idNode := item.member("id"); idNode != nil {
+			idStart = idNode.start
+		}
+
+		custom := true
+		for _, std := range stdLevels {
+			sameName := strings.EqualFold(lvl.Name, std.Name)
+			switch {
+			case sameName && lvl.ID != std.ID:
+				v.addf(idStart, path+".id", "level %q must have id %d, not %d", lvl.Name, std.ID, lvl.ID)
+			case !sameName && lvl.ID == std.ID:
+				if s := suggest(lvl.Name, stdNames); s != "" {
+					v.addf(nameStart, path+".name", "unknown level name %q for id %d%s", lvl.Name, lvl.ID, s)
+				} else {
+					v.addf(nameStart, path+".name", "level id %d is reserved for %q; custom levels must use another id", lvl.ID, std.Name)
+				}
+			}
+			if sameName || lvl.ID == std.ID {
+				custom = false
+			}
+		}
+
+		// custom level names one edit away from a standard level name are likely typos.
+		if custom {
+			for _, stdName := range stdNames {
+				if editDistance(strings.ToLower(lvl.Name), stdName) == 1 {
+					v.addf(nameStart, path+".name", "unknown level name %q, did you mean %q?", lvl.Name, stdName)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) checkPostProcessor(n *jsonNode, path string) {
+	if !v.expectObject(n, path) {
+		return
+	}
+	v.checkKeys(n, path, reflect.TypeOf(PostProcessorCfg{}))
+
+	tn := n.member("type")
+	if tn == nil {
+		v.addf(n.start, path, `missing field "type"`)
+		return
+	}
+	s, ok := v.expectString(tn, path+".type")
+	if !ok {
+		return
+	}
+	newOptions, ok := builtinPostProcessors[strings.ToLower(s)]
+	if !ok {
+		v.addf(tn.start, path+".type", "unknown post-processor type %q%s", s, suggest(strings.ToLower(s), mapKeys(builtinPostProcessors)))
+		return
+	}
+
+	options := newOptions()
+	if on := n.member("options"); on != nil {
+		if !v.checkOptions(on, path+".options", options) {
+			return
+		}
+	}
+	if cv, ok := options.(interface{ CheckValid() error }); ok && n.member("options") == nil {
+		if err := cv.CheckValid(); err != nil {
+			v.addf(n.start, path, "invalid options: %s", err.Error())
+		}
+	}
+}
+
+// checkOptions checks the keys and types of an options object, then calls its
+// `CheckValid` method if any. Returns false if any problems were found.
+func (v *validator) checkOptions(n *jsonNode, path string, options interface{}) bool {
+	if !v.expectObject(n, path) {
+		return false
+	}
+	count := len(v.errs)
+	v.checkKeys(n, path, reflect.TypeOf(options).Elem())
+	if !v.checkDecode(n, path, options) {
+		return false
+	}
+	if cv, ok := options.(interface{ CheckValid() error }); ok {
+		if err := cv.CheckValid(); err != nil {
+			v.addf(n.start, path, "invalid options: %s", err.Error())
+		}
+	}
+	return len(v.errs) == count
+}
+
+// checkKeys reports any keys of the object not matching a JSON field of the struct type.
+func (v *validator) checkKeys(n *jsonNode, path string, t reflect.Type) {
+	known := jsonFieldNames(t)
+	for _, m := range n.members {
+		if !containsString(known, m.key) {
+			v.addf(m.keyStart, path, "unknown field %q%s", m.key, suggest(m.key, known))
+		}
+	}
+}
+
+// checkDecode decodes the value into dst, reporting any type errors.
+func (v *validator) checkDecode(n *jsonNode, path string, dst interface{}) bool {
+	err := json.Unmarshal(v.data[n.start:n.end], dst)
+	if err == nil {
+		return true
+	}
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		p := path
+		if te.Field != "" {
+			p = path + "." + te.Field
+		}
+		v.addf(n.start+valueStart(v.data[n.start:n.end], te.Offset), p, "cannot use %s as %s", te.Value, te.Type.String())
+		return false
+	}
+	v.addf(n.start, path, "%s", err.Error())
+	return false
+}
+
+// valueStart returns the start of the value ending at offset.
+func valueStart(data []byte, offset int64) int64 {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	i := bytes.LastIndexAny(data[:offset], ":[,")
+	if i < 0 {
+		return 0
+	}
+	i++
+	for i < len(data) && strings.IndexByte(" \t\r\n", data[i]) >= 0 {
+		i++
+	}
+	return int64(i)
+}
+
+// jsonFieldNames returns the JSON names of the fields of a struct type.
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFieldNames(f.Type)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// suggest returns a " did you mean" suffix for the candidate closest to s, or
+// an empty string if no candidate is close.
+func suggest(s string, candidates []string) string {
+	best := ""
+	bestDist := len(s)/3 + 1
+	if bestDist > 3 {
+		bestDist = 3
+	}
+	for _, c := range candidates {
+		if strings.EqualFold(s, c) {
+			return fmt.Sprintf(", did you mean %q?", c)
+		}
+		if d := editDistance(strings.ToLower(s), strings.ToLower(c)); d <= bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// stdLevelNames returns the names of the standard levels.
+func stdLevelNames() []string {
+	names := make([]string, 0, len(stdLevels))
+	for _, lvl := range stdLevels {
+		names = append(names, lvl.Name)
+	}
+	return names
+}
+
+func mapKeys(m map[string]func() interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type jsonKind int
+
+const (
+	kindScalar jsonKind = iota
+	kindObject
+	kindArray
+)
+
+// jsonNode is a parsed JSON value which retains its location in the input.
+type jsonNode struct {
+	kind    jsonKind
+	start   int64
+	end     int64
+	value   interface{} // scalars only
+	members []jsonMember
+	items   []*jsonNode
+}
+
+type jsonMember struct {
+	key      string
+	keyStart int64
+	node     *jsonNode
+}
+
+// member returns the value of the object member with the key, or nil.
+func (n *jsonNode) member(key string) *jsonNode {
+	for _, m := range n.members {
+		if m.key == key {
+			return m.node
+		}
+	}
+	return nil
+}
+
+type jsonParser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// parseError is a JSON syntax error and its offset.
+type parseError struct {
+	offset int64
+	msg    string
+}
+
+// parseJSON parses data into a tree of nodes.
+func parseJSON(data []byte) (*jsonNode, *parseError) {
+	p := &jsonParser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+
+	root, err := p.parseValue()
+	if err == nil {
+		if _, err = p.dec.Token(); err == io.EOF {
+			return root, nil
+		}
+		if err == nil {
+			return nil, &parseError{offset: p.next(), msg: "unexpected data after configuration"}
+		}
+	}
+
+	var se *json.SyntaxError
+	switch {
+	case errors.As(err, &se) && se.Offset < int64(len(data)):
+		return nil, &parseError{offset: se.Offset, msg: se.Error()}
+	case se != nil, errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return nil, &parseError{offset: int64(len(data)), msg: "unexpected end of configuration"}
+	default:
+		return nil, &parseError{offset: p.next(), msg: err.Error()}
+	}
+}
+
+// next returns the offset of the start of the next token.
+func (p *jsonParser) next() int64 {
+	off := p.dec.InputOffset()
+	for off < int64(len(p.data)) && strings.IndexByte(" \t\r\n:,", p.data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+func (p *jsonParser) parseValue() (*jsonNode, error) {
+	n := &jsonNode{start: p.next()}
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		n.kind = kindObject
+		for p.dec.More() {
+			keyStart := p.next()
+			key, err := p.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			child, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			n.members = append(n.members, jsonMember{key: key.(string), keyStart: keyStart, node: child})
+		}
+	case json.Delim('['):
+		n.kind = kindArray
+		for p.dec.More() {
+			child, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, child)
+		}
+	default:
+		n.value = tok
+	}
+
+	if n.kind != kindScalar {
+		if _, err := p.dec.Token(); err != nil { // closing delimiter
+			return nil, err
+		}
+	}
+	n.end = p.dec.InputOffset()
+	return n, nil
+}
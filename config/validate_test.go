@@ -0,0 +1,90 @@
+package config
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSample(t *testing.T) {
+	b, err := ioutil.ReadFile("sample-config.json")
+	require.NoError(t, err)
+	assert.NoError(t, Validate(b, nil))
+}
+
+func TestValidate(t *testing.T) {
+	cfg := `{
+    "console": {
+        "type": "consol",
+        "format": "plain",
+        "levels": [
+            {"id": 4, "name": "inf"},
+            {"id": 3, "name": "warn"},
+            {"id": 2, "name": "error", "stacktrace": true}
+        ],
+        "block_level": "eror"
+    },
+    "file": {
+        "type": "file",
+        "options": {
+            "filename": "test.log",
+            "max_sizee": 1000
+        },
+        "format": "jsn",
+        "levles": [],
+        "maxqueuesize": "big"
+    },
+    "processed": {
+        "type": "none",
+        "format": "json",
+        "post_processors": [{"type": "gzipp"}]
+    }
+}`
+
+	err := Validate([]byte(cfg), nil)
+	require.Error(t, err)
+	errs, ok := err.(ValidationErrors)
+	require.True(t, ok)
+
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Error())
+	}
+	assert.Equal(t, []string{
+		`3:17: console.type: unknown target type "consol", did you mean "console"?`,
+		`6:31: console.levels[0].name: unknown level name "inf" for id 4, did you mean "info"?`,
+		`10:24: console.block_level: unknown level name "eror", did you mean "error"?`,
+		`19:9: file: unknown field "levles", did you mean "levels"?`,
+		`20:25: file.maxqueuesize: cannot use string as int`,
+		`16:13: file.options: unknown field "max_sizee", did you mean "max_size"?`,
+		`18:19: file.format: unknown format "jsn", did you mean "json"?`,
+	}, got)
+}
+
+func TestValidateSyntax(t *testing.T) {
+	err := Validate([]byte("{\n  \"console\": {\n    \"type\": \"console\",\n  }\n}"), nil)
+	require.Error(t, err)
+	errs := err.(ValidationErrors)
+	require.Len(t, errs, 1)
+	// the trailing comma is reported.
+	assert.Equal(t, 3, errs[0].Line)
+	assert.Equal(t, 23, errs[0].Column)
+
+	err = Validate([]byte(`{"console": {"type": "console"`), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected end of configuration")
+}
+
+func TestValidateFactories(t *testing.T) {
+	cfg := `{"custom": {"type": "my_custom_target", "format": "my_format", "levels": []}}`
+	assert.Error(t, Validate([]byte(cfg), nil))
+	assert.NoError(t, Validate([]byte(cfg), &Factories{TargetFactory: makeCustomTargetFactory(ioutil.Discard), FormatterFactory: customFormatFactory}))
+}
+
+func TestSuggest(t *testing.T) {
+	assert.Equal(t, `, did you mean "console"?`, suggest("consle", []string{"console", "file"}))
+	assert.Equal(t, `, did you mean "file"?`, suggest("FILE", []string{"console", "file"}))
+	assert.Equal(t, "", suggest("kafka", []string{"console", "file"}))
+}
@@ -31,4 +31,8 @@ const (
 	// DefaultMaxPooledBuffer is the maximum size a pooled buffer can be.
 	// Buffers that grow beyond this size are garbage collected.
 	DefaultMaxPooledBuffer = 1024 * 1024
+
+	// DefaultCoalesceKey is the default key of the field containing the number of
+	// merged records when the `Coalesce` option is used.
+	DefaultCoalesceKey = "count"
 )
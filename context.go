@@ -0,0 +1,133 @@
+package logr
+
+import "context"
+
+// ContextExtractor returns zero or more fields derived from a context, such as
+// request IDs or trace IDs. See `ContextExtractors` option.
+type ContextExtractor func(ctx context.Context) []Field
+
+// ContextHook is called synchronously by the `XXXCtx` style log APIs for each log
+// record emitted, with the record's level, message and fields, including fields added
+// via `Logger.With` and any extracted from the context. Hooks can be used to mirror
+// log records to request scoped facilities that may no longer exist by the time the
+// record reaches the targets, such as the active trace span. Like all log records, hooks
+// are only called for levels enabled by at least one target. Hooks must not modify or
+// retain the fields slice, and should return quickly. See `ContextHooks` option.
+type ContextHook func(ctx context.Context, lvl Level, msg string, fields []Field)
+
+// TraceSampler reports whether the trace carried by a context is sampled. The ok
+// result is false if the context carries no trace. See `SkipUnsampledBelow` option.
+//
+// For example, using OpenTelemetry:
+//
+//	func(ctx context.Context) (bool, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return sc.IsSampled(), sc.IsValid()
+//	}
+type TraceSampler func(ctx context.Context) (sampled bool, ok bool)
+
+// contextFields calls all context extractors and returns the combined fields.
+func (lgr *Logr) contextFields(ctx context.Context) []Field {
+	var fields []Field
+	for _, extract := range lgr.options.contextExtractors {
+		fields = append(fields, extract(ctx)...)
+	}
+	return fields
+}
+
+// isCanceledSkip returns true if a log record for the level should be skipped
+// because the context is canceled. See `SkipCanceledBelow` option.
+func (lgr *Logr) isCanceledSkip(ctx context.Context, lvl Level) bool {
+	if !lgr.options.skipCanceled || ctx.Err() == nil {
+		return false
+	}
+	return lvl.ID > lgr.options.skipCanceledLevel.ID
+}
+
+// isUnsampledSkip returns true if a log record for the level should be skipped
+// because the context carries a trace that is not sampled. See `SkipUnsampledBelow` option.
+func (lgr *Logr) isUnsampledSkip(ctx context.Context, lvl Level) bool {
+	if lgr.options.traceSampler == nil || lvl.ID <= lgr.options.skipUnsampledLevel.ID {
+		return false
+	}
+	sampled, ok := lgr.options.traceSampler(ctx)
+	return ok && !sampled
+}
+
+// LogCtx is like `Log` but also adds any fields extracted from the context via
+// the registered `ContextExtractor`s. If the context is already canceled and the
+// `SkipCanceledBelow` option applies to the level then the record is skipped. Likewise
+// if the context carries an unsampled trace and the `SkipUnsampledBelow` option applies.
+// If the context carries a `CanonicalLine` the fields are also merged into it.
+func (logger Logger) LogCtx(ctx context.Context, lvl Level, msg string, fields ...Field) {
+	if logger.isMuted(lvl) {
+		return
+	}
+	status := logger.lgr.IsLevelEnabled(lvl)
+	if !status.Enabled {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if logger.lgr.isCanceledSkip(ctx, lvl) || logger.lgr.isUnsampledSkip(ctx, lvl) || logger.lgr.isSampledOut(lvl, msg) {
+		return
+	}
+
+	if ctxFields := logger.lgr.contextFields(ctx); len(ctxFields) > 0 {
+		fields = append(ctxFields, fields...)
+	}
+
+	fields = logger.lgr.enrich(lvl, fields)
+
+	if hooks := logger.lgr.options.contextHooks; len(hooks) > 0 {
+		all := make([]Field, 0, logger.fields.len()+len(fields))
+		all = logger.fields.appendTo(all)
+		all = append(all, fields...)
+		for _, hook := range hooks {
+			hook(ctx, lvl, msg, all)
+		}
+	}
+
+	if cl := CanonicalLineFromContext(ctx); cl != nil && cl.merge(lvl, fields) {
+		return
+	}
+
+	rec := NewLogRec(lvl, logger, msg, fields, status.Stacktrace)
+	logger.lgr.enqueue(rec)
+}
+
+// TraceCtx is a convenience method equivalent to `LogCtx(ctx, TraceLevel, msg, fields...)`.
+func (logger Logger) TraceCtx(ctx context.Context, msg string, fields ...Field) {
+	logger.LogCtx(ctx, Trace, msg, fields...)
+}
+
+// DebugCtx is a convenience method equivalent to `LogCtx(ctx, DebugLevel, msg, fields...)`.
+func (logger Logger) DebugCtx(ctx context.Context, msg string, fields ...Field) {
+	logger.LogCtx(ctx, Debug, msg, fields...)
+}
+
+// InfoCtx is a convenience method equivalent to `LogCtx(ctx, InfoLevel, msg, fields...)`.
+func (logger Logger) InfoCtx(ctx context.Context, msg string, fields ...Field) {
+	logger.LogCtx(ctx, Info, msg, fields...)
+}
+
+// WarnCtx is a convenience method equivalent to `LogCtx(ctx, WarnLevel, msg, fields...)`.
+func (logger Logger) WarnCtx(ctx context.Context, msg string, fields ...Field) {
+	logger.LogCtx(ctx, Warn, msg, fields...)
+}
+
+// ErrorCtx is a convenience method equivalent to `LogCtx(ctx, ErrorLevel, msg, fields...)`.
+func (logger Logger) ErrorCtx(ctx context.Context, msg string, fields ...Field) {
+	logger.LogCtx(ctx, Error, msg, fields...)
+}
+
+// FatalCtx is a convenience method equivalent to `LogCtx(ctx, FatalLevel, msg, fields...)`.
+func (logger Logger) FatalCtx(ctx context.Context, msg string, fields ...Field) {
+	logger.LogCtx(ctx, Fatal, msg, fields...)
+}
+
+// PanicCtx is a convenience method equivalent to `LogCtx(ctx, PanicLevel, msg, fields...)`.
+func (logger Logger) PanicCtx(ctx context.Context, msg string, fields ...Field) {
+	logger.LogCtx(ctx, Panic, msg, fields...)
+}
@@ -0,0 +1,126 @@
+package logr_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+func TestLogCtx(t *testing.T) {
+	extractor := func(ctx context.Context) []logr.Field {
+		if id, ok := ctx.Value(ctxKey{}).(string); ok {
+			return []logr.Field{logr.String("request_id", id)}
+		}
+		return nil
+	}
+
+	lgr, err := logr.New(logr.ContextExtractors(extractor), logr.SkipCanceledBelow(logr.Warn))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "ctxTest", &logr.StdFilter{Lvl: logr.Debug}, formatter, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "wiggin"))
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "abc123"))
+	logger.InfoCtx(ctx, "before cancel", logr.Int("n", 1))
+	cancel()
+	logger.InfoCtx(ctx, "info after cancel")
+	logger.WarnCtx(ctx, "warn after cancel")
+	logger.DebugCtx(context.Background(), "no request")
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "before cancel user=wiggin request_id=abc123 n=1")
+	assert.NotContains(t, output, "info after cancel")
+	assert.Contains(t, output, "warn after cancel user=wiggin request_id=abc123")
+	assert.Contains(t, output, "no request user=wiggin")
+}
+
+type traceKey struct{}
+
+func TestSkipUnsampled(t *testing.T) {
+	sampler := func(ctx context.Context) (bool, bool) {
+		sampled, ok := ctx.Value(traceKey{}).(bool)
+		return sampled, ok
+	}
+
+	lgr, err := logr.New(logr.SkipUnsampledBelow(logr.Info, sampler))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "sampleTest", &logr.StdFilter{Lvl: logr.Trace}, formatter, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+
+	sampled := context.WithValue(context.Background(), traceKey{}, true)
+	unsampled := context.WithValue(context.Background(), traceKey{}, false)
+
+	logger.DebugCtx(sampled, "debug sampled")
+	logger.TraceCtx(sampled, "trace sampled")
+	logger.DebugCtx(unsampled, "debug unsampled")
+	logger.TraceCtx(unsampled, "trace unsampled")
+	logger.InfoCtx(unsampled, "info unsampled")
+	logger.DebugCtx(context.Background(), "debug no trace")
+	logger.Debug("debug no context")
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "debug sampled")
+	assert.Contains(t, output, "trace sampled")
+	assert.NotContains(t, output, "debug unsampled")
+	assert.NotContains(t, output, "trace unsampled")
+	assert.Contains(t, output, "info unsampled")
+	assert.Contains(t, output, "debug no trace")
+	assert.Contains(t, output, "debug no context")
+
+	_, err = logr.New(logr.SkipUnsampledBelow(logr.Info, nil))
+	assert.Error(t, err)
+}
+
+func TestContextHooks(t *testing.T) {
+	var got []string
+	hook := func(ctx context.Context, lvl logr.Level, msg string, fields []logr.Field) {
+		id, _ := ctx.Value(ctxKey{}).(string)
+		var keys []string
+		for _, f := range fields {
+			keys = append(keys, f.Key)
+		}
+		got = append(got, lvl.Name+" "+msg+" "+id+" "+strings.Join(keys, ","))
+	}
+
+	lgr, err := logr.New(logr.ContextHooks(hook))
+	require.NoError(t, err)
+	err = lgr.AddTarget(targets.NewWriterTarget(&test.Buffer{}), "hookTest", &logr.StdFilter{Lvl: logr.Info}, nil, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "wiggin"))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "abc123")
+	logger.WarnCtx(ctx, "first", logr.Int("n", 1))
+	logger.DebugCtx(ctx, "disabled")
+	logger.Warn("no context")
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"warn first abc123 user,n"}, got)
+
+	_, err = logr.New(logr.ContextHooks(nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+// Package correlation provides generation and propagation of correlation IDs so that
+// log records emitted by multiple services for the same request can be lined up in
+// log search.
+//
+// Register `Extractor` with the Logr instance, then use the `XXXCtx` style log APIs:
+//
+//	lgr, _ := logr.New(logr.ContextExtractors(correlation.Extractor))
+//	...
+//	http.Handle("/", correlation.Middleware(handler))
+//	...
+//	logger.InfoCtx(r.Context(), "handling request")
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	// Header is the HTTP header used to propagate correlation IDs.
+	Header = "X-Correlation-ID"
+
+	// FieldKey is the key of the field added to log records.
+	FieldKey = "correlation_id"
+
+	// maxIDLen is the maximum length of an ID accepted from an incoming request.
+	maxIDLen = 128
+)
+
+type ctxKey struct{}
+
+// NewID generates a new random correlation ID.
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithID returns a copy of ctx carrying the correlation ID.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the correlation ID carried by ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Ensure returns ctx and its correlation ID if one exists, otherwise a new ID is
+// generated and a copy of ctx carrying the new ID is returned.
+func Ensure(ctx context.Context) (context.Context, string) {
+	if id, ok := FromContext(ctx); ok {
+		return ctx, id
+	}
+	id := NewID()
+	return WithID(ctx, id), id
+}
+
+// Extractor is a `logr.ContextExtractor` that adds the correlation ID carried by
+// the context, if any, to log records.
+func Extractor(ctx context.Context) []logr.Field {
+	if id, ok := FromContext(ctx); ok {
+		return []logr.Field{logr.String(FieldKey, id)}
+	}
+	return nil
+}
+
+// FromRequest returns the correlation ID from the request headers, if any.
+func FromRequest(r *http.Request) (string, bool) {
+	id := r.Header.Get(Header)
+	if id == "" || len(id) > maxIDLen {
+		return "", false
+	}
+	return id, true
+}
+
+// SetHeader writes the correlation ID to the headers.
+func SetHeader(h http.Header, id string) {
+	h.Set(Header, id)
+}
+
+// Inject copies the correlation ID carried by ctx, if any, to the headers of an
+// outgoing request so the ID propagates to downstream services.
+func Inject(ctx context.Context, r *http.Request) {
+	if id, ok := FromContext(ctx); ok {
+		SetHeader(r.Header, id)
+	}
+}
+
+// Middleware reads the correlation ID from an incoming request, or generates one
+// if none exists, adds it to the request context, and echoes it in the response headers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := FromRequest(r)
+		if !ok {
+			id = NewID()
+		}
+		SetHeader(w.Header(), id)
+		next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,70 @@
+package correlation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsure(t *testing.T) {
+	ctx, id := Ensure(context.Background())
+	assert.Len(t, id, 32)
+
+	ctx2, id2 := Ensure(ctx)
+	assert.Equal(t, id, id2)
+	assert.Equal(t, ctx, ctx2)
+
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestMiddleware(t *testing.T) {
+	lgr, err := logr.New(logr.ContextExtractors(Extractor))
+	require.NoError(t, err)
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "corrTest", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+	logger := lgr.NewLogger()
+
+	var outgoing *http.Request
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoCtx(r.Context(), "handled")
+		outgoing, _ = http.NewRequest(http.MethodGet, "http://downstream", nil)
+		Inject(r.Context(), outgoing)
+	}))
+
+	t.Run("propagates incoming id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, "incoming-id")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "incoming-id", rec.Header().Get(Header))
+		assert.Equal(t, "incoming-id", outgoing.Header.Get(Header))
+	})
+
+	t.Run("generates missing id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		id := rec.Header().Get(Header)
+		assert.Len(t, id, 32)
+		assert.Equal(t, id, outgoing.Header.Get(Header))
+	})
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "handled correlation_id=incoming-id")
+	assert.Contains(t, buf.String(), "handled correlation_id="+outgoing.Header.Get(Header))
+}
@@ -0,0 +1,207 @@
+package logr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCrashFlushTimeout is the default amount of time the normal pipeline is given
+// to deliver queued log records during a crash, before they are written to the
+// crash target instead.
+const DefaultCrashFlushTimeout = time.Second * 2
+
+// crashTarget is the synchronous target designated via `CrashTarget`.
+type crashTarget struct {
+	mux       sync.Mutex
+	target    Target
+	formatter Formatter
+	timeout   time.Duration
+}
+
+// CrashTarget designates a target, typically a local file or stderr, to which Error
+// and more severe log records are written synchronously by `FlushOnPanic` and
+// `Logr.CrashFlush` when the normal pipeline cannot deliver them before the process
+// dies. The normal pipeline is given up to timeout to flush first; zero means
+// `DefaultCrashFlushTimeout`. The target should not also be added via `AddTarget`.
+func CrashTarget(target Target, formatter Formatter, timeout time.Duration) Option {
+	return func(l *Logr) error {
+		if target == nil {
+			return errors.New("crash target cannot be nil")
+		}
+		if timeout < 0 {
+			return errors.New("crash flush timeout cannot be less than zero")
+		}
+		if timeout == 0 {
+			timeout = DefaultCrashFlushTimeout
+		}
+		if formatter == nil {
+			formatter = &DefaultFormatter{}
+		}
+		if err := target.Init(); err != nil {
+			return fmt.Errorf("cannot initialize crash target: %w", err)
+		}
+		l.options.crashTarget = &crashTarget{target: target, formatter: formatter, timeout: timeout}
+		return nil
+	}
+}
+
+// FlushOnPanic recovers from a panic, logs it at Panic level like `LogPanic`, makes a
+// best-effort synchronous flush via `Logr.CrashFlush`, and then re-panics with the
+// same value. It must be deferred directly, typically first thing in main and in
+// each goroutine that may panic:
+//
+//	defer logr.FlushOnPanic(lgr)
+func FlushOnPanic(lgr *Logr) {
+	if r := recover(); r != nil {
+		logRecovered(lgr.NewLogger(), r, 4)
+		lgr.CrashFlush()
+		panic(r)
+	}
+}
+
+// CrashFlush makes a best-effort attempt to output queued log records before the
+// process dies. The Logr is flushed with the timeout given to `CrashTarget`, or
+// `DefaultCrashFlushTimeout`. If the flush does not complete, for example because a
+// target is stalled, the log records remaining in the Logr queue and target queues
+// are drained: Error and more severe records are written synchronously to the crash
+// target, if any, and the rest are discarded. Records already being written by a
+// stalled target are lost.
+//
+// CrashFlush is intended for code paths that end the process, such as `OnExit`
+// handlers; the Logr should not be used for normal logging afterwards.
+func (lgr *Logr) CrashFlush() {
+	crash := lgr.options.crashTarget
+
+	timeout := DefaultCrashFlushTimeout
+	if crash != nil {
+		timeout = crash.timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := lgr.FlushWithTimeout(ctx); err == nil || crash == nil {
+		return
+	}
+
+	recs := lgr.drainQueues()
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].Time().Before(recs[j].Time())
+	})
+
+	crash.mux.Lock()
+	defer crash.mux.Unlock()
+	for _, rec := range recs {
+		if rec.Level().ID > Error.ID || lgr.isIsolated(rec.Level()) {
+			continue
+		}
+		if err := lgr.writeCrashRec(crash, rec); err != nil {
+			fmt.Fprintln(os.Stderr, "crash target write failed --", err)
+		}
+	}
+}
+
+// drainQueues removes the log records from all target queues and the Logr queue
+// without blocking, returning each prepped record once. Flush records are
+// acknowledged so the read loops waiting on them can continue.
+func (lgr *Logr) drainQueues() []*LogRec {
+	var recs []*LogRec
+	seen := make(map[*LogRec]struct{})
+
+	drain := func(in chan *LogRec, prep bool) {
+		for {
+			select {
+			case rec := <-in:
+				if rec.flush != nil {
+					go func() { rec.flush <- struct{}{} }()
+					continue
+				}
+				if _, ok := seen[rec]; ok {
+					continue
+				}
+				seen[rec] = struct{}{}
+				if prep {
+					rec.prep()
+				}
+				recs = append(recs, rec)
+			default:
+				return
+			}
+		}
+	}
+
+	lgr.tmux.RLock()
+	for _, host := range lgr.targetHosts {
+		drain(host.queue(), false)
+	}
+	lgr.tmux.RUnlock()
+
+	drain(lgr.in, true)
+	return recs
+}
+
+func (lgr *Logr) writeCrashRec(crash *crashTarget, rec *LogRec) error {
+	buf := lgr.BorrowBuffer()
+	defer lgr.ReleaseBuffer(buf)
+
+	buf, err := crash.formatter.Format(rec, rec.Level(), buf)
+	if err != nil {
+		return err
+	}
+	_, err = crash.target.Write(buf.Bytes(), rec)
+	return err
+}
+
+// shutdownCrashTarget shuts down the crash target, if any.
+func (lgr *Logr) shutdownCrashTarget() error {
+	crash := lgr.options.crashTarget
+	if crash == nil {
+		return nil
+	}
+	crash.mux.Lock()
+	defer crash.mux.Unlock()
+	return crash.target.Shutdown()
+}
+
+// HandleCrashSignals installs a handler that calls `CrashFlush` when a fatal signal
+// is received (SIGABRT or SIGQUIT), then re-raises the signal with default handling.
+// Unrecovered panics cannot be intercepted this way; use `FlushOnPanic` for those.
+// On platforms without these signals no handler is installed. Call the returned
+// function to remove the handler.
+func (lgr *Logr) HandleCrashSignals() (stop func()) {
+	if len(crashSignals) == 0 {
+		return func() {}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, crashSignals...)
+
+	go func() {
+		select {
+		case <-done:
+		case sig := <-ch:
+			lgr.CrashFlush()
+
+			// re-raise the signal with default handling.
+			signal.Reset(sig)
+			if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+				return
+			}
+			os.Exit(2)
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package logr_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stalledTarget blocks all writes until released.
+type stalledTarget struct {
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (st *stalledTarget) Init() error     { return nil }
+func (st *stalledTarget) Shutdown() error { return nil }
+
+func (st *stalledTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	select {
+	case st.writing <- struct{}{}:
+	default:
+	}
+	<-st.release
+	return len(p), nil
+}
+
+func TestFlushOnPanic(t *testing.T) {
+	crashBuf := &test.Buffer{}
+	lgr, err := logr.New(logr.CrashTarget(targets.NewWriterTarget(crashBuf),
+		&formatters.Plain{DisableTimestamp: true}, time.Millisecond*100))
+	require.NoError(t, err)
+
+	target := &stalledTarget{writing: make(chan struct{}, 1), release: make(chan struct{})}
+	err = lgr.AddTarget(target, "stalled", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("lost while stalled")
+	<-target.writing
+
+	logger.Error("first error", logr.Int("n", 1))
+	logger.Info("not an error")
+	logger.Error("second error", logr.Int("n", 2))
+
+	assert.PanicsWithValue(t, "boom!", func() {
+		defer logr.FlushOnPanic(lgr)
+		panic("boom!")
+	})
+
+	lines := strings.Split(strings.TrimSpace(crashBuf.String()), "\n")
+	require.Len(t, lines, 3, crashBuf.String())
+	assert.Contains(t, lines[0], "first error")
+	assert.Contains(t, lines[1], "second error")
+	assert.Contains(t, lines[2], "recovered from panic")
+	assert.Contains(t, lines[2], `panic="boom!"`)
+	assert.NotContains(t, crashBuf.String(), "not an error")
+
+	close(target.release)
+}
+
+func TestCrashFlushHealthy(t *testing.T) {
+	crashBuf := &test.Buffer{}
+	lgr, err := logr.New(logr.CrashTarget(targets.NewWriterTarget(crashBuf), nil, 0))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "healthy", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Error("delivered normally")
+	lgr.CrashFlush()
+
+	assert.Contains(t, buf.String(), "delivered normally")
+	assert.Empty(t, crashBuf.String())
+	require.NoError(t, lgr.Shutdown())
+
+	_, err = logr.New(logr.CrashTarget(nil, nil, 0))
+	assert.Error(t, err)
+}
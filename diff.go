@@ -0,0 +1,178 @@
+package logr
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diffMaxDepth limits how deep `Diff` descends, which also guards against cycles.
+const diffMaxDepth = 32
+
+// Change is the old and new value of a path reported by `Diff`. A nil value means
+// the path did not exist, e.g. a map key that was added or removed.
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// LogWrite outputs the change as "old -> new".
+func (c Change) LogWrite(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s -> %s", changeValue(c.Old), changeValue(c.New))
+	return err
+}
+
+func changeValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Diff compares two values, typically structs or maps, and returns a field for each
+// path whose value changed, keyed by the path prefixed with key. For example,
+//
+//	logger.Info("config reloaded", logr.Diff("config", oldCfg, newCfg)...)
+//
+// might output `config.db.host="db1" -> "db2"`. Structs and maps are compared
+// recursively, as are slices of equal length. Struct fields use their json tag name
+// when present, and unexported fields are ignored.
+func Diff(key string, oldVal, newVal interface{}) []Field {
+	return diffFields(key, oldVal, newVal, diffMaxDepth)
+}
+
+// ShallowDiff is like `Diff` but only compares the top level fields or keys of
+// the values, reporting the complete old and new value for each that changed.
+func ShallowDiff(key string, oldVal, newVal interface{}) []Field {
+	return diffFields(key, oldVal, newVal, 1)
+}
+
+func diffFields(key string, oldVal, newVal interface{}, depth int) []Field {
+	d := &differ{maxDepth: depth}
+	d.diff(key, reflect.ValueOf(oldVal), reflect.ValueOf(newVal), 0)
+	return d.fields
+}
+
+type differ struct {
+	maxDepth int
+	fields   []Field
+}
+
+func (d *differ) diff(path string, a, b reflect.Value, depth int) {
+	a, b = diffIndirect(a), diffIndirect(b)
+
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() || depth >= d.maxDepth {
+		d.compareLeaf(path, a, b)
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		if a.Type() == reflect.TypeOf(time.Time{}) || !hasExportedField(a.Type()) {
+			d.compareLeaf(path, a, b)
+			return
+		}
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, ok := diffFieldName(sf)
+			if !ok {
+				continue
+			}
+			d.diff(path+"."+name, a.Field(i), b.Field(i), depth+1)
+		}
+
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, k := range a.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			k := keys[name]
+			d.diff(path+"."+name, a.MapIndex(k), b.MapIndex(k), depth+1)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			d.compareLeaf(path, a, b)
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			d.diff(path+"["+strconv.Itoa(i)+"]", a.Index(i), b.Index(i), depth+1)
+		}
+
+	default:
+		d.compareLeaf(path, a, b)
+	}
+}
+
+// compareLeaf adds a field for the path if the values differ.
+func (d *differ) compareLeaf(path string, a, b reflect.Value) {
+	var av, bv interface{}
+	if a.IsValid() {
+		av = a.Interface()
+	}
+	if b.IsValid() {
+		bv = b.Interface()
+	}
+
+	if at, ok := av.(time.Time); ok {
+		if bt, ok := bv.(time.Time); ok && at.Equal(bt) {
+			return
+		}
+	} else if reflect.DeepEqual(av, bv) {
+		return
+	}
+	d.fields = append(d.fields, Field{Key: path, Type: StructType, Interface: Change{Old: av, New: bv}})
+}
+
+// diffIndirect dereferences pointers and interfaces, returning an invalid value for nil.
+func diffIndirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func hasExportedField(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFieldName returns the name used in paths for a struct field, or false if the
+// field is unexported or excluded from JSON.
+func diffFieldName(sf reflect.StructField) (string, bool) {
+	if sf.PkgPath != "" {
+		return "", false
+	}
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return sf.Name, true
+}
@@ -0,0 +1,84 @@
+package logr_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dbConfig struct {
+	Host    string        `json:"host"`
+	Port    int           `json:"port"`
+	Timeout time.Duration `json:"timeout"`
+	secret  string
+}
+
+type appConfig struct {
+	Name     string            `json:"name"`
+	DB       *dbConfig         `json:"db"`
+	Features map[string]bool   `json:"features"`
+	Hosts    []string          `json:"hosts"`
+	Labels   map[string]string `json:"-"`
+	Updated  time.Time
+}
+
+func diffStrings(fields []logr.Field) map[string]string {
+	out := make(map[string]string)
+	for _, f := range fields {
+		var sb strings.Builder
+		_ = f.ValueString(&sb, nil)
+		out[f.Key] = sb.String()
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Now()
+	old := appConfig{
+		Name:     "app",
+		DB:       &dbConfig{Host: "db1", Port: 5432, Timeout: time.Second, secret: "a"},
+		Features: map[string]bool{"beta": true, "legacy": true},
+		Hosts:    []string{"a", "b"},
+		Labels:   map[string]string{"x": "1"},
+		Updated:  now,
+	}
+	updated := old
+	updated.DB = &dbConfig{Host: "db2", Port: 5432, Timeout: time.Second * 2, secret: "b"}
+	updated.Features = map[string]bool{"beta": false, "new": true}
+	updated.Hosts = []string{"a", "c"}
+	updated.Labels = map[string]string{"x": "2"}
+	updated.Updated = now.UTC() // same instant
+
+	fields := logr.Diff("config", old, updated)
+	assert.Equal(t, map[string]string{
+		"config.db.host":         `"db1" -> "db2"`,
+		"config.db.timeout":      "1s -> 2s",
+		"config.features.beta":   "true -> false",
+		"config.features.legacy": "true -> <none>",
+		"config.features.new":    "<none> -> true",
+		"config.hosts[1]":        `"b" -> "c"`,
+	}, diffStrings(fields))
+
+	// paths are output in a stable order.
+	require.Len(t, fields, 6)
+	assert.Equal(t, "config.db.host", fields[0].Key)
+
+	b, err := json.Marshal(fields[0].Interface)
+	require.NoError(t, err)
+	assert.Equal(t, `{"old":"db1","new":"db2"}`, string(b))
+
+	shallow := logr.ShallowDiff("config", old, updated)
+	keys := make([]string, 0, len(shallow))
+	for _, f := range shallow {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal(t, []string{"config.db", "config.features", "config.hosts"}, keys)
+
+	assert.Empty(t, logr.Diff("config", old, old))
+	assert.Equal(t, map[string]string{"config": "<none> -> 3"}, diffStrings(logr.Diff("config", nil, 3)))
+}
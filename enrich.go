@@ -0,0 +1,99 @@
+package logr
+
+import (
+	"bytes"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"strconv"
+)
+
+const (
+	// GoroutineIDKey is the field key used by the `GoroutineID` enricher.
+	GoroutineIDKey = "goroutine_id"
+	// GoroutineCountKey is the field key used by the `GoroutineCount` enricher.
+	GoroutineCountKey = "goroutines"
+	// HeapInUseKey is the field key used by the `HeapInUse` enricher.
+	HeapInUseKey = "heap_inuse"
+)
+
+// Enricher returns zero or more fields to be added to a log record of the
+// specified level. Enrichers are called on the goroutine emitting the log record,
+// before it is queued, so they must be fast and safe for concurrent use.
+// See `Enrichers` option.
+type Enricher func(lvl Level) []Field
+
+type enricherEntry struct {
+	filter   Filter
+	enricher Enricher
+}
+
+// enrich appends the fields from all enrichers applicable to the level.
+func (lgr *Logr) enrich(lvl Level, fields []Field) []Field {
+	if len(lgr.options.enrichers) == 0 {
+		return fields
+	}
+	// copy so the caller's slice is never appended to.
+	fields = append(make([]Field, 0, len(fields)+len(lgr.options.enrichers)), fields...)
+
+	for _, e := range lgr.options.enrichers {
+		if e.filter != nil {
+			if _, enabled := e.filter.GetEnabledLevel(lvl); !enabled {
+				continue
+			}
+		}
+		fields = append(fields, e.enricher(lvl)...)
+	}
+	return fields
+}
+
+// GoroutineID returns an enricher that adds the id of the goroutine emitting the
+// log record. Goroutine ids are not exposed by the Go runtime so the id is parsed
+// from a stack trace header; this costs roughly a microsecond per record.
+func GoroutineID() Enricher {
+	return func(lvl Level) []Field {
+		return []Field{Uint64(GoroutineIDKey, goroutineID())}
+	}
+}
+
+// GoroutineCount returns an enricher that adds the number of goroutines that
+// currently exist.
+func GoroutineCount() Enricher {
+	return func(lvl Level) []Field {
+		return []Field{Int(GoroutineCountKey, runtime.NumGoroutine())}
+	}
+}
+
+// HeapInUse returns an enricher that adds the number of bytes in in-use heap spans.
+// Unlike `runtime.ReadMemStats`, this does not stop the world.
+func HeapInUse() Enricher {
+	return func(lvl Level) []Field {
+		samples := []rtmetrics.Sample{
+			{Name: "/memory/classes/heap/objects:bytes"},
+			{Name: "/memory/classes/heap/unused:bytes"},
+		}
+		rtmetrics.Read(samples)
+
+		var total uint64
+		for _, s := range samples {
+			if s.Value.Kind() == rtmetrics.KindUint64 {
+				total += s.Value.Uint64()
+			}
+		}
+		return []Field{Uint64(HeapInUseKey, total)}
+	}
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID parses the current goroutine id from the header of a stack trace,
+// e.g. "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var arr [64]byte
+	b := arr[:runtime.Stack(arr[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,38 @@
+package logr_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrichers(t *testing.T) {
+	lgr, err := logr.New(
+		logr.Enrichers(&logr.StdFilter{Lvl: logr.Warn}, logr.GoroutineID(), logr.GoroutineCount(), logr.HeapInUse()),
+	)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "enrichTest", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Warn("enriched", logr.String("a", "b"))
+	logger.Info("plain")
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, regexp.MustCompile(`enriched a=b goroutine_id=[1-9][0-9]* goroutines=[1-9][0-9]* heap_inuse=[1-9][0-9]*$`), lines[0])
+	assert.NotContains(t, lines[1], "goroutine")
+}
@@ -0,0 +1,127 @@
+package logr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AsyncEnricher returns fields derived from a log record, for example GeoIP data
+// for a "remote_ip" field or the browser parsed from a "user_agent" field. Unlike
+// `Enricher`, async enrichers run on a pool of worker goroutines after the record
+// is queued, so slow lookups do not add latency to the goroutine emitting the record.
+// The context is canceled once the timeout passed to `AsyncEnrichers` expires.
+type AsyncEnricher func(ctx context.Context, rec *LogRec) []Field
+
+// asyncEnrichStage runs async enrichers on a bounded pool of workers. Records are
+// fanned out to targets in the order they were submitted.
+type asyncEnrichStage struct {
+	lgr       *Logr
+	enrichers []AsyncEnricher
+	timeout   time.Duration
+
+	jobs    chan *enrichJob // records waiting for a worker
+	ordered chan *enrichJob // records waiting to be fanned out, in order
+	pending sync.WaitGroup  // records submitted but not yet fanned out
+	done    chan struct{}   // closed when the fanout loop exits
+}
+
+type enrichJob struct {
+	rec  *LogRec
+	done chan struct{}
+}
+
+func newAsyncEnrichStage(lgr *Logr, workers int, queueSize int, timeout time.Duration, enrichers []AsyncEnricher) *asyncEnrichStage {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	s := &asyncEnrichStage{
+		lgr:       lgr,
+		enrichers: enrichers,
+		timeout:   timeout,
+		jobs:      make(chan *enrichJob, queueSize),
+		ordered:   make(chan *enrichJob, queueSize),
+		done:      make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	go s.fanout()
+	return s
+}
+
+// submit queues a record for enrichment, blocking while the stage is full so that
+// slow enrichers back up the Logr queue rather than using unbounded memory.
+func (s *asyncEnrichStage) submit(rec *LogRec) {
+	s.pending.Add(1)
+	job := &enrichJob{rec: rec, done: make(chan struct{})}
+	s.ordered <- job
+	s.jobs <- job
+}
+
+// wait blocks until all submitted records have been fanned out.
+func (s *asyncEnrichStage) wait() {
+	s.pending.Wait()
+}
+
+// stop shuts down the workers once all submitted records have been fanned out.
+// No records can be submitted after stop is called.
+func (s *asyncEnrichStage) stop(ctx context.Context) error {
+	close(s.jobs)
+	close(s.ordered)
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return newTimeoutError("async enrichment shutdown timeout")
+	}
+}
+
+func (s *asyncEnrichStage) work() {
+	for job := range s.jobs {
+		s.enrich(job.rec)
+		close(job.done)
+	}
+}
+
+func (s *asyncEnrichStage) enrich(rec *LogRec) {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var fields []Field
+	for _, e := range s.enrichers {
+		fields = append(fields, s.call(ctx, e, rec)...)
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	rec.fieldsAll, _ = dedupFields(append(rec.fieldsAll, fields...))
+}
+
+// call runs an enricher, reporting rather than propagating any panic.
+func (s *asyncEnrichStage) call(ctx context.Context, e AsyncEnricher, rec *LogRec) (fields []Field) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.lgr.ReportError(fmt.Errorf("async enricher panic: %v", r))
+			fields = nil
+		}
+	}()
+	return e(ctx, rec)
+}
+
+func (s *asyncEnrichStage) fanout() {
+	defer close(s.done)
+	for job := range s.ordered {
+		<-job.done
+		s.lgr.fanout(job.rec)
+		s.pending.Done()
+	}
+}
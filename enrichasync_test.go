@@ -0,0 +1,68 @@
+package logr_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncEnrichers(t *testing.T) {
+	_, err := logr.New(logr.AsyncEnrichers(0, 0, func(ctx context.Context, rec *logr.LogRec) []logr.Field { return nil }))
+	assert.Error(t, err)
+
+	geoIP := func(ctx context.Context, rec *logr.LogRec) []logr.Field {
+		for _, f := range rec.Fields() {
+			if f.Key == "remote_ip" {
+				time.Sleep(time.Millisecond * 20) // slow lookup
+				return []logr.Field{logr.String("country", "CA-"+f.String)}
+			}
+		}
+		return nil
+	}
+	panics := func(ctx context.Context, rec *logr.LogRec) []logr.Field {
+		if rec.Msg() == "boom" {
+			panic("lookup failed")
+		}
+		return nil
+	}
+
+	var errs []error
+	lgr, err := logr.New(
+		logr.AsyncEnrichers(4, time.Second, geoIP, panics),
+		logr.OnLoggerError(func(err error) { errs = append(errs, err) }),
+	)
+	require.NoError(t, err)
+
+	var buf syncBuffer
+	formatter := &formatters.Plain{DisableTimestamp: true, DisableLevel: true}
+	err = lgr.AddTarget(targets.NewWriterTarget(&buf), "test", &logr.StdFilter{Lvl: logr.Info}, formatter, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	const count = 12
+	start := time.Now()
+	for i := 0; i < count; i++ {
+		logger.Info("request "+strconv.Itoa(i), logr.String("remote_ip", strconv.Itoa(i)))
+	}
+	logger.Info("boom")
+	assert.Less(t, int64(time.Since(start)), int64(time.Millisecond*20*count))
+	require.NoError(t, lgr.Shutdown())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, count+1)
+	for i := 0; i < count; i++ {
+		assert.Contains(t, lines[i], "request "+strconv.Itoa(i))
+		assert.Contains(t, lines[i], "country=CA-"+strconv.Itoa(i))
+	}
+	assert.Contains(t, lines[count], "boom")
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "lookup failed")
+}
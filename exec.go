@@ -0,0 +1,137 @@
+package logr
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// CmdKey is the field key used for the name of the command whose output is logged
+	// via `CaptureCmd`.
+	CmdKey = "cmd"
+	// CmdStreamKey is the field key used for the stream, "stdout" or "stderr", a line
+	// logged via `CaptureCmd` was read from.
+	CmdStreamKey = "stream"
+
+	// DefaultCmdMaxLineSize is the default maximum length of a line logged via `CaptureCmd`.
+	DefaultCmdMaxLineSize = 64 * 1024
+)
+
+// CmdOptions determine how the output of a child process is logged by `CaptureCmd`.
+type CmdOptions struct {
+	// StdoutLevel is the level lines written to stdout are logged at. Defaults to Info.
+	StdoutLevel Level
+
+	// StderrLevel is the level lines written to stderr are logged at. Defaults to Warn.
+	StderrLevel Level
+
+	// Fields are added to each line logged.
+	Fields []Field
+
+	// MaxLineSize is the maximum length of a line; longer lines are split.
+	// Defaults to `DefaultCmdMaxLineSize`.
+	MaxLineSize int
+}
+
+// CaptureCmd sets the stdout and stderr of cmd so that each line written by the child
+// process is logged via logger, with the command name and stream as fields, so that
+// subprocess output flows through the same targets and formatters. It must be called
+// before the command is started, and the returned function called after `cmd.Wait`
+// returns, to log any final line not terminated by a newline:
+//
+//	done := logr.CaptureCmd(logger, cmd, logr.CmdOptions{})
+//	err := cmd.Run()
+//	done()
+//
+// See `RunCmd`.
+func CaptureCmd(logger Logger, cmd *exec.Cmd, opts CmdOptions) (done func()) {
+	if opts.StdoutLevel.Name == "" {
+		opts.StdoutLevel = Info
+	}
+	if opts.StderrLevel.Name == "" {
+		opts.StderrLevel = Warn
+	}
+	if opts.MaxLineSize <= 0 {
+		opts.MaxLineSize = DefaultCmdMaxLineSize
+	}
+
+	name := cmd.Path
+	if len(cmd.Args) > 0 {
+		name = cmd.Args[0]
+	}
+	logger = logger.With(append([]Field{String(CmdKey, filepath.Base(name))}, opts.Fields...)...)
+
+	stdout := &lineLogger{logger: logger.With(String(CmdStreamKey, "stdout")), level: opts.StdoutLevel, max: opts.MaxLineSize}
+	stderr := &lineLogger{logger: logger.With(String(CmdStreamKey, "stderr")), level: opts.StderrLevel, max: opts.MaxLineSize}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return func() {
+		stdout.flush()
+		stderr.flush()
+	}
+}
+
+// RunCmd runs cmd, logging each line of its output as described by `CaptureCmd`,
+// and returns the result of `cmd.Run`.
+func RunCmd(logger Logger, cmd *exec.Cmd, opts CmdOptions) error {
+	done := CaptureCmd(logger, cmd, opts)
+	err := cmd.Run()
+	done()
+	return err
+}
+
+// lineLogger is an io.Writer that logs each complete line written to it.
+type lineLogger struct {
+	logger Logger
+	level  Level
+	max    int
+
+	mux sync.Mutex
+	buf []byte
+}
+
+// Write implements io.Writer
+func (ll *lineLogger) Write(p []byte) (int, error) {
+	ll.mux.Lock()
+	defer ll.mux.Unlock()
+
+	ll.buf = append(ll.buf, p...)
+	for {
+		i := bytes.IndexByte(ll.buf, '\n')
+		if i < 0 {
+			break
+		}
+		ll.logLine(ll.buf[:i])
+		ll.buf = ll.buf[i+1:]
+	}
+	for len(ll.buf) >= ll.max {
+		ll.logLine(ll.buf[:ll.max])
+		ll.buf = ll.buf[ll.max:]
+	}
+	if len(ll.buf) == 0 {
+		ll.buf = nil
+	}
+	return len(p), nil
+}
+
+// flush logs any remaining partial line.
+func (ll *lineLogger) flush() {
+	ll.mux.Lock()
+	defer ll.mux.Unlock()
+	if len(ll.buf) > 0 {
+		ll.logLine(ll.buf)
+		ll.buf = nil
+	}
+}
+
+func (ll *lineLogger) logLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	for len(line) > ll.max {
+		ll.logger.Log(ll.level, string(line[:ll.max]))
+		line = line[ll.max:]
+	}
+	ll.logger.Log(ll.level, string(line))
+}
@@ -0,0 +1,61 @@
+package logr_test
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCmd(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "cmd", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+	logger := lgr.NewLogger()
+
+	cmd := exec.Command("sh", "-c", `echo "first line"; echo oops >&2; echo second; printf partial; exit 3`)
+	err = logr.RunCmd(logger, cmd, logr.CmdOptions{StderrLevel: logr.Error, Fields: []logr.Field{logr.String("job", "build")}})
+	require.Error(t, err)
+
+	cmd = exec.Command("sh", "-c", `printf abcdefghij`)
+	require.NoError(t, logr.RunCmd(logger, cmd, logr.CmdOptions{StdoutLevel: logr.Warn, MaxLineSize: 4}))
+	require.NoError(t, lgr.Shutdown())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 7)
+	assert.Equal(t, []string{
+		"warn abcd cmd=sh stream=stdout",
+		"warn efgh cmd=sh stream=stdout",
+		"warn ij cmd=sh stream=stdout",
+	}, lines[4:])
+
+	lines = lines[:4]
+	assert.ElementsMatch(t, []string{
+		"info first line cmd=sh job=build stream=stdout",
+		"info second cmd=sh job=build stream=stdout",
+		"info partial cmd=sh job=build stream=stdout",
+		"error oops cmd=sh job=build stream=stderr",
+	}, lines)
+
+	stdout := []string{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "info") {
+			stdout = append(stdout, line)
+		}
+	}
+	assert.Equal(t, "info first line cmd=sh job=build stream=stdout", stdout[0])
+	assert.Equal(t, "info partial cmd=sh job=build stream=stdout", stdout[2])
+}
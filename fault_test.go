@@ -0,0 +1,118 @@
+package logr_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFaultyLogr(t *testing.T, fw *test.FaultyWriter, qSize int, opts ...logr.Option) (*logr.Logr, *errCollector) {
+	errs := &errCollector{}
+	lgr, err := logr.New(append(opts, logr.OnLoggerError(errs.add))...)
+	require.NoError(t, err)
+
+	formatter := &formatters.Plain{DisableTimestamp: true, DisableLevel: true}
+	err = lgr.AddTarget(targets.NewWriterTarget(fw), "faulty", &logr.StdFilter{Lvl: logr.Info}, formatter, qSize)
+	require.NoError(t, err)
+	return lgr, errs
+}
+
+type errCollector struct {
+	mux  sync.Mutex
+	errs []error
+}
+
+func (ec *errCollector) add(err error) {
+	ec.mux.Lock()
+	defer ec.mux.Unlock()
+	ec.errs = append(ec.errs, err)
+}
+
+func (ec *errCollector) count() int {
+	ec.mux.Lock()
+	defer ec.mux.Unlock()
+	return len(ec.errs)
+}
+
+func TestFaultyWriterErrors(t *testing.T) {
+	buf := &test.Buffer{}
+	fw := test.NewFaultyWriter(buf)
+	fw.FailEvery(3)
+	lgr, errs := newFaultyLogr(t, fw, 100)
+
+	logger := lgr.NewLogger()
+	for _, msg := range []string{"one", "two", "three", "four", "five", "six", "seven"} {
+		logger.Info(msg)
+	}
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, 2, fw.Failures())
+	assert.Equal(t, 2, errs.count())
+	assert.Equal(t, "one\ntwo\nfour\nfive\nseven\n", strings.ReplaceAll(buf.String(), " ", ""))
+
+	stats := lgr.StatsSnapshot()
+	assert.Equal(t, uint64(2), stats.Targets[0].Errors)
+	assert.Equal(t, uint64(5), stats.Targets[0].Logged)
+}
+
+func TestFaultyWriterOverflow(t *testing.T) {
+	buf := &test.Buffer{}
+	fw := test.NewFaultyWriter(buf)
+	fw.BlockFor(time.Hour)
+	drop := logr.OnTargetQueueFull(func(target logr.Target, rec *logr.LogRec, maxQueueSize int) bool {
+		return true
+	})
+	lgr, _ := newFaultyLogr(t, fw, 2, drop)
+
+	logger := lgr.NewLogger()
+	logger.Info("blocked")
+	require.Eventually(t, func() bool { return fw.Writes() == 1 }, time.Second*5, time.Millisecond*10)
+
+	for _, msg := range []string{"queued1", "queued2", "dropped1", "dropped2", "dropped3"} {
+		logger.Info(msg)
+	}
+	require.Eventually(t, func() bool {
+		return lgr.StatsSnapshot().Targets[0].Dropped == 3
+	}, time.Second*5, time.Millisecond*10)
+
+	// wait for the queue to drain so the flush on shutdown is not dropped.
+	fw.BlockFor(0)
+	require.Eventually(t, func() bool { return fw.Writes() == 3 }, time.Second*5, time.Millisecond*10)
+	require.NoError(t, lgr.Shutdown())
+
+	output := buf.String()
+	assert.NotContains(t, output, "dropped")
+	for _, s := range []string{"blocked", "queued1", "queued2"} {
+		assert.Contains(t, output, s)
+	}
+}
+
+func TestFaultyWriterPanic(t *testing.T) {
+	buf := &test.Buffer{}
+	fw := test.NewFaultyWriter(buf)
+	lgr, _ := newFaultyLogr(t, fw, 100)
+
+	logger := lgr.NewLogger()
+	logger.Info("before")
+	require.NoError(t, lgr.Flush())
+
+	fw.PanicNext()
+	logger.Info("panic")
+	logger.Info("after")
+	require.NoError(t, lgr.Shutdown())
+
+	// the target recovers from the panic and continues with the next record.
+	output := buf.String()
+	assert.Contains(t, output, "before")
+	assert.NotContains(t, output, "panic")
+	assert.Contains(t, output, "after")
+	assert.Equal(t, 3, fw.Writes())
+}
@@ -1,6 +1,8 @@
 package logr
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -96,6 +98,13 @@ func (f Field) ValueString(w io.Writer, shouldQuote func(s string) bool) error {
 		shouldQuote = func(s string) bool { return false }
 	}
 	var err error
+	switch f.Type {
+	case Int64Type, Float64Type:
+		if ok, err := writeHinted(w, f, shouldQuote); ok {
+			return err
+		}
+	}
+
 	switch f.Type {
 	case StringType:
 		err = quoteString(w, f.String, shouldQuote)
@@ -162,7 +171,14 @@ func (f Field) ValueString(w io.Writer, shouldQuote func(s string) bool) error {
 	case BinaryType:
 		b, ok := f.Interface.([]byte)
 		if ok {
-			_, err = fmt.Fprintf(w, "[%X]", b)
+			switch fieldHint(f.Integer) {
+			case hintHex:
+				_, err = io.WriteString(w, hex.EncodeToString(b))
+			case hintBase64:
+				err = quoteString(w, base64.StdEncoding.EncodeToString(b), shouldQuote)
+			default:
+				_, err = fmt.Fprintf(w, "[%X]", b)
+			}
 			break
 		}
 		_, err = fmt.Fprintf(w, "[%v]", f.Interface)
@@ -233,11 +249,77 @@ func (f Field) ValueString(w io.Writer, shouldQuote func(s string) bool) error {
 	return err
 }
 
+// nilValue marks the empty string fields created by `Any` for nil pointers, so
+// formatters can tell them apart from empty strings. See `Field.IsNil`.
+type nilValue struct{}
+
 func nilField(key string) Field {
-	return String(key, "")
+	return Field{Key: key, Type: StringType, Interface: nilValue{}}
+}
+
+// IsNil returns true if the field holds a nil value, such as a nil error,
+// fmt.Stringer, pointer, map or slice.
+func (f Field) IsNil() bool {
+	switch f.Type {
+	case StringType:
+		_, ok := f.Interface.(nilValue)
+		return ok
+	case StringerType, StructType, ErrorType, TimeType, BinaryType, ArrayType, MapType, UnknownType:
+		if f.Interface == nil {
+			return true
+		}
+		v := reflect.ValueOf(f.Interface)
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+			return v.IsNil()
+		}
+	}
+	return false
+}
+
+// IsZero returns true if the field holds a zero value: nil, an empty string or
+// binary value, false, zero, or the zero time.
+func (f Field) IsZero() bool {
+	if f.IsNil() {
+		return true
+	}
+	switch f.Type {
+	case StringType:
+		return f.String == ""
+	case BoolType, TimestampMillisType, DurationType, Int64Type, Int32Type, IntType, Uint64Type, Uint32Type, UintType:
+		return f.Integer == 0
+	case Float64Type, Float32Type:
+		return f.Float == 0
+	case TimeType:
+		t, ok := f.Interface.(time.Time)
+		return ok && t.IsZero()
+	case BinaryType:
+		b, ok := f.Interface.([]byte)
+		return ok && len(b) == 0
+	}
+	return false
 }
 
 func fieldForAny(key string, val interface{}) Field {
+	// clone first so a registered encoder, called when the field is formatted,
+	// encodes the clone rather than a value the caller may mutate.
+	cloned := false
+	switch v := val.(type) {
+	case LogCloner:
+		if v != nil {
+			val, cloned = v.LogClone(), true
+		}
+	case *LogCloner:
+		if v != nil {
+			val, cloned = (*v).LogClone(), true
+		}
+	}
+	if f, ok := encodedField(key, val); ok {
+		return f
+	}
+	if cloned {
+		return Field{Key: key, Type: StructType, Interface: val}
+	}
 	switch v := val.(type) {
 	case LogCloner:
 		if v == nil {
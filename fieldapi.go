@@ -99,6 +99,18 @@ func Millis(key string, val int64) Field {
 	return Field{Key: key, Type: TimestampMillisType, Integer: val}
 }
 
+// Hex constructs a field containing binary data that is output as lowercase hex
+// by all formatters, e.g. a checksum.
+func Hex(key string, val []byte) Field {
+	return Field{Key: key, Type: BinaryType, Interface: val, Integer: int64(hintHex)}
+}
+
+// Base64 constructs a field containing binary data that is output using standard
+// base64 encoding by all formatters.
+func Base64(key string, val []byte) Field {
+	return Field{Key: key, Type: BinaryType, Interface: val, Integer: int64(hintBase64)}
+}
+
 // Array constructs a field containing a key and array value.
 func Array(key string, val interface{}) Field {
 	return Field{Key: key, Type: ArrayType, Interface: val}
@@ -0,0 +1,165 @@
+package logr
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Encoder receives the parts of a value encoded by a `FieldEncoder`. The parts are
+// output as an object by structured formatters such as JSON, and as key=value
+// pairs within braces by text formatters such as Plain.
+type Encoder interface {
+	AddString(key string, val string)
+	AddInt(key string, val int64)
+	AddUint(key string, val uint64)
+	AddFloat(key string, val float64)
+	AddBool(key string, val bool)
+	AddDuration(key string, val time.Duration)
+	AddTime(key string, val time.Time)
+
+	// AddField adds any field, such as one created with `Any`, which is itself
+	// encoded if its type has a registered encoder.
+	AddField(field Field)
+}
+
+// FieldEncoder encodes a value of a type registered via `RegisterFieldEncoder`.
+type FieldEncoder func(val interface{}, enc Encoder)
+
+var (
+	fieldEncoders    atomic.Value // map[reflect.Type]FieldEncoder
+	fieldEncodersMux sync.Mutex   // serializes registration
+)
+
+// RegisterFieldEncoder registers an encoder for the type of sample, so values of
+// that type, or non-nil pointers to it, logged via `Any` render consistently in
+// all formatters instead of each relying on `fmt.Stringer` or reflection. The
+// encoder is called lazily, each time the field is formatted, with the value, not
+// a pointer, and takes precedence over `LogWriter`. Values implementing `LogCloner`
+// are cloned first and the clone is encoded. Registering an
+// encoder for a type again replaces it; a nil encoder removes it. Encoders are
+// typically registered during initialization.
+func RegisterFieldEncoder(sample interface{}, encoder FieldEncoder) {
+	t := reflect.TypeOf(sample)
+	if t == nil {
+		return
+	}
+
+	fieldEncodersMux.Lock()
+	defer fieldEncodersMux.Unlock()
+
+	old, _ := fieldEncoders.Load().(map[reflect.Type]FieldEncoder)
+	encoders := make(map[reflect.Type]FieldEncoder, len(old)+1)
+	for k, v := range old {
+		encoders[k] = v
+	}
+	if encoder == nil {
+		delete(encoders, t)
+	} else {
+		encoders[t] = encoder
+	}
+	fieldEncoders.Store(encoders)
+}
+
+// encodedField returns a field for val if its type has a registered encoder.
+func encodedField(key string, val interface{}) (Field, bool) {
+	encoders, _ := fieldEncoders.Load().(map[reflect.Type]FieldEncoder)
+	if len(encoders) == 0 || val == nil {
+		return Field{}, false
+	}
+	t := reflect.TypeOf(val)
+	if encoder, ok := encoders[t]; ok {
+		return Field{Key: key, Type: StructType, Interface: encodedValue{val: val, encoder: encoder}}, true
+	}
+	if t.Kind() != reflect.Ptr {
+		return Field{}, false
+	}
+	encoder, ok := encoders[t.Elem()]
+	if !ok {
+		return Field{}, false
+	}
+	v := reflect.ValueOf(val)
+	if v.IsNil() {
+		return nilField(key), true
+	}
+	return Field{Key: key, Type: StructType, Interface: encodedValue{val: v.Elem().Interface(), encoder: encoder}}, true
+}
+
+// EncodedFields returns the parts of the field's value as fields, and true, if the
+// value's type has an encoder registered via `RegisterFieldEncoder`. Formatters
+// use this to output the value as an object.
+func (f Field) EncodedFields() ([]Field, bool) {
+	ev, ok := f.Interface.(encodedValue)
+	if !ok {
+		return nil, false
+	}
+	return ev.fields(), true
+}
+
+// encodedValue is a value with a registered encoder. It implements `LogWriter` so
+// formatters using `Field.ValueString` output the encoded parts.
+type encodedValue struct {
+	val     interface{}
+	encoder FieldEncoder
+}
+
+func (ev encodedValue) fields() []Field {
+	enc := &fieldsEncoder{}
+	ev.encoder(ev.val, enc)
+	return enc.fields
+}
+
+// LogWrite writes the encoded parts as space separated key=value pairs in braces.
+func (ev encodedValue) LogWrite(w io.Writer) error {
+	if _, err := io.WriteString(w, "{"); err != nil {
+		return err
+	}
+	for i, f := range ev.fields() {
+		if i > 0 {
+			if _, err := io.WriteString(w, " "); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, f.Key); err != nil {
+			return err
+		}
+		if _, err := w.Write(Equals); err != nil {
+			return err
+		}
+		if err := f.ValueString(w, shouldQuoteEncoded); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+func shouldQuoteEncoded(s string) bool {
+	return s == "" || strings.ContainsAny(s, " =\"{}")
+}
+
+// fieldsEncoder collects encoded parts as fields.
+type fieldsEncoder struct {
+	fields []Field
+}
+
+func (e *fieldsEncoder) AddString(key string, val string) { e.AddField(String(key, val)) }
+func (e *fieldsEncoder) AddInt(key string, val int64)     { e.AddField(Int64(key, val)) }
+func (e *fieldsEncoder) AddUint(key string, val uint64)   { e.AddField(Uint64(key, val)) }
+func (e *fieldsEncoder) AddFloat(key string, val float64) { e.AddField(Float64(key, val)) }
+func (e *fieldsEncoder) AddBool(key string, val bool)     { e.AddField(Bool(key, val)) }
+
+func (e *fieldsEncoder) AddDuration(key string, val time.Duration) {
+	e.AddField(Duration(key, val))
+}
+
+func (e *fieldsEncoder) AddTime(key string, val time.Time) {
+	e.AddField(Time(key, val))
+}
+
+func (e *fieldsEncoder) AddField(field Field) {
+	e.fields = append(e.fields, field)
+}
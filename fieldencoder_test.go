@@ -0,0 +1,94 @@
+package logr_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type money struct {
+	cents    int64
+	currency string
+}
+
+func TestRegisterFieldEncoder(t *testing.T) {
+	logr.RegisterFieldEncoder(money{}, func(val interface{}, enc logr.Encoder) {
+		m := val.(money)
+		enc.AddFloat("amount", float64(m.cents)/100)
+		enc.AddString("currency", m.currency)
+	})
+	defer logr.RegisterFieldEncoder(money{}, nil)
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	plainBuf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(plainBuf), "plain", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 10)
+	require.NoError(t, err)
+	jsonBuf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(jsonBuf), "json", &logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{DisableTimestamp: true}, 10)
+	require.NoError(t, err)
+
+	var nilMoney *money
+	price := money{cents: 1250, currency: "EUR"}
+	logger := lgr.NewLogger()
+	logger.Info("charged", logr.Any("price", price))
+	logger.Info("refunded", logr.Any("price", &price))
+	logger.Info("free", logr.Any("price", nilMoney))
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, "info charged price={amount=12.5 currency=EUR}\n"+
+		"info refunded price={amount=12.5 currency=EUR}\n"+
+		"info free price=\n", plainBuf.String())
+	assert.Equal(t, `{"level":"info","msg":"charged","price":{"amount":12.5,"currency":"EUR"}}`+"\n"+
+		`{"level":"info","msg":"refunded","price":{"amount":12.5,"currency":"EUR"}}`+"\n"+
+		`{"level":"info","msg":"free","price":""}`+"\n", jsonBuf.String())
+
+	fields, ok := logr.Any("price", price).EncodedFields()
+	require.True(t, ok)
+	assert.Equal(t, []logr.Field{logr.Float64("amount", 12.5), logr.String("currency", "EUR")}, fields)
+
+	logr.RegisterFieldEncoder(money{}, nil)
+	_, ok = logr.Any("price", price).EncodedFields()
+	assert.False(t, ok)
+}
+
+type cart struct {
+	items []string
+}
+
+func (c *cart) LogClone() interface{} {
+	return &cart{items: append([]string(nil), c.items...)}
+}
+
+func TestRegisterFieldEncoderLogCloner(t *testing.T) {
+	logr.RegisterFieldEncoder(cart{}, func(val interface{}, enc logr.Encoder) {
+		enc.AddString("items", strings.Join(val.(cart).items, ","))
+	})
+	defer logr.RegisterFieldEncoder(cart{}, nil)
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "blocked", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 10)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("first")
+	<-target.Blocked()
+
+	// mutated while queued; the clone is encoded.
+	c := &cart{items: []string{"apple", "pear"}}
+	logger.Info("checkout", logr.Any("cart", c))
+	c.items[0] = "plum"
+
+	target.Unblock()
+	require.NoError(t, lgr.Shutdown())
+	assert.Contains(t, buf.String(), "info checkout cart={items=apple,pear}\n")
+}
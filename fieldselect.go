@@ -0,0 +1,79 @@
+package logr
+
+import "errors"
+
+// Keys that can be used in field allow and deny lists to select parts of a log
+// record other than fields.
+const (
+	FieldKeyStacktrace = "stacktrace"
+	FieldKeyCaller     = "caller"
+)
+
+// FieldAllowList restricts the fields output by a target to those with the
+// specified keys. The stack trace and caller are only output if `FieldKeyStacktrace`
+// and `FieldKeyCaller` are included. This allows, for example, an external sink to
+// receive a minimal subset of what an internal target receives.
+func FieldAllowList(keys ...string) TargetOption {
+	return func(opts *targetHostOptions) error {
+		return opts.setFieldSelector(newFieldSelector(keys, true))
+	}
+}
+
+// FieldDenyList excludes fields with the specified keys from a target's output.
+// Include `FieldKeyStacktrace` or `FieldKeyCaller` to also exclude the stack trace
+// or caller.
+func FieldDenyList(keys ...string) TargetOption {
+	return func(opts *targetHostOptions) error {
+		return opts.setFieldSelector(newFieldSelector(keys, false))
+	}
+}
+
+func (opts *targetHostOptions) setFieldSelector(fs *fieldSelector) error {
+	if opts.fieldSelector != nil {
+		return errors.New("only one field allow or deny list can be set")
+	}
+	opts.fieldSelector = fs
+	return nil
+}
+
+// fieldSelector selects the fields of a log record a target receives.
+type fieldSelector struct {
+	keys  map[string]struct{}
+	allow bool
+}
+
+func newFieldSelector(keys []string, allow bool) *fieldSelector {
+	fs := &fieldSelector{keys: make(map[string]struct{}, len(keys)), allow: allow}
+	for _, key := range keys {
+		fs.keys[key] = struct{}{}
+	}
+	return fs
+}
+
+func (fs *fieldSelector) includes(key string) bool {
+	_, ok := fs.keys[key]
+	return ok == fs.allow
+}
+
+// apply returns a copy of the log record containing only the selected fields, and
+// the level with stack traces disabled if the stack trace is not selected.
+func (fs *fieldSelector) apply(rec *LogRec, level Level) (*LogRec, Level) {
+	fields := rec.Fields()
+	selected := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if fs.includes(f.Key) {
+			selected = append(selected, f)
+		}
+	}
+
+	rec = rec.clone()
+	rec.fieldsAll = selected
+	if !fs.includes(FieldKeyStacktrace) {
+		rec.frames = nil
+		level.Stacktrace = false
+	}
+	if !fs.includes(FieldKeyCaller) {
+		rec.caller = ""
+	}
+	return rec, level
+}
@@ -0,0 +1,68 @@
+package logr_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mux.Lock()
+	defer sb.mux.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mux.Lock()
+	defer sb.mux.Unlock()
+	return sb.buf.String()
+}
+
+func TestFieldAllowDenyList(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(targets.NewWriterTarget(&bytes.Buffer{}), "both", nil, nil, 10,
+		logr.FieldAllowList("a"), logr.FieldDenyList("b"))
+	assert.Error(t, err)
+
+	filter := &logr.StdFilter{Lvl: logr.Info, Stacktrace: logr.Error}
+	formatter := &formatters.Plain{DisableTimestamp: true, EnableCaller: true}
+	var internal, external, denied syncBuffer
+	require.NoError(t, lgr.AddTarget(targets.NewWriterTarget(&internal), "internal", filter, formatter, 10))
+	require.NoError(t, lgr.AddTarget(targets.NewWriterTarget(&external), "external", filter, formatter, 10,
+		logr.FieldAllowList("request_id")))
+	require.NoError(t, lgr.AddTarget(targets.NewWriterTarget(&denied), "denied", filter, formatter, 10,
+		logr.FieldDenyList("email", logr.FieldKeyStacktrace)))
+
+	logger := lgr.NewLogger().With(logr.String("request_id", "r1"))
+	logger.Error("login failed", logr.String("email", "sam@example.com"), logr.Int("attempt", 3))
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Contains(t, internal.String(), "email=sam@example.com")
+	assert.Contains(t, internal.String(), "fieldselect_test.go")
+
+	out := external.String()
+	assert.Contains(t, out, "request_id=r1")
+	assert.NotContains(t, out, "email")
+	assert.NotContains(t, out, "attempt")
+	assert.NotContains(t, out, "fieldselect_test.go") // no caller or stack trace
+
+	out = denied.String()
+	assert.Contains(t, out, "request_id=r1")
+	assert.Contains(t, out, "attempt=3")
+	assert.NotContains(t, out, "email")
+	assert.Equal(t, 1, strings.Count(out, "fieldselect_test.go")) // caller only
+}
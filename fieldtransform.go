@@ -0,0 +1,106 @@
+package logr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Field transform actions. See `FieldTransform`.
+const (
+	TransformRename = "rename"
+	TransformHash   = "hash"
+	TransformDrop   = "drop"
+)
+
+// FieldTransform describes a change made to a field before it is output by a
+// target, allowing one record to serve targets with different privacy requirements.
+type FieldTransform struct {
+	// Key is the key of the field to transform.
+	Key string `json:"key"`
+
+	// Action is one of "rename", "hash" or "drop". Hash replaces the value with the
+	// hex encoded SHA-256 hash of the salt followed by the value's string form.
+	Action string `json:"action"`
+
+	// To is the new key when renaming.
+	To string `json:"to,omitempty"`
+
+	// Salt is prepended to the value before hashing, which prevents hashes of
+	// low entropy values such as user IDs being reversed via a lookup table.
+	Salt string `json:"salt,omitempty"`
+}
+
+// CheckValid returns an error if the transform is invalid.
+func (ft FieldTransform) CheckValid() error {
+	if ft.Key == "" {
+		return errors.New("field transform missing key")
+	}
+	switch ft.Action {
+	case TransformRename:
+		if ft.To == "" {
+			return fmt.Errorf("field transform for %s missing new key", ft.Key)
+		}
+	case TransformHash, TransformDrop:
+	default:
+		return fmt.Errorf("field transform for %s has invalid action %q", ft.Key, ft.Action)
+	}
+	return nil
+}
+
+// FieldTransforms applies transforms, in order, to the fields of each record output
+// by a target. Transforms are applied after any `FieldAllowList` or `FieldDenyList`,
+// and each transform sees the keys resulting from the previous ones.
+func FieldTransforms(transforms ...FieldTransform) TargetOption {
+	return func(opts *targetHostOptions) error {
+		for _, ft := range transforms {
+			if err := ft.CheckValid(); err != nil {
+				return err
+			}
+		}
+		opts.fieldTransforms = append(opts.fieldTransforms, transforms...)
+		return nil
+	}
+}
+
+// applyFieldTransforms returns a copy of the log record with the transforms applied
+// to its fields.
+func applyFieldTransforms(rec *LogRec, transforms []FieldTransform) *LogRec {
+	fields := rec.Fields()
+	out := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		keep := true
+		for _, ft := range transforms {
+			if f.Key != ft.Key {
+				continue
+			}
+			switch ft.Action {
+			case TransformRename:
+				f.Key = ft.To
+			case TransformHash:
+				f = hashField(f, ft.Salt)
+			case TransformDrop:
+				keep = false
+			}
+			if !keep {
+				break
+			}
+		}
+		if keep {
+			out = append(out, f)
+		}
+	}
+
+	rec = rec.clone()
+	rec.fieldsAll = out
+	return rec
+}
+
+func hashField(f Field, salt string) Field {
+	var sb strings.Builder
+	_ = f.ValueString(&sb, nil)
+	sum := sha256.Sum256([]byte(salt + sb.String()))
+	return Field{Key: f.Key, Type: StringType, String: hex.EncodeToString(sum[:])}
+}
@@ -0,0 +1,45 @@
+package logr_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldTransforms(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	var buf syncBuffer
+	err = lgr.AddTarget(targets.NewWriterTarget(&buf), "bad", nil, nil, 10,
+		logr.FieldTransforms(logr.FieldTransform{Key: "user_id", Action: "encrypt"}))
+	assert.Error(t, err)
+	err = lgr.AddTarget(targets.NewWriterTarget(&buf), "bad", nil, nil, 10,
+		logr.FieldTransforms(logr.FieldTransform{Key: "user_id", Action: logr.TransformRename}))
+	assert.Error(t, err)
+
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	err = lgr.AddTarget(targets.NewWriterTarget(&buf), "analytics", &logr.StdFilter{Lvl: logr.Info}, formatter, 10,
+		logr.FieldTransforms(
+			logr.FieldTransform{Key: "user_id", Action: logr.TransformHash, Salt: "pepper"},
+			logr.FieldTransform{Key: "user_id", Action: logr.TransformRename, To: "user"},
+			logr.FieldTransform{Key: "email", Action: logr.TransformDrop},
+		))
+	require.NoError(t, err)
+
+	lgr.NewLogger().Info("login", logr.Int("user_id", 42), logr.String("email", "sam@example.com"), logr.Int("attempt", 1))
+	require.NoError(t, lgr.Shutdown())
+
+	sum := sha256.Sum256([]byte("pepper42"))
+	out := buf.String()
+	assert.Contains(t, out, "user="+hex.EncodeToString(sum[:]))
+	assert.Contains(t, out, "attempt=1")
+	assert.NotContains(t, out, "user_id")
+	assert.NotContains(t, out, "email")
+}
@@ -8,3 +8,13 @@ package logr
 type Filter interface {
 	GetEnabledLevel(level Level) (Level, bool)
 }
+
+// RecordFilter is an optional interface that a Filter can implement to include or
+// exclude individual log records based on their content, such as message or fields.
+// It is only called for records whose level is enabled via `GetEnabledLevel`, after
+// the record has been prepared. Calls for a target are never concurrent, including
+// for `Synchronous` targets, but a filter shared by several targets must be safe
+// for concurrent use.
+type RecordFilter interface {
+	IsRecordEnabled(rec *LogRec) bool
+}
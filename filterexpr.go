@@ -0,0 +1,573 @@
+package logr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ExprFilter is a filter defined by a small expression language, allowing routing
+// to be adjusted via configuration without code changes. For example:
+//
+//	level >= warn && fields.subsystem == "auth" && msg =~ "timeout"
+//
+// Operands can be `level`, `msg`, `fields.<key>`, string literals (double quoted),
+// numbers, `true`/`false`, or bare level names. Supported operators are
+// `==`, `!=`, `<`, `<=`, `>`, `>=`, `=~` (regex match), `!~` (regex non-match),
+// `&&`, `||`, `!` and parentheses. A lone `fields.<key>` is true if the field exists.
+//
+// Comparing `level` to a standard level name compares severity, so `level >= warn`
+// matches warn, error, fatal and panic. Any other level comparison matches by name.
+//
+// The expression is compiled once; evaluation is performed per record.
+type ExprFilter struct {
+	// Stacktrace determines which levels include a stack trace, as with `StdFilter`.
+	// Levels at or above this severity include a stack trace.
+	Stacktrace Level
+
+	expr string
+	root exprNode
+}
+
+// ExprFilterOptions provides the JSON options for the "expr" filter factory.
+type ExprFilterOptions struct {
+	Expr       string `json:"expr"`
+	Stacktrace string `json:"stacktrace,omitempty"` // level name, e.g. "error"
+}
+
+// NewExprFilter compiles an expression into a filter.
+func NewExprFilter(expr string) (*ExprFilter, error) {
+	p := &exprParser{input: expr}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("filter expr: unexpected '%s' at %d", p.tok.text, p.tok.pos)
+	}
+	return &ExprFilter{expr: expr, root: root}, nil
+}
+
+func newExprFilterFromOptions(options json.RawMessage) (Filter, error) {
+	var opts ExprFilterOptions
+	if len(options) == 0 {
+		return nil, errors.New("missing expr filter options")
+	}
+	if err := json.Unmarshal(options, &opts); err != nil {
+		return nil, fmt.Errorf("error decoding expr filter options: %w", err)
+	}
+	f, err := NewExprFilter(opts.Expr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Stacktrace != "" {
+		lvl, ok := GetLevelMapper().Name(opts.Stacktrace)
+		if !ok {
+			return nil, fmt.Errorf("unknown stacktrace level '%s'", opts.Stacktrace)
+		}
+		f.Stacktrace = lvl
+	}
+	return f, nil
+}
+
+func init() {
+	_ = RegisterFilterFactory("expr", newExprFilterFromOptions)
+}
+
+// String returns the source expression.
+func (f *ExprFilter) String() string {
+	return f.expr
+}
+
+// GetEnabledLevel returns the Level with the specified Level.ID and whether the level
+// could be enabled by this filter. Since only the level is known at this point, parts
+// of the expression referring to the message or fields are assumed to possibly match;
+// `IsRecordEnabled` makes the final determination.
+func (f *ExprFilter) GetEnabledLevel(level Level) (Level, bool) {
+	res := f.root.eval(exprEnv{level: level})
+	if res == triFalse {
+		return Level{}, false
+	}
+	if level.ID <= f.Stacktrace.ID {
+		level.Stacktrace = true
+	}
+	return level, true
+}
+
+// IsRecordEnabled evaluates the full expression against a log record.
+func (f *ExprFilter) IsRecordEnabled(rec *LogRec) bool {
+	return f.root.eval(exprEnv{level: rec.Level(), rec: rec}) == triTrue
+}
+
+// tri is a three-valued logic result. Unknown is used when evaluating with only
+// the level known.
+type tri int
+
+const (
+	triFalse tri = iota
+	triTrue
+	triUnknown
+)
+
+func toTri(b bool) tri {
+	if b {
+		return triTrue
+	}
+	return triFalse
+}
+
+type exprEnv struct {
+	level Level
+	rec   *LogRec // nil when only the level is known.
+}
+
+type exprNode interface {
+	eval(env exprEnv) tri
+}
+
+type andNode struct{ left, right exprNode }
+
+func (n andNode) eval(env exprEnv) tri {
+	l := n.left.eval(env)
+	if l == triFalse {
+		return triFalse
+	}
+	r := n.right.eval(env)
+	if r == triFalse {
+		return triFalse
+	}
+	if l == triTrue && r == triTrue {
+		return triTrue
+	}
+	return triUnknown
+}
+
+type orNode struct{ left, right exprNode }
+
+func (n orNode) eval(env exprEnv) tri {
+	l := n.left.eval(env)
+	if l == triTrue {
+		return triTrue
+	}
+	r := n.right.eval(env)
+	if r == triTrue {
+		return triTrue
+	}
+	if l == triFalse && r == triFalse {
+		return triFalse
+	}
+	return triUnknown
+}
+
+type notNode struct{ operand exprNode }
+
+func (n notNode) eval(env exprEnv) tri {
+	switch n.operand.eval(env) {
+	case triTrue:
+		return triFalse
+	case triFalse:
+		return triTrue
+	}
+	return triUnknown
+}
+
+// operand kinds
+const (
+	opLiteral = iota
+	opLevel
+	opMsg
+	opField
+)
+
+type operand struct {
+	kind    int
+	key     string // field key for opField
+	str     string
+	num     float64
+	isNum   bool
+	stdLvl  bool // literal is a level name known to the `LevelMapper`
+	lvlID   LevelID
+	boolean bool
+	isBool  bool
+}
+
+type value struct {
+	str   string
+	num   float64
+	isNum bool
+	found bool
+}
+
+// resolve returns the operand value, and false if it cannot be determined yet.
+func (o operand) resolve(env exprEnv) (value, bool) {
+	switch o.kind {
+	case opLevel:
+		return value{str: env.level.Name, found: true}, true
+	case opMsg:
+		if env.rec == nil {
+			return value{}, false
+		}
+		return value{str: env.rec.Msg(), found: true}, true
+	case opField:
+		if env.rec == nil {
+			return value{}, false
+		}
+		for _, fld := range env.rec.Fields() {
+			if fld.Key == o.key {
+				return fieldValue(fld), true
+			}
+		}
+		return value{}, true
+	}
+	return value{str: o.str, num: o.num, isNum: o.isNum, found: true}, true
+}
+
+func fieldValue(fld Field) value {
+	switch fld.Type {
+	case Int64Type, Int32Type, IntType:
+		return value{num: float64(fld.Integer), isNum: true, str: strconv.FormatInt(fld.Integer, 10), found: true}
+	case Uint64Type, Uint32Type, UintType:
+		return value{num: float64(uint64(fld.Integer)), isNum: true, str: strconv.FormatUint(uint64(fld.Integer), 10), found: true}
+	case Float64Type, Float32Type:
+		return value{num: fld.Float, isNum: true, str: strconv.FormatFloat(fld.Float, 'f', -1, 64), found: true}
+	case BoolType:
+		return value{str: strconv.FormatBool(fld.Integer != 0), found: true}
+	}
+	var sb strings.Builder
+	_ = fld.ValueString(&sb, nil)
+	return value{str: sb.String(), found: true}
+}
+
+// existsNode is a lone operand, true if a field exists or a literal is true.
+type existsNode struct{ o operand }
+
+func (n existsNode) eval(env exprEnv) tri {
+	if n.o.kind == opLiteral && n.o.isBool {
+		return toTri(n.o.boolean)
+	}
+	v, ok := n.o.resolve(env)
+	if !ok {
+		return triUnknown
+	}
+	return toTri(v.found)
+}
+
+type cmpNode struct {
+	op          string
+	left, right operand
+	re          *regexp.Regexp
+}
+
+func (n cmpNode) eval(env exprEnv) tri {
+	// level compared to a standard level name compares severity.
+	if n.left.kind == opLevel && n.right.kind == opLiteral && n.right.stdLvl {
+		// lower IDs are more severe; invert so that `>` means more severe.
+		return toTri(compareNum(n.op, -float64(env.level.ID), -float64(n.right.lvlID)))
+	}
+	if n.right.kind == opLevel && n.left.kind == opLiteral && n.left.stdLvl {
+		return toTri(compareNum(n.op, -float64(n.left.lvlID), -float64(env.level.ID)))
+	}
+
+	l, ok := n.left.resolve(env)
+	if !ok {
+		return triUnknown
+	}
+
+	if n.re != nil {
+		if !l.found {
+			return toTri(n.op == "!~")
+		}
+		matched := n.re.MatchString(l.str)
+		return toTri(matched == (n.op == "=~"))
+	}
+
+	r, ok := n.right.resolve(env)
+	if !ok {
+		return triUnknown
+	}
+	if !l.found || !r.found {
+		return toTri(n.op == "!=")
+	}
+
+	if l.isNum && r.isNum {
+		return toTri(compareNum(n.op, l.num, r.num))
+	}
+	if n.left.kind == opLevel || n.right.kind == opLevel {
+		return toTri(compareStr(n.op, strings.ToLower(l.str), strings.ToLower(r.str)))
+	}
+	return toTri(compareStr(n.op, l.str, r.str))
+}
+
+func compareNum(op string, a, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func compareStr(op string, a, b string) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// tokenizer and parser
+
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+type exprParser struct {
+	input string
+	pos   int
+	tok   token
+}
+
+func (p *exprParser) next() error {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		p.tok = token{kind: tokLParen, text: "(", pos: start}
+	case c == ')':
+		p.pos++
+		p.tok = token{kind: tokRParen, text: ")", pos: start}
+	case c == '"':
+		end := p.pos + 1
+		for end < len(p.input) && p.input[end] != '"' {
+			if p.input[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.input) {
+			return fmt.Errorf("filter expr: unterminated string at %d", start)
+		}
+		s, err := strconv.Unquote(p.input[start : end+1])
+		if err != nil {
+			return fmt.Errorf("filter expr: invalid string at %d: %w", start, err)
+		}
+		p.pos = end + 1
+		p.tok = token{kind: tokString, text: s, pos: start}
+	case strings.ContainsRune("=!<>&|", rune(c)):
+		for _, op := range []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"} {
+			if strings.HasPrefix(p.input[p.pos:], op) {
+				p.pos += len(op)
+				p.tok = token{kind: tokOp, text: op, pos: start}
+				return nil
+			}
+		}
+		return fmt.Errorf("filter expr: invalid operator at %d", start)
+	case c == '-' || (c >= '0' && c <= '9'):
+		end := p.pos + 1
+		for end < len(p.input) && (p.input[end] == '.' || (p.input[end] >= '0' && p.input[end] <= '9')) {
+			end++
+		}
+		p.pos = end
+		p.tok = token{kind: tokNumber, text: p.input[start:end], pos: start}
+	case isIdentChar(c):
+		end := p.pos
+		for end < len(p.input) && isIdentChar(p.input[end]) {
+			end++
+		}
+		p.pos = end
+		p.tok = token{kind: tokIdent, text: p.input[start:end], pos: start}
+	default:
+		return fmt.Errorf("filter expr: unexpected character '%c' at %d", c, start)
+	}
+	return nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && p.tok.text == "||" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && p.tok.text == "&&" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.tok.kind == tokOp && p.tok.text == "!" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.tok.kind == tokLParen {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("filter expr: expected ')' at %d", p.tok.pos)
+		}
+		return n, p.next()
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokOp {
+		return existsNode{o: left}, nil
+	}
+	op := p.tok.text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+	default:
+		return existsNode{o: left}, nil
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	n := cmpNode{op: op, left: left, right: right}
+	if op == "=~" || op == "!~" {
+		if right.kind != opLiteral || right.isNum {
+			return nil, fmt.Errorf("filter expr: right side of %s must be a string", op)
+		}
+		if n.re, err = regexp.Compile(right.str); err != nil {
+			return nil, fmt.Errorf("filter expr: invalid regex: %w", err)
+		}
+	}
+	return n, nil
+}
+
+func (p *exprParser) parseOperand() (operand, error) {
+	tok := p.tok
+	var o operand
+
+	switch tok.kind {
+	case tokString:
+		o = operand{kind: opLiteral, str: tok.text}
+		if lvl, ok := GetLevelMapper().Name(tok.text); ok {
+			o.stdLvl = true
+			o.lvlID = lvl.ID
+		}
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return o, fmt.Errorf("filter expr: invalid number '%s' at %d", tok.text, tok.pos)
+		}
+		o = operand{kind: opLiteral, str: tok.text, num: f, isNum: true}
+	case tokIdent:
+		switch {
+		case tok.text == "level":
+			o = operand{kind: opLevel}
+		case tok.text == "msg":
+			o = operand{kind: opMsg}
+		case strings.HasPrefix(tok.text, "fields.") && len(tok.text) > len("fields."):
+			o = operand{kind: opField, key: strings.TrimPrefix(tok.text, "fields.")}
+		case tok.text == "true" || tok.text == "false":
+			o = operand{kind: opLiteral, str: tok.text, isBool: true, boolean: tok.text == "true"}
+		default:
+			o = operand{kind: opLiteral, str: tok.text}
+			if lvl, ok := GetLevelMapper().Name(tok.text); ok {
+				o.stdLvl = true
+				o.lvlID = lvl.ID
+			}
+		}
+	default:
+		return o, fmt.Errorf("filter expr: expected operand at %d", tok.pos)
+	}
+	return o, p.next()
+}
@@ -0,0 +1,133 @@
+package logr_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExprFilterLevels(t *testing.T) {
+	tests := []struct {
+		expr    string
+		enabled []logr.Level
+	}{
+		{"level >= warn", []logr.Level{logr.Panic, logr.Fatal, logr.Error, logr.Warn}},
+		{"level >= warning", []logr.Level{logr.Panic, logr.Fatal, logr.Error, logr.Warn}},
+		{"level < info", []logr.Level{logr.Debug, logr.Trace}},
+		{"level == error || level == \"debug\"", []logr.Level{logr.Error, logr.Debug}},
+		{"!(level <= info)", []logr.Level{logr.Panic, logr.Fatal, logr.Error, logr.Warn}},
+		{"error <= level", []logr.Level{logr.Panic, logr.Fatal, logr.Error}},
+		{"level >= warn && fields.subsystem == \"auth\"", []logr.Level{logr.Panic, logr.Fatal, logr.Error, logr.Warn}},
+		{"level == trace || msg =~ \"x\"", []logr.Level{logr.Panic, logr.Fatal, logr.Error, logr.Warn, logr.Info, logr.Debug, logr.Trace}},
+		{"false", nil},
+	}
+
+	all := []logr.Level{logr.Panic, logr.Fatal, logr.Error, logr.Warn, logr.Info, logr.Debug, logr.Trace}
+
+	for _, tt := range tests {
+		f, err := logr.NewExprFilter(tt.expr)
+		require.NoError(t, err, tt.expr)
+
+		var enabled []logr.Level
+		for _, lvl := range all {
+			if _, ok := f.GetEnabledLevel(lvl); ok {
+				enabled = append(enabled, lvl)
+			}
+		}
+		assert.Equal(t, tt.enabled, enabled, tt.expr)
+	}
+}
+
+func TestExprFilterRecords(t *testing.T) {
+	f, err := logr.NewExprFilter(`level >= warn && fields.subsystem == "auth" && msg =~ "timeout"`)
+	require.NoError(t, err)
+
+	lgr, _ := logr.New()
+	buf := &test.Buffer{}
+	formatter := &formatters.Plain{DisableTimestamp: true, Delim: " "}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "exprTest", f, formatter, 100)
+	require.NoError(t, err)
+
+	auth := lgr.NewLogger().With(logr.String("subsystem", "auth"))
+	other := lgr.NewLogger().With(logr.String("subsystem", "db"))
+
+	auth.Error("login timeout 1")
+	auth.Info("login timeout 2")
+	auth.Warn("bad password 3")
+	other.Error("query timeout 4")
+	auth.Warn("session timeout 5")
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "timeout 1")
+	assert.NotContains(t, output, "timeout 2")
+	assert.NotContains(t, output, "password 3")
+	assert.NotContains(t, output, "timeout 4")
+	assert.Contains(t, output, "timeout 5")
+}
+
+func TestExprFilterFieldTypes(t *testing.T) {
+	lgr, _ := logr.New()
+	buf := &test.Buffer{}
+	f, err := logr.NewExprFilter(`fields.count > 10 && !fields.skip && fields.ok == true`)
+	require.NoError(t, err)
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "exprTest", f, &formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("one", logr.Int("count", 11), logr.Bool("ok", true))
+	logger.Info("two", logr.Int("count", 9), logr.Bool("ok", true))
+	logger.Info("three", logr.Int("count", 12), logr.Bool("ok", true), logr.String("skip", "y"))
+	logger.Info("four", logr.Float64("count", 10.5), logr.Bool("ok", false))
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "one")
+	assert.NotContains(t, buf.String(), "two")
+	assert.NotContains(t, buf.String(), "three")
+	assert.NotContains(t, buf.String(), "four")
+}
+
+func TestExprFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"level >=",
+		"(level == warn",
+		"msg =~ \"[\"",
+		"msg =~ 3",
+		"level == warn extra",
+		"\"unterminated",
+		"level # warn",
+	} {
+		_, err := logr.NewExprFilter(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestExprFilterFactory(t *testing.T) {
+	factory, ok := logr.GetFilterFactory("expr")
+	require.True(t, ok)
+
+	opts, _ := json.Marshal(logr.ExprFilterOptions{Expr: "level >= info", Stacktrace: "error"})
+	f, err := factory(opts)
+	require.NoError(t, err)
+
+	lvl, ok := f.GetEnabledLevel(logr.Error)
+	assert.True(t, ok)
+	assert.True(t, lvl.Stacktrace)
+
+	lvl, ok = f.GetEnabledLevel(logr.Info)
+	assert.True(t, ok)
+	assert.False(t, lvl.Stacktrace)
+
+	_, ok = f.GetEnabledLevel(logr.Debug)
+	assert.False(t, ok)
+}
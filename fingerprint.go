@@ -0,0 +1,88 @@
+package logr
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+)
+
+const (
+	// DefaultFingerprintKey is the default key of the field added by `ErrorFingerprint`.
+	DefaultFingerprintKey = "fingerprint"
+
+	// FingerprintFrames is the maximum number of stack frames included in a fingerprint.
+	FingerprintFrames = 5
+)
+
+// fingerprintVariable matches parts of a message that typically vary between
+// occurrences of the same error: numbers, hex strings and UUIDs.
+var fingerprintVariable = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|0x[0-9a-fA-F]+|[0-9]+`)
+
+// ErrorFingerprint adds a field containing a stable fingerprint to each Error and
+// more severe log record, so that downstream tools can group and deduplicate
+// occurrences of the same error in any target. The fingerprint is a hash of:
+//   - the type of the root cause of each error field,
+//   - the functions of the top `FingerprintFrames` stack frames, excluding the
+//     runtime; line numbers are excluded so fingerprints survive unrelated edits,
+//   - the message with numbers, hex strings and UUIDs masked.
+//
+// Stack frames are only available when stack traces are enabled for the level, e.g.
+// via `StdFilter.Stacktrace`. If key is empty then `DefaultFingerprintKey` is used.
+func ErrorFingerprint(key string) Option {
+	return func(l *Logr) error {
+		if key == "" {
+			key = DefaultFingerprintKey
+		}
+		l.options.fingerprintKey = key
+		return nil
+	}
+}
+
+// addFingerprint adds the fingerprint field to a prepped Error or more severe record.
+func (lgr *Logr) addFingerprint(rec *LogRec) {
+	if rec.level.ID > Error.ID {
+		return
+	}
+	fields := make([]Field, 0, len(rec.fieldsAll)+1)
+	fields = append(fields, rec.fieldsAll...)
+	rec.fieldsAll = append(fields, String(lgr.options.fingerprintKey, fingerprint(rec)))
+}
+
+// fingerprint returns the fingerprint of a prepped record as 16 hex digits.
+func fingerprint(rec *LogRec) string {
+	h := fnv.New64a()
+	write := func(s string) {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+
+	for _, f := range rec.fieldsAll {
+		if f.Type != ErrorType {
+			continue
+		}
+		err, ok := f.Interface.(error)
+		if !ok || err == nil {
+			continue
+		}
+		for next := errors.Unwrap(err); next != nil; next = errors.Unwrap(err) {
+			err = next
+		}
+		write(fmt.Sprintf("%T", err))
+	}
+
+	var n int
+	for _, frame := range rec.frames {
+		if n == FingerprintFrames {
+			break
+		}
+		if pkg := ResolvePackageName(frame.Function); pkg == "runtime" || pkg == "testing" {
+			continue
+		}
+		write(frame.Function)
+		n++
+	}
+
+	write(fingerprintVariable.ReplaceAllString(rec.msg, "#"))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
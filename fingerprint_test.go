@@ -0,0 +1,55 @@
+package logr_test
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type notFoundError struct{ id int }
+
+func (e notFoundError) Error() string { return fmt.Sprintf("%d not found", e.id) }
+
+func TestErrorFingerprint(t *testing.T) {
+	lgr, err := logr.New(logr.ErrorFingerprint(""))
+	require.NoError(t, err)
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "test", &logr.StdFilter{Lvl: logr.Info, Stacktrace: logr.Error},
+		&formatters.Plain{DisableTimestamp: true, DisableStacktrace: true}, 1000)
+	require.NoError(t, err)
+	logger := lgr.NewLogger()
+
+	logFailure := func(id int, cause error) {
+		logger.Error(fmt.Sprintf("cannot load user %d", id), logr.Err(fmt.Errorf("load: %w", cause)))
+	}
+	logFailure(1, notFoundError{1})
+	logFailure(22, notFoundError{22})
+	logFailure(3, io.ErrUnexpectedEOF)
+	logger.Info("no fingerprint")
+	logger.Error("cannot load user 4", logr.Err(notFoundError{4}))
+	require.NoError(t, lgr.Shutdown())
+
+	re := regexp.MustCompile(`fingerprint=([0-9a-f]{16})`)
+	var prints []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if m := re.FindStringSubmatch(line); m != nil {
+			prints = append(prints, m[1])
+		} else {
+			prints = append(prints, "")
+		}
+	}
+	require.Len(t, prints, 5)
+	assert.Equal(t, prints[0], prints[1], "same error type, call site and message template")
+	assert.NotEqual(t, prints[0], prints[2], "different error type")
+	assert.Empty(t, prints[3], "info records are not fingerprinted")
+	assert.NotEqual(t, prints[0], prints[4], "different call site")
+}
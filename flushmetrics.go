@@ -0,0 +1,81 @@
+package logr
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// FlushMetricsCollector is optionally implemented by a `MetricsCollector` to
+// measure target flushes and shutdowns. A growing abandoned count means graceful
+// shutdown is losing log records, typically because the shutdown timeout is too
+// short for slow targets.
+type FlushMetricsCollector interface {
+	// FlushDurationGauge returns a Gauge set to the duration in seconds of the named
+	// target's most recent flush, from when the flush was queued for the target.
+	FlushDurationGauge(target string) (Gauge, error)
+	// FlushedRecordsGauge returns a Gauge set to the number of records, written or
+	// failed, by the named target's most recent flush, including those queued ahead
+	// of it.
+	FlushedRecordsGauge(target string) (Gauge, error)
+	// ShutdownDrainGauge returns a Gauge set to the time in seconds taken by the
+	// named target to drain its queue and shut down.
+	ShutdownDrainGauge(target string) (Gauge, error)
+	// AbandonedCounter returns a Counter incremented by the number of records still
+	// queued for the named target when it shut down.
+	AbandonedCounter(target string) (Counter, error)
+}
+
+type flushMetrics struct {
+	flushDurationGauge  Gauge
+	flushedRecordsGauge Gauge
+	shutdownDrainGauge  Gauge
+	abandonedCounter    Counter
+}
+
+func newFlushMetrics(collector FlushMetricsCollector, name string) (*flushMetrics, error) {
+	var err error
+	fm := &flushMetrics{}
+	if fm.flushDurationGauge, err = collector.FlushDurationGauge(name); err != nil {
+		return nil, err
+	}
+	if fm.flushedRecordsGauge, err = collector.FlushedRecordsGauge(name); err != nil {
+		return nil, err
+	}
+	if fm.shutdownDrainGauge, err = collector.ShutdownDrainGauge(name); err != nil {
+		return nil, err
+	}
+	if fm.abandonedCounter, err = collector.AbandonedCounter(name); err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// flushQueued records when a flush record is queued and how many records are
+// queued ahead of it, all of which are processed by the flush.
+func (h *TargetHost) flushQueued(ahead int) {
+	atomic.StoreUint64(&h.stats.flushQueuedAhead, uint64(ahead))
+	atomic.StoreInt64(&h.stats.flushQueuedAt, time.Now().UnixNano())
+}
+
+// setFlushMetrics is called once a flush completes, with the number of records
+// processed while draining the queue after the flush record was read.
+func (h *TargetHost) setFlushMetrics(drained int) {
+	d := time.Duration(time.Now().UnixNano() - atomic.LoadInt64(&h.stats.flushQueuedAt))
+	records := atomic.LoadUint64(&h.stats.flushQueuedAhead) + uint64(drained)
+
+	atomic.StoreInt64(&h.stats.lastFlushNanos, int64(d))
+	atomic.StoreUint64(&h.stats.lastFlushRecords, records)
+	if h.flushMetrics != nil {
+		h.flushMetrics.flushDurationGauge.Set(d.Seconds())
+		h.flushMetrics.flushedRecordsGauge.Set(float64(records))
+	}
+}
+
+func (h *TargetHost) setShutdownMetrics(d time.Duration, abandoned int) {
+	atomic.StoreInt64(&h.stats.shutdownNanos, int64(d))
+	atomic.AddUint64(&h.stats.abandoned, uint64(abandoned))
+	if h.flushMetrics != nil {
+		h.flushMetrics.shutdownDrainGauge.Set(d.Seconds())
+		h.flushMetrics.abandonedCounter.Add(float64(abandoned))
+	}
+}
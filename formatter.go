@@ -64,7 +64,7 @@ func (p *DefaultFormatter) Format(rec *LogRec, level Level, buf *bytes.Buffer) (
 	buf.WriteString(rec.Time().Format(timestampFmt))
 	buf.Write(Space)
 
-	buf.WriteString(level.Name)
+	buf.WriteString(level.DisplayName())
 	buf.Write(Space)
 
 	buf.WriteString(rec.Msg())
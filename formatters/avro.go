@@ -0,0 +1,218 @@
+package formatters
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// AvroSchema is the Avro schema of the records output by the Avro formatter.
+// Field values are output as booleans, longs, doubles or strings; other types are
+// output as strings in the same form as the Plain formatter.
+const AvroSchema = `{"type":"record","name":"LogRecord","namespace":"logr","fields":[` +
+	`{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-millis"}},` +
+	`{"name":"level","type":"string"},` +
+	`{"name":"msg","type":"string"},` +
+	`{"name":"caller","type":["null","string"],"default":null},` +
+	`{"name":"fields","type":{"type":"map","values":["null","boolean","long","double","string"]}}]}`
+
+const (
+	// AvroRegistryTimeout is the timeout for schema registry requests.
+	AvroRegistryTimeout = 10 * time.Second
+
+	// avroMagic is the first byte of the Confluent wire format.
+	avroMagic = 0
+)
+
+// Avro formats log records using Avro binary encoding and `AvroSchema`, so records
+// sent to brokers such as Kafka are compact and schema validated. Each record is
+// encoded on its own, without an Avro container file header.
+type Avro struct {
+	// EnableCaller enables output of the file and line number that emitted a log record.
+	EnableCaller bool `json:"enable_caller"`
+
+	// Registry, if not nil, registers `AvroSchema` with a Confluent compatible schema
+	// registry and prefixes each record with the schema ID, as expected by Kafka
+	// consumers using the registry's deserializers.
+	Registry *AvroRegistry `json:"registry,omitempty"`
+
+	mux      sync.Mutex
+	schemaID int32
+	idValid  bool
+}
+
+// AvroRegistry provides parameters for schema registry integration.
+type AvroRegistry struct {
+	// URL is the base URL of the schema registry, e.g. "http://localhost:8081".
+	URL string `json:"url"`
+
+	// Subject is the subject the schema is registered under, typically the Kafka
+	// topic name suffixed with "-value".
+	Subject string `json:"subject"`
+
+	// Username and Password are optional credentials for basic authentication.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// CheckValid returns an error if the formatter options are invalid.
+func (a *Avro) CheckValid() error {
+	if a.Registry != nil {
+		if a.Registry.URL == "" {
+			return errors.New("missing schema registry url")
+		}
+		if _, err := url.Parse(a.Registry.URL); err != nil {
+			return fmt.Errorf("invalid schema registry url: %w", err)
+		}
+		if a.Registry.Subject == "" {
+			return errors.New("missing schema registry subject")
+		}
+	}
+	return nil
+}
+
+// IsStacktraceNeeded returns true if a stacktrace is needed so we can output the `Caller` field.
+func (a *Avro) IsStacktraceNeeded() bool {
+	return a.EnableCaller
+}
+
+// Format converts a log record to bytes in Avro binary encoding. When a registry
+// is configured, the schema is registered on first use; if registration fails an
+// error is returned and registration is retried for the next record.
+func (a *Avro) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if buf == nil {
+		buf = &bytes.Buffer{}
+	}
+
+	if a.Registry != nil {
+		id, err := a.registerSchema()
+		if err != nil {
+			return nil, err
+		}
+		var hdr [5]byte
+		hdr[0] = avroMagic
+		binary.BigEndian.PutUint32(hdr[1:], uint32(id))
+		buf.Write(hdr[:])
+	}
+
+	enc := avroEncoder{buf: buf}
+	enc.long(rec.Time().UnixNano() / int64(time.Millisecond))
+	enc.string(level.JSONName())
+	enc.string(rec.Msg())
+	if a.EnableCaller {
+		enc.long(1)
+		enc.string(rec.Caller())
+	} else {
+		enc.long(0)
+	}
+
+	fields := rec.Fields()
+	if len(fields) > 0 {
+		enc.long(int64(len(fields)))
+		for _, f := range fields {
+			enc.string(f.Key)
+			enc.fieldValue(f)
+		}
+	}
+	enc.long(0)
+	return buf, nil
+}
+
+// registerSchema returns the schema ID, registering the schema if needed.
+func (a *Avro) registerSchema() (int32, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if a.idValid {
+		return a.schemaID, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"schema": AvroSchema})
+	if err != nil {
+		return 0, err
+	}
+	u := strings.TrimSuffix(a.Registry.URL, "/") + "/subjects/" + url.PathEscape(a.Registry.Subject) + "/versions"
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if a.Registry.Username != "" {
+		req.SetBasicAuth(a.Registry.Username, a.Registry.Password)
+	}
+
+	client := &http.Client{Timeout: AvroRegistryTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("cannot register Avro schema: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("cannot register Avro schema: status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		ID int32 `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return 0, fmt.Errorf("cannot decode schema registry response: %w", err)
+	}
+	a.schemaID = result.ID
+	a.idValid = true
+	return a.schemaID, nil
+}
+
+type avroEncoder struct {
+	buf *bytes.Buffer
+}
+
+func (enc avroEncoder) long(n int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	l := binary.PutVarint(tmp[:], n) // zig-zag encoded as required by Avro
+	enc.buf.Write(tmp[:l])
+}
+
+func (enc avroEncoder) string(s string) {
+	enc.long(int64(len(s)))
+	enc.buf.WriteString(s)
+}
+
+// fieldValue writes a field value as the union branch matching its type.
+func (enc avroEncoder) fieldValue(f logr.Field) {
+	switch f.Type {
+	case logr.BoolType:
+		enc.long(1)
+		enc.buf.WriteByte(byte(f.Integer))
+	case logr.Int64Type, logr.Int32Type, logr.IntType, logr.Uint64Type, logr.Uint32Type, logr.UintType,
+		logr.DurationType, logr.TimestampMillisType:
+		enc.long(2)
+		enc.long(f.Integer)
+	case logr.Float64Type, logr.Float32Type:
+		enc.long(3)
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(f.Float))
+		enc.buf.Write(b[:])
+	case logr.StringType:
+		enc.long(4)
+		enc.string(f.String)
+	default:
+		var sb bytes.Buffer
+		if err := f.ValueString(&sb, nil); err != nil {
+			enc.long(0)
+			return
+		}
+		enc.long(4)
+		enc.string(sb.String())
+	}
+}
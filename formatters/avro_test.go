@@ -0,0 +1,131 @@
+package formatters_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type avroRecord struct {
+	timestamp int64
+	level     string
+	msg       string
+	caller    interface{}
+	fields    map[string]interface{}
+}
+
+// decodeAvro decodes a record encoded using formatters.AvroSchema.
+func decodeAvro(t *testing.T, r *bytes.Reader) avroRecord {
+	long := func() int64 {
+		n, err := binary.ReadVarint(r)
+		require.NoError(t, err)
+		return n
+	}
+	str := func() string {
+		b := make([]byte, long())
+		_, _ = r.Read(b)
+		return string(b)
+	}
+
+	rec := avroRecord{timestamp: long(), level: str(), msg: str(), fields: map[string]interface{}{}}
+	if long() == 1 {
+		rec.caller = str()
+	}
+	for count := long(); count != 0; count = long() {
+		for i := int64(0); i < count; i++ {
+			key := str()
+			switch long() {
+			case 0:
+				rec.fields[key] = nil
+			case 1:
+				b, _ := r.ReadByte()
+				rec.fields[key] = b == 1
+			case 2:
+				rec.fields[key] = long()
+			case 3:
+				var b [8]byte
+				_, _ = r.Read(b[:])
+				rec.fields[key] = math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+			case 4:
+				rec.fields[key] = str()
+			}
+		}
+	}
+	return rec
+}
+
+func TestAvro(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 59, 0, 0, time.UTC)
+	newLogger := func(formatter logr.Formatter) (logr.Logger, *test.Buffer) {
+		lgr, err := logr.New(logr.Clock(func() time.Time { return now }))
+		require.NoError(t, err)
+		buf := &test.Buffer{}
+		err = lgr.AddTarget(targets.NewWriterTarget(buf), "avro", &logr.StdFilter{Lvl: logr.Info}, formatter, 1000)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = lgr.Shutdown() })
+		return lgr.NewLogger(), buf
+	}
+
+	t.Run("encoding", func(t *testing.T) {
+		logger, buf := newLogger(&formatters.Avro{})
+		logger.Info("hello", logr.String("s", "one"), logr.Int("n", -7), logr.Float64("f", 2.5),
+			logr.Bool("b", true), logr.Duration("d", time.Second), logr.Array("a", []int{1, 2}))
+		require.NoError(t, logger.Logr().Flush())
+
+		rec := decodeAvro(t, bytes.NewReader(buf.Bytes()))
+		assert.Equal(t, now.UnixNano()/int64(time.Millisecond), rec.timestamp)
+		assert.Equal(t, "info", rec.level)
+		assert.Equal(t, "hello", rec.msg)
+		assert.Nil(t, rec.caller)
+		assert.Equal(t, map[string]interface{}{
+			"s": "one", "n": int64(-7), "f": 2.5, "b": true, "d": int64(time.Second), "a": "1,2,",
+		}, rec.fields)
+	})
+
+	t.Run("schema registry", func(t *testing.T) {
+		var registrations int
+		registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			registrations++
+			assert.Equal(t, "/subjects/logs-value/versions", r.URL.Path)
+			body, _ := ioutil.ReadAll(r.Body)
+			var req map[string]string
+			require.NoError(t, json.Unmarshal(body, &req))
+			assert.Equal(t, formatters.AvroSchema, req["schema"])
+			_, _ = w.Write([]byte(`{"id":42}`))
+		}))
+		defer registry.Close()
+
+		logger, buf := newLogger(&formatters.Avro{Registry: &formatters.AvroRegistry{URL: registry.URL, Subject: "logs-value"}})
+		logger.Info("one")
+		logger.Info("two")
+		require.NoError(t, logger.Logr().Flush())
+
+		r := bytes.NewReader(buf.Bytes())
+		for _, msg := range []string{"one", "two"} {
+			var hdr [5]byte
+			_, _ = r.Read(hdr[:])
+			assert.Equal(t, []byte{0, 0, 0, 0, 42}, hdr[:])
+			assert.Equal(t, msg, decodeAvro(t, r).msg)
+		}
+		assert.Equal(t, 1, registrations)
+	})
+}
+
+func TestAvroSchemaIsJSON(t *testing.T) {
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(formatters.AvroSchema), &schema))
+	assert.Equal(t, "LogRecord", schema["name"])
+}
@@ -0,0 +1,92 @@
+package formatters
+
+import (
+	"fmt"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// EmptyMode determines how a formatter outputs a field holding a nil, empty or
+// zero value. See `EmptyFields`.
+type EmptyMode string
+
+const (
+	// EmptyDefault outputs the value as is.
+	EmptyDefault EmptyMode = ""
+
+	// EmptyOmit omits the field.
+	EmptyOmit EmptyMode = "omit"
+
+	// EmptyNull outputs the value as null.
+	EmptyNull EmptyMode = "null"
+
+	// EmptyBlank outputs the value as an empty string.
+	EmptyBlank EmptyMode = "empty"
+)
+
+// EmptyFields determines how fields holding nil, empty or zero values are output,
+// so output matches what downstream schemas expect. When a field matches more than
+// one category the most specific applies: Nil, then EmptyString, then Zero.
+type EmptyFields struct {
+	// Nil applies to nil values, such as nil errors, pointers, maps and slices.
+	Nil EmptyMode `json:"nil,omitempty"`
+
+	// EmptyString applies to empty strings.
+	EmptyString EmptyMode `json:"empty_string,omitempty"`
+
+	// Zero applies to zero values: false, zero numbers and durations, the zero time,
+	// and empty binary values, as well as nil values and empty strings when their
+	// own mode is EmptyDefault.
+	Zero EmptyMode `json:"zero,omitempty"`
+}
+
+// CheckValid returns an error if any mode is invalid.
+func (ef EmptyFields) CheckValid() error {
+	for _, mode := range []EmptyMode{ef.Nil, ef.EmptyString, ef.Zero} {
+		switch mode {
+		case EmptyDefault, EmptyOmit, EmptyNull, EmptyBlank:
+		default:
+			return fmt.Errorf("invalid empty field mode %q", mode)
+		}
+	}
+	return nil
+}
+
+// mode returns the mode applying to the field.
+func (ef EmptyFields) mode(field logr.Field) EmptyMode {
+	if ef == (EmptyFields{}) || !field.IsZero() {
+		return EmptyDefault
+	}
+	var mode EmptyMode
+	switch {
+	case field.IsNil():
+		mode = ef.Nil
+	case field.Type == logr.StringType:
+		mode = ef.EmptyString
+	}
+	if mode == EmptyDefault {
+		mode = ef.Zero
+	}
+	return mode
+}
+
+// apply returns the fields with the modes applied, using null to create the
+// field output for EmptyNull. The fields are returned unmodified if no mode is set.
+func (ef EmptyFields) apply(fields []logr.Field, null func(key string) logr.Field) []logr.Field {
+	if ef == (EmptyFields{}) {
+		return fields
+	}
+	out := make([]logr.Field, 0, len(fields))
+	for _, field := range fields {
+		switch ef.mode(field) {
+		case EmptyOmit:
+			continue
+		case EmptyNull:
+			field = null(field.Key)
+		case EmptyBlank:
+			field = logr.String(field.Key, "")
+		}
+		out = append(out, field)
+	}
+	return out
+}
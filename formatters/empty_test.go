@@ -0,0 +1,64 @@
+package formatters_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmptyFields(t *testing.T) {
+	var nilPtr *int
+	fields := []logr.Field{
+		logr.Any("ptr", nilPtr),
+		logr.NamedErr("err", nil),
+		logr.String("str", ""),
+		logr.Int("count", 0),
+		logr.Bool("ok", false),
+		logr.String("name", "bob"),
+		logr.Err(errors.New("boom")),
+	}
+
+	format := func(formatter logr.Formatter) string {
+		lgr, err := logr.New()
+		require.NoError(t, err)
+		buf := &test.Buffer{}
+		err = lgr.AddTarget(targets.NewWriterTarget(buf), "emptyTest", &logr.StdFilter{Lvl: logr.Info}, formatter, 10)
+		require.NoError(t, err)
+		lgr.NewLogger().Info("msg", fields...)
+		require.NoError(t, lgr.Shutdown())
+		return buf.String()
+	}
+
+	t.Run("default", func(t *testing.T) {
+		output := format(&formatters.JSON{DisableTimestamp: true})
+		assert.Equal(t, `{"level":"info","msg":"msg","ptr":"","err":"<nil>","str":"","count":0,"ok":false,"name":"bob","error":"boom"}`+"\n", output)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		output := format(&formatters.JSON{DisableTimestamp: true, EmptyFields: formatters.EmptyFields{
+			Nil:         formatters.EmptyNull,
+			EmptyString: formatters.EmptyOmit,
+			Zero:        formatters.EmptyBlank,
+		}})
+		assert.Equal(t, `{"level":"info","msg":"msg","ptr":null,"err":null,"count":"","ok":"","name":"bob","error":"boom"}`+"\n", output)
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		output := format(&formatters.Plain{DisableTimestamp: true, EmptyFields: formatters.EmptyFields{
+			Nil:  formatters.EmptyNull,
+			Zero: formatters.EmptyOmit,
+		}})
+		assert.Equal(t, "info msg ptr=null err=null name=bob error=boom\n", output)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		formatter := &formatters.JSON{EmptyFields: formatters.EmptyFields{Zero: "drop"}}
+		assert.Error(t, formatter.CheckValid())
+	})
+}
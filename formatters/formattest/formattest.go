@@ -0,0 +1,241 @@
+// Package formattest implements support for testing implementations of
+// logr.Formatter, including formatters provided by third parties.
+package formattest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/wiggin77/merror"
+)
+
+// Goroutines is the number of goroutines concurrently formatting records
+// during the concurrency check.
+const Goroutines = 10
+
+const prefix = "formattest-prefix:"
+
+type testCase struct {
+	name   string
+	level  logr.Level
+	msg    string
+	fields []logr.Field
+}
+
+type nilStringer struct{}
+
+func (*nilStringer) String() string { return "nil stringer" }
+
+var huge = strings.Repeat("0123456789abcdef", 1<<16) // 1MB
+
+func testCases() []testCase {
+	stackLevel := logr.Error
+	stackLevel.Stacktrace = true
+
+	bigMap := make(map[string]int, 1000)
+	bigArray := make([]int, 10000)
+	for i := range bigArray {
+		bigArray[i] = i
+		if i < 1000 {
+			bigMap[fmt.Sprintf("key%d", i)] = i
+		}
+	}
+
+	return []testCase{
+		{name: "empty", level: logr.Info},
+		{name: "empty field", level: logr.Info, msg: "empty field",
+			fields: []logr.Field{logr.String("", ""), logr.String("empty", ""), logr.Any("nil", nil)}},
+		{name: "nil error", level: logr.Warn, msg: "nil error",
+			fields: []logr.Field{logr.Err(nil), logr.NamedErr("cause", nil)}},
+		{name: "nil pointer", level: logr.Info, msg: "nil pointer",
+			fields: []logr.Field{logr.Any("ptr", (*int)(nil)), logr.Stringer("stringer", (*nilStringer)(nil))}},
+		{name: "huge values", level: logr.Info, msg: huge,
+			fields: []logr.Field{logr.String("huge", huge), logr.Array("array", bigArray), logr.Map("map", bigMap)}},
+		{name: "invalid utf-8", level: logr.Info, msg: "invalid \xff\xfe utf-8",
+			fields: []logr.Field{logr.String("key\xc3", "\xed\xa0\x80"), logr.String("truncated", "\xe2\x82")}},
+		{name: "control characters", level: logr.Info, msg: "line1\nline2\ttab\x00nul\x1b[31mcolor\r",
+			fields: []logr.Field{logr.String("ctrl", "a\"b\\c\nd\x7f")}},
+		{name: "all types", level: logr.Debug, msg: "all types",
+			fields: []logr.Field{
+				logr.Int64("int64", math.MinInt64),
+				logr.Uint64("uint64", math.MaxUint64),
+				logr.Float64("nan", math.NaN()),
+				logr.Float64("inf", math.Inf(1)),
+				logr.Float32("float32", math.SmallestNonzeroFloat32),
+				logr.Bool("bool", true),
+				logr.Time("time", time.Time{}),
+				logr.Duration("duration", -time.Hour),
+				logr.Millis("millis", 0),
+				logr.Hex("hex", []byte{0xde, 0xad}),
+				logr.Base64("base64", nil),
+				logr.Any("struct", struct{ A, b int }{1, 2}),
+			}},
+		{name: "stacktrace", level: stackLevel, msg: "stacktrace"},
+		{name: "custom level", level: logr.Level{ID: 1001, Name: "", Color: logr.Magenta}, msg: "custom level"},
+	}
+}
+
+// TestFormatter tests a logr.Formatter implementation. It formats log records
+// exercising corner cases such as empty fields, huge values, invalid UTF-8 and
+// nil errors, and checks that:
+//   - Format does not return an error or panic;
+//   - Format allocates a buffer when passed nil;
+//   - Format appends to a non-empty buffer rather than overwriting it;
+//   - Format and IsStacktraceNeeded can be called concurrently.
+//
+// Run with the race detector enabled to catch unsynchronized access.
+//
+// TestFormatter returns an error describing all failures, or nil if none.
+// Typical usage inside a test is:
+//
+//	if err := formattest.TestFormatter(&MyFormatter{}); err != nil {
+//		t.Fatal(err)
+//	}
+func TestFormatter(formatter logr.Formatter) error {
+	if formatter == nil {
+		return errors.New("formatter is nil")
+	}
+
+	cases := testCases()
+	recs, err := newRecords(cases, formatter.IsStacktraceNeeded())
+	if err != nil {
+		return err
+	}
+
+	errs := merror.New()
+	for i, tc := range cases {
+		checkFormat(formatter, tc, recs[i], errs)
+	}
+	checkConcurrent(formatter, cases, recs, errs)
+	return errs.ErrorOrNil()
+}
+
+func checkFormat(formatter logr.Formatter, tc testCase, rec *logr.LogRec, errs *merror.MError) {
+	buf, err := format(formatter, rec, tc.level, nil)
+	if err != nil {
+		errs.Append(fmt.Errorf("%s: %w", tc.name, err))
+		return
+	}
+	if buf == nil {
+		errs.Append(fmt.Errorf("%s: nil buffer returned for nil buffer", tc.name))
+		return
+	}
+	if buf.Len() == 0 {
+		errs.Append(fmt.Errorf("%s: no output", tc.name))
+	}
+
+	buf, err = format(formatter, rec, tc.level, bytes.NewBufferString(prefix))
+	if err != nil {
+		errs.Append(fmt.Errorf("%s: %w", tc.name, err))
+		return
+	}
+	if buf == nil || !bytes.HasPrefix(buf.Bytes(), []byte(prefix)) {
+		errs.Append(fmt.Errorf("%s: existing buffer contents not preserved", tc.name))
+	}
+}
+
+func checkConcurrent(formatter logr.Formatter, cases []testCase, recs []*logr.LogRec, errs *merror.MError) {
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	for g := 0; g < Goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, tc := range cases {
+				_ = formatter.IsStacktraceNeeded()
+				if _, err := format(formatter, recs[i], tc.level, &bytes.Buffer{}); err != nil {
+					mux.Lock()
+					errs.Append(fmt.Errorf("%s (concurrent): %w", tc.name, err))
+					mux.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// format calls the formatter, converting any panic to an error.
+func format(formatter logr.Formatter, rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (out *bytes.Buffer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return formatter.Format(rec, level, buf)
+}
+
+// newRecords logs each test case through a Logr so the resulting records are
+// prepared exactly as a target would receive them.
+func newRecords(cases []testCase, stacktrace bool) ([]*logr.LogRec, error) {
+	lgr, err := logr.New()
+	if err != nil {
+		return nil, err
+	}
+
+	target := &captureTarget{}
+	err = lgr.AddTarget(target, "formattest", allFilter{}, captureFormatter{stacktrace: stacktrace}, len(cases))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := lgr.NewLogger()
+	for _, tc := range cases {
+		logger.Log(tc.level, tc.msg, tc.fields...)
+	}
+
+	if err := lgr.Shutdown(); err != nil {
+		return nil, err
+	}
+	if len(target.recs) != len(cases) {
+		return nil, fmt.Errorf("expected %d records, got %d", len(cases), len(target.recs))
+	}
+	return target.recs, nil
+}
+
+// allFilter enables every level as-is.
+type allFilter struct{}
+
+func (allFilter) GetEnabledLevel(level logr.Level) (logr.Level, bool) {
+	return level, true
+}
+
+// captureFormatter produces no output; it only requests stack traces when the
+// formatter being tested needs them.
+type captureFormatter struct {
+	stacktrace bool
+}
+
+func (f captureFormatter) IsStacktraceNeeded() bool {
+	return f.stacktrace
+}
+
+func (f captureFormatter) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if buf == nil {
+		buf = &bytes.Buffer{}
+	}
+	return buf, nil
+}
+
+// captureTarget keeps every record written to it.
+type captureTarget struct {
+	recs []*logr.LogRec
+}
+
+func (t *captureTarget) Init() error {
+	return nil
+}
+
+func (t *captureTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	t.recs = append(t.recs, rec)
+	return len(p), nil
+}
+
+func (t *captureTarget) Shutdown() error {
+	return nil
+}
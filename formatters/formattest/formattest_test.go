@@ -0,0 +1,48 @@
+package formattest_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/formatters/formattest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinFormatters(t *testing.T) {
+	tests := map[string]logr.Formatter{
+		"default": &logr.DefaultFormatter{},
+		"plain":   &formatters.Plain{EnableCaller: true, EnableColor: true},
+		"json":    &formatters.JSON{EnableCaller: true},
+		"gelf":    &formatters.Gelf{EnableCaller: true},
+		"chain":   formatters.NewChain(&formatters.JSON{}, &formatters.Gzip{}, &formatters.Base64{}),
+	}
+	for name, formatter := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, formattest.TestFormatter(formatter))
+		})
+	}
+}
+
+// badFormatter overwrites the buffer and fails on records without fields.
+type badFormatter struct{}
+
+func (badFormatter) IsStacktraceNeeded() bool { return false }
+
+func (badFormatter) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if len(rec.Fields()) == 0 {
+		return nil, errors.New("no fields")
+	}
+	return bytes.NewBufferString(rec.Fields()[0].Key), nil
+}
+
+func TestBadFormatter(t *testing.T) {
+	err := formattest.TestFormatter(badFormatter{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "empty: no fields")
+		assert.Contains(t, err.Error(), "existing buffer contents not preserved")
+	}
+	assert.Error(t, formattest.TestFormatter(nil))
+}
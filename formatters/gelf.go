@@ -119,7 +119,7 @@ func (gr gelfRecord) MarshalJSONObject(enc *gojay.Encoder) {
 			if !strings.HasPrefix("_", field.Key) {
 				field.Key = "_" + field.Key
 			}
-			if err := encodeField(enc, field); err != nil {
+			if err := encodeField(enc, field, nil); err != nil {
 				enc.AddStringKey(field.Key, fmt.Sprintf("<error encoding field: %v>", err))
 			}
 		}
@@ -26,17 +26,37 @@ type JSON struct {
 	DisableStacktrace bool `json:"disable_stacktrace"`
 	// EnableCaller enables output of the file and line number that emitted a log record.
 	EnableCaller bool `json:"enable_caller"`
+	// EnableSchemaVersion enables output of the record schema version. See `MigrateJSON`.
+	EnableSchemaVersion bool `json:"enable_schema_version"`
+	// EnableMonotonic enables output of the monotonic timestamp in nanoseconds, when
+	// available. See `logr.MonotonicTime`.
+	EnableMonotonic bool `json:"enable_monotonic"`
 
 	// TimestampFormat is an optional format for timestamps. If empty
 	// then DefTimestampFormat is used.
 	TimestampFormat string `json:"timestamp_format"`
 
+	// TimestampResolution truncates timestamps to seconds, millis, micros or nanos,
+	// adjusting the fractional seconds of the timestamp format to match. Defaults to
+	// the precision of the timestamp format.
+	TimestampResolution TimeResolution `json:"timestamp_resolution"`
+
+	// EpochTimestamp outputs timestamps as an integer count of TimestampResolution
+	// units since the Unix epoch, e.g. millis for CloudWatch or nanos for Loki,
+	// instead of formatted text. Defaults to millis when no resolution is set.
+	EpochTimestamp bool `json:"epoch_timestamp"`
+
 	// KeyTimestamp overrides the timestamp field key name.
 	KeyTimestamp string `json:"key_timestamp"`
 
 	// KeyLevel overrides the level field key name.
 	KeyLevel string `json:"key_level"`
 
+	// LevelNames optionally maps level names to the value output for the level field,
+	// e.g. to output the severities expected by a log aggregator. Levels not in the
+	// map are output using the level name.
+	LevelNames map[string]string `json:"level_names"`
+
 	// KeyMsg overrides the msg field key name.
 	KeyMsg string `json:"key_msg"`
 
@@ -44,12 +64,44 @@ type JSON struct {
 	// under this key.
 	KeyGroupFields string `json:"key_group_fields"`
 
-	// KeyStacktrace overrides the stacktrace field key name.
+	// KeyStacktrace overrides the stacktrace field key name. The stack trace is
+	// output as an array of frame objects, each with function, file and line keys.
 	KeyStacktrace string `json:"key_stacktrace"`
 
+	// KeyFrameFunction, KeyFrameFile and KeyFrameLine override the key names of each
+	// stack frame object, e.g. to match what a log backend indexes. Default to
+	// "Function", "File" and "Line".
+	KeyFrameFunction string `json:"key_frame_function"`
+	KeyFrameFile     string `json:"key_frame_file"`
+	KeyFrameLine     string `json:"key_frame_line"`
+
 	// KeyCaller overrides the caller field key name.
 	KeyCaller string `json:"key_caller"`
 
+	// KeySchemaVersion overrides the schema version field key name.
+	KeySchemaVersion string `json:"key_schema_version"`
+
+	// KeyMonotonic overrides the monotonic timestamp field key name.
+	KeyMonotonic string `json:"key_monotonic"`
+
+	// IntegersAsStrings determines when integer fields are output as strings, e.g.
+	// only beyond ±(2^53-1) to avoid precision loss in JavaScript consumers.
+	// Defaults to IntStringNever.
+	IntegersAsStrings IntStringMode `json:"integers_as_strings"`
+
+	// FloatPrecision, when greater than zero, is the number of digits output after
+	// the decimal point for float fields. Defaults to the fewest digits needed to
+	// represent the value exactly.
+	FloatPrecision int `json:"float_precision"`
+
+	// NonFiniteFloats determines how NaN and infinite float fields are output.
+	// Defaults to NonFiniteString.
+	NonFiniteFloats NonFiniteMode `json:"non_finite_floats"`
+
+	// EmptyFields determines how fields holding nil, empty or zero values are output,
+	// e.g. omitted or as null. Defaults to outputting them as is.
+	EmptyFields EmptyFields `json:"empty_fields"`
+
 	// FieldSorter allows custom sorting of the fields. If nil then
 	// no sorting is done.
 	FieldSorter func(fields []logr.Field) []logr.Field `json:"-"`
@@ -58,7 +110,13 @@ type JSON struct {
 }
 
 func (j *JSON) CheckValid() error {
-	return nil
+	if err := j.checkNumbers(); err != nil {
+		return err
+	}
+	if err := j.TimestampResolution.CheckValid(); err != nil {
+		return err
+	}
+	return j.EmptyFields.CheckValid()
 }
 
 // IsStacktraceNeeded returns true if a stacktrace is needed so we can output the `Caller` field.
@@ -106,9 +164,24 @@ func (j *JSON) applyDefaultKeyNames() {
 	if j.KeyStacktrace == "" {
 		j.KeyStacktrace = "stacktrace"
 	}
+	if j.KeyFrameFunction == "" {
+		j.KeyFrameFunction = "Function"
+	}
+	if j.KeyFrameFile == "" {
+		j.KeyFrameFile = "File"
+	}
+	if j.KeyFrameLine == "" {
+		j.KeyFrameLine = "Line"
+	}
 	if j.KeyCaller == "" {
 		j.KeyCaller = "caller"
 	}
+	if j.KeySchemaVersion == "" {
+		j.KeySchemaVersion = DefaultKeySchemaVersion
+	}
+	if j.KeyMonotonic == "" {
+		j.KeyMonotonic = "mono"
+	}
 }
 
 // JSONLogRec decorates a LogRec adding JSON encoding.
@@ -121,16 +194,32 @@ type JSONLogRec struct {
 
 // MarshalJSONObject encodes the LogRec as JSON.
 func (jlr JSONLogRec) MarshalJSONObject(enc *gojay.Encoder) {
+	if jlr.EnableSchemaVersion {
+		enc.AddIntKey(jlr.KeySchemaVersion, JSONSchemaVersion)
+	}
 	if !jlr.DisableTimestamp {
-		timestampFmt := jlr.TimestampFormat
-		if timestampFmt == "" {
-			timestampFmt = logr.DefTimestampFormat
+		if jlr.EpochTimestamp {
+			enc.AddInt64Key(jlr.KeyTimestamp, jlr.TimestampResolution.epoch(jlr.Time()))
+		} else {
+			timestampFmt := jlr.TimestampFormat
+			if timestampFmt == "" {
+				timestampFmt = logr.DefTimestampFormat
+			}
+			time, layout := jlr.TimestampResolution.apply(jlr.Time(), timestampFmt)
+			enc.AddTimeKey(jlr.KeyTimestamp, &time, layout)
+		}
+	}
+	if jlr.EnableMonotonic {
+		if mono, ok := jlr.Monotonic(); ok {
+			enc.AddInt64Key(jlr.KeyMonotonic, int64(mono))
 		}
-		time := jlr.Time()
-		enc.AddTimeKey(jlr.KeyTimestamp, &time, timestampFmt)
 	}
 	if !jlr.DisableLevel {
-		enc.AddStringKey(jlr.KeyLevel, jlr.level.Name)
+		name := jlr.level.JSONName()
+		if mapped, ok := jlr.LevelNames[jlr.level.Name]; ok {
+			name = mapped
+		}
+		enc.AddStringKey(jlr.KeyLevel, name)
 	}
 	if !jlr.DisableMsg {
 		enc.AddStringKey(jlr.KeyMsg, jlr.Msg())
@@ -139,17 +228,17 @@ func (jlr JSONLogRec) MarshalJSONObject(enc *gojay.Encoder) {
 		enc.AddStringKey(jlr.KeyCaller, jlr.Caller())
 	}
 	if !jlr.DisableFields {
-		fields := jlr.Fields()
+		fields := jlr.EmptyFields.apply(jlr.Fields(), jsonNull)
 		if jlr.sorter != nil {
 			fields = jlr.sorter(fields)
 		}
 		if jlr.KeyGroupFields != "" {
-			enc.AddObjectKey(jlr.KeyGroupFields, FieldArray(fields))
+			enc.AddObjectKey(jlr.KeyGroupFields, fieldGroup{fields: fields, j: jlr.JSON})
 		} else {
 			if len(fields) > 0 {
 				for _, field := range fields {
 					field = jlr.prefixCollision(field)
-					if err := encodeField(enc, field); err != nil {
+					if err := encodeField(enc, field, jlr.JSON); err != nil {
 						enc.AddStringKey(field.Key, "<error encoding field: "+err.Error()+">")
 					}
 				}
@@ -159,7 +248,7 @@ func (jlr JSONLogRec) MarshalJSONObject(enc *gojay.Encoder) {
 	if jlr.level.Stacktrace && !jlr.DisableStacktrace {
 		frames := jlr.StackFrames()
 		if len(frames) > 0 {
-			enc.AddArrayKey(jlr.KeyStacktrace, stackFrames(frames))
+			enc.AddArrayKey(jlr.KeyStacktrace, stackFrames{frames: frames, j: jlr.JSON})
 		}
 	}
 }
@@ -176,30 +265,46 @@ func (rec JSONLogRec) prefixCollision(field logr.Field) logr.Field {
 		f.Key = "_" + field.Key
 		return rec.prefixCollision(f)
 	}
+	if rec.EnableSchemaVersion && field.Key == rec.KeySchemaVersion {
+		f := field
+		f.Key = "_" + field.Key
+		return rec.prefixCollision(f)
+	}
 	return field
 }
 
-type stackFrames []runtime.Frame
+// jsonNull returns a field output as a JSON null.
+func jsonNull(key string) logr.Field {
+	return logr.Field{Key: key, Type: logr.UnknownType}
+}
+
+type stackFrames struct {
+	frames []runtime.Frame
+	j      *JSON
+}
 
 // MarshalJSONArray encodes stackFrames slice as JSON.
 func (s stackFrames) MarshalJSONArray(enc *gojay.Encoder) {
-	for _, frame := range s {
-		enc.AddObject(stackFrame(frame))
+	for _, frame := range s.frames {
+		enc.AddObject(stackFrame{frame: frame, j: s.j})
 	}
 }
 
 // IsNil returns true if stackFrames is empty slice.
 func (s stackFrames) IsNil() bool {
-	return len(s) == 0
+	return len(s.frames) == 0
 }
 
-type stackFrame runtime.Frame
+type stackFrame struct {
+	frame runtime.Frame
+	j     *JSON
+}
 
-// MarshalJSONArray encodes stackFrame as JSON.
+// MarshalJSONObject encodes stackFrame as JSON.
 func (f stackFrame) MarshalJSONObject(enc *gojay.Encoder) {
-	enc.AddStringKey("Function", f.Function)
-	enc.AddStringKey("File", f.File)
-	enc.AddIntKey("Line", f.Line)
+	enc.AddStringKey(f.j.KeyFrameFunction, f.frame.Function)
+	enc.AddStringKey(f.j.KeyFrameFile, f.frame.File)
+	enc.AddIntKey(f.j.KeyFrameLine, f.frame.Line)
 }
 
 func (f stackFrame) IsNil() bool {
@@ -210,11 +315,7 @@ type FieldArray []logr.Field
 
 // MarshalJSONObject encodes Fields map to JSON.
 func (fa FieldArray) MarshalJSONObject(enc *gojay.Encoder) {
-	for _, fld := range fa {
-		if err := encodeField(enc, fld); err != nil {
-			enc.AddStringKey(fld.Key, "<error encoding field: "+err.Error()+">")
-		}
-	}
+	fieldGroup{fields: fa}.MarshalJSONObject(enc)
 }
 
 // IsNil returns true if map is nil.
@@ -222,7 +323,33 @@ func (fa FieldArray) IsNil() bool {
 	return fa == nil
 }
 
-func encodeField(enc *gojay.Encoder, field logr.Field) error {
+// fieldGroup encodes fields grouped under a key using the options of j, if any.
+type fieldGroup struct {
+	fields []logr.Field
+	j      *JSON
+}
+
+// MarshalJSONObject encodes the fields to JSON.
+func (fg fieldGroup) MarshalJSONObject(enc *gojay.Encoder) {
+	for _, fld := range fg.fields {
+		if err := encodeField(enc, fld, fg.j); err != nil {
+			enc.AddStringKey(fld.Key, "<error encoding field: "+err.Error()+">")
+		}
+	}
+}
+
+// IsNil returns true if there are no fields.
+func (fg fieldGroup) IsNil() bool {
+	return fg.fields == nil
+}
+
+// encodeField encodes a field, using the number options of j if not nil.
+func encodeField(enc *gojay.Encoder, field logr.Field, j *JSON) error {
+	if fields, ok := field.EncodedFields(); ok {
+		enc.AddObjectKey(field.Key, fieldGroup{fields: fields, j: j})
+		return nil
+	}
+
 	// first check if the value has a marshaller already.
 	switch vt := field.Interface.(type) {
 	case gojay.MarshalerJSONObject:
@@ -258,13 +385,13 @@ func encodeField(enc *gojay.Encoder, field logr.Field) error {
 		enc.AddStringKey(field.Key, buf.String())
 
 	case logr.Int64Type, logr.Int32Type, logr.IntType:
-		enc.AddInt64Key(field.Key, field.Integer)
+		encodeInt(enc, field.Key, field.Integer, j)
 
 	case logr.Uint64Type, logr.Uint32Type, logr.UintType:
-		enc.AddUint64Key(field.Key, uint64(field.Integer))
+		encodeUint(enc, field.Key, uint64(field.Integer), j)
 
 	case logr.Float64Type, logr.Float32Type:
-		enc.AddFloat64Key(field.Key, field.Float)
+		encodeFloat(enc, field.Key, field.Float, j)
 
 	default:
 		return fmt.Errorf("invalid field type: %d", field.Type)
@@ -1,7 +1,10 @@
 package formatters_test
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"regexp"
 	"sort"
 	"strings"
@@ -91,6 +94,54 @@ func TestJSONFieldTypes(t *testing.T) {
 		}
 	})
 
+	t.Run("binary hints", func(t *testing.T) {
+		buf := &test.Buffer{}
+		target := targets.NewWriterTarget(buf)
+		err := lgr.AddTarget(target, "binaryTest", filter, formatter, 1000)
+		if err != nil {
+			t.Error(err)
+		}
+
+		logger := lgr.NewLogger()
+
+		b := []byte{0xde, 0xad, 0xbe, 0xef}
+		logger.Error("Binary hints test", logr.Hex("f1", b), logr.Base64("f2", b))
+		err = lgr.Flush()
+		require.NoError(t, err)
+
+		want := NL(`{"level":"error","msg":"Binary hints test","f1":"deadbeef","f2":"3q2+7w=="}`)
+
+		if strings.Compare(want, buf.String()) != 0 {
+			t.Errorf("JSON does not match: expected %s   got %s", want, buf.String())
+		}
+	})
+
+	t.Run("human types", func(t *testing.T) {
+		buf := &test.Buffer{}
+		target := targets.NewWriterTarget(buf)
+		err := lgr.AddTarget(target, "humanTest", filter, formatter, 1000)
+		if err != nil {
+			t.Error(err)
+		}
+
+		logger := lgr.NewLogger()
+
+		logger.Error("Human types test",
+			logr.Bytes("f1", 1468006),
+			logr.DurationHuman("f2", time.Second*90),
+			logr.Percent("f3", 0.425),
+		)
+		err = lgr.Flush()
+		require.NoError(t, err)
+
+		// raw values are output rather than the human friendly rendering.
+		want := NL(`{"level":"error","msg":"Human types test","f1":1468006,"f2":90000000000,"f3":0.425}`)
+
+		if strings.Compare(want, buf.String()) != 0 {
+			t.Errorf("JSON does not match: expected %s   got %s", want, buf.String())
+		}
+	})
+
 	t.Run("struct types", func(t *testing.T) {
 		buf := &test.Buffer{}
 		target := targets.NewWriterTarget(buf)
@@ -389,3 +440,189 @@ func reverseSorter(fields []logr.Field) []logr.Field {
 func NL(s string) string {
 	return s + "\n"
 }
+
+func TestJSONSchemaVersion(t *testing.T) {
+	lgr, _ := logr.New()
+	filter := &logr.StdFilter{Lvl: logr.Error}
+	formatter := &formatters.JSON{
+		DisableTimestamp:    true,
+		EnableSchemaVersion: true,
+	}
+
+	buf := &test.Buffer{}
+	target := targets.NewWriterTarget(buf)
+	err := lgr.AddTarget(target, "schemaTest", filter, formatter, 1000)
+	require.NoError(t, err)
+
+	lgr.NewLogger().Error("This is an error.", logr.String("schema", "collides"))
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	want := NL(`{"schema":1,"level":"error","msg":"This is an error.","_schema":"collides"}`)
+	assert.Equal(t, want, buf.String())
+
+	t.Run("migrate current", func(t *testing.T) {
+		rec, err := formatters.MigrateJSON(buf.Bytes(), "")
+		require.NoError(t, err)
+		assert.Equal(t, "1", fmt.Sprint(rec["schema"]))
+		assert.Equal(t, "This is an error.", rec["msg"])
+		assert.Equal(t, "collides", rec["_schema"])
+	})
+
+	t.Run("migrate unversioned", func(t *testing.T) {
+		rec, err := formatters.MigrateJSON([]byte(`{"level":"info","msg":"old","count":9007199254740993}`), "")
+		require.NoError(t, err)
+		assert.Equal(t, "1", fmt.Sprint(rec["schema"]))
+		assert.Equal(t, "9007199254740993", fmt.Sprint(rec["count"]))
+	})
+
+	t.Run("migrate unversioned with schema field", func(t *testing.T) {
+		for _, v := range []string{`"v2"`, `7`} {
+			rec, err := formatters.MigrateJSON([]byte(`{"level":"info","msg":"old","schema":`+v+`,"_schema":"x"}`), "")
+			require.NoError(t, err)
+			assert.Equal(t, "1", fmt.Sprint(rec["schema"]))
+			assert.Equal(t, strings.Trim(v, `"`), fmt.Sprint(rec["__schema"]))
+			assert.Equal(t, "x", rec["_schema"])
+		}
+	})
+
+	t.Run("migrate newer", func(t *testing.T) {
+		_, err := formatters.MigrateJSON([]byte(`{"schema":99,"msg":"future"}`), "")
+		assert.Error(t, err)
+	})
+}
+
+func TestJSONMonotonic(t *testing.T) {
+	lgr, _ := logr.New(logr.MonotonicTime(0, ""))
+	filter := &logr.StdFilter{Lvl: logr.Error}
+	formatter := &formatters.JSON{
+		DisableTimestamp: true,
+		EnableMonotonic:  true,
+	}
+
+	buf := &test.Buffer{}
+	target := targets.NewWriterTarget(buf)
+	err := lgr.AddTarget(target, "monoTest", filter, formatter, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Error("first")
+	logger.LogWithTime(time.Now().Round(0), logr.Error, "second")
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var rec map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	mono, ok := rec["mono"].(float64)
+	require.True(t, ok, lines[0])
+	assert.True(t, mono > 0)
+
+	// no monotonic timestamp is available for records with an explicit time.
+	assert.Equal(t, `{"level":"error","msg":"second"}`, lines[1])
+}
+
+func TestJSONStacktraceFrames(t *testing.T) {
+	lgr, _ := logr.New()
+	filter := &logr.StdFilter{Lvl: logr.Error, Stacktrace: logr.Error}
+	formatter := &formatters.JSON{
+		DisableTimestamp: true,
+		KeyStacktrace:    "error.stack_trace",
+		KeyFrameFunction: "function",
+		KeyFrameFile:     "file",
+		KeyFrameLine:     "line",
+	}
+
+	buf := &test.Buffer{}
+	target := targets.NewWriterTarget(buf)
+	err := lgr.AddTarget(target, "framesTest", filter, formatter, 1000)
+	require.NoError(t, err)
+
+	lgr.NewLogger().Error("with stack")
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	var rec struct {
+		Frames []struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+		} `json:"error.stack_trace"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec), buf.String())
+	require.NotEmpty(t, rec.Frames)
+	assert.Contains(t, rec.Frames[0].Function, "TestJSONStacktraceFrames")
+	assert.True(t, strings.HasSuffix(rec.Frames[0].File, "json_test.go"))
+	assert.True(t, rec.Frames[0].Line > 0)
+}
+
+func TestJSONNumbers(t *testing.T) {
+	format := func(formatter *formatters.JSON) string {
+		lgr, _ := logr.New()
+		buf := &test.Buffer{}
+		err := lgr.AddTarget(targets.NewWriterTarget(buf), "numbersTest", &logr.StdFilter{Lvl: logr.Info}, formatter, 10)
+		require.NoError(t, err)
+		lgr.NewLogger().Info("numbers",
+			logr.Int64("small", 42),
+			logr.Int64("big", 1<<60),
+			logr.Uint64("ubig", 1<<63),
+			logr.Float64("pi", 3.14159),
+			logr.Float64("nan", math.NaN()),
+			logr.Float64("inf", math.Inf(-1)),
+		)
+		require.NoError(t, lgr.Shutdown())
+		return buf.String()
+	}
+
+	output := format(&formatters.JSON{DisableTimestamp: true})
+	assert.Equal(t, NL(`{"level":"info","msg":"numbers","small":42,"big":1152921504606846976,"ubig":9223372036854775808,"pi":3.14159,"nan":"NaN","inf":"-Inf"}`), output)
+
+	output = format(&formatters.JSON{
+		DisableTimestamp:  true,
+		IntegersAsStrings: formatters.IntStringLarge,
+		FloatPrecision:    2,
+		NonFiniteFloats:   formatters.NonFiniteNull,
+	})
+	assert.Equal(t, NL(`{"level":"info","msg":"numbers","small":42,"big":"1152921504606846976","ubig":"9223372036854775808","pi":3.14,"nan":null,"inf":null}`), output)
+
+	output = format(&formatters.JSON{DisableTimestamp: true, IntegersAsStrings: formatters.IntStringAlways, KeyGroupFields: "f"})
+	assert.Contains(t, output, `"f":{"small":"42","big":"1152921504606846976"`)
+
+	assert.Error(t, (&formatters.JSON{IntegersAsStrings: "sometimes"}).CheckValid())
+	assert.Error(t, (&formatters.JSON{FloatPrecision: -1}).CheckValid())
+}
+
+func TestTimestampResolution(t *testing.T) {
+	ts := time.Date(2021, 3, 4, 10, 11, 12, 123456789, time.UTC)
+	format := func(formatter logr.Formatter) string {
+		lgr, _ := logr.New()
+		buf := &test.Buffer{}
+		err := lgr.AddTarget(targets.NewWriterTarget(buf), "resTest", &logr.StdFilter{Lvl: logr.Info}, formatter, 10)
+		require.NoError(t, err)
+		lgr.NewLogger().LogWithTime(ts, logr.Info, "msg")
+		require.NoError(t, lgr.Shutdown())
+		return buf.String()
+	}
+
+	tests := []struct {
+		res   formatters.TimeResolution
+		plain string
+		json  string
+		epoch string
+	}{
+		{formatters.TimeResolutionDefault, "[2021-03-04 10:11:12.123 Z]", `"timestamp":"2021-03-04T10:11:12.123456789Z"`, `"timestamp":1614852672123`},
+		{formatters.TimeResolutionSeconds, "[2021-03-04 10:11:12 Z]", `"timestamp":"2021-03-04T10:11:12Z"`, `"timestamp":1614852672,`},
+		{formatters.TimeResolutionMillis, "[2021-03-04 10:11:12.123 Z]", `"timestamp":"2021-03-04T10:11:12.123Z"`, `"timestamp":1614852672123,`},
+		{formatters.TimeResolutionMicros, "[2021-03-04 10:11:12.123456 Z]", `"timestamp":"2021-03-04T10:11:12.123456Z"`, `"timestamp":1614852672123456,`},
+		{formatters.TimeResolutionNanos, "[2021-03-04 10:11:12.123456789 Z]", `"timestamp":"2021-03-04T10:11:12.123456789Z"`, `"timestamp":1614852672123456789,`},
+	}
+	for _, tt := range tests {
+		assert.Contains(t, format(&formatters.Plain{TimestampResolution: tt.res}), tt.plain)
+		assert.Contains(t, format(&formatters.JSON{TimestampResolution: tt.res, TimestampFormat: time.RFC3339Nano}), tt.json)
+		assert.Contains(t, format(&formatters.JSON{TimestampResolution: tt.res, EpochTimestamp: true}), tt.epoch)
+	}
+
+	assert.Error(t, (&formatters.Plain{TimestampResolution: "minutes"}).CheckValid())
+}
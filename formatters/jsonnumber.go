@@ -0,0 +1,100 @@
+package formatters
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/francoispqt/gojay"
+)
+
+// maxSafeInteger is the largest integer a JSON number can hold without losing
+// precision when parsed as an IEEE 754 double, as JavaScript does.
+const maxSafeInteger = 1<<53 - 1
+
+// IntStringMode determines when the JSON formatter outputs integers as strings.
+// See `JSON.IntegersAsStrings`.
+type IntStringMode string
+
+const (
+	// IntStringNever outputs all integers as numbers.
+	IntStringNever IntStringMode = ""
+
+	// IntStringLarge outputs integers beyond ±(2^53-1) as strings, since JavaScript
+	// and other consumers parsing numbers as doubles would lose precision.
+	IntStringLarge IntStringMode = "large"
+
+	// IntStringAlways outputs all integers as strings.
+	IntStringAlways IntStringMode = "always"
+)
+
+// NonFiniteMode determines how the JSON formatter outputs NaN and infinite floats,
+// which JSON numbers cannot represent. See `JSON.NonFiniteFloats`.
+type NonFiniteMode string
+
+const (
+	// NonFiniteString outputs the strings "NaN", "+Inf" and "-Inf".
+	NonFiniteString NonFiniteMode = ""
+
+	// NonFiniteNull outputs null.
+	NonFiniteNull NonFiniteMode = "null"
+)
+
+// checkNumbers returns an error if the number options are invalid.
+func (j *JSON) checkNumbers() error {
+	switch j.IntegersAsStrings {
+	case IntStringNever, IntStringLarge, IntStringAlways:
+	default:
+		return fmt.Errorf("invalid integers_as_strings %q", j.IntegersAsStrings)
+	}
+	switch j.NonFiniteFloats {
+	case NonFiniteString, NonFiniteNull:
+	default:
+		return fmt.Errorf("invalid non_finite_floats %q", j.NonFiniteFloats)
+	}
+	if j.FloatPrecision < 0 {
+		return fmt.Errorf("float_precision cannot be negative")
+	}
+	return nil
+}
+
+// encodeInt adds a signed integer, as a string if required by j.
+func encodeInt(enc *gojay.Encoder, key string, n int64, j *JSON) {
+	if j != nil && (j.IntegersAsStrings == IntStringAlways ||
+		(j.IntegersAsStrings == IntStringLarge && (n > maxSafeInteger || n < -maxSafeInteger))) {
+		enc.AddStringKey(key, strconv.FormatInt(n, 10))
+		return
+	}
+	enc.AddInt64Key(key, n)
+}
+
+// encodeUint adds an unsigned integer, as a string if required by j.
+func encodeUint(enc *gojay.Encoder, key string, n uint64, j *JSON) {
+	if j != nil && (j.IntegersAsStrings == IntStringAlways ||
+		(j.IntegersAsStrings == IntStringLarge && n > maxSafeInteger)) {
+		enc.AddStringKey(key, strconv.FormatUint(n, 10))
+		return
+	}
+	enc.AddUint64Key(key, n)
+}
+
+// encodeFloat adds a float with the precision set by j. NaN and infinite floats are
+// output as set by j, defaulting to strings since bare NaN or Inf is invalid JSON.
+func encodeFloat(enc *gojay.Encoder, key string, f float64, j *JSON) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if j != nil && j.NonFiniteFloats == NonFiniteNull {
+			enc.AddEmbeddedJSONKey(key, &jsonNullEmbed)
+			return
+		}
+		enc.AddStringKey(key, strconv.FormatFloat(f, 'g', -1, 64))
+		return
+	}
+	if j != nil && j.FloatPrecision > 0 {
+		embed := gojay.EmbeddedJSON(strconv.AppendFloat(nil, f, 'f', j.FloatPrecision, 64))
+		enc.AddEmbeddedJSONKey(key, &embed)
+		return
+	}
+	enc.AddFloat64Key(key, f)
+}
+
+var jsonNullEmbed = gojay.EmbeddedJSON("null")
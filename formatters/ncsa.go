@@ -0,0 +1,171 @@
+package formatters
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// NCSATimestampFormat is the timestamp format of NCSA common and combined log lines.
+const NCSATimestampFormat = "02/Jan/2006:15:04:05 -0700"
+
+// NCSA formats log records carrying HTTP request fields as NCSA combined (or common)
+// log format lines, as written by Apache and nginx, so they can be processed by
+// existing access log tooling. For example:
+//
+//	10.0.0.1 - bob [04/Mar/2021:10:59:00 +0000] "GET /index.html HTTP/1.1" 200 2326 "https://example.com/" "Mozilla/5.0"
+//
+// The default field keys match those logged by `middleware.AccessLog`. Missing
+// values are output as "-". Records without request fields are output as lines of
+// "-", so targets using this formatter should filter for access log records.
+type NCSA struct {
+	// Common outputs the NCSA common log format, without the referer and user agent.
+	Common bool `json:"common"`
+
+	// KeyRemoteAddr overrides the remote address field key name.
+	KeyRemoteAddr string `json:"key_remote_addr"`
+	// KeyUser overrides the authenticated user field key name.
+	KeyUser string `json:"key_user"`
+	// KeyMethod overrides the request method field key name.
+	KeyMethod string `json:"key_method"`
+	// KeyPath overrides the request URI field key name.
+	KeyPath string `json:"key_path"`
+	// KeyProto overrides the protocol field key name.
+	KeyProto string `json:"key_proto"`
+	// KeyStatus overrides the response status field key name.
+	KeyStatus string `json:"key_status"`
+	// KeySize overrides the response size field key name.
+	KeySize string `json:"key_size"`
+	// KeyReferer overrides the referer field key name.
+	KeyReferer string `json:"key_referer"`
+	// KeyUserAgent overrides the user agent field key name.
+	KeyUserAgent string `json:"key_user_agent"`
+
+	// LineEnd sets the end of line character(s). Defaults to '\n'.
+	LineEnd string `json:"line_end"`
+
+	once sync.Once
+}
+
+// CheckValid returns an error if the formatter options are invalid.
+func (n *NCSA) CheckValid() error {
+	return nil
+}
+
+// IsStacktraceNeeded returns false; the NCSA formatter does not output stack traces.
+func (n *NCSA) IsStacktraceNeeded() bool {
+	return false
+}
+
+func (n *NCSA) applyDefaultKeyNames() {
+	defaults := []struct {
+		key *string
+		def string
+	}{
+		{&n.KeyRemoteAddr, "remote_addr"},
+		{&n.KeyUser, "user"},
+		{&n.KeyMethod, "method"},
+		{&n.KeyPath, "path"},
+		{&n.KeyProto, "proto"},
+		{&n.KeyStatus, "status"},
+		{&n.KeySize, "size"},
+		{&n.KeyReferer, "referer"},
+		{&n.KeyUserAgent, "user_agent"},
+	}
+	for _, d := range defaults {
+		if *d.key == "" {
+			*d.key = d.def
+		}
+	}
+}
+
+// Format converts a log record to an NCSA log line.
+func (n *NCSA) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if buf == nil {
+		buf = &bytes.Buffer{}
+	}
+	n.once.Do(n.applyDefaultKeyNames)
+
+	values := make(map[string]string, 9)
+	for _, f := range rec.Fields() {
+		if _, ok := values[f.Key]; ok {
+			continue
+		}
+		if f.Type == logr.StringType {
+			values[f.Key] = f.String
+			continue
+		}
+		var sb bytes.Buffer
+		if err := f.ValueString(&sb, nil); err == nil {
+			values[f.Key] = sb.String()
+		}
+	}
+	value := func(key string) string {
+		if v := values[key]; v != "" {
+			return v
+		}
+		return "-"
+	}
+
+	buf.WriteString(value(n.KeyRemoteAddr))
+	buf.WriteString(" - ")
+	buf.WriteString(value(n.KeyUser))
+	buf.WriteString(" [")
+	buf.WriteString(rec.Time().Format(NCSATimestampFormat))
+	buf.WriteString("] ")
+
+	method, path := values[n.KeyMethod], values[n.KeyPath]
+	if method == "" && path == "" {
+		buf.WriteString(`"-"`)
+	} else {
+		request := method + " " + path
+		if proto := values[n.KeyProto]; proto != "" {
+			request += " " + proto
+		}
+		writeNCSAQuoted(buf, request)
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(value(n.KeyStatus))
+	buf.WriteByte(' ')
+	if size := values[n.KeySize]; size != "" && size != "0" {
+		buf.WriteString(size)
+	} else {
+		buf.WriteByte('-')
+	}
+
+	if !n.Common {
+		buf.WriteByte(' ')
+		writeNCSAQuoted(buf, value(n.KeyReferer))
+		buf.WriteByte(' ')
+		writeNCSAQuoted(buf, value(n.KeyUserAgent))
+	}
+
+	if n.LineEnd == "" {
+		buf.WriteByte('\n')
+	} else {
+		buf.WriteString(n.LineEnd)
+	}
+	return buf, nil
+}
+
+// writeNCSAQuoted outputs a quoted string, escaping quotes, backslashes and
+// control characters as Apache does.
+func writeNCSAQuoted(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			buf.WriteString(fmt.Sprintf("\\x%02x", c))
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,38 @@
+package formatters_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNCSA(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 59, 0, 0, time.FixedZone("", -7*3600))
+	lgr, err := logr.New(logr.Clock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	common := &test.Buffer{}
+	combined := &test.Buffer{}
+	filter := &logr.StdFilter{Lvl: logr.Info}
+	require.NoError(t, lgr.AddTarget(targets.NewWriterTarget(common), "common", filter,
+		&formatters.NCSA{Common: true, KeyRemoteAddr: "ip"}, 100))
+	require.NoError(t, lgr.AddTarget(targets.NewWriterTarget(combined), "combined", filter,
+		&formatters.NCSA{KeyRemoteAddr: "ip"}, 100))
+
+	logger := lgr.NewLogger()
+	logger.Info("request", logr.String("ip", "10.0.0.1"), logr.String("method", "POST"), logr.String("path", "/upload"),
+		logr.String("proto", "HTTP/1.0"), logr.Int("status", 201), logr.Int("size", 0), logr.String("user_agent", "curl\n"))
+	logger.Info("not a request")
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, `10.0.0.1 - - [04/Mar/2021:10:59:00 -0700] "POST /upload HTTP/1.0" 201 -`+"\n"+
+		`- - - [04/Mar/2021:10:59:00 -0700] "-" - -`+"\n", common.String())
+	assert.Equal(t, `10.0.0.1 - - [04/Mar/2021:10:59:00 -0700] "POST /upload HTTP/1.0" 201 - "-" "curl\x0a"`+"\n"+
+		`- - - [04/Mar/2021:10:59:00 -0700] "-" - - "-" "-"`+"\n", combined.String())
+}
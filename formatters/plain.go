@@ -23,6 +23,9 @@ type Plain struct {
 	DisableStacktrace bool `json:"disable_stacktrace"`
 	// EnableCaller enables output of the file and line number that emitted a log record.
 	EnableCaller bool `json:"enable_caller"`
+	// EnableMonotonic enables output of the monotonic timestamp as a `mono` field, when
+	// available. See `logr.MonotonicTime`.
+	EnableMonotonic bool `json:"enable_monotonic"`
 
 	// Delim is an optional delimiter output between each log field.
 	// Defaults to a single space.
@@ -40,18 +43,30 @@ type Plain struct {
 	// then DefTimestampFormat is used.
 	TimestampFormat string `json:"timestamp_format"`
 
+	// TimestampResolution truncates timestamps to seconds, millis, micros or nanos,
+	// adjusting the fractional seconds of the timestamp format to match. Defaults to
+	// the precision of the timestamp format.
+	TimestampResolution TimeResolution `json:"timestamp_resolution"`
+
 	// LineEnd sets the end of line character(s). Defaults to '\n'.
 	LineEnd string `json:"line_end"`
 
 	// EnableColor sets whether output should include color.
 	EnableColor bool `json:"enable_color"`
+
+	// EmptyFields determines how fields holding nil, empty or zero values are output,
+	// e.g. omitted or as `key=null`. Defaults to outputting them as is.
+	EmptyFields EmptyFields `json:"empty_fields"`
 }
 
 func (p *Plain) CheckValid() error {
 	if p.MinMessageLen < 0 || p.MinMessageLen > 1024 {
 		return fmt.Errorf("min_msg_len is invalid(%d)", p.MinMessageLen)
 	}
-	return nil
+	if err := p.TimestampResolution.CheckValid(); err != nil {
+		return err
+	}
+	return p.EmptyFields.CheckValid()
 }
 
 // IsStacktraceNeeded returns true if a stacktrace is needed so we can output the `Caller` field.
@@ -76,12 +91,13 @@ func (p *Plain) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*
 
 	color := logr.NoColor
 	if p.EnableColor {
-		color = level.Color
+		color = level.DisplayColor()
 	}
 
 	if !p.DisableLevel {
-		_ = logr.WriteWithColor(buf, level.Name, color)
-		count := len(level.Name)
+		name := level.DisplayName()
+		_ = logr.WriteWithColor(buf, name, color)
+		count := len(name)
 		if p.MinLevelLen > count {
 			_, _ = buf.WriteString(strings.Repeat(" ", p.MinLevelLen-count))
 		}
@@ -90,7 +106,8 @@ func (p *Plain) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*
 
 	if !p.DisableTimestamp {
 		var arr [128]byte
-		tbuf := rec.Time().AppendFormat(arr[:0], timestampFmt)
+		t, layout := p.TimestampResolution.apply(rec.Time(), timestampFmt)
+		tbuf := t.AppendFormat(arr[:0], layout)
 		buf.WriteByte('[')
 		buf.Write(tbuf)
 		buf.WriteByte(']')
@@ -116,8 +133,14 @@ func (p *Plain) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*
 		fields = append(fields, fld)
 	}
 
+	if p.EnableMonotonic {
+		if mono, ok := rec.Monotonic(); ok {
+			fields = append(fields, logr.Duration("mono", mono))
+		}
+	}
+
 	if !p.DisableFields {
-		fields = append(fields, rec.Fields()...)
+		fields = append(fields, p.EmptyFields.apply(rec.Fields(), plainNull)...)
 	}
 
 	if len(fields) > 0 {
@@ -144,3 +167,8 @@ func (p *Plain) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*
 
 	return buf, nil
 }
+
+// plainNull returns a field output as `key=null`.
+func plainNull(key string) logr.Field {
+	return logr.String(key, "null")
+}
@@ -113,3 +113,29 @@ func TestPlainColorStd(t *testing.T) {
 	err = lgr.Shutdown()
 	require.NoError(t, err)
 }
+
+func TestLevelDisplay(t *testing.T) {
+	logr.SetLevelDisplay(logr.Warn, logr.LevelDisplay{Name: "WARNING", JSONName: "warning", Color: logr.Magenta})
+	defer logr.SetLevelDisplay(logr.Warn, logr.LevelDisplay{})
+
+	lgr, _ := logr.New()
+	plain := &test.Buffer{}
+	err := lgr.AddTarget(targets.NewWriterTarget(plain), "plain", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true, EnableColor: true}, 100)
+	require.NoError(t, err)
+	json := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(json), "json", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.JSON{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Warn("disk low")
+	logger.Info("ok")
+	require.NoError(t, lgr.Shutdown())
+
+	require.Equal(t, "\u001b[35mWARNING\u001b[0m disk low \n\u001b[36minfo\u001b[0m ok \n", plain.String())
+	require.Equal(t, `{"level":"warning","msg":"disk low"}`+"\n"+`{"level":"info","msg":"ok"}`+"\n", json.String())
+
+	logr.SetLevelDisplay(logr.Warn, logr.LevelDisplay{})
+	require.Equal(t, "warn", logr.Warn.DisplayName())
+}
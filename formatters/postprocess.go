@@ -0,0 +1,410 @@
+package formatters
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// PostProcessor transforms the bytes produced by a formatter before they
+// are passed to a target. Examples include compression, encryption, signing
+// and encoding.
+type PostProcessor interface {
+	// Process reads the formatted bytes from `in` and writes the transformed
+	// result to `out`.
+	Process(in []byte, out *bytes.Buffer) error
+}
+
+// Chain wraps a formatter with zero or more post-processors which are applied,
+// in order, to the formatted bytes.
+type Chain struct {
+	// Formatter is the wrapped formatter. Defaults to `logr.DefaultFormatter`.
+	Formatter logr.Formatter
+
+	// PostProcessors are applied in order to the formatted bytes.
+	PostProcessors []PostProcessor
+
+	// Delimiter, when not empty, is appended after all post-processors have been
+	// applied. Any trailing newline output by the wrapped formatter is removed
+	// before post-processing. This is useful when a post-processor produces output
+	// that may contain newlines, or no longer ends with one.
+	Delimiter string
+}
+
+// NewChain creates a Chain formatter that wraps `formatter` with post-processors.
+func NewChain(formatter logr.Formatter, pp ...PostProcessor) *Chain {
+	return &Chain{
+		Formatter:      formatter,
+		PostProcessors: pp,
+	}
+}
+
+// IsStacktraceNeeded returns true if the wrapped formatter needs a stacktrace.
+func (c *Chain) IsStacktraceNeeded() bool {
+	return c.formatter().IsStacktraceNeeded()
+}
+
+// Format converts a log record to bytes using the wrapped formatter, then applies
+// each post-processor in turn.
+func (c *Chain) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if buf == nil {
+		buf = &bytes.Buffer{}
+	}
+
+	if len(c.PostProcessors) == 0 && c.Delimiter == "" {
+		return c.formatter().Format(rec, level, buf)
+	}
+
+	in, err := c.formatter().Format(rec, level, &bytes.Buffer{})
+	if err != nil {
+		return nil, err
+	}
+
+	data := in.Bytes()
+	if c.Delimiter != "" {
+		data = bytes.TrimSuffix(data, logr.Newline)
+	}
+
+	for _, pp := range c.PostProcessors {
+		out := &bytes.Buffer{}
+		if err := pp.Process(data, out); err != nil {
+			return nil, err
+		}
+		data = out.Bytes()
+	}
+
+	buf.Write(data)
+	buf.WriteString(c.Delimiter)
+	return buf, nil
+}
+
+func (c *Chain) formatter() logr.Formatter {
+	if c.Formatter == nil {
+		return &logr.DefaultFormatter{}
+	}
+	return c.Formatter
+}
+
+// Gzip compresses formatted bytes. Each log record becomes a complete gzip member;
+// concatenated members are a valid gzip stream.
+type Gzip struct {
+	// Level is the compression level, see `compress/gzip`. Zero means
+	// gzip.DefaultCompression.
+	Level int `json:"level"`
+}
+
+func (g *Gzip) CheckValid() error {
+	if g.Level < gzip.HuffmanOnly || g.Level > gzip.BestCompression {
+		return fmt.Errorf("gzip level is invalid(%d)", g.Level)
+	}
+	return nil
+}
+
+// Process compresses `in` and writes the result to `out`.
+func (g *Gzip) Process(in []byte, out *bytes.Buffer) error {
+	level := g.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	zw, err := gzip.NewWriterLevel(out, level)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(in); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Base64 encodes formatted bytes using base64.
+type Base64 struct {
+	// URLEncoding selects the URL safe alphabet instead of the standard one.
+	URLEncoding bool `json:"url_encoding"`
+}
+
+func (b *Base64) CheckValid() error {
+	return nil
+}
+
+// Process encodes `in` and writes the result to `out`.
+func (b *Base64) Process(in []byte, out *bytes.Buffer) error {
+	enc := base64.StdEncoding
+	if b.URLEncoding {
+		enc = base64.URLEncoding
+	}
+	w := base64.NewEncoder(enc, out)
+	if _, err := w.Write(in); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// HMAC signs formatted bytes using HMAC-SHA256. The base64 encoded signature
+// is appended to the record following a separator.
+type HMAC struct {
+	// Key is the secret key used to sign.
+	Key []byte `json:"key"`
+
+	// Separator is output between the record and the signature. Defaults to a single space.
+	Separator string `json:"separator"`
+}
+
+func (h *HMAC) CheckValid() error {
+	if len(h.Key) == 0 {
+		return errors.New("hmac key cannot be empty")
+	}
+	return nil
+}
+
+// Process writes `in` to `out` followed by the separator and signature.
+func (h *HMAC) Process(in []byte, out *bytes.Buffer) error {
+	if len(h.Key) == 0 {
+		return errors.New("hmac key cannot be empty")
+	}
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write(in)
+
+	out.Write(in)
+	out.WriteString(separator(h.Separator))
+	out.WriteString(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+// Ed25519 signs formatted bytes using Ed25519. The base64 encoded signature is
+// appended to the record following a separator. Unlike `HMAC`, verifying needs
+// only the public key, so those able to verify records cannot forge them, making
+// log integrity provable end-to-end. Set `Chain.Delimiter` to "\n" so each record
+// is signed without its trailing newline and output as a single line. Records can
+// be checked with `VerifyEd25519` or the logr-verify command.
+type Ed25519 struct {
+	// PrivateKey is the 32 byte seed or 64 byte private key used to sign.
+	PrivateKey []byte `json:"private_key"`
+
+	// Separator is output between the record and the signature. Defaults to a single space.
+	Separator string `json:"separator"`
+
+	once sync.Once
+	key  ed25519.PrivateKey
+}
+
+func (e *Ed25519) CheckValid() error {
+	switch len(e.PrivateKey) {
+	case ed25519.SeedSize, ed25519.PrivateKeySize:
+		return nil
+	}
+	return fmt.Errorf("ed25519 private key length is invalid(%d)", len(e.PrivateKey))
+}
+
+// Process writes `in` to `out` followed by the separator and signature.
+func (e *Ed25519) Process(in []byte, out *bytes.Buffer) error {
+	e.once.Do(func() {
+		switch len(e.PrivateKey) {
+		case ed25519.SeedSize:
+			e.key = ed25519.NewKeyFromSeed(e.PrivateKey)
+		case ed25519.PrivateKeySize:
+			e.key = ed25519.PrivateKey(e.PrivateKey)
+		}
+	})
+	if e.key == nil {
+		return e.CheckValid()
+	}
+
+	out.Write(in)
+	out.WriteString(separator(e.Separator))
+	out.WriteString(base64.StdEncoding.EncodeToString(ed25519.Sign(e.key, in)))
+	return nil
+}
+
+// VerifyEd25519 checks the signature of a record signed by `Ed25519`, returning
+// the record without the separator and signature. A trailing newline is ignored.
+func VerifyEd25519(publicKey ed25519.PublicKey, signed []byte, sep string) ([]byte, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public key length is invalid(%d)", len(publicKey))
+	}
+	signed = bytes.TrimSuffix(signed, logr.Newline)
+	sep = separator(sep)
+
+	idx := bytes.LastIndex(signed, []byte(sep))
+	if idx < 0 {
+		return nil, errors.New("record is not signed")
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(signed[idx+len(sep):]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	rec := signed[:idx]
+	if !ed25519.Verify(publicKey, rec, sig) {
+		return nil, errors.New("invalid signature")
+	}
+	return rec, nil
+}
+
+func separator(sep string) string {
+	if sep == "" {
+		return " "
+	}
+	return sep
+}
+
+// AESGCM encrypts formatted bytes using AES-GCM. The output is the random nonce
+// followed by the sealed ciphertext. Combine with `Base64` when the target expects text.
+type AESGCM struct {
+	// Key must be 16, 24, or 32 bytes to select AES-128, AES-192, or AES-256.
+	Key []byte `json:"key"`
+}
+
+func (a *AESGCM) CheckValid() error {
+	switch len(a.Key) {
+	case 16, 24, 32:
+		return nil
+	}
+	return fmt.Errorf("aes key length is invalid(%d)", len(a.Key))
+}
+
+// Process encrypts `in` and writes the nonce and ciphertext to `out`.
+func (a *AESGCM) Process(in []byte, out *bytes.Buffer) error {
+	block, err := aes.NewCipher(a.Key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	out.Write(gcm.Seal(nonce, nonce, in, nil))
+	return nil
+}
+
+// LengthPrefix frames formatted bytes with a binary length prefix so that records
+// written to a stream, such as the TCP, socket or file targets, can be split reliably
+// without relying on newlines. This suits binary formats such as msgpack, protobuf
+// or CBOR, which may contain newlines. Use `ReadFrame` to read records back.
+type LengthPrefix struct {
+	// Size is the size in bytes of the length: 2, 4 or 8. Zero means 4.
+	// Ignored when Varint is true.
+	Size int `json:"size"`
+
+	// LittleEndian encodes the length little-endian instead of big-endian
+	// (network byte order).
+	LittleEndian bool `json:"little_endian"`
+
+	// Varint encodes the length as an unsigned varint, as used by size-delimited
+	// protobuf streams.
+	Varint bool `json:"varint"`
+
+	// TrimNewline removes a trailing newline output by text formatters.
+	TrimNewline bool `json:"trim_newline"`
+
+	// MaxFrameSize, when greater than zero, is the largest frame accepted by `ReadFrame`.
+	MaxFrameSize int `json:"max_frame_size"`
+}
+
+func (lp *LengthPrefix) CheckValid() error {
+	switch lp.Size {
+	case 0, 2, 4, 8:
+	default:
+		return fmt.Errorf("length prefix size is invalid(%d)", lp.Size)
+	}
+	if lp.MaxFrameSize < 0 {
+		return errors.New("max frame size cannot be negative")
+	}
+	return nil
+}
+
+// Process writes the length of `in` followed by `in` to `out`.
+func (lp *LengthPrefix) Process(in []byte, out *bytes.Buffer) error {
+	if lp.TrimNewline {
+		in = bytes.TrimSuffix(in, logr.Newline)
+	}
+
+	var prefix [binary.MaxVarintLen64]byte
+	n := uint64(len(in))
+	switch {
+	case lp.Varint:
+		out.Write(prefix[:binary.PutUvarint(prefix[:], n)])
+	case lp.Size == 2:
+		if n > math.MaxUint16 {
+			return fmt.Errorf("record of %d bytes is too large for a 2 byte length prefix", n)
+		}
+		lp.byteOrder().PutUint16(prefix[:], uint16(n))
+		out.Write(prefix[:2])
+	case lp.Size == 8:
+		lp.byteOrder().PutUint64(prefix[:], n)
+		out.Write(prefix[:8])
+	default:
+		if n > math.MaxUint32 {
+			return fmt.Errorf("record of %d bytes is too large for a 4 byte length prefix", n)
+		}
+		lp.byteOrder().PutUint32(prefix[:], uint32(n))
+		out.Write(prefix[:4])
+	}
+	out.Write(in)
+	return nil
+}
+
+// ReadFrame reads one record framed by `Process` from `r`. io.EOF is returned if
+// there are no more records; io.ErrUnexpectedEOF if a record is truncated.
+func (lp *LengthPrefix) ReadFrame(r *bufio.Reader) ([]byte, error) {
+	var n uint64
+	if lp.Varint {
+		var err error
+		if n, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+	} else {
+		size := lp.Size
+		if size == 0 {
+			size = 4
+		}
+		var prefix [8]byte
+		if _, err := io.ReadFull(r, prefix[:size]); err != nil {
+			return nil, err
+		}
+		switch size {
+		case 2:
+			n = uint64(lp.byteOrder().Uint16(prefix[:]))
+		case 8:
+			n = lp.byteOrder().Uint64(prefix[:])
+		default:
+			n = uint64(lp.byteOrder().Uint32(prefix[:]))
+		}
+	}
+
+	if lp.MaxFrameSize > 0 && n > uint64(lp.MaxFrameSize) {
+		return nil, fmt.Errorf("frame of %d bytes exceeds maximum of %d", n, lp.MaxFrameSize)
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (lp *LengthPrefix) byteOrder() binary.ByteOrder {
+	if lp.LittleEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
@@ -0,0 +1,161 @@
+package formatters_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	filter := &logr.StdFilter{Lvl: logr.Info}
+	plain := &formatters.Plain{DisableTimestamp: true, Delim: " | "}
+
+	logOne := func(t *testing.T, formatter logr.Formatter) string {
+		lgr, _ := logr.New()
+		buf := &test.Buffer{}
+		err := lgr.AddTarget(targets.NewWriterTarget(buf), "chainTest", filter, formatter, 100)
+		require.NoError(t, err)
+
+		lgr.NewLogger().Info("chain test", logr.String("name", "wiggin"))
+		err = lgr.Shutdown()
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	want := "info | chain test | name=wiggin\n"
+
+	t.Run("no post-processors", func(t *testing.T) {
+		out := logOne(t, formatters.NewChain(plain))
+		assert.Equal(t, want, out)
+	})
+
+	t.Run("gzip then base64", func(t *testing.T) {
+		chain := formatters.NewChain(plain, &formatters.Gzip{}, &formatters.Base64{})
+		chain.Delimiter = "\n"
+		out := logOne(t, chain)
+		require.True(t, strings.HasSuffix(out, "\n"))
+
+		zipped, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(out, "\n"))
+		require.NoError(t, err)
+		zr, err := gzip.NewReader(bytes.NewReader(zipped))
+		require.NoError(t, err)
+		plainOut, err := ioutil.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, strings.TrimSuffix(want, "\n"), string(plainOut))
+	})
+
+	t.Run("hmac", func(t *testing.T) {
+		key := []byte("secret")
+		out := logOne(t, formatters.NewChain(plain, &formatters.HMAC{Key: key}))
+
+		idx := strings.LastIndex(out, " ")
+		require.Greater(t, idx, 0)
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(out[:idx]))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), out[idx+1:])
+		assert.Equal(t, want, out[:idx])
+	})
+
+	t.Run("ed25519", func(t *testing.T) {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		chain := formatters.NewChain(plain, &formatters.Ed25519{PrivateKey: priv.Seed()})
+		chain.Delimiter = "\n"
+		out := logOne(t, chain)
+
+		rec, err := formatters.VerifyEd25519(pub, []byte(out), "")
+		require.NoError(t, err)
+		assert.Equal(t, strings.TrimSuffix(want, "\n"), string(rec))
+
+		_, err = formatters.VerifyEd25519(pub, []byte(strings.Replace(out, "wiggin", "wiggle", 1)), "")
+		assert.Error(t, err)
+		_, err = formatters.VerifyEd25519(pub, []byte(want), "")
+		assert.Error(t, err)
+	})
+
+	t.Run("aes-gcm", func(t *testing.T) {
+		key := []byte("0123456789abcdef")
+		out := logOne(t, formatters.NewChain(plain, &formatters.AESGCM{Key: key}))
+
+		block, err := aes.NewCipher(key)
+		require.NoError(t, err)
+		gcm, err := cipher.NewGCM(block)
+		require.NoError(t, err)
+
+		data := []byte(out)
+		nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+		opened, err := gcm.Open(nil, nonce, sealed, nil)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(opened))
+	})
+}
+
+func TestLengthPrefix(t *testing.T) {
+	for _, lp := range []*formatters.LengthPrefix{
+		{TrimNewline: true},
+		{Size: 2, LittleEndian: true},
+		{Size: 8},
+		{Varint: true, TrimNewline: true},
+	} {
+		lgr, _ := logr.New()
+		buf := &test.Buffer{}
+		chain := formatters.NewChain(&formatters.Plain{DisableTimestamp: true}, lp)
+		err := lgr.AddTarget(targets.NewWriterTarget(buf), "framed", &logr.StdFilter{Lvl: logr.Info}, chain, 100)
+		require.NoError(t, err)
+
+		logger := lgr.NewLogger()
+		logger.Info("first\nline")
+		logger.Info("second")
+		require.NoError(t, lgr.Shutdown())
+
+		suffix := "\n"
+		if lp.TrimNewline {
+			suffix = ""
+		}
+		r := bufio.NewReader(strings.NewReader(buf.String()))
+		for _, want := range []string{"info first\nline " + suffix, "info second " + suffix} {
+			frame, err := lp.ReadFrame(r)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(frame))
+		}
+		_, err = lp.ReadFrame(r)
+		assert.Equal(t, io.EOF, err)
+	}
+
+	lp := &formatters.LengthPrefix{MaxFrameSize: 4}
+	out := &bytes.Buffer{}
+	require.NoError(t, lp.Process([]byte("too long"), out))
+	_, err := lp.ReadFrame(bufio.NewReader(out))
+	assert.Error(t, err)
+
+	_, err = (&formatters.LengthPrefix{}).ReadFrame(bufio.NewReader(bytes.NewReader([]byte{0, 0, 0, 9, 'x'})))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestChainCheckValid(t *testing.T) {
+	assert.Error(t, (&formatters.AESGCM{Key: []byte("short")}).CheckValid())
+	assert.Error(t, (&formatters.HMAC{}).CheckValid())
+	assert.Error(t, (&formatters.Ed25519{PrivateKey: []byte("short")}).CheckValid())
+	assert.Error(t, (&formatters.Gzip{Level: 42}).CheckValid())
+	assert.NoError(t, (&formatters.Gzip{}).CheckValid())
+	assert.Error(t, (&formatters.LengthPrefix{Size: 3}).CheckValid())
+	assert.NoError(t, (&formatters.LengthPrefix{}).CheckValid())
+}
@@ -0,0 +1,126 @@
+package formatters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// JSONSchemaVersion is the current version of the record schema output by the
+	// JSON formatter when `EnableSchemaVersion` is true. It is incremented whenever
+	// the record model changes in a way that affects how records are parsed.
+	//
+	// Version history:
+	//   0 - records without a schema version field.
+	//   1 - adds the schema version field.
+	JSONSchemaVersion = 1
+
+	// DefaultKeySchemaVersion is the default key name for the schema version field.
+	DefaultKeySchemaVersion = "schema"
+)
+
+// jsonMigration upgrades a decoded JSON record from one schema version to the next.
+// keySchemaVersion is the key name of the schema version field.
+type jsonMigration func(rec map[string]interface{}, keySchemaVersion string) error
+
+// jsonMigrations maps a schema version to the migration that upgrades a record from
+// that version to the next. Every version less than JSONSchemaVersion must have an entry.
+var jsonMigrations = map[int]jsonMigration{
+	0: migrateJSONv0,
+}
+
+// migrateJSONv0 moves any field using the schema version key, as the JSON formatter
+// does for version 1 records, so the field is not overwritten by the version.
+func migrateJSONv0(rec map[string]interface{}, keySchemaVersion string) error {
+	v, ok := rec[keySchemaVersion]
+	if !ok {
+		return nil
+	}
+	delete(rec, keySchemaVersion)
+	key := "_" + keySchemaVersion
+	for {
+		if _, ok := rec[key]; !ok {
+			break
+		}
+		key = "_" + key
+	}
+	rec[key] = v
+	return nil
+}
+
+// MigrateJSON decodes a single JSON record produced by any version of the JSON
+// formatter and upgrades it to the current `JSONSchemaVersion`. The schema version
+// field is always output first, so records whose first field is not the schema
+// version are treated as version 0. A field in a version 0 record with the same key
+// as the schema version is kept, prefixed with underscores, as the JSON formatter
+// does for current records.
+//
+// `keySchemaVersion` is the key name of the schema version field; if empty then
+// DefaultKeySchemaVersion is used. Numbers are decoded as `json.Number` so that
+// integer precision is preserved.
+func MigrateJSON(data []byte, keySchemaVersion string) (map[string]interface{}, error) {
+	if keySchemaVersion == "" {
+		keySchemaVersion = DefaultKeySchemaVersion
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	rec := make(map[string]interface{})
+	if err := dec.Decode(&rec); err != nil {
+		return nil, fmt.Errorf("cannot decode JSON record: %w", err)
+	}
+
+	version := 0
+	if firstKey(data) == keySchemaVersion {
+		var err error
+		if version, err = schemaVersion(rec[keySchemaVersion]); err != nil {
+			return nil, err
+		}
+	}
+	if version > JSONSchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d; newest supported is %d", version, JSONSchemaVersion)
+	}
+
+	for ; version < JSONSchemaVersion; version++ {
+		migrate, ok := jsonMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration for schema version %d", version)
+		}
+		if err := migrate(rec, keySchemaVersion); err != nil {
+			return nil, fmt.Errorf("cannot migrate schema version %d: %w", version, err)
+		}
+	}
+
+	rec[keySchemaVersion] = json.Number(fmt.Sprint(JSONSchemaVersion))
+	return rec, nil
+}
+
+// firstKey returns the key of the first field of a JSON object, or empty string if
+// there is none.
+func firstKey(data []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return ""
+	}
+	t, err := dec.Token()
+	if err != nil {
+		return ""
+	}
+	key, _ := t.(string)
+	return key
+}
+
+func schemaVersion(v interface{}) (int, error) {
+	num, ok := v.(json.Number)
+	if !ok {
+		return 0, errors.New("schema version must be a number")
+	}
+	version, err := num.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("schema version is invalid(%s)", num)
+	}
+	return int(version), nil
+}
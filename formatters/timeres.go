@@ -0,0 +1,103 @@
+package formatters
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeResolution is the resolution timestamps are output with, since some sinks
+// expect a specific precision and extra precision bloats every record.
+type TimeResolution string
+
+const (
+	// TimeResolutionDefault outputs timestamps as set by the timestamp format.
+	TimeResolutionDefault TimeResolution = ""
+
+	TimeResolutionSeconds TimeResolution = "s"
+	TimeResolutionMillis  TimeResolution = "ms"
+	TimeResolutionMicros  TimeResolution = "us"
+	TimeResolutionNanos   TimeResolution = "ns"
+)
+
+// CheckValid returns an error if the resolution is unknown.
+func (tr TimeResolution) CheckValid() error {
+	switch tr {
+	case TimeResolutionDefault, TimeResolutionSeconds, TimeResolutionMillis, TimeResolutionMicros, TimeResolutionNanos:
+		return nil
+	}
+	return fmt.Errorf("invalid timestamp resolution %q", tr)
+}
+
+// unit returns the duration of one unit of the resolution, defaulting to
+// milliseconds.
+func (tr TimeResolution) unit() time.Duration {
+	switch tr {
+	case TimeResolutionSeconds:
+		return time.Second
+	case TimeResolutionMicros:
+		return time.Microsecond
+	case TimeResolutionNanos:
+		return time.Nanosecond
+	}
+	return time.Millisecond
+}
+
+// digits returns the number of fractional second digits of the resolution.
+func (tr TimeResolution) digits() int {
+	switch tr {
+	case TimeResolutionSeconds:
+		return 0
+	case TimeResolutionMicros:
+		return 6
+	case TimeResolutionNanos:
+		return 9
+	}
+	return 3
+}
+
+// apply returns the time truncated to the resolution, and the layout with its
+// fractional seconds, if any, changed to match the resolution.
+func (tr TimeResolution) apply(t time.Time, layout string) (time.Time, string) {
+	if tr == TimeResolutionDefault {
+		return t, layout
+	}
+	return t.Truncate(tr.unit()), tr.layout(layout)
+}
+
+// layout returns the layout with the fractional seconds following the seconds
+// element ("05") changed to the number of digits of the resolution.
+func (tr TimeResolution) layout(layout string) string {
+	i := strings.Index(layout, "05")
+	if i < 0 {
+		return layout
+	}
+	start := i + len("05")
+	end := start
+	if end < len(layout) && (layout[end] == '.' || layout[end] == ',') {
+		end++
+		for end < len(layout) && (layout[end] == '0' || layout[end] == '9') {
+			end++
+		}
+	}
+	if end == start+1 {
+		// a '.' or ',' not followed by fractional seconds.
+		end = start
+	}
+
+	frac := ""
+	if n := tr.digits(); n > 0 {
+		sep := "."
+		if end > start {
+			sep = layout[start : start+1]
+		}
+		frac = sep + strings.Repeat("0", n)
+	}
+	return layout[:start] + frac + layout[end:]
+}
+
+// epoch returns the time since the Unix epoch in units of the resolution,
+// defaulting to milliseconds.
+func (tr TimeResolution) epoch(t time.Time) int64 {
+	return t.UnixNano() / int64(tr.unit())
+}
@@ -0,0 +1,173 @@
+package formatters
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// W3C field identifiers with values taken from the log record rather than its fields.
+const (
+	W3CDate  = "date"
+	W3CTime  = "time"
+	W3CLevel = "x-level"
+	W3CMsg   = "x-msg"
+)
+
+// W3C formats log records in the W3C Extended Log File Format
+// (https://www.w3.org/TR/WD-logfile.html), as read by legacy web log analyzers.
+// Directives, including the `#Fields` directive listing the fields, are output
+// before the first record and again before the first record of each UTC day,
+// matching daily log files.
+//
+// A W3C formatter keeps track of the directives it output so should not be shared
+// by multiple targets.
+type W3C struct {
+	// Fields are the W3C field identifiers output for each record, e.g. "date", "time",
+	// "c-ip", "cs-method", "cs-uri-stem", "sc-status", "time-taken". The date, time,
+	// x-level and x-msg fields are taken from the log record; all other values are
+	// taken from the log record field with the same key, or as mapped via FieldKeys.
+	// Missing values are output as "-".
+	Fields []string `json:"fields"`
+
+	// FieldKeys optionally maps W3C field identifiers to log record field keys, e.g.
+	// "c-ip" to "remote_addr".
+	FieldKeys map[string]string `json:"field_keys"`
+
+	// Software is output in the `#Software` directive. Defaults to "logr".
+	Software string `json:"software"`
+
+	// LineEnd sets the end of line character(s). Defaults to "\r\n" as recommended by
+	// the format specification.
+	LineEnd string `json:"line_end"`
+
+	mux      sync.Mutex
+	lastDate string
+}
+
+// CheckValid returns an error if the formatter options are invalid.
+func (w *W3C) CheckValid() error {
+	if len(w.Fields) == 0 {
+		return errors.New("missing fields")
+	}
+	for _, f := range w.Fields {
+		if f == "" || strings.ContainsAny(f, " \t\r\n") {
+			return errors.New("invalid field identifier " + strconv.Quote(f))
+		}
+	}
+	return nil
+}
+
+// IsStacktraceNeeded returns false; the W3C formatter does not output stack traces.
+func (w *W3C) IsStacktraceNeeded() bool {
+	return false
+}
+
+// ResetDirectives causes directives to be output before the next record, for
+// example after a log file is rotated.
+func (w *W3C) ResetDirectives() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.lastDate = ""
+}
+
+// Format converts a log record to a W3C extended log format line, preceded by
+// directives when needed.
+func (w *W3C) Format(rec *logr.LogRec, level logr.Level, buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if buf == nil {
+		buf = &bytes.Buffer{}
+	}
+	lineEnd := w.LineEnd
+	if lineEnd == "" {
+		lineEnd = "\r\n"
+	}
+
+	t := rec.Time().UTC()
+	date := t.Format("2006-01-02")
+
+	w.mux.Lock()
+	header := date != w.lastDate
+	w.lastDate = date
+	w.mux.Unlock()
+
+	if header {
+		software := w.Software
+		if software == "" {
+			software = "logr"
+		}
+		buf.WriteString("#Version: 1.0" + lineEnd)
+		buf.WriteString("#Software: " + software + lineEnd)
+		buf.WriteString("#Date: " + t.Format("2006-01-02 15:04:05") + lineEnd)
+		buf.WriteString("#Fields: " + strings.Join(w.Fields, " ") + lineEnd)
+	}
+
+	fields := rec.Fields()
+	for i, id := range w.Fields {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		switch id {
+		case W3CDate:
+			buf.WriteString(date)
+		case W3CTime:
+			buf.WriteString(t.Format("15:04:05"))
+		case W3CLevel:
+			w.writeValue(buf, level.DisplayName())
+		case W3CMsg:
+			w.writeValue(buf, rec.Msg())
+		default:
+			key := id
+			if mapped, ok := w.FieldKeys[id]; ok {
+				key = mapped
+			}
+			w.writeField(buf, fields, key)
+		}
+	}
+	buf.WriteString(lineEnd)
+	return buf, nil
+}
+
+func (w *W3C) writeField(buf *bytes.Buffer, fields []logr.Field, key string) {
+	for _, f := range fields {
+		if f.Key != key {
+			continue
+		}
+		switch f.Type {
+		case logr.StringType:
+			w.writeValue(buf, f.String)
+		case logr.DurationType:
+			// time-taken and similar fields are in seconds.
+			buf.WriteString(strconv.FormatFloat(time.Duration(f.Integer).Seconds(), 'f', 3, 64))
+		default:
+			var sb strings.Builder
+			if err := f.ValueString(&sb, nil); err != nil {
+				buf.WriteByte('-')
+				return
+			}
+			w.writeValue(buf, sb.String())
+		}
+		return
+	}
+	buf.WriteByte('-')
+}
+
+// writeValue outputs a value, quoting it if it contains whitespace or quotes.
+func (w *W3C) writeValue(buf *bytes.Buffer, s string) {
+	if s == "" {
+		buf.WriteByte('-')
+		return
+	}
+	if !strings.ContainsAny(s, " \t\r\n\"") {
+		buf.WriteString(s)
+		return
+	}
+	s = strings.NewReplacer("\"", "\"\"", "\r", " ", "\n", " ").Replace(s)
+	buf.WriteByte('"')
+	buf.WriteString(s)
+	buf.WriteByte('"')
+}
@@ -0,0 +1,69 @@
+package formatters_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestW3C(t *testing.T) {
+	var mux sync.Mutex
+	now := time.Date(2021, 3, 4, 23, 59, 58, 0, time.UTC)
+	lgr, err := logr.New(logr.Clock(func() time.Time {
+		mux.Lock()
+		defer mux.Unlock()
+		return now
+	}))
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+
+	formatter := &formatters.W3C{
+		Fields:    []string{"date", "time", "c-ip", "cs-method", "cs-uri-stem", "sc-status", "time-taken", "cs(User-Agent)"},
+		FieldKeys: map[string]string{"c-ip": "remote_addr", "cs(User-Agent)": "agent"},
+		LineEnd:   "\n",
+	}
+	require.NoError(t, formatter.CheckValid())
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "w3c", &logr.StdFilter{Lvl: logr.Info}, formatter, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	request := func(uri string, status int) {
+		logger.Info("request", logr.String("remote_addr", "10.0.0.1"), logr.String("cs-method", "GET"),
+			logr.String("cs-uri-stem", uri), logr.Int("sc-status", status),
+			logr.Duration("time-taken", 1500*time.Millisecond), logr.String("agent", `Mozilla/5.0 "test"`))
+	}
+	request("/index.html", 200)
+	request("/missing", 404)
+	require.NoError(t, lgr.Flush())
+
+	mux.Lock()
+	now = now.Add(time.Minute)
+	mux.Unlock()
+	logger.Info("no fields")
+	require.NoError(t, lgr.Flush())
+
+	want := `#Version: 1.0
+#Software: logr
+#Date: 2021-03-04 23:59:58
+#Fields: date time c-ip cs-method cs-uri-stem sc-status time-taken cs(User-Agent)
+2021-03-04 23:59:58 10.0.0.1 GET /index.html 200 1.500 "Mozilla/5.0 ""test"""
+2021-03-04 23:59:58 10.0.0.1 GET /missing 404 1.500 "Mozilla/5.0 ""test"""
+#Version: 1.0
+#Software: logr
+#Date: 2021-03-05 00:00:58
+#Fields: date time c-ip cs-method cs-uri-stem sc-status time-taken cs(User-Agent)
+2021-03-05 00:00:58 - - - - - -
+`
+	assert.Equal(t, want, buf.String())
+
+	assert.Error(t, (&formatters.W3C{}).CheckValid())
+}
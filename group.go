@@ -0,0 +1,113 @@
+package logr
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// InTargetGroup adds the target to the named group of targets that share a budget
+// of maxQueued records, bounding the memory used by the group's queued records
+// regardless of how many targets it contains. A record is dropped, and counted by
+// the target's dropped counter, when the records queued by all the group's targets
+// reach the budget, unless its level is at or above the target's `BlockLevel`. Each
+// target's own queue size still applies. Targets in the same group must use the
+// same budget, unless all of the group's targets have been removed.
+func InTargetGroup(name string, maxQueued int) TargetOption {
+	return func(opts *targetHostOptions) error {
+		if name == "" {
+			return errors.New("target group name cannot be empty")
+		}
+		if maxQueued <= 0 {
+			return errors.New("target group budget must be greater than zero")
+		}
+		opts.groupName = name
+		opts.groupMaxQueued = maxQueued
+		return nil
+	}
+}
+
+// GroupStats is a point in time snapshot of statistics for one target group.
+type GroupStats struct {
+	Name      string `json:"name"`
+	MaxQueued int    `json:"max_queued"`
+	Queued    int    `json:"queued"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// targetGroup is a group of targets sharing a queued record budget. See
+// `InTargetGroup`.
+type targetGroup struct {
+	name      string
+	maxQueued int
+	dropped   uint64 // records dropped because the budget was reached
+}
+
+// targetGroup returns the named group, creating it if needed. The caller must
+// hold the Logr's target write lock.
+func (lgr *Logr) targetGroup(name string, maxQueued int) (*targetGroup, error) {
+	group, ok := lgr.targetGroups[name]
+	if !ok {
+		group = &targetGroup{name: name, maxQueued: maxQueued}
+		if lgr.targetGroups == nil {
+			lgr.targetGroups = make(map[string]*targetGroup)
+		}
+		lgr.targetGroups[name] = group
+		return group, nil
+	}
+	if group.maxQueued == maxQueued {
+		return group, nil
+	}
+	for _, host := range lgr.targetHosts {
+		if host.group == group {
+			return nil, fmt.Errorf("target group %s has a budget of %d records, not %d", name, group.maxQueued, maxQueued)
+		}
+	}
+	group = &targetGroup{name: name, maxQueued: maxQueued}
+	lgr.targetGroups[name] = group
+	return group, nil
+}
+
+// groupQueued returns the number of records queued by the targets in the group.
+// The caller must hold the Logr's target read lock.
+func (lgr *Logr) groupQueued(group *targetGroup) int {
+	var queued int
+	for _, host := range lgr.targetHosts {
+		if host.group == group {
+			queued += host.queueLen()
+		}
+	}
+	return queued
+}
+
+// overGroupBudget returns true, counting the record as dropped, if a record cannot
+// be queued because the target's group has reached its budget. The caller must hold
+// the Logr's target read lock.
+func (h *TargetHost) overGroupBudget(lgr *Logr) bool {
+	if lgr.groupQueued(h.group) < h.group.maxQueued {
+		return false
+	}
+	atomic.AddUint64(&h.group.dropped, 1)
+	h.incDroppedCounter()
+	return true
+}
+
+// groupStats returns a snapshot of each target group, sorted by name. The caller
+// must hold the Logr's target read lock.
+func (lgr *Logr) groupStats() []GroupStats {
+	if len(lgr.targetGroups) == 0 {
+		return nil
+	}
+	stats := make([]GroupStats, 0, len(lgr.targetGroups))
+	for _, group := range lgr.targetGroups {
+		stats = append(stats, GroupStats{
+			Name:      group.name,
+			MaxQueued: group.maxQueued,
+			Queued:    lgr.groupQueued(group),
+			Dropped:   atomic.LoadUint64(&group.dropped),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
@@ -0,0 +1,56 @@
+package logr_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetGroup(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	targetA := test.NewBlockingTarget(&test.Buffer{})
+	err = lgr.AddTarget(targetA, "a", &logr.StdFilter{Lvl: logr.Info}, formatter, 10, logr.InTargetGroup("shared", 5))
+	require.NoError(t, err)
+	targetB := test.NewBlockingTarget(&test.Buffer{})
+	err = lgr.AddTarget(targetB, "b", &logr.StdFilter{Lvl: logr.Info}, formatter, 10,
+		logr.InTargetGroup("shared", 5), logr.BlockLevel(logr.Error))
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(test.NewBlockingTarget(&test.Buffer{}), "c", &logr.StdFilter{Lvl: logr.Info}, formatter, 10,
+		logr.InTargetGroup("shared", 8))
+	assert.Error(t, err, "budget differs from the group's")
+
+	logger := lgr.NewLogger()
+	logger.Info("first")
+	<-targetA.Blocked()
+	<-targetB.Blocked()
+
+	for i := 0; i < 10; i++ {
+		logger.Info("fill")
+	}
+	// target b blocks on errors rather than dropping them.
+	logger.Error("error")
+
+	var stats logr.Stats
+	assert.Eventually(t, func() bool {
+		stats = lgr.StatsSnapshot()
+		return stats.QueueSize == 0 && len(stats.Groups) == 1 && stats.Groups[0].Queued == 6
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, logr.GroupStats{Name: "shared", MaxQueued: 5, Queued: 6, Dropped: 16}, stats.Groups[0])
+	for _, ts := range stats.Targets {
+		assert.Equal(t, 3, ts.QueueSize, ts.Name)
+		assert.Equal(t, uint64(8), ts.Dropped, ts.Name)
+	}
+
+	targetA.Unblock()
+	targetB.Unblock()
+	require.NoError(t, lgr.Shutdown())
+}
@@ -0,0 +1,5 @@
+// Package logpb contains the protobuf messages and `LogService` gRPC API used to
+// stream log records to a central collector. See logservice.proto.
+package logpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative logservice.proto
@@ -0,0 +1,433 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: logservice.proto
+
+package logpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Field struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*Field_StringValue
+	//	*Field_IntValue
+	//	*Field_UintValue
+	//	*Field_DoubleValue
+	//	*Field_BoolValue
+	//	*Field_BytesValue
+	Value         isField_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Field) Reset() {
+	*x = Field{}
+	mi := &file_logservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Field) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Field) ProtoMessage() {}
+
+func (x *Field) ProtoReflect() protoreflect.Message {
+	mi := &file_logservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Field.ProtoReflect.Descriptor instead.
+func (*Field) Descriptor() ([]byte, []int) {
+	return file_logservice_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Field) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Field) GetValue() isField_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Field) GetStringValue() string {
+	if x != nil {
+		if x, ok := x.Value.(*Field_StringValue); ok {
+			return x.StringValue
+		}
+	}
+	return ""
+}
+
+func (x *Field) GetIntValue() int64 {
+	if x != nil {
+		if x, ok := x.Value.(*Field_IntValue); ok {
+			return x.IntValue
+		}
+	}
+	return 0
+}
+
+func (x *Field) GetUintValue() uint64 {
+	if x != nil {
+		if x, ok := x.Value.(*Field_UintValue); ok {
+			return x.UintValue
+		}
+	}
+	return 0
+}
+
+func (x *Field) GetDoubleValue() float64 {
+	if x != nil {
+		if x, ok := x.Value.(*Field_DoubleValue); ok {
+			return x.DoubleValue
+		}
+	}
+	return 0
+}
+
+func (x *Field) GetBoolValue() bool {
+	if x != nil {
+		if x, ok := x.Value.(*Field_BoolValue); ok {
+			return x.BoolValue
+		}
+	}
+	return false
+}
+
+func (x *Field) GetBytesValue() []byte {
+	if x != nil {
+		if x, ok := x.Value.(*Field_BytesValue); ok {
+			return x.BytesValue
+		}
+	}
+	return nil
+}
+
+type isField_Value interface {
+	isField_Value()
+}
+
+type Field_StringValue struct {
+	StringValue string `protobuf:"bytes,2,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type Field_IntValue struct {
+	IntValue int64 `protobuf:"varint,3,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type Field_UintValue struct {
+	UintValue uint64 `protobuf:"varint,4,opt,name=uint_value,json=uintValue,proto3,oneof"`
+}
+
+type Field_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,5,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+type Field_BoolValue struct {
+	BoolValue bool `protobuf:"varint,6,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type Field_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+func (*Field_StringValue) isField_Value() {}
+
+func (*Field_IntValue) isField_Value() {}
+
+func (*Field_UintValue) isField_Value() {}
+
+func (*Field_DoubleValue) isField_Value() {}
+
+func (*Field_BoolValue) isField_Value() {}
+
+func (*Field_BytesValue) isField_Value() {}
+
+type LogRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	TimeUnixNano  int64                  `protobuf:"varint,2,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	Level         string                 `protobuf:"bytes,3,opt,name=level,proto3" json:"level,omitempty"`
+	LevelId       int32                  `protobuf:"varint,4,opt,name=level_id,json=levelId,proto3" json:"level_id,omitempty"`
+	Msg           string                 `protobuf:"bytes,5,opt,name=msg,proto3" json:"msg,omitempty"`
+	Fields        []*Field               `protobuf:"bytes,6,rep,name=fields,proto3" json:"fields,omitempty"`
+	Caller        string                 `protobuf:"bytes,7,opt,name=caller,proto3" json:"caller,omitempty"`
+	Stacktrace    []string               `protobuf:"bytes,8,rep,name=stacktrace,proto3" json:"stacktrace,omitempty"`
+	Formatted     []byte                 `protobuf:"bytes,9,opt,name=formatted,proto3" json:"formatted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogRecord) Reset() {
+	*x = LogRecord{}
+	mi := &file_logservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogRecord) ProtoMessage() {}
+
+func (x *LogRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_logservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogRecord.ProtoReflect.Descriptor instead.
+func (*LogRecord) Descriptor() ([]byte, []int) {
+	return file_logservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LogRecord) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *LogRecord) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *LogRecord) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogRecord) GetLevelId() int32 {
+	if x != nil {
+		return x.LevelId
+	}
+	return 0
+}
+
+func (x *LogRecord) GetMsg() string {
+	if x != nil {
+		return x.Msg
+	}
+	return ""
+}
+
+func (x *LogRecord) GetFields() []*Field {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *LogRecord) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *LogRecord) GetStacktrace() []string {
+	if x != nil {
+		return x.Stacktrace
+	}
+	return nil
+}
+
+func (x *LogRecord) GetFormatted() []byte {
+	if x != nil {
+		return x.Formatted
+	}
+	return nil
+}
+
+type StreamAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Window        uint32                 `protobuf:"varint,2,opt,name=window,proto3" json:"window,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamAck) Reset() {
+	*x = StreamAck{}
+	mi := &file_logservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamAck) ProtoMessage() {}
+
+func (x *StreamAck) ProtoReflect() protoreflect.Message {
+	mi := &file_logservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamAck.ProtoReflect.Descriptor instead.
+func (*StreamAck) Descriptor() ([]byte, []int) {
+	return file_logservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamAck) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *StreamAck) GetWindow() uint32 {
+	if x != nil {
+		return x.Window
+	}
+	return 0
+}
+
+var File_logservice_proto protoreflect.FileDescriptor
+
+const file_logservice_proto_rawDesc = "" +
+	"\n" +
+	"\x10logservice.proto\x12\alogr.v1\"\xf0\x01\n" +
+	"\x05Field\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12#\n" +
+	"\fstring_value\x18\x02 \x01(\tH\x00R\vstringValue\x12\x1d\n" +
+	"\tint_value\x18\x03 \x01(\x03H\x00R\bintValue\x12\x1f\n" +
+	"\n" +
+	"uint_value\x18\x04 \x01(\x04H\x00R\tuintValue\x12#\n" +
+	"\fdouble_value\x18\x05 \x01(\x01H\x00R\vdoubleValue\x12\x1f\n" +
+	"\n" +
+	"bool_value\x18\x06 \x01(\bH\x00R\tboolValue\x12!\n" +
+	"\vbytes_value\x18\a \x01(\fH\x00R\n" +
+	"bytesValueB\a\n" +
+	"\x05value\"\x8e\x02\n" +
+	"\tLogRecord\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12$\n" +
+	"\x0etime_unix_nano\x18\x02 \x01(\x03R\ftimeUnixNano\x12\x14\n" +
+	"\x05level\x18\x03 \x01(\tR\x05level\x12\x19\n" +
+	"\blevel_id\x18\x04 \x01(\x05R\alevelId\x12\x10\n" +
+	"\x03msg\x18\x05 \x01(\tR\x03msg\x12&\n" +
+	"\x06fields\x18\x06 \x03(\v2\x0e.logr.v1.FieldR\x06fields\x12\x16\n" +
+	"\x06caller\x18\a \x01(\tR\x06caller\x12\x1e\n" +
+	"\n" +
+	"stacktrace\x18\b \x03(\tR\n" +
+	"stacktrace\x12\x1c\n" +
+	"\tformatted\x18\t \x01(\fR\tformatted\"?\n" +
+	"\tStreamAck\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12\x16\n" +
+	"\x06window\x18\x02 \x01(\rR\x06window2B\n" +
+	"\n" +
+	"LogService\x124\n" +
+	"\x06Stream\x12\x12.logr.v1.LogRecord\x1a\x12.logr.v1.StreamAck(\x010\x01B*Z(github.com/mattermost/logr/v2/grpc/logpbb\x06proto3"
+
+var (
+	file_logservice_proto_rawDescOnce sync.Once
+	file_logservice_proto_rawDescData []byte
+)
+
+func file_logservice_proto_rawDescGZIP() []byte {
+	file_logservice_proto_rawDescOnce.Do(func() {
+		file_logservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_logservice_proto_rawDesc), len(file_logservice_proto_rawDesc)))
+	})
+	return file_logservice_proto_rawDescData
+}
+
+var file_logservice_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_logservice_proto_goTypes = []any{
+	(*Field)(nil),     // 0: logr.v1.Field
+	(*LogRecord)(nil), // 1: logr.v1.LogRecord
+	(*StreamAck)(nil), // 2: logr.v1.StreamAck
+}
+var file_logservice_proto_depIdxs = []int32{
+	0, // 0: logr.v1.LogRecord.fields:type_name -> logr.v1.Field
+	1, // 1: logr.v1.LogService.Stream:input_type -> logr.v1.LogRecord
+	2, // 2: logr.v1.LogService.Stream:output_type -> logr.v1.StreamAck
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_logservice_proto_init() }
+func file_logservice_proto_init() {
+	if File_logservice_proto != nil {
+		return
+	}
+	file_logservice_proto_msgTypes[0].OneofWrappers = []any{
+		(*Field_StringValue)(nil),
+		(*Field_IntValue)(nil),
+		(*Field_UintValue)(nil),
+		(*Field_DoubleValue)(nil),
+		(*Field_BoolValue)(nil),
+		(*Field_BytesValue)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_logservice_proto_rawDesc), len(file_logservice_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_logservice_proto_goTypes,
+		DependencyIndexes: file_logservice_proto_depIdxs,
+		MessageInfos:      file_logservice_proto_msgTypes,
+	}.Build()
+	File_logservice_proto = out.File
+	file_logservice_proto_goTypes = nil
+	file_logservice_proto_depIdxs = nil
+}
@@ -0,0 +1,103 @@
+package logrgrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/grpc/logpb"
+	"github.com/mattermost/logr/v2/receiver"
+	"github.com/mattermost/logr/v2/replay"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// ProtobufContentType is the HTTP content type of length-delimited `logpb.LogRecord`
+// messages, as decoded by `DecodeDelimited`.
+const ProtobufContentType = "application/x-protobuf"
+
+// ReceiverHandler returns a `Handler` that injects each record received by a `Server`
+// into rcv, so that records streamed over gRPC flow into the local pipeline the same
+// way as records received over TCP or HTTP.
+func ReceiverHandler(rcv *receiver.Receiver) Handler {
+	return func(ctx context.Context, rec *logpb.LogRecord) error {
+		var source string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			source = p.Addr.String()
+		}
+		rcv.Inject(NewRecord(rec), source)
+		return nil
+	}
+}
+
+// DecodeDelimited is a `receiver.Decoder` for HTTP bodies containing a sequence of
+// size-delimited `logpb.LogRecord` messages. Register it for `ProtobufContentType`:
+//
+//	receiver.Options{Decoders: map[string]receiver.Decoder{
+//	    logrgrpc.ProtobufContentType: logrgrpc.DecodeDelimited,
+//	}}
+func DecodeDelimited(r io.Reader) ([]*replay.Record, error) {
+	br := bufio.NewReader(r)
+	var recs []*replay.Record
+	for {
+		lr := &logpb.LogRecord{}
+		err := protodelim.UnmarshalFrom(br, lr)
+		if errors.Is(err, io.EOF) {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, NewRecord(lr))
+	}
+}
+
+// NewRecord converts a protobuf log record, as sent by `Target`, to a record that can
+// be re-emitted. Level names are mapped via `logr.GetLevelMapper()`; unmapped levels
+// keep their name and ID. The caller and stack trace, if any, become fields.
+func NewRecord(lr *logpb.LogRecord) *replay.Record {
+	lvl, ok := logr.GetLevelMapper().Name(lr.Level)
+	if !ok {
+		lvl = logr.Level{ID: logr.LevelID(lr.LevelId), Name: lr.Level}
+	}
+
+	rec := &replay.Record{
+		Time:   time.Unix(0, lr.TimeUnixNano),
+		Level:  lvl,
+		Msg:    lr.Msg,
+		Fields: make([]logr.Field, 0, len(lr.Fields)+2),
+	}
+	for _, pf := range lr.Fields {
+		rec.Fields = append(rec.Fields, fieldFromProto(pf))
+	}
+	if lr.Caller != "" {
+		rec.Fields = append(rec.Fields, logr.String("caller", lr.Caller))
+	}
+	if len(lr.Stacktrace) > 0 {
+		rec.Fields = append(rec.Fields, logr.Array("stacktrace", lr.Stacktrace))
+	}
+	return rec
+}
+
+// fieldFromProto converts a protobuf field, the inverse of newField.
+func fieldFromProto(pf *logpb.Field) logr.Field {
+	key := pf.Key
+	switch v := pf.Value.(type) {
+	case *logpb.Field_StringValue:
+		return logr.String(key, v.StringValue)
+	case *logpb.Field_IntValue:
+		return logr.Int64(key, v.IntValue)
+	case *logpb.Field_UintValue:
+		return logr.Uint64(key, v.UintValue)
+	case *logpb.Field_DoubleValue:
+		return logr.Float64(key, v.DoubleValue)
+	case *logpb.Field_BoolValue:
+		return logr.Bool(key, v.BoolValue)
+	case *logpb.Field_BytesValue:
+		return logr.Any(key, v.BytesValue)
+	}
+	return logr.String(key, "")
+}
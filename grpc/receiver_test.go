@@ -0,0 +1,63 @@
+package logrgrpc
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/grpc/logpb"
+	"github.com/mattermost/logr/v2/receiver"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+func TestReceiverHandler(t *testing.T) {
+	hub, err := logr.New()
+	require.NoError(t, err)
+	buf := &test.Buffer{}
+	err = hub.AddTarget(targets.NewWriterTarget(buf), "hub", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+	rcv, err := receiver.New(hub.NewLogger(), receiver.Options{})
+	require.NoError(t, err)
+
+	// records streamed over gRPC.
+	target := startServer(t, ReceiverHandler(rcv), 0, Options{})
+	spoke, err := logr.New()
+	require.NoError(t, err)
+	err = spoke.AddTarget(target, "grpc", &logr.StdFilter{Lvl: logr.Info}, nil, 100)
+	require.NoError(t, err)
+	spoke.NewLogger().Warn("streamed", logr.Int("count", 3), logr.Bool("ok", true))
+	require.NoError(t, spoke.Shutdown())
+
+	// records POSTed as size-delimited protobuf.
+	posted, err := receiver.New(hub.NewLogger(), receiver.Options{
+		Decoders: map[string]receiver.Decoder{ProtobufContentType: DecodeDelimited},
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(posted)
+	defer server.Close()
+
+	var body bytes.Buffer
+	for _, lr := range []*logpb.LogRecord{
+		{Level: "error", Msg: "posted", Fields: []*logpb.Field{{Key: "ratio", Value: &logpb.Field_DoubleValue{DoubleValue: 0.5}}}},
+		{Level: "audit", LevelId: 100, Msg: "custom level"},
+	} {
+		_, err = protodelim.MarshalTo(&body, lr)
+		require.NoError(t, err)
+	}
+	resp, err := http.Post(server.URL, ProtobufContentType, &body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.NoError(t, hub.Shutdown())
+	assert.Equal(t, uint64(1), rcv.Received())
+	assert.Equal(t, "warn streamed count=3 ok=true\nerror posted ratio=0.5\n", buf.String())
+}
@@ -0,0 +1,60 @@
+package logrgrpc
+
+import (
+	"context"
+	"io"
+
+	"github.com/mattermost/logr/v2/grpc/logpb"
+	"google.golang.org/grpc"
+)
+
+// Handler processes a log record received by a `Server`. Returning an error aborts
+// the stream; the client reconnects and resends any records not yet acknowledged,
+// so a record may be handled more than once.
+type Handler func(ctx context.Context, rec *logpb.LogRecord) error
+
+// Server implements `logpb.LogServiceServer` by passing each received record to a
+// `Handler`, and acknowledging records once handled. Register it with
+// `logpb.RegisterLogServiceServer`.
+type Server struct {
+	logpb.UnimplementedLogServiceServer
+
+	handler Handler
+	window  uint32
+}
+
+// NewServer creates a `LogService` implementation that calls handler for each record
+// received. If window is non-zero it overrides the window configured by clients,
+// limiting how many records each client may have outstanding.
+func NewServer(handler Handler, window uint32) *Server {
+	return &Server{handler: handler, window: window}
+}
+
+// Stream receives records from a client until the client closes the stream.
+func (s *Server) Stream(stream grpc.BidiStreamingServer[logpb.LogRecord, logpb.StreamAck]) error {
+	ctx := stream.Context()
+
+	if s.window > 0 {
+		if err := stream.Send(&logpb.StreamAck{Window: s.window}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		rec, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.handler(ctx, rec); err != nil {
+			return err
+		}
+
+		if err := stream.Send(&logpb.StreamAck{Sequence: rec.Sequence}); err != nil {
+			return err
+		}
+	}
+}
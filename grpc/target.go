@@ -0,0 +1,409 @@
+// Package logrgrpc provides a Logr target that streams log records to a central
+// collector implementing the `LogService` gRPC API, and a server side helper for
+// implementing such a collector.
+//
+// It is a separate module so applications that do not ship logs over gRPC do not
+// depend on gRPC.
+package logrgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/grpc/logpb"
+	"github.com/mattermost/logr/v2/targets"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// DefaultWindow is the default maximum number of records sent but not yet
+	// acknowledged by the collector.
+	DefaultWindow = 1000
+
+	// DefaultFlushTimeout is how long Flush waits for outstanding records to be acknowledged.
+	DefaultFlushTimeout = time.Second * targets.WriteTimeoutSecs
+)
+
+// Options provides parameters for the gRPC target.
+type Options struct {
+	// Address is the collector address, e.g. "collector:4317" or "dns:///collector:4317".
+	Address string `json:"address"`
+
+	// Insecure disables transport security. Ignored if DialOptions are provided.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// Window is the maximum number of records sent but not yet acknowledged. Once
+	// reached, writes block until the collector catches up, which back-pressures the
+	// target queue. The collector may override this via `StreamAck.window`.
+	// Defaults to DefaultWindow.
+	Window int `json:"window,omitempty"`
+
+	// IncludeFormatted sends the formatter output with each record in addition to
+	// the structured fields.
+	IncludeFormatted bool `json:"include_formatted,omitempty"`
+
+	// FlushTimeoutMillis is how long Flush waits for outstanding records to be
+	// acknowledged. Defaults to DefaultFlushTimeout.
+	FlushTimeoutMillis int64 `json:"flush_timeout_millis,omitempty"`
+
+	// Backoff determines the delay between reconnect attempts. Defaults to `targets.DefaultBackoff`.
+	Backoff *targets.ExponentialBackoff `json:"backoff,omitempty"`
+
+	// DialOptions are passed to `grpc.NewClient`, for example to provide TLS credentials.
+	DialOptions []grpc.DialOption `json:"-"`
+}
+
+// CheckValid returns an error if the options are invalid.
+func (o Options) CheckValid() error {
+	if o.Address == "" {
+		return errors.New("missing address")
+	}
+	if o.Window < 0 {
+		return errors.New("window cannot be negative")
+	}
+	if o.FlushTimeoutMillis < 0 {
+		return errors.New("flush timeout cannot be negative")
+	}
+	if !o.Insecure && len(o.DialOptions) == 0 {
+		return errors.New("transport credentials must be provided via DialOptions unless insecure is set")
+	}
+	if o.Backoff != nil {
+		return o.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// Target streams log records to a `LogService` collector. Records are kept until
+// acknowledged, and resent after a reconnect, so records are not lost when the
+// collector restarts. At most `Window` records are outstanding at once.
+type Target struct {
+	options Options
+	backoff targets.Backoff
+
+	conn   *grpc.ClientConn
+	client logpb.LogServiceClient
+
+	mux     sync.Mutex
+	stream  logpb.LogService_StreamClient
+	cancel  context.CancelFunc
+	seq     uint64
+	pending []*logpb.LogRecord // sent but not acknowledged, in sequence order
+	window  int
+
+	notify   chan struct{} // signalled when records are acknowledged or the stream breaks
+	shutdown chan struct{}
+	once     sync.Once
+}
+
+// NewTarget creates a target capable of streaming log records to a `LogService` collector.
+func NewTarget(options Options) (*Target, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+	t := &Target{
+		options:  options,
+		backoff:  targets.DefaultBackoff(),
+		window:   options.Window,
+		notify:   make(chan struct{}, 1),
+		shutdown: make(chan struct{}),
+	}
+	if t.window == 0 {
+		t.window = DefaultWindow
+	}
+	if options.Backoff != nil {
+		t.backoff = options.Backoff
+	}
+	return t, nil
+}
+
+// Init is called once to initialize the target.
+func (t *Target) Init() error {
+	dialOpts := t.options.DialOptions
+	if len(dialOpts) == 0 {
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(t.options.Address, dialOpts...)
+	if err != nil {
+		return fmt.Errorf("log target %s client error: %w", t, err)
+	}
+	t.conn = conn
+	t.client = logpb.NewLogServiceClient(conn)
+	return nil
+}
+
+// Write sends the log record to the collector.
+// Called by dedicated target goroutine and will block while the window is full.
+func (t *Target) Write(p []byte, rec *logr.LogRec) (int, error) {
+	return t.WriteContext(context.Background(), p, rec)
+}
+
+// WriteContext is like Write but gives up once ctx is done, returning the context error.
+func (t *Target) WriteContext(ctx context.Context, p []byte, rec *logr.LogRec) (int, error) {
+	lr := newLogRecord(rec)
+	if t.options.IncludeFormatted {
+		lr.Formatted = append([]byte(nil), p...)
+	}
+
+	for attempt := 0; ; {
+		stream, full := t.state()
+		if stream == nil {
+			if attempt > 0 {
+				if err := t.wait(ctx, t.backoff.Delay(attempt)); err != nil {
+					return 0, err
+				}
+			}
+			attempt++
+			if err := t.connect(); err != nil {
+				rec.Logger().Logr().ReportError(fmt.Errorf("log target %s connection error: %w", t, err))
+			}
+			continue
+		}
+		attempt = 0
+
+		if full {
+			if err := t.wait(ctx, 0); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		t.mux.Lock()
+		t.seq++
+		lr.Sequence = t.seq
+		t.pending = append(t.pending, lr)
+		t.mux.Unlock()
+
+		// The record is pending, so it is resent after a reconnect if the send fails.
+		if err := stream.Send(lr); err != nil {
+			t.resetStream(stream)
+			rec.Logger().Logr().ReportError(fmt.Errorf("log target %s send error: %w", t, err))
+		}
+		return len(p), nil
+	}
+}
+
+// Flush waits for all records sent to be acknowledged by the collector.
+func (t *Target) Flush() error {
+	timeout := DefaultFlushTimeout
+	if t.options.FlushTimeoutMillis > 0 {
+		timeout = time.Duration(t.options.FlushTimeoutMillis) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for attempt := 0; ; {
+		t.mux.Lock()
+		stream, outstanding := t.stream, len(t.pending)
+		t.mux.Unlock()
+
+		if outstanding == 0 {
+			return nil
+		}
+
+		var err error
+		if stream == nil {
+			if attempt > 0 {
+				err = t.wait(ctx, t.backoff.Delay(attempt))
+			}
+			attempt++
+			if err == nil {
+				_ = t.connect()
+			}
+		} else {
+			attempt = 0
+			err = t.wait(ctx, 0)
+		}
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("%d records not acknowledged: %w", outstanding, err)
+			}
+			return err
+		}
+	}
+}
+
+// Shutdown waits for outstanding records to be acknowledged and closes the connection.
+func (t *Target) Shutdown() error {
+	var errs []error
+	if t.client != nil {
+		if err := t.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.once.Do(func() { close(t.shutdown) })
+
+	t.mux.Lock()
+	if t.stream != nil {
+		_ = t.stream.CloseSend()
+		t.cancel()
+		t.stream = nil
+	}
+	t.mux.Unlock()
+
+	if t.conn != nil {
+		if err := t.conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("log target %s shutdown error: %v", t, errs)
+	}
+	return nil
+}
+
+// String returns a string representation of this target.
+func (t *Target) String() string {
+	return fmt.Sprintf("GRPCTarget[%s]", t.options.Address)
+}
+
+// state returns the current stream, or nil if not connected, and whether the
+// window is full.
+func (t *Target) state() (logpb.LogService_StreamClient, bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.stream, len(t.pending) >= t.window
+}
+
+// wait blocks until the delay expires or, if delay is zero, until notified.
+func (t *Target) wait(ctx context.Context, delay time.Duration) error {
+	var wake <-chan struct{} = t.notify
+	var timer <-chan time.Time
+	if delay > 0 {
+		tm := time.NewTimer(delay)
+		defer tm.Stop()
+		timer = tm.C
+		wake = nil
+	}
+	select {
+	case <-wake:
+	case <-timer:
+	case <-ctx.Done():
+		return fmt.Errorf("log target %s write aborted: %w", t, ctx.Err())
+	case <-t.shutdown:
+		return fmt.Errorf("log target %s is shut down", t)
+	}
+	return nil
+}
+
+// connect opens a new stream and resends any records not yet acknowledged.
+func (t *Target) connect() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := t.client.Stream(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	t.mux.Lock()
+	t.stream = stream
+	t.cancel = cancel
+	resend := append([]*logpb.LogRecord(nil), t.pending...)
+	t.mux.Unlock()
+
+	go t.receive(stream)
+
+	for _, lr := range resend {
+		if err := stream.Send(lr); err != nil {
+			t.resetStream(stream)
+			return err
+		}
+	}
+	return nil
+}
+
+// receive processes acks from the collector until the stream breaks.
+func (t *Target) receive(stream logpb.LogService_StreamClient) {
+	for {
+		ack, err := stream.Recv()
+		if err != nil {
+			t.resetStream(stream)
+			return
+		}
+
+		t.mux.Lock()
+		i := 0
+		for i < len(t.pending) && t.pending[i].Sequence <= ack.Sequence {
+			t.pending[i] = nil
+			i++
+		}
+		t.pending = t.pending[i:]
+		if ack.Window > 0 {
+			t.window = int(ack.Window)
+		}
+		t.mux.Unlock()
+		t.signal()
+	}
+}
+
+// resetStream discards the stream, if still current, so the next write reconnects.
+func (t *Target) resetStream(stream logpb.LogService_StreamClient) {
+	t.mux.Lock()
+	if t.stream == stream {
+		t.cancel()
+		t.stream = nil
+	}
+	t.mux.Unlock()
+	t.signal()
+}
+
+func (t *Target) signal() {
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
+
+// newLogRecord converts a log record to its protobuf representation.
+func newLogRecord(rec *logr.LogRec) *logpb.LogRecord {
+	lr := &logpb.LogRecord{
+		TimeUnixNano: rec.Time().UnixNano(),
+		Level:        strings.TrimSpace(rec.Level().Name),
+		LevelId:      int32(rec.Level().ID),
+		Msg:          rec.Msg(),
+		Caller:       rec.Caller(),
+	}
+
+	fields := rec.Fields()
+	lr.Fields = make([]*logpb.Field, 0, len(fields))
+	for _, f := range fields {
+		lr.Fields = append(lr.Fields, newField(f))
+	}
+
+	for _, frame := range rec.StackFrames() {
+		lr.Stacktrace = append(lr.Stacktrace, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+	}
+	return lr
+}
+
+// newField converts a field to its protobuf representation, keeping the type of
+// numeric, boolean and binary values.
+func newField(f logr.Field) *logpb.Field {
+	pf := &logpb.Field{Key: f.Key}
+	switch f.Type {
+	case logr.BoolType:
+		pf.Value = &logpb.Field_BoolValue{BoolValue: f.Integer != 0}
+	case logr.Int64Type, logr.Int32Type, logr.IntType, logr.DurationType, logr.TimestampMillisType:
+		pf.Value = &logpb.Field_IntValue{IntValue: f.Integer}
+	case logr.Uint64Type, logr.Uint32Type, logr.UintType:
+		pf.Value = &logpb.Field_UintValue{UintValue: uint64(f.Integer)}
+	case logr.Float64Type, logr.Float32Type:
+		pf.Value = &logpb.Field_DoubleValue{DoubleValue: f.Float}
+	case logr.StringType:
+		pf.Value = &logpb.Field_StringValue{StringValue: f.String}
+	default:
+		if b, ok := f.Interface.([]byte); ok && f.Type == logr.BinaryType {
+			pf.Value = &logpb.Field_BytesValue{BytesValue: b}
+			break
+		}
+		var sb strings.Builder
+		_ = f.ValueString(&sb, nil)
+		pf.Value = &logpb.Field_StringValue{StringValue: sb.String()}
+	}
+	return pf
+}
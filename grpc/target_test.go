@@ -0,0 +1,172 @@
+package logrgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/grpc/logpb"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type collector struct {
+	mux  sync.Mutex
+	recs []*logpb.LogRecord
+}
+
+func (c *collector) handle(ctx context.Context, rec *logpb.LogRecord) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.recs = append(c.recs, rec)
+	return nil
+}
+
+func (c *collector) msgs() []string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var msgs []string
+	for _, rec := range c.recs {
+		msgs = append(msgs, rec.Msg)
+	}
+	return msgs
+}
+
+// startServer starts a LogService on an in-memory listener and returns a target connected to it.
+func startServer(t *testing.T, handler Handler, window uint32, options Options) *Target {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	logpb.RegisterLogServiceServer(srv, NewServer(handler, window))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	options.Address = "passthrough:///bufnet"
+	options.DialOptions = []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	}
+	target, err := NewTarget(options)
+	require.NoError(t, err)
+	return target
+}
+
+func TestTarget(t *testing.T) {
+	c := &collector{}
+	target := startServer(t, c.handle, 0, Options{IncludeFormatted: true})
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	err = lgr.AddTarget(target, "grpc_test", &logr.StdFilter{Lvl: logr.Info}, nil, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "wiggin"))
+	logger.Info("first", logr.Int("count", 3), logr.Bool("ok", true), logr.Float64("ratio", 0.5))
+	logger.Warn("second")
+	require.NoError(t, lgr.Flush())
+
+	require.Equal(t, []string{"first", "second"}, c.msgs())
+	first := c.recs[0]
+	assert.Equal(t, uint64(1), first.Sequence)
+	assert.Equal(t, "info", first.Level)
+	assert.NotEmpty(t, first.Formatted)
+	require.Len(t, first.Fields, 4)
+	assert.Equal(t, "wiggin", first.Fields[0].GetStringValue())
+	assert.Equal(t, int64(3), first.Fields[1].GetIntValue())
+	assert.True(t, first.Fields[2].GetBoolValue())
+	assert.Equal(t, 0.5, first.Fields[3].GetDoubleValue())
+
+	require.NoError(t, lgr.Shutdown())
+}
+
+func TestTargetBackpressure(t *testing.T) {
+	c := &collector{}
+	release := make(chan struct{})
+	handler := func(ctx context.Context, rec *logpb.LogRecord) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return c.handle(ctx, rec)
+	}
+	target := startServer(t, handler, 2, Options{Window: 100})
+	require.NoError(t, target.Init())
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	rec := logr.NewLogRec(logr.Info, lgr.NewLogger(), "msg", nil, false)
+
+	// The server's window of 2 overrides the client's, so the third write blocks.
+	_, err = target.Write(nil, rec)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		target.mux.Lock()
+		defer target.mux.Unlock()
+		return target.window == 2
+	}, time.Second, time.Millisecond*10)
+	_, err = target.Write(nil, rec)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	_, err = target.WriteContext(ctx, nil, rec)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	close(release)
+	_, err = target.Write(nil, rec)
+	require.NoError(t, err)
+	require.NoError(t, target.Flush())
+	assert.Len(t, c.msgs(), 3)
+
+	require.NoError(t, target.Shutdown())
+	require.NoError(t, lgr.Shutdown())
+}
+
+func TestTargetResend(t *testing.T) {
+	c := &collector{}
+	var failOnce sync.Once
+	handler := func(ctx context.Context, rec *logpb.LogRecord) error {
+		var err error
+		if rec.Msg == "second" {
+			failOnce.Do(func() { err = errors.New("collector unavailable") })
+		}
+		if err != nil {
+			return err
+		}
+		return c.handle(ctx, rec)
+	}
+	backoff := &targets.ExponentialBackoff{InitialMillis: 10, MaxMillis: 50}
+	target := startServer(t, handler, 0, Options{Backoff: backoff})
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	err = lgr.AddTarget(target, "grpc_test", &logr.StdFilter{Lvl: logr.Info}, nil, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("first")
+	logger.Info("second")
+	logger.Info("third")
+	require.NoError(t, lgr.Flush())
+
+	// Unacknowledged records are resent once the stream is re-established, so
+	// records may be received more than once but none are lost.
+	seen := make(map[uint64]string)
+	c.mux.Lock()
+	for _, rec := range c.recs {
+		seen[rec.Sequence] = rec.Msg
+	}
+	c.mux.Unlock()
+	assert.Equal(t, map[uint64]string{1: "first", 2: "second", 3: "third"}, seen)
+	require.NoError(t, lgr.Shutdown())
+}
@@ -0,0 +1,126 @@
+package logr
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldHint requests a rendering by `Field.ValueString`. For numeric fields it is
+// stored in `Field.Interface` and requests human friendly rendering; formatters that
+// encode numbers natively, such as JSON, ignore the hint and output the raw value.
+// For binary fields it is stored in `Field.Integer` and selects the encoding.
+type fieldHint uint8
+
+const (
+	hintNone fieldHint = iota
+	hintBytes
+	hintDuration
+	hintPercent
+	hintHex
+	hintBase64
+)
+
+// Bytes constructs a field containing a size in bytes. The raw value is output by
+// JSON formatters while plain text formatters render it using binary units, e.g. "1.4 MiB".
+func Bytes(key string, n int64) Field {
+	return Field{Key: key, Type: Int64Type, Integer: n, Interface: hintBytes}
+}
+
+// DurationHuman constructs a field containing a duration. The raw value in nanoseconds
+// is output by JSON formatters while plain text formatters render it rounded to the
+// two most significant units, e.g. "2h 5m" or "1.5s".
+func DurationHuman(key string, d time.Duration) Field {
+	return Field{Key: key, Type: Int64Type, Integer: int64(d), Interface: hintDuration}
+}
+
+// Percent constructs a field containing a fraction, where 1.0 is 100%. The raw value
+// is output by JSON formatters while plain text formatters render it as a
+// percentage, e.g. "42.5%".
+func Percent(key string, f float64) Field {
+	return Field{Key: key, Type: Float64Type, Float: f, Interface: hintPercent}
+}
+
+// writeHinted writes the human friendly rendering of a field, returning false if
+// the field has no hint.
+func writeHinted(w io.Writer, f Field, shouldQuote func(s string) bool) (bool, error) {
+	hint, ok := f.Interface.(fieldHint)
+	if !ok || hint == hintNone {
+		return false, nil
+	}
+
+	var s string
+	switch hint {
+	case hintBytes:
+		s = humanBytes(f.Integer)
+	case hintDuration:
+		s = humanDuration(time.Duration(f.Integer))
+	case hintPercent:
+		s = trimZeroDecimal(strconv.FormatFloat(f.Float*100, 'f', 1, 64)) + "%"
+	default:
+		return false, nil
+	}
+	return true, quoteString(w, s, shouldQuote)
+}
+
+var byteUnits = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+func humanBytes(n int64) string {
+	sign := ""
+	v := float64(n)
+	if n < 0 {
+		sign = "-"
+		v = -v
+	}
+	if v < 1024 {
+		return sign + strconv.FormatFloat(v, 'f', -1, 64) + " B"
+	}
+	unit := -1
+	for v >= 1024 && unit < len(byteUnits)-1 {
+		v /= 1024
+		unit++
+	}
+	return sign + trimZeroDecimal(strconv.FormatFloat(v, 'f', 1, 64)) + " " + byteUnits[unit]
+}
+
+func humanDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	const day = 24 * time.Hour
+	var s string
+	switch {
+	case d < time.Microsecond:
+		s = strconv.FormatInt(int64(d), 10) + "ns"
+	case d < time.Millisecond:
+		s = trimZeroDecimal(strconv.FormatFloat(float64(d)/float64(time.Microsecond), 'f', 1, 64)) + "µs"
+	case d < time.Second:
+		s = trimZeroDecimal(strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64)) + "ms"
+	case d < time.Minute:
+		s = trimZeroDecimal(strconv.FormatFloat(d.Seconds(), 'f', 1, 64)) + "s"
+	case d < time.Hour:
+		s = twoUnits(d, time.Minute, "m", time.Second, "s")
+	case d < day:
+		s = twoUnits(d, time.Hour, "h", time.Minute, "m")
+	default:
+		s = twoUnits(d, day, "d", time.Hour, "h")
+	}
+	return sign + s
+}
+
+func twoUnits(d time.Duration, major time.Duration, majorName string, minor time.Duration, minorName string) string {
+	d = d.Round(minor)
+	s := strconv.FormatInt(int64(d/major), 10) + majorName
+	if rem := (d % major) / minor; rem != 0 {
+		s += " " + strconv.FormatInt(int64(rem), 10) + minorName
+	}
+	return s
+}
+
+func trimZeroDecimal(s string) string {
+	return strings.TrimSuffix(s, ".0")
+}
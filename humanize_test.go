@@ -0,0 +1,67 @@
+package logr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHumanBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                      "0 B",
+		512:                    "512 B",
+		1024:                   "1 KiB",
+		1536:                   "1.5 KiB",
+		1468006:                "1.4 MiB",
+		5 * 1024 * 1024 * 1024: "5 GiB",
+		-2048:                  "-2 KiB",
+	}
+	for n, want := range tests {
+		assert.Equal(t, want, humanBytes(n), n)
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		500:                           "500ns",
+		1500 * time.Microsecond:       "1.5ms",
+		time.Second * 3 / 2:           "1.5s",
+		5*time.Minute + 3*time.Second: "5m 3s",
+		2*time.Hour + 5*time.Minute:   "2h 5m",
+		2 * time.Hour:                 "2h",
+		50*time.Hour + 29*time.Minute: "2d 2h",
+		-(90 * time.Second):           "-1m 30s",
+	}
+	for d, want := range tests {
+		assert.Equal(t, want, humanDuration(d), d.String())
+	}
+}
+
+func TestHumanFields(t *testing.T) {
+	valueString := func(f Field) string {
+		var sb strings.Builder
+		assert.NoError(t, f.ValueString(&sb, nil))
+		return sb.String()
+	}
+	assert.Equal(t, "1.4 MiB", valueString(Bytes("size", 1468006)))
+	assert.Equal(t, "2h 5m", valueString(DurationHuman("uptime", 2*time.Hour+5*time.Minute)))
+	assert.Equal(t, "42.5%", valueString(Percent("cpu", 0.425)))
+
+	// raw values are preserved.
+	assert.Equal(t, int64(1468006), Bytes("size", 1468006).Integer)
+	assert.Equal(t, 0.425, Percent("cpu", 0.425).Float)
+}
+
+func TestBinaryHints(t *testing.T) {
+	valueString := func(f Field) string {
+		var sb strings.Builder
+		assert.NoError(t, f.ValueString(&sb, nil))
+		return sb.String()
+	}
+	b := []byte{0xde, 0xad, 0xbe, 0xef, 0x01}
+	assert.Equal(t, "deadbeef01", valueString(Hex("checksum", b)))
+	assert.Equal(t, "3q2+7wE=", valueString(Base64("key", b)))
+	assert.Equal(t, "[DEADBEEF01]", valueString(Any("raw", b)))
+}
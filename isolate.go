@@ -0,0 +1,43 @@
+package logr
+
+import "errors"
+
+// IsolateLevels marks levels, such as a custom Security or Audit level, as isolated:
+// their records are only delivered to targets added with `AllowIsolatedLevels` for
+// that level, even if other targets' filters would accept them. This also applies
+// to records rerouted by `OnTargetQueueFull`, and they are never written to the
+// `CrashTarget`. Opted-in targets must still enable the level via their filter.
+func IsolateLevels(levels ...Level) Option {
+	return func(l *Logr) error {
+		if len(levels) == 0 {
+			return errors.New("at least one level must be isolated")
+		}
+		if l.options.isolatedLevels == nil {
+			l.options.isolatedLevels = make(map[LevelID]struct{})
+		}
+		for _, lvl := range levels {
+			l.options.isolatedLevels[lvl.ID] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// AllowIsolatedLevels opts a target in to receiving records at the specified levels
+// isolated via `IsolateLevels`.
+func AllowIsolatedLevels(levels ...Level) TargetOption {
+	return func(opts *targetHostOptions) error {
+		if opts.allowed == nil {
+			opts.allowed = make(map[LevelID]struct{})
+		}
+		for _, lvl := range levels {
+			opts.allowed[lvl.ID] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// isIsolated returns true if the level was isolated via `IsolateLevels`.
+func (lgr *Logr) isIsolated(lvl Level) bool {
+	_, ok := lgr.options.isolatedLevels[lvl.ID]
+	return ok
+}
@@ -0,0 +1,38 @@
+package logr_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsolateLevels(t *testing.T) {
+	security := logr.Level{ID: 150, Name: "security"}
+	lgr, err := logr.New(logr.IsolateLevels(security))
+	require.NoError(t, err)
+
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	general := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(general), "general", logr.NewCustomFilter(logr.Info, security), formatter, 100)
+	require.NoError(t, err)
+	audit := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(audit), "audit", logr.NewCustomFilter(security), formatter, 100,
+		logr.AllowIsolatedLevels(security))
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("request")
+	logger.Log(security, "login failed", logr.String("user", "bob"))
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, "info request \n", general.String())
+	assert.Equal(t, "security login failed user=bob\n", audit.String())
+
+	_, err = logr.New(logr.IsolateLevels())
+	assert.Error(t, err)
+}
@@ -1,5 +1,10 @@
 package logr
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 var AnsiColorPrefix = []byte("\u001b[")
 var AnsiColorSuffix = []byte("m")
 
@@ -32,3 +37,81 @@ type Level struct {
 func (level Level) String() string {
 	return level.Name
 }
+
+// LevelDisplay customizes how formatters render a level. See `SetLevelDisplay`.
+type LevelDisplay struct {
+	// Name, when not empty, replaces the level name output by text formatters such
+	// as Plain, e.g. "WARNING" instead of "warn".
+	Name string
+
+	// JSONName, when not empty, replaces the level name output by structured
+	// formatters such as JSON. Defaults to Name.
+	JSONName string
+
+	// Color, when not NoColor, replaces the level color.
+	Color Color
+}
+
+// levelDisplays holds a map[LevelID]LevelDisplay, replaced on each change
+// while holding levelDisplaysMux.
+var (
+	levelDisplays    atomic.Value
+	levelDisplaysMux sync.Mutex
+)
+
+// SetLevelDisplay customizes, for all formatters, how levels with the same ID as
+// the specified level are rendered, e.g. to output "WARNING" instead of "warn"
+// for the built-in Warn level without redefining filters. Only rendering is
+// affected; level names used by filters and configuration are unchanged. A zero
+// LevelDisplay restores the default rendering.
+func SetLevelDisplay(level Level, display LevelDisplay) {
+	levelDisplaysMux.Lock()
+	defer levelDisplaysMux.Unlock()
+
+	old, _ := levelDisplays.Load().(map[LevelID]LevelDisplay)
+	displays := make(map[LevelID]LevelDisplay, len(old)+1)
+	for id, d := range old {
+		displays[id] = d
+	}
+	if display == (LevelDisplay{}) {
+		delete(displays, level.ID)
+	} else {
+		displays[level.ID] = display
+	}
+	levelDisplays.Store(displays)
+}
+
+func (level Level) display() (LevelDisplay, bool) {
+	displays, _ := levelDisplays.Load().(map[LevelID]LevelDisplay)
+	d, ok := displays[level.ID]
+	return d, ok
+}
+
+// DisplayName returns the name text formatters output for this level.
+func (level Level) DisplayName() string {
+	if d, ok := level.display(); ok && d.Name != "" {
+		return d.Name
+	}
+	return level.Name
+}
+
+// JSONName returns the name structured formatters output for this level.
+func (level Level) JSONName() string {
+	if d, ok := level.display(); ok {
+		if d.JSONName != "" {
+			return d.JSONName
+		}
+		if d.Name != "" {
+			return d.Name
+		}
+	}
+	return level.Name
+}
+
+// DisplayColor returns the color formatters use for this level.
+func (level Level) DisplayColor() Color {
+	if d, ok := level.display(); ok && d.Color != NoColor {
+		return d.Color
+	}
+	return level.Color
+}
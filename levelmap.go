@@ -0,0 +1,113 @@
+package logr
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// LevelMapping configures a `LevelMapper`.
+type LevelMapping struct {
+	// Names maps external level names, matched case insensitively, to levels. The
+	// entries are added to, or replace, the default names.
+	Names map[string]Level `json:"names,omitempty"`
+
+	// Verbosity maps verbosity V(i) to Verbosity[i]; verbosities beyond the end
+	// map to the last level. Replaces the default of Info for V(0), Debug for
+	// V(1) to V(3) and Trace for V(4) and above.
+	Verbosity []Level `json:"verbosity,omitempty"`
+}
+
+// LevelMapper maps levels of external logging APIs, expressed as names such as
+// "WARNING" or verbosity integers such as V(4), to logr levels. Adapters bridging
+// other logging APIs, and readers of other log formats, share a mapper so that
+// they agree on how foreign levels map into the level set. A LevelMapper is
+// immutable and safe for concurrent use.
+type LevelMapper struct {
+	names     map[string]Level
+	verbosity []Level
+}
+
+var defaultLevelNames = map[string]Level{
+	"panic":         Panic,
+	"emerg":         Panic,
+	"emergency":     Panic,
+	"alert":         Panic,
+	"fatal":         Fatal,
+	"crit":          Fatal,
+	"critical":      Fatal,
+	"error":         Error,
+	"err":           Error,
+	"warn":          Warn,
+	"warning":       Warn,
+	"info":          Info,
+	"information":   Info,
+	"informational": Info,
+	"notice":        Info,
+	"debug":         Debug,
+	"trace":         Trace,
+}
+
+var defaultVerbosity = []Level{Info, Debug, Debug, Debug, Trace}
+
+// NewLevelMapper creates a LevelMapper from the default mapping, covering the
+// standard level names and common aliases such as "warning", "err", "critical"
+// and the syslog severities, amended by the specified mapping.
+func NewLevelMapper(mapping LevelMapping) *LevelMapper {
+	m := &LevelMapper{
+		names:     make(map[string]Level, len(defaultLevelNames)+len(mapping.Names)),
+		verbosity: defaultVerbosity,
+	}
+	for name, lvl := range defaultLevelNames {
+		m.names[name] = lvl
+	}
+	for name, lvl := range mapping.Names {
+		m.names[strings.ToLower(name)] = lvl
+	}
+	if len(mapping.Verbosity) > 0 {
+		m.verbosity = append([]Level(nil), mapping.Verbosity...)
+	}
+	return m
+}
+
+// Name returns the level mapped to by an external level name, matched case
+// insensitively, or false if the name is not mapped.
+func (m *LevelMapper) Name(name string) (Level, bool) {
+	lvl, ok := m.names[name]
+	if !ok {
+		lvl, ok = m.names[strings.ToLower(name)]
+	}
+	return lvl, ok
+}
+
+// Verbosity returns the level mapped to by an external verbosity, where V(0) is
+// the least verbose. Negative verbosities are treated as zero.
+func (m *LevelMapper) Verbosity(v int) Level {
+	if v < 0 {
+		v = 0
+	}
+	if v >= len(m.verbosity) {
+		v = len(m.verbosity) - 1
+	}
+	return m.verbosity[v]
+}
+
+var levelMapper atomic.Value
+
+// SetLevelMapper replaces the LevelMapper returned by `GetLevelMapper`, used by
+// adapters that are not given their own. Nil restores the default mapping.
+func SetLevelMapper(m *LevelMapper) {
+	if m == nil {
+		m = NewLevelMapper(LevelMapping{})
+	}
+	levelMapper.Store(m)
+}
+
+// GetLevelMapper returns the LevelMapper shared by adapters that are not given
+// their own.
+func GetLevelMapper() *LevelMapper {
+	return levelMapper.Load().(*LevelMapper)
+}
+
+func init() {
+	SetLevelMapper(nil)
+}
@@ -0,0 +1,48 @@
+package logr_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelMapper(t *testing.T) {
+	mapper := logr.NewLevelMapper(logr.LevelMapping{})
+
+	for name, want := range map[string]logr.Level{
+		"info":     logr.Info,
+		"WARNING":  logr.Warn,
+		"Err":      logr.Error,
+		"CRITICAL": logr.Fatal,
+		"emerg":    logr.Panic,
+	} {
+		lvl, ok := mapper.Name(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, lvl, name)
+	}
+	_, ok := mapper.Name("audit")
+	assert.False(t, ok)
+
+	assert.Equal(t, logr.Info, mapper.Verbosity(-1))
+	assert.Equal(t, logr.Info, mapper.Verbosity(0))
+	assert.Equal(t, logr.Debug, mapper.Verbosity(1))
+	assert.Equal(t, logr.Trace, mapper.Verbosity(4))
+	assert.Equal(t, logr.Trace, mapper.Verbosity(10))
+
+	audit := logr.Level{ID: 100, Name: "audit"}
+	mapper = logr.NewLevelMapper(logr.LevelMapping{
+		Names:     map[string]logr.Level{"Audit": audit, "warning": logr.Error},
+		Verbosity: []logr.Level{logr.Info, logr.Trace},
+	})
+	lvl, _ := mapper.Name("AUDIT")
+	assert.Equal(t, audit, lvl)
+	lvl, _ = mapper.Name("warning")
+	assert.Equal(t, logr.Error, lvl)
+	assert.Equal(t, logr.Trace, mapper.Verbosity(1))
+
+	logr.SetLevelMapper(mapper)
+	defer logr.SetLevelMapper(nil)
+	lvl, _ = logr.GetLevelMapper().Name("audit")
+	assert.Equal(t, audit, lvl)
+}
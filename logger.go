@@ -1,11 +1,41 @@
 package logr
 
-import "log"
+import (
+	"log"
+	"time"
+)
 
 // Logger provides context for logging via fields.
 type Logger struct {
-	lgr    *Logr
+	lgr      *Logr
+	fields   *fieldChain
+	minLevel *Level
+}
+
+// fieldChain holds the fields added by one call to `Logger.With` plus a link to
+// the fields of the parent logger. Chains are immutable once created so derived
+// loggers share their parent's fields instead of copying them.
+type fieldChain struct {
+	parent *fieldChain
 	fields []Field
+	size   int // number of fields including all parents
+}
+
+// appendTo appends all fields in the chain to dst, parent fields first.
+func (fc *fieldChain) appendTo(dst []Field) []Field {
+	if fc == nil {
+		return dst
+	}
+	dst = fc.parent.appendTo(dst)
+	return append(dst, fc.fields...)
+}
+
+// len returns the number of fields in the chain.
+func (fc *fieldChain) len() int {
+	if fc == nil {
+		return 0
+	}
+	return fc.size
 }
 
 // Logr returns the `Logr` instance that created this `Logger`.
@@ -14,17 +44,38 @@ func (logger Logger) Logr() *Logr {
 }
 
 // With creates a new `Logger` with any existing fields plus the new ones.
+// Existing fields are shared with this `Logger` rather than copied, making it
+// cheap to derive request scoped loggers from a logger with many fields.
 func (logger Logger) With(fields ...Field) Logger {
-	l := Logger{lgr: logger.lgr}
-	size := len(logger.fields) + len(fields)
-	if size > 0 {
-		l.fields = make([]Field, 0, size)
-		l.fields = append(l.fields, logger.fields...)
-		l.fields = append(l.fields, fields...)
+	l := logger
+	if len(fields) > 0 {
+		// copy the new fields since the caller may reuse the slice.
+		fc := &fieldChain{
+			parent: logger.fields,
+			fields: make([]Field, len(fields)),
+			size:   logger.fields.len() + len(fields),
+		}
+		copy(fc.fields, fields)
+		l.fields = fc
 	}
 	return l
 }
 
+// WithMinLevel creates a new `Logger` with any existing fields that only emits
+// records with a level at least as severe as the specified level, regardless of
+// what the targets accept. This allows a noisy subsystem to be muted at the source.
+// Levels are compared by ID in the same manner as `StdFilter`.
+func (logger Logger) WithMinLevel(level Level) Logger {
+	l := logger
+	l.minLevel = &level
+	return l
+}
+
+// isMuted returns true if the level is less severe than the logger's minimum level.
+func (logger Logger) isMuted(level Level) bool {
+	return logger.minLevel != nil && level.ID > logger.minLevel.ID
+}
+
 // StdLogger creates a standard logger backed by this `Logr.Logger` instance.
 // All log records are emitted with the specified log level.
 func (logger Logger) StdLogger(level Level) *log.Logger {
@@ -34,6 +85,9 @@ func (logger Logger) StdLogger(level Level) *log.Logger {
 // IsLevelEnabled determines if the specified level is enabled for at least
 // one log target.
 func (logger Logger) IsLevelEnabled(level Level) bool {
+	if logger.isMuted(level) {
+		return false
+	}
 	status := logger.Logr().IsLevelEnabled(level)
 	return status.Enabled
 }
@@ -49,13 +103,33 @@ func (logger Logger) Sugar(fields ...Field) Sugar {
 // if so, generates a log record that is added to the Logr queue.
 // Arguments are handled in the manner of fmt.Print.
 func (logger Logger) Log(lvl Level, msg string, fields ...Field) {
+	if logger.isMuted(lvl) {
+		return
+	}
 	status := logger.lgr.IsLevelEnabled(lvl)
-	if status.Enabled {
+	if status.Enabled && !logger.lgr.isSampledOut(lvl, msg) {
+		fields = logger.lgr.enrich(lvl, fields)
 		rec := NewLogRec(lvl, logger, msg, fields, status.Stacktrace)
 		logger.lgr.enqueue(rec)
 	}
 }
 
+// LogWithTime is like `Log` but the log record uses the supplied time stamp
+// instead of the current time, and no stack trace is generated. This is useful
+// when re-emitting records that were created elsewhere, such as when replaying
+// archived logs.
+func (logger Logger) LogWithTime(t time.Time, lvl Level, msg string, fields ...Field) {
+	if logger.isMuted(lvl) {
+		return
+	}
+	status := logger.lgr.IsLevelEnabled(lvl)
+	if status.Enabled {
+		rec := NewLogRec(lvl, logger, msg, fields, false)
+		rec.time = t
+		logger.lgr.enqueue(rec)
+	}
+}
+
 // LogM calls `Log` multiple times, one for each level provided.
 func (logger Logger) LogM(levels []Level, msg string, fields ...Field) {
 	for _, lvl := range levels {
@@ -0,0 +1,84 @@
+package logr_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerWithMinLevel(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "minLevelTest", &logr.StdFilter{Lvl: logr.Debug},
+		&formatters.Plain{DisableTimestamp: true, DisableLevel: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	noisy := logger.WithMinLevel(logr.Warn).With(logr.String("sub", "noisy"))
+
+	assert.True(t, logger.IsLevelEnabled(logr.Debug))
+	assert.False(t, noisy.IsLevelEnabled(logr.Info))
+	assert.True(t, noisy.IsLevelEnabled(logr.Error))
+
+	logger.Debug("one", logr.Int("n", 1))
+	noisy.Debug("muted")
+	noisy.InfoCtx(context.Background(), "muted")
+	noisy.Sugar().Infof("muted %d", 1)
+	noisy.Warn("two")
+	noisy.WithMinLevel(logr.Debug).Debug("three")
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{"one n=1", "two sub=noisy", "three sub=noisy"}, lines)
+}
+
+func TestFieldConflicts(t *testing.T) {
+	var conflicts []string
+	lgr, err := logr.New(logr.OnFieldConflict(func(rec *logr.LogRec, key string) {
+		conflicts = append(conflicts, rec.Msg()+":"+key)
+	}))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "conflictTest", &logr.StdFilter{Lvl: logr.Debug},
+		&formatters.JSON{DisableTimestamp: true, DisableLevel: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "a"), logr.Int("id", 1)).With(logr.String("user", "b"))
+	logger.Info("with", logr.Bool("ok", true))
+	logger.Info("call", logr.Int("id", 2), logr.Int("id", 3))
+
+	many := make([]logr.Field, 0, 40)
+	for i := 0; i < 20; i++ {
+		many = append(many, logr.Int(fmt.Sprintf("k%d", i), i))
+	}
+	many = append(many, logr.Int("k0", 99), logr.Int("k19", 99))
+	lgr.NewLogger().Info("many", many...)
+	// empty keys, e.g. positional sugar args, are not conflicts.
+	lgr.NewLogger().Info("positional", logr.String("", "a"), logr.String("", "b"))
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, `{"msg":"with","user":"b","id":1,"ok":true}`, lines[0])
+	assert.Equal(t, `{"msg":"call","user":"b","id":3}`, lines[1])
+	assert.Contains(t, lines[2], `"k0":99,"k1":1,`)
+	assert.Contains(t, lines[2], `"k18":18,"k19":99}`)
+	assert.Equal(t, `{"msg":"positional","":"a","":"b"}`, lines[3])
+
+	assert.Equal(t, []string{"with:user", "call:user", "call:id", "many:k0", "many:k19"}, conflicts)
+}
@@ -0,0 +1,232 @@
+package logr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Histogram is a metrics sink that observes values, such as durations, into buckets.
+// Implementations are external to Logr and provided via `LogMetricsCollector`.
+type Histogram interface {
+	// Observe adds a single observation to the histogram.
+	Observe(float64)
+}
+
+// LogMetricsCollector is optionally implemented by a `MetricsCollector` to provide
+// the metrics derived from log records by `LogMetrics` rules.
+type LogMetricsCollector interface {
+	// LogMetricCounter returns a Counter for the named metric and label values. It is
+	// called once for each combination of label values.
+	LogMetricCounter(name string, labels map[string]string) (Counter, error)
+
+	// LogMetricHistogram returns a Histogram for the named metric and label values.
+	// It is called once for each combination of label values.
+	LogMetricHistogram(name string, labels map[string]string) (Histogram, error)
+}
+
+// MetricRule derives a metric from the log records it matches. See `LogMetrics`.
+type MetricRule struct {
+	// Name is the name of the metric.
+	Name string `json:"name"`
+
+	// Levels, when not empty, restricts the rule to records with one of these levels.
+	Levels []Level `json:"levels,omitempty"`
+
+	// MsgPattern, when not empty, is a regular expression the record message must match.
+	MsgPattern string `json:"msg_pattern,omitempty"`
+
+	// Field, when not empty, is the key of a field the record must contain. For
+	// histograms, its numeric value is observed, with durations in seconds.
+	Field string `json:"field,omitempty"`
+
+	// Histogram, when true, observes the value of Field into a histogram instead of
+	// incrementing a counter.
+	Histogram bool `json:"histogram,omitempty"`
+
+	// Labels are the keys of fields whose values become the metric's labels. Missing
+	// fields have an empty label value.
+	Labels []string `json:"labels,omitempty"`
+}
+
+// LogMetrics derives metrics from existing log statements without re-instrumenting.
+// Each record passing through the Logr is matched against the rules; for each
+// matching rule a counter is incremented, or the value of a field observed into a
+// histogram, via the metrics collector set with `SetMetricsCollector`, which must
+// implement `LogMetricsCollector`. Records only reach the Logr when their level is
+// enabled by at least one target, so rules cannot match records at other levels.
+func LogMetrics(rules ...MetricRule) Option {
+	return func(l *Logr) error {
+		for i, rule := range rules {
+			mr, err := newMetricRule(rule)
+			if err != nil {
+				return fmt.Errorf("metric rule %d: %w", i, err)
+			}
+			l.options.metricRules = append(l.options.metricRules, mr)
+		}
+		return nil
+	}
+}
+
+type metricRule struct {
+	MetricRule
+	levels map[LevelID]struct{}
+	regex  *regexp.Regexp
+}
+
+func newMetricRule(rule MetricRule) (*metricRule, error) {
+	if rule.Name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	if rule.Histogram && rule.Field == "" {
+		return nil, errors.New("histogram requires a field")
+	}
+	mr := &metricRule{MetricRule: rule}
+	if len(rule.Levels) > 0 {
+		mr.levels = make(map[LevelID]struct{}, len(rule.Levels))
+		for _, lvl := range rule.Levels {
+			mr.levels[lvl.ID] = struct{}{}
+		}
+	}
+	if rule.MsgPattern != "" {
+		re, err := regexp.Compile(rule.MsgPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid msg pattern: %w", err)
+		}
+		mr.regex = re
+	}
+	return mr, nil
+}
+
+// match returns true and the field named by the rule, if any, when a prepped
+// record matches the rule.
+func (mr *metricRule) match(rec *LogRec) (Field, bool) {
+	if mr.levels != nil {
+		if _, ok := mr.levels[rec.level.ID]; !ok {
+			return Field{}, false
+		}
+	}
+	if mr.regex != nil && !mr.regex.MatchString(rec.msg) {
+		return Field{}, false
+	}
+	if mr.Field == "" {
+		return Field{}, true
+	}
+	for _, f := range rec.fieldsAll {
+		if f.Key == mr.Field {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// logMetrics applies `LogMetrics` rules.
+type logMetrics struct {
+	rules []*metricRule
+
+	mux        sync.Mutex
+	collector  LogMetricsCollector
+	counters   map[string]Counter
+	histograms map[string]Histogram
+}
+
+func newLogMetrics(rules []*metricRule) *logMetrics {
+	return &logMetrics{
+		rules:      rules,
+		counters:   make(map[string]Counter),
+		histograms: make(map[string]Histogram),
+	}
+}
+
+// setCollector replaces the metrics collector, discarding metrics from any
+// previous collector.
+func (lm *logMetrics) setCollector(collector MetricsCollector) {
+	lm.mux.Lock()
+	defer lm.mux.Unlock()
+	lm.collector, _ = collector.(LogMetricsCollector)
+	lm.counters = make(map[string]Counter)
+	lm.histograms = make(map[string]Histogram)
+}
+
+// observe updates the metrics of each rule matching a prepped record.
+func (lm *logMetrics) observe(rec *LogRec) {
+	lm.mux.Lock()
+	defer lm.mux.Unlock()
+	if lm.collector == nil {
+		return
+	}
+
+	for _, rule := range lm.rules {
+		field, ok := rule.match(rec)
+		if !ok {
+			continue
+		}
+		var value float64
+		if rule.Histogram {
+			if value, ok = fieldFloat(field); !ok {
+				continue
+			}
+		}
+
+		labels := make(map[string]string, len(rule.Labels))
+		var sb strings.Builder
+		sb.WriteString(rule.Name)
+		for _, key := range rule.Labels {
+			labels[key] = fieldValueString(rec.fieldsAll, key)
+			sb.WriteByte(0)
+			sb.WriteString(labels[key])
+		}
+		id := sb.String()
+
+		if rule.Histogram {
+			h, ok := lm.histograms[id]
+			if !ok {
+				h, _ = lm.collector.LogMetricHistogram(rule.Name, labels)
+				lm.histograms[id] = h
+			}
+			if h != nil {
+				h.Observe(value)
+			}
+			continue
+		}
+		c, ok := lm.counters[id]
+		if !ok {
+			c, _ = lm.collector.LogMetricCounter(rule.Name, labels)
+			lm.counters[id] = c
+		}
+		if c != nil {
+			c.Inc()
+		}
+	}
+}
+
+// fieldFloat returns the numeric value of a field, with durations in seconds.
+func fieldFloat(f Field) (float64, bool) {
+	switch f.Type {
+	case Int64Type, Int32Type, IntType:
+		return float64(f.Integer), true
+	case Uint64Type, Uint32Type, UintType:
+		return float64(uint64(f.Integer)), true
+	case Float64Type, Float32Type:
+		return f.Float, true
+	case DurationType:
+		return time.Duration(f.Integer).Seconds(), true
+	}
+	return 0, false
+}
+
+// fieldValueString returns the value of the field with the key as a string, or
+// empty if there is no such field.
+func fieldValueString(fields []Field, key string) string {
+	for _, f := range fields {
+		if f.Key == key {
+			var sb strings.Builder
+			_ = f.ValueString(&sb, nil)
+			return sb.String()
+		}
+	}
+	return ""
+}
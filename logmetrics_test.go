@@ -0,0 +1,53 @@
+package logr_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogMetrics(t *testing.T) {
+	collector := test.NewTestMetricsCollector()
+	lgr, err := logr.New(
+		logr.SetMetricsCollector(collector, 1000),
+		logr.LogMetrics(
+			logr.MetricRule{Name: "login_failures", Levels: []logr.Level{logr.Warn}, MsgPattern: "^login failed", Labels: []string{"reason"}},
+			logr.MetricRule{Name: "request_seconds", Field: "elapsed", Histogram: true, Labels: []string{"method"}},
+			logr.MetricRule{Name: "errors"},
+		),
+	)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "metricsTest", &logr.StdFilter{Lvl: logr.Debug}, &formatters.Plain{}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Warn("login failed for user", logr.String("reason", "password"))
+	logger.Warn("login failed for user", logr.String("reason", "password"))
+	logger.Warn("login failed for user", logr.String("reason", "locked"))
+	logger.Info("login failed for user", logr.String("reason", "password"))
+	logger.Warn("login succeeded")
+	logger.Info("request", logr.String("method", "GET"), logr.Duration("elapsed", 1500*time.Millisecond))
+	logger.Info("request", logr.String("method", "GET"), logr.Float64("elapsed", 0.5))
+	logger.Info("request", logr.String("method", "GET"), logr.String("elapsed", "n/a"))
+	logger.Info("request", logr.String("method", "POST"))
+	require.NoError(t, lgr.Flush())
+
+	assert.Equal(t, 2.0, collector.GetLogMetric("login_failures", "reason", "password"))
+	assert.Equal(t, 1.0, collector.GetLogMetric("login_failures", "reason", "locked"))
+	assert.Equal(t, 2.0, collector.GetLogMetric("request_seconds", "method", "GET"))
+	assert.Equal(t, 0.0, collector.GetLogMetric("request_seconds", "method", "POST"))
+	assert.Equal(t, 9.0, collector.GetLogMetric("errors"))
+
+	_, err = logr.New(logr.LogMetrics(logr.MetricRule{Name: "bad", MsgPattern: "("}))
+	assert.Error(t, err)
+	_, err = logr.New(logr.LogMetrics(logr.MetricRule{Name: "hist", Histogram: true}))
+	assert.Error(t, err)
+}
@@ -19,6 +19,9 @@ type Logr struct {
 	tmux        sync.RWMutex // targetHosts mutex
 	targetHosts []*TargetHost
 
+	// targetGroups are keyed by name and guarded by tmux. See `InTargetGroup`.
+	targetGroups map[string]*targetGroup
+
 	in         chan *LogRec
 	quit       chan struct{} // closed by Shutdown to exit read loop
 	done       chan struct{} // closed when read loop exited
@@ -29,6 +32,51 @@ type Logr struct {
 	metricsMux sync.RWMutex
 	metrics    *metrics
 
+	// coalescer is only accessed by the read loop; nil if not enabled.
+	coalescer *coalescer
+
+	// globalFields are shared by all loggers; nil if none.
+	globalFields *fieldChain
+
+	// sampler limits repetitive records; nil if not enabled.
+	sampler *sampler
+
+	// clock records monotonic timestamps; nil if not enabled.
+	// Only accessed by the read loop.
+	clock *clockMonitor
+
+	// volume aggregates record and byte counts by field; nil if not enabled.
+	volume *volumeAccounting
+
+	// quota is only accessed by the read loop; nil if not enabled.
+	quota *quotaLimiter
+
+	// summarizer is only accessed by the read loop; nil if not enabled.
+	summarizer *summarizer
+
+	// schema applies `FieldSchema`; nil if not enabled.
+	schema *fieldSchema
+
+	// logMetrics applies `LogMetrics` rules; nil if not enabled.
+	logMetrics *logMetrics
+
+	// alerter is only accessed by the read loop; nil if not enabled.
+	alerter *alerter
+
+	// burst applies `BurstDetection`; nil if not enabled.
+	burst *burstDetector
+
+	// enrichStage runs async enrichers before fanout; nil if not enabled.
+	enrichStage *asyncEnrichStage
+
+	stats *statCounters
+
+	// syncTargets is the number of targets added with `Synchronous`.
+	syncTargets int32
+
+	// inlineMux serializes processing in minimal mode. See `Minimal`.
+	inlineMux sync.Mutex
+
 	shutdown int32
 }
 
@@ -44,7 +92,7 @@ func New(opts ...Option) (*Logr, error) {
 		maxPooledBuffer: DefaultMaxPooledBuffer,
 	}
 
-	lgr := &Logr{options: options}
+	lgr := &Logr{options: options, stats: &statCounters{}}
 
 	// apply the options
 	for _, opt := range opts {
@@ -52,6 +100,11 @@ func New(opts ...Option) (*Logr, error) {
 			return nil, err
 		}
 	}
+	if lgr.options.minimal {
+		if err := lgr.checkMinimal(); err != nil {
+			return nil, err
+		}
+	}
 	pkgName := GetLogrPackageName()
 	if pkgName != "" {
 		opt := StackFilter(pkgName, pkgName+"/targets", pkgName+"/formatters")
@@ -59,6 +112,46 @@ func New(opts ...Option) (*Logr, error) {
 	}
 
 	lgr.in = make(chan *LogRec, lgr.options.maxQueueSize)
+	if lgr.options.coalesceWindow > 0 {
+		lgr.coalescer = newCoalescer(lgr.options.coalesceWindow, lgr.options.coalesceKey)
+	}
+	if lgr.options.volumeKey != "" {
+		lgr.volume = newVolumeAccounting(lgr.options.volumeKey)
+	}
+	if lgr.options.quota != nil {
+		if lgr.volume == nil {
+			return nil, errors.New("VolumeQuota requires VolumeAccounting")
+		}
+		lgr.quota = newQuotaLimiter(*lgr.options.quota)
+	}
+	if len(lgr.options.summaries) > 0 {
+		lgr.summarizer = newSummarizer(lgr.options.summaries)
+	}
+	if lgr.options.schema != nil {
+		lgr.schema = newFieldSchema(*lgr.options.schema)
+	}
+	if len(lgr.options.metricRules) > 0 {
+		lgr.logMetrics = newLogMetrics(lgr.options.metricRules)
+	}
+	if len(lgr.options.alertRules) > 0 {
+		lgr.alerter = newAlerter(lgr.options.alertRules)
+	}
+	if lgr.options.burst != nil {
+		lgr.burst = newBurstDetector(*lgr.options.burst)
+	}
+	if n := len(lgr.options.globalFields); n > 0 {
+		lgr.globalFields = &fieldChain{fields: lgr.options.globalFields, size: n}
+	}
+	if lgr.options.sampleTick > 0 {
+		lgr.sampler = newSampler(lgr.options.sampleTick, lgr.options.sampleFirst, lgr.options.sampleThereafter)
+	}
+	if lgr.options.monotonic {
+		lgr.clock = newClockMonitor(lgr.options.clockSkewThreshold, lgr.options.clockSkewKey)
+	}
+	if len(lgr.options.asyncEnrichers) > 0 {
+		lgr.enrichStage = newAsyncEnrichStage(lgr, lgr.options.asyncEnrichWorkers, lgr.options.maxQueueSize,
+			lgr.options.asyncEnrichTimeout, lgr.options.asyncEnrichers)
+	}
 	lgr.quit = make(chan struct{})
 	lgr.done = make(chan struct{})
 
@@ -77,14 +170,24 @@ func New(opts ...Option) (*Logr, error) {
 
 	lgr.initMetrics(lgr.options.metricsCollector, lgr.options.metricsUpdateFreqMillis)
 
+	if lgr.options.pressure != nil {
+		go lgr.watchPressure(*lgr.options.pressure)
+	}
+
+	if lgr.options.minimal {
+		close(lgr.done)
+		return lgr, nil
+	}
+
 	go lgr.start()
 
 	return lgr, nil
 }
 
 // AddTarget adds a target to the logger which will receive
-// log records for outputting.
-func (lgr *Logr) AddTarget(target Target, name string, filter Filter, formatter Formatter, maxQueueSize int) error {
+// log records for outputting. Options such as `WriteTimeout` can be
+// provided to configure how the target is hosted.
+func (lgr *Logr) AddTarget(target Target, name string, filter Filter, formatter Formatter, maxQueueSize int, opts ...TargetOption) error {
 	if lgr.IsShutdown() {
 		return fmt.Errorf("AddTarget called after Logr shut down")
 	}
@@ -93,12 +196,36 @@ func (lgr *Logr) AddTarget(target Target, name string, filter Filter, formatter
 	metrics := lgr.metrics
 	lgr.metricsMux.RUnlock()
 
+	if maxQueueSize <= 0 && lgr.options.targetQueueSize > 0 {
+		maxQueueSize = lgr.options.targetQueueSize
+	}
+
 	hostOpts := targetHostOptions{
 		name:         name,
 		filter:       filter,
 		formatter:    formatter,
 		maxQueueSize: maxQueueSize,
 		metrics:      metrics,
+		logger:       lgr.NewLogger(),
+		isolated:     lgr.options.isolatedLevels,
+	}
+	for _, opt := range opts {
+		if err := opt(&hostOpts); err != nil {
+			return err
+		}
+	}
+	if lgr.options.minimal {
+		hostOpts.inline = true
+		hostOpts.synchronous = false
+	}
+	if hostOpts.groupName != "" {
+		lgr.tmux.Lock()
+		group, err := lgr.targetGroup(hostOpts.groupName, hostOpts.groupMaxQueued)
+		lgr.tmux.Unlock()
+		if err != nil {
+			return err
+		}
+		hostOpts.group = group
 	}
 
 	host, err := newTargetHost(target, hostOpts)
@@ -110,6 +237,9 @@ func (lgr *Logr) AddTarget(target Target, name string, filter Filter, formatter
 	defer lgr.tmux.Unlock()
 
 	lgr.targetHosts = append(lgr.targetHosts, host)
+	if host.synchronous {
+		atomic.AddInt32(&lgr.syncTargets, 1)
+	}
 
 	lgr.ResetLevelCache()
 
@@ -120,7 +250,7 @@ func (lgr *Logr) AddTarget(target Target, name string, filter Filter, formatter
 // enough to create on-demand, but typically one or more Loggers are
 // created and re-used.
 func (lgr *Logr) NewLogger() Logger {
-	logger := Logger{lgr: lgr}
+	logger := Logger{lgr: lgr, fields: lgr.globalFields}
 	return logger
 }
 
@@ -149,7 +279,7 @@ func (lgr *Logr) IsLevelEnabled(lvl Level) LevelStatus {
 		enabled, level := host.IsLevelEnabled(lvl)
 		if enabled {
 			status.Enabled = true
-			if level.Stacktrace || host.formatter.IsStacktraceNeeded() {
+			if level.Stacktrace || host.Formatter().IsStacktraceNeeded() {
 				status.Stacktrace = true
 				break // if both level and stacktrace enabled then no sense checking more targets
 			}
@@ -201,6 +331,23 @@ func (lgr *Logr) TargetInfos() []TargetInfo {
 // closing, with cxt determining how much time can be spent in total.
 // Note, keep the timeout short since this method blocks certain logging operations.
 func (lgr *Logr) RemoveTargets(cxt context.Context, f func(ti TargetInfo) bool) error {
+	// select the targets and stop them accepting records under the read lock, so
+	// logging goroutines blocked queuing to them, e.g. for `BlockLevel`, release
+	// the lock before it is write locked.
+	removed := make(map[*TargetHost]struct{})
+	lgr.tmux.RLock()
+	for _, host := range lgr.targetHosts {
+		inf := TargetInfo{
+			Name: host.String(),
+			Type: fmt.Sprintf("%T", host.target),
+		}
+		if f(inf) {
+			removed[host] = struct{}{}
+			host.stop()
+		}
+	}
+	lgr.tmux.RUnlock()
+
 	errs := merror.New()
 	hosts := make([]*TargetHost, 0)
 
@@ -208,14 +355,13 @@ func (lgr *Logr) RemoveTargets(cxt context.Context, f func(ti TargetInfo) bool)
 	defer lgr.tmux.Unlock()
 
 	for _, host := range lgr.targetHosts {
-		inf := TargetInfo{
-			Name: host.String(),
-			Type: fmt.Sprintf("%T", host.target),
-		}
-		if f(inf) {
+		if _, ok := removed[host]; ok {
 			if err := host.Shutdown(cxt); err != nil {
 				errs.Append(err)
 			}
+			if host.synchronous {
+				atomic.AddInt32(&lgr.syncTargets, -1)
+			}
 		} else {
 			hosts = append(hosts, host)
 		}
@@ -227,6 +373,52 @@ func (lgr *Logr) RemoveTargets(cxt context.Context, f func(ti TargetInfo) bool)
 	return errs.ErrorOrNil()
 }
 
+// SetTargetFormatter replaces the formatter of the target with the specified name
+// while the target continues to run, for example to switch a console target from
+// plain text to JSON. Records queued before the call may be output with either
+// formatter. A nil formatter selects `DefaultFormatter`.
+func (lgr *Logr) SetTargetFormatter(name string, formatter Formatter) error {
+	host := lgr.findTarget(name)
+	if host == nil {
+		return fmt.Errorf("target %s not found", name)
+	}
+	host.setFormatter(formatter)
+
+	// the new formatter may need stacktraces where the old one did not.
+	lgr.ResetLevelCache()
+	return nil
+}
+
+// SetTargetQueueSize changes the maximum queue size of the target with the specified
+// name while the target continues to run, for example to absorb a temporary slowdown
+// of the destination. When shrinking, queued records are first written until they fit
+// within the new size; records logged meanwhile block, and ctx determines how long to
+// wait.
+func (lgr *Logr) SetTargetQueueSize(ctx context.Context, name string, size int) error {
+	host := lgr.findTarget(name)
+	if host == nil {
+		return fmt.Errorf("target %s not found", name)
+	}
+	return host.setQueueSize(ctx, size)
+}
+
+// findTarget returns the target host with the specified name, or nil.
+func (lgr *Logr) findTarget(name string) *TargetHost {
+	lgr.tmux.RLock()
+	defer lgr.tmux.RUnlock()
+	return lgr.findTargetLocked(name)
+}
+
+// findTargetLocked is findTarget for callers already holding the target lock.
+func (lgr *Logr) findTargetLocked(name string) *TargetHost {
+	for _, host := range lgr.targetHosts {
+		if host.String() == name {
+			return host
+		}
+	}
+	return nil
+}
+
 // ResetLevelCache resets the cached results of `IsLevelEnabled`. This is
 // called any time a Target is added or a target's level is changed.
 func (lgr *Logr) ResetLevelCache() {
@@ -239,6 +431,10 @@ func (lgr *Logr) ResetLevelCache() {
 // To ensure all targets use a collector, use the `SetMetricsCollector` option when
 // creating the Logr instead, or configure/reconfigure the Logr after calling this method.
 func (lgr *Logr) SetMetricsCollector(collector MetricsCollector, updateFreqMillis int64) {
+	if lgr.options.minimal {
+		lgr.ReportError(errors.New("metrics are not supported in minimal mode"))
+		return
+	}
 	lgr.initMetrics(collector, updateFreqMillis)
 }
 
@@ -246,15 +442,58 @@ func (lgr *Logr) SetMetricsCollector(collector MetricsCollector, updateFreqMilli
 // this function either blocks or the log record is dropped, depending on
 // the result of calling `OnQueueFull`.
 func (lgr *Logr) enqueue(rec *LogRec) {
+	if lgr.options.minimal {
+		lgr.processInline(rec)
+		return
+	}
+	if rec.flush == nil && atomic.LoadInt32(&lgr.syncTargets) > 0 {
+		lgr.writeSync(rec)
+	}
+	if lgr.options.traceHook != nil && lgr.sampleRec(rec) {
+		start := time.Now()
+		rec.enqueuedAt = start
+		lgr.push(rec)
+		lgr.tracePhase(rec, PhaseEnqueue, "", time.Since(start))
+		return
+	}
+	lgr.push(rec)
+}
+
+// writeSync preps a log record, applies the field schema, and writes it to all
+// synchronous targets on the calling goroutine. The read loop does not prep the
+// record or apply the schema again.
+func (lgr *Logr) writeSync(rec *LogRec) {
+	rec.prep()
+	if lgr.schema != nil && !lgr.applySchema(rec) {
+		return
+	}
+
+	lgr.tmux.RLock()
+	defer lgr.tmux.RUnlock()
+	for _, host := range lgr.targetHosts {
+		if !host.synchronous {
+			continue
+		}
+		if enabled, _ := host.IsLevelEnabled(rec.Level()); enabled {
+			host.writeSyncEnabled(rec)
+		}
+	}
+}
+
+// push adds a log record to the logr queue.
+func (lgr *Logr) push(rec *LogRec) {
 	select {
 	case lgr.in <- rec:
 	default:
 		if lgr.options.onQueueFull != nil && lgr.options.onQueueFull(rec, cap(lgr.in)) {
+			atomic.AddUint64(&lgr.stats.dropped, 1)
 			return // drop the record
 		}
 		select {
 		case <-time.After(lgr.options.enqueueTimeout):
 			lgr.ReportError(fmt.Errorf("enqueue timed out for log rec [%v]", rec))
+		case <-lgr.quit:
+			// shut down after the level check; the queue will not be drained.
 		case lgr.in <- rec: // block until success or timeout
 		}
 	}
@@ -286,6 +525,10 @@ func (lgr *Logr) FlushWithTimeout(ctx context.Context) error {
 		return errors.New("Flush called on shut down Logr")
 	}
 
+	if lgr.options.minimal {
+		return lgr.flushInline()
+	}
+
 	rec := newFlushLogRec(lgr.NewLogger())
 	lgr.enqueue(rec)
 
@@ -334,6 +577,7 @@ func (lgr *Logr) ShutdownWithTimeout(ctx context.Context) error {
 	lgr.stopMetricsUpdater()
 
 	close(lgr.quit)
+	defer lgr.summarizer.stop()
 
 	errs := merror.New()
 
@@ -342,6 +586,11 @@ func (lgr *Logr) ShutdownWithTimeout(ctx context.Context) error {
 	case <-ctx.Done():
 		errs.Append(newTimeoutError("logr queue shutdown timeout"))
 	case <-lgr.done:
+		if lgr.enrichStage != nil {
+			if err := lgr.enrichStage.stop(ctx); err != nil {
+				errs.Append(err)
+			}
+		}
 	}
 
 	// logr.in channel should now be drained to targets and no more log records
@@ -354,6 +603,9 @@ func (lgr *Logr) ShutdownWithTimeout(ctx context.Context) error {
 			errs.Append(err)
 		}
 	}
+	if err := lgr.shutdownCrashTarget(); err != nil {
+		errs.Append(err)
+	}
 	return errs.ErrorOrNil()
 }
 
@@ -364,6 +616,10 @@ func (lgr *Logr) ReportError(err interface{}) {
 	lgr.incErrorCounter()
 
 	if lgr.options.onLoggerError == nil {
+		if lgr.options.internalLogger != nil {
+			lgr.options.internalLogger.Println(err)
+			return
+		}
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
@@ -406,15 +662,67 @@ func (lgr *Logr) start() {
 			if rec.flush != nil {
 				lgr.flush(rec.flush)
 			} else {
+				if rec.traceID != 0 {
+					lgr.tracePhase(rec, PhaseDequeue, "", time.Since(rec.enqueuedAt))
+				}
 				rec.prep()
-				lgr.fanout(rec)
+				lgr.process(rec)
 			}
+		case <-lgr.coalescer.expired():
+			lgr.dispatch(lgr.coalescer.release())
+		case <-lgr.summarizer.tick():
+			lgr.outputSummaries(lgr.summarizer.roll(lgr.now(), lgr.NewLogger()))
 		case <-lgr.quit:
 			return
 		}
 	}
 }
 
+// process fans out a prepped LogRec to all targets, unless it is rejected by
+// the field schema, summarized, over quota or merged by the coalescer.
+func (lgr *Logr) process(rec *LogRec) {
+	lgr.clock.observe(rec)
+	if lgr.schema != nil && !lgr.applySchema(rec) {
+		return
+	}
+	if lgr.options.fingerprintKey != "" {
+		lgr.addFingerprint(rec)
+	}
+	if lgr.logMetrics != nil {
+		lgr.logMetrics.observe(rec)
+	}
+	if lgr.burst != nil {
+		lgr.outputBursts(lgr.burst.observe(rec))
+	}
+	if lgr.alerter != nil {
+		lgr.raiseAlerts(lgr.alerter.observe(rec))
+	}
+	if lgr.summarizer != nil {
+		lgr.outputSummaries(lgr.summarizer.roll(rec.time, lgr.NewLogger()))
+		if lgr.summarizer.add(rec) {
+			return
+		}
+	}
+	if lgr.quota != nil && lgr.isOverQuota(rec) {
+		return
+	}
+	if lgr.coalescer != nil {
+		if rec = lgr.coalescer.add(rec); rec == nil {
+			return
+		}
+	}
+	lgr.dispatch(rec)
+}
+
+// dispatch fans out a LogRec to all targets, via the async enrichment stage if enabled.
+func (lgr *Logr) dispatch(rec *LogRec) {
+	if lgr.enrichStage != nil {
+		lgr.enrichStage.submit(rec)
+		return
+	}
+	lgr.fanout(rec)
+}
+
 // fanout pushes a LogRec to all targets.
 func (lgr *Logr) fanout(rec *LogRec) {
 	var host *TargetHost
@@ -426,10 +734,20 @@ func (lgr *Logr) fanout(rec *LogRec) {
 
 	var logged bool
 
+	if rec.traceID != 0 {
+		rec.fanoutAt = time.Now()
+	}
+	if lgr.volume != nil {
+		rec.volumeKey = lgr.volume.keyOf(rec)
+	}
+
 	lgr.tmux.RLock()
 	defer lgr.tmux.RUnlock()
 	for _, host = range lgr.targetHosts {
-		if enabled, _ := host.IsLevelEnabled(rec.Level()); enabled {
+		if host.synchronous {
+			continue
+		}
+		if enabled, _ := host.IsLevelEnabled(rec.Level()); enabled && host.IsRecordEnabled(rec) {
 			host.Log(rec)
 			logged = true
 		}
@@ -437,35 +755,73 @@ func (lgr *Logr) fanout(rec *LogRec) {
 
 	if logged {
 		lgr.incLoggedCounter()
+		if lgr.volume != nil {
+			lgr.volume.addRecord(rec.volumeKey)
+		}
 	}
 }
 
-// flush drains the queue and notifies when done.
+// flush drains the queue and notifies when done, including the callers of any
+// other flushes drained.
 func (lgr *Logr) flush(done chan<- struct{}) {
 	// first drain the logr queue.
+	var drained []*LogRec
 loop:
 	for {
 		var rec *LogRec
 		select {
 		case rec = <-lgr.in:
-			if rec.flush == nil {
-				rec.prep()
-				lgr.fanout(rec)
+			if rec.flush != nil {
+				drained = append(drained, rec)
+				continue
 			}
+			rec.prep()
+			lgr.process(rec)
 		default:
 			break loop
 		}
 	}
 
+	if lgr.coalescer != nil {
+		if rec := lgr.coalescer.release(); rec != nil {
+			lgr.dispatch(rec)
+		}
+	}
+
+	if lgr.summarizer != nil {
+		lgr.outputSummaries(lgr.summarizer.drain(lgr.NewLogger()))
+	}
+
+	if lgr.quota != nil {
+		lgr.outputQuotaSummaries(lgr.quota.drain())
+	}
+
+	if lgr.enrichStage != nil {
+		lgr.enrichStage.wait()
+	}
+
 	logger := lgr.NewLogger()
 
-	// drain all the targets; block until finished.
+	// drain all the targets; block until finished or the target is being removed.
 	lgr.tmux.RLock()
 	defer lgr.tmux.RUnlock()
 	for _, host := range lgr.targetHosts {
 		rec := newFlushLogRec(logger)
 		host.Log(rec)
-		<-rec.flush
+		select {
+		case <-rec.flush:
+		case <-host.done:
+		}
 	}
 	done <- struct{}{}
+	notifyFlushed(drained)
+}
+
+// notifyFlushed signals flush records drained while handling another flush. Each
+// is signaled on its own goroutine so a caller that gave up waiting cannot block
+// the read loop.
+func notifyFlushed(recs []*LogRec) {
+	for _, rec := range recs {
+		go func(rec *LogRec) { rec.flush <- struct{}{} }(rec)
+	}
 }
@@ -12,6 +12,9 @@ import (
 // LogRec collects raw, unformatted data to be logged.
 // TODO:  pool these?  how to reliably know when targets are done with them? Copy for each target?
 type LogRec struct {
+	// size caches the estimated size, set atomically. First for 64-bit alignment.
+	size int64
+
 	mux  sync.RWMutex
 	time time.Time
 
@@ -28,15 +31,37 @@ type LogRec struct {
 	// flushes Logr and target queues when not nil.
 	flush chan struct{}
 
+	// set when the record is sampled for pipeline tracing. See `PipelineTracing`.
+	traceID    uint64
+	enqueuedAt time.Time
+	fanoutAt   time.Time
+
+	// set at fanout when volume accounting is enabled. See `VolumeAccounting`.
+	volumeKey string
+
+	// set once the field schema is applied, with the result. See `FieldSchema`.
+	schemaChecked bool
+	schemaOK      bool
+
 	// remaining fields calculated by `prep`
 	frames    []runtime.Frame
 	fieldsAll []Field
 	caller    string
+	prepped   bool
+
+	// set when monotonic time is enabled. See `MonotonicTime`.
+	mono    time.Duration
+	hasMono bool
 }
 
-// NewLogRec creates a new LogRec with the current time and optional stack trace.
+// NewLogRec creates a new LogRec with the current time, as provided by the `Clock`
+// option if set, and optional stack trace.
 func NewLogRec(lvl Level, logger Logger, msg string, fields []Field, incStacktrace bool) *LogRec {
-	rec := &LogRec{time: time.Now(), logger: logger, level: lvl, msg: msg, fields: fields}
+	now := time.Now
+	if logger.lgr != nil && logger.lgr.options.clock != nil {
+		now = logger.lgr.options.clock
+	}
+	rec := &LogRec{time: now(), logger: logger, level: lvl, msg: msg, fields: fields}
 	if incStacktrace {
 		rec.stackPC = make([]uintptr, DefaultMaxStackFrames)
 		rec.stackCount = runtime.Callers(2, rec.stackPC)
@@ -50,15 +75,32 @@ func newFlushLogRec(logger Logger) *LogRec {
 	return &LogRec{logger: logger, flush: make(chan struct{})}
 }
 
-// prep resolves stack trace to frames.
+// prep resolves stack trace to frames and merges fields. Records already
+// prepped, e.g. for a synchronous target, are not prepped again.
 func (rec *LogRec) prep() {
+	conflicts := rec.prepLocked()
+
+	if onConflict := rec.logger.lgr.options.onFieldConflict; onConflict != nil {
+		for _, key := range conflicts {
+			onConflict(rec, key)
+		}
+	}
+}
+
+func (rec *LogRec) prepLocked() (conflicts []string) {
 	rec.mux.Lock()
 	defer rec.mux.Unlock()
 
+	if rec.prepped {
+		return nil
+	}
+	rec.prepped = true
+
 	// include log rec fields and logger fields added via "With"
-	rec.fieldsAll = make([]Field, 0, len(rec.fields)+len(rec.logger.fields))
-	rec.fieldsAll = append(rec.fieldsAll, rec.logger.fields...)
+	rec.fieldsAll = make([]Field, 0, len(rec.fields)+rec.logger.fields.len())
+	rec.fieldsAll = rec.logger.fields.appendTo(rec.fieldsAll)
 	rec.fieldsAll = append(rec.fieldsAll, rec.fields...)
+	rec.fieldsAll, conflicts = dedupFields(rec.fieldsAll)
 
 	filter := rec.logger.lgr.options.stackFilter
 
@@ -83,8 +125,81 @@ func (rec *LogRec) prep() {
 
 	// calc caller if stack trace provided
 	if len(rec.frames) > 0 {
-		rec.caller = calcCaller(rec.frames)
+		if sp := rec.logger.lgr.options.sourcePaths; sp != nil {
+			sp.trimFrames(rec.frames)
+			rec.caller = calcTrimmedCaller(rec.frames)
+		} else {
+			rec.caller = calcCaller(rec.frames)
+		}
+		rec.frames = stableFrames(rec.frames, rec.logger.lgr.options.stackMode)
+	}
+	return conflicts
+}
+
+// dedupFields resolves duplicate keys such that the last field with a given key wins.
+// The winning field takes the position of the first field with that key so fields
+// added via `Logger.With` keep their place. Fields with empty keys, such as the
+// positional args of `Sugar`, are never deduplicated. The slice is modified in place
+// and the keys that had duplicates are returned.
+func dedupFields(fields []Field) ([]Field, []string) {
+	if len(fields) < 2 {
+		return fields, nil
+	}
+
+	var conflicts []string
+	out := fields[:0]
+	var index map[string]int
+	for _, f := range fields {
+		pos := -1
+		if f.Key == "" {
+			out = append(out, f)
+			continue
+		}
+		if index != nil {
+			if i, ok := index[f.Key]; ok {
+				pos = i
+			}
+		} else {
+			for i := range out {
+				if out[i].Key == f.Key {
+					pos = i
+					break
+				}
+			}
+		}
+
+		if pos >= 0 {
+			out[pos] = f
+			conflicts = appendUnique(conflicts, f.Key)
+			continue
+		}
+
+		out = append(out, f)
+		if index == nil && len(out) > dedupLinearMax {
+			index = make(map[string]int, len(fields))
+			for i := range out {
+				if out[i].Key != "" {
+					index[out[i].Key] = i
+				}
+			}
+		} else if index != nil {
+			index[f.Key] = len(out) - 1
+		}
 	}
+	return out, conflicts
+}
+
+// dedupLinearMax is the number of fields above which dedupFields uses a map
+// instead of a linear scan.
+const dedupLinearMax = 16
+
+func appendUnique(keys []string, key string) []string {
+	for _, k := range keys {
+		if k == key {
+			return keys
+		}
+	}
+	return append(keys, key)
 }
 
 // WithTime returns a shallow copy of the log record while replacing
@@ -107,6 +222,31 @@ func (rec *LogRec) WithTime(time time.Time) *LogRec {
 	}
 }
 
+// clone returns a shallow copy of the log record, including the fields calculated by `prep`.
+func (rec *LogRec) clone() *LogRec {
+	rec.mux.RLock()
+	defer rec.mux.RUnlock()
+
+	return &LogRec{
+		time:       rec.time,
+		level:      rec.level,
+		logger:     rec.logger,
+		msg:        rec.msg,
+		newline:    rec.newline,
+		fields:     rec.fields,
+		stackPC:    rec.stackPC,
+		stackCount: rec.stackCount,
+		traceID:    rec.traceID,
+		enqueuedAt: rec.enqueuedAt,
+		fanoutAt:   rec.fanoutAt,
+		frames:     rec.frames,
+		fieldsAll:  rec.fieldsAll,
+		caller:     rec.caller,
+		mono:       rec.mono,
+		hasMono:    rec.hasMono,
+	}
+}
+
 // Logger returns the `Logger` that created this `LogRec`.
 func (rec *LogRec) Logger() Logger {
 	return rec.logger
@@ -118,6 +258,14 @@ func (rec *LogRec) Time() time.Time {
 	return rec.time
 }
 
+// Monotonic returns this log record's monotonic timestamp, measured from the
+// creation of the Logr, and true if available. See `MonotonicTime`.
+func (rec *LogRec) Monotonic() (time.Duration, bool) {
+	rec.mux.RLock()
+	defer rec.mux.RUnlock()
+	return rec.mono, rec.hasMono
+}
+
 // Level returns this log record's Level.
 func (rec *LogRec) Level() Level {
 	// no locking needed as this field is not mutated.
@@ -167,6 +315,71 @@ func (rec *LogRec) String() string {
 	return strings.TrimSpace(buf.String())
 }
 
+// StackMode determines how captured stack traces are resolved. See `StableStacktraces`.
+type StackMode int
+
+const (
+	// StackModeFull keeps all frames with absolute file paths.
+	StackModeFull StackMode = iota
+
+	// StackModePlaceholder replaces each stack trace with a single frame whose
+	// function is `StackPlaceholder`. The caller is still resolved.
+	StackModePlaceholder
+
+	// StackModeRelative removes standard library frames, such as those in the
+	// runtime and testing packages, and trims file paths to the package directory
+	// and file name.
+	StackModeRelative
+)
+
+// StackPlaceholder is the function name of the frame replacing stack traces
+// when using StackModePlaceholder.
+const StackPlaceholder = "[stacktrace]"
+
+// stableFrames returns the frames modified according to mode.
+func stableFrames(frames []runtime.Frame, mode StackMode) []runtime.Frame {
+	switch mode {
+	case StackModePlaceholder:
+		return []runtime.Frame{{Function: StackPlaceholder}}
+	case StackModeRelative:
+		stable := make([]runtime.Frame, 0, len(frames))
+		for _, frame := range frames {
+			if isStdLibPackage(ResolvePackageName(frame.Function)) {
+				continue
+			}
+			stable = append(stable, runtime.Frame{
+				Function: frame.Function,
+				File:     trimPath(frame.File),
+				Line:     frame.Line,
+			})
+		}
+		return stable
+	}
+	return frames
+}
+
+// isStdLibPackage returns true if the package is part of the standard library,
+// meaning the first element of the import path has no dot.
+func isStdLibPackage(pkg string) bool {
+	if pkg == "" || pkg == "main" {
+		return false
+	}
+	first := pkg
+	if i := strings.Index(pkg, "/"); i >= 0 {
+		first = pkg[:i]
+	}
+	return !strings.Contains(first, ".")
+}
+
+// trimPath returns the last directory and file name of the path.
+func trimPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	dir, file := filepath.Split(path)
+	return filepath.Base(dir) + "/" + file
+}
+
 func calcCaller(frames []runtime.Frame) string {
 	for _, frame := range frames {
 		if frame.File == "" {
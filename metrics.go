@@ -1,6 +1,9 @@
 package logr
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
 
 const (
 	DefMetricsUpdateFreqMillis = 15000 // 15 seconds
@@ -61,6 +64,19 @@ type metrics struct {
 func (lgr *Logr) initMetrics(collector MetricsCollector, updatefreq int64) {
 	lgr.stopMetricsUpdater()
 
+	if lgr.volume != nil {
+		lgr.volume.setCollector(collector)
+	}
+	if lgr.schema != nil {
+		lgr.schema.setCollector(collector)
+	}
+	if lgr.logMetrics != nil {
+		lgr.logMetrics.setCollector(collector)
+	}
+	if lgr.burst != nil {
+		lgr.burst.setCollector(collector)
+	}
+
 	if collector == nil {
 		lgr.metricsMux.Lock()
 		lgr.metrics = nil
@@ -94,6 +110,8 @@ func (lgr *Logr) setQueueSizeGauge(val float64) {
 }
 
 func (lgr *Logr) incLoggedCounter() {
+	atomic.AddUint64(&lgr.stats.logged, 1)
+
 	lgr.metricsMux.RLock()
 	defer lgr.metricsMux.RUnlock()
 
@@ -103,6 +121,8 @@ func (lgr *Logr) incLoggedCounter() {
 }
 
 func (lgr *Logr) incErrorCounter() {
+	atomic.AddUint64(&lgr.stats.errors, 1)
+
 	lgr.metricsMux.RLock()
 	defer lgr.metricsMux.RUnlock()
 
@@ -2,7 +2,9 @@ package logr_test
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/mattermost/logr/v2"
 	"github.com/mattermost/logr/v2/formatters"
@@ -138,4 +140,63 @@ func TestLogr_SetMetricsCollector(t *testing.T) {
 		require.EqualValues(t, 0, metricsTarget1.Errors)
 		require.EqualValues(t, 0, metricsTarget2.Errors)
 	})
+	t.Run("flush and shutdown metrics", func(t *testing.T) {
+		collector := test.NewTestMetricsCollector()
+		opt := logr.SetMetricsCollector(collector, 1000)
+
+		lgr, err := logr.New(opt)
+		require.NoError(t, err)
+		defer func() {
+			err := lgr.Shutdown()
+			require.NoError(t, err)
+		}()
+
+		buf := &test.Buffer{}
+		tgt := test.NewBlockingTarget(buf)
+		err = lgr.AddTarget(tgt, TestTargetName, filter, formatter, 100)
+		require.NoError(t, err)
+
+		logger := lgr.NewLogger()
+		logger.Info("Here's looking at you, kid.")
+		<-tgt.Blocked()
+		logger.Info("Go ahead, make my day.")
+		logger.Info("May the Force be with you.")
+
+		go func() {
+			time.Sleep(time.Millisecond * 20)
+			tgt.Unblock()
+		}()
+		err = lgr.Flush()
+		require.NoError(t, err)
+
+		metricsTarget := collector.Get(TestTargetName)
+		require.EqualValues(t, 2, metricsTarget.FlushedRecords)
+		require.Greater(t, metricsTarget.FlushDuration, float64(0))
+
+		stats := lgr.StatsSnapshot().Targets[0]
+		require.EqualValues(t, 2, stats.LastFlushRecords)
+		require.Greater(t, int64(stats.LastFlushDuration), int64(0))
+
+		// a target stuck writing abandons its queued records when shut down.
+		tgt2 := test.NewBlockingTarget(&test.Buffer{})
+		err = lgr.AddTarget(tgt2, TestTargetName+"2", filter, formatter, 100)
+		require.NoError(t, err)
+		logger.Info("Elementary, my dear Watson.")
+		<-tgt2.Blocked()
+		logger.Info("Houston, we have a problem.")
+		logger.Info("I'll be back.")
+		require.Eventually(t, func() bool {
+			return lgr.StatsSnapshot().Targets[1].QueueSize == 2
+		}, time.Second*5, time.Millisecond*10)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+		err = lgr.RemoveTargets(ctx, func(ti logr.TargetInfo) bool { return ti.Name == TestTargetName+"2" })
+		require.NoError(t, err)
+		tgt2.Unblock()
+
+		metricsTarget2 := collector.Get(TestTargetName + "2")
+		require.EqualValues(t, 2, metricsTarget2.Abandoned)
+		require.GreaterOrEqual(t, metricsTarget2.ShutdownDrain, 0.05)
+	})
 }
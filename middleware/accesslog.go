@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// AccessLogMsg is the message of the log records output by `AccessLog`.
+const AccessLogMsg = "http request"
+
+// AccessLog returns middleware that logs each request at Info level once the wrapped
+// handler returns, with the standard fields remote_addr, user, method, path, proto,
+// status, size, referer, user_agent and duration. A target using
+// `formatters.NCSA` outputs these records as NCSA combined log lines.
+func AccessLog(logger logr.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			remote := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(remote); err == nil {
+				remote = host
+			}
+			user := ""
+			if r.URL.User != nil {
+				user = r.URL.User.Username()
+			} else if u, _, ok := r.BasicAuth(); ok {
+				user = u
+			}
+
+			logger.Info(AccessLogMsg,
+				logr.String("remote_addr", remote),
+				logr.String("user", user),
+				logr.String("method", r.Method),
+				logr.String("path", r.URL.RequestURI()),
+				logr.String("proto", r.Proto),
+				logr.Int("status", rw.status),
+				logr.Int64("size", rw.size),
+				logr.String("referer", r.Referer()),
+				logr.String("user_agent", r.UserAgent()),
+				logr.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// responseRecorder captures the status and size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(p)
+	rr.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the wrapped writer does.
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 59, 0, 0, time.UTC)
+	lgr, err := logr.New(logr.Clock(func() time.Time { return now }))
+	require.NoError(t, err)
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "accessTest", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.NCSA{}, 100)
+	require.NoError(t, err)
+
+	handler := AccessLog(lgr.NewLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing?q=1", nil)
+	req.RemoteAddr = "10.0.0.1:51234"
+	req.SetBasicAuth("bob", "secret")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", `Mozilla/5.0 "quoted"`)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NoError(t, lgr.Shutdown())
+	assert.Equal(t, `10.0.0.1 - bob [04/Mar/2021:10:59:00 +0000] "GET /missing?q=1 HTTP/1.1" 404 9 "https://example.com/" "Mozilla/5.0 \"quoted\""`+"\n", buf.String())
+}
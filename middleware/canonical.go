@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// CanonicalLine returns middleware that adds a `logr.CanonicalLine` to each request's
+// context and emits it with message msg when the wrapped handler returns, with the
+// method, path and status fields added. Records logged via the `XXXCtx` style APIs
+// with the request context are merged into the line, and if suppress is true are
+// not logged individually.
+func CanonicalLine(logger logr.Logger, msg string, suppress bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cl := logr.NewCanonicalLine(logger, msg, suppress)
+			cl.Add(logr.String("method", r.Method), logr.String("path", r.URL.Path))
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				cl.Add(logr.Int("status", rw.status))
+				cl.Emit()
+			}()
+			next.ServeHTTP(rw, r.WithContext(logr.ContextWithCanonicalLine(r.Context(), cl)))
+		})
+	}
+}
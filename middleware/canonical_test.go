@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalLine(t *testing.T) {
+	lgr, _ := logr.New()
+	buf := &test.Buffer{}
+	err := lgr.AddTarget(targets.NewWriterTarget(buf), "canonicalTest", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+	logger := lgr.NewLogger()
+
+	handler := CanonicalLine(logger, "request", true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoCtx(r.Context(), "lookup", logr.String("user", "bob"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	require.NoError(t, lgr.Shutdown())
+	out := strings.TrimSpace(buf.String())
+	assert.NotContains(t, out, "lookup")
+	assert.Contains(t, out, "info request method=POST path=/users user=bob status=201 duration=")
+}
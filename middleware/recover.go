@@ -0,0 +1,43 @@
+// Package middleware provides HTTP middleware that integrates with Logr.
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// Recover returns middleware that recovers from panics in the wrapped handler and
+// logs them at Panic level with the recovered value and full stack as fields.
+// If repanic is true the panic continues after logging (the Logr is flushed first),
+// otherwise a 500 Internal Server Error response is written.
+//
+// `http.ErrAbortHandler` panics are used by handlers to abort a response and are
+// not logged.
+func Recover(logger logr.Logger, repanic bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				logr.LogRecovered(logger.With(
+					logr.String("method", r.Method),
+					logr.String("path", r.URL.Path),
+				), rec)
+
+				if repanic {
+					_ = logger.Logr().Flush()
+					panic(rec)
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
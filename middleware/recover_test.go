@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover(t *testing.T) {
+	lgr, _ := logr.New()
+	buf := &test.Buffer{}
+	err := lgr.AddTarget(targets.NewWriterTarget(buf), "recoverTest", &logr.StdFilter{Lvl: logr.Panic},
+		&formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler exploded")
+	})
+
+	t.Run("recover", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Recover(lgr.NewLogger(), false)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("repanic", func(t *testing.T) {
+		assert.Panics(t, func() {
+			rec := httptest.NewRecorder()
+			Recover(lgr.NewLogger(), true)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/again", nil))
+		})
+	})
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "panic=\"handler exploded\"")
+	assert.Contains(t, buf.String(), "path=/boom")
+	assert.Contains(t, buf.String(), "method=POST path=/again")
+}
@@ -0,0 +1,72 @@
+package logr
+
+import (
+	"errors"
+
+	"github.com/wiggin77/merror"
+)
+
+// Minimal selects a lightweight mode for short-lived programs such as CLI tools.
+// No goroutines or queues are used: each record is processed and written to all
+// targets on the goroutine logging it, under a mutex, before the logging call
+// returns. Calling `Shutdown` is still recommended to close targets, but nothing
+// is lost without it for targets that do not buffer, such as a writer target on
+// stderr.
+//
+// Options that need background goroutines, i.e. `AsyncEnrichers`, coalescing,
+// metrics collection and `OnPressure`, are not supported in minimal mode.
+func Minimal() Option {
+	return func(l *Logr) error {
+		l.options.minimal = true
+		return nil
+	}
+}
+
+// checkMinimal returns an error if options incompatible with minimal mode are set.
+func (lgr *Logr) checkMinimal() error {
+	switch {
+	case len(lgr.options.asyncEnrichers) > 0:
+		return errors.New("AsyncEnrichers is not supported in minimal mode")
+	case lgr.options.coalesceWindow > 0:
+		return errors.New("coalescing is not supported in minimal mode")
+	case lgr.options.metricsCollector != nil:
+		return errors.New("metrics are not supported in minimal mode")
+	case lgr.options.pressure != nil:
+		return errors.New("OnPressure is not supported in minimal mode")
+	}
+	return nil
+}
+
+// processInline processes a log record on the calling goroutine in minimal mode.
+func (lgr *Logr) processInline(rec *LogRec) {
+	lgr.inlineMux.Lock()
+	defer lgr.inlineMux.Unlock()
+
+	rec.prep()
+	lgr.process(rec)
+}
+
+// flushInline outputs any duration and quota summaries and flushes all targets
+// that buffer records, in minimal mode.
+func (lgr *Logr) flushInline() error {
+	lgr.inlineMux.Lock()
+	defer lgr.inlineMux.Unlock()
+
+	if lgr.summarizer != nil {
+		lgr.outputSummaries(lgr.summarizer.drain(lgr.NewLogger()))
+	}
+
+	if lgr.quota != nil {
+		lgr.outputQuotaSummaries(lgr.quota.drain())
+	}
+
+	errs := merror.New()
+	lgr.tmux.RLock()
+	defer lgr.tmux.RUnlock()
+	for _, host := range lgr.targetHosts {
+		if err := host.flushInline(); err != nil {
+			errs.Append(err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,50 @@
+package logr_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimal(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	lgr, err := logr.New(logr.Minimal())
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "console", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, goroutines, runtime.NumGoroutine())
+
+	logger := lgr.NewLogger().With(logr.String("cmd", "list"))
+	logger.Info("listing", logr.Int("n", 3))
+	logger.Debug("disabled")
+	logger.Error("failed")
+	assert.Equal(t, "info listing cmd=list n=3\nerror failed cmd=list\n", buf.String())
+
+	require.NoError(t, lgr.Flush())
+	require.NoError(t, lgr.Shutdown())
+	logger.Info("after shutdown")
+	assert.NotContains(t, buf.String(), "after shutdown")
+	assert.Equal(t, uint64(2), lgr.StatsSnapshot().Targets[0].Logged)
+}
+
+func TestMinimalUnsupported(t *testing.T) {
+	_, err := logr.New(logr.Minimal(), logr.Coalesce(time.Second, ""))
+	assert.Error(t, err)
+
+	_, err = logr.New(logr.Minimal(), logr.SetMetricsCollector(test.NewTestMetricsCollector(), 0))
+	assert.Error(t, err)
+
+	_, err = logr.New(logr.Minimal(), logr.OnPressure(time.Second, func(logr.PressureEvent) {}, 0.5))
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package logr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// OffloadRefSuffix is appended to the key of a field whose value was moved to a
+// `BlobStore` to form the key of the reference field replacing it.
+const OffloadRefSuffix = "_ref"
+
+// BlobStore stores field values moved out of log records by `OffloadFields`.
+// Store is called from the target's goroutine so may block, but slow stores
+// slow the target.
+type BlobStore interface {
+	// Store saves a field value and returns a reference to it, such as a file
+	// path or URL.
+	Store(key string, value []byte) (ref string, err error)
+}
+
+type fieldOffloader struct {
+	maxSize int
+	store   BlobStore
+}
+
+// OffloadFields moves field values larger than maxSize bytes, such as request
+// bodies, out of each record output by a target and into store, keeping the
+// target's output lean while preserving the data. Each such field is replaced by a
+// string field with the key suffixed by `OffloadRefSuffix` containing the reference
+// returned by the store. If the store fails, the error is reported via
+// `OnLoggerError` and the field is output unchanged.
+//
+// String, binary, error, stringer, struct, array and map values are checked; the
+// size of non-string values is that of their plain text form. Offloading happens
+// after any field selection and transforms.
+func OffloadFields(maxSize int, store BlobStore) TargetOption {
+	return func(opts *targetHostOptions) error {
+		if maxSize <= 0 {
+			return errors.New("offload max size must be greater than zero")
+		}
+		if store == nil {
+			return errors.New("offload blob store cannot be nil")
+		}
+		opts.offloader = &fieldOffloader{maxSize: maxSize, store: store}
+		return nil
+	}
+}
+
+// apply returns the log record, or a copy with oversized fields replaced by
+// references if any were offloaded.
+func (fo *fieldOffloader) apply(rec *LogRec, target string) *LogRec {
+	fields := rec.Fields()
+	var out []Field
+	for i, f := range fields {
+		value, ok := fo.oversized(f)
+		if !ok {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+
+		ref, err := fo.store.Store(f.Key, value)
+		if err != nil {
+			rec.Logger().Logr().ReportError(fmt.Errorf("cannot offload field %s for target %s: %w", f.Key, target, err))
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+
+		if out == nil {
+			out = make([]Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		out = append(out, String(f.Key+OffloadRefSuffix, ref))
+	}
+
+	if out == nil {
+		return rec
+	}
+	rec = rec.clone()
+	rec.fieldsAll = out
+	return rec
+}
+
+// oversized returns the field's value if larger than the maximum size.
+func (fo *fieldOffloader) oversized(f Field) ([]byte, bool) {
+	switch f.Type {
+	case StringType:
+		if len(f.String) > fo.maxSize {
+			return []byte(f.String), true
+		}
+		return nil, false
+	case BinaryType:
+		if b, ok := f.Interface.([]byte); ok {
+			return b, len(b) > fo.maxSize
+		}
+	case StringerType, StructType, ErrorType, ArrayType, MapType:
+	default:
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	if err := f.ValueString(&buf, nil); err != nil || buf.Len() <= fo.maxSize {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
@@ -0,0 +1,69 @@
+package logr_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memBlobStore keeps blobs in memory, failing for keys in fail.
+type memBlobStore struct {
+	mux   sync.Mutex
+	blobs map[string][]byte
+	fail  string
+}
+
+func (s *memBlobStore) Store(key string, value []byte) (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if key == s.fail {
+		return "", errors.New("store failed")
+	}
+	ref := fmt.Sprintf("mem://%d", len(s.blobs))
+	s.blobs[ref] = value
+	return ref, nil
+}
+
+func TestOffloadFields(t *testing.T) {
+	var reported []error
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) { reported = append(reported, err) }))
+	require.NoError(t, err)
+
+	store := &memBlobStore{blobs: make(map[string][]byte), fail: "broken"}
+	lean := &test.Buffer{}
+	full := &test.Buffer{}
+	filter := &logr.StdFilter{Lvl: logr.Info}
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	err = lgr.AddTarget(targets.NewWriterTarget(lean), "lean", filter, formatter, 10, logr.OffloadFields(16, store))
+	require.NoError(t, err)
+	err = lgr.AddTarget(targets.NewWriterTarget(full), "full", filter, formatter, 10)
+	require.NoError(t, err)
+
+	assert.Error(t, lgr.AddTarget(targets.NewWriterTarget(lean), "bad", filter, formatter, 10, logr.OffloadFields(0, store)))
+	assert.Error(t, lgr.AddTarget(targets.NewWriterTarget(lean), "bad", filter, formatter, 10, logr.OffloadFields(16, nil)))
+
+	body := strings.Repeat("x", 17)
+	logger := lgr.NewLogger()
+	logger.Info("request",
+		logr.String("small", "tiny"),
+		logr.String("body", body),
+		logr.Array("items", []int{1000, 2000, 3000, 4000, 5000}),
+		logr.String("broken", body))
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, "info request small=tiny body_ref=\"mem://0\" items_ref=\"mem://1\" broken="+body+"\n", lean.String())
+	assert.Contains(t, full.String(), "body="+body)
+	assert.Equal(t, []byte(body), store.blobs["mem://0"])
+	assert.Equal(t, "1000,2000,3000,4000,5000,", string(store.blobs["mem://1"]))
+	require.Len(t, reported, 1)
+	assert.Contains(t, reported[0].Error(), "broken")
+}
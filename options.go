@@ -2,6 +2,7 @@ package logr
 
 import (
 	"errors"
+	"log"
 	"time"
 )
 
@@ -12,6 +13,7 @@ type options struct {
 	onLoggerError           func(error)
 	onQueueFull             func(rec *LogRec, maxQueueSize int) bool
 	onTargetQueueFull       func(target Target, rec *LogRec, maxQueueSize int) bool
+	targetQueueFullPolicy   TargetQueueFullPolicy
 	onExit                  func(code int)
 	onPanic                 func(err interface{})
 	enqueueTimeout          time.Duration
@@ -23,6 +25,46 @@ type options struct {
 	metricsCollector        MetricsCollector
 	metricsUpdateFreqMillis int64
 	stackFilter             map[string]struct{}
+	stackMode               StackMode
+	sourcePaths             *sourcePathOptions
+	contextExtractors       []ContextExtractor
+	contextHooks            []ContextHook
+	skipCanceled            bool
+	skipCanceledLevel       Level
+	traceSampler            TraceSampler
+	skipUnsampledLevel      Level
+	enrichers               []enricherEntry
+	asyncEnrichers          []AsyncEnricher
+	asyncEnrichWorkers      int
+	asyncEnrichTimeout      time.Duration
+	onFieldConflict         func(rec *LogRec, key string)
+	coalesceWindow          time.Duration
+	coalesceKey             string
+	volumeKey               string
+	quota                   *quotaOptions
+	summaries               []summaryOptions
+	metricRules             []*metricRule
+	alertRules              []AlertRule
+	burst                   *burstOptions
+	pressure                *pressureOptions
+	schema                  *schemaOptions
+	fingerprintKey          string
+	crashTarget             *crashTarget
+	isolatedLevels          map[LevelID]struct{}
+	minimal                 bool
+	shedHighWater           float64
+	traceSampleEvery        uint64
+	traceHook               func(PipelineEvent)
+	monotonic               bool
+	clockSkewThreshold      time.Duration
+	clockSkewKey            string
+	sampleTick              time.Duration
+	sampleFirst             int
+	sampleThereafter        int
+	targetQueueSize         int
+	clock                   func() time.Time
+	internalLogger          *log.Logger
+	globalFields            []Field
 }
 
 // MaxQueueSize is the maximum number of log records that can be queued.
@@ -68,6 +110,7 @@ func OnQueueFull(f func(rec *LogRec, maxQueueSize int) bool) Option {
 // This function should return quickly, with a bool indicating whether
 // the log record should be dropped (true) or block until the log record
 // is successfully added (false). If nil then blocking (false) is assumed.
+// Ignored when `OnTargetQueueFullPolicy` is set.
 func OnTargetQueueFull(f func(target Target, rec *LogRec, maxQueueSize int) bool) Option {
 	return func(l *Logr) error {
 		l.options.onTargetQueueFull = f
@@ -190,3 +233,280 @@ func StackFilter(pkg ...string) Option {
 		return nil
 	}
 }
+
+// StableStacktraces determines how captured stack traces are resolved, allowing
+// stack traces to be made independent of the toolchain and checkout location for
+// golden-file and Example-based tests. See `StackMode`. Defaults to StackModeFull.
+func StableStacktraces(mode StackMode) Option {
+	return func(l *Logr) error {
+		if mode < StackModeFull || mode > StackModeRelative {
+			return errors.New("invalid stack mode")
+		}
+		l.options.stackMode = mode
+		return nil
+	}
+}
+
+// ContextExtractors adds one or more functions that extract fields from a
+// `context.Context`. Extractors are called, in order, by the `XXXCtx` style
+// log APIs (e.g. `Logger.InfoCtx`) and the resulting fields are added to the
+// log record.
+func ContextExtractors(extractors ...ContextExtractor) Option {
+	return func(l *Logr) error {
+		for _, e := range extractors {
+			if e == nil {
+				return errors.New("context extractor cannot be nil")
+			}
+		}
+		l.options.contextExtractors = append(l.options.contextExtractors, extractors...)
+		return nil
+	}
+}
+
+// ContextHooks adds one or more functions called synchronously, in order, by the
+// `XXXCtx` style log APIs (e.g. `Logger.ErrorCtx`) for each log record emitted. Hooks
+// receive the context, and can be used to mirror log records as events on the active
+// trace span, for example.
+func ContextHooks(hooks ...ContextHook) Option {
+	return func(l *Logr) error {
+		for _, h := range hooks {
+			if h == nil {
+				return errors.New("context hook cannot be nil")
+			}
+		}
+		l.options.contextHooks = append(l.options.contextHooks, hooks...)
+		return nil
+	}
+}
+
+// SkipCanceledBelow causes the `XXXCtx` style log APIs to skip log records
+// when the supplied context is already canceled and the record's level is less
+// severe than `level`. This reduces useless logs from abandoned requests. For example,
+// `SkipCanceledBelow(logr.Warn)` skips Info, Debug and Trace records for canceled contexts.
+func SkipCanceledBelow(level Level) Option {
+	return func(l *Logr) error {
+		l.options.skipCanceled = true
+		l.options.skipCanceledLevel = level
+		return nil
+	}
+}
+
+// SkipUnsampledBelow causes the `XXXCtx` style log APIs to skip log records when the
+// supplied context carries a trace that is not sampled and the record's level is less
+// severe than `level`. This keeps detailed logs aligned with sampled traces and greatly
+// reduces volume for unsampled requests. For example, `SkipUnsampledBelow(logr.Info, sampler)`
+// only emits Debug and Trace records for sampled traces. Records logged with a context
+// carrying no trace, or via the non-context APIs, are not affected.
+func SkipUnsampledBelow(level Level, sampler TraceSampler) Option {
+	return func(l *Logr) error {
+		if sampler == nil {
+			return errors.New("trace sampler cannot be nil")
+		}
+		l.options.traceSampler = sampler
+		l.options.skipUnsampledLevel = level
+		return nil
+	}
+}
+
+// Enrichers adds one or more enrichers which add fields to log records as they are
+// emitted. The enrichers only apply to levels enabled by `filter`; if filter is nil
+// then the enrichers apply to all levels. For example, to add the goroutine id and
+// count to Debug and more severe records:
+//
+//	logr.Enrichers(&logr.StdFilter{Lvl: logr.Debug}, logr.GoroutineID(), logr.GoroutineCount())
+func Enrichers(filter Filter, enrichers ...Enricher) Option {
+	return func(l *Logr) error {
+		for _, e := range enrichers {
+			if e == nil {
+				return errors.New("enricher cannot be nil")
+			}
+			l.options.enrichers = append(l.options.enrichers, enricherEntry{filter: filter, enricher: e})
+		}
+		return nil
+	}
+}
+
+// AsyncEnrichers adds one or more enrichers that add fields derived from a log
+// record, such as GeoIP data for a "remote_ip" field. The enrichers run on a pool of
+// `workers` goroutines so slow lookups do not block the goroutine emitting the record,
+// and each record is given `timeout` (zero for none) for all enrichers to complete.
+// Records are still output in order. At most `MaxQueueSize` records are enriched at
+// once; beyond that the Logr queue backs up as it would for a slow target.
+func AsyncEnrichers(workers int, timeout time.Duration, enrichers ...AsyncEnricher) Option {
+	return func(l *Logr) error {
+		if workers < 1 {
+			return errors.New("async enrichers require at least one worker")
+		}
+		if timeout < 0 {
+			return errors.New("async enricher timeout cannot be negative")
+		}
+		for _, e := range enrichers {
+			if e == nil {
+				return errors.New("async enricher cannot be nil")
+			}
+		}
+		l.options.asyncEnrichers = append(l.options.asyncEnrichers, enrichers...)
+		l.options.asyncEnrichWorkers = workers
+		l.options.asyncEnrichTimeout = timeout
+		return nil
+	}
+}
+
+// OnFieldConflict, when not nil, is called whenever a log record contains more than
+// one field with the same key, for example when a call-site field has the same key
+// as a field added via `Logger.With`. Duplicate keys are always resolved by keeping
+// the last value; this callback can be used to warn about such conflicts.
+// The callback is called from the Logr goroutine and should return quickly.
+func OnFieldConflict(f func(rec *LogRec, key string)) Option {
+	return func(l *Logr) error {
+		l.options.onFieldConflict = f
+		return nil
+	}
+}
+
+// Coalesce enables merging of identical consecutive log records, meaning same level,
+// message and fields, that arrive within the window. The first record is held for up
+// to the window duration and output once with an integer field named by `key`
+// containing the number of records merged, or without the field if there were no
+// repeats. This happens before records are queued for targets, relieving backpressure
+// during a storm of repeated records. If key is empty then `DefaultCoalesceKey` is used.
+func Coalesce(window time.Duration, key string) Option {
+	return func(l *Logr) error {
+		if window <= 0 {
+			return errors.New("coalesce window must be greater than zero")
+		}
+		if key == "" {
+			key = DefaultCoalesceKey
+		}
+		l.options.coalesceWindow = window
+		l.options.coalesceKey = key
+		return nil
+	}
+}
+
+// LoadShedding enables priority aware load shedding for all targets. When a target
+// queue is filled beyond the high-water mark, expressed as a fraction of the queue size
+// between 0 and 1, the least severe log records are dropped before being queued.
+// Trace records are shed first, then Debug, Info and Warn as the queue continues
+// to fill. Error and more severe records are never shed and are subject to the
+// normal `OnTargetQueueFull` handling. See `ShedCounterCollector` for metrics.
+func LoadShedding(highWater float64) Option {
+	return func(l *Logr) error {
+		if highWater <= 0 || highWater > 1 {
+			return errors.New("highWater must be greater than zero and at most 1")
+		}
+		l.options.shedHighWater = highWater
+		return nil
+	}
+}
+
+// PipelineTracing enables timing of the enqueue, dequeue, format and write phases
+// of the logging pipeline for one in every `sampleEvery` log records. The hook is
+// called with the duration of each phase of a sampled record, and can be used to feed
+// a tracing system or histogram to see where latency accumulates under load.
+// The hook is called from multiple goroutines and should return quickly.
+func PipelineTracing(sampleEvery int, hook func(PipelineEvent)) Option {
+	return func(l *Logr) error {
+		if sampleEvery < 1 {
+			return errors.New("sampleEvery must be at least 1")
+		}
+		if hook == nil {
+			return errors.New("hook cannot be nil")
+		}
+		l.options.traceSampleEvery = uint64(sampleEvery)
+		l.options.traceHook = hook
+		return nil
+	}
+}
+
+// MonotonicTime enables recording of a monotonic timestamp for each log record,
+// measured from the creation of the Logr and unaffected by wall-clock adjustments.
+// The timestamp is available via `LogRec.Monotonic` and can be output by formatters,
+// allowing accurate latency analysis even when the wall clock is stepped by NTP.
+//
+// If skewThreshold is greater than zero then backward jumps of the wall clock larger
+// than the threshold are detected, and the first record logged after a jump is
+// annotated with a duration field, named by `key`, containing the (negative) size of
+// the jump. If key is empty then `DefaultClockSkewKey` is used.
+func MonotonicTime(skewThreshold time.Duration, key string) Option {
+	return func(l *Logr) error {
+		if skewThreshold < 0 {
+			return errors.New("skewThreshold cannot be negative")
+		}
+		if key == "" {
+			key = DefaultClockSkewKey
+		}
+		l.options.monotonic = true
+		l.options.clockSkewThreshold = skewThreshold
+		l.options.clockSkewKey = key
+		return nil
+	}
+}
+
+// Sampling limits the volume of repetitive log records. Within each `tick`, the first
+// `first` records with a given level and message are logged, then only every
+// `thereafter`th record; if thereafter is zero the rest are dropped. Records of Error
+// level and more severe are never sampled. Records are sampled before being queued,
+// so sampling is cheap and relieves the whole pipeline. The number of records dropped
+// is available via `Logr.StatsSnapshot`.
+func Sampling(tick time.Duration, first int, thereafter int) Option {
+	return func(l *Logr) error {
+		if tick <= 0 {
+			return errors.New("tick must be greater than zero")
+		}
+		if first < 0 || thereafter < 0 {
+			return errors.New("first and thereafter cannot be negative")
+		}
+		l.options.sampleTick = tick
+		l.options.sampleFirst = first
+		l.options.sampleThereafter = thereafter
+		return nil
+	}
+}
+
+// DefaultTargetQueueSize sets the queue size used for targets added with a
+// `maxQueueSize` of zero or less via `Logr.AddTarget`.
+func DefaultTargetQueueSize(size int) Option {
+	return func(l *Logr) error {
+		if size <= 0 {
+			return errors.New("size must be greater than zero")
+		}
+		l.options.targetQueueSize = size
+		return nil
+	}
+}
+
+// Clock sets the function used to timestamp log records, instead of `time.Now`.
+// This is useful for deterministic output in tests.
+func Clock(now func() time.Time) Option {
+	return func(l *Logr) error {
+		if now == nil {
+			return errors.New("clock cannot be nil")
+		}
+		l.options.clock = now
+		return nil
+	}
+}
+
+// InternalLogger sets the logger used to output errors that occur within Logr,
+// such as target write failures, when no `OnLoggerError` callback is set.
+// Defaults to writing to stderr.
+func InternalLogger(logger *log.Logger) Option {
+	return func(l *Logr) error {
+		if logger == nil {
+			return errors.New("internal logger cannot be nil")
+		}
+		l.options.internalLogger = logger
+		return nil
+	}
+}
+
+// GlobalFields adds fields to every log record, as if every `Logger` created via
+// `Logr.NewLogger` was created with `Logger.With(fields...)`. This is useful for
+// fields such as the service name or version.
+func GlobalFields(fields ...Field) Option {
+	return func(l *Logr) error {
+		l.options.globalFields = append(l.options.globalFields, fields...)
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package logr_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstructionOptions(t *testing.T) {
+	ts := time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC)
+	internal := &bytes.Buffer{}
+
+	lgr, err := logr.New(
+		logr.Clock(func() time.Time { return ts }),
+		logr.GlobalFields(logr.String("service", "billing")),
+		logr.InternalLogger(log.New(internal, "logr: ", 0)),
+		logr.DefaultTargetQueueSize(7),
+	)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	formatter := &formatters.Plain{TimestampFormat: time.RFC3339}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "optionsTest", &logr.StdFilter{Lvl: logr.Info}, formatter, 0)
+	require.NoError(t, err)
+
+	lgr.NewLogger().With(logr.Int("n", 1)).Info("hello")
+	lgr.ReportError(errors.New("target failed"))
+
+	stats := lgr.StatsSnapshot()
+	require.Len(t, stats.Targets, 1)
+	assert.Equal(t, 7, stats.Targets[0].QueueCapacity)
+
+	require.NoError(t, lgr.Shutdown())
+	assert.Equal(t, "info [2021-03-14T15:09:26Z] hello service=billing n=1\n", buf.String())
+	assert.Equal(t, "logr: target failed\n", internal.String())
+}
+
+func TestConstructionOptionsInvalid(t *testing.T) {
+	_, err := logr.New(logr.Clock(nil))
+	assert.Error(t, err)
+	_, err = logr.New(logr.InternalLogger(nil))
+	assert.Error(t, err)
+	_, err = logr.New(logr.DefaultTargetQueueSize(0))
+	assert.Error(t, err)
+	_, err = logr.New(logr.StableStacktraces(logr.StackMode(99)))
+	assert.Error(t, err)
+}
+
+func TestStableStacktraces(t *testing.T) {
+	logError := func(mode logr.StackMode) string {
+		lgr, err := logr.New(logr.StableStacktraces(mode))
+		require.NoError(t, err)
+
+		buf := &test.Buffer{}
+		formatter := &formatters.Plain{DisableTimestamp: true, EnableCaller: true}
+		err = lgr.AddTarget(targets.NewWriterTarget(buf), "stackTest", &logr.StdFilter{Lvl: logr.Info, Stacktrace: logr.Error}, formatter, 10)
+		require.NoError(t, err)
+
+		lgr.NewLogger().Error("failed")
+		require.NoError(t, lgr.Shutdown())
+		return buf.String()
+	}
+
+	output := logError(logr.StackModePlaceholder)
+	assert.Regexp(t, `^error failed caller="[^/"]+/options_test.go:\d+"\n  \[stacktrace\]\n\n$`, output)
+
+	output = logError(logr.StackModeRelative)
+	assert.Contains(t, output, "logr/v2_test.TestStableStacktraces")
+	assert.Regexp(t, `\n      [^/\s]+/options_test.go:\d+\n`, output)
+	assert.NotContains(t, output, "testing.tRunner")
+	assert.NotContains(t, output, "runtime.goexit")
+
+	output = logError(logr.StackModeFull)
+	assert.Contains(t, output, "testing.tRunner")
+}
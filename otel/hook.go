@@ -0,0 +1,89 @@
+// Package logrotel mirrors Logr log records to OpenTelemetry traces, so errors show
+// up inline in distributed traces without instrumenting code twice.
+//
+// Register the hook with the Logr instance, then use the `XXXCtx` style log APIs
+// with a context carrying the active span:
+//
+//	lgr, _ := logr.New(logr.ContextHooks(logrotel.NewSpanEventHook(logrotel.Options{})))
+//	...
+//	logger.ErrorCtx(ctx, "payment failed", logr.Err(err))
+//
+// This package is a separate module so that applications not using OpenTelemetry
+// do not depend on it.
+package logrotel
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttrLevel is the key of the event attribute containing the log record's level name.
+const AttrLevel = "log.level"
+
+// Options provides parameters for the span event hook.
+type Options struct {
+	// Filter determines which log records are mirrored to spans. Defaults to Warn and
+	// more severe levels.
+	Filter logr.Filter
+
+	// SetErrorStatus sets the span status to error, with the record's message as the
+	// description, for records of Error level or more severe.
+	SetErrorStatus bool
+}
+
+// NewSpanEventHook creates a hook that adds log records as events on the recording span
+// carried by the context. The event name is the record's message, and the record's level
+// and fields are added as event attributes. Records logged with a context carrying no
+// recording span are ignored.
+func NewSpanEventHook(options Options) logr.ContextHook {
+	filter := options.Filter
+	if filter == nil {
+		filter = &logr.StdFilter{Lvl: logr.Warn}
+	}
+
+	return func(ctx context.Context, lvl logr.Level, msg string, fields []logr.Field) {
+		if _, enabled := filter.GetEnabledLevel(lvl); !enabled {
+			return
+		}
+		span := trace.SpanFromContext(ctx)
+		if !span.IsRecording() {
+			return
+		}
+
+		attrs := make([]attribute.KeyValue, 0, len(fields)+1)
+		attrs = append(attrs, attribute.String(AttrLevel, lvl.Name))
+		for _, f := range fields {
+			attrs = append(attrs, newAttribute(f))
+		}
+		span.AddEvent(msg, trace.WithAttributes(attrs...))
+
+		if options.SetErrorStatus && lvl.ID <= logr.Error.ID {
+			span.SetStatus(codes.Error, msg)
+		}
+	}
+}
+
+// newAttribute converts a field to an attribute, keeping numeric and boolean types.
+// Other types are converted to strings.
+func newAttribute(f logr.Field) attribute.KeyValue {
+	switch f.Type {
+	case logr.BoolType:
+		return attribute.Bool(f.Key, f.Integer != 0)
+	case logr.Int64Type, logr.Int32Type, logr.IntType, logr.DurationType, logr.TimestampMillisType,
+		logr.Uint64Type, logr.Uint32Type, logr.UintType:
+		return attribute.Int64(f.Key, f.Integer)
+	case logr.Float64Type, logr.Float32Type:
+		return attribute.Float64(f.Key, f.Float)
+	case logr.StringType:
+		return attribute.String(f.Key, f.String)
+	default:
+		var sb strings.Builder
+		_ = f.ValueString(&sb, nil)
+		return attribute.String(f.Key, sb.String())
+	}
+}
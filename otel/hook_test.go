@@ -0,0 +1,58 @@
+package logrotel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpanEventHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	lgr, err := logr.New(logr.ContextHooks(NewSpanEventHook(Options{SetErrorStatus: true})))
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+	err = lgr.AddTarget(targets.NewWriterTarget(io.Discard), "discard", &logr.StdFilter{Lvl: logr.Info}, nil, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "wiggin"))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+	logger.InfoCtx(ctx, "not mirrored")
+	logger.WarnCtx(ctx, "slow query", logr.Int("millis", 1200), logr.Bool("cached", false))
+	logger.ErrorCtx(ctx, "payment failed", logr.Err(errors.New("card declined")))
+	span.End()
+
+	// no span in context.
+	logger.ErrorCtx(context.Background(), "no span")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	events := spans[0].Events()
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "slow query", events[0].Name)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String(AttrLevel, "warn"),
+		attribute.String("user", "wiggin"),
+		attribute.Int64("millis", 1200),
+		attribute.Bool("cached", false),
+	}, events[0].Attributes)
+
+	assert.Equal(t, "payment failed", events[1].Name)
+	assert.Contains(t, events[1].Attributes, attribute.String("error", "card declined"))
+
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "payment failed", spans[0].Status().Description)
+}
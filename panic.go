@@ -0,0 +1,73 @@
+package logr
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+const (
+	// PanicValueKey is the field key used for the value recovered from a panic.
+	PanicValueKey = "panic"
+	// PanicStackKey is the field key used for the stack trace of a recovered panic.
+	PanicStackKey = "panic_stack"
+)
+
+// LogPanic recovers from a panic, logs it at Panic level with the recovered value
+// and full stack as fields, flushes the Logr, and then re-panics with the same value.
+// It must be deferred directly:
+//
+//	defer logr.LogPanic(logger)
+func LogPanic(logger Logger) {
+	if r := recover(); r != nil {
+		logRecovered(logger, r, 4)
+		_ = logger.Logr().Flush()
+		panic(r)
+	}
+}
+
+// RecoverAndLog recovers from a panic and logs it at Panic level with the recovered
+// value and full stack as fields. Unlike `LogPanic` the panic does not continue.
+// It must be deferred directly:
+//
+//	defer logr.RecoverAndLog(logger)
+func RecoverAndLog(logger Logger) {
+	if r := recover(); r != nil {
+		logRecovered(logger, r, 4)
+	}
+}
+
+// LogRecovered logs a value already obtained via `recover()` at Panic level, with the
+// recovered value and full stack as fields. This is useful when the recovery must
+// be handled by the caller, for example in middleware.
+func LogRecovered(logger Logger, recovered interface{}) {
+	logRecovered(logger, recovered, 3)
+}
+
+func logRecovered(logger Logger, recovered interface{}, skip int) {
+	var val Field
+	if err, ok := recovered.(error); ok {
+		val = NamedErr(PanicValueKey, err)
+	} else {
+		val = String(PanicValueKey, fmt.Sprint(recovered))
+	}
+
+	logger.Log(Panic, "recovered from panic", val, Array(PanicStackKey, panicStack(skip)))
+}
+
+// panicStack returns the current stack, one "function file:line" entry per frame.
+func panicStack(skip int) []string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make([]string, 0, n)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return stack
+}
@@ -0,0 +1,45 @@
+package logr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogPanic(t *testing.T) {
+	lgr, _ := logr.New()
+	buf := &test.Buffer{}
+	err := lgr.AddTarget(targets.NewWriterTarget(buf), "panicTest", &logr.StdFilter{Lvl: logr.Panic},
+		&formatters.JSON{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+	logger := lgr.NewLogger()
+
+	t.Run("recover and log", func(t *testing.T) {
+		func() {
+			defer logr.RecoverAndLog(logger)
+			panic("boom!")
+		}()
+		require.NoError(t, lgr.Flush())
+		assert.Contains(t, buf.String(), `"panic":"boom!"`)
+		assert.Contains(t, buf.String(), `"panic_stack":["`)
+		assert.Contains(t, buf.String(), "logr/v2_test.TestLogPanic")
+	})
+
+	t.Run("log and repanic", func(t *testing.T) {
+		panicErr := errors.New("kaboom")
+		assert.PanicsWithValue(t, panicErr, func() {
+			defer logr.LogPanic(logger)
+			panic(panicErr)
+		})
+		assert.Contains(t, buf.String(), `"panic":"kaboom"`)
+	})
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+}
@@ -0,0 +1,111 @@
+package logr
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// PressureEvent describes a pressure threshold crossing. See `OnPressure`.
+type PressureEvent struct {
+	// Pressure is the pressure when the crossing was observed.
+	Pressure float64
+
+	// Threshold is the threshold crossed.
+	Threshold float64
+
+	// Rising is true when pressure rose to or above the threshold, and false when it
+	// fell below.
+	Rising bool
+}
+
+// OnPressure calls f each time the pressure returned by `Logr.Pressure` crosses one
+// of the thresholds, which must be greater than zero and at most 1. Pressure is
+// polled every interval on a separate goroutine, so crossings are observed even when
+// the queues are blocked. When several thresholds are crossed between polls, f is
+// called for each in the order crossed. f is called on the polling goroutine and
+// should return promptly; logging from f may block while the queues are full.
+func OnPressure(interval time.Duration, f func(event PressureEvent), thresholds ...float64) Option {
+	return func(l *Logr) error {
+		if interval <= 0 {
+			return errors.New("pressure interval must be greater than zero")
+		}
+		if f == nil {
+			return errors.New("pressure callback cannot be nil")
+		}
+		if len(thresholds) == 0 {
+			return errors.New("at least one pressure threshold is required")
+		}
+		sorted := append([]float64(nil), thresholds...)
+		sort.Float64s(sorted)
+		for _, t := range sorted {
+			if t <= 0 || t > 1 {
+				return errors.New("pressure thresholds must be greater than 0 and at most 1")
+			}
+		}
+		l.options.pressure = &pressureOptions{interval: interval, onPressure: f, thresholds: sorted}
+		return nil
+	}
+}
+
+type pressureOptions struct {
+	interval   time.Duration
+	onPressure func(event PressureEvent)
+	thresholds []float64
+}
+
+// Pressure returns how saturated logging is, from 0.0 when all queues are empty to
+// 1.0 when any is full. It is the highest fill ratio of the Logr queue and the queues
+// of its targets. Applications can use it to proactively reduce their verbosity or
+// shed work before records are dropped or callers blocked.
+func (lgr *Logr) Pressure() float64 {
+	pressure := queueFill(len(lgr.in), cap(lgr.in))
+
+	lgr.tmux.RLock()
+	defer lgr.tmux.RUnlock()
+	for _, host := range lgr.targetHosts {
+		if fill := queueFill(host.queueLen(), cap(host.queue())); fill > pressure {
+			pressure = fill
+		}
+	}
+	return pressure
+}
+
+// queueFill returns the fill ratio of a queue, capped to 1 since records held by a
+// priority queue are counted in addition to the queue.
+func queueFill(n int, size int) float64 {
+	if size == 0 {
+		return 0
+	}
+	fill := float64(n) / float64(size)
+	if fill > 1 {
+		fill = 1
+	}
+	return fill
+}
+
+// watchPressure polls the pressure until the Logr is shut down, calling the
+// `OnPressure` callback for each threshold crossed.
+func (lgr *Logr) watchPressure(opts pressureOptions) {
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	// crossed is the number of thresholds at or below the pressure.
+	var crossed int
+	for {
+		select {
+		case <-lgr.quit:
+			return
+		case <-ticker.C:
+		}
+
+		pressure := lgr.Pressure()
+		n := sort.Search(len(opts.thresholds), func(i int) bool { return opts.thresholds[i] > pressure })
+		for ; crossed < n; crossed++ {
+			opts.onPressure(PressureEvent{Pressure: pressure, Threshold: opts.thresholds[crossed], Rising: true})
+		}
+		for ; crossed > n; crossed-- {
+			opts.onPressure(PressureEvent{Pressure: pressure, Threshold: opts.thresholds[crossed-1], Rising: false})
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package logr_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPressure(t *testing.T) {
+	var mux sync.Mutex
+	var events []logr.PressureEvent
+	lgr, err := logr.New(logr.OnPressure(time.Millisecond*5, func(event logr.PressureEvent) {
+		mux.Lock()
+		defer mux.Unlock()
+		events = append(events, event)
+	}, 0.8, 0.5))
+	require.NoError(t, err)
+	getEvents := func() []logr.PressureEvent {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]logr.PressureEvent(nil), events...)
+	}
+
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "pressureTest", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, lgr.Pressure())
+
+	logger := lgr.NewLogger()
+	logger.Info("first")
+	<-target.Blocked()
+	for i := 0; i < 6; i++ {
+		logger.Info("fill")
+	}
+
+	assert.Eventually(t, func() bool { return lgr.Pressure() == 0.6 }, time.Second*5, time.Millisecond*10)
+	assert.Eventually(t, func() bool { return len(getEvents()) == 1 }, time.Second*5, time.Millisecond*10)
+
+	target.Unblock()
+	assert.Eventually(t, func() bool { return len(getEvents()) == 2 }, time.Second*5, time.Millisecond*10)
+	require.NoError(t, lgr.Shutdown())
+
+	events = getEvents()
+	require.Len(t, events, 2)
+	assert.Equal(t, 0.5, events[0].Threshold)
+	assert.True(t, events[0].Rising)
+	assert.Equal(t, 0.5, events[1].Threshold)
+	assert.False(t, events[1].Rising)
+}
+
+func TestOnPressureOptions(t *testing.T) {
+	f := func(logr.PressureEvent) {}
+	_, err := logr.New(logr.OnPressure(0, f, 0.5))
+	assert.Error(t, err)
+	_, err = logr.New(logr.OnPressure(time.Second, nil, 0.5))
+	assert.Error(t, err)
+	_, err = logr.New(logr.OnPressure(time.Second, f))
+	assert.Error(t, err)
+	_, err = logr.New(logr.OnPressure(time.Second, f, 1.5))
+	assert.Error(t, err)
+}
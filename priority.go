@@ -0,0 +1,128 @@
+package logr
+
+import (
+	"container/heap"
+	"sync/atomic"
+)
+
+// PriorityQueue delivers queued records to the target ordered by level, most severe
+// first, then by time, instead of first-in first-out. When a backlog builds up, for
+// example while an alerting sink is slow, errors are delivered ahead of older
+// chatty records. Records are reordered within a window of up to the target's queue
+// size, held by the target in addition to its queue. Flushing delivers all records
+// queued before the flush. Custom levels are compared by ID, where lower IDs are
+// more severe.
+func PriorityQueue() TargetOption {
+	return func(opts *targetHostOptions) error {
+		opts.priority = true
+		return nil
+	}
+}
+
+// priorityQueue is a heap of dequeued records awaiting delivery. It is only
+// accessed by the target's read loop, except for the length.
+type priorityQueue struct {
+	recs  []priorityRec
+	seq   uint64
+	count int32
+}
+
+type priorityRec struct {
+	rec *LogRec
+	seq uint64 // preserves queue order for records with the same level and time
+}
+
+func (pq *priorityQueue) Len() int { return len(pq.recs) }
+
+func (pq *priorityQueue) Less(i, j int) bool {
+	a, b := pq.recs[i], pq.recs[j]
+	if a.rec.Level().ID != b.rec.Level().ID {
+		return a.rec.Level().ID < b.rec.Level().ID
+	}
+	if !a.rec.Time().Equal(b.rec.Time()) {
+		return a.rec.Time().Before(b.rec.Time())
+	}
+	return a.seq < b.seq
+}
+
+func (pq *priorityQueue) Swap(i, j int) { pq.recs[i], pq.recs[j] = pq.recs[j], pq.recs[i] }
+
+func (pq *priorityQueue) Push(x interface{}) {
+	pq.recs = append(pq.recs, x.(priorityRec))
+}
+
+func (pq *priorityQueue) Pop() interface{} {
+	last := len(pq.recs) - 1
+	pr := pq.recs[last]
+	pq.recs[last] = priorityRec{}
+	pq.recs = pq.recs[:last]
+	return pr
+}
+
+func (pq *priorityQueue) push(rec *LogRec) {
+	pq.seq++
+	heap.Push(pq, priorityRec{rec: rec, seq: pq.seq})
+	atomic.AddInt32(&pq.count, 1)
+}
+
+func (pq *priorityQueue) pop() *LogRec {
+	atomic.AddInt32(&pq.count, -1)
+	return heap.Pop(pq).(priorityRec).rec
+}
+
+// pending returns the number of records awaiting delivery; safe for any goroutine.
+func (pq *priorityQueue) pending() int {
+	return int(atomic.LoadInt32(&pq.count))
+}
+
+// popPriority removes the most severe record from the heap, releasing its bytes
+// since records held by the heap count towards `MaxQueueBytes`.
+func (h *TargetHost) popPriority() *LogRec {
+	rec := h.priority.pop()
+	h.releaseBytes(rec)
+	return rec
+}
+
+// startPriority is the read loop for targets with a priority queue. Records are
+// moved from the queue to the heap while available and the heap has room, and
+// the most severe is written whenever the queue is empty or the heap is full.
+func (h *TargetHost) startPriority() {
+	pq := h.priority
+	in := h.queue()
+	for {
+		var rec *LogRec
+		var ok bool
+		switch {
+		case pq.Len() == 0:
+			select {
+			case rec, ok = <-in:
+			case <-h.quit:
+				return
+			}
+		case pq.Len() < cap(in):
+			select {
+			case rec, ok = <-in:
+			case <-h.quit:
+				return
+			default:
+				h.writeQueued(h.popPriority())
+				continue
+			}
+		default:
+			h.writeQueued(h.popPriority())
+			continue
+		}
+
+		switch {
+		case !ok:
+			in = h.queue() // replaced by setQueueSize
+		case rec.flush != nil:
+			for pq.Len() > 0 {
+				h.writeQueued(h.popPriority())
+			}
+			in = h.flush(in, rec)
+		default:
+			pq.push(rec)
+		}
+	}
+}
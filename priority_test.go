@@ -0,0 +1,45 @@
+package logr_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "alerts", &logr.StdFilter{Lvl: logr.Debug}, &formatters.Plain{DisableTimestamp: true}, 100,
+		logr.PriorityQueue())
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("outage")
+	<-target.Blocked()
+
+	logger.Debug("first debug")
+	logger.Info("first info")
+	logger.Error("first error")
+	logger.Warn("warning")
+	logger.Error("second error")
+	logger.Debug("second debug")
+
+	target.Unblock()
+	require.NoError(t, lgr.Flush())
+
+	logger.Info("after flush")
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, "info outage \n"+
+		"error first error \nerror second error \nwarn warning \n"+
+		"info first info \ndebug first debug \ndebug second debug \n"+
+		"info after flush \n", buf.String())
+	assert.Equal(t, 0, lgr.StatsSnapshot().Targets[0].QueueSize)
+}
@@ -0,0 +1,47 @@
+package logr
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// MaxQueueBytes bounds the approximate size of the records queued for the target,
+// in addition to the queue size, since a few huge records can use a lot of memory
+// long before the queue is full. Sizes are estimated from the records' messages,
+// fields and stack traces rather than formatted. A record that would exceed the
+// bound is dropped, and counted by the target's dropped counter, unless its level is
+// at or above the target's `BlockLevel` or the queue is empty. The bytes queued
+// are reported in `TargetStats.QueueBytes`.
+func MaxQueueBytes(maxBytes int) TargetOption {
+	return func(opts *targetHostOptions) error {
+		if maxBytes < 0 {
+			return errors.New("max queue bytes cannot be negative")
+		}
+		opts.maxQueueBytes = int64(maxBytes)
+		return nil
+	}
+}
+
+// reserveBytes adds the size of a record about to be queued to the bytes queued,
+// returning false, and counting the record as dropped, if it would exceed
+// `MaxQueueBytes`. block is true for records at or above the `BlockLevel`.
+func (h *TargetHost) reserveBytes(rec *LogRec, block bool) bool {
+	if h.maxQueueBytes == 0 || rec.flush != nil {
+		return true
+	}
+	size := rec.estimatedSize()
+	queued := atomic.AddInt64(&h.stats.queuedBytes, size)
+	if block || queued <= h.maxQueueBytes || queued == size {
+		return true
+	}
+	atomic.AddInt64(&h.stats.queuedBytes, -size)
+	h.incDroppedCounter()
+	return false
+}
+
+// releaseBytes subtracts the size of a record no longer queued from the bytes queued.
+func (h *TargetHost) releaseBytes(rec *LogRec) {
+	if h.maxQueueBytes > 0 && rec.flush == nil {
+		atomic.AddInt64(&h.stats.queuedBytes, -rec.estimatedSize())
+	}
+}
@@ -0,0 +1,57 @@
+package logr_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxQueueBytes(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "bytesTest", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100, logr.MaxQueueBytes(1000), logr.BlockLevel(logr.Error))
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("first")
+	<-target.Blocked()
+
+	big := strings.Repeat("x", 400)
+	logger.Info("big1", logr.String("data", big))
+	logger.Info("big2", logr.String("data", big))
+	// exceeds the bound.
+	logger.Info("dropped", logr.String("data", big))
+	// still fits.
+	logger.Info("small")
+	// never dropped.
+	logger.Error("error", logr.String("data", big))
+
+	var stats logr.TargetStats
+	assert.Eventually(t, func() bool {
+		stats = lgr.StatsSnapshot().Targets[0]
+		return stats.QueueSize == 4
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Greater(t, stats.QueueBytes, int64(1000))
+
+	target.Unblock()
+	require.NoError(t, lgr.Flush())
+	assert.Equal(t, int64(0), lgr.StatsSnapshot().Targets[0].QueueBytes)
+	require.NoError(t, lgr.Shutdown())
+
+	output := buf.String()
+	assert.NotContains(t, output, "dropped")
+	for _, s := range []string{"first", "big1", "big2", "small", "error"} {
+		assert.Contains(t, output, s)
+	}
+}
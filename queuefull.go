@@ -0,0 +1,155 @@
+package logr
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RecentDropsWindow is the period covered by `TargetQueueFullInfo.RecentDrops`.
+const RecentDropsWindow = time.Second * 10
+
+// QueueFullAction determines what happens to a log record that cannot be added
+// to a full target queue. See `OnTargetQueueFullPolicy`.
+type QueueFullAction int
+
+const (
+	// QueueFullBlock blocks until the record is queued or `EnqueueTimeout` expires.
+	QueueFullBlock QueueFullAction = iota
+
+	// QueueFullDrop drops the record.
+	QueueFullDrop
+
+	// QueueFullReroute queues the record for the target named by
+	// `QueueFullDecision.Target` instead. The record is dropped if that target
+	// does not exist or does not enable the record's level, and is not rerouted
+	// again should that target's queue also be full.
+	QueueFullReroute
+)
+
+// QueueFullDecision is returned by a `TargetQueueFullPolicy`.
+type QueueFullDecision struct {
+	Action QueueFullAction
+
+	// Target is the name of the target to reroute to when Action is QueueFullReroute.
+	Target string
+}
+
+// TargetQueueFullInfo describes a log record that cannot be added to a full
+// target queue.
+type TargetQueueFullInfo struct {
+	// Target and Name identify the target whose queue is full.
+	Target Target
+	Name   string
+
+	// Rec is the log record being added, and Level its level.
+	Rec   *LogRec
+	Level Level
+
+	// Stats are the target's current queue size, capacity and counters.
+	Stats TargetStats
+
+	// RecentDrops is the number of records dropped by the target during the
+	// last RecentDropsWindow.
+	RecentDrops uint64
+}
+
+// TargetQueueFullPolicy decides what happens to a log record that cannot be added
+// to a full target queue. It is called from the Logr's goroutine and should return
+// quickly.
+type TargetQueueFullPolicy func(info TargetQueueFullInfo) QueueFullDecision
+
+// OnTargetQueueFullPolicy sets a policy called on an attempt to add a log record to a
+// full target queue. Unlike `OnTargetQueueFull`, which it replaces when set, the policy
+// receives the target's queue statistics and recent drop count, and can reroute the
+// record to another target as well as block or drop it.
+func OnTargetQueueFullPolicy(policy TargetQueueFullPolicy) Option {
+	return func(l *Logr) error {
+		l.options.targetQueueFullPolicy = policy
+		return nil
+	}
+}
+
+// queueFull applies the queue full policy, or `OnTargetQueueFull` handler, and
+// returns true if the record was handled, otherwise the record should block.
+// The caller must hold the Logr's target read lock.
+func (h *TargetHost) queueFull(lgr *Logr, rec *LogRec, in chan *LogRec, rerouted bool) bool {
+	policy := lgr.options.targetQueueFullPolicy
+	if policy == nil {
+		handler := lgr.options.onTargetQueueFull
+		if handler != nil && handler(h.target, rec, cap(in)) {
+			h.incDroppedCounter()
+			return true
+		}
+		return false
+	}
+
+	decision := policy(TargetQueueFullInfo{
+		Target:      h.target,
+		Name:        h.name,
+		Rec:         rec,
+		Level:       rec.Level(),
+		Stats:       h.statsSnapshot(),
+		RecentDrops: h.recentDrops.count(time.Now()),
+	})
+
+	switch decision.Action {
+	case QueueFullDrop:
+		h.incDroppedCounter()
+		return true
+	case QueueFullReroute:
+		if rerouted {
+			h.incDroppedCounter()
+			return true
+		}
+		dest := lgr.findTargetLocked(decision.Target)
+		if dest == nil || dest == h {
+			lgr.ReportError(fmt.Errorf("cannot reroute log rec from target %s to %s", h.name, decision.Target))
+			h.incDroppedCounter()
+			return true
+		}
+		if enabled, _ := dest.IsLevelEnabled(rec.Level()); !enabled || !dest.IsRecordEnabled(rec) {
+			h.incDroppedCounter()
+			return true
+		}
+		atomic.AddUint64(&h.stats.rerouted, 1)
+		dest.log(rec, true)
+		return true
+	}
+	return false
+}
+
+// dropWindow counts drops over the last RecentDropsWindow using one bucket per second.
+type dropWindow struct {
+	mux     sync.Mutex
+	buckets [10]uint64
+	secs    [10]int64
+}
+
+func (w *dropWindow) add(now time.Time) {
+	sec := now.Unix()
+	i := sec % int64(len(w.buckets))
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.secs[i] != sec {
+		w.secs[i] = sec
+		w.buckets[i] = 0
+	}
+	w.buckets[i]++
+}
+
+func (w *dropWindow) count(now time.Time) uint64 {
+	sec := now.Unix()
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	var total uint64
+	for i, s := range w.secs {
+		if sec-s < int64(len(w.buckets)) {
+			total += w.buckets[i]
+		}
+	}
+	return total
+}
@@ -0,0 +1,124 @@
+package logr_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnTargetQueueFullPolicy(t *testing.T) {
+	var mux sync.Mutex
+	var infos []logr.TargetQueueFullInfo
+	policy := logr.OnTargetQueueFullPolicy(func(info logr.TargetQueueFullInfo) logr.QueueFullDecision {
+		mux.Lock()
+		infos = append(infos, info)
+		mux.Unlock()
+
+		switch info.Rec.Msg() {
+		case "rerouted":
+			return logr.QueueFullDecision{Action: logr.QueueFullReroute, Target: "overflow"}
+		case "missing":
+			return logr.QueueFullDecision{Action: logr.QueueFullReroute, Target: "missing"}
+		}
+		return logr.QueueFullDecision{Action: logr.QueueFullDrop}
+	})
+	var reported []error
+	lgr, err := logr.New(policy, logr.OnLoggerError(func(err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		reported = append(reported, err)
+	}))
+	require.NoError(t, err)
+
+	filter := &logr.StdFilter{Lvl: logr.Info}
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	primaryBuf := &test.Buffer{}
+	primary := test.NewBlockingTarget(primaryBuf)
+	require.NoError(t, lgr.AddTarget(primary, "primary", filter, formatter, 1))
+	overflowBuf := &test.Buffer{}
+	require.NoError(t, lgr.AddTarget(targets.NewWriterTarget(overflowBuf), "overflow", &logr.StdFilter{Lvl: logr.Error}, formatter, 10))
+
+	logger := lgr.NewLogger()
+	logger.Info("blocked")
+	<-primary.Blocked()
+
+	logger.Info("queued")
+	logger.Warn("dropped")
+	logger.Error("rerouted")
+	logger.Info("missing")
+
+	require.Eventually(t, func() bool {
+		mux.Lock()
+		defer mux.Unlock()
+		return len(infos) == 3
+	}, time.Second*5, time.Millisecond*10)
+
+	primary.Unblock()
+	require.NoError(t, lgr.Shutdown())
+
+	require.Len(t, infos, 3)
+	assert.Equal(t, "primary", infos[0].Name)
+	assert.Equal(t, logr.Warn, infos[0].Level)
+	assert.Equal(t, 1, infos[0].Stats.QueueSize)
+	assert.Equal(t, 1, infos[0].Stats.QueueCapacity)
+	assert.Equal(t, uint64(0), infos[0].RecentDrops)
+	assert.Equal(t, uint64(1), infos[1].RecentDrops)
+	assert.Equal(t, uint64(1), infos[2].Stats.Rerouted)
+
+	stats := lgr.StatsSnapshot().Targets
+	assert.Equal(t, uint64(2), stats[0].Dropped)
+	assert.Equal(t, uint64(1), stats[0].Rerouted)
+	assert.Len(t, reported, 1)
+
+	assert.Contains(t, primaryBuf.String(), "queued")
+	assert.NotContains(t, primaryBuf.String(), "rerouted")
+	// overflow logs errors itself as well as those rerouted from primary.
+	assert.Equal(t, 2, strings.Count(overflowBuf.String(), "rerouted"))
+	for _, s := range []string{"dropped", "missing"} {
+		assert.NotContains(t, primaryBuf.String(), s)
+		assert.NotContains(t, overflowBuf.String(), s)
+	}
+}
+
+func TestOnTargetQueueFullPolicyRecordFilter(t *testing.T) {
+	policy := logr.OnTargetQueueFullPolicy(func(info logr.TargetQueueFullInfo) logr.QueueFullDecision {
+		return logr.QueueFullDecision{Action: logr.QueueFullReroute, Target: "overflow"}
+	})
+	lgr, err := logr.New(policy)
+	require.NoError(t, err)
+
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	primary := test.NewBlockingTarget(&test.Buffer{})
+	require.NoError(t, lgr.AddTarget(primary, "primary", &logr.StdFilter{Lvl: logr.Info}, formatter, 1))
+	overflowFilter, err := logr.NewExprFilter(`level >= info && msg != "secret"`)
+	require.NoError(t, err)
+	overflowBuf := &test.Buffer{}
+	require.NoError(t, lgr.AddTarget(targets.NewWriterTarget(overflowBuf), "overflow", overflowFilter, formatter, 10))
+
+	logger := lgr.NewLogger()
+	logger.Info("blocked")
+	<-primary.Blocked()
+
+	// only records enabled by the overflow target's record filter are rerouted.
+	logger.Info("queued")
+	logger.Info("secret")
+	logger.Info("public")
+	require.Eventually(t, func() bool {
+		return lgr.StatsSnapshot().Targets[0].Rerouted == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	primary.Unblock()
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, uint64(1), lgr.StatsSnapshot().Targets[0].Dropped)
+	assert.NotContains(t, overflowBuf.String(), "info secret")
+	assert.Contains(t, overflowBuf.String(), "info public")
+}
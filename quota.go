@@ -0,0 +1,152 @@
+package logr
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// QuotaWindow is the period over which `VolumeQuota` limits are applied.
+	QuotaWindow = time.Minute
+
+	// QuotaSummaryMsg is the message of the summary record output for each key
+	// that exceeded its quota.
+	QuotaSummaryMsg = "log quota exceeded"
+)
+
+// VolumeQuota limits the number of log records per minute for each value of the
+// field designated via `VolumeAccounting`, protecting shared targets from a single
+// noisy tenant. Once a key exceeds its quota within a minute, its Info and less
+// severe records are sampled: only every `thereafter`th is logged, or none if
+// thereafter is zero. Warn and more severe records are never dropped but do count
+// towards the quota.
+//
+// perMinute is the quota for every key, with overrides containing quotas for specific
+// keys. A quota of zero or less means no limit, so a zero perMinute limits only the
+// keys in overrides.
+//
+// When a minute ends, and on flush, a Warn record with message `QuotaSummaryMsg` is
+// output for each key that had records dropped, with fields containing the key,
+// the quota and the number of records dropped. The total number of records dropped
+// is available via `Logr.StatsSnapshot`.
+func VolumeQuota(perMinute int, thereafter int, overrides map[string]int) Option {
+	return func(l *Logr) error {
+		if thereafter < 0 {
+			return errors.New("thereafter cannot be negative")
+		}
+		q := &quotaOptions{perMinute: perMinute, thereafter: thereafter, overrides: make(map[string]int, len(overrides))}
+		for key, quota := range overrides {
+			q.overrides[key] = quota
+		}
+		l.options.quota = q
+		return nil
+	}
+}
+
+type quotaOptions struct {
+	perMinute  int
+	thereafter int
+	overrides  map[string]int
+}
+
+type quotaCount struct {
+	n       int
+	dropped int
+}
+
+// quotaLimiter applies `VolumeQuota`. It is only accessed by the Logr read loop
+// so needs no locking.
+type quotaLimiter struct {
+	quotaOptions
+	windowEnd time.Time
+	counts    map[string]*quotaCount
+}
+
+type quotaSummary struct {
+	key     string
+	quota   int
+	dropped int
+}
+
+func newQuotaLimiter(opts quotaOptions) *quotaLimiter {
+	return &quotaLimiter{quotaOptions: opts, counts: make(map[string]*quotaCount)}
+}
+
+func (q *quotaLimiter) quota(key string) int {
+	if quota, ok := q.overrides[key]; ok {
+		return quota
+	}
+	return q.perMinute
+}
+
+// allow counts a record for the key and returns true if it should be logged.
+func (q *quotaLimiter) allow(key string, lvl Level) bool {
+	c, ok := q.counts[key]
+	if !ok {
+		c = &quotaCount{}
+		q.counts[key] = c
+	}
+	c.n++
+
+	quota := q.quota(key)
+	if quota <= 0 || c.n <= quota || lvl.ID < Info.ID {
+		return true
+	}
+	if q.thereafter > 0 && (c.n-quota)%q.thereafter == 0 {
+		return true
+	}
+	c.dropped++
+	return false
+}
+
+// roll starts a new window if the current one ended before now, returning
+// summaries for keys that had records dropped.
+func (q *quotaLimiter) roll(now time.Time) []quotaSummary {
+	if now.Before(q.windowEnd) {
+		return nil
+	}
+	summaries := q.drain()
+	q.counts = make(map[string]*quotaCount)
+	q.windowEnd = now.Truncate(QuotaWindow).Add(QuotaWindow)
+	return summaries
+}
+
+// drain returns summaries for keys that had records dropped, sorted by key,
+// and resets their dropped counts.
+func (q *quotaLimiter) drain() []quotaSummary {
+	var summaries []quotaSummary
+	for key, c := range q.counts {
+		if c.dropped > 0 {
+			summaries = append(summaries, quotaSummary{key: key, quota: q.quota(key), dropped: c.dropped})
+			c.dropped = 0
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].key < summaries[j].key })
+	return summaries
+}
+
+// isOverQuota returns true if a prepped log record should be dropped because its
+// key exceeded its quota, first outputting summaries for a window that ended.
+func (lgr *Logr) isOverQuota(rec *LogRec) bool {
+	lgr.outputQuotaSummaries(lgr.quota.roll(rec.time))
+	if lgr.quota.allow(lgr.volume.keyOf(rec), rec.level) {
+		return false
+	}
+	atomic.AddUint64(&lgr.stats.overQuota, 1)
+	return true
+}
+
+func (lgr *Logr) outputQuotaSummaries(summaries []quotaSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	logger := lgr.NewLogger()
+	for _, s := range summaries {
+		fields := []Field{String(lgr.volume.key, s.key), Int("quota", s.quota), Int("dropped", s.dropped)}
+		rec := NewLogRec(Warn, logger, QuotaSummaryMsg, fields, false)
+		rec.prep()
+		lgr.dispatch(rec)
+	}
+}
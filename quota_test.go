@@ -0,0 +1,82 @@
+package logr_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolumeQuota(t *testing.T) {
+	_, err := logr.New(logr.VolumeQuota(10, 0, nil))
+	assert.Error(t, err)
+	_, err = logr.New(logr.VolumeAccounting("tenant"), logr.VolumeQuota(10, -1, nil))
+	assert.Error(t, err)
+
+	var mux sync.Mutex
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mux.Lock()
+		defer mux.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		now = now.Add(d)
+	}
+
+	lgr, err := logr.New(logr.Clock(clock), logr.VolumeAccounting("tenant"),
+		logr.VolumeQuota(3, 2, map[string]int{"vip": 0, "small": 1}))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "test", &logr.StdFilter{Lvl: logr.Debug},
+		&formatters.Plain{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	noisy := logger.With(logr.String("tenant", "noisy"))
+	for i := 0; i < 10; i++ {
+		noisy.Info("noisy info", logr.Int("n", i))
+	}
+	noisy.Error("noisy error")
+	for i := 0; i < 10; i++ {
+		logger.Debug("vip debug", logr.String("tenant", "vip"))
+	}
+	small := logger.With(logr.String("tenant", "small"))
+	small.Info("small one")
+	small.Info("small two")
+
+	advance(time.Minute)
+	noisy.Info("next window")
+	require.NoError(t, lgr.Flush())
+
+	output := buf.String()
+	// first 3 within quota, then every 2nd.
+	for _, n := range []string{"0", "1", "2", "4", "6", "8"} {
+		assert.Contains(t, output, "noisy info tenant=noisy n="+n+"\n")
+	}
+	for _, n := range []string{"3", "5", "7", "9"} {
+		assert.NotContains(t, output, "noisy info tenant=noisy n="+n+"\n")
+	}
+	assert.Contains(t, output, "noisy error")
+	assert.Equal(t, 10, strings.Count(output, "vip debug"))
+	assert.Contains(t, output, "small one")
+	assert.NotContains(t, output, "small two")
+	assert.Contains(t, output, "next window")
+
+	assert.Equal(t, 2, strings.Count(output, logr.QuotaSummaryMsg))
+	assert.Contains(t, output, "log quota exceeded tenant=noisy quota=3 dropped=4")
+	assert.Contains(t, output, "log quota exceeded tenant=small quota=1 dropped=1")
+	assert.Equal(t, uint64(5), lgr.StatsSnapshot().OverQuota)
+
+	require.NoError(t, lgr.Shutdown())
+}
@@ -0,0 +1,281 @@
+// Package receiver accepts log records forwarded by other logr instances and
+// re-injects them into the local pipeline, enabling a hub-and-spoke aggregation
+// topology built entirely from logr: spokes use a TCP or HTTP target with the
+// `formatters.JSON` formatter, and the hub runs a `Receiver`.
+//
+// Records are accepted as NDJSON, or logfmt, in the format understood by the
+// replay package, over raw TCP connections via `Serve`, or over HTTP via
+// `ServeHTTP`. Other encodings can be accepted over HTTP by registering a
+// `Decoder` per content type; the grpc module provides one for protobuf records.
+package receiver
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/replay"
+	"github.com/wiggin77/merror"
+)
+
+// DefaultMaxBodyBytes is the default maximum size of an HTTP request body.
+const DefaultMaxBodyBytes = 10 * 1024 * 1024
+
+// Decoder decodes the records in the body of an HTTP request.
+type Decoder func(r io.Reader) ([]*replay.Record, error)
+
+// Options configure a `Receiver`.
+type Options struct {
+	// Replay describes how NDJSON and logfmt records are formatted. The zero value
+	// matches the defaults of `formatters.JSON`. Records that cannot be parsed are
+	// reported via `Logr.ReportError` and skipped.
+	Replay replay.Options
+
+	// Decoders maps additional HTTP content types to decoders.
+	Decoders map[string]Decoder
+
+	// MaxBodyBytes is the maximum size of an HTTP request body, after decompression.
+	// Defaults to `DefaultMaxBodyBytes`.
+	MaxBodyBytes int64
+
+	// Relabel rules are applied, in order, to each received record before it is
+	// logged. Records can be dropped, fields added, rewritten or removed, and levels
+	// remapped by setting `LabelLevel` to a level name, matched against `Replay.Levels`
+	// or, when empty, mapped via `Replay.LevelMapper`.
+	Relabel []RelabelRule
+}
+
+// Receiver re-injects records received over TCP or HTTP into the local pipeline
+// via a logger, preserving their time stamps, levels and fields.
+type Receiver struct {
+	logger logr.Logger
+	opts   Options
+	rules  []relabelRule
+	levels map[string]logr.Level
+
+	received uint64 // atomic
+	dropped  uint64 // atomic
+
+	mux     sync.Mutex
+	conns   map[net.Conn]struct{}
+	lns     map[net.Listener]struct{}
+	closed  bool
+	connsWG sync.WaitGroup
+}
+
+// New creates a Receiver that logs received records via logger. An error is
+// returned if a relabel rule is invalid.
+func New(logger logr.Logger, opts Options) (*Receiver, error) {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	rules, err := compileRelabelRules(opts.Relabel)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := opts.Replay.Levels
+	if opts.Replay.LevelMapper == nil {
+		opts.Replay.LevelMapper = logr.GetLevelMapper()
+	}
+	r := &Receiver{
+		logger: logger,
+		opts:   opts,
+		rules:  rules,
+		levels: make(map[string]logr.Level, len(levels)),
+		conns:  make(map[net.Conn]struct{}),
+		lns:    make(map[net.Listener]struct{}),
+	}
+	for _, lvl := range levels {
+		r.levels[strings.ToLower(lvl.Name)] = lvl
+	}
+	return r, nil
+}
+
+// Inject applies the relabel rules to a received record and logs it via the
+// receiver's logger. source is the address of the sender, if known. Inject is used
+// by `Serve` and `ServeHTTP`, and can be used to feed records received by other
+// means into the receiver.
+func (r *Receiver) Inject(rec *replay.Record, source string) {
+	atomic.AddUint64(&r.received, 1)
+	if len(r.rules) > 0 && !r.relabel(rec, source) {
+		atomic.AddUint64(&r.dropped, 1)
+		return
+	}
+	r.logger.LogWithTime(rec.Time, rec.Level, rec.Msg, rec.Fields...)
+}
+
+// Received returns the number of records received so far, including dropped records.
+func (r *Receiver) Received() uint64 {
+	return atomic.LoadUint64(&r.received)
+}
+
+// Dropped returns the number of records dropped by relabel rules so far.
+func (r *Receiver) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// Serve accepts TCP connections on ln, reading newline delimited records from
+// each until the connection is closed. Serve blocks until ln fails or `Close` is
+// called, and always returns a non-nil error; after `Close` the error is
+// `net.ErrClosed`. Use a listener from `tls.NewListener` to accept TLS connections.
+func (r *Receiver) Serve(ln net.Listener) error {
+	r.mux.Lock()
+	if r.closed {
+		r.mux.Unlock()
+		return net.ErrClosed
+	}
+	r.lns[ln] = struct{}{}
+	r.mux.Unlock()
+
+	defer func() {
+		r.mux.Lock()
+		delete(r.lns, ln)
+		r.mux.Unlock()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			r.mux.Lock()
+			closed := r.closed
+			r.mux.Unlock()
+			if closed {
+				return net.ErrClosed
+			}
+			return err
+		}
+
+		r.mux.Lock()
+		if r.closed {
+			r.mux.Unlock()
+			conn.Close()
+			return net.ErrClosed
+		}
+		r.conns[conn] = struct{}{}
+		r.connsWG.Add(1)
+		r.mux.Unlock()
+
+		go r.handleConn(conn)
+	}
+}
+
+func (r *Receiver) handleConn(conn net.Conn) {
+	defer r.connsWG.Done()
+	defer func() {
+		r.mux.Lock()
+		delete(r.conns, conn)
+		r.mux.Unlock()
+		conn.Close()
+	}()
+
+	source := conn.RemoteAddr().String()
+	err := r.read(conn, source)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		r.logger.Logr().ReportError(fmt.Errorf("receiver connection from %s failed: %w", source, err))
+	}
+}
+
+// read injects all records read from rd, skipping records that cannot be parsed.
+func (r *Receiver) read(rd io.Reader, source string) error {
+	rdr := replay.NewReader(rd, r.opts.Replay)
+	for {
+		rec, err := rdr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var perr *replay.ParseError
+			if errors.As(err, &perr) {
+				r.logger.Logr().ReportError(fmt.Errorf("receiver skipped record from %s: %w", source, err))
+				continue
+			}
+			return err
+		}
+		r.Inject(rec, source)
+	}
+}
+
+// ServeHTTP accepts records POSTed as NDJSON or logfmt, or in a content type
+// registered via `Options.Decoders`. Bodies compressed with gzip are accepted
+// when the Content-Encoding header is set. Responds with 204 No Content once all
+// records are injected.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, req.Body, r.opts.MaxBodyBytes)
+	switch strings.ToLower(req.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = io.LimitReader(zr, r.opts.MaxBodyBytes)
+	default:
+		http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	source := req.RemoteAddr
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if decode, ok := r.opts.Decoders[contentType]; ok {
+		recs, err := decode(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rec := range recs {
+			r.Inject(rec, source)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch contentType {
+	case "", "application/x-ndjson", "application/json", "text/plain":
+	default:
+		http.Error(w, "unsupported content type "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	if err := r.read(body, source); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Close stops all listeners passed to `Serve` and closes open connections,
+// waiting until records already read from them are injected.
+func (r *Receiver) Close() error {
+	errs := merror.New()
+
+	r.mux.Lock()
+	r.closed = true
+	for ln := range r.lns {
+		if err := ln.Close(); err != nil {
+			errs.Append(err)
+		}
+	}
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mux.Unlock()
+
+	r.connsWG.Wait()
+	return errs.ErrorOrNil()
+}
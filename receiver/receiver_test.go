@@ -0,0 +1,117 @@
+package receiver_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/receiver"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHub(t *testing.T, opts ...logr.Option) (*logr.Logr, *test.Buffer) {
+	hub, err := logr.New(opts...)
+	require.NoError(t, err)
+	buf := &test.Buffer{}
+	err = hub.AddTarget(targets.NewWriterTarget(buf), "hub", &logr.StdFilter{Lvl: logr.Debug},
+		&formatters.Plain{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+	return hub, buf
+}
+
+func TestReceiverTCP(t *testing.T) {
+	hub, buf := newHub(t)
+	rcv, err := receiver.New(hub.NewLogger(), receiver.Options{})
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- rcv.Serve(ln) }()
+
+	// a spoke forwards records as NDJSON via the TCP target.
+	spoke, err := logr.New()
+	require.NoError(t, err)
+	addr := ln.Addr().(*net.TCPAddr)
+	err = spoke.AddTarget(targets.NewTcpTarget(&targets.TcpOptions{Host: "127.0.0.1", Port: addr.Port}), "forward",
+		&logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{}, 1000)
+	require.NoError(t, err)
+
+	logger := spoke.NewLogger().With(logr.String("host", "spoke1"))
+	logger.Info("user login", logr.Int("user_id", 42))
+	logger.Error("disk full", logr.Float64("pct", 99.5))
+	require.NoError(t, spoke.Shutdown())
+
+	require.Eventually(t, func() bool { return rcv.Received() == 2 }, time.Second*5, time.Millisecond*10)
+	require.NoError(t, rcv.Close())
+	assert.True(t, errors.Is(<-served, net.ErrClosed))
+	require.NoError(t, hub.Shutdown())
+
+	assert.Equal(t, "info user login host=spoke1 user_id=42\nerror disk full host=spoke1 pct=99.5\n", buf.String())
+}
+
+func TestReceiverHTTP(t *testing.T) {
+	var mux sync.Mutex
+	var errs []error
+	hub, buf := newHub(t, logr.OnLoggerError(func(err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		errs = append(errs, err)
+	}))
+	rcv, err := receiver.New(hub.NewLogger(), receiver.Options{})
+	require.NoError(t, err)
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	ndjson := `{"timestamp":"2021-03-04 10:59:00.000 Z","level":"warn","msg":"slow query","ms":1200}
+not a record
+{"timestamp":"2021-03-04 10:59:01.000 Z","level":"debug","msg":"retrying"}
+`
+	resp, err := http.Post(server.URL, "application/x-ndjson", strings.NewReader(ndjson))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var zbuf bytes.Buffer
+	zw := gzip.NewWriter(&zbuf)
+	_, _ = zw.Write([]byte(`{"level":"info","msg":"compressed"}` + "\n"))
+	require.NoError(t, zw.Close())
+	req, err := http.NewRequest(http.MethodPost, server.URL, &zbuf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Post(server.URL, "application/xml", strings.NewReader("<log/>"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	require.NoError(t, hub.Shutdown())
+	assert.Equal(t, uint64(3), rcv.Received())
+	assert.Equal(t, "warn slow query ms=1200\ndebug retrying \ninfo compressed \n", buf.String())
+
+	mux.Lock()
+	defer mux.Unlock()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "line 2")
+}
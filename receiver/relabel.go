@@ -0,0 +1,223 @@
+package receiver
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/replay"
+)
+
+// Pseudo-labels available to relabeling rules in addition to the record's fields.
+// Labels starting with "__" are not output as fields.
+const (
+	// LabelSource is the address of the sender, when known.
+	LabelSource = "__source__"
+	// LabelLevel is the level name. Setting it remaps the record's level.
+	LabelLevel = "__level__"
+	// LabelMsg is the message. Setting it rewrites the record's message.
+	LabelMsg = "__msg__"
+)
+
+// RelabelAction is the action performed by a `RelabelRule`.
+type RelabelAction string
+
+const (
+	// RelabelReplace sets TargetLabel to Replacement, with regex capture groups
+	// expanded, if Regex matches the joined source label values. An empty result
+	// removes the label.
+	RelabelReplace RelabelAction = "replace"
+	// RelabelKeep drops records for which Regex does not match the joined source label values.
+	RelabelKeep RelabelAction = "keep"
+	// RelabelDrop drops records for which Regex matches the joined source label values.
+	RelabelDrop RelabelAction = "drop"
+	// RelabelLabelDrop removes the labels whose names match Regex.
+	RelabelLabelDrop RelabelAction = "labeldrop"
+	// RelabelLabelKeep removes the labels whose names do not match Regex, except
+	// the pseudo-labels.
+	RelabelLabelKeep RelabelAction = "labelkeep"
+	// RelabelLabelMap copies the value of each label whose name matches Regex to the
+	// label named by Replacement, with regex capture groups expanded.
+	RelabelLabelMap RelabelAction = "labelmap"
+)
+
+// RelabelRule is a Prometheus-style relabeling rule applied to received records.
+// Field values are treated as strings; fields that are not changed keep their type.
+type RelabelRule struct {
+	// SourceLabels are the labels whose values are joined with Separator and matched
+	// against Regex.
+	SourceLabels []string `json:"source_labels,omitempty"`
+
+	// Separator joins the source label values. Defaults to ";".
+	Separator string `json:"separator,omitempty"`
+
+	// Regex is matched against the joined values, or against label names for the
+	// label actions. It is anchored at both ends. Defaults to "(.*)".
+	Regex string `json:"regex,omitempty"`
+
+	// TargetLabel is the label set by `RelabelReplace`.
+	TargetLabel string `json:"target_label,omitempty"`
+
+	// Replacement is the value, or for `RelabelLabelMap` the label name, to set.
+	// Defaults to "$1". Use `RelabelLabelDrop` to remove labels.
+	Replacement string `json:"replacement,omitempty"`
+
+	// Action defaults to `RelabelReplace`.
+	Action RelabelAction `json:"action,omitempty"`
+}
+
+type relabelRule struct {
+	RelabelRule
+	regex *regexp.Regexp
+}
+
+func compileRelabelRules(rules []RelabelRule) ([]relabelRule, error) {
+	out := make([]relabelRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Separator == "" {
+			rule.Separator = ";"
+		}
+		if rule.Regex == "" {
+			rule.Regex = "(.*)"
+		}
+		if rule.Action == "" {
+			rule.Action = RelabelReplace
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = "$1"
+		}
+
+		re, err := regexp.Compile("^(?:" + rule.Regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel rule %d: invalid regex: %w", i, err)
+		}
+
+		switch rule.Action {
+		case RelabelReplace:
+			if rule.TargetLabel == "" {
+				return nil, fmt.Errorf("relabel rule %d: missing target_label", i)
+			}
+		case RelabelKeep, RelabelDrop:
+			if len(rule.SourceLabels) == 0 {
+				return nil, fmt.Errorf("relabel rule %d: missing source_labels", i)
+			}
+		case RelabelLabelDrop, RelabelLabelKeep, RelabelLabelMap:
+		default:
+			return nil, fmt.Errorf("relabel rule %d: unknown action %q", i, rule.Action)
+		}
+		out = append(out, relabelRule{RelabelRule: rule, regex: re})
+	}
+	return out, nil
+}
+
+// level returns the level matching a level name, via `Replay.Levels` when set,
+// otherwise via `Replay.LevelMapper`.
+func (r *Receiver) level(name string) (logr.Level, bool) {
+	if len(r.levels) == 0 {
+		return r.opts.Replay.LevelMapper.Name(name)
+	}
+	lvl, ok := r.levels[strings.ToLower(name)]
+	return lvl, ok
+}
+
+// relabel applies the rules to a record, returning false if it should be dropped.
+func (r *Receiver) relabel(rec *replay.Record, source string) bool {
+	labels := make(map[string]string, len(rec.Fields)+3)
+	original := make(map[string]string, len(rec.Fields))
+	for _, f := range rec.Fields {
+		var sb strings.Builder
+		_ = f.ValueString(&sb, nil)
+		labels[f.Key] = sb.String()
+		original[f.Key] = labels[f.Key]
+	}
+	labels[LabelSource] = source
+	labels[LabelLevel] = rec.Level.Name
+	labels[LabelMsg] = rec.Msg
+
+	for _, rule := range r.rules {
+		if !rule.apply(labels) {
+			return false
+		}
+	}
+
+	if name, ok := labels[LabelLevel]; ok && name != rec.Level.Name {
+		if lvl, ok := r.level(name); ok {
+			rec.Level = lvl
+		} else {
+			r.logger.Logr().ReportError(fmt.Errorf("relabel: unknown level %q", name))
+		}
+	}
+	rec.Msg = labels[LabelMsg]
+
+	fields := make([]logr.Field, 0, len(labels))
+	for _, f := range rec.Fields {
+		val, ok := labels[f.Key]
+		switch {
+		case !ok:
+			continue
+		case val == original[f.Key]:
+			fields = append(fields, f)
+		default:
+			fields = append(fields, logr.String(f.Key, val))
+		}
+	}
+	var added []logr.Field
+	for key, val := range labels {
+		if _, ok := original[key]; ok || strings.HasPrefix(key, "__") {
+			continue
+		}
+		added = append(added, logr.String(key, val))
+	}
+	sort.Sort(logr.FieldSorter(added))
+	rec.Fields = append(fields, added...)
+	return true
+}
+
+// apply applies the rule to labels, returning false if the record should be dropped.
+func (rule relabelRule) apply(labels map[string]string) bool {
+	values := make([]string, 0, len(rule.SourceLabels))
+	for _, l := range rule.SourceLabels {
+		values = append(values, labels[l])
+	}
+	joined := strings.Join(values, rule.Separator)
+
+	switch rule.Action {
+	case RelabelKeep:
+		return rule.regex.MatchString(joined)
+	case RelabelDrop:
+		return !rule.regex.MatchString(joined)
+	case RelabelReplace:
+		m := rule.regex.FindStringSubmatchIndex(joined)
+		if m == nil {
+			return true
+		}
+		val := string(rule.regex.ExpandString(nil, rule.Replacement, joined, m))
+		if val == "" {
+			delete(labels, rule.TargetLabel)
+		} else {
+			labels[rule.TargetLabel] = val
+		}
+	case RelabelLabelDrop, RelabelLabelKeep:
+		for name := range labels {
+			if strings.HasPrefix(name, "__") {
+				continue
+			}
+			if rule.regex.MatchString(name) == (rule.Action == RelabelLabelDrop) {
+				delete(labels, name)
+			}
+		}
+	case RelabelLabelMap:
+		mapped := make(map[string]string)
+		for name, val := range labels {
+			if m := rule.regex.FindStringSubmatchIndex(name); m != nil {
+				mapped[string(rule.regex.ExpandString(nil, rule.Replacement, name, m))] = val
+			}
+		}
+		for name, val := range mapped {
+			labels[name] = val
+		}
+	}
+	return true
+}
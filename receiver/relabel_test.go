@@ -0,0 +1,45 @@
+package receiver_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/receiver"
+	"github.com/mattermost/logr/v2/replay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelabel(t *testing.T) {
+	hub, buf := newHub(t)
+	rcv, err := receiver.New(hub.NewLogger(), receiver.Options{Relabel: []receiver.RelabelRule{
+		{SourceLabels: []string{receiver.LabelSource}, Regex: `(.*):\d+`, TargetLabel: "source_host"},
+		{SourceLabels: []string{receiver.LabelMsg}, Regex: "healthcheck.*", Action: receiver.RelabelDrop},
+		{SourceLabels: []string{receiver.LabelLevel}, Regex: "warning", TargetLabel: receiver.LabelLevel, Replacement: "warn"},
+		{Regex: "password|token", Action: receiver.RelabelLabelDrop},
+		{SourceLabels: []string{"user_id"}, Regex: `(\d+)`, TargetLabel: "user_id", Replacement: "user-$1"},
+		{Regex: "k8s_(.*)", Replacement: "$1", Action: receiver.RelabelLabelMap},
+	}})
+	require.NoError(t, err)
+
+	record := func(lvl logr.Level, msg string, fields ...logr.Field) *replay.Record {
+		return &replay.Record{Time: time.Now(), Level: lvl, Msg: msg, Fields: fields}
+	}
+	rcv.Inject(record(logr.Level{ID: logr.Info.ID, Name: "warning"}, "login",
+		logr.Int("user_id", 42), logr.String("password", "hunter2"), logr.String("k8s_pod", "web-1"),
+		logr.Int("attempt", 2)), "10.0.0.5:4312")
+	rcv.Inject(record(logr.Info, "healthcheck ok"), "10.0.0.5:4312")
+	rcv.Inject(record(logr.Error, "no port", logr.String("user_id", "anon")), "local")
+	require.NoError(t, hub.Shutdown())
+
+	assert.Equal(t, uint64(3), rcv.Received())
+	assert.Equal(t, uint64(1), rcv.Dropped())
+	assert.Equal(t, "warn login user_id=user-42 k8s_pod=web-1 attempt=2 pod=web-1 source_host=10.0.0.5\n"+
+		"error no port user_id=anon\n", buf.String())
+
+	_, err = receiver.New(hub.NewLogger(), receiver.Options{Relabel: []receiver.RelabelRule{{Action: receiver.RelabelKeep}}})
+	assert.Error(t, err)
+	_, err = receiver.New(hub.NewLogger(), receiver.Options{Relabel: []receiver.RelabelRule{{Regex: "(", TargetLabel: "x"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package logr
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+const (
+	// recSizeOverhead approximates the time stamp, level and punctuation of a
+	// formatted record.
+	recSizeOverhead = 48
+
+	// fieldSizeOverhead approximates the separators and quotes of a formatted field.
+	fieldSizeOverhead = 4
+
+	// opaqueFieldSize approximates the formatted size of field values that cannot be
+	// sized without formatting them, such as errors and structs.
+	opaqueFieldSize = 64
+)
+
+// FormattedBytesCollector is optionally implemented by a `MetricsCollector` to count
+// the actual size of the records written by each target once formatted, which can
+// be compared with `LogRec.EstimatedSize`.
+type FormattedBytesCollector interface {
+	// FormattedBytesCounter returns a Counter that will be incremented by the named
+	// target by the formatted size of each record written.
+	FormattedBytesCounter(target string) (Counter, error)
+}
+
+// EstimatedSize returns the approximate size in bytes of the record once formatted,
+// from its message, fields and stack trace, without formatting it. The estimate is
+// computed once and cached. It is the size model used by `MaxQueueBytes`, and is
+// summed per target in `TargetStats.EstimatedBytes` alongside the actual formatted
+// size in `TargetStats.FormattedBytes`. Records not yet prepped for output are
+// estimated without the logger's fields or stack trace.
+func (rec *LogRec) EstimatedSize() int {
+	return int(rec.estimatedSize())
+}
+
+func (rec *LogRec) estimatedSize() int64 {
+	if size := atomic.LoadInt64(&rec.size); size > 0 {
+		return size
+	}
+
+	rec.mux.RLock()
+	defer rec.mux.RUnlock()
+
+	fields := rec.fields
+	if rec.prepped {
+		fields = rec.fieldsAll
+	}
+	size := int64(recSizeOverhead + len(rec.msg) + len(rec.caller))
+	for _, f := range fields {
+		size += fieldSizeOverhead + int64(len(f.Key)) + fieldValueSize(f)
+	}
+	for _, frame := range rec.frames {
+		size += int64(fieldSizeOverhead + len(frame.Function) + len(frame.File) + 6)
+	}
+	if rec.prepped {
+		atomic.StoreInt64(&rec.size, size)
+	}
+	return size
+}
+
+// fieldValueSize returns the approximate formatted size of a field's value.
+func fieldValueSize(f Field) int64 {
+	switch f.Type {
+	case StringType:
+		return int64(len(f.String))
+	case BoolType:
+		return 5
+	case Int64Type, Int32Type, IntType, Uint64Type, Uint32Type, UintType, TimestampMillisType:
+		return 20
+	case Float64Type, Float32Type, DurationType:
+		return 24
+	case TimeType:
+		return 35
+	case BinaryType:
+		if b, ok := f.Interface.([]byte); ok {
+			return int64(len(b) * 2)
+		}
+	case ArrayType, MapType:
+		if v := reflect.ValueOf(f.Interface); v.Kind() == reflect.Slice || v.Kind() == reflect.Array || v.Kind() == reflect.Map {
+			return int64(v.Len()) * (fieldSizeOverhead + opaqueFieldSize/4)
+		}
+	}
+	return opaqueFieldSize
+}
+
+// addFormattedBytes accounts for a record written with its formatted and estimated
+// sizes.
+func (h *TargetHost) addFormattedBytes(formatted int, estimate int64) {
+	atomic.AddUint64(&h.stats.formattedBytes, uint64(formatted))
+	atomic.AddUint64(&h.stats.estimatedBytes, uint64(estimate))
+	if h.formattedBytesCounter != nil {
+		h.formattedBytesCounter.Add(float64(formatted))
+	}
+}
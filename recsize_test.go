@@ -0,0 +1,50 @@
+package logr_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimatedSize(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+	logger := lgr.NewLogger().With(logr.String("request_id", "abc123"))
+
+	small := logr.NewLogRec(logr.Info, logger, "hello", nil, false)
+	big := logr.NewLogRec(logr.Info, logger, "hello", []logr.Field{logr.String("data", strings.Repeat("x", 1000))}, false)
+	assert.Greater(t, small.EstimatedSize(), len("hello"))
+	assert.Greater(t, big.EstimatedSize(), small.EstimatedSize()+1000)
+	assert.Equal(t, big.EstimatedSize(), big.EstimatedSize())
+}
+
+func TestFormattedBytes(t *testing.T) {
+	collector := test.NewTestMetricsCollector()
+	lgr, err := logr.New(logr.SetMetricsCollector(collector, 1000))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "sizeTest", &logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("request_id", "abc123"))
+	for i := 0; i < 10; i++ {
+		logger.Info("request handled", logr.Int("status", 200), logr.String("path", "/api/v4/users/me"))
+	}
+	logger.Error("request failed", logr.Err(assert.AnError))
+	require.NoError(t, lgr.Flush())
+
+	stats := lgr.StatsSnapshot().Targets[0]
+	assert.Equal(t, uint64(len(buf.String())), stats.FormattedBytes)
+	assert.Equal(t, float64(len(buf.String())), collector.Get("sizeTest").FormattedBytes)
+	// the estimate is approximate.
+	assert.InDelta(t, float64(stats.FormattedBytes), float64(stats.EstimatedBytes), float64(stats.FormattedBytes)/2)
+	require.NoError(t, lgr.Shutdown())
+}
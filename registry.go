@@ -0,0 +1,110 @@
+package logr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// TargetFactoryFunc creates a Target from JSON options.
+type TargetFactoryFunc func(options json.RawMessage) (Target, error)
+
+// FormatterFactoryFunc creates a Formatter from JSON options.
+type FormatterFactoryFunc func(options json.RawMessage) (Formatter, error)
+
+// FilterFactoryFunc creates a Filter from JSON options.
+type FilterFactoryFunc func(options json.RawMessage) (Filter, error)
+
+var (
+	registryMux        sync.RWMutex
+	targetFactories    = make(map[string]TargetFactoryFunc)
+	formatterFactories = make(map[string]FormatterFactoryFunc)
+	filterFactories    = make(map[string]FilterFactoryFunc)
+)
+
+// RegisterTargetFactory registers a factory that creates targets of the named type.
+// This allows plugins and config files to instantiate custom targets by name.
+// Names are case-insensitive. An error is returned if the name is already registered.
+func RegisterTargetFactory(name string, factory TargetFactoryFunc) error {
+	if factory == nil {
+		return errors.New("target factory cannot be nil")
+	}
+	return register(name, "target", func(key string) bool {
+		if _, ok := targetFactories[key]; ok {
+			return false
+		}
+		targetFactories[key] = factory
+		return true
+	})
+}
+
+// RegisterFormatterFactory registers a factory that creates formatters of the named type.
+// Names are case-insensitive. An error is returned if the name is already registered.
+func RegisterFormatterFactory(name string, factory FormatterFactoryFunc) error {
+	if factory == nil {
+		return errors.New("formatter factory cannot be nil")
+	}
+	return register(name, "formatter", func(key string) bool {
+		if _, ok := formatterFactories[key]; ok {
+			return false
+		}
+		formatterFactories[key] = factory
+		return true
+	})
+}
+
+// RegisterFilterFactory registers a factory that creates filters of the named type.
+// Names are case-insensitive. An error is returned if the name is already registered.
+func RegisterFilterFactory(name string, factory FilterFactoryFunc) error {
+	if factory == nil {
+		return errors.New("filter factory cannot be nil")
+	}
+	return register(name, "filter", func(key string) bool {
+		if _, ok := filterFactories[key]; ok {
+			return false
+		}
+		filterFactories[key] = factory
+		return true
+	})
+}
+
+func register(name string, kind string, add func(key string) bool) error {
+	key := strings.ToLower(name)
+	if key == "" {
+		return fmt.Errorf("%s factory name cannot be empty", kind)
+	}
+
+	registryMux.Lock()
+	defer registryMux.Unlock()
+
+	if !add(key) {
+		return fmt.Errorf("%s factory '%s' already registered", kind, name)
+	}
+	return nil
+}
+
+// GetTargetFactory returns the target factory registered for the named type.
+func GetTargetFactory(name string) (TargetFactoryFunc, bool) {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	f, ok := targetFactories[strings.ToLower(name)]
+	return f, ok
+}
+
+// GetFormatterFactory returns the formatter factory registered for the named type.
+func GetFormatterFactory(name string) (FormatterFactoryFunc, bool) {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	f, ok := formatterFactories[strings.ToLower(name)]
+	return f, ok
+}
+
+// GetFilterFactory returns the filter factory registered for the named type.
+func GetFilterFactory(name string) (FilterFactoryFunc, bool) {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	f, ok := filterFactories[strings.ToLower(name)]
+	return f, ok
+}
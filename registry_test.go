@@ -0,0 +1,56 @@
+package logr_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterTargetFactory(t *testing.T) {
+	factory := func(options json.RawMessage) (logr.Target, error) {
+		return test.NewFailingTarget(), nil
+	}
+
+	err := logr.RegisterTargetFactory("Registry_Test_Target", factory)
+	require.NoError(t, err)
+
+	err = logr.RegisterTargetFactory("registry_test_target", factory)
+	assert.Error(t, err, "duplicate names should error")
+
+	err = logr.RegisterTargetFactory("", factory)
+	assert.Error(t, err, "empty name should error")
+
+	err = logr.RegisterTargetFactory("registry_test_nil", nil)
+	assert.Error(t, err, "nil factory should error")
+
+	f, ok := logr.GetTargetFactory("REGISTRY_TEST_TARGET")
+	require.True(t, ok)
+	target, err := f(nil)
+	require.NoError(t, err)
+	assert.IsType(t, &test.FailingTarget{}, target)
+
+	_, ok = logr.GetTargetFactory("registry_test_missing")
+	assert.False(t, ok)
+}
+
+func TestRegisterFormatterAndFilterFactory(t *testing.T) {
+	err := logr.RegisterFormatterFactory("registry_test_formatter", func(options json.RawMessage) (logr.Formatter, error) {
+		return &logr.DefaultFormatter{}, nil
+	})
+	require.NoError(t, err)
+
+	err = logr.RegisterFilterFactory("registry_test_filter", func(options json.RawMessage) (logr.Filter, error) {
+		return &logr.StdFilter{Lvl: logr.Warn}, nil
+	})
+	require.NoError(t, err)
+
+	_, ok := logr.GetFormatterFactory("registry_test_formatter")
+	assert.True(t, ok)
+
+	_, ok = logr.GetFilterFactory("registry_test_filter")
+	assert.True(t, ok)
+}
@@ -0,0 +1,86 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// parseLogfmt parses a single logfmt line (key=value pairs separated by spaces,
+// values optionally double-quoted) into a map. Numbers and booleans are converted
+// so they can be treated the same as decoded JSON values.
+func parseLogfmt(line string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	i := 0
+	for i < len(line) {
+		// skip whitespace
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, errors.New("logfmt: missing key")
+		}
+
+		if i >= len(line) || line[i] == ' ' {
+			// key without value is a boolean flag.
+			m[key] = true
+			continue
+		}
+		i++ // skip '='
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, errors.New("logfmt: unterminated quoted value")
+			}
+			val, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		m[key] = logfmtValue(line[start:i])
+	}
+
+	if len(m) == 0 {
+		return nil, errors.New("logfmt: no fields")
+	}
+	return m, nil
+}
+
+func logfmtValue(s string) interface{} {
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return json.Number(s)
+	}
+	return s
+}
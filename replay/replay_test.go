@@ -0,0 +1,127 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	filter := &logr.StdFilter{Lvl: logr.Debug}
+	formatter := &formatters.JSON{EnableSchemaVersion: true, FieldSorter: sorter}
+
+	// produce an archive
+	archive := &test.Buffer{}
+	lgr, _ := logr.New()
+	err := lgr.AddTarget(targets.NewWriterTarget(archive), "archive", filter, formatter, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "wiggin"))
+	logger.Info("first", logr.Int("count", 1))
+	logger.Warn("second", logr.Bool("ok", true), logr.Float64("pi", 3.14))
+	logger.Debug("third", logr.Map("props", map[string]int{"a": 1}))
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	// replay it into a new sink
+	replayed := &test.Buffer{}
+	lgr2, _ := logr.New()
+	err = lgr2.AddTarget(targets.NewWriterTarget(replayed), "replayed", filter, formatter, 100)
+	require.NoError(t, err)
+
+	count, err := Replay(context.Background(), bytes.NewReader(archive.Bytes()), lgr2.NewLogger(), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	err = lgr2.Shutdown()
+	require.NoError(t, err)
+
+	assert.Equal(t, archive.String(), replayed.String())
+}
+
+func TestReader(t *testing.T) {
+	input := `{"level":"info","msg":"one","group":{"a":"b"}}
+
+not json
+{"level":"bogus","msg":"two"}
+{"level":"error","msg":"three"}
+`
+	rdr := NewReader(strings.NewReader(input), Options{KeyGroupFields: "group"})
+
+	rec, err := rdr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, logr.Info, rec.Level)
+	assert.Equal(t, "one", rec.Msg)
+	require.Len(t, rec.Fields, 1)
+	assert.Equal(t, "a", rec.Fields[0].Key)
+
+	var perr *ParseError
+	_, err = rdr.Next()
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, 3, perr.Line)
+
+	_, err = rdr.Next()
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, 4, perr.Line)
+
+	rec, err = rdr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "three", rec.Msg)
+
+	t.Run("logfmt", func(t *testing.T) {
+		rdr := NewReader(strings.NewReader(`level=warn msg="disk \"full\"" free=12 ok=false`+"\n"), Options{})
+		rec, err := rdr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, logr.Warn, rec.Level)
+		assert.Equal(t, `disk "full"`, rec.Msg)
+		require.Len(t, rec.Fields, 2)
+		assert.Equal(t, logr.Field{Key: "free", Type: logr.Int64Type, Integer: 12}, rec.Fields[0])
+		assert.Equal(t, logr.Bool("ok", false), rec.Fields[1])
+	})
+
+	t.Run("default level", func(t *testing.T) {
+		def := logr.Info
+		rdr := NewReader(strings.NewReader(`{"level":"audit","msg":"custom"}`), Options{DefaultLevel: &def})
+		rec, err := rdr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, logr.Info.ID, rec.Level.ID)
+		assert.Equal(t, "audit", rec.Level.Name)
+	})
+
+	t.Run("level mapper", func(t *testing.T) {
+		audit := logr.Level{ID: 100, Name: "audit"}
+		mapper := logr.NewLevelMapper(logr.LevelMapping{Names: map[string]logr.Level{"AUDIT": audit}})
+		rdr := NewReader(strings.NewReader(`{"level":"WARNING","msg":"a"}`+"\n"+`{"level":"audit","msg":"b"}`), Options{LevelMapper: mapper})
+		rec, err := rdr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, logr.Warn, rec.Level)
+		rec, err = rdr.Next()
+		require.NoError(t, err)
+		assert.Equal(t, audit, rec.Level)
+	})
+
+	t.Run("skip invalid", func(t *testing.T) {
+		lgr, _ := logr.New(logr.OnLoggerError(func(error) {}))
+		count, err := Replay(context.Background(), strings.NewReader(input), lgr.NewLogger(), Options{SkipInvalid: true})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		require.NoError(t, lgr.Shutdown())
+	})
+}
+
+func sorter(fields []logr.Field) []logr.Field {
+	cf := make([]logr.Field, len(fields))
+	copy(cf, fields)
+	sort.Sort(logr.FieldSorter(cf))
+	return cf
+}
@@ -0,0 +1,75 @@
+package logr
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// samplerBuckets is the number of counters used by the sampler. Records whose
+// level and message hash to the same counter are sampled together.
+const samplerBuckets = 4096
+
+// sampler limits the rate of log records with the same level and message.
+// See `Sampling` option.
+type sampler struct {
+	tick       int64 // nanoseconds
+	first      uint64
+	thereafter uint64
+	counters   [samplerBuckets]samplerCounter
+}
+
+type samplerCounter struct {
+	resetAt int64 // unix nanoseconds
+	count   uint64
+}
+
+func newSampler(tick time.Duration, first int, thereafter int) *sampler {
+	return &sampler{tick: tick.Nanoseconds(), first: uint64(first), thereafter: uint64(thereafter)}
+}
+
+// allow returns true if a record with the level and message should be logged.
+func (s *sampler) allow(lvl Level, msg string, now time.Time) bool {
+	if lvl.ID <= Error.ID {
+		return true
+	}
+	c := &s.counters[samplerHash(lvl, msg)%samplerBuckets]
+	n := c.inc(now.UnixNano(), s.tick)
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// inc increments the count, resetting it first if the tick has elapsed.
+func (c *samplerCounter) inc(now int64, tick int64) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	atomic.StoreUint64(&c.count, 1)
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+tick) {
+		// another goroutine reset the counter.
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
+
+// samplerHash returns the FNV-1a hash of the level id and message.
+func samplerHash(lvl Level, msg string) uint32 {
+	const prime = 16777619
+	h := uint32(2166136261)
+	h = (h ^ uint32(lvl.ID)) * prime
+	for i := 0; i < len(msg); i++ {
+		h = (h ^ uint32(msg[i])) * prime
+	}
+	return h
+}
+
+// isSampledOut returns true if a log record should be dropped by the sampler.
+func (lgr *Logr) isSampledOut(lvl Level, msg string) bool {
+	if lgr.sampler == nil || lgr.sampler.allow(lvl, msg, time.Now()) {
+		return false
+	}
+	atomic.AddUint64(&lgr.stats.sampledOut, 1)
+	return true
+}
@@ -0,0 +1,57 @@
+package logr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler(t *testing.T) {
+	s := newSampler(time.Second, 2, 3)
+	now := time.Now()
+
+	var allowed []int
+	for i := 1; i <= 10; i++ {
+		if s.allow(Info, "repeated", now) {
+			allowed = append(allowed, i)
+		}
+	}
+	// first 2, then every 3rd.
+	assert.Equal(t, []int{1, 2, 5, 8}, allowed)
+
+	// other messages and levels are counted separately.
+	assert.True(t, s.allow(Info, "other", now))
+	assert.True(t, s.allow(Debug, "repeated", now))
+
+	// errors are never sampled.
+	for i := 0; i < 10; i++ {
+		assert.True(t, s.allow(Error, "repeated", now))
+	}
+
+	// counts reset after the tick.
+	assert.True(t, s.allow(Info, "repeated", now.Add(time.Second)))
+}
+
+func TestSamplingOption(t *testing.T) {
+	lgr, err := New(Sampling(time.Minute, 1, 0))
+	require.NoError(t, err)
+	defer lgr.Shutdown()
+	require.NoError(t, lgr.AddTarget(&discardTarget{}, "discard", &StdFilter{Lvl: Info}, &DefaultFormatter{}, 100))
+
+	logger := lgr.NewLogger()
+	for i := 0; i < 5; i++ {
+		logger.Info("storm")
+	}
+	assert.Equal(t, uint64(4), lgr.StatsSnapshot().SampledOut)
+
+	_, err = New(Sampling(0, 1, 1))
+	assert.Error(t, err)
+}
+
+type discardTarget struct{}
+
+func (dt *discardTarget) Init() error                              { return nil }
+func (dt *discardTarget) Write(p []byte, rec *LogRec) (int, error) { return len(p), nil }
+func (dt *discardTarget) Shutdown() error                          { return nil }
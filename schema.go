@@ -0,0 +1,231 @@
+package logr
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultSchemaViolationKey is the key of the field describing schema violations
+// added to log records by the `SchemaFlag` policy.
+const DefaultSchemaViolationKey = "schema_violation"
+
+// SchemaType is the expected type of a field declared via `FieldSchema`.
+type SchemaType string
+
+const (
+	SchemaString   SchemaType = "string"
+	SchemaInt      SchemaType = "int"   // any signed or unsigned integer
+	SchemaFloat    SchemaType = "float" // any float or integer
+	SchemaBool     SchemaType = "bool"
+	SchemaTime     SchemaType = "time" // `Time` or `Millis`
+	SchemaDuration SchemaType = "duration"
+	SchemaError    SchemaType = "error"
+	SchemaObject   SchemaType = "object" // struct, map or stringer
+	SchemaArray    SchemaType = "array"
+	SchemaAny      SchemaType = "any"
+)
+
+// SchemaPolicy determines what happens to log records that violate a `FieldSchema`.
+type SchemaPolicy int
+
+const (
+	// SchemaFlag adds a string field with key `DefaultSchemaViolationKey` describing
+	// the violations.
+	SchemaFlag SchemaPolicy = iota
+	// SchemaCount only counts violations.
+	SchemaCount
+	// SchemaReject drops log records with violations.
+	SchemaReject
+)
+
+// SchemaCollector is optionally implemented by a `MetricsCollector` to count
+// log records violating the schema declared via `FieldSchema`.
+type SchemaCollector interface {
+	// SchemaViolationCounter returns a Counter that will be incremented for each log
+	// record in which the field with the specified key violates the schema.
+	// Undeclared fields in strict mode are counted under the empty key.
+	SchemaViolationCounter(key string) (Counter, error)
+}
+
+// FieldSchema declares the expected type of fields by key, so that the output of
+// many teams sharing one pipeline stays consistent. A log record violates the schema
+// when a declared field has a value of another type, or, if strict is true, when it
+// contains a field that is not declared. Violating records are handled according to
+// policy, counted in `Logr.StatsSnapshot` and, when the metrics collector implements
+// `SchemaCollector`, counted per field key.
+func FieldSchema(fields map[string]SchemaType, policy SchemaPolicy, strict bool) Option {
+	return func(l *Logr) error {
+		if len(fields) == 0 {
+			return errors.New("field schema cannot be empty")
+		}
+		if policy < SchemaFlag || policy > SchemaReject {
+			return fmt.Errorf("invalid schema policy %d", policy)
+		}
+		s := &schemaOptions{fields: make(map[string]SchemaType, len(fields)), policy: policy, strict: strict}
+		for key, typ := range fields {
+			if !typ.isValid() {
+				return fmt.Errorf("invalid schema type %q for field %s", typ, key)
+			}
+			s.fields[key] = typ
+		}
+		l.options.schema = s
+		return nil
+	}
+}
+
+func (t SchemaType) isValid() bool {
+	switch t {
+	case SchemaString, SchemaInt, SchemaFloat, SchemaBool, SchemaTime, SchemaDuration,
+		SchemaError, SchemaObject, SchemaArray, SchemaAny:
+		return true
+	}
+	return false
+}
+
+// matches returns true if a field of type ft is a valid value of the schema type.
+func (t SchemaType) matches(ft FieldType) bool {
+	switch t {
+	case SchemaAny:
+		return true
+	case SchemaString:
+		return ft == StringType
+	case SchemaInt:
+		return isIntegerType(ft)
+	case SchemaFloat:
+		return ft == Float64Type || ft == Float32Type || isIntegerType(ft)
+	case SchemaBool:
+		return ft == BoolType
+	case SchemaTime:
+		return ft == TimeType || ft == TimestampMillisType
+	case SchemaDuration:
+		return ft == DurationType
+	case SchemaError:
+		return ft == ErrorType
+	case SchemaObject:
+		return ft == StructType || ft == MapType || ft == StringerType
+	case SchemaArray:
+		return ft == ArrayType
+	}
+	return false
+}
+
+func isIntegerType(ft FieldType) bool {
+	switch ft {
+	case Int64Type, Int32Type, IntType, Uint64Type, Uint32Type, UintType:
+		return true
+	}
+	return false
+}
+
+// schemaTypeOf returns the schema type name of a field type, for violation messages.
+func schemaTypeOf(ft FieldType) SchemaType {
+	for _, t := range []SchemaType{SchemaString, SchemaInt, SchemaFloat, SchemaBool, SchemaTime,
+		SchemaDuration, SchemaError, SchemaObject, SchemaArray} {
+		if t.matches(ft) {
+			return t
+		}
+	}
+	return "unknown"
+}
+
+type schemaOptions struct {
+	fields map[string]SchemaType
+	policy SchemaPolicy
+	strict bool
+}
+
+// fieldSchema applies `FieldSchema`. check is only called by the Logr read loop;
+// the mutex guards the metrics counters.
+type fieldSchema struct {
+	schemaOptions
+
+	mux       sync.Mutex
+	collector SchemaCollector
+	counters  map[string]Counter
+}
+
+func newFieldSchema(opts schemaOptions) *fieldSchema {
+	return &fieldSchema{schemaOptions: opts, counters: make(map[string]Counter)}
+}
+
+// setCollector replaces the metrics collector, discarding counters from any
+// previous collector.
+func (fs *fieldSchema) setCollector(collector MetricsCollector) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	fs.collector, _ = collector.(SchemaCollector)
+	fs.counters = make(map[string]Counter)
+}
+
+func (fs *fieldSchema) incViolation(key string) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	if fs.collector == nil {
+		return
+	}
+	c, ok := fs.counters[key]
+	if !ok {
+		c, _ = fs.collector.SchemaViolationCounter(key)
+		fs.counters[key] = c
+	}
+	if c != nil {
+		c.Inc()
+	}
+}
+
+// violations returns a description of each schema violation in a prepped record,
+// sorted by key.
+func (fs *fieldSchema) violations(rec *LogRec) []string {
+	var out []string
+	for _, f := range rec.fieldsAll {
+		typ, ok := fs.fields[f.Key]
+		if !ok {
+			if fs.strict && f.Key != DefaultSchemaViolationKey {
+				out = append(out, f.Key+": undeclared")
+				fs.incViolation("")
+			}
+			continue
+		}
+		if !typ.matches(f.Type) {
+			out = append(out, fmt.Sprintf("%s: %s expected, got %s", f.Key, typ, schemaTypeOf(f.Type)))
+			fs.incViolation(f.Key)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// applySchema applies the schema to a prepped record once, returning false if the
+// record should be dropped. Records written to synchronous targets are checked
+// before being queued, so the read loop reuses the result.
+func (lgr *Logr) applySchema(rec *LogRec) bool {
+	if !rec.schemaChecked {
+		rec.schemaChecked = true
+		rec.schemaOK = lgr.checkSchema(rec)
+	}
+	return rec.schemaOK
+}
+
+// checkSchema applies the schema to a prepped record, returning false if the
+// record should be dropped.
+func (lgr *Logr) checkSchema(rec *LogRec) bool {
+	violations := lgr.schema.violations(rec)
+	if len(violations) == 0 {
+		return true
+	}
+	atomic.AddUint64(&lgr.stats.schemaViolations, 1)
+
+	switch lgr.schema.policy {
+	case SchemaReject:
+		return false
+	case SchemaFlag:
+		fields := make([]Field, 0, len(rec.fieldsAll)+1)
+		fields = append(fields, rec.fieldsAll...)
+		rec.fieldsAll = append(fields, String(DefaultSchemaViolationKey, strings.Join(violations, "; ")))
+	}
+	return true
+}
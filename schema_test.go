@@ -0,0 +1,95 @@
+package logr_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldSchema(t *testing.T) {
+	schema := map[string]logr.SchemaType{
+		"user_id":  logr.SchemaInt,
+		"latency":  logr.SchemaFloat,
+		"route":    logr.SchemaString,
+		"error":    logr.SchemaError,
+		"metadata": logr.SchemaAny,
+	}
+
+	_, err := logr.New(logr.FieldSchema(map[string]logr.SchemaType{"x": "number"}, logr.SchemaFlag, false))
+	assert.Error(t, err)
+	_, err = logr.New(logr.FieldSchema(schema, logr.SchemaPolicy(7), false))
+	assert.Error(t, err)
+
+	logAll := func(t *testing.T, policy logr.SchemaPolicy, strict bool) (*logr.Logr, string, *test.TestMetricsCollector) {
+		collector := test.NewTestMetricsCollector()
+		lgr, err := logr.New(logr.FieldSchema(schema, policy, strict), logr.SetMetricsCollector(collector, 1000))
+		require.NoError(t, err)
+		buf := &test.Buffer{}
+		err = lgr.AddTarget(targets.NewWriterTarget(buf), "test", &logr.StdFilter{Lvl: logr.Info},
+			&formatters.Plain{DisableTimestamp: true}, 1000)
+		require.NoError(t, err)
+
+		logger := lgr.NewLogger()
+		logger.Info("valid", logr.Int("user_id", 1), logr.Int("latency", 3), logr.Err(errors.New("x")), logr.Any("metadata", []int{1}))
+		logger.Info("wrong type", logr.String("user_id", "bob"), logr.Bool("route", true))
+		logger.Info("undeclared", logr.String("route", "/"), logr.String("team", "payments"))
+		require.NoError(t, lgr.Shutdown())
+		return lgr, buf.String(), collector
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		lgr, out, collector := logAll(t, logr.SchemaFlag, false)
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 3)
+		assert.NotContains(t, lines[0], "schema_violation")
+		assert.Contains(t, lines[1], `schema_violation="route: string expected, got bool; user_id: int expected, got string"`)
+		assert.NotContains(t, lines[2], "schema_violation")
+		assert.Equal(t, uint64(1), lgr.StatsSnapshot().SchemaViolations)
+		assert.Equal(t, 1.0, collector.GetSchemaViolations("user_id"))
+		assert.Equal(t, 1.0, collector.GetSchemaViolations("route"))
+	})
+
+	t.Run("reject synchronous", func(t *testing.T) {
+		lgr, err := logr.New(logr.FieldSchema(schema, logr.SchemaReject, false))
+		require.NoError(t, err)
+		queued := &test.Buffer{}
+		err = lgr.AddTarget(targets.NewWriterTarget(queued), "queued", &logr.StdFilter{Lvl: logr.Info},
+			&formatters.Plain{DisableTimestamp: true}, 1000)
+		require.NoError(t, err)
+		sync := &test.Buffer{}
+		err = lgr.AddTarget(targets.NewWriterTarget(sync), "sync", &logr.StdFilter{Lvl: logr.Info},
+			&formatters.Plain{DisableTimestamp: true}, 1000, logr.Synchronous())
+		require.NoError(t, err)
+
+		logger := lgr.NewLogger()
+		logger.Info("valid", logr.Int("user_id", 1))
+		logger.Info("wrong type", logr.String("user_id", "bob"))
+		require.NoError(t, lgr.Shutdown())
+
+		for _, out := range []string{queued.String(), sync.String()} {
+			assert.Equal(t, "info valid user_id=1\n", out)
+		}
+		assert.Equal(t, uint64(1), lgr.StatsSnapshot().SchemaViolations)
+	})
+
+	t.Run("strict count", func(t *testing.T) {
+		lgr, out, collector := logAll(t, logr.SchemaCount, true)
+		assert.Equal(t, 3, strings.Count(out, "\n"))
+		assert.NotContains(t, out, "schema_violation")
+		assert.Equal(t, uint64(2), lgr.StatsSnapshot().SchemaViolations)
+		assert.Equal(t, 1.0, collector.GetSchemaViolations(""))
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		_, out, _ := logAll(t, logr.SchemaReject, true)
+		assert.Equal(t, "info valid", strings.SplitN(out, " user_id", 2)[0])
+		assert.Equal(t, 1, strings.Count(out, "\n"))
+	})
+}
@@ -0,0 +1,77 @@
+package logr
+
+import "sync"
+
+// shedLevels is the number of levels, starting with Trace and ending with Warn,
+// that can be shed by load shedding. Error and more severe are never shed.
+const shedLevels = 4
+
+// ShedCounterCollector is optionally implemented by a `MetricsCollector` to count
+// log records shed per level by targets under backpressure. See the `LoadShedding`
+// option. Shed records are also counted by the target's dropped counter.
+type ShedCounterCollector interface {
+	// ShedCounter returns a Counter that will be incremented by the named target
+	// each time a record with the named level is shed.
+	ShedCounter(target string, level string) (Counter, error)
+}
+
+type shedCounters struct {
+	mux       sync.Mutex
+	collector ShedCounterCollector
+	counters  map[string]Counter
+}
+
+// shouldShed returns true if a record with the specified level should be
+// dropped because the target queue is above the high-water mark. The further
+// the queue fills beyond the high-water mark the more severe the levels that are
+// shed: Trace first, then Debug, Info and finally Warn. Error and more severe
+// levels are always admitted. Custom levels with IDs greater than Trace are
+// treated as Trace.
+func (h *TargetHost) shouldShed(in chan *LogRec, lvl Level, highWater float64) bool {
+	if lvl.ID <= Error.ID {
+		return false
+	}
+
+	size := cap(in)
+	if size == 0 {
+		return false
+	}
+	fill := float64(len(in)) / float64(size)
+	if fill < highWater {
+		return false
+	}
+
+	n := 1
+	if highWater < 1 {
+		n += int((fill - highWater) / (1 - highWater) * shedLevels)
+	}
+	if n > shedLevels {
+		n = shedLevels
+	}
+	shedFrom := Trace.ID - LevelID(n-1)
+	return lvl.ID >= shedFrom
+}
+
+func (h *TargetHost) incShedCounter(lvl Level) {
+	h.incDroppedCounter()
+
+	sc := h.shedCounters
+	if sc == nil {
+		return
+	}
+
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	counter, ok := sc.counters[lvl.Name]
+	if !ok {
+		var err error
+		if counter, err = sc.collector.ShedCounter(h.name, lvl.Name); err != nil {
+			counter = nil
+		}
+		sc.counters[lvl.Name] = counter
+	}
+	if counter != nil {
+		counter.Inc()
+	}
+}
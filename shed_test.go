@@ -0,0 +1,65 @@
+package logr_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadShedding(t *testing.T) {
+	collector := test.NewTestMetricsCollector()
+	lgr, err := logr.New(logr.LoadShedding(0.5), logr.SetMetricsCollector(collector, 1000))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "shedTest", &logr.StdFilter{Lvl: logr.Trace},
+		&formatters.Plain{DisableTimestamp: true, DisableLevel: true}, 10)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Error("first")
+	<-target.Blocked()
+
+	for i := 0; i < 5; i++ {
+		logger.Info("fill")
+	}
+	// queue is 50% full; only Trace is shed.
+	logger.Trace("shed")
+	logger.Debug("debug1")
+	// 60% full
+	logger.Debug("debug2")
+	// 70% full; Debug is shed
+	logger.Debug("shed")
+	logger.Info("info1")
+	// 80% full; Info is shed
+	logger.Info("shed")
+	logger.Warn("warn1")
+	// 90% full; Warn is shed
+	logger.Warn("shed")
+	logger.Error("error1")
+
+	assert.Eventually(t, func() bool {
+		return collector.Get("shedTest").Dropped == 4
+	}, time.Second*5, time.Millisecond*10)
+	target.Unblock()
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.NotContains(t, output, "shed")
+	for _, s := range []string{"first", "debug1", "debug2", "info1", "warn1", "error1"} {
+		assert.Contains(t, output, s)
+	}
+	assert.Equal(t, 11, strings.Count(output, "\n"))
+
+	for _, lvl := range []logr.Level{logr.Trace, logr.Debug, logr.Info, logr.Warn} {
+		assert.Equal(t, 1.0, collector.GetShed("shedTest", lvl.Name), lvl.Name)
+	}
+}
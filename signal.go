@@ -0,0 +1,145 @@
+package logr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/wiggin77/merror"
+)
+
+// TargetReopener is implemented by targets that can reopen their destination,
+// typically a file that has been moved aside by an external log rotation tool.
+type TargetReopener interface {
+	Reopen() error
+}
+
+// Reopen calls `Reopen` for all targets implementing `TargetReopener`.
+func (lgr *Logr) Reopen() error {
+	errs := merror.New()
+
+	lgr.tmux.RLock()
+	defer lgr.tmux.RUnlock()
+
+	for _, host := range lgr.targetHosts {
+		if r, ok := host.target.(TargetReopener); ok {
+			if err := r.Reopen(); err != nil {
+				errs.Append(fmt.Errorf("cannot reopen target %s: %w", host.String(), err))
+			}
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// SignalOptions determine the behavior of `HandleSignals`.
+type SignalOptions struct {
+	// ShutdownTimeout is the maximum amount of time Shutdown can take when a terminate
+	// signal is received. Defaults to the `ShutdownTimeout` option of the Logr.
+	ShutdownTimeout time.Duration
+
+	// OnReload, when not nil, is called after all targets are reopened when a reload
+	// signal is received. This can be used to reload logging configuration.
+	OnReload func() error
+
+	// OnShutdown, when not nil, is called after the Logr is shut down when a terminate
+	// signal is received. Typically this is used to exit the application. If nil then the
+	// signal is re-raised with default handling, which normally terminates the process.
+	OnShutdown func(err error)
+}
+
+// HandleSignals installs signal handlers wired to this Logr:
+//
+//   - SIGUSR1 flushes all queued log records
+//   - SIGHUP reopens all targets implementing `TargetReopener` and calls `SignalOptions.OnReload`
+//   - SIGTERM shuts down the Logr with a deadline, then calls `SignalOptions.OnShutdown`
+//
+// On platforms without these signals only os.Interrupt is handled, causing shutdown.
+// Errors are reported via `ReportError`. Call the returned function to remove the handlers.
+func (lgr *Logr) HandleSignals(opts SignalOptions) (stop func()) {
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = lgr.options.shutdownTimeout
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	var sigs []os.Signal
+	sigs = append(sigs, flushSignals...)
+	sigs = append(sigs, reloadSignals...)
+	sigs = append(sigs, terminateSignals...)
+	signal.Notify(ch, sigs...)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-ch:
+				switch {
+				case containsSignal(flushSignals, sig):
+					if err := lgr.Flush(); err != nil {
+						lgr.ReportError(fmt.Errorf("flush on signal %v failed: %w", sig, err))
+					}
+				case containsSignal(reloadSignals, sig):
+					lgr.reload(sig, opts.OnReload)
+				case containsSignal(terminateSignals, sig):
+					lgr.shutdownOnSignal(sig, opts)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	}
+}
+
+func (lgr *Logr) reload(sig os.Signal, onReload func() error) {
+	if err := lgr.Reopen(); err != nil {
+		lgr.ReportError(fmt.Errorf("reopen on signal %v failed: %w", sig, err))
+	}
+	if onReload != nil {
+		if err := onReload(); err != nil {
+			lgr.ReportError(fmt.Errorf("reload on signal %v failed: %w", sig, err))
+		}
+	}
+}
+
+func (lgr *Logr) shutdownOnSignal(sig os.Signal, opts SignalOptions) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	err := lgr.ShutdownWithTimeout(ctx)
+
+	if opts.OnShutdown != nil {
+		opts.OnShutdown(err)
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	// re-raise the signal with default handling.
+	signal.Reset(sig)
+	if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+		return
+	}
+	os.Exit(1)
+}
+
+func containsSignal(sigs []os.Signal, sig os.Signal) bool {
+	for _, s := range sigs {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
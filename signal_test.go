@@ -0,0 +1,66 @@
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+package logr_test
+
+import (
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reopenTarget struct {
+	*targets.Writer
+	reopened int32
+}
+
+func (rt *reopenTarget) Reopen() error {
+	atomic.AddInt32(&rt.reopened, 1)
+	return nil
+}
+
+func TestHandleSignals(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	target := &reopenTarget{Writer: targets.NewWriterTarget(buf)}
+	err = lgr.AddTarget(target, "signalTest", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{}, 100)
+	require.NoError(t, err)
+
+	var reloaded int32
+	shutdown := make(chan error, 1)
+	stop := lgr.HandleSignals(logr.SignalOptions{
+		OnReload:   func() error { atomic.AddInt32(&reloaded, 1); return nil },
+		OnShutdown: func(err error) { shutdown <- err },
+	})
+	defer stop()
+
+	lgr.NewLogger().Info("flushed by signal")
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return buf.String() != ""
+	}, time.Second*5, time.Millisecond*10)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&target.reopened) == 1 && atomic.LoadInt32(&reloaded) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	select {
+	case err := <-shutdown:
+		assert.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		require.Fail(t, "timed out waiting for shutdown")
+	}
+	assert.True(t, lgr.IsShutdown())
+}
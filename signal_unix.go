@@ -0,0 +1,16 @@
+//go:build !windows && !nacl && !plan9
+// +build !windows,!nacl,!plan9
+
+package logr
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	flushSignals     = []os.Signal{syscall.SIGUSR1}
+	reloadSignals    = []os.Signal{syscall.SIGHUP}
+	terminateSignals = []os.Signal{syscall.SIGTERM}
+	crashSignals     = []os.Signal{syscall.SIGABRT, syscall.SIGQUIT}
+)
@@ -0,0 +1,13 @@
+//go:build windows || nacl || plan9
+// +build windows nacl plan9
+
+package logr
+
+import "os"
+
+var (
+	flushSignals     []os.Signal
+	reloadSignals    []os.Signal
+	terminateSignals = []os.Signal{os.Interrupt}
+	crashSignals     []os.Signal
+)
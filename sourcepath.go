@@ -0,0 +1,99 @@
+package logr
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+)
+
+type sourcePathOptions struct {
+	prefixes []string
+}
+
+// TrimSourcePaths trims the file paths in stack traces and the caller, keeping
+// output compact and free of build machine paths. With no prefixes, paths are made
+// relative to the root of the module or GOPATH containing them, as the package
+// import path followed by the file name, e.g. "targets/file.go" for a package in
+// the main module or "github.com/pkg/errors@v0.9.1/errors.go" for a dependency in
+// the module cache. Otherwise the first matching prefix is stripped from each path,
+// and paths matching no prefix are unchanged.
+//
+// The caller is output as the trimmed path and line instead of the package
+// directory, file name and line. `StableStacktraces` with StackModeRelative takes
+// precedence for stack traces.
+func TrimSourcePaths(prefixes ...string) Option {
+	return func(l *Logr) error {
+		for _, prefix := range prefixes {
+			if prefix == "" {
+				return fmt.Errorf("prefix cannot be empty")
+			}
+		}
+		l.options.sourcePaths = &sourcePathOptions{prefixes: prefixes}
+		return nil
+	}
+}
+
+// trimFrames trims the file path of each frame, in place.
+func (opts *sourcePathOptions) trimFrames(frames []runtime.Frame) {
+	for i := range frames {
+		frames[i].File = opts.trim(frames[i])
+	}
+}
+
+// trim returns the trimmed file path of a frame.
+func (opts *sourcePathOptions) trim(frame runtime.Frame) string {
+	if frame.File == "" {
+		return ""
+	}
+	if len(opts.prefixes) > 0 {
+		for _, prefix := range opts.prefixes {
+			if strings.HasPrefix(frame.File, prefix) {
+				return strings.TrimPrefix(frame.File[len(prefix):], "/")
+			}
+		}
+		return frame.File
+	}
+	return moduleRelativePath(ResolvePackageName(frame.Function), frame.File)
+}
+
+// moduleRelativePath returns the file path relative to the root of the module or
+// GOPATH containing it, found by matching the trailing directories of the file path
+// against the trailing elements of the package import path. Module cache
+// directories, such as "errors@v0.9.1", match the element without the version.
+// When nothing matches the file is in the module root, named independently of the
+// module path, so just the file name is returned. The import path of package main
+// is unknown, so its parent directory and file name are returned.
+func moduleRelativePath(pkg string, file string) string {
+	dir, name := path.Split(file)
+	dirs := strings.Split(strings.TrimSuffix(dir, "/"), "/")
+	elems := strings.Split(strings.TrimSuffix(pkg, "_test"), "/")
+
+	matched := 0
+	for matched < len(dirs) && matched < len(elems) {
+		d := dirs[len(dirs)-1-matched]
+		e := elems[len(elems)-1-matched]
+		if d != e && !strings.HasPrefix(d, e+"@") {
+			break
+		}
+		matched++
+	}
+	if pkg == "main" {
+		return trimPath(file)
+	}
+	if matched == 0 {
+		return name
+	}
+	return strings.Join(dirs[len(dirs)-matched:], "/") + "/" + name
+}
+
+// calcTrimmedCaller returns the caller as the trimmed path and line of the first
+// frame with a file.
+func calcTrimmedCaller(frames []runtime.Frame) string {
+	for _, frame := range frames {
+		if frame.File != "" {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+	}
+	return ""
+}
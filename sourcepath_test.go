@@ -0,0 +1,45 @@
+package logr
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleRelativePath(t *testing.T) {
+	tests := []struct {
+		pkg  string
+		file string
+		want string
+	}{
+		{"github.com/mattermost/logr/v2/targets", "/home/dev/src/logr/targets/file.go", "targets/file.go"},
+		{"github.com/mattermost/logr/v2", "/home/dev/src/logr/logr.go", "logr.go"},
+		{"github.com/mattermost/logr/v2_test", "/home/dev/src/logr/logr_test.go", "logr_test.go"},
+		{"github.com/pkg/errors", "/home/dev/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go", "github.com/pkg/errors@v0.9.1/errors.go"},
+		{"github.com/x/app/sub", "/home/dev/go/src/github.com/x/app/sub/sub.go", "github.com/x/app/sub/sub.go"},
+		{"net/http", "/usr/local/go/src/net/http/server.go", "net/http/server.go"},
+		{"main", "/home/dev/src/app/cmd/tool/main.go", "tool/main.go"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, moduleRelativePath(tt.pkg, tt.file), tt.file)
+	}
+}
+
+func TestTrimSourcePaths(t *testing.T) {
+	frames := []runtime.Frame{
+		{Function: "github.com/x/app/sub.Do", File: "/build/app/sub/sub.go", Line: 10},
+		{Function: "github.com/pkg/errors.New", File: "/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go", Line: 20},
+		{Function: "runtime.goexit"},
+	}
+
+	opts := &sourcePathOptions{prefixes: []string{"/go/pkg/mod/", "/build/app"}}
+	opts.trimFrames(frames)
+	assert.Equal(t, "sub/sub.go", frames[0].File)
+	assert.Equal(t, "github.com/pkg/errors@v0.9.1/errors.go", frames[1].File)
+	assert.Equal(t, "", frames[2].File)
+	assert.Equal(t, "sub/sub.go:10", calcTrimmedCaller(frames))
+
+	_, err := New(TrimSourcePaths(""))
+	assert.Error(t, err)
+}
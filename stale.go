@@ -0,0 +1,59 @@
+package logr
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// StaleCounterCollector is optionally implemented by a `MetricsCollector` to count
+// log records dropped by targets because they were older than the target's
+// `MaxRecordAge` when dequeued. Stale records are also counted by the target's
+// dropped counter.
+type StaleCounterCollector interface {
+	// StaleCounter returns a Counter that will be incremented by the named target
+	// each time a stale record is dropped.
+	StaleCounter(target string) (Counter, error)
+}
+
+type staleOptions struct {
+	maxAge time.Duration
+	level  Level
+}
+
+// MaxRecordAge drops records at the specified level, or less severe, that are older
+// than maxAge when dequeued by the target, instead of writing them. After a long
+// outage of the target's destination this keeps a recovering target from spending
+// minutes shipping a backlog of worthless records, e.g. MaxRecordAge(time.Minute, Debug).
+// Age is measured from the record's time stamp using the Logr's `Clock`, if any.
+// Stale records are counted in `TargetStats.Stale` and as dropped. Custom levels
+// are compared by ID, where lower IDs are more severe.
+func MaxRecordAge(maxAge time.Duration, level Level) TargetOption {
+	return func(opts *targetHostOptions) error {
+		if maxAge <= 0 {
+			return errors.New("max record age must be greater than zero")
+		}
+		opts.stale = &staleOptions{maxAge: maxAge, level: level}
+		return nil
+	}
+}
+
+// isStale returns true if a dequeued record should be dropped due to its age.
+func (h *TargetHost) isStale(rec *LogRec) bool {
+	if h.stale == nil || rec.Level().ID < h.stale.level.ID {
+		return false
+	}
+	now := time.Now
+	if clock := rec.logger.lgr.options.clock; clock != nil {
+		now = clock
+	}
+	return now().Sub(rec.Time()) > h.stale.maxAge
+}
+
+func (h *TargetHost) incStaleCounter() {
+	h.incDroppedCounter()
+	atomic.AddUint64(&h.stats.stale, 1)
+	if h.staleCounter != nil {
+		h.staleCounter.Inc()
+	}
+}
@@ -0,0 +1,62 @@
+package logr_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxRecordAge(t *testing.T) {
+	var mux sync.Mutex
+	now := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		now = now.Add(d)
+	}
+
+	collector := test.NewTestMetricsCollector()
+	lgr, err := logr.New(logr.SetMetricsCollector(collector, 1000), logr.Clock(func() time.Time {
+		mux.Lock()
+		defer mux.Unlock()
+		return now
+	}))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "recovering", &logr.StdFilter{Lvl: logr.Debug}, &formatters.Plain{DisableTimestamp: true}, 100,
+		logr.MaxRecordAge(time.Minute, logr.Debug))
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("outage")
+	<-target.Blocked()
+
+	logger.Debug("stale debug")
+	logger.Info("old info")
+	advance(time.Minute * 2)
+	logger.Debug("fresh debug")
+
+	target.Unblock()
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, "info outage \ninfo old info \ndebug fresh debug \n", buf.String())
+	stats := lgr.StatsSnapshot().Targets[0]
+	assert.Equal(t, uint64(1), stats.Stale)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, float64(1), collector.Get("recovering").Stale)
+
+	lgr2, err := logr.New()
+	require.NoError(t, err)
+	err = lgr2.AddTarget(test.NewBlockingTarget(buf), "invalid", &logr.StdFilter{Lvl: logr.Debug}, nil, 100,
+		logr.MaxRecordAge(0, logr.Debug))
+	assert.Error(t, err)
+	require.NoError(t, lgr2.Shutdown())
+}
@@ -0,0 +1,145 @@
+package logr
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statCounters are maintained regardless of whether a `MetricsCollector` is
+// configured. Always allocated separately to guarantee 64-bit alignment.
+type statCounters struct {
+	logged   uint64
+	errors   uint64
+	dropped  uint64
+	blocked  uint64
+	rerouted uint64 // records rerouted by `OnTargetQueueFullPolicy`
+	sampled  uint64 // records considered for pipeline tracing
+
+	flushQueuedAt    int64 // unix nanos the latest flush record was queued
+	flushQueuedAhead uint64
+	lastFlushNanos   int64
+	lastFlushRecords uint64
+	shutdownNanos    int64
+	abandoned        uint64 // records still queued when the target shut down
+	stale            uint64 // records dropped by `MaxRecordAge`
+
+	sampledOut       uint64 // records dropped by the sampler
+	overQuota        uint64 // records dropped by `VolumeQuota`
+	schemaViolations uint64 // records violating `FieldSchema`
+	queuedBytes      int64  // estimated size of queued records; see `MaxQueueBytes`
+	formattedBytes   uint64 // formatted size of records written
+	estimatedBytes   uint64 // estimated size of records written; see `LogRec.EstimatedSize`
+}
+
+// Stats is a point in time snapshot of Logr pipeline statistics.
+type Stats struct {
+	QueueSize        int           `json:"queue_size"`
+	QueueCapacity    int           `json:"queue_capacity"`
+	Logged           uint64        `json:"logged"`
+	Errors           uint64        `json:"errors"`
+	Dropped          uint64        `json:"dropped"`
+	SampledOut       uint64        `json:"sampled_out"`
+	OverQuota        uint64        `json:"over_quota"`
+	SchemaViolations uint64        `json:"schema_violations"`
+	Targets          []TargetStats `json:"targets"`
+	Groups           []GroupStats  `json:"groups,omitempty"`
+}
+
+// TargetStats is a point in time snapshot of statistics for one target.
+type TargetStats struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	QueueSize     int    `json:"queue_size"`
+	QueueCapacity int    `json:"queue_capacity"`
+	QueueBytes    int64  `json:"queue_bytes"`
+	Logged        uint64 `json:"logged"`
+	Errors        uint64 `json:"errors"`
+	Dropped       uint64 `json:"dropped"`
+	Blocked       uint64 `json:"blocked"`
+	Rerouted      uint64 `json:"rerouted"`
+
+	LastFlushDuration time.Duration `json:"last_flush_duration"`
+	LastFlushRecords  uint64        `json:"last_flush_records"`
+	ShutdownDrain     time.Duration `json:"shutdown_drain"`
+	Abandoned         uint64        `json:"abandoned"`
+	Stale             uint64        `json:"stale"`
+
+	// FormattedBytes and EstimatedBytes are the total formatted and estimated sizes
+	// of the records written, the latter from `LogRec.EstimatedSize`.
+	FormattedBytes uint64 `json:"formatted_bytes"`
+	EstimatedBytes uint64 `json:"estimated_bytes"`
+}
+
+// StatsSnapshot returns the current queue depths and counts of logged, dropped and
+// failed log records for this Logr and each of its targets. Unlike metrics provided
+// via `MetricsCollector`, statistics are always collected.
+func (lgr *Logr) StatsSnapshot() Stats {
+	s := Stats{
+		QueueSize:        len(lgr.in),
+		QueueCapacity:    cap(lgr.in),
+		Logged:           atomic.LoadUint64(&lgr.stats.logged),
+		Errors:           atomic.LoadUint64(&lgr.stats.errors),
+		Dropped:          atomic.LoadUint64(&lgr.stats.dropped),
+		SampledOut:       atomic.LoadUint64(&lgr.stats.sampledOut),
+		OverQuota:        atomic.LoadUint64(&lgr.stats.overQuota),
+		SchemaViolations: atomic.LoadUint64(&lgr.stats.schemaViolations),
+	}
+
+	lgr.tmux.RLock()
+	defer lgr.tmux.RUnlock()
+
+	s.Targets = make([]TargetStats, 0, len(lgr.targetHosts))
+	for _, host := range lgr.targetHosts {
+		s.Targets = append(s.Targets, host.statsSnapshot())
+	}
+	s.Groups = lgr.groupStats()
+	return s
+}
+
+// expvarMux serializes checking for and publishing expvars, since expvar.Publish
+// panics if the name is already published.
+var expvarMux sync.Mutex
+
+// PublishExpvar publishes the result of `StatsSnapshot` as an expvar with the
+// specified name, making it available at `/debug/vars` when the expvar HTTP handler
+// is registered. An error is returned if the name is already published.
+func (lgr *Logr) PublishExpvar(name string) error {
+	expvarMux.Lock()
+	defer expvarMux.Unlock()
+
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar '%s' already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return lgr.StatsSnapshot()
+	}))
+	return nil
+}
+
+func (h *TargetHost) statsSnapshot() TargetStats {
+	in := h.queue()
+	return TargetStats{
+		Name:          h.name,
+		Type:          fmt.Sprintf("%T", h.target),
+		QueueSize:     h.queueLen(),
+		QueueCapacity: cap(in),
+		QueueBytes:    atomic.LoadInt64(&h.stats.queuedBytes),
+		Logged:        atomic.LoadUint64(&h.stats.logged),
+		Errors:        atomic.LoadUint64(&h.stats.errors),
+		Dropped:       atomic.LoadUint64(&h.stats.dropped),
+		Blocked:       atomic.LoadUint64(&h.stats.blocked),
+		Rerouted:      atomic.LoadUint64(&h.stats.rerouted),
+
+		LastFlushDuration: time.Duration(atomic.LoadInt64(&h.stats.lastFlushNanos)),
+		LastFlushRecords:  atomic.LoadUint64(&h.stats.lastFlushRecords),
+		ShutdownDrain:     time.Duration(atomic.LoadInt64(&h.stats.shutdownNanos)),
+		Abandoned:         atomic.LoadUint64(&h.stats.abandoned),
+		Stale:             atomic.LoadUint64(&h.stats.stale),
+
+		FormattedBytes: atomic.LoadUint64(&h.stats.formattedBytes),
+		EstimatedBytes: atomic.LoadUint64(&h.stats.estimatedBytes),
+	}
+}
@@ -0,0 +1,83 @@
+package logr_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsSnapshot(t *testing.T) {
+	lgr, err := logr.New(logr.OnLoggerError(func(error) {}))
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(targets.NewWriterTarget(&test.Buffer{}), "good", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{}, 10)
+	require.NoError(t, err)
+	err = lgr.AddTarget(test.NewFailingTarget(), "bad", &logr.StdFilter{Lvl: logr.Error},
+		&formatters.Plain{}, 20)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("one")
+	logger.Error("two")
+	logger.Debug("filtered")
+	require.NoError(t, lgr.Flush())
+
+	stats := lgr.StatsSnapshot()
+	assert.Equal(t, uint64(2), stats.Logged)
+	assert.Equal(t, uint64(1), stats.Errors)
+	require.Len(t, stats.Targets, 2)
+	// flush stats depend on timing so are only checked for being set.
+	targetStats := make([]logr.TargetStats, len(stats.Targets))
+	for i, ts := range stats.Targets {
+		assert.Greater(t, int64(ts.LastFlushDuration), int64(0))
+		ts.LastFlushDuration, ts.LastFlushRecords = 0, 0
+		// byte counts depend on the time stamp so are checked separately.
+		assert.Equal(t, ts.Logged > 0, ts.FormattedBytes > 0 && ts.EstimatedBytes > 0)
+		ts.FormattedBytes, ts.EstimatedBytes = 0, 0
+		targetStats[i] = ts
+	}
+	assert.Equal(t, logr.TargetStats{Name: "good", Type: "*targets.Writer", QueueCapacity: 10, Logged: 2}, targetStats[0])
+	assert.Equal(t, logr.TargetStats{Name: "bad", Type: "*test.FailingTarget", QueueCapacity: 20, Errors: 1}, targetStats[1])
+
+	require.NoError(t, lgr.PublishExpvar("logr_stats_test"))
+	assert.Error(t, lgr.PublishExpvar("logr_stats_test"))
+
+	var published logr.Stats
+	err = json.Unmarshal([]byte(expvar.Get("logr_stats_test").String()), &published)
+	require.NoError(t, err)
+	assert.Equal(t, stats, published)
+
+	require.NoError(t, lgr.Shutdown())
+}
+
+func TestPublishExpvarConcurrent(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	// exactly one publish succeeds; the rest must not panic.
+	var published int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lgr.PublishExpvar("logr_stats_concurrent_test") == nil {
+				atomic.AddInt32(&published, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), published)
+
+	require.NoError(t, lgr.Shutdown())
+}
@@ -0,0 +1,276 @@
+package logr
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SummarizeDurations aggregates high-frequency records into periodic summaries,
+// turning log spam into useful stats. Records with message msg and a duration field
+// named durationKey are not output; instead their durations are accumulated in a
+// histogram for each value of the field named groupKey, or a single histogram if
+// groupKey is empty.
+//
+// When each interval ends, and on flush, a record is output for each histogram with
+// message msg and the level of the last record summarized. Its fields are the
+// group field, `count`, and the `p50`, `p95` and `max` durations. Percentiles are
+// accurate to within about 6%.
+//
+// This option can be used multiple times to summarize different messages.
+func SummarizeDurations(msg string, durationKey string, groupKey string, interval time.Duration) Option {
+	return func(l *Logr) error {
+		if msg == "" || durationKey == "" {
+			return errors.New("msg and duration key cannot be empty")
+		}
+		if interval <= 0 {
+			return errors.New("summary interval must be greater than zero")
+		}
+		l.options.summaries = append(l.options.summaries, summaryOptions{
+			msg:         msg,
+			durationKey: durationKey,
+			groupKey:    groupKey,
+			interval:    interval,
+		})
+		return nil
+	}
+}
+
+type summaryOptions struct {
+	msg         string
+	durationKey string
+	groupKey    string
+	interval    time.Duration
+}
+
+// summarizer applies `SummarizeDurations`. It is only accessed by the Logr read
+// loop, or under the inline mutex in minimal mode, so needs no locking.
+type summarizer struct {
+	rules  []*summaryRule
+	byMsg  map[string]*summaryRule
+	ticker *time.Ticker
+}
+
+type summaryRule struct {
+	summaryOptions
+	windowEnd time.Time
+	groups    map[string]*durationHistogram
+}
+
+func newSummarizer(opts []summaryOptions) *summarizer {
+	s := &summarizer{byMsg: make(map[string]*summaryRule, len(opts))}
+	tick := opts[0].interval
+	for _, o := range opts {
+		rule := &summaryRule{summaryOptions: o, groups: make(map[string]*durationHistogram)}
+		s.rules = append(s.rules, rule)
+		s.byMsg[o.msg] = rule
+		if o.interval < tick {
+			tick = o.interval
+		}
+	}
+	s.ticker = time.NewTicker(tick)
+	return s
+}
+
+// tick returns a channel signaled periodically so summaries are output even when
+// no more records arrive, or nil if not enabled.
+func (s *summarizer) tick() <-chan time.Time {
+	if s == nil {
+		return nil
+	}
+	return s.ticker.C
+}
+
+func (s *summarizer) stop() {
+	if s != nil {
+		s.ticker.Stop()
+	}
+}
+
+// add accumulates a prepped record, returning true if it was summarized and should
+// not be output.
+func (s *summarizer) add(rec *LogRec) bool {
+	rule, ok := s.byMsg[rec.msg]
+	if !ok {
+		return false
+	}
+
+	var d time.Duration
+	var group Field
+	var found bool
+	for _, f := range rec.fieldsAll {
+		switch {
+		case f.Key == rule.durationKey && f.Type == DurationType:
+			d = time.Duration(f.Integer)
+			found = true
+		case rule.groupKey != "" && f.Key == rule.groupKey:
+			group = f
+		}
+	}
+	if !found {
+		return false
+	}
+
+	var key string
+	if rule.groupKey != "" {
+		if group.Key == "" {
+			group = String(rule.groupKey, "")
+		}
+		var sb strings.Builder
+		_ = group.ValueString(&sb, nil)
+		key = sb.String()
+	}
+
+	if len(rule.groups) == 0 {
+		rule.windowEnd = rec.time.Add(rule.interval)
+	}
+	h, ok := rule.groups[key]
+	if !ok {
+		h = &durationHistogram{group: group, buckets: make(map[int]uint64)}
+		rule.groups[key] = h
+	}
+	h.add(d)
+	h.level = rec.level
+	return true
+}
+
+// roll returns summary records for rules whose interval ended before now, and
+// resets them.
+func (s *summarizer) roll(now time.Time, logger Logger) []*LogRec {
+	var recs []*LogRec
+	for _, rule := range s.rules {
+		if len(rule.groups) > 0 && !now.Before(rule.windowEnd) {
+			recs = append(recs, rule.drain(logger)...)
+		}
+	}
+	return recs
+}
+
+// drain returns summary records for all rules and resets them.
+func (s *summarizer) drain(logger Logger) []*LogRec {
+	var recs []*LogRec
+	for _, rule := range s.rules {
+		recs = append(recs, rule.drain(logger)...)
+	}
+	return recs
+}
+
+// drain returns a summary record for each group, sorted by group, and resets them.
+func (r *summaryRule) drain(logger Logger) []*LogRec {
+	keys := make([]string, 0, len(r.groups))
+	for key := range r.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	recs := make([]*LogRec, 0, len(keys))
+	for _, key := range keys {
+		h := r.groups[key]
+		fields := make([]Field, 0, 5)
+		if r.groupKey != "" {
+			fields = append(fields, h.group)
+		}
+		fields = append(fields,
+			Int("count", int(h.count)),
+			Duration("p50", h.quantile(0.5)),
+			Duration("p95", h.quantile(0.95)),
+			Duration("max", h.max),
+		)
+		recs = append(recs, NewLogRec(h.level, logger, r.msg, fields, false))
+	}
+	r.groups = make(map[string]*durationHistogram)
+	return recs
+}
+
+// histSubBuckets is the number of buckets each power of two is divided into,
+// bounding the relative error of quantiles to 1/histSubBuckets.
+const (
+	histSubBits    = 4
+	histSubBuckets = 1 << histSubBits
+)
+
+// durationHistogram is a sparse log-linear histogram of durations.
+type durationHistogram struct {
+	group   Field
+	level   Level
+	count   uint64
+	max     time.Duration
+	buckets map[int]uint64
+}
+
+func (h *durationHistogram) add(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+	h.buckets[histBucket(uint64(d))]++
+}
+
+// quantile returns the upper bound of the bucket holding the q quantile, capped
+// to the maximum duration.
+func (h *durationHistogram) quantile(q float64) time.Duration {
+	idxs := make([]int, 0, len(h.buckets))
+	for idx := range h.buckets {
+		idxs = append(idxs, idx)
+	}
+	sort.Ints(idxs)
+
+	rank := uint64(q*float64(h.count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for _, idx := range idxs {
+		seen += h.buckets[idx]
+		if seen >= rank {
+			if d := time.Duration(histUpperBound(idx)); d < h.max {
+				return d
+			}
+			break
+		}
+	}
+	return h.max
+}
+
+// histBucket returns the bucket index of v. Values below histSubBuckets have
+// their own bucket; larger values share a bucket with others having the same
+// leading histSubBits+1 bits.
+func histBucket(v uint64) int {
+	if v < histSubBuckets {
+		return int(v)
+	}
+	exp := bits.Len64(v) - 1 - histSubBits
+	sub := int(v>>uint(exp)) & (histSubBuckets - 1)
+	return histSubBuckets + exp*histSubBuckets + sub
+}
+
+// histUpperBound returns the largest value in the bucket at idx.
+func histUpperBound(idx int) uint64 {
+	if idx < histSubBuckets {
+		return uint64(idx)
+	}
+	exp := uint((idx - histSubBuckets) / histSubBuckets)
+	sub := uint64((idx - histSubBuckets) % histSubBuckets)
+	return (histSubBuckets+sub+1)<<exp - 1
+}
+
+// outputSummaries prepares and dispatches summary records.
+func (lgr *Logr) outputSummaries(recs []*LogRec) {
+	for _, rec := range recs {
+		rec.prep()
+		lgr.dispatch(rec)
+	}
+}
+
+// now returns the current time, as provided by the `Clock` option if set.
+func (lgr *Logr) now() time.Time {
+	if lgr.options.clock != nil {
+		return lgr.options.clock()
+	}
+	return time.Now()
+}
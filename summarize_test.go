@@ -0,0 +1,89 @@
+package logr_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeDurations(t *testing.T) {
+	var mux sync.Mutex
+	now := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		now = now.Add(d)
+	}
+
+	lgr, err := logr.New(
+		logr.SummarizeDurations("query", "elapsed", "table", time.Minute),
+		logr.Clock(func() time.Time {
+			mux.Lock()
+			defer mux.Unlock()
+			return now
+		}),
+	)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "summaryTest", &logr.StdFilter{Lvl: logr.Debug}, formatter, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	for i := 1; i <= 100; i++ {
+		logger.Debug("query", logr.String("table", "users"), logr.Duration("elapsed", time.Duration(i)*time.Millisecond))
+	}
+	logger.Debug("query", logr.String("table", "posts"), logr.Duration("elapsed", 3*time.Second))
+	logger.Debug("query", logr.String("table", "posts"))
+	logger.Info("other")
+
+	// the next record after the interval ends causes the summaries to be output.
+	advance(time.Minute)
+	logger.Info("later")
+	logger.Debug("query", logr.String("table", "users"), logr.Duration("elapsed", time.Second))
+	require.NoError(t, lgr.Shutdown())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 6, buf.String())
+	assert.Equal(t, "debug query table=posts", strings.TrimSpace(lines[0]))
+	assert.Equal(t, "info other", strings.TrimSpace(lines[1]))
+	assert.Equal(t, "debug query table=posts count=1 p50=3s p95=3s max=3s", lines[2])
+	assert.Equal(t, "debug query table=users count=100 p50=50.331647ms p95=96.468991ms max=100ms", lines[3])
+	assert.Equal(t, "info later", strings.TrimSpace(lines[4]))
+	// output on shutdown.
+	assert.Equal(t, "debug query table=users count=1 p50=1s p95=1s max=1s", lines[5])
+
+	_, err = logr.New(logr.SummarizeDurations("query", "elapsed", "", 0))
+	assert.Error(t, err)
+}
+
+func TestSummarizeDurationsMinimal(t *testing.T) {
+	lgr, err := logr.New(logr.Minimal(), logr.SummarizeDurations("req", "elapsed", "", time.Minute))
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "summaryTest", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 0)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	for i := 1; i <= 3; i++ {
+		logger.Info("req", logr.Duration("elapsed", time.Duration(i)*time.Millisecond))
+	}
+	assert.Empty(t, buf.String())
+
+	// output on shutdown, even without a read loop.
+	require.NoError(t, lgr.Shutdown())
+	output := strings.TrimSpace(buf.String())
+	assert.True(t, strings.HasPrefix(output, "info req count=3 p50="), output)
+	assert.True(t, strings.HasSuffix(output, " max=3ms"), output)
+}
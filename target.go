@@ -1,10 +1,12 @@
 package logr
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -23,6 +25,83 @@ type Target interface {
 	Shutdown() error
 }
 
+// TargetWithContext is implemented by targets that can abort a write when the
+// context is done, such as network targets that may otherwise hang on a stalled
+// connection. When implemented, `WriteContext` is called instead of `Write` with
+// a context that expires after the target's write timeout, if any. See `WriteTimeout`.
+type TargetWithContext interface {
+	// WriteContext outputs to this target's destination, aborting if ctx is done.
+	WriteContext(ctx context.Context, p []byte, rec *LogRec) (int, error)
+}
+
+// TargetFlusher is implemented by targets that buffer records, for example to send
+// them in batches. `Flush` is called from the target's goroutine when the Logr is
+// flushed, after the target queue is drained.
+type TargetFlusher interface {
+	Flush() error
+}
+
+// TargetOption configures how a target is hosted. See `Logr.AddTarget`.
+type TargetOption func(*targetHostOptions) error
+
+// WriteTimeout sets the maximum amount of time a single write can take for targets
+// that implement `TargetWithContext`. Once the timeout expires the write is aborted,
+// allowing the target to move on instead of stalling its queue. Zero means no timeout.
+func WriteTimeout(timeout time.Duration) TargetOption {
+	return func(opts *targetHostOptions) error {
+		if timeout < 0 {
+			return errors.New("write timeout cannot be negative")
+		}
+		opts.writeTimeout = timeout
+		return nil
+	}
+}
+
+// BlockLevel makes records at or above the specified level always block until they
+// can be queued for the target, never being dropped by `OnTargetQueueFull`, load
+// shedding or `EnqueueTimeout`. Less severe levels use the normal overflow policy.
+// For example, with BlockLevel(Error) errors are never lost while chatty debug
+// records can be dropped. Custom levels are compared by ID, where lower IDs are
+// more severe. Note a stalled target will stall logging at these levels.
+func BlockLevel(level Level) TargetOption {
+	return func(opts *targetHostOptions) error {
+		opts.blockLevel = &level
+		return nil
+	}
+}
+
+// Synchronous writes records to the target on the goroutine logging them, under a
+// mutex, instead of via the target queue. Each logging call returns only once the
+// record is written, so output is immediate and ordered with respect to the caller,
+// at the cost of throughput. This is intended for crash-critical sinks and CLI tools.
+// Records rejected by `FieldSchema` are not written, and violations are flagged,
+// as for queued targets. Records are written before the remaining Logr-level
+// processing that changes output, which only applies to queued targets:
+// `ErrorFingerprint` fields, `SummarizeDurations`, `VolumeQuota`, coalescing and
+// async enrichment. A slow synchronous target slows down all logging.
+func Synchronous() TargetOption {
+	return func(opts *targetHostOptions) error {
+		opts.synchronous = true
+		return nil
+	}
+}
+
+// FlushInterval periodically flushes the target, draining its queue and calling
+// `TargetFlusher.Flush`, so buffered targets such as HTTP batchers emit records
+// within a latency bound even under low traffic instead of waiting for a size
+// threshold. A flush is skipped while the queue is full since records are being
+// delivered anyway. This has no effect on targets that do not implement
+// `TargetFlusher`, or in `Minimal` mode.
+func FlushInterval(interval time.Duration) TargetOption {
+	return func(opts *targetHostOptions) error {
+		if interval < 0 {
+			return errors.New("flush interval cannot be negative")
+		}
+		opts.flushInterval = interval
+		return nil
+	}
+}
+
 type targetMetrics struct {
 	queueSizeGauge Gauge
 	loggedCounter  Counter
@@ -37,6 +116,27 @@ type targetHostOptions struct {
 	formatter    Formatter
 	maxQueueSize int
 	metrics      *metrics
+	logger       Logger
+	writeTimeout time.Duration
+	blockLevel   *Level
+	synchronous  bool
+	inline       bool
+	stale        *staleOptions
+	priority     bool
+	isolated     map[LevelID]struct{}
+	allowed      map[LevelID]struct{}
+
+	flushInterval time.Duration
+
+	fieldSelector   *fieldSelector
+	fieldTransforms []FieldTransform
+	offloader       *fieldOffloader
+
+	groupName      string
+	groupMaxQueued int
+	group          *targetGroup
+
+	maxQueueBytes int64
 }
 
 // TargetHost hosts and manages the lifecycle of a target.
@@ -46,26 +146,101 @@ type TargetHost struct {
 	target Target
 	name   string
 
-	filter    Filter
-	formatter Formatter
-
-	in            chan *LogRec
-	quit          chan struct{} // closed by Shutdown to exit read loop
+	filter          Filter
+	excluded        map[LevelID]struct{} // isolated levels not allowed; see `IsolateLevels`
+	fmux            sync.RWMutex
+	formatter       Formatter
+	writeTimeout    time.Duration
+	blockLevel      *Level
+	fieldSelector   *fieldSelector
+	fieldTransforms []FieldTransform
+	offloader       *fieldOffloader
+
+	// synchronous targets are written by the logging goroutine; smux serializes
+	// writes, flushes and shutdown. See `Synchronous`.
+	synchronous bool
+	smux        sync.Mutex
+
+	// stale drops old records at dequeue; nil if not enabled. See `MaxRecordAge`.
+	stale        *staleOptions
+	staleCounter Counter
+
+	// formattedBytesCounter is nil unless the metrics collector implements
+	// `FormattedBytesCollector`.
+	formattedBytesCounter Counter
+
+	// priority holds dequeued records for delivery by level; nil for FIFO
+	// delivery. See `PriorityQueue`.
+	priority *priorityQueue
+
+	// batchWriter is set for targets that write queued records in batches of up
+	// to maxBatch. batch is reused by the read loop. See `TargetBatchWriter`.
+	batchWriter TargetBatchWriter
+	maxBatch    int
+	batch       []*LogRec
+
+	// group shares a queued record budget with other targets; nil if not in a
+	// group. See `InTargetGroup`.
+	group *targetGroup
+
+	// maxQueueBytes bounds the estimated size of queued records, tracked in
+	// stats.queuedBytes; zero if not bounded. See `MaxQueueBytes`.
+	maxQueueBytes int64
+
+	// inline targets have no goroutine; records are written by the logging
+	// goroutine in minimal mode. See `Minimal`.
+	inline bool
+
+	qmux          sync.RWMutex  // write locked while the queue is replaced
+	in            atomic.Value  // chan *LogRec, replaced and closed by setQueueSize
+	quit          chan struct{} // closed by stop to exit read loop
+	quitOnce      sync.Once
 	done          chan struct{} // closed when read loop exited
 	targetMetrics *targetMetrics
+	flushMetrics  *flushMetrics
+	shedCounters  *shedCounters
+	stats         *statCounters
+	recentDrops   dropWindow
 
 	shutdown int32
 }
 
 func newTargetHost(target Target, options targetHostOptions) (*TargetHost, error) {
 	host := &TargetHost{
-		target:    target,
-		name:      options.name,
-		filter:    options.filter,
-		formatter: options.formatter,
-		in:        make(chan *LogRec, options.maxQueueSize),
-		quit:      make(chan struct{}),
-		done:      make(chan struct{}),
+		target:          target,
+		name:            options.name,
+		filter:          options.filter,
+		formatter:       options.formatter,
+		writeTimeout:    options.writeTimeout,
+		blockLevel:      options.blockLevel,
+		fieldSelector:   options.fieldSelector,
+		fieldTransforms: options.fieldTransforms,
+		offloader:       options.offloader,
+		synchronous:     options.synchronous,
+		inline:          options.inline,
+		stale:           options.stale,
+		group:           options.group,
+		maxQueueBytes:   options.maxQueueBytes,
+		quit:            make(chan struct{}),
+		done:            make(chan struct{}),
+		stats:           &statCounters{},
+	}
+
+	host.in.Store(make(chan *LogRec, options.maxQueueSize))
+	for id := range options.isolated {
+		if _, ok := options.allowed[id]; !ok {
+			if host.excluded == nil {
+				host.excluded = make(map[LevelID]struct{})
+			}
+			host.excluded[id] = struct{}{}
+		}
+	}
+	if options.priority {
+		host.priority = &priorityQueue{}
+	}
+	if bw, ok := target.(TargetBatchWriter); ok && bw.MaxBatchSize() > 1 {
+		host.batchWriter = bw
+		host.maxBatch = bw.MaxBatchSize()
 	}
 
 	if host.name == "" {
@@ -89,8 +264,17 @@ func newTargetHost(target Target, options targetHostOptions) (*TargetHost, error
 		return nil, err
 	}
 
+	if host.inline {
+		close(host.done)
+		return host, nil
+	}
+
 	go host.start()
 
+	if _, ok := target.(TargetFlusher); ok && options.flushInterval > 0 {
+		go host.startFlushTicker(options.logger, options.flushInterval)
+	}
+
 	return host, nil
 }
 
@@ -119,6 +303,25 @@ func (h *TargetHost) initMetrics(metrics *metrics) error {
 	}
 	h.targetMetrics = tmetrics
 
+	if sc, ok := metrics.collector.(ShedCounterCollector); ok {
+		h.shedCounters = &shedCounters{collector: sc, counters: make(map[string]Counter)}
+	}
+	if sc, ok := metrics.collector.(StaleCounterCollector); ok && h.stale != nil {
+		if h.staleCounter, err = sc.StaleCounter(h.name); err != nil {
+			return err
+		}
+	}
+	if bc, ok := metrics.collector.(FormattedBytesCollector); ok {
+		if h.formattedBytesCounter, err = bc.FormattedBytesCounter(h.name); err != nil {
+			return err
+		}
+	}
+	if fc, ok := metrics.collector.(FlushMetricsCollector); ok {
+		if h.flushMetrics, err = newFlushMetrics(fc, h.name); err != nil {
+			return err
+		}
+	}
+
 	updateFreqMillis := metrics.updateFreqMillis
 	if updateFreqMillis == 0 {
 		updateFreqMillis = DefMetricsUpdateFreqMillis
@@ -133,10 +336,41 @@ func (h *TargetHost) initMetrics(metrics *metrics) error {
 
 // IsLevelEnabled returns true if this target should emit logs for the specified level.
 func (h *TargetHost) IsLevelEnabled(lvl Level) (enabled bool, level Level) {
+	if _, ok := h.excluded[lvl.ID]; ok {
+		return false, lvl
+	}
 	level, enabled = h.filter.GetEnabledLevel(lvl)
 	return enabled, level
 }
 
+// Formatter returns the formatter currently used by this target.
+func (h *TargetHost) Formatter() Formatter {
+	h.fmux.RLock()
+	defer h.fmux.RUnlock()
+	return h.formatter
+}
+
+// setFormatter replaces the formatter used by this target. Records already being
+// formatted complete with the previous formatter.
+func (h *TargetHost) setFormatter(formatter Formatter) {
+	if formatter == nil {
+		formatter = &DefaultFormatter{}
+	}
+	h.fmux.Lock()
+	defer h.fmux.Unlock()
+	h.formatter = formatter
+}
+
+// IsRecordEnabled returns true if this target should emit the log record. This
+// only applies to targets with a filter implementing `RecordFilter`, otherwise
+// true is always returned.
+func (h *TargetHost) IsRecordEnabled(rec *LogRec) bool {
+	if rf, ok := h.filter.(RecordFilter); ok {
+		return rf.IsRecordEnabled(rec)
+	}
+	return true
+}
+
 // Shutdown stops processing log records after making best
 // effort to flush queue.
 func (h *TargetHost) Shutdown(ctx context.Context) error {
@@ -144,39 +378,145 @@ func (h *TargetHost) Shutdown(ctx context.Context) error {
 		return errors.New("targetHost shutdown called more than once")
 	}
 
-	close(h.quit)
+	start := time.Now()
+	h.stop()
 
 	// No more records can be accepted; now wait for read loop to exit.
 	select {
 	case <-ctx.Done():
 	case <-h.done:
 	}
+	h.setShutdownMetrics(time.Since(start), h.queueLen())
+
+	h.smux.Lock()
+	defer h.smux.Unlock()
 
 	// b.in channel should now be drained.
 	return h.target.Shutdown()
 }
 
+// stop closes the quit channel, releasing any goroutine blocked queuing a record,
+// without waiting for the read loop to exit. Safe to call more than once.
+func (h *TargetHost) stop() {
+	h.quitOnce.Do(func() { close(h.quit) })
+}
+
+// flushInline flushes an inline target if it buffers records.
+func (h *TargetHost) flushInline() error {
+	f, ok := h.target.(TargetFlusher)
+	if !ok {
+		return nil
+	}
+
+	h.smux.Lock()
+	defer h.smux.Unlock()
+	if err := f.Flush(); err != nil {
+		h.incErrorCounter()
+		return fmt.Errorf("flush failed for target %s: %w", h.name, err)
+	}
+	return nil
+}
+
+// writeSync writes a prepped log record to a synchronous target on the
+// calling goroutine.
+func (h *TargetHost) writeSync(rec *LogRec) {
+	h.smux.Lock()
+	defer h.smux.Unlock()
+	h.writeSyncLocked(rec)
+}
+
+// writeSyncEnabled writes a log record to a synchronous target if enabled by the
+// target's `RecordFilter`, which is called under the target mutex so calls are not
+// concurrent when several goroutines log at once.
+func (h *TargetHost) writeSyncEnabled(rec *LogRec) {
+	h.smux.Lock()
+	defer h.smux.Unlock()
+	if h.IsRecordEnabled(rec) {
+		h.writeSyncLocked(rec)
+	}
+}
+
+func (h *TargetHost) writeSyncLocked(rec *LogRec) {
+	if atomic.LoadInt32(&h.shutdown) != 0 {
+		return
+	}
+
+	if err := h.writeRec(rec); err != nil {
+		h.incErrorCounter()
+		rec.Logger().Logr().ReportError(err)
+	} else {
+		h.incLoggedCounter()
+	}
+}
+
 // Log queues a log record to be output to this target's destination.
 func (h *TargetHost) Log(rec *LogRec) {
+	h.log(rec, false)
+}
+
+// log queues a log record. rerouted is true when the record was rerouted here
+// from another target's full queue.
+func (h *TargetHost) log(rec *LogRec, rerouted bool) {
 	if atomic.LoadInt32(&h.shutdown) != 0 {
 		return
 	}
 
+	if h.inline {
+		if rec.flush == nil {
+			h.writeSync(rec)
+		}
+		return
+	}
+
+	h.qmux.RLock()
+	defer h.qmux.RUnlock()
+	in := h.queue()
+
 	lgr := rec.Logger().Logr()
+	block := h.blockLevel != nil && rec.Level().ID <= h.blockLevel.ID
+	if hw := lgr.options.shedHighWater; hw > 0 && !block && h.shouldShed(in, rec.Level(), hw) {
+		h.incShedCounter(rec.Level())
+		return
+	}
+
+	if h.group != nil && rec.flush == nil && !block && h.overGroupBudget(lgr) {
+		return
+	}
+
+	if !h.reserveBytes(rec, block) {
+		return
+	}
+
+	if rec.flush != nil {
+		h.flushQueued(len(in))
+	}
+
 	select {
-	case h.in <- rec:
+	case in <- rec:
 	default:
-		handler := lgr.options.onTargetQueueFull
-		if handler != nil && handler(h.target, rec, cap(h.in)) {
-			h.incDroppedCounter()
-			return // drop the record
+		if block {
+			h.incBlockedCounter()
+			select {
+			case <-h.quit:
+				h.releaseBytes(rec)
+			case in <- rec: // block until success or shutdown
+			}
+			return
+		}
+
+		if rec.flush == nil && h.queueFull(lgr, rec, in, rerouted) {
+			h.releaseBytes(rec)
+			return // dropped or rerouted
 		}
 		h.incBlockedCounter()
 
 		select {
 		case <-time.After(lgr.options.enqueueTimeout):
+			h.releaseBytes(rec)
 			lgr.ReportError(fmt.Errorf("target enqueue timeout for log rec [%v]", rec))
-		case h.in <- rec: // block until success or timeout
+		case <-h.quit:
+			h.releaseBytes(rec)
+		case in <- rec: // block until success or timeout
 		}
 	}
 }
@@ -188,29 +528,95 @@ func (h *TargetHost) setQueueSizeGauge(val float64) {
 }
 
 func (h *TargetHost) incLoggedCounter() {
+	atomic.AddUint64(&h.stats.logged, 1)
 	if h.targetMetrics != nil {
 		h.targetMetrics.loggedCounter.Inc()
 	}
 }
 
 func (h *TargetHost) incErrorCounter() {
+	atomic.AddUint64(&h.stats.errors, 1)
 	if h.targetMetrics != nil {
 		h.targetMetrics.errorCounter.Inc()
 	}
 }
 
 func (h *TargetHost) incDroppedCounter() {
+	atomic.AddUint64(&h.stats.dropped, 1)
+	h.recentDrops.add(time.Now())
 	if h.targetMetrics != nil {
 		h.targetMetrics.droppedCounter.Inc()
 	}
 }
 
 func (h *TargetHost) incBlockedCounter() {
+	atomic.AddUint64(&h.stats.blocked, 1)
 	if h.targetMetrics != nil {
 		h.targetMetrics.blockedCounter.Inc()
 	}
 }
 
+// queue returns the channel currently used to queue records for this target.
+func (h *TargetHost) queue() chan *LogRec {
+	return h.in.Load().(chan *LogRec)
+}
+
+// queueLen returns the number of records queued for this target, including
+// those awaiting delivery by a priority queue.
+func (h *TargetHost) queueLen() int {
+	n := len(h.queue())
+	if h.priority != nil {
+		n += h.priority.pending()
+	}
+	return n
+}
+
+// setQueueSize replaces the queue with one of the specified capacity, moving any
+// queued records. When shrinking, this waits until the queued records fit, with
+// new records blocked meanwhile, until ctx is done. The read loop does not take
+// the queue lock so it can keep draining.
+func (h *TargetHost) setQueueSize(ctx context.Context, size int) error {
+	if size <= 0 {
+		return errors.New("queue size must be greater than zero")
+	}
+
+	h.qmux.Lock()
+	defer h.qmux.Unlock()
+
+	old := h.queue()
+	if cap(old) == size {
+		return nil
+	}
+
+	for len(old) > size {
+		if atomic.LoadInt32(&h.shutdown) != 0 {
+			return fmt.Errorf("target %s shut down", h.name)
+		}
+		select {
+		case <-ctx.Done():
+			return newTimeoutError(fmt.Sprintf("timeout draining queue for target %s", h.name))
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	in := make(chan *LogRec, size)
+	for moved := false; !moved; {
+		select {
+		case rec := <-old:
+			in <- rec
+		default:
+			moved = true
+		}
+	}
+	h.in.Store(in)
+
+	// wakes the read loop, which then picks up the new queue.
+	close(old)
+
+	h.setQueueSizeGauge(float64(len(in)))
+	return nil
+}
+
 // String returns a name for this target.
 func (h *TargetHost) String() string {
 	return h.name
@@ -228,20 +634,27 @@ func (h *TargetHost) start() {
 		}
 	}()
 
+	if h.priority != nil {
+		h.startPriority()
+		return
+	}
+
+	in := h.queue()
 	for {
-		var rec *LogRec
 		select {
-		case rec = <-h.in:
-			if rec.flush != nil {
-				h.flush(rec.flush)
-			} else {
-				err := h.writeRec(rec)
-				if err != nil {
-					h.incErrorCounter()
-					rec.Logger().Logr().ReportError(err)
-				} else {
-					h.incLoggedCounter()
-				}
+		case rec, ok := <-in:
+			if !ok {
+				in = h.queue() // replaced by setQueueSize
+				continue
+			}
+			h.releaseBytes(rec)
+			switch {
+			case rec.flush != nil:
+				in = h.flush(in, rec)
+			case h.batchWriter != nil:
+				in = h.writeBatch(in, rec)
+			default:
+				h.writeQueued(rec)
 			}
 		case <-h.quit:
 			return
@@ -249,22 +662,116 @@ func (h *TargetHost) start() {
 	}
 }
 
+// writeQueued writes a dequeued log record to the target, unless stale.
+func (h *TargetHost) writeQueued(rec *LogRec) {
+	if h.isStale(rec) {
+		h.incStaleCounter()
+		return
+	}
+
+	err := h.writeRec(rec)
+	if err != nil {
+		h.incErrorCounter()
+		rec.Logger().Logr().ReportError(err)
+	} else {
+		h.incLoggedCounter()
+	}
+}
+
 func (h *TargetHost) writeRec(rec *LogRec) error {
+	f, err := h.formatRec(rec)
+	if err != nil {
+		return err
+	}
+	err = h.write(f.buf.Bytes(), f.rec)
+	h.wrote(f, err)
+	return err
+}
+
+// formattedRec is a log record formatted for output by a target.
+type formattedRec struct {
+	rec       *LogRec // after field selection and transforms
+	buf       *bytes.Buffer
+	volumeKey string
+	estimate  int64     // estimated size of the record as queued
+	start     time.Time // when the write started, if traced
+}
+
+// formatRec applies the target's field options and formatter to a record. The
+// buffer is borrowed from the Logr and must be returned via `wrote`.
+func (h *TargetHost) formatRec(rec *LogRec) (formattedRec, error) {
 	level, enabled := h.filter.GetEnabledLevel(rec.Level())
 	if !enabled {
 		// how did we get here?
-		return fmt.Errorf("level %s not enabled for target %s", rec.Level().Name, h.name)
+		return formattedRec{}, fmt.Errorf("level %s not enabled for target %s", rec.Level().Name, h.name)
+	}
+
+	f := formattedRec{volumeKey: rec.volumeKey, estimate: rec.estimatedSize()}
+	if h.fieldSelector != nil {
+		rec, level = h.fieldSelector.apply(rec, level)
 	}
+	if len(h.fieldTransforms) > 0 {
+		rec = applyFieldTransforms(rec, h.fieldTransforms)
+	}
+	if h.offloader != nil {
+		rec = h.offloader.apply(rec, h.name)
+	}
+	f.rec = rec
+
+	lgr := rec.logger.lgr
+	buf := lgr.BorrowBuffer()
 
-	buf := rec.logger.lgr.BorrowBuffer()
-	defer rec.logger.lgr.ReleaseBuffer(buf)
+	var start time.Time
+	if rec.traceID != 0 {
+		start = time.Now()
+		lgr.tracePhase(rec, PhaseTargetDequeue, h.name, start.Sub(rec.fanoutAt))
+	}
 
-	buf, err := h.formatter.Format(rec, level, buf)
+	buf, err := h.Formatter().Format(rec, level, buf)
 	if err != nil {
+		lgr.ReleaseBuffer(buf)
+		return formattedRec{}, err
+	}
+	f.buf = buf
+
+	if rec.traceID != 0 {
+		f.start = time.Now()
+		lgr.tracePhase(rec, PhaseFormat, h.name, f.start.Sub(start))
+	}
+	return f, nil
+}
+
+// wrote accounts for a formatted record once written and releases its buffer.
+func (h *TargetHost) wrote(f formattedRec, err error) {
+	lgr := f.rec.logger.lgr
+	if err == nil {
+		n := f.buf.Len()
+		h.addFormattedBytes(n, f.estimate)
+		if lgr.volume != nil {
+			lgr.volume.addBytes(f.volumeKey, n)
+		}
+	}
+	if f.rec.traceID != 0 {
+		lgr.tracePhase(f.rec, PhaseWrite, h.name, time.Since(f.start))
+	}
+	lgr.ReleaseBuffer(f.buf)
+}
+
+// write outputs the formatted record to the target, applying the write timeout
+// for targets that support it.
+func (h *TargetHost) write(p []byte, rec *LogRec) error {
+	if tc, ok := h.target.(TargetWithContext); ok {
+		ctx := context.Background()
+		if h.writeTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, h.writeTimeout)
+			defer cancel()
+		}
+		_, err := tc.WriteContext(ctx, p, rec)
 		return err
 	}
 
-	_, err = h.target.Write(buf.Bytes(), rec)
+	_, err := h.target.Write(p, rec)
 	return err
 }
 
@@ -276,20 +783,80 @@ func (h *TargetHost) startMetricsUpdater(updateFreqMillis int64) {
 		case <-h.done:
 			return
 		case <-time.After(time.Duration(updateFreqMillis) * time.Millisecond):
-			h.setQueueSizeGauge(float64(len(h.in)))
+			h.setQueueSizeGauge(float64(h.queueLen()))
 		}
 	}
 }
 
-// flush drains the queue and notifies when done.
-func (h *TargetHost) flush(done chan<- struct{}) {
+// startFlushTicker queues a flush record every interval until the target is
+// shut down, waiting for each flush to complete.
+func (h *TargetHost) startFlushTicker(logger Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+		}
+
+		rec := newFlushLogRec(logger)
+		if !h.tryQueueFlush(rec) {
+			continue // queue full
+		}
+		select {
+		case <-h.done:
+			return
+		case <-rec.flush:
+		}
+	}
+}
+
+// tryQueueFlush queues a flush record without blocking, returning false if
+// the queue is full.
+func (h *TargetHost) tryQueueFlush(rec *LogRec) bool {
+	h.qmux.RLock()
+	defer h.qmux.RUnlock()
+
+	in := h.queue()
+	if len(in) == cap(in) {
+		return false
+	}
+	h.flushQueued(len(in))
+
+	select {
+	case in <- rec:
+		return true
+	default:
+		return false
+	}
+}
+
+// flush drains the queue, flushes the target if it buffers records, and
+// notifies when done, including the senders of any other flush records drained,
+// e.g. by `FlushInterval` racing `Logr.Flush`. The queue in use when done is
+// returned.
+func (h *TargetHost) flush(in chan *LogRec, flushRec *LogRec) chan *LogRec {
+	var records int
+	var drained []*LogRec
 	for {
-		var rec *LogRec
 		var err error
 		select {
-		case rec = <-h.in:
-			// ignore any redundant flush records.
-			if rec.flush == nil {
+		case rec, ok := <-in:
+			if !ok {
+				in = h.queue() // replaced by setQueueSize
+				continue
+			}
+			h.releaseBytes(rec)
+			switch {
+			case rec.flush != nil:
+				// redundant; notified once this flush is done.
+				drained = append(drained, rec)
+			case h.isStale(rec):
+				h.incStaleCounter()
+			default:
+				records++
 				err = h.writeRec(rec)
 				if err != nil {
 					h.incErrorCounter()
@@ -297,8 +864,19 @@ func (h *TargetHost) flush(done chan<- struct{}) {
 				}
 			}
 		default:
-			done <- struct{}{}
-			return
+			if f, ok := h.target.(TargetFlusher); ok {
+				h.smux.Lock()
+				err = f.Flush()
+				h.smux.Unlock()
+				if err != nil {
+					h.incErrorCounter()
+					flushRec.Logger().Logr().ReportError(fmt.Errorf("flush failed for target %s: %w", h.name, err))
+				}
+			}
+			h.setFlushMetrics(records)
+			flushRec.flush <- struct{}{}
+			notifyFlushed(drained)
+			return in
 		}
 	}
 }
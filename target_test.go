@@ -0,0 +1,410 @@
+package logr_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hungTarget simulates a network target whose connection never completes a write.
+type hungTarget struct {
+	mux       sync.Mutex
+	deadlines []bool
+}
+
+func (ht *hungTarget) Init() error     { return nil }
+func (ht *hungTarget) Shutdown() error { return nil }
+
+func (ht *hungTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	return 0, errors.New("Write should not be called")
+}
+
+func (ht *hungTarget) WriteContext(ctx context.Context, p []byte, rec *logr.LogRec) (int, error) {
+	_, ok := ctx.Deadline()
+	ht.mux.Lock()
+	ht.deadlines = append(ht.deadlines, ok)
+	ht.mux.Unlock()
+
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestWriteTimeout(t *testing.T) {
+	var errs []error
+	var mux sync.Mutex
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		errs = append(errs, err)
+	}))
+	require.NoError(t, err)
+
+	target := &hungTarget{}
+	err = lgr.AddTarget(target, "bad", nil, nil, 10, logr.WriteTimeout(-1))
+	assert.Error(t, err)
+
+	err = lgr.AddTarget(target, "hung", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{}, 10,
+		logr.WriteTimeout(time.Millisecond*50))
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("one")
+	logger.Info("two")
+
+	start := time.Now()
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second*5))
+
+	assert.Equal(t, []bool{true, true}, target.deadlines)
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), context.DeadlineExceeded.Error())
+}
+
+func TestSetTargetFormatter(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "console", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+
+	// log concurrently while swapping formatters to exercise synchronization.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("busy", logr.Int("j", j))
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		f := logr.Formatter(&formatters.JSON{DisableTimestamp: true})
+		if i%2 == 0 {
+			f = &formatters.Plain{DisableTimestamp: true}
+		}
+		require.NoError(t, lgr.SetTargetFormatter("console", f))
+	}
+	wg.Wait()
+	require.NoError(t, lgr.Flush())
+
+	err = lgr.SetTargetFormatter("console", &formatters.Plain{DisableTimestamp: true})
+	require.NoError(t, err)
+	logger.Info("plain")
+	require.NoError(t, lgr.Flush())
+
+	err = lgr.SetTargetFormatter("console", &formatters.JSON{DisableTimestamp: true})
+	require.NoError(t, err)
+	logger.Info("json")
+
+	err = lgr.SetTargetFormatter("missing", &formatters.JSON{})
+	assert.Error(t, err)
+
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 502)
+	assert.Equal(t, "info plain", strings.TrimSpace(lines[500]))
+	assert.Equal(t, `{"level":"info","msg":"json"}`, lines[501])
+}
+
+func TestSetTargetQueueSize(t *testing.T) {
+	drop := logr.OnTargetQueueFull(func(target logr.Target, rec *logr.LogRec, maxQueueSize int) bool {
+		return true
+	})
+	lgr, err := logr.New(drop)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "resize", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 2)
+	require.NoError(t, err)
+
+	queued := func() int { return lgr.StatsSnapshot().Targets[0].QueueSize }
+
+	logger := lgr.NewLogger()
+	logger.Info("blocked")
+	<-target.Blocked()
+
+	// grow so records that would have been dropped are queued.
+	require.NoError(t, lgr.SetTargetQueueSize(context.Background(), "resize", 10))
+	for i := 0; i < 6; i++ {
+		logger.Info("queued")
+	}
+	require.Eventually(t, func() bool { return queued() == 6 }, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, 10, lgr.StatsSnapshot().Targets[0].QueueCapacity)
+	assert.Equal(t, uint64(0), lgr.StatsSnapshot().Targets[0].Dropped)
+
+	// shrinking waits for queued records to drain.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err = lgr.SetTargetQueueSize(ctx, "resize", 3)
+	assert.True(t, logr.IsTimeoutError(err))
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		target.Unblock()
+	}()
+	require.NoError(t, lgr.SetTargetQueueSize(context.Background(), "resize", 3))
+	assert.Equal(t, 3, lgr.StatsSnapshot().Targets[0].QueueCapacity)
+
+	assert.Error(t, lgr.SetTargetQueueSize(context.Background(), "resize", 0))
+	assert.Error(t, lgr.SetTargetQueueSize(context.Background(), "missing", 5))
+
+	logger.Info("after")
+	require.NoError(t, lgr.Shutdown())
+
+	output := buf.String()
+	assert.Equal(t, 6, strings.Count(output, "queued"))
+	assert.Contains(t, output, "blocked")
+	assert.Contains(t, output, "after")
+}
+
+func TestBlockLevel(t *testing.T) {
+	drop := logr.OnTargetQueueFull(func(target logr.Target, rec *logr.LogRec, maxQueueSize int) bool {
+		return true
+	})
+	lgr, err := logr.New(drop)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	target := test.NewBlockingTarget(buf)
+	err = lgr.AddTarget(target, "block", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 1,
+		logr.BlockLevel(logr.Error))
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("blocked")
+	<-target.Blocked()
+
+	logger.Info("queued")
+	logger.Info("dropped")
+	logger.Error("important")
+
+	stats := func() logr.TargetStats { return lgr.StatsSnapshot().Targets[0] }
+	require.Eventually(t, func() bool { return stats().Blocked == 1 }, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, uint64(1), stats().Dropped)
+
+	target.Unblock()
+	require.NoError(t, lgr.Shutdown())
+
+	output := buf.String()
+	for _, s := range []string{"blocked", "queued", "important"} {
+		assert.Contains(t, output, s)
+	}
+	assert.NotContains(t, output, "dropped")
+}
+
+func TestBlockLevelRemoveTargets(t *testing.T) {
+	drop := logr.OnTargetQueueFull(func(target logr.Target, rec *logr.LogRec, maxQueueSize int) bool {
+		return true
+	})
+	lgr, err := logr.New(drop)
+	require.NoError(t, err)
+
+	target := test.NewBlockingTarget(&test.Buffer{})
+	err = lgr.AddTarget(target, "block", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{}, 1,
+		logr.BlockLevel(logr.Error))
+	require.NoError(t, err)
+	defer target.Unblock()
+
+	logger := lgr.NewLogger()
+	logger.Info("blocked")
+	<-target.Blocked()
+	logger.Info("queued")
+
+	// blocks queuing to the stalled target while fanning out.
+	go logger.Error("important")
+	require.Eventually(t, func() bool {
+		return lgr.StatsSnapshot().Targets[0].Blocked == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	removed := make(chan struct{})
+	go func() {
+		defer close(removed)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+		_ = lgr.RemoveTargets(ctx, func(ti logr.TargetInfo) bool { return ti.Name == "block" })
+	}()
+
+	select {
+	case <-removed:
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "RemoveTargets deadlocked with a blocked record")
+	}
+	assert.Empty(t, lgr.StatsSnapshot().Targets)
+	require.NoError(t, lgr.Shutdown())
+}
+
+func TestSynchronous(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	blocked := test.NewBlockingTarget(&test.Buffer{})
+	err = lgr.AddTarget(blocked, "queued", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{}, 100)
+	require.NoError(t, err)
+
+	buf := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf), "sync", &logr.StdFilter{Lvl: logr.Info},
+		&formatters.Plain{DisableTimestamp: true}, 100, logr.Synchronous())
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("first")
+	<-blocked.Blocked()
+
+	// written before Log returns, even though the queued target is stalled.
+	logger.Warn("second", logr.Int("n", 2))
+	logger.Debug("disabled")
+	assert.Equal(t, "info first \nwarn second n=2\n", buf.String())
+
+	blocked.Unblock()
+	require.NoError(t, lgr.Shutdown())
+	assert.Equal(t, uint64(2), lgr.StatsSnapshot().Targets[1].Logged)
+}
+
+// concurrencyFilter records whether IsRecordEnabled is ever called concurrently.
+type concurrencyFilter struct {
+	logr.StdFilter
+	inside     int32
+	concurrent int32
+}
+
+func (cf *concurrencyFilter) IsRecordEnabled(rec *logr.LogRec) bool {
+	if !atomic.CompareAndSwapInt32(&cf.inside, 0, 1) {
+		atomic.StoreInt32(&cf.concurrent, 1)
+		return true
+	}
+	time.Sleep(time.Microsecond * 100)
+	atomic.StoreInt32(&cf.inside, 0)
+	return true
+}
+
+func TestSynchronousRecordFilter(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	filter := &concurrencyFilter{StdFilter: logr.StdFilter{Lvl: logr.Info}}
+	err = lgr.AddTarget(targets.NewWriterTarget(&test.Buffer{}), "sync", filter,
+		&formatters.Plain{DisableTimestamp: true}, 100, logr.Synchronous())
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				logger.Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&filter.concurrent))
+	assert.Equal(t, uint64(160), lgr.StatsSnapshot().Targets[0].Logged)
+}
+
+// batchTarget buffers records until flushed.
+type batchTarget struct {
+	mux     sync.Mutex
+	pending strings.Builder
+	out     test.Buffer
+}
+
+func (bt *batchTarget) Init() error     { return nil }
+func (bt *batchTarget) Shutdown() error { return bt.Flush() }
+
+func (bt *batchTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	bt.mux.Lock()
+	defer bt.mux.Unlock()
+	return bt.pending.Write(p)
+}
+
+func (bt *batchTarget) Flush() error {
+	bt.mux.Lock()
+	defer bt.mux.Unlock()
+	_, err := bt.out.Write([]byte(bt.pending.String()))
+	bt.pending.Reset()
+	return err
+}
+
+func TestFlushInterval(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	target := &batchTarget{}
+	err = lgr.AddTarget(target, "batch", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 100,
+		logr.FlushInterval(time.Millisecond*20))
+	require.NoError(t, err)
+
+	lgr.NewLogger().Info("low traffic")
+	assert.Eventually(t, func() bool {
+		return target.out.String() == "info low traffic \n"
+	}, time.Second*5, time.Millisecond*10)
+
+	err = lgr.AddTarget(&batchTarget{}, "invalid", nil, nil, 100, logr.FlushInterval(-time.Second))
+	assert.Error(t, err)
+	require.NoError(t, lgr.Shutdown())
+}
+
+// slowBatchTarget is a batchTarget that is slow to write.
+type slowBatchTarget struct {
+	batchTarget
+}
+
+func (st *slowBatchTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	time.Sleep(time.Millisecond)
+	return st.batchTarget.Write(p, rec)
+}
+
+func TestFlushIntervalConcurrentFlush(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	target := &slowBatchTarget{}
+	err = lgr.AddTarget(target, "batch", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 100,
+		logr.FlushInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	// flushes racing the ticker drain each other's flush records.
+	logger := lgr.NewLogger()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				logger.Info("busy")
+				assert.NoError(t, lgr.Flush())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// periodic flushing still works.
+	logger.Info("after")
+	assert.Eventually(t, func() bool {
+		return strings.HasSuffix(target.out.String(), "info after \n")
+	}, time.Second*5, time.Millisecond*10)
+	require.NoError(t, lgr.Shutdown())
+}
@@ -0,0 +1,345 @@
+package targets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// Limits imposed by the Azure Monitor Logs ingestion API.
+const (
+	AzureMonitorMaxRequestBytes = 1000 * 1000
+
+	AzureMonitorAPIVersion                 = "2023-01-01"
+	AzureMonitorDefaultMaxRetries          = 5
+	AzureMonitorDefaultMaxBatchCount       = 10000
+	AzureMonitorDefaultFlushIntervalMillis = 1000
+	AzureMonitorRequestTimeoutSecs         = 30
+
+	azureMonitorScope      = "https://monitor.azure.com/.default"
+	azureMonitorResource   = "https://monitor.azure.com"
+	azureDefaultAuthority  = "https://login.microsoftonline.com"
+	azureIMDSTokenURL      = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azureTokenEarlyExpiry  = time.Minute
+	azureMonitorTimeFormat = time.RFC3339Nano
+)
+
+// AzureCredentials determine how requests to Azure are authorized. If ClientSecret
+// is provided a service principal is used, otherwise the managed identity of the
+// host, optionally selected by ClientID.
+type AzureCredentials struct {
+	TenantID     string `json:"tenant_id,omitempty"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+
+	// Authority overrides the Azure AD authority host, e.g. for sovereign clouds.
+	Authority string `json:"authority,omitempty"`
+}
+
+// DefaultAzureMonitorColumns is the column mapping used when none is provided.
+// The DCR stream must declare these columns.
+func DefaultAzureMonitorColumns() []Column {
+	return []Column{
+		{Name: "TimeGenerated", Source: ColumnSourceTime},
+		{Name: "Level", Source: ColumnSourceLevel},
+		{Name: "Message", Source: ColumnSourceMsg},
+		{Name: "Properties", Source: ColumnSourceFields},
+	}
+}
+
+// AzureMonitorOptions provides parameters for the Azure Monitor target.
+type AzureMonitorOptions struct {
+	AzureCredentials
+
+	// Endpoint is the data collection endpoint (DCE) or DCR logs ingestion endpoint,
+	// e.g. "https://my-dce-abcd.eastus-1.ingest.monitor.azure.com".
+	Endpoint string `json:"endpoint"`
+
+	// RuleID is the immutable ID of the data collection rule (DCR), e.g. "dcr-0123...".
+	RuleID string `json:"rule_id"`
+
+	// Stream is the name of the stream declared in the DCR, e.g. "Custom-MyAppLogs".
+	Stream string `json:"stream"`
+
+	// Columns maps stream columns to parts of the log record. Defaults to
+	// `DefaultAzureMonitorColumns`.
+	Columns []Column `json:"columns,omitempty"`
+
+	// MaxRetries is the maximum number of times a batch is retried when throttled
+	// or on server errors. Defaults to AzureMonitorDefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between retries. A longer delay requested via
+	// Retry-After is honored. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (ao AzureMonitorOptions) CheckValid() error {
+	if _, err := url.ParseRequestURI(ao.Endpoint); err != nil || ao.Endpoint == "" {
+		return errors.New("missing or invalid endpoint")
+	}
+	if ao.RuleID == "" {
+		return errors.New("missing rule_id")
+	}
+	if ao.Stream == "" {
+		return errors.New("missing stream")
+	}
+	if ao.ClientSecret != "" && (ao.TenantID == "" || ao.ClientID == "") {
+		return errors.New("client_secret requires tenant_id and client_id")
+	}
+	for _, col := range ao.Columns {
+		if err := col.CheckValid(); err != nil {
+			return err
+		}
+	}
+	if ao.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if ao.Backoff != nil {
+		return ao.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// AzureMonitor sends log records to Azure Monitor Logs (Log Analytics) via the
+// DCR-based logs ingestion API.
+type AzureMonitor struct {
+	options  AzureMonitorOptions
+	url      string
+	backoff  Backoff
+	client   *http.Client
+	tokens   *azureTokenSource
+	batch    *batcher
+	shutdown chan struct{}
+
+	shutdownOnce sync.Once
+}
+
+// NewAzureMonitorTarget creates a target capable of sending log records to Azure Monitor.
+func NewAzureMonitorTarget(options AzureMonitorOptions) (*AzureMonitor, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	am := &AzureMonitor{
+		options:  options,
+		backoff:  DefaultBackoff(),
+		client:   &http.Client{Timeout: time.Second * AzureMonitorRequestTimeoutSecs},
+		shutdown: make(chan struct{}),
+	}
+	if options.Backoff != nil {
+		am.backoff = options.Backoff
+	}
+	if am.options.MaxRetries == 0 {
+		am.options.MaxRetries = AzureMonitorDefaultMaxRetries
+	}
+	if len(am.options.Columns) == 0 {
+		am.options.Columns = DefaultAzureMonitorColumns()
+	}
+	am.url = fmt.Sprintf("%s/dataCollectionRules/%s/streams/%s?api-version=%s",
+		strings.TrimSuffix(options.Endpoint, "/"), url.PathEscape(options.RuleID), url.PathEscape(options.Stream), AzureMonitorAPIVersion)
+	am.tokens = &azureTokenSource{creds: options.AzureCredentials, client: am.client}
+
+	am.batch = newBatcher(options.BatchOptions.withDefaults(BatchOptions{
+		MaxBatchCount:       AzureMonitorDefaultMaxBatchCount,
+		MaxBatchBytes:       AzureMonitorMaxRequestBytes - 2, // allow for the enclosing array
+		FlushIntervalMillis: AzureMonitorDefaultFlushIntervalMillis,
+	}), am.send)
+	return am, nil
+}
+
+// Init is called once to initialize the target.
+func (am *AzureMonitor) Init() error {
+	return nil
+}
+
+// Write converts the log record to a row and buffers it to be sent with the next batch.
+func (am *AzureMonitor) Write(p []byte, rec *logr.LogRec) (int, error) {
+	row, err := json.Marshal(columnRow(am.options.Columns, p, rec, azureMonitorTimeFormat))
+	if err != nil {
+		return 0, err
+	}
+	if len(row) > AzureMonitorMaxRequestBytes-2 {
+		return 0, fmt.Errorf("log record of %d bytes exceeds %s limit of %d bytes", len(row), am, AzureMonitorMaxRequestBytes)
+	}
+	if err = am.batch.add(row, "", rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends any buffered log records.
+func (am *AzureMonitor) Flush() error {
+	return am.batch.flush()
+}
+
+// Shutdown sends any buffered log records.
+func (am *AzureMonitor) Shutdown() error {
+	err := am.batch.flush()
+	am.shutdownOnce.Do(func() { close(am.shutdown) })
+	return err
+}
+
+// String returns a string representation of this target.
+func (am *AzureMonitor) String() string {
+	return fmt.Sprintf("AzureMonitorTarget[%s]", am.options.Stream)
+}
+
+func (am *AzureMonitor) send(items []batchItem) error {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, _ = zw.Write([]byte{'['})
+	for i, item := range items {
+		if i > 0 {
+			_, _ = zw.Write([]byte{','})
+		}
+		_, _ = zw.Write(item.data)
+	}
+	_, _ = zw.Write([]byte{']'})
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	err := retry(am.backoff, am.options.MaxRetries, am.shutdown, func() (bool, error) {
+		return am.post(body.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("%s could not send %d records: %w", am, len(items), err)
+	}
+	return nil
+}
+
+func (am *AzureMonitor) post(body []byte) (retryable bool, err error) {
+	token, err := am.tokens.Token()
+	if err != nil {
+		return true, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, am.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := am.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("ingestion failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		am.tokens.Invalidate()
+		return true, err
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, retryAfterError{err: err, delay: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode >= 500:
+		return true, err
+	}
+	return false, err
+}
+
+// azureTokenSource provides Azure AD access tokens for Azure Monitor, caching them
+// until shortly before they expire.
+type azureTokenSource struct {
+	creds  AzureCredentials
+	client *http.Client
+
+	mux    sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns a valid access token, fetching a new one if needed.
+func (ts *azureTokenSource) Token() (string, error) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	if ts.token != "" && time.Now().Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.creds.ClientSecret != "" {
+		authority := ts.creds.Authority
+		if authority == "" {
+			authority = azureDefaultAuthority
+		}
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {ts.creds.ClientID},
+			"client_secret": {ts.creds.ClientSecret},
+			"scope":         {azureMonitorScope},
+		}
+		tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), url.PathEscape(ts.creds.TenantID))
+		req, err = http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		query := url.Values{"api-version": {"2018-02-01"}, "resource": {azureMonitorResource}}
+		if ts.creds.ClientID != "" {
+			query.Set("client_id", ts.creds.ClientID)
+		}
+		req, err = http.NewRequest(http.MethodGet, azureIMDSTokenURL+"?"+query.Encode(), nil)
+		if req != nil {
+			req.Header.Set("Metadata", "true")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch Azure access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot fetch Azure access token: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// IMDS returns expires_in as a string, Azure AD as a number.
+	var tr struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err = json.Unmarshal(body, &tr); err != nil || tr.AccessToken == "" {
+		return "", fmt.Errorf("invalid Azure token response: %v", err)
+	}
+	secs, _ := tr.ExpiresIn.Int64()
+	ts.token = tr.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(secs)*time.Second - azureTokenEarlyExpiry)
+	return ts.token, nil
+}
+
+// Invalidate discards the cached token, for example after the API rejects it.
+func (ts *azureTokenSource) Invalidate() {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+	ts.token = ""
+}
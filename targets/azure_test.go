@@ -0,0 +1,98 @@
+package targets
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureMonitorTarget(t *testing.T) {
+	var mux sync.Mutex
+	var tokens int
+	var paths []string
+	var rows []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+			_ = r.ParseForm()
+			if r.Form.Get("client_secret") != "secret" || r.Form.Get("scope") != "https://monitor.azure.com/.default" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokens++
+			fmt.Fprintf(w, `{"token_type":"Bearer","expires_in":3599,"access_token":"token%d"}`, tokens)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		paths = append(paths, r.URL.RequestURI())
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var batch []map[string]interface{}
+		if err = json.NewDecoder(zr).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rows = append(rows, batch...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	am, err := NewAzureMonitorTarget(AzureMonitorOptions{
+		AzureCredentials: AzureCredentials{TenantID: "tenant", ClientID: "client", ClientSecret: "secret", Authority: server.URL},
+		Endpoint:         server.URL,
+		RuleID:           "dcr-123",
+		Stream:           "Custom-AppLogs",
+		Columns: []Column{
+			{Name: "TimeGenerated", Source: ColumnSourceTime},
+			{Name: "Level", Source: ColumnSourceLevel},
+			{Name: "Message", Source: ColumnSourceMsg},
+			{Name: "UserId", Source: "field:user_id"},
+		},
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(am, "azure_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("first", logr.String("user_id", "u1"))
+	require.NoError(t, lgr.Flush())
+	logger.Warn("second")
+	require.NoError(t, lgr.Shutdown())
+
+	mux.Lock()
+	defer mux.Unlock()
+	assert.Equal(t, 1, tokens, "token should be cached")
+	require.Len(t, paths, 2)
+	assert.Equal(t, "/dataCollectionRules/dcr-123/streams/Custom-AppLogs?api-version=2023-01-01", paths[0])
+	require.Len(t, rows, 2)
+	assert.Equal(t, "first", rows[0]["Message"])
+	assert.Equal(t, "u1", rows[0]["UserId"])
+	assert.Equal(t, "warn", rows[1]["Level"])
+	assert.Nil(t, rows[1]["UserId"])
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}T`, rows[0]["TimeGenerated"])
+}
@@ -0,0 +1,160 @@
+package targets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Backoff determines how long network targets wait before retrying a failed
+// connection or write. Implementations must be safe for concurrent use.
+type Backoff interface {
+	// Delay returns the amount of time to wait before the specified attempt,
+	// where the first retry is attempt 1.
+	Delay(attempt int) time.Duration
+}
+
+// ExponentialBackoff is a `Backoff` where the delay grows by `Multiplier` for each
+// attempt, up to `MaxMillis`, with random jitter applied so that many clients
+// reconnecting at once do not retry in lock step.
+type ExponentialBackoff struct {
+	// InitialMillis is the delay before the first retry. Defaults to RetryBackoffMillis.
+	InitialMillis int64 `json:"initial_millis,omitempty"`
+
+	// MaxMillis is the maximum delay between retries. Defaults to MaxRetryBackoffMillis.
+	MaxMillis int64 `json:"max_millis,omitempty"`
+
+	// Multiplier is the factor the delay is increased by for each attempt. Defaults to 1.5.
+	Multiplier float64 `json:"multiplier,omitempty"`
+
+	// Jitter is the fraction, between 0 and 1, of the delay that is randomized. For example
+	// 0.2 means the delay varies by up to 20% either way. Defaults to zero (no jitter).
+	Jitter float64 `json:"jitter,omitempty"`
+
+	rndMux sync.Mutex
+	rnd    *rand.Rand
+}
+
+// DefaultBackoff returns the jittered exponential backoff used by network targets
+// when none is configured.
+func DefaultBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialMillis: RetryBackoffMillis,
+		MaxMillis:     MaxRetryBackoffMillis,
+		Multiplier:    1.5,
+		Jitter:        0.2,
+	}
+}
+
+// CheckValid returns an error if any of the options are invalid.
+func (eb *ExponentialBackoff) CheckValid() error {
+	if eb.InitialMillis < 0 || eb.MaxMillis < 0 {
+		return errors.New("backoff delays cannot be negative")
+	}
+	if eb.Multiplier != 0 && eb.Multiplier < 1 {
+		return errors.New("backoff multiplier must be at least 1")
+	}
+	if eb.Jitter < 0 || eb.Jitter > 1 {
+		return errors.New("backoff jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// Delay returns the amount of time to wait before the specified attempt.
+func (eb *ExponentialBackoff) Delay(attempt int) time.Duration {
+	initial := float64(eb.InitialMillis)
+	if initial <= 0 {
+		initial = float64(RetryBackoffMillis)
+	}
+	max := float64(eb.MaxMillis)
+	if max <= 0 {
+		max = float64(MaxRetryBackoffMillis)
+	}
+	mult := eb.Multiplier
+	if mult < 1 {
+		mult = 1.5
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := math.Min(initial*math.Pow(mult, float64(attempt-1)), max)
+
+	if eb.Jitter > 0 {
+		delay += delay * eb.Jitter * (2*eb.random() - 1)
+	}
+	return time.Duration(delay * float64(time.Millisecond))
+}
+
+func (eb *ExponentialBackoff) random() float64 {
+	eb.rndMux.Lock()
+	defer eb.rndMux.Unlock()
+	if eb.rnd == nil {
+		eb.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return eb.rnd.Float64()
+}
+
+// errRetryShutdown is returned by retry when the target is shut down while waiting
+// to retry.
+var errRetryShutdown = errors.New("shut down while waiting to retry")
+
+// retryAfterError wraps an error for which the server indicated how long to wait
+// before retrying, e.g. via a Retry-After header.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e retryAfterError) Error() string { return e.err.Error() }
+func (e retryAfterError) Unwrap() error { return e.err }
+
+// retry calls fn until it succeeds, returns an error that is not retryable, or
+// maxRetries retries have been made, waiting between attempts as determined by b.
+// If fn returns a `retryAfterError` the wait is at least the delay requested.
+// Waiting is aborted when shutdown is closed.
+func retry(b Backoff, maxRetries int, shutdown <-chan struct{}, fn func() (retryable bool, err error)) error {
+	var err error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := b.Delay(attempt)
+			var ra retryAfterError
+			if errors.As(err, &ra) && ra.delay > delay {
+				delay = ra.delay
+			}
+			select {
+			case <-shutdown:
+				return fmt.Errorf("%w: %v", errRetryShutdown, err)
+			case <-time.After(delay):
+			}
+		}
+
+		var retryable bool
+		if retryable, err = fn(); err == nil || !retryable {
+			return err
+		}
+	}
+	return fmt.Errorf("gave up after %d retries: %w", maxRetries, err)
+}
+
+// parseRetryAfter returns the delay from a Retry-After header, which can be either
+// a number of seconds or an HTTP date. Zero is returned if the header is missing or invalid.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package targets
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	eb := &ExponentialBackoff{InitialMillis: 100, MaxMillis: 1000, Multiplier: 2}
+	assert.Equal(t, 100*time.Millisecond, eb.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, eb.Delay(2))
+	assert.Equal(t, 800*time.Millisecond, eb.Delay(4))
+	assert.Equal(t, 1000*time.Millisecond, eb.Delay(5))
+	assert.Equal(t, 1000*time.Millisecond, eb.Delay(500))
+
+	eb.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := eb.Delay(2)
+		assert.GreaterOrEqual(t, int64(d), int64(100*time.Millisecond))
+		assert.LessOrEqual(t, int64(d), int64(300*time.Millisecond))
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		eb := &ExponentialBackoff{}
+		assert.Equal(t, time.Duration(RetryBackoffMillis)*time.Millisecond, eb.Delay(0))
+		assert.Equal(t, time.Duration(MaxRetryBackoffMillis)*time.Millisecond, eb.Delay(1000))
+	})
+
+	t.Run("config", func(t *testing.T) {
+		var opts TcpOptions
+		err := json.Unmarshal([]byte(`{"host":"localhost","port":514,"backoff":{"initial_millis":50,"multiplier":3,"jitter":2}}`), &opts)
+		require.NoError(t, err)
+		assert.Error(t, opts.CheckValid())
+
+		opts.Backoff.Jitter = 0
+		require.NoError(t, opts.CheckValid())
+		tcp := NewTcpTarget(&opts)
+		assert.Equal(t, 150*time.Millisecond, tcp.backoff.Delay(2))
+	})
+}
+
+func TestRetryFaultyWriter(t *testing.T) {
+	noDelay := &ExponentialBackoff{InitialMillis: 1, MaxMillis: 1}
+	write := func(fw *test.FaultyWriter) func() (bool, error) {
+		return func() (bool, error) {
+			_, err := fw.Write([]byte("data"))
+			return true, err
+		}
+	}
+
+	buf := &test.Buffer{}
+	fw := test.NewFaultyWriter(buf)
+	fw.FailNext(2)
+	err := retry(noDelay, 3, nil, write(fw))
+	require.NoError(t, err)
+	assert.Equal(t, 3, fw.Writes())
+	assert.Equal(t, "data", buf.String())
+
+	fw = test.NewFaultyWriter(nil)
+	fw.FailNext(5)
+	err = retry(noDelay, 3, nil, write(fw))
+	assert.True(t, errors.Is(err, test.ErrInjected))
+	assert.Equal(t, 4, fw.Writes())
+
+	shutdown := make(chan struct{})
+	close(shutdown)
+	fw = test.NewFaultyWriter(nil)
+	fw.FailEvery(1)
+	err = retry(&ExponentialBackoff{InitialMillis: 10000, MaxMillis: 10000}, 3, shutdown, write(fw))
+	assert.True(t, errors.Is(err, errRetryShutdown))
+	assert.Equal(t, 1, fw.Writes())
+}
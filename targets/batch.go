@@ -0,0 +1,140 @@
+package targets
+
+import (
+	"sync"
+	"time"
+)
+
+// BatchOptions determine how buffered targets group log records into batches
+// before sending. Zero values are replaced by defaults specific to each target.
+type BatchOptions struct {
+	// MaxBatchCount is the maximum number of records sent in one batch.
+	MaxBatchCount int `json:"max_batch_count,omitempty"`
+
+	// MaxBatchBytes is the maximum number of bytes of formatted records sent in one batch.
+	MaxBatchBytes int `json:"max_batch_bytes,omitempty"`
+
+	// FlushIntervalMillis is the maximum amount of time a record is buffered before
+	// the batch containing it is sent.
+	FlushIntervalMillis int64 `json:"flush_interval_millis,omitempty"`
+}
+
+// withDefaults returns a copy of the options with zero values replaced by defaults
+// and limits capped to the maximums.
+func (bo BatchOptions) withDefaults(def BatchOptions) BatchOptions {
+	if bo.MaxBatchCount <= 0 || bo.MaxBatchCount > def.MaxBatchCount {
+		bo.MaxBatchCount = def.MaxBatchCount
+	}
+	if bo.MaxBatchBytes <= 0 || bo.MaxBatchBytes > def.MaxBatchBytes {
+		bo.MaxBatchBytes = def.MaxBatchBytes
+	}
+	if bo.FlushIntervalMillis <= 0 {
+		bo.FlushIntervalMillis = def.FlushIntervalMillis
+	}
+	return bo
+}
+
+// batchItem is a formatted log record waiting to be sent.
+type batchItem struct {
+	data []byte
+	key  string // target specific, e.g. partition or ordering key.
+}
+
+// batcher buffers items and calls send with batches that respect the
+// configured count and size limits. Batches are sent when full, when the flush
+// interval elapses, or when flushed explicitly. Batches are always sent in order.
+type batcher struct {
+	opts BatchOptions
+	send func(items []batchItem) error
+
+	sendMux sync.Mutex // held while taking and sending a batch to keep batches in order
+
+	mux    sync.Mutex
+	items  []batchItem
+	size   int
+	timer  *time.Timer
+	report func(err interface{})
+}
+
+func newBatcher(opts BatchOptions, send func(items []batchItem) error) *batcher {
+	return &batcher{
+		opts: opts,
+		send: send,
+	}
+}
+
+// add buffers a copy of the data, sending a batch if the limits are reached.
+// Errors from sending triggered by the flush interval are passed to report.
+func (b *batcher) add(data []byte, key string, report func(err interface{})) error {
+	item := batchItem{data: make([]byte, len(data)), key: key}
+	copy(item.data, data)
+
+	var err error
+
+	b.mux.Lock()
+	b.report = report
+	fits := len(b.items) == 0 ||
+		(len(b.items)+1 <= b.opts.MaxBatchCount && b.size+len(item.data) <= b.opts.MaxBatchBytes)
+	b.mux.Unlock()
+
+	if !fits {
+		err = b.flush()
+	}
+
+	b.mux.Lock()
+	b.items = append(b.items, item)
+	b.size += len(item.data)
+	if len(b.items) == 1 {
+		b.startTimer()
+	}
+	full := len(b.items) >= b.opts.MaxBatchCount || b.size >= b.opts.MaxBatchBytes
+	b.mux.Unlock()
+
+	if full {
+		if errFlush := b.flush(); errFlush != nil {
+			err = errFlush
+		}
+	}
+	return err
+}
+
+// flush sends any buffered items.
+func (b *batcher) flush() error {
+	b.sendMux.Lock()
+	defer b.sendMux.Unlock()
+
+	b.mux.Lock()
+	items := b.items
+	b.items = nil
+	b.size = 0
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.mux.Unlock()
+
+	if len(items) == 0 {
+		return nil
+	}
+	return b.send(items)
+}
+
+// startTimer must be called with mux held.
+func (b *batcher) startTimer() {
+	interval := time.Duration(b.opts.FlushIntervalMillis) * time.Millisecond
+	if b.timer == nil {
+		b.timer = time.AfterFunc(interval, b.onTimer)
+		return
+	}
+	b.timer.Reset(interval)
+}
+
+func (b *batcher) onTimer() {
+	if err := b.flush(); err != nil {
+		b.mux.Lock()
+		report := b.report
+		b.mux.Unlock()
+		if report != nil {
+			report(err)
+		}
+	}
+}
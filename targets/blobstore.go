@@ -0,0 +1,64 @@
+package targets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileBlobStore is a `logr.BlobStore` that saves each field value offloaded via
+// `logr.OffloadFields` to a file in a directory. Files are named by the SHA-256
+// hash of their contents, so a value logged repeatedly is stored once.
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore creates a FileBlobStore saving to dir, which is created if it
+// does not exist.
+func NewFileBlobStore(dir string) (*FileBlobStore, error) {
+	if dir == "" {
+		return nil, errors.New("blob store directory cannot be empty")
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	return &FileBlobStore{dir: dir}, nil
+}
+
+// Store saves the value and returns the path of the file containing it.
+func (s *FileBlobStore) Store(key string, value []byte) (string, error) {
+	sum := sha256.Sum256(value)
+	path := filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := writeFileAtomic(path, value); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writeFileAtomic writes data to a temp file in the same directory then renames
+// it, so a partially written file is never visible at path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package targets_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBlobStore(t *testing.T) {
+	_, err := targets.NewFileBlobStore("")
+	assert.Error(t, err)
+
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := targets.NewFileBlobStore(dir)
+	require.NoError(t, err)
+
+	ref, err := store.Store("body", []byte("request body"))
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(ref))
+
+	data, err := ioutil.ReadFile(ref)
+	require.NoError(t, err)
+	assert.Equal(t, "request body", string(data))
+
+	// identical values are stored once.
+	ref2, err := store.Store("other", []byte("request body"))
+	require.NoError(t, err)
+	assert.Equal(t, ref, ref2)
+
+	ref3, err := store.Store("body", []byte("another body"))
+	require.NoError(t, err)
+	assert.NotEqual(t, ref, ref3)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
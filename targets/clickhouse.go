@@ -0,0 +1,268 @@
+package targets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	ClickHouseDefaultEndpoint            = "http://localhost:8123"
+	ClickHouseDefaultMaxBatchCount       = 10000
+	ClickHouseDefaultMaxBatchBytes       = 16 * 1024 * 1024
+	ClickHouseDefaultFlushIntervalMillis = 2000
+	ClickHouseDefaultMaxRetries          = 5
+	ClickHouseRequestTimeoutSecs         = 60
+
+	clickhouseTimeFormat = "2006-01-02 15:04:05.000000"
+)
+
+// DefaultClickHouseColumns is the schema mapping used when none is provided, suitable for:
+//
+//	CREATE TABLE logs (
+//	    timestamp DateTime64(6),
+//	    level LowCardinality(String),
+//	    message String,
+//	    fields Map(String, String)
+//	) ENGINE = MergeTree ORDER BY timestamp
+func DefaultClickHouseColumns() []Column {
+	return []Column{
+		{Name: "timestamp", Source: ColumnSourceTime},
+		{Name: "level", Source: ColumnSourceLevel},
+		{Name: "message", Source: ColumnSourceMsg},
+		{Name: "fields", Source: ColumnSourceFields},
+	}
+}
+
+// ClickHouseOptions provides parameters for the ClickHouse target.
+type ClickHouseOptions struct {
+	// Endpoint is the URL of the ClickHouse HTTP interface. Defaults to ClickHouseDefaultEndpoint.
+	Endpoint string `json:"endpoint,omitempty"`
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Columns maps table columns to parts of the log record. Defaults to `DefaultClickHouseColumns`.
+	Columns []Column `json:"columns,omitempty"`
+
+	// AsyncInsert, when true, uses ClickHouse asynchronous inserts so the server batches
+	// small inserts from many clients.
+	AsyncInsert bool `json:"async_insert,omitempty"`
+
+	// Compress, when true, gzips request bodies.
+	Compress bool `json:"compress,omitempty"`
+
+	// MaxRetries is the maximum number of times a batch is retried on transient errors.
+	// Defaults to ClickHouseDefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between retries. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	BatchOptions
+}
+
+var clickhouseIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// CheckValid returns an error if the options are invalid.
+func (co ClickHouseOptions) CheckValid() error {
+	if !clickhouseIdentifier.MatchString(co.Table) {
+		return errors.New("missing or invalid table")
+	}
+	if co.Database != "" && !clickhouseIdentifier.MatchString(co.Database) {
+		return errors.New("invalid database")
+	}
+	if co.Endpoint != "" {
+		if _, err := url.Parse(co.Endpoint); err != nil {
+			return fmt.Errorf("invalid endpoint: %w", err)
+		}
+	}
+	for _, col := range co.Columns {
+		if !clickhouseIdentifier.MatchString(col.Name) {
+			return fmt.Errorf("invalid column name %q", col.Name)
+		}
+		if err := col.CheckValid(); err != nil {
+			return err
+		}
+	}
+	if co.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if co.Backoff != nil {
+		return co.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// ClickHouse batch-inserts log records into a ClickHouse table via the HTTP interface.
+type ClickHouse struct {
+	options  ClickHouseOptions
+	url      string
+	backoff  Backoff
+	client   *http.Client
+	batch    *batcher
+	shutdown chan struct{}
+
+	shutdownOnce sync.Once
+}
+
+// NewClickHouseTarget creates a target capable of inserting log records into ClickHouse.
+func NewClickHouseTarget(options ClickHouseOptions) (*ClickHouse, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	ch := &ClickHouse{
+		options:  options,
+		backoff:  DefaultBackoff(),
+		client:   &http.Client{Timeout: time.Second * ClickHouseRequestTimeoutSecs},
+		shutdown: make(chan struct{}),
+	}
+	if options.Backoff != nil {
+		ch.backoff = options.Backoff
+	}
+	if ch.options.MaxRetries == 0 {
+		ch.options.MaxRetries = ClickHouseDefaultMaxRetries
+	}
+	if len(ch.options.Columns) == 0 {
+		ch.options.Columns = DefaultClickHouseColumns()
+	}
+
+	table := options.Table
+	if options.Database != "" {
+		table = options.Database + "." + table
+	}
+	names := make([]string, 0, len(ch.options.Columns))
+	for _, col := range ch.options.Columns {
+		names = append(names, col.Name)
+	}
+
+	endpoint := options.Endpoint
+	if endpoint == "" {
+		endpoint = ClickHouseDefaultEndpoint
+	}
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf("INSERT INTO %s (%s) FORMAT JSONEachRow", table, strings.Join(names, ", ")))
+	if options.AsyncInsert {
+		query.Set("async_insert", "1")
+		query.Set("wait_for_async_insert", "1")
+	}
+	ch.url = strings.TrimSuffix(endpoint, "/") + "/?" + query.Encode()
+
+	ch.batch = newBatcher(options.BatchOptions.withDefaults(BatchOptions{
+		MaxBatchCount:       ClickHouseDefaultMaxBatchCount,
+		MaxBatchBytes:       ClickHouseDefaultMaxBatchBytes,
+		FlushIntervalMillis: ClickHouseDefaultFlushIntervalMillis,
+	}), ch.send)
+	return ch, nil
+}
+
+// Init is called once to initialize the target.
+func (ch *ClickHouse) Init() error {
+	return nil
+}
+
+// Write converts the log record to a row and buffers it to be inserted with the next batch.
+func (ch *ClickHouse) Write(p []byte, rec *logr.LogRec) (int, error) {
+	row, err := ch.row(p, rec)
+	if err != nil {
+		return 0, err
+	}
+	if err = ch.batch.add(row, "", rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// row builds a JSONEachRow row using the column mapping.
+func (ch *ClickHouse) row(p []byte, rec *logr.LogRec) ([]byte, error) {
+	data, err := json.Marshal(columnRow(ch.options.Columns, p, rec, clickhouseTimeFormat))
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Flush inserts any buffered log records.
+func (ch *ClickHouse) Flush() error {
+	return ch.batch.flush()
+}
+
+// Shutdown inserts any buffered log records.
+func (ch *ClickHouse) Shutdown() error {
+	err := ch.batch.flush()
+	ch.shutdownOnce.Do(func() { close(ch.shutdown) })
+	return err
+}
+
+// String returns a string representation of this target.
+func (ch *ClickHouse) String() string {
+	return fmt.Sprintf("ClickHouseTarget[%s]", ch.options.Table)
+}
+
+func (ch *ClickHouse) send(items []batchItem) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var zw *gzip.Writer
+	if ch.options.Compress {
+		zw = gzip.NewWriter(&body)
+		w = zw
+	}
+	for _, item := range items {
+		_, _ = w.Write(item.data)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+
+	err := retry(ch.backoff, ch.options.MaxRetries, ch.shutdown, func() (bool, error) {
+		return ch.insert(body.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("%s could not insert %d records: %w", ch, len(items), err)
+	}
+	return nil
+}
+
+func (ch *ClickHouse) insert(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, ch.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if ch.options.Username != "" {
+		req.Header.Set("X-ClickHouse-User", ch.options.Username)
+		req.Header.Set("X-ClickHouse-Key", ch.options.Password)
+	}
+	if ch.options.Compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := ch.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+	err = fmt.Errorf("insert failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
@@ -0,0 +1,91 @@
+package targets
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickHouseTarget(t *testing.T) {
+	var mux sync.Mutex
+	var queries []string
+	var rows []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+		if r.Header.Get("X-ClickHouse-User") != "logr" || r.Header.Get("X-ClickHouse-Key") != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		queries = append(queries, r.URL.Query().Get("query"))
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			rows = append(rows, row)
+		}
+	}))
+	defer server.Close()
+
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	ch, err := NewClickHouseTarget(ClickHouseOptions{
+		Endpoint: server.URL,
+		Database: "app",
+		Table:    "logs",
+		Username: "logr",
+		Password: "pw",
+		Columns: []Column{
+			{Name: "ts", Source: "time"},
+			{Name: "lvl", Source: "level"},
+			{Name: "line", Source: "formatted"},
+			{Name: "user_id", Source: "field:user_id"},
+			{Name: "attempts", Source: "field:attempts"},
+			{Name: "attrs", Source: "fields"},
+		},
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(ch, "clickhouse_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user_id", "u1"))
+	logger.Info("login", logr.Int("attempts", 3))
+	logger.Error("logout")
+	require.NoError(t, lgr.Shutdown())
+
+	mux.Lock()
+	defer mux.Unlock()
+	require.Equal(t, []string{"INSERT INTO app.logs (ts, lvl, line, user_id, attempts, attrs) FORMAT JSONEachRow"}, queries)
+	require.Len(t, rows, 2)
+
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{6}$`, rows[0]["ts"])
+	assert.Equal(t, "info", rows[0]["lvl"])
+	assert.Contains(t, rows[0]["line"], "login")
+	assert.Equal(t, "u1", rows[0]["user_id"])
+	assert.Equal(t, float64(3), rows[0]["attempts"])
+	assert.Equal(t, map[string]interface{}{"user_id": "u1", "attempts": "3"}, rows[0]["attrs"])
+	assert.Nil(t, rows[1]["attempts"])
+}
+
+func TestClickHouseOptionsCheckValid(t *testing.T) {
+	assert.NoError(t, ClickHouseOptions{Table: "logs"}.CheckValid())
+	assert.Error(t, ClickHouseOptions{Table: "logs; DROP TABLE x"}.CheckValid())
+	assert.Error(t, ClickHouseOptions{Table: "logs", Columns: []Column{{Name: "a", Source: "field:"}}}.CheckValid())
+	assert.Error(t, ClickHouseOptions{Table: "logs", Columns: []Column{{Name: "a", Source: "bogus"}}}.CheckValid())
+}
@@ -0,0 +1,102 @@
+package targets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// Column sources for `Column`.
+const (
+	ColumnSourceTime      = "time"      // record timestamp
+	ColumnSourceLevel     = "level"     // level name
+	ColumnSourceMsg       = "msg"       // message
+	ColumnSourceFormatted = "formatted" // output of the target's formatter
+	ColumnSourceFields    = "fields"    // all fields as an object of strings
+	ColumnSourceField     = "field:"    // prefix for a single field, e.g. "field:user_id"
+)
+
+// Column maps a table column to a part of the log record, for targets that
+// insert records into tables.
+type Column struct {
+	Name string `json:"name"`
+
+	// Source is one of "time", "level", "msg", "formatted", "fields", or "field:<key>".
+	Source string `json:"source"`
+}
+
+// CheckValid returns an error if the column source is invalid.
+func (c Column) CheckValid() error {
+	if c.Name == "" {
+		return errors.New("missing column name")
+	}
+	switch c.Source {
+	case ColumnSourceTime, ColumnSourceLevel, ColumnSourceMsg, ColumnSourceFormatted, ColumnSourceFields:
+		return nil
+	}
+	if !strings.HasPrefix(c.Source, ColumnSourceField) || len(c.Source) == len(ColumnSourceField) {
+		return fmt.Errorf("invalid source %q for column %s", c.Source, c.Name)
+	}
+	return nil
+}
+
+// columnRow returns the column values for the log record, formatting the
+// timestamp using timeFormat.
+func columnRow(columns []Column, p []byte, rec *logr.LogRec, timeFormat string) map[string]interface{} {
+	row := make(map[string]interface{}, len(columns))
+	for _, col := range columns {
+		switch col.Source {
+		case ColumnSourceTime:
+			row[col.Name] = rec.Time().UTC().Format(timeFormat)
+		case ColumnSourceLevel:
+			row[col.Name] = rec.Level().Name
+		case ColumnSourceMsg:
+			row[col.Name] = rec.Msg()
+		case ColumnSourceFormatted:
+			row[col.Name] = string(bytes.TrimRight(p, "\n"))
+		case ColumnSourceFields:
+			fields := rec.Fields()
+			m := make(map[string]string, len(fields))
+			for _, f := range fields {
+				var sb strings.Builder
+				_ = f.ValueString(&sb, neverQuote)
+				m[f.Key] = sb.String()
+			}
+			row[col.Name] = m
+		default:
+			row[col.Name] = typedFieldValue(rec, strings.TrimPrefix(col.Source, ColumnSourceField))
+		}
+	}
+	return row
+}
+
+// typedFieldValue returns the field value with numeric and boolean types preserved,
+// or nil if the record does not contain the field.
+func typedFieldValue(rec *logr.LogRec, key string) interface{} {
+	fields := rec.Fields()
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Key != key {
+			continue
+		}
+		switch f.Type {
+		case logr.BoolType:
+			return f.Integer != 0
+		case logr.Int64Type, logr.Int32Type, logr.IntType, logr.DurationType, logr.TimestampMillisType:
+			return f.Integer
+		case logr.Uint64Type, logr.Uint32Type, logr.UintType:
+			return uint64(f.Integer)
+		case logr.Float64Type, logr.Float32Type:
+			return f.Float
+		case logr.StringType:
+			return f.String
+		}
+		var sb strings.Builder
+		_ = f.ValueString(&sb, neverQuote)
+		return sb.String()
+	}
+	return nil
+}
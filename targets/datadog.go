@@ -0,0 +1,307 @@
+package targets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// Limits imposed by the Datadog logs intake API.
+const (
+	DatadogMaxEntriesPerRequest = 1000
+	DatadogMaxRequestBytes      = 5 * 1000 * 1000
+	DatadogMaxEntryBytes        = 1000 * 1000
+
+	DatadogDefaultSite                = "datadoghq.com"
+	DatadogDefaultMaxRetries          = 5
+	DatadogDefaultFlushIntervalMillis = 1000
+	DatadogRequestTimeoutSecs         = 30
+)
+
+// DatadogOptions provides parameters for the Datadog target.
+type DatadogOptions struct {
+	// APIKey is the Datadog API key. Defaults to the DD_API_KEY environment variable.
+	APIKey string `json:"api_key,omitempty"`
+
+	// Site is the Datadog site, e.g. "datadoghq.eu". Defaults to DatadogDefaultSite.
+	Site string `json:"site,omitempty"`
+
+	// Endpoint overrides the intake URL derived from Site.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Service, Source, Tags and Hostname set the reserved attributes "service",
+	// "ddsource", "ddtags" and "hostname". Each is a template, e.g. "{service}",
+	// see `fieldTemplate`. Attributes already present in the record are not overwritten.
+	Service  string `json:"service,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Tags     string `json:"tags,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+
+	// Compress, when true, gzips request bodies.
+	Compress bool `json:"compress,omitempty"`
+
+	// MaxRetries is the maximum number of times a batch is retried when rate limited
+	// or on server errors. Defaults to DatadogDefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between retries. A longer delay requested via
+	// Retry-After is honored. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (do DatadogOptions) CheckValid() error {
+	if do.APIKey == "" && os.Getenv("DD_API_KEY") == "" {
+		return errors.New("missing api_key")
+	}
+	for name, tmpl := range map[string]string{"service": do.Service, "source": do.Source, "tags": do.Tags, "hostname": do.Hostname} {
+		if _, err := parseFieldTemplate(tmpl); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	if do.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if do.Backoff != nil {
+		return do.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// Datadog ships log records to the Datadog logs intake API.
+//
+// If the formatted record is a JSON object, e.g. when using `formatters.JSON`, its
+// attributes are sent as-is with "msg" and "level" mapped to the reserved "message"
+// and "status" attributes. Otherwise the formatted record is sent as the message.
+type Datadog struct {
+	options  DatadogOptions
+	apiKey   string
+	url      string
+	attrs    []datadogAttr
+	backoff  Backoff
+	client   *http.Client
+	batch    *batcher
+	shutdown chan struct{}
+
+	shutdownOnce sync.Once
+}
+
+type datadogAttr struct {
+	name string
+	tmpl *fieldTemplate
+}
+
+// NewDatadogTarget creates a target capable of outputting log records to Datadog.
+func NewDatadogTarget(options DatadogOptions) (*Datadog, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	dd := &Datadog{
+		options:  options,
+		apiKey:   options.APIKey,
+		backoff:  DefaultBackoff(),
+		client:   &http.Client{Timeout: time.Second * DatadogRequestTimeoutSecs},
+		shutdown: make(chan struct{}),
+	}
+	if dd.apiKey == "" {
+		dd.apiKey = os.Getenv("DD_API_KEY")
+	}
+	if options.Backoff != nil {
+		dd.backoff = options.Backoff
+	}
+	if dd.options.MaxRetries == 0 {
+		dd.options.MaxRetries = DatadogDefaultMaxRetries
+	}
+
+	dd.url = options.Endpoint
+	if dd.url == "" {
+		site := options.Site
+		if site == "" {
+			site = DatadogDefaultSite
+		}
+		dd.url = fmt.Sprintf("https://http-intake.logs.%s/api/v2/logs", site)
+	}
+
+	for _, a := range []struct{ name, tmpl string }{
+		{"service", options.Service}, {"ddsource", options.Source}, {"ddtags", options.Tags}, {"hostname", options.Hostname},
+	} {
+		if tmpl, _ := parseFieldTemplate(a.tmpl); !tmpl.isEmpty() {
+			dd.attrs = append(dd.attrs, datadogAttr{name: a.name, tmpl: tmpl})
+		}
+	}
+
+	dd.batch = newBatcher(options.BatchOptions.withDefaults(BatchOptions{
+		MaxBatchCount:       DatadogMaxEntriesPerRequest,
+		MaxBatchBytes:       DatadogMaxRequestBytes - 2, // allow for the enclosing array
+		FlushIntervalMillis: DatadogDefaultFlushIntervalMillis,
+	}), dd.send)
+	return dd, nil
+}
+
+// Init is called once to initialize the target.
+func (dd *Datadog) Init() error {
+	return nil
+}
+
+// Write converts the formatted log record to a Datadog log entry and buffers it
+// to be sent with the next batch.
+func (dd *Datadog) Write(p []byte, rec *logr.LogRec) (int, error) {
+	entry, err := dd.entry(p, rec)
+	if err != nil {
+		return 0, err
+	}
+	if len(entry) > DatadogMaxEntryBytes {
+		return 0, fmt.Errorf("log entry of %d bytes exceeds %s limit of %d bytes", len(entry), dd, DatadogMaxEntryBytes)
+	}
+	if err = dd.batch.add(entry, "", rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// entry builds the JSON log entry, mapping reserved attributes.
+func (dd *Datadog) entry(p []byte, rec *logr.LogRec) ([]byte, error) {
+	p = bytes.TrimSpace(p)
+
+	var attrs map[string]json.RawMessage
+	if len(p) == 0 || p[0] != '{' || json.Unmarshal(p, &attrs) != nil {
+		attrs = map[string]json.RawMessage{"message": mustMarshal(string(p))}
+	} else {
+		if msg, ok := attrs["msg"]; ok {
+			if _, exists := attrs["message"]; !exists {
+				attrs["message"] = msg
+				delete(attrs, "msg")
+			}
+		}
+		delete(attrs, "level")
+	}
+	attrs["status"] = mustMarshal(datadogStatus(rec.Level()))
+
+	for _, a := range dd.attrs {
+		if _, exists := attrs[a.name]; !exists {
+			if v := a.tmpl.execute(rec); v != "" {
+				attrs[a.name] = mustMarshal(v)
+			}
+		}
+	}
+	return json.Marshal(attrs)
+}
+
+// datadogStatus maps a Logr level to a Datadog status.
+func datadogStatus(level logr.Level) string {
+	switch level.ID {
+	case logr.Panic.ID:
+		return "emergency"
+	case logr.Fatal.ID:
+		return "critical"
+	case logr.Error.ID:
+		return "error"
+	case logr.Warn.ID:
+		return "warning"
+	case logr.Info.ID:
+		return "info"
+	case logr.Debug.ID, logr.Trace.ID:
+		return "debug"
+	}
+	return strings.ToLower(strings.TrimSpace(level.Name))
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// Flush sends any buffered log records.
+func (dd *Datadog) Flush() error {
+	return dd.batch.flush()
+}
+
+// Shutdown sends any buffered log records.
+func (dd *Datadog) Shutdown() error {
+	err := dd.batch.flush()
+	dd.shutdownOnce.Do(func() { close(dd.shutdown) })
+	return err
+}
+
+// String returns a string representation of this target.
+func (dd *Datadog) String() string {
+	return fmt.Sprintf("DatadogTarget[%s]", dd.url)
+}
+
+func (dd *Datadog) send(items []batchItem) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var zw *gzip.Writer
+	if dd.options.Compress {
+		zw = gzip.NewWriter(&body)
+		w = zw
+	}
+
+	_, _ = w.Write([]byte{'['})
+	for i, item := range items {
+		if i > 0 {
+			_, _ = w.Write([]byte{','})
+		}
+		_, _ = w.Write(item.data)
+	}
+	_, _ = w.Write([]byte{']'})
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+
+	err := retry(dd.backoff, dd.options.MaxRetries, dd.shutdown, func() (bool, error) {
+		return dd.post(body.Bytes())
+	})
+	if err != nil {
+		return fmt.Errorf("%s could not send %d records: %w", dd, len(items), err)
+	}
+	return nil
+}
+
+func (dd *Datadog) post(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, dd.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", dd.apiKey)
+	if dd.options.Compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := dd.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("intake failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, retryAfterError{err: err, delay: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		return true, err
+	}
+	return false, err
+}
@@ -0,0 +1,111 @@
+package targets
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type datadogServer struct {
+	mux      sync.Mutex
+	requests int
+	entries  []map[string]interface{}
+	apiKeys  []string
+}
+
+func (ds *datadogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ds.mux.Lock()
+	defer ds.mux.Unlock()
+
+	ds.requests++
+	ds.apiKeys = append(ds.apiKeys, r.Header.Get("DD-API-KEY"))
+	if ds.requests == 1 {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var entries []map[string]interface{}
+	if err = json.NewDecoder(zr).Decode(&entries); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ds.entries = append(ds.entries, entries...)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestDatadogTarget(t *testing.T) {
+	ds := &datadogServer{}
+	server := httptest.NewServer(ds)
+	defer server.Close()
+
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	dd, err := NewDatadogTarget(DatadogOptions{
+		APIKey:   "key",
+		Endpoint: server.URL,
+		Service:  "{service}",
+		Source:   "go",
+		Tags:     "env:test,tenant:{tenant}",
+		Compress: true,
+		Backoff:  &ExponentialBackoff{InitialMillis: 1, MaxMillis: 5},
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(dd, "datadog_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("service", "api"), logr.String("tenant", "t1"))
+	logger.Info("hello", logr.Int("n", 1))
+	logger.Warn("careful")
+
+	start := time.Now()
+	require.NoError(t, lgr.Shutdown())
+
+	ds.mux.Lock()
+	defer ds.mux.Unlock()
+	assert.Equal(t, 2, ds.requests)
+	assert.True(t, time.Since(start) >= time.Second, "Retry-After should be honored")
+	assert.Equal(t, []string{"key", "key"}, ds.apiKeys)
+	require.Len(t, ds.entries, 2)
+
+	assert.Equal(t, "hello", ds.entries[0]["message"])
+	assert.Equal(t, "info", ds.entries[0]["status"])
+	assert.Equal(t, "api", ds.entries[0]["service"])
+	assert.Equal(t, "go", ds.entries[0]["ddsource"])
+	assert.Equal(t, "env:test,tenant:t1", ds.entries[0]["ddtags"])
+	assert.Equal(t, float64(1), ds.entries[0]["n"])
+	assert.NotContains(t, ds.entries[0], "msg")
+	assert.NotContains(t, ds.entries[0], "level")
+	assert.Equal(t, "warning", ds.entries[1]["status"])
+}
+
+func TestDatadogPlainEntry(t *testing.T) {
+	dd, err := NewDatadogTarget(DatadogOptions{APIKey: "key"})
+	require.NoError(t, err)
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	rec := logr.NewLogRec(logr.Error, lgr.NewLogger(), "boom", nil, false)
+
+	entry, err := dd.entry([]byte("error boom\n"), rec)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message":"error boom","status":"error"}`, string(entry))
+}
@@ -76,3 +76,9 @@ func (f *File) Write(p []byte, rec *logr.LogRec) (int, error) {
 func (f *File) Shutdown() error {
 	return f.out.Close()
 }
+
+// Reopen closes the file so that it is reopened on the next write. This allows
+// external tools such as logrotate to move the file aside.
+func (f *File) Reopen() error {
+	return f.out.Close()
+}
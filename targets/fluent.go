@@ -0,0 +1,463 @@
+package targets
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	FluentDefaultPort                = 24224
+	FluentDefaultMessageKey          = "log"
+	FluentDefaultFlushIntervalMillis = 1000
+	FluentDefaultMaxRetries          = 5
+	FluentMaxBatchCount              = 10000
+	FluentMaxBatchBytes              = 8 * 1024 * 1024
+	FluentAckTimeoutSecs             = 30
+)
+
+// FluentOptions provides parameters for the Fluent Forward target.
+type FluentOptions struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"` // defaults to FluentDefaultPort
+
+	TLS      bool   `json:"tls,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+
+	// Tag is a template for the Fluent tag, e.g. "app.{level}". See `fieldTemplate`.
+	Tag string `json:"tag"`
+
+	// Structured, when true, sends the record's level, message and fields as a map
+	// instead of the formatted output. The target's formatter is then only used to
+	// determine whether the record is output.
+	Structured bool `json:"structured,omitempty"`
+
+	// MessageKey is the record key holding the formatted output when not structured.
+	// Defaults to FluentDefaultMessageKey.
+	MessageKey string `json:"message_key,omitempty"`
+
+	// RequireAck, when true, requests an acknowledgement for each chunk and resends
+	// chunks that are not acknowledged, providing at-least-once delivery.
+	RequireAck bool `json:"require_ack,omitempty"`
+
+	// SharedKey enables the secure forward handshake. Username and Password are
+	// sent if the server requires user authentication.
+	SharedKey    string `json:"shared_key,omitempty"`
+	SelfHostname string `json:"self_hostname,omitempty"` // defaults to os.Hostname
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password,omitempty"`
+
+	// MaxRetries is the maximum number of times a chunk is resent. Defaults to FluentDefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between reconnect attempts. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (fo FluentOptions) CheckValid() error {
+	if fo.Host == "" {
+		return errors.New("missing host")
+	}
+	if fo.Port < 0 || fo.Port > 65535 {
+		return errors.New("invalid port")
+	}
+	if fo.Tag == "" {
+		return errors.New("missing tag")
+	}
+	if _, err := parseFieldTemplate(fo.Tag); err != nil {
+		return fmt.Errorf("invalid tag: %w", err)
+	}
+	if fo.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if fo.Backoff != nil {
+		return fo.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// Fluent outputs log records to a Fluentd or Fluent Bit collector using the
+// Forward protocol.
+type Fluent struct {
+	options  FluentOptions
+	addr     string
+	tag      *fieldTemplate
+	backoff  Backoff
+	batch    *batcher
+	shutdown chan struct{}
+
+	mux  sync.Mutex // protects conn, held while sending a chunk
+	conn net.Conn
+	rd   *bufio.Reader
+
+	shutdownOnce sync.Once
+}
+
+// NewFluentTarget creates a target capable of outputting log records to a Fluent collector.
+func NewFluentTarget(options FluentOptions) (*Fluent, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	f := &Fluent{
+		options:  options,
+		backoff:  DefaultBackoff(),
+		shutdown: make(chan struct{}),
+	}
+	if f.options.Port == 0 {
+		f.options.Port = FluentDefaultPort
+	}
+	f.addr = net.JoinHostPort(options.Host, fmt.Sprintf("%d", f.options.Port))
+	f.tag, _ = parseFieldTemplate(options.Tag)
+	if options.Backoff != nil {
+		f.backoff = options.Backoff
+	}
+	if f.options.MessageKey == "" {
+		f.options.MessageKey = FluentDefaultMessageKey
+	}
+	if f.options.MaxRetries == 0 {
+		f.options.MaxRetries = FluentDefaultMaxRetries
+	}
+	if f.options.SelfHostname == "" {
+		f.options.SelfHostname, _ = os.Hostname()
+	}
+
+	f.batch = newBatcher(options.BatchOptions.withDefaults(BatchOptions{
+		MaxBatchCount:       FluentMaxBatchCount,
+		MaxBatchBytes:       FluentMaxBatchBytes,
+		FlushIntervalMillis: FluentDefaultFlushIntervalMillis,
+	}), f.send)
+	return f, nil
+}
+
+// Init is called once to initialize the target.
+func (f *Fluent) Init() error {
+	return nil
+}
+
+// Write encodes the log record as a Forward protocol entry and buffers it to be
+// sent with the next chunk.
+func (f *Fluent) Write(p []byte, rec *logr.LogRec) (int, error) {
+	entry := make([]byte, 0, len(p)+64)
+	entry = appendMsgpackArrayHeader(entry, 2)
+	entry = appendMsgpackEventTime(entry, rec.Time())
+
+	if f.options.Structured {
+		entry = appendFluentRecord(entry, rec)
+	} else {
+		entry = appendMsgpackMapHeader(entry, 1)
+		entry = appendMsgpackString(entry, f.options.MessageKey)
+		entry = appendMsgpackString(entry, strings.TrimSuffix(string(p), "\n"))
+	}
+
+	if err := f.batch.add(entry, f.tag.execute(rec), rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendFluentRecord appends the record's level, message and fields as a map.
+func appendFluentRecord(b []byte, rec *logr.LogRec) []byte {
+	fields := rec.Fields()
+	b = appendMsgpackMapHeader(b, len(fields)+2)
+	b = appendMsgpackString(b, "level")
+	b = appendMsgpackString(b, rec.Level().Name)
+	b = appendMsgpackString(b, "msg")
+	b = appendMsgpackString(b, rec.Msg())
+
+	for _, field := range fields {
+		b = appendMsgpackString(b, field.Key)
+		switch field.Type {
+		case logr.StringType:
+			b = appendMsgpackString(b, field.String)
+		case logr.BoolType:
+			b = appendMsgpackBool(b, field.Integer != 0)
+		case logr.Int64Type, logr.Int32Type, logr.IntType:
+			b = appendMsgpackInt(b, field.Integer)
+		case logr.Uint64Type, logr.Uint32Type, logr.UintType:
+			b = appendMsgpackUint(b, uint64(field.Integer))
+		case logr.Float64Type, logr.Float32Type:
+			b = appendMsgpackFloat(b, field.Float)
+		default:
+			var sb strings.Builder
+			_ = field.ValueString(&sb, neverQuote)
+			b = appendMsgpackString(b, sb.String())
+		}
+	}
+	return b
+}
+
+// Flush sends any buffered log records.
+func (f *Fluent) Flush() error {
+	return f.batch.flush()
+}
+
+// Shutdown sends any buffered log records and closes the connection.
+func (f *Fluent) Shutdown() error {
+	err := f.batch.flush()
+	f.shutdownOnce.Do(func() { close(f.shutdown) })
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.closeConn()
+	return err
+}
+
+// String returns a string representation of this target.
+func (f *Fluent) String() string {
+	return fmt.Sprintf("FluentTarget[%s]", f.addr)
+}
+
+// send writes the batch as one Forward mode message per run of entries with the same tag.
+func (f *Fluent) send(items []batchItem) error {
+	for len(items) > 0 {
+		n := 1
+		for n < len(items) && items[n].key == items[0].key {
+			n++
+		}
+		if err := f.sendChunk(items[0].key, items[:n]); err != nil {
+			return err
+		}
+		items = items[n:]
+	}
+	return nil
+}
+
+func (f *Fluent) sendChunk(tag string, items []batchItem) error {
+	size := 0
+	for _, item := range items {
+		size += len(item.data)
+	}
+
+	msg := make([]byte, 0, size+len(tag)+64)
+	msg = appendMsgpackArrayHeader(msg, 3)
+	msg = appendMsgpackString(msg, tag)
+	msg = appendMsgpackArrayHeader(msg, len(items))
+	for _, item := range items {
+		msg = append(msg, item.data...)
+	}
+
+	var chunk string
+	if f.options.RequireAck {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+		msg = appendMsgpackMapHeader(msg, 2)
+		msg = appendMsgpackString(msg, "size")
+		msg = appendMsgpackInt(msg, int64(len(items)))
+		msg = appendMsgpackString(msg, "chunk")
+		msg = appendMsgpackString(msg, chunk)
+	} else {
+		msg = appendMsgpackMapHeader(msg, 1)
+		msg = appendMsgpackString(msg, "size")
+		msg = appendMsgpackInt(msg, int64(len(items)))
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	err := retry(f.backoff, f.options.MaxRetries, f.shutdown, func() (bool, error) {
+		err := f.write(msg, chunk)
+		if err != nil {
+			f.closeConn()
+		}
+		return true, err
+	})
+	if err != nil {
+		return fmt.Errorf("%s could not send %d records: %w", f, len(items), err)
+	}
+	return nil
+}
+
+// write sends one message on the connection, connecting first if needed, and
+// waits for the ack if requested. Must be called with mux held.
+func (f *Fluent) write(msg []byte, chunk string) error {
+	if f.conn == nil {
+		if err := f.connect(); err != nil {
+			return err
+		}
+	}
+
+	_ = f.conn.SetWriteDeadline(time.Now().Add(time.Second * WriteTimeoutSecs))
+	if _, err := f.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	_ = f.conn.SetReadDeadline(time.Now().Add(time.Second * FluentAckTimeoutSecs))
+	resp, err := f.readMap()
+	if err != nil {
+		return fmt.Errorf("no ack received: %w", err)
+	}
+	if ack, _ := resp["ack"].(string); ack != chunk {
+		return fmt.Errorf("unexpected ack %q", ack)
+	}
+	return nil
+}
+
+// closeConn must be called with mux held.
+func (f *Fluent) closeConn() {
+	if f.conn != nil {
+		f.conn.Close()
+		f.conn = nil
+		f.rd = nil
+	}
+}
+
+// connect dials the collector and performs the handshake if a shared key is configured.
+// Must be called with mux held.
+func (f *Fluent) connect() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*DialTimeoutSecs)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", f.addr)
+	if err != nil {
+		return err
+	}
+	if f.options.TLS {
+		tlsconfig := &tls.Config{
+			ServerName:         f.options.Host,
+			InsecureSkipVerify: f.options.Insecure,
+		}
+		if f.options.Cert != "" {
+			pool, errPool := GetCertPool(f.options.Cert)
+			if errPool != nil {
+				conn.Close()
+				return errPool
+			}
+			tlsconfig.RootCAs = pool
+		}
+		conn = tls.Client(conn, tlsconfig)
+	}
+
+	f.conn = conn
+	f.rd = bufio.NewReader(conn)
+
+	if f.options.SharedKey != "" {
+		_ = conn.SetDeadline(time.Now().Add(time.Second * DialTimeoutSecs))
+		if err = f.handshake(); err != nil {
+			f.closeConn()
+			return fmt.Errorf("handshake failed: %w", err)
+		}
+		_ = conn.SetDeadline(time.Time{})
+	}
+	return nil
+}
+
+// handshake performs the secure forward HELO/PING/PONG exchange.
+func (f *Fluent) handshake() error {
+	helo, err := f.readArray("HELO")
+	if err != nil {
+		return err
+	}
+	if len(helo) < 2 {
+		return errors.New("invalid HELO")
+	}
+	opts, _ := helo[1].(map[string]interface{})
+	nonce := msgpackBytes(opts["nonce"])
+	authSalt := msgpackBytes(opts["auth"])
+
+	var salt [16]byte
+	if _, err = rand.Read(salt[:]); err != nil {
+		return err
+	}
+	hostname := f.options.SelfHostname
+
+	var passwordDigest string
+	if len(authSalt) > 0 {
+		passwordDigest = sha512Hex(authSalt, []byte(f.options.Username), []byte(f.options.Password))
+	}
+	ping := appendMsgpackArrayHeader(nil, 6)
+	ping = appendMsgpackString(ping, "PING")
+	ping = appendMsgpackString(ping, hostname)
+	ping = appendMsgpackBin(ping, salt[:])
+	ping = appendMsgpackString(ping, sha512Hex(salt[:], []byte(hostname), nonce, []byte(f.options.SharedKey)))
+	ping = appendMsgpackString(ping, f.options.Username)
+	ping = appendMsgpackString(ping, passwordDigest)
+	if _, err = f.conn.Write(ping); err != nil {
+		return err
+	}
+
+	pong, err := f.readArray("PONG")
+	if err != nil {
+		return err
+	}
+	if len(pong) < 5 {
+		return errors.New("invalid PONG")
+	}
+	if ok, _ := pong[1].(bool); !ok {
+		return fmt.Errorf("authentication failed: %v", pong[2])
+	}
+	serverHostname, _ := pong[3].(string)
+	digest, _ := pong[4].(string)
+	if digest != sha512Hex(salt[:], []byte(serverHostname), nonce, []byte(f.options.SharedKey)) {
+		return errors.New("server shared key mismatch")
+	}
+	return nil
+}
+
+func (f *Fluent) readArray(kind string) ([]interface{}, error) {
+	d := msgpackDecoder{r: f.rd}
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) == 0 || arr[0] != kind {
+		return nil, fmt.Errorf("expected %s message", kind)
+	}
+	return arr, nil
+}
+
+func (f *Fluent) readMap() (map[string]interface{}, error) {
+	d := msgpackDecoder{r: f.rd}
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("expected map")
+	}
+	return m, nil
+}
+
+func msgpackBytes(v interface{}) []byte {
+	switch val := v.(type) {
+	case []byte:
+		return val
+	case string:
+		return []byte(val)
+	}
+	return nil
+}
+
+func sha512Hex(parts ...[]byte) string {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
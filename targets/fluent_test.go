@@ -0,0 +1,138 @@
+package targets
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	values := []interface{}{
+		nil, true, false, int64(0), int64(127), int64(-1), int64(-33), int64(-200), int64(-40000), int64(-3000000000),
+		uint64(200), uint64(70000), uint64(5000000000), 1.5, "", "short", string(bytes.Repeat([]byte{'x'}, 300)),
+		[]byte{1, 2, 3}, []interface{}{"a", int64(1)}, map[string]interface{}{"k": "v", "n": int64(2)},
+	}
+	for _, v := range values {
+		buf := appendMsgpackValue(nil, v)
+		d := msgpackDecoder{r: bytes.NewReader(buf)}
+		got, err := d.decode()
+		require.NoError(t, err)
+		if i, ok := v.(int64); ok && i >= 0 {
+			assert.Equal(t, i, got) // positive fixints decode as int64
+			continue
+		}
+		assert.Equal(t, v, got)
+	}
+}
+
+type fluentServer struct {
+	listener  net.Listener
+	sharedKey string
+
+	mux     sync.Mutex
+	entries map[string][]map[string]interface{} // by tag
+	authed  bool
+}
+
+func (fs *fluentServer) serve() {
+	for {
+		conn, err := fs.listener.Accept()
+		if err != nil {
+			return
+		}
+		go fs.handle(conn)
+	}
+}
+
+func (fs *fluentServer) handle(conn net.Conn) {
+	defer conn.Close()
+	d := msgpackDecoder{r: bufio.NewReader(conn)}
+	nonce := []byte("0123456789abcdef")
+
+	helo := appendMsgpackValue(nil, []interface{}{"HELO", map[string]interface{}{"nonce": nonce, "auth": "", "keepalive": true}})
+	if _, err := conn.Write(helo); err != nil {
+		return
+	}
+	v, err := d.decode()
+	if err != nil {
+		return
+	}
+	ping := v.([]interface{})
+	salt := msgpackBytes(ping[2])
+	ok := ping[3] == sha512Hex(salt, msgpackBytes(ping[1]), nonce, []byte(fs.sharedKey))
+	pong := appendMsgpackValue(nil, []interface{}{"PONG", ok, "", "server", sha512Hex(salt, []byte("server"), nonce, []byte(fs.sharedKey))})
+	if _, err = conn.Write(pong); err != nil || !ok {
+		return
+	}
+	fs.mux.Lock()
+	fs.authed = true
+	fs.mux.Unlock()
+
+	for {
+		v, err := d.decode()
+		if err != nil {
+			return
+		}
+		msg := v.([]interface{})
+		tag := msg[0].(string)
+		fs.mux.Lock()
+		for _, e := range msg[1].([]interface{}) {
+			entry := e.([]interface{})
+			fs.entries[tag] = append(fs.entries[tag], entry[1].(map[string]interface{}))
+		}
+		fs.mux.Unlock()
+
+		if chunk, ok := msg[2].(map[string]interface{})["chunk"]; ok {
+			_, _ = conn.Write(appendMsgpackValue(nil, map[string]interface{}{"ack": chunk}))
+		}
+	}
+}
+
+func TestFluentTarget(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fs := &fluentServer{listener: l, sharedKey: "secret", entries: make(map[string][]map[string]interface{})}
+	go fs.serve()
+	defer l.Close()
+
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	f, err := NewFluentTarget(FluentOptions{
+		Host:       "127.0.0.1",
+		Port:       l.Addr().(*net.TCPAddr).Port,
+		Tag:        "app.{level}",
+		Structured: true,
+		RequireAck: true,
+		SharedKey:  "secret",
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(f, "fluent_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "wiggin"))
+	logger.Info("one", logr.Int("n", 1))
+	logger.Info("two", logr.Bool("ok", true))
+	logger.Error("three", logr.Float64("f", 1.5))
+	require.NoError(t, lgr.Shutdown())
+
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	assert.True(t, fs.authed)
+	require.Len(t, fs.entries["app.info"], 2)
+	require.Len(t, fs.entries["app.error"], 1)
+
+	assert.Equal(t, map[string]interface{}{"level": "info", "msg": "one", "user": "wiggin", "n": int64(1)}, fs.entries["app.info"][0])
+	assert.Equal(t, true, fs.entries["app.info"][1]["ok"])
+	assert.Equal(t, 1.5, fs.entries["app.error"][0]["f"])
+}
@@ -0,0 +1,213 @@
+package targets
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpDefaultTokenURL  = "https://oauth2.googleapis.com/token"
+	gcpTokenLifetime    = time.Hour
+	gcpTokenEarlyExpiry = time.Minute
+)
+
+// GCPCredentials determine how requests to Google Cloud APIs are authorized. If
+// none of the fields are provided then the service account file named by the
+// GOOGLE_APPLICATION_CREDENTIALS environment variable is used, and failing that
+// the GCE/GKE metadata server.
+type GCPCredentials struct {
+	// CredentialsFile is the path to a service account key file.
+	CredentialsFile string `json:"credentials_file,omitempty"`
+
+	// CredentialsJSON is the contents of a service account key file.
+	CredentialsJSON string `json:"credentials_json,omitempty"`
+
+	// AccessToken is a pre-issued OAuth2 access token. It is not refreshed.
+	AccessToken string `json:"access_token,omitempty"`
+}
+
+type gcpServiceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// gcpTokenSource provides OAuth2 access tokens, caching them until shortly
+// before they expire.
+type gcpTokenSource struct {
+	scope  string
+	client *http.Client
+
+	static  string
+	account *gcpServiceAccount
+	key     *rsa.PrivateKey
+
+	mux    sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newGCPTokenSource(creds GCPCredentials, scope string, client *http.Client) (*gcpTokenSource, error) {
+	ts := &gcpTokenSource{scope: scope, client: client, static: creds.AccessToken}
+	if ts.static != "" {
+		return ts, nil
+	}
+
+	data := []byte(creds.CredentialsJSON)
+	file := creds.CredentialsFile
+	if file == "" && len(data) == 0 {
+		file = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if file != "" {
+		var err error
+		if data, err = ioutil.ReadFile(file); err != nil {
+			return nil, fmt.Errorf("cannot read GCP credentials: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return ts, nil // use metadata server
+	}
+
+	account := &gcpServiceAccount{}
+	if err := json.Unmarshal(data, account); err != nil {
+		return nil, fmt.Errorf("cannot decode GCP credentials: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("GCP credentials must be a service account key")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = gcpDefaultTokenURL
+	}
+	key, err := parseRSAPrivateKey([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GCP private key: %w", err)
+	}
+	ts.account = account
+	ts.key = key
+	return ts, nil
+}
+
+// Token returns a valid access token, fetching a new one if needed.
+func (ts *gcpTokenSource) Token() (string, error) {
+	if ts.static != "" {
+		return ts.static, nil
+	}
+
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	if ts.token != "" && time.Now().Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.account != nil {
+		req, err = ts.jwtRequest()
+	} else {
+		req, err = http.NewRequest(http.MethodGet, gcpMetadataTokenURL, nil)
+		if req != nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch GCP access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot fetch GCP access token: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = json.Unmarshal(body, &tr); err != nil || tr.AccessToken == "" {
+		return "", fmt.Errorf("invalid GCP token response: %v", err)
+	}
+	ts.token = tr.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(tr.ExpiresIn)*time.Second - gcpTokenEarlyExpiry)
+	return ts.token, nil
+}
+
+// Invalidate discards the cached token, for example after the API rejects it.
+func (ts *gcpTokenSource) Invalidate() {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+	ts.token = ""
+}
+
+// jwtRequest creates a request exchanging a signed JWT assertion for an access token.
+func (ts *gcpTokenSource) jwtRequest() (*http.Request, error) {
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": ts.account.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   ts.account.ClientEmail,
+		"scope": ts.scope,
+		"aud":   ts.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(gcpTokenLifetime).Unix(),
+	})
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign GCP token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + enc.EncodeToString(sig)},
+	}
+	req, err := http.NewRequest(http.MethodPost, ts.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
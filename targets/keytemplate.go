@@ -0,0 +1,78 @@
+package targets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// keyTokens are the placeholders allowed in object key templates.
+var keyTokens = []string{"{year}", "{month}", "{day}", "{hour}", "{minute}", "{host}", "{timestamp}", "{seq}"}
+
+func checkKeyTemplate(tmpl string) error {
+	if !strings.Contains(tmpl, "{seq}") {
+		return errors.New("template must contain {seq}")
+	}
+	s := tmpl
+	for _, token := range keyTokens {
+		s = strings.ReplaceAll(s, token, "")
+	}
+	if i := strings.IndexAny(s, "{}"); i >= 0 {
+		return fmt.Errorf("unknown placeholder near %q", s[i:])
+	}
+	return nil
+}
+
+// keyTemplate names the objects written by archive targets. Keys are expanded in
+// two steps: the time placeholders from the time of each record, grouping records
+// into partitions, then the remaining placeholders when an object is written.
+type keyTemplate struct {
+	tmpl string
+	host string
+	seq  uint64
+}
+
+func newKeyTemplate(tmpl string) *keyTemplate {
+	host, _ := os.Hostname()
+	return &keyTemplate{tmpl: tmpl, host: host}
+}
+
+// partition expands the time placeholders for a record time.
+func (kt *keyTemplate) partition(t time.Time) string {
+	t = t.UTC()
+	return strings.NewReplacer(
+		"{year}", t.Format("2006"),
+		"{month}", t.Format("01"),
+		"{day}", t.Format("02"),
+		"{hour}", t.Format("15"),
+		"{minute}", t.Format("04"),
+	).Replace(kt.tmpl)
+}
+
+// objectKey expands the remaining placeholders of a partition.
+func (kt *keyTemplate) objectKey(partition string) string {
+	seq := atomic.AddUint64(&kt.seq, 1)
+	return strings.NewReplacer(
+		"{host}", kt.host,
+		"{timestamp}", strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+		"{seq}", fmt.Sprintf("%06d", seq),
+	).Replace(partition)
+}
+
+// groupByKey groups batch items by key, returning the keys in the order they
+// first appear.
+func groupByKey(items []batchItem) ([]string, map[string][]batchItem) {
+	var order []string
+	groups := make(map[string][]batchItem)
+	for _, item := range items {
+		if _, ok := groups[item.key]; !ok {
+			order = append(order, item.key)
+		}
+		groups[item.key] = append(groups[item.key], item)
+	}
+	return order, groups
+}
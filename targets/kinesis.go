@@ -0,0 +1,404 @@
+package targets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// Limits imposed by the Kinesis Data Streams PutRecords API and the
+// Firehose PutRecordBatch API.
+const (
+	KinesisMaxRecordsPerRequest  = 500
+	KinesisMaxRequestBytes       = 5 * 1024 * 1024
+	KinesisMaxRecordBytes        = 1024 * 1024
+	FirehoseMaxRecordsPerRequest = 500
+	FirehoseMaxRequestBytes      = 4 * 1024 * 1024
+	FirehoseMaxRecordBytes       = 1000 * 1024
+
+	KinesisDefaultMaxRetries          = 5
+	KinesisDefaultFlushIntervalMillis = 1000
+	KinesisRequestTimeoutSecs         = 30
+)
+
+// KinesisOptions provides parameters for the Kinesis target. Exactly one of
+// `StreamName` (Kinesis Data Streams) or `DeliveryStreamName` (Kinesis Data Firehose)
+// must be provided.
+type KinesisOptions struct {
+	AWSCredentials
+
+	// StreamName is the name of the Kinesis data stream.
+	StreamName string `json:"stream_name,omitempty"`
+
+	// DeliveryStreamName is the name of the Firehose delivery stream.
+	DeliveryStreamName string `json:"delivery_stream_name,omitempty"`
+
+	// Endpoint overrides the regional service endpoint, e.g. for VPC endpoints.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// PartitionKey is a template for the Kinesis partition key, e.g. "{tenant}-{level}".
+	// See `fieldTemplate`. If empty, a random partition key is used to spread records
+	// across shards. Not used for Firehose.
+	PartitionKey string `json:"partition_key,omitempty"`
+
+	// Aggregate, when true, packs multiple formatted log records (with the same
+	// partition key) into each Kinesis record, up to the record size limit. This
+	// greatly reduces the number of records, and therefore cost and throttling.
+	// Consumers must split records on the formatter's delimiter (newline by default).
+	Aggregate bool `json:"aggregate,omitempty"`
+
+	// MaxRetries is the maximum number of times a batch, or the failed records
+	// within a batch, are retried when throttled. Defaults to KinesisDefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between retries. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (ko KinesisOptions) CheckValid() error {
+	if (ko.StreamName == "") == (ko.DeliveryStreamName == "") {
+		return errors.New("exactly one of stream_name or delivery_stream_name is required")
+	}
+	creds := ko.AWSCredentials.withEnv()
+	if creds.Region == "" && ko.Endpoint == "" {
+		return errors.New("missing region")
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return errors.New("missing AWS credentials")
+	}
+	if ko.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if _, err := parseFieldTemplate(ko.PartitionKey); err != nil {
+		return fmt.Errorf("invalid partition_key: %w", err)
+	}
+	if ko.Backoff != nil {
+		return ko.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// Kinesis outputs log records to an AWS Kinesis data stream or Firehose delivery stream.
+// Records are batched to stay within the API limits, and records rejected due to
+// throttling (e.g. ProvisionedThroughputExceededException) are retried with backoff.
+type Kinesis struct {
+	options  KinesisOptions
+	creds    AWSCredentials
+	firehose bool
+	endpoint string
+	keyTmpl  *fieldTemplate
+	backoff  Backoff
+	client   *http.Client
+	batch    *batcher
+
+	maxRecordBytes int
+	rndMux         sync.Mutex
+	rnd            *rand.Rand
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+// NewKinesisTarget creates a target capable of outputting log records to Kinesis.
+func NewKinesisTarget(options KinesisOptions) (*Kinesis, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	k := &Kinesis{
+		options:  options,
+		creds:    options.AWSCredentials.withEnv(),
+		firehose: options.DeliveryStreamName != "",
+		endpoint: options.Endpoint,
+		backoff:  DefaultBackoff(),
+		client:   &http.Client{Timeout: time.Second * KinesisRequestTimeoutSecs},
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		shutdown: make(chan struct{}),
+	}
+	k.keyTmpl, _ = parseFieldTemplate(options.PartitionKey)
+	if options.Backoff != nil {
+		k.backoff = options.Backoff
+	}
+	if k.options.MaxRetries == 0 {
+		k.options.MaxRetries = KinesisDefaultMaxRetries
+	}
+
+	limits := BatchOptions{
+		MaxBatchCount:       KinesisMaxRecordsPerRequest,
+		MaxBatchBytes:       KinesisMaxRequestBytes,
+		FlushIntervalMillis: KinesisDefaultFlushIntervalMillis,
+	}
+	k.maxRecordBytes = KinesisMaxRecordBytes
+	service := "kinesis"
+	if k.firehose {
+		limits.MaxBatchCount = FirehoseMaxRecordsPerRequest
+		limits.MaxBatchBytes = FirehoseMaxRequestBytes
+		k.maxRecordBytes = FirehoseMaxRecordBytes
+		service = "firehose"
+	}
+	if k.endpoint == "" {
+		k.endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, k.creds.Region)
+	}
+
+	batchOpts := options.BatchOptions
+	if options.Aggregate {
+		// aggregated records are packed by size, so allow as many log records as fit.
+		if batchOpts.MaxBatchCount == 0 {
+			batchOpts.MaxBatchCount = limits.MaxBatchBytes
+		}
+		limits.MaxBatchCount = limits.MaxBatchBytes
+	}
+	k.batch = newBatcher(batchOpts.withDefaults(limits), k.send)
+	return k, nil
+}
+
+// Init is called once to initialize the target.
+func (k *Kinesis) Init() error {
+	return nil
+}
+
+// Write buffers the formatted log record to be sent with the next batch.
+func (k *Kinesis) Write(p []byte, rec *logr.LogRec) (int, error) {
+	key := k.keyTmpl.execute(rec)
+	if len(p)+len(key) > k.maxRecordBytes {
+		return 0, fmt.Errorf("log record of %d bytes exceeds %s limit of %d bytes", len(p)+len(key), k, k.maxRecordBytes)
+	}
+	if err := k.batch.add(p, key, rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends any buffered log records.
+func (k *Kinesis) Flush() error {
+	return k.batch.flush()
+}
+
+// Shutdown sends any buffered log records.
+func (k *Kinesis) Shutdown() error {
+	err := k.batch.flush()
+	k.shutdownOnce.Do(func() { close(k.shutdown) })
+	return err
+}
+
+// String returns a string representation of this target.
+func (k *Kinesis) String() string {
+	if k.firehose {
+		return fmt.Sprintf("FirehoseTarget[%s]", k.options.DeliveryStreamName)
+	}
+	return fmt.Sprintf("KinesisTarget[%s]", k.options.StreamName)
+}
+
+type kinesisRecord struct {
+	Data         []byte `json:"Data"` // base64 encoded by encoding/json
+	PartitionKey string `json:"PartitionKey,omitempty"`
+}
+
+func (kr kinesisRecord) size() int {
+	return len(kr.Data) + len(kr.PartitionKey)
+}
+
+// send converts the batch to Kinesis records and puts them in as many requests
+// as needed to stay within the API limits.
+func (k *Kinesis) send(items []batchItem) error {
+	records := k.toRecords(items)
+
+	maxCount := KinesisMaxRecordsPerRequest
+	maxBytes := KinesisMaxRequestBytes
+	if k.firehose {
+		maxCount = FirehoseMaxRecordsPerRequest
+		maxBytes = FirehoseMaxRequestBytes
+	}
+
+	for len(records) > 0 {
+		n, size := 0, 0
+		for n < len(records) && n < maxCount && size+records[n].size() <= maxBytes {
+			size += records[n].size()
+			n++
+		}
+		if err := k.putWithRetry(records[:n]); err != nil {
+			return err
+		}
+		records = records[n:]
+	}
+	return nil
+}
+
+func (k *Kinesis) toRecords(items []batchItem) []kinesisRecord {
+	records := make([]kinesisRecord, 0, len(items))
+	if !k.options.Aggregate {
+		for _, item := range items {
+			records = append(records, kinesisRecord{Data: item.data, PartitionKey: k.partitionKey(item.key)})
+		}
+		return records
+	}
+
+	// aggregate items with the same key into as few records as possible.
+	current := make(map[string]int)
+	for _, item := range items {
+		if idx, ok := current[item.key]; ok && records[idx].size()+len(item.data) <= k.maxRecordBytes {
+			records[idx].Data = append(records[idx].Data, item.data...)
+			continue
+		}
+		records = append(records, kinesisRecord{Data: item.data, PartitionKey: k.partitionKey(item.key)})
+		current[item.key] = len(records) - 1
+	}
+	return records
+}
+
+func (k *Kinesis) partitionKey(key string) string {
+	if k.firehose {
+		return ""
+	}
+	if key != "" {
+		return key
+	}
+	k.rndMux.Lock()
+	defer k.rndMux.Unlock()
+	return strconv.FormatUint(k.rnd.Uint64(), 16)
+}
+
+// putWithRetry puts the records, retrying any that fail due to throttling or
+// transient errors.
+func (k *Kinesis) putWithRetry(records []kinesisRecord) error {
+	err := retry(k.backoff, k.options.MaxRetries, k.shutdown, func() (bool, error) {
+		failed, retryable, err := k.put(records)
+		if failed != nil {
+			records = failed
+		}
+		return retryable, err
+	})
+	if err != nil {
+		return fmt.Errorf("%s could not put %d records: %w", k, len(records), err)
+	}
+	return nil
+}
+
+type kinesisRequest struct {
+	StreamName         string          `json:"StreamName,omitempty"`
+	DeliveryStreamName string          `json:"DeliveryStreamName,omitempty"`
+	Records            []kinesisRecord `json:"Records"`
+}
+
+type kinesisResponse struct {
+	FailedRecordCount int `json:"FailedRecordCount"`
+	FailedPutCount    int `json:"FailedPutCount"`
+	Records           []struct {
+		ErrorCode    string `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+	} `json:"Records"`
+	RequestResponses []struct {
+		ErrorCode    string `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+	} `json:"RequestResponses"`
+}
+
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// put sends one request. If some records fail then those records are returned
+// along with an error. retryable indicates whether the error is transient.
+func (k *Kinesis) put(records []kinesisRecord) (failed []kinesisRecord, retryable bool, err error) {
+	req := kinesisRequest{Records: records}
+	target := "Kinesis_20131202.PutRecords"
+	if k.firehose {
+		req.DeliveryStreamName = k.options.DeliveryStreamName
+		target = "Firehose_20150804.PutRecordBatch"
+	} else {
+		req.StreamName = k.options.StreamName
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, k.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	httpReq.Header.Set("X-Amz-Target", target)
+	signAWSv4(httpReq, body, k.creds, k.service(), time.Now())
+
+	resp, err := k.client.Do(httpReq)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return nil, true, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var ae awsError
+		_ = json.Unmarshal(respBody, &ae)
+		err = fmt.Errorf("request failed with status %d: %s %s", resp.StatusCode, ae.Type, ae.Message)
+		return nil, isRetryableAWSError(resp.StatusCode, ae.Type), err
+	}
+
+	var kr kinesisResponse
+	if err = json.Unmarshal(respBody, &kr); err != nil {
+		return nil, false, fmt.Errorf("cannot decode response: %w", err)
+	}
+
+	results := kr.Records
+	if k.firehose {
+		results = kr.RequestResponses
+	}
+	if kr.FailedRecordCount == 0 && kr.FailedPutCount == 0 {
+		return nil, false, nil
+	}
+
+	var lastCode string
+	for i, r := range results {
+		if r.ErrorCode != "" && i < len(records) {
+			failed = append(failed, records[i])
+			lastCode = r.ErrorCode
+			if isRetryableAWSError(0, r.ErrorCode) || strings.Contains(r.ErrorCode, "InternalFailure") {
+				retryable = true
+			}
+		}
+	}
+	return failed, retryable, fmt.Errorf("failed to put %d of %d records: %s", len(failed), len(records), lastCode)
+}
+
+func (k *Kinesis) service() string {
+	if k.firehose {
+		return "firehose"
+	}
+	return "kinesis"
+}
+
+func isRetryableAWSError(status int, errType string) bool {
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return true
+	}
+	// __type may be prefixed with a namespace, e.g. "com.amazonaws#ThrottlingException"
+	if idx := strings.LastIndexByte(errType, '#'); idx >= 0 {
+		errType = errType[idx+1:]
+	}
+	switch errType {
+	case "ProvisionedThroughputExceededException", "ThrottlingException", "LimitExceededException",
+		"ServiceUnavailableException", "KMSThrottlingException", "InternalFailure":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package targets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAWSv4(t *testing.T) {
+	// example from the AWS Signature Version 4 documentation.
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	creds := AWSCredentials{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signAWSv4(req, nil, creds, "iam", now)
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7", req.Header.Get("Authorization"))
+}
+
+type kinesisServer struct {
+	mux      sync.Mutex
+	requests []kinesisRequest
+	throttle int // number of leading records to reject in the first request
+}
+
+func (ks *kinesisServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	var req kinesisRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ks.mux.Lock()
+	ks.requests = append(ks.requests, req)
+	throttle := ks.throttle
+	ks.throttle = 0
+	ks.mux.Unlock()
+
+	results := make([]string, 0, len(req.Records))
+	for i := range req.Records {
+		if i < throttle {
+			results = append(results, `{"ErrorCode":"ProvisionedThroughputExceededException","ErrorMessage":"slow down"}`)
+		} else {
+			results = append(results, fmt.Sprintf(`{"SequenceNumber":"%d","ShardId":"shardId-000000000000"}`, i))
+		}
+	}
+	fmt.Fprintf(w, `{"FailedRecordCount":%d,"Records":[%s]}`, throttle, strings.Join(results, ","))
+}
+
+func (ks *kinesisServer) records() []kinesisRecord {
+	ks.mux.Lock()
+	defer ks.mux.Unlock()
+	var records []kinesisRecord
+	for _, req := range ks.requests {
+		records = append(records, req.Records...)
+	}
+	return records
+}
+
+func newTestKinesis(t *testing.T, ks *kinesisServer, aggregate bool) (*logr.Logr, *httptest.Server) {
+	server := httptest.NewServer(ks)
+
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	k, err := NewKinesisTarget(KinesisOptions{
+		AWSCredentials: AWSCredentials{Region: "us-east-1", AccessKeyID: "id", SecretAccessKey: "secret"},
+		StreamName:     "logs",
+		Endpoint:       server.URL,
+		PartitionKey:   "{tenant}",
+		Aggregate:      aggregate,
+		Backoff:        &ExponentialBackoff{InitialMillis: 1, MaxMillis: 5},
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(k, "kinesis_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+	return lgr, server
+}
+
+func TestKinesisTarget(t *testing.T) {
+	t.Run("partition key and retry", func(t *testing.T) {
+		ks := &kinesisServer{throttle: 2}
+		lgr, server := newTestKinesis(t, ks, false)
+		defer server.Close()
+
+		logger := lgr.NewLogger()
+		for i := 0; i < 5; i++ {
+			logger.Info("msg", logr.String("tenant", fmt.Sprintf("t%d", i%2)))
+		}
+		require.NoError(t, lgr.Shutdown())
+
+		ks.mux.Lock()
+		require.Len(t, ks.requests, 2)
+		assert.Len(t, ks.requests[0].Records, 5)
+		assert.Len(t, ks.requests[1].Records, 2, "only throttled records should be retried")
+		assert.Equal(t, "logs", ks.requests[0].StreamName)
+		ks.mux.Unlock()
+
+		records := ks.records()
+		assert.Equal(t, "t0", records[0].PartitionKey)
+		assert.Equal(t, "t1", records[1].PartitionKey)
+		assert.Equal(t, records[0], records[5])
+	})
+
+	t.Run("aggregate", func(t *testing.T) {
+		ks := &kinesisServer{}
+		lgr, server := newTestKinesis(t, ks, true)
+		defer server.Close()
+
+		logger := lgr.NewLogger()
+		for i := 0; i < 10; i++ {
+			logger.Info("msg", logr.String("tenant", fmt.Sprintf("t%d", i%2)))
+		}
+		require.NoError(t, lgr.Shutdown())
+
+		records := ks.records()
+		require.Len(t, records, 2)
+		for _, r := range records {
+			assert.Equal(t, 5, strings.Count(string(r.Data), "\n"), r.PartitionKey)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package targets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// LocalSocketOptions provides parameters for the local socket target.
+type LocalSocketOptions struct {
+	// Path is the path of a Unix domain socket, e.g. "/var/run/vector.sock", or on
+	// Windows the name of a named pipe, e.g. `\\.\pipe\fluent-bit`.
+	Path string `json:"path"`
+
+	// Network is "unix" (stream, the default) or "unixgram" (datagram). Ignored on Windows.
+	Network string `json:"network,omitempty"`
+
+	// Backoff determines the delay between reconnect attempts. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+}
+
+// CheckValid returns an error if the options are invalid.
+func (lo LocalSocketOptions) CheckValid() error {
+	if lo.Path == "" {
+		return errors.New("missing path")
+	}
+	switch lo.Network {
+	case "", "unix", "unixgram":
+	default:
+		return fmt.Errorf("invalid network %q", lo.Network)
+	}
+	if lo.Backoff != nil {
+		return lo.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// LocalSocket outputs newline delimited log records to a Unix domain socket or
+// Windows named pipe, for host-local collectors such as Vector or Fluent Bit.
+// The connection is re-established if the collector restarts.
+type LocalSocket struct {
+	options LocalSocketOptions
+	backoff Backoff
+
+	mux      sync.Mutex
+	conn     io.WriteCloser
+	shutdown chan struct{}
+}
+
+// NewLocalSocketTarget creates a target capable of outputting log records to a
+// Unix domain socket or Windows named pipe.
+func NewLocalSocketTarget(options LocalSocketOptions) (*LocalSocket, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+	ls := &LocalSocket{
+		options:  options,
+		backoff:  DefaultBackoff(),
+		shutdown: make(chan struct{}),
+	}
+	if ls.options.Network == "" {
+		ls.options.Network = "unix"
+	}
+	if options.Backoff != nil {
+		ls.backoff = options.Backoff
+	}
+	return ls, nil
+}
+
+// Init is called once to initialize the target.
+func (ls *LocalSocket) Init() error {
+	return nil
+}
+
+// Write outputs the log record, appending a newline if needed.
+// Called by dedicated target goroutine and will block until success or shutdown.
+func (ls *LocalSocket) Write(p []byte, rec *logr.LogRec) (int, error) {
+	return ls.WriteContext(context.Background(), p, rec)
+}
+
+// WriteContext is like Write but gives up once ctx is done, returning the context error.
+func (ls *LocalSocket) WriteContext(ctx context.Context, p []byte, rec *logr.LogRec) (int, error) {
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		line := make([]byte, len(p), len(p)+1)
+		copy(line, p)
+		p = append(line, '\n')
+	}
+
+	ls.mux.Lock()
+	defer ls.mux.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		if ls.conn == nil {
+			conn, err := dialLocalSocket(ctx, ls.options.Network, ls.options.Path)
+			if err == nil {
+				ls.conn = conn
+			} else {
+				rec.Logger().Logr().ReportError(fmt.Errorf("log target %s connection error: %w", ls, err))
+			}
+		}
+
+		if ls.conn != nil {
+			if d, ok := ls.conn.(interface{ SetWriteDeadline(time.Time) error }); ok {
+				deadline := time.Now().Add(time.Second * WriteTimeoutSecs)
+				if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+					deadline = dl
+				}
+				_ = d.SetWriteDeadline(deadline)
+			}
+			n, err := ls.conn.Write(p)
+			if err == nil {
+				return n, nil
+			}
+			rec.Logger().Logr().ReportError(fmt.Errorf("log target %s write error: %w", ls, err))
+			ls.conn.Close()
+			ls.conn = nil
+		}
+
+		select {
+		case <-ls.shutdown:
+			return 0, nil
+		case <-ctx.Done():
+			return 0, fmt.Errorf("log target %s write aborted: %w", ls, ctx.Err())
+		case <-time.After(ls.backoff.Delay(attempt)):
+		}
+	}
+}
+
+// Shutdown closes the connection.
+func (ls *LocalSocket) Shutdown() error {
+	close(ls.shutdown)
+
+	ls.mux.Lock()
+	defer ls.mux.Unlock()
+	var err error
+	if ls.conn != nil {
+		err = ls.conn.Close()
+		ls.conn = nil
+	}
+	return err
+}
+
+// String returns a string representation of this target.
+func (ls *LocalSocket) String() string {
+	return fmt.Sprintf("LocalSocketTarget[%s]", ls.options.Path)
+}
@@ -0,0 +1,93 @@
+//go:build !windows
+// +build !windows
+
+package targets
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lineCollector struct {
+	mux   sync.Mutex
+	lines []string
+}
+
+func (lc *lineCollector) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lc.mux.Lock()
+				lc.lines = append(lc.lines, scanner.Text())
+				lc.mux.Unlock()
+			}
+		}()
+	}
+}
+
+func (lc *lineCollector) count() int {
+	lc.mux.Lock()
+	defer lc.mux.Unlock()
+	return len(lc.lines)
+}
+
+func TestLocalSocketTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logr.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	lc := &lineCollector{}
+	go lc.serve(l)
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	ls, err := NewLocalSocketTarget(LocalSocketOptions{
+		Path:    path,
+		Backoff: &ExponentialBackoff{InitialMillis: 5, MaxMillis: 20},
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(ls, "socket_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("one")
+	logger.Info("two")
+	require.NoError(t, lgr.Flush())
+	assert.Eventually(t, func() bool { return lc.count() == 2 }, time.Second, time.Millisecond*10)
+
+	// restart the collector; the target should reconnect.
+	l.Close()
+	l, err = net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+	go lc.serve(l)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("after restart")
+		time.Sleep(time.Millisecond * 20)
+	}
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Eventually(t, func() bool { return lc.count() >= 4 }, time.Second*2, time.Millisecond*10)
+	lc.mux.Lock()
+	defer lc.mux.Unlock()
+	assert.Contains(t, lc.lines[0], `"msg":"one"`)
+	assert.Contains(t, lc.lines[len(lc.lines)-1], "after restart")
+}
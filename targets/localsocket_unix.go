@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package targets
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+// dialLocalSocket connects to a Unix domain socket.
+func dialLocalSocket(ctx context.Context, network string, path string) (io.WriteCloser, error) {
+	dialer := net.Dialer{Timeout: time.Second * DialTimeoutSecs}
+	return dialer.DialContext(ctx, network, path)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package targets
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// dialLocalSocket opens a named pipe for writing. The network is ignored.
+func dialLocalSocket(ctx context.Context, network string, path string) (io.WriteCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY, 0)
+}
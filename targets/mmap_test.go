@@ -0,0 +1,84 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package targets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMmapTarget(t *testing.T) {
+	dir := t.TempDir()
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	target, err := NewMmapTarget(MmapOptions{Dir: dir, SegmentSize: MmapMinSegmentSize, MaxSegments: 3})
+	require.NoError(t, err)
+	err = lgr.AddTarget(target, "mmap", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	var expected strings.Builder
+	logger := lgr.NewLogger()
+	for i := 0; i < 500; i++ {
+		logger.Info("hot path", logr.Int("i", i))
+		fmt.Fprintf(&expected, "info hot path i=%d\n", i)
+	}
+	require.NoError(t, lgr.Shutdown())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	var output strings.Builder
+	for _, file := range files {
+		assert.False(t, strings.HasSuffix(file, mmapActiveSuffix))
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		output.Write(data)
+	}
+	assert.True(t, strings.HasSuffix(expected.String(), output.String()))
+	assert.True(t, strings.HasSuffix(output.String(), "info hot path i=499\n"))
+}
+
+func TestMmapRecovery(t *testing.T) {
+	dir := t.TempDir()
+
+	// a segment left active by a crash while writing "partial".
+	segment := make([]byte, MmapMinSegmentSize)
+	n := copy(segment, "complete\n")
+	copy(segment[n:], "partial")
+	binary.LittleEndian.PutUint64(segment[len(segment)-mmapFooterSize:], uint64(n))
+	err := ioutil.WriteFile(filepath.Join(dir, "logr.000003.log"+mmapActiveSuffix), segment, 0640)
+	require.NoError(t, err)
+
+	target, err := NewMmapTarget(MmapOptions{Dir: dir, SegmentSize: MmapMinSegmentSize})
+	require.NoError(t, err)
+	require.NoError(t, target.Init())
+	assert.Equal(t, 1, target.Recovered())
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "logr.000003.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "complete\n", string(data))
+
+	_, err = target.Write([]byte("restarted\n"), nil)
+	require.NoError(t, err)
+	require.NoError(t, target.Shutdown())
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "logr.000004.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "restarted\n", string(data))
+
+	_, err = os.Stat(filepath.Join(dir, "logr.000004.log"+mmapActiveSuffix))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package targets
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the first size bytes of the file for reading and writing.
+func mmapFile(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// munmapFile unmaps memory mapped by mmapFile.
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,567 @@
+package targets
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	MQTTDefaultKeepAliveSecs     = 60
+	MQTTDefaultOfflineBufferSize = 1000
+	MQTTAckTimeoutSecs           = 10
+	mqttMaxPacketSize            = 256 * 1024 * 1024
+)
+
+// MQTT control packet types.
+const (
+	mqttConnect    byte = 1
+	mqttConnack    byte = 2
+	mqttPublish    byte = 3
+	mqttPuback     byte = 4
+	mqttPubrec     byte = 5
+	mqttPubrel     byte = 6
+	mqttPubcomp    byte = 7
+	mqttPingreq    byte = 12
+	mqttPingresp   byte = 13
+	mqttDisconnect byte = 14
+)
+
+// MQTTWill is the Last Will and Testament published by the broker if the
+// connection is lost without a clean disconnect.
+type MQTTWill struct {
+	Topic   string `json:"topic"`
+	Payload string `json:"payload"`
+	QoS     byte   `json:"qos"`
+	Retain  bool   `json:"retain"`
+}
+
+// MQTTOptions provides parameters for the MQTT target.
+type MQTTOptions struct {
+	// Broker is the broker URL, e.g. "tcp://localhost:1883" or "tls://broker:8883".
+	Broker   string `json:"broker"`
+	ClientID string `json:"client_id"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Cert and Insecure apply to "tls" brokers. See `TcpOptions`.
+	Cert     string `json:"cert,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+
+	// Topic is a template for the topic each record is published to, e.g. "logs/{level}"
+	// or "devices/{device_id}/logs". See `fieldTemplate`. Wildcard characters in
+	// expanded field values are replaced with underscores.
+	Topic string `json:"topic"`
+
+	// QoS is the quality of service level (0, 1 or 2) used when publishing.
+	QoS byte `json:"qos"`
+
+	// Retain sets the retain flag on published records.
+	Retain bool `json:"retain,omitempty"`
+
+	// KeepAliveSecs is the keep alive interval. Defaults to MQTTDefaultKeepAliveSecs.
+	KeepAliveSecs int `json:"keep_alive_secs,omitempty"`
+
+	// Will, if not nil, is registered with the broker when connecting.
+	Will *MQTTWill `json:"will,omitempty"`
+
+	// OfflineBufferSize is the maximum number of records buffered in memory while the
+	// broker is unreachable. When full the oldest records are dropped. Defaults to
+	// MQTTDefaultOfflineBufferSize; -1 disables buffering.
+	OfflineBufferSize int `json:"offline_buffer_size,omitempty"`
+
+	// Backoff determines the delay between reconnect attempts. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+}
+
+// CheckValid returns an error if the options are invalid.
+func (mo MQTTOptions) CheckValid() error {
+	u, err := url.Parse(mo.Broker)
+	if err != nil || mo.Broker == "" {
+		return errors.New("invalid or missing broker")
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "tls", "ssl", "mqtts":
+	default:
+		return fmt.Errorf("unsupported broker scheme %q", u.Scheme)
+	}
+	if mo.ClientID == "" {
+		return errors.New("missing client_id")
+	}
+	if mo.Topic == "" {
+		return errors.New("missing topic")
+	}
+	if _, err := parseFieldTemplate(mo.Topic); err != nil {
+		return fmt.Errorf("invalid topic: %w", err)
+	}
+	if strings.ContainsAny(mo.Topic, "+#") {
+		return errors.New("topic cannot contain wildcards")
+	}
+	if mo.QoS > 2 {
+		return errors.New("qos must be 0, 1 or 2")
+	}
+	if mo.Will != nil && (mo.Will.Topic == "" || mo.Will.QoS > 2) {
+		return errors.New("will requires a topic and a qos of 0, 1 or 2")
+	}
+	if mo.KeepAliveSecs < 0 || mo.KeepAliveSecs > 65535 {
+		return errors.New("keep_alive_secs must be between 0 and 65535")
+	}
+	if mo.Backoff != nil {
+		return mo.Backoff.CheckValid()
+	}
+	return nil
+}
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+// MQTT publishes log records to an MQTT broker, buffering records in memory while
+// the broker is unreachable.
+type MQTT struct {
+	options MQTTOptions
+	addr    string
+	useTLS  bool
+	topic   *fieldTemplate
+	backoff Backoff
+
+	mux         sync.Mutex
+	session     *mqttSession
+	buffer      []mqttMessage
+	attempt     int
+	nextAttempt time.Time
+	packetID    uint16
+}
+
+// NewMQTTTarget creates a target capable of publishing log records to an MQTT broker.
+func NewMQTTTarget(options MQTTOptions) (*MQTT, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(options.Broker)
+
+	m := &MQTT{
+		options: options,
+		addr:    u.Host,
+		backoff: DefaultBackoff(),
+	}
+	switch u.Scheme {
+	case "tls", "ssl", "mqtts":
+		m.useTLS = true
+		if u.Port() == "" {
+			m.addr = net.JoinHostPort(u.Hostname(), "8883")
+		}
+	default:
+		if u.Port() == "" {
+			m.addr = net.JoinHostPort(u.Hostname(), "1883")
+		}
+	}
+	m.topic, _ = parseFieldTemplate(options.Topic)
+	if options.Backoff != nil {
+		m.backoff = options.Backoff
+	}
+	if m.options.KeepAliveSecs == 0 {
+		m.options.KeepAliveSecs = MQTTDefaultKeepAliveSecs
+	}
+	if m.options.OfflineBufferSize == 0 {
+		m.options.OfflineBufferSize = MQTTDefaultOfflineBufferSize
+	}
+	return m, nil
+}
+
+// Init is called once to initialize the target.
+func (m *MQTT) Init() error {
+	return nil
+}
+
+// Write publishes the formatted log record.
+func (m *MQTT) Write(p []byte, rec *logr.LogRec) (int, error) {
+	return m.WriteContext(context.Background(), p, rec)
+}
+
+var mqttTopicReplacer = strings.NewReplacer("+", "_", "#", "_")
+
+// WriteContext publishes the formatted log record, or buffers it if the broker cannot
+// be reached. The record is not retried until the next write or flush.
+func (m *MQTT) WriteContext(ctx context.Context, p []byte, rec *logr.LogRec) (int, error) {
+	msg := mqttMessage{
+		topic:   mqttTopicReplacer.Replace(m.topic.execute(rec)),
+		payload: make([]byte, len(p)),
+	}
+	copy(msg.payload, p)
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.buffer = append(m.buffer, msg)
+	err := m.drain(ctx, false)
+	if err == nil {
+		return len(p), nil
+	}
+
+	if m.options.OfflineBufferSize < 0 {
+		m.buffer = m.buffer[:0]
+		return 0, err
+	}
+	if over := len(m.buffer) - m.options.OfflineBufferSize; over > 0 {
+		m.buffer = append(m.buffer[:0], m.buffer[over:]...)
+		return len(p), fmt.Errorf("%s offline buffer full; dropped %d oldest records: %w", m, over, err)
+	}
+	return len(p), nil
+}
+
+// Flush publishes any records buffered while the broker was unreachable, reconnecting
+// immediately if needed.
+func (m *MQTT) Flush() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*DialTimeoutSecs)
+	defer cancel()
+	return m.drain(ctx, true)
+}
+
+// Shutdown publishes any buffered records, then disconnects cleanly so the will
+// is not published.
+func (m *MQTT) Shutdown() error {
+	err := m.Flush()
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.session != nil {
+		_ = m.session.write([]byte{mqttDisconnect << 4, 0})
+		m.session.close()
+		m.session = nil
+	}
+	if n := len(m.buffer); n > 0 {
+		return fmt.Errorf("%s shut down with %d unsent records: %v", m, n, err)
+	}
+	return nil
+}
+
+// String returns a string representation of this target.
+func (m *MQTT) String() string {
+	return fmt.Sprintf("MQTTTarget[%s]", m.addr)
+}
+
+// drain publishes buffered records in order, connecting first if needed. Unless
+// force is true, reconnect attempts are limited by the backoff.
+// Must be called with mux held.
+func (m *MQTT) drain(ctx context.Context, force bool) error {
+	for len(m.buffer) > 0 {
+		if m.session == nil {
+			if !force && time.Now().Before(m.nextAttempt) {
+				return errors.New("waiting to reconnect")
+			}
+			s, err := m.connect(ctx)
+			if err != nil {
+				m.attempt++
+				m.nextAttempt = time.Now().Add(m.backoff.Delay(m.attempt))
+				return err
+			}
+			m.attempt = 0
+			m.session = s
+		}
+
+		if err := m.publish(ctx, m.buffer[0]); err != nil {
+			m.session.close()
+			m.session = nil
+			return err
+		}
+		m.buffer[0] = mqttMessage{}
+		m.buffer = m.buffer[1:]
+	}
+	return nil
+}
+
+func (m *MQTT) connect(ctx context.Context) (*mqttSession, error) {
+	dialer := net.Dialer{Timeout: time.Second * DialTimeoutSecs}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return nil, err
+	}
+	if m.useTLS {
+		tlsconfig := &tls.Config{
+			ServerName:         strings.Split(m.addr, ":")[0],
+			InsecureSkipVerify: m.options.Insecure,
+		}
+		if m.options.Cert != "" {
+			pool, errPool := GetCertPool(m.options.Cert)
+			if errPool != nil {
+				conn.Close()
+				return nil, errPool
+			}
+			tlsconfig.RootCAs = pool
+		}
+		conn = tls.Client(conn, tlsconfig)
+	}
+
+	s := newMQTTSession(conn)
+	if err = s.write(m.connectPacket()); err != nil {
+		s.close()
+		return nil, err
+	}
+	ack, err := s.waitAck(ctx, mqttConnack, 0)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("%s connect failed: %w", m, err)
+	}
+	if ack.code != 0 {
+		s.close()
+		return nil, fmt.Errorf("%s connection refused with code %d", m, ack.code)
+	}
+	go s.keepAlive(time.Duration(m.options.KeepAliveSecs) * time.Second)
+	return s, nil
+}
+
+func (m *MQTT) connectPacket() []byte {
+	var flags byte = 0x02 // clean session
+	var payload []byte
+	payload = appendMQTTString(payload, m.options.ClientID)
+	if w := m.options.Will; w != nil {
+		flags |= 0x04 | w.QoS<<3
+		if w.Retain {
+			flags |= 0x20
+		}
+		payload = appendMQTTString(payload, w.Topic)
+		payload = appendMQTTString(payload, w.Payload)
+	}
+	if m.options.Username != "" {
+		flags |= 0x80
+		payload = appendMQTTString(payload, m.options.Username)
+	}
+	if m.options.Password != "" {
+		flags |= 0x40
+		payload = appendMQTTString(payload, m.options.Password)
+	}
+
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags, byte(m.options.KeepAliveSecs>>8), byte(m.options.KeepAliveSecs))
+	body = append(body, payload...)
+	return mqttPacket(mqttConnect<<4, body)
+}
+
+// publish sends the message and waits for the acknowledgements required by the QoS.
+func (m *MQTT) publish(ctx context.Context, msg mqttMessage) error {
+	qos := m.options.QoS
+	header := mqttPublish<<4 | qos<<1
+	if m.options.Retain {
+		header |= 0x01
+	}
+
+	body := appendMQTTString(nil, msg.topic)
+	var id uint16
+	if qos > 0 {
+		m.packetID++
+		if m.packetID == 0 {
+			m.packetID = 1
+		}
+		id = m.packetID
+		body = append(body, byte(id>>8), byte(id))
+	}
+	body = append(body, msg.payload...)
+
+	if err := m.session.write(mqttPacket(header, body)); err != nil {
+		return err
+	}
+
+	switch qos {
+	case 1:
+		_, err := m.session.waitAck(ctx, mqttPuback, id)
+		return err
+	case 2:
+		if _, err := m.session.waitAck(ctx, mqttPubrec, id); err != nil {
+			return err
+		}
+		if err := m.session.write([]byte{mqttPubrel<<4 | 0x02, 2, byte(id >> 8), byte(id)}); err != nil {
+			return err
+		}
+		_, err := m.session.waitAck(ctx, mqttPubcomp, id)
+		return err
+	}
+	return nil
+}
+
+type mqttAck struct {
+	kind byte
+	id   uint16
+	code byte // CONNACK return code
+}
+
+// mqttSession is a single connection to the broker. A goroutine reads incoming
+// packets, forwarding acknowledgements, until the connection is closed.
+type mqttSession struct {
+	conn      net.Conn
+	acks      chan mqttAck
+	dead      chan struct{}
+	closeOnce sync.Once
+
+	writeMux  sync.Mutex
+	lastWrite time.Time
+}
+
+func newMQTTSession(conn net.Conn) *mqttSession {
+	s := &mqttSession{
+		conn: conn,
+		acks: make(chan mqttAck, 10),
+		dead: make(chan struct{}),
+	}
+	go s.read()
+	return s
+}
+
+func (s *mqttSession) write(p []byte) error {
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(time.Second * WriteTimeoutSecs))
+	_, err := s.conn.Write(p)
+	s.lastWrite = time.Now()
+	return err
+}
+
+func (s *mqttSession) close() {
+	s.closeOnce.Do(func() {
+		s.conn.Close()
+		close(s.dead)
+	})
+}
+
+func (s *mqttSession) read() {
+	defer s.close()
+	for {
+		header, body, err := readMQTTPacket(s.conn)
+		if err != nil {
+			return
+		}
+		ack := mqttAck{kind: header >> 4}
+		switch ack.kind {
+		case mqttConnack:
+			if len(body) == 2 {
+				ack.code = body[1]
+			}
+		case mqttPuback, mqttPubrec, mqttPubcomp:
+			if len(body) >= 2 {
+				ack.id = binary.BigEndian.Uint16(body)
+			}
+		default:
+			continue // PINGRESP, or messages we never subscribed to.
+		}
+		select {
+		case s.acks <- ack:
+		case <-s.dead:
+			return
+		}
+	}
+}
+
+// waitAck waits for an acknowledgement of the specified kind and packet id.
+func (s *mqttSession) waitAck(ctx context.Context, kind byte, id uint16) (mqttAck, error) {
+	timeout := time.NewTimer(time.Second * MQTTAckTimeoutSecs)
+	defer timeout.Stop()
+	for {
+		select {
+		case ack := <-s.acks:
+			if ack.kind == kind && ack.id == id {
+				return ack, nil
+			}
+		case <-s.dead:
+			return mqttAck{}, errors.New("connection lost")
+		case <-ctx.Done():
+			return mqttAck{}, ctx.Err()
+		case <-timeout.C:
+			return mqttAck{}, errors.New("timed out waiting for acknowledgement")
+		}
+	}
+}
+
+// keepAlive sends PINGREQ when nothing has been written for half the keep alive interval.
+func (s *mqttSession) keepAlive(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.dead:
+			return
+		case <-ticker.C:
+			s.writeMux.Lock()
+			idle := time.Since(s.lastWrite)
+			s.writeMux.Unlock()
+			if idle >= interval/2 {
+				if err := s.write([]byte{mqttPingreq << 4, 0}); err != nil {
+					s.close()
+					return
+				}
+			}
+		}
+	}
+}
+
+// mqttPacket builds a control packet from the fixed header byte and body.
+func mqttPacket(header byte, body []byte) []byte {
+	p := make([]byte, 0, len(body)+5)
+	p = append(p, header)
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		p = append(p, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(p, body...)
+}
+
+func appendMQTTString(p []byte, s string) []byte {
+	p = append(p, byte(len(s)>>8), byte(len(s)))
+	return append(p, s...)
+}
+
+// readMQTTPacket reads one control packet, returning the fixed header byte and body.
+func readMQTTPacket(r io.Reader) (byte, []byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, nil, err
+	}
+	header := b[0]
+
+	var n, mult int = 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n += int(b[0]&0x7f) * mult
+		if b[0]&0x80 == 0 {
+			break
+		}
+		mult *= 128
+	}
+	if n > mqttMaxPacketSize {
+		return 0, nil, errors.New("packet too large")
+	}
+
+	body := make([]byte, n)
+	_, err := io.ReadFull(r, body)
+	return header, body, err
+}
@@ -0,0 +1,150 @@
+package targets
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mqttPublished struct {
+	topic   string
+	payload string
+	qos     byte
+}
+
+// mqttBroker is a minimal broker that acknowledges CONNECT and PUBLISH packets.
+type mqttBroker struct {
+	listener net.Listener
+
+	mux       sync.Mutex
+	connect   []byte
+	published []mqttPublished
+}
+
+func newMQTTBroker(t *testing.T, addr string) *mqttBroker {
+	l, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	b := &mqttBroker{listener: l}
+	go b.serve()
+	return b
+}
+
+func (b *mqttBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *mqttBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		header, body, err := readMQTTPacket(conn)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case mqttConnect:
+			b.mux.Lock()
+			b.connect = body
+			b.mux.Unlock()
+			_, _ = conn.Write([]byte{mqttConnack << 4, 2, 0, 0})
+		case mqttPublish:
+			qos := header >> 1 & 0x03
+			n := int(binary.BigEndian.Uint16(body))
+			pub := mqttPublished{topic: string(body[2 : 2+n]), qos: qos}
+			body = body[2+n:]
+			if qos > 0 {
+				id := body[:2]
+				body = body[2:]
+				_, _ = conn.Write([]byte{mqttPuback << 4, 2, id[0], id[1]})
+			}
+			pub.payload = string(body)
+			b.mux.Lock()
+			b.published = append(b.published, pub)
+			b.mux.Unlock()
+		case mqttDisconnect:
+			return
+		}
+	}
+}
+
+func (b *mqttBroker) getPublished() []mqttPublished {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return append([]mqttPublished(nil), b.published...)
+}
+
+func TestMQTTTarget(t *testing.T) {
+	// reserve a port, then leave the broker down so records are buffered.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	var errs []error
+	var errMux sync.Mutex
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		errMux.Lock()
+		errs = append(errs, err)
+		errMux.Unlock()
+	}))
+	require.NoError(t, err)
+
+	m, err := NewMQTTTarget(MQTTOptions{
+		Broker:            "tcp://" + addr,
+		ClientID:          "logr-test",
+		Topic:             "logs/{level}/{device}",
+		QoS:               1,
+		Will:              &MQTTWill{Topic: "status/logr-test", Payload: "offline", QoS: 1},
+		OfflineBufferSize: 3,
+		Backoff:           &ExponentialBackoff{InitialMillis: 1, MaxMillis: 1},
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(m, "mqtt_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	for i := 0; i < 4; i++ {
+		logger.Info("offline", logr.Int("n", i), logr.String("device", "d+1"))
+		time.Sleep(time.Millisecond * 2)
+	}
+	require.NoError(t, lgr.Flush()) // reconnect fails and is reported
+
+	broker := newMQTTBroker(t, addr)
+	defer broker.listener.Close()
+
+	require.NoError(t, lgr.Flush())
+	assert.Len(t, broker.getPublished(), 3)
+
+	logger.Warn("online", logr.String("device", "d2"))
+	require.NoError(t, lgr.Shutdown())
+
+	published := broker.getPublished()
+	require.Len(t, published, 4, "oldest record should be dropped from the offline buffer")
+	assert.Equal(t, "logs/info/d_1", published[0].topic)
+	assert.Contains(t, published[0].payload, "n=1")
+	assert.Equal(t, "logs/warn/d2", published[3].topic)
+	assert.Equal(t, byte(1), published[3].qos)
+
+	broker.mux.Lock()
+	assert.Contains(t, string(broker.connect), "status/logr-test")
+	broker.mux.Unlock()
+
+	errMux.Lock()
+	defer errMux.Unlock()
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "offline buffer full")
+	assert.Contains(t, errs[1].Error(), "flush failed")
+}
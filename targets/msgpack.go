@@ -0,0 +1,343 @@
+package targets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Minimal MessagePack encoding and decoding, sufficient for protocols such as
+// Fluent Forward. See https://github.com/msgpack/msgpack/blob/master/spec.md.
+
+func appendMsgpackNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func appendMsgpackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendMsgpackUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return append(b, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		return appendUint32(append(b, 0xd2), uint32(v))
+	}
+	return appendUint64(append(b, 0xd3), uint64(v))
+}
+
+func appendMsgpackUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return append(b, 0xcd, byte(v>>8), byte(v))
+	case v <= math.MaxUint32:
+		return appendUint32(append(b, 0xce), uint32(v))
+	}
+	return appendUint64(append(b, 0xcf), v)
+}
+
+func appendMsgpackFloat(b []byte, v float64) []byte {
+	return appendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackBin(b []byte, v []byte) []byte {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, v...)
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	}
+	return appendUint32(append(b, 0xdd), uint32(n))
+}
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	}
+	return appendUint32(append(b, 0xdf), uint32(n))
+}
+
+// appendMsgpackEventTime appends t as a Fluent EventTime (ext type 0) with
+// nanosecond precision.
+func appendMsgpackEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = appendUint32(b, uint32(t.Unix()))
+	return appendUint32(b, uint32(t.Nanosecond()))
+}
+
+// appendMsgpackValue appends common Go types. Unsupported types are encoded as
+// their string representation.
+func appendMsgpackValue(b []byte, v interface{}) []byte {
+	switch val := v.(type) {
+	case nil:
+		return appendMsgpackNil(b)
+	case bool:
+		return appendMsgpackBool(b, val)
+	case int:
+		return appendMsgpackInt(b, int64(val))
+	case int64:
+		return appendMsgpackInt(b, val)
+	case uint64:
+		return appendMsgpackUint(b, val)
+	case float64:
+		return appendMsgpackFloat(b, val)
+	case string:
+		return appendMsgpackString(b, val)
+	case []byte:
+		return appendMsgpackBin(b, val)
+	case []interface{}:
+		b = appendMsgpackArrayHeader(b, len(val))
+		for _, item := range val {
+			b = appendMsgpackValue(b, item)
+		}
+		return b
+	case map[string]interface{}:
+		b = appendMsgpackMapHeader(b, len(val))
+		for k, item := range val {
+			b = appendMsgpackString(b, k)
+			b = appendMsgpackValue(b, item)
+		}
+		return b
+	}
+	return appendMsgpackString(b, fmt.Sprintf("%v", v))
+}
+
+// msgpackDecoder decodes values into nil, bool, int64, uint64, float64, string,
+// []byte, []interface{} and map[string]interface{}. Extension types are returned
+// as []byte.
+type msgpackDecoder struct {
+	r   io.Reader
+	buf [8]byte
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	var p []byte
+	if n <= len(d.buf) {
+		p = d.buf[:n]
+	} else {
+		p = make([]byte, n)
+	}
+	_, err := io.ReadFull(d.r, p)
+	return p, err
+}
+
+func (d *msgpackDecoder) readLen(size int) (int, error) {
+	p, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(p[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(p)), nil
+	}
+	return int(binary.BigEndian.Uint32(p)), nil
+}
+
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	p, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := p[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLen(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeBytes(n)
+	case 0xca:
+		if p, err = d.read(4); err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(p))), nil
+	case 0xcb:
+		if p, err = d.read(8); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(p)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		size := 1 << (c - 0xcc)
+		if p, err = d.read(size); err != nil {
+			return nil, err
+		}
+		var v uint64
+		for _, x := range p {
+			v = v<<8 | uint64(x)
+		}
+		return v, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		if p, err = d.read(size); err != nil {
+			return nil, err
+		}
+		var v uint64
+		for _, x := range p {
+			v = v<<8 | uint64(x)
+		}
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeBytes(1 + 1<<(c-0xd4)) // ext type byte + data
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readLen(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeBytes(n + 1)
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLen(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := d.readLen(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := d.readLen(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+	return nil, fmt.Errorf("invalid msgpack type 0x%x", c)
+}
+
+const msgpackMaxLen = 64 * 1024 * 1024
+
+func (d *msgpackDecoder) decodeBytes(n int) ([]byte, error) {
+	if n > msgpackMaxLen {
+		return nil, errors.New("msgpack value too large")
+	}
+	p := make([]byte, n)
+	_, err := io.ReadFull(d.r, p)
+	return p, err
+}
+
+func (d *msgpackDecoder) decodeString(n int) (string, error) {
+	p, err := d.decodeBytes(n)
+	return string(p), err
+}
+
+func (d *msgpackDecoder) decodeArray(n int) ([]interface{}, error) {
+	if n > msgpackMaxLen {
+		return nil, errors.New("msgpack array too large")
+	}
+	arr := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int) (map[string]interface{}, error) {
+	if n > msgpackMaxLen {
+		return nil, errors.New("msgpack map too large")
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		switch key := k.(type) {
+		case string:
+			m[key] = v
+		case []byte:
+			m[string(key)] = v
+		default:
+			m[fmt.Sprintf("%v", key)] = v
+		}
+	}
+	return m, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
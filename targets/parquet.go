@@ -0,0 +1,459 @@
+package targets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	ParquetDefaultKeyTemplate         = "year={year}/month={month}/day={day}/hour={hour}/{host}-{timestamp}-{seq}.parquet"
+	ParquetDefaultMaxRows             = 100000
+	ParquetMaxRows                    = 10000000
+	ParquetDefaultMaxBytes            = 128 * 1024 * 1024
+	ParquetMaxBytes                   = 1024 * 1024 * 1024
+	ParquetDefaultFlushIntervalMillis = 5 * 60 * 1000
+
+	// Names of the columns output for every log record.
+	ParquetTimeColumn  = "time"
+	ParquetLevelColumn = "level"
+	ParquetMsgColumn   = "msg"
+)
+
+// ParquetColumn describes a column populated from a log record field.
+type ParquetColumn struct {
+	// Name is the column name.
+	Name string `json:"name"`
+
+	// Field is the key of the field containing the column value. Defaults to Name.
+	Field string `json:"field,omitempty"`
+
+	// Type is one of "string", "int64", "double", "bool" or "timestamp". Field values
+	// are converted where possible, e.g. an Int field to a "double" column, and any
+	// field can be output to a "string" column. Columns are null for records without
+	// the field or whose field cannot be converted.
+	Type string `json:"type"`
+}
+
+// ParquetOptions provides parameters for the Parquet target.
+type ParquetOptions struct {
+	// Columns are the typed columns output in addition to the time, level and msg
+	// columns.
+	Columns []ParquetColumn `json:"columns,omitempty"`
+
+	// RawColumn, if not empty, is the name of a string column containing each log
+	// record as output by the target's formatter, e.g. `formatters.JSON`, keeping
+	// fields without a column of their own.
+	RawColumn string `json:"raw_column,omitempty"`
+
+	// Dir is the directory files are written to. Exactly one of Dir and S3 must be set.
+	Dir string `json:"dir,omitempty"`
+
+	// S3 determines the bucket files are uploaded to. Exactly one of Dir and S3 must be set.
+	S3 *S3BlobStoreOptions `json:"s3,omitempty"`
+
+	// KeyTemplate determines each file's name relative to Dir or the S3 prefix, using
+	// the same placeholders as `S3Options.KeyTemplate`. Defaults to
+	// ParquetDefaultKeyTemplate, which uses Hive style partitioning.
+	KeyTemplate string `json:"key_template,omitempty"`
+
+	// DisableCompression, when true, writes uncompressed column data rather than gzipped.
+	DisableCompression bool `json:"disable_compression,omitempty"`
+
+	// BatchOptions determine when files are written. MaxBatchCount is the maximum
+	// number of rows in a file, defaulting to ParquetDefaultMaxRows; MaxBatchBytes
+	// limits the memory used to buffer rows, defaulting to ParquetDefaultMaxBytes;
+	// FlushIntervalMillis defaults to ParquetDefaultFlushIntervalMillis.
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (po ParquetOptions) CheckValid() error {
+	if (po.Dir == "") == (po.S3 == nil) {
+		return errors.New("exactly one of dir and s3 must be set")
+	}
+	if po.S3 != nil {
+		if err := (S3Options{AWSCredentials: po.S3.AWSCredentials, Bucket: po.S3.Bucket}).CheckValid(); err != nil {
+			return fmt.Errorf("invalid s3 options: %w", err)
+		}
+	}
+	if po.KeyTemplate != "" {
+		if err := checkKeyTemplate(po.KeyTemplate); err != nil {
+			return fmt.Errorf("invalid key_template: %w", err)
+		}
+	}
+	_, err := newParquetSchema(po.Columns, po.RawColumn)
+	return err
+}
+
+type parquetColumnKind int
+
+const (
+	parquetTimeKind parquetColumnKind = iota
+	parquetLevelKind
+	parquetMsgKind
+	parquetRawKind
+	parquetFieldKind
+)
+
+type parquetColumnSpec struct {
+	name      string
+	field     string
+	kind      parquetColumnKind
+	typ       int32
+	converted int32
+}
+
+func newParquetSchema(columns []ParquetColumn, raw string) ([]parquetColumnSpec, error) {
+	schema := []parquetColumnSpec{
+		{name: ParquetTimeColumn, kind: parquetTimeKind, typ: parquetInt64, converted: parquetTimestampMillis},
+		{name: ParquetLevelColumn, kind: parquetLevelKind, typ: parquetByteArray, converted: parquetUTF8},
+		{name: ParquetMsgColumn, kind: parquetMsgKind, typ: parquetByteArray, converted: parquetUTF8},
+	}
+	names := map[string]bool{ParquetTimeColumn: true, ParquetLevelColumn: true, ParquetMsgColumn: true}
+
+	for _, c := range columns {
+		if c.Name == "" {
+			return nil, errors.New("column name cannot be empty")
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate column %s", c.Name)
+		}
+		names[c.Name] = true
+
+		spec := parquetColumnSpec{name: c.Name, field: c.Field, kind: parquetFieldKind, converted: parquetNoConvertedType}
+		if spec.field == "" {
+			spec.field = c.Name
+		}
+		switch c.Type {
+		case "string":
+			spec.typ, spec.converted = parquetByteArray, parquetUTF8
+		case "int64":
+			spec.typ = parquetInt64
+		case "double":
+			spec.typ = parquetDouble
+		case "bool":
+			spec.typ = parquetBoolean
+		case "timestamp":
+			spec.typ, spec.converted = parquetInt64, parquetTimestampMillis
+		default:
+			return nil, fmt.Errorf("invalid type %q for column %s", c.Type, c.Name)
+		}
+		schema = append(schema, spec)
+	}
+
+	if raw != "" {
+		if names[raw] {
+			return nil, fmt.Errorf("duplicate column %s", raw)
+		}
+		schema = append(schema, parquetColumnSpec{name: raw, kind: parquetRawKind, typ: parquetByteArray, converted: parquetUTF8})
+	}
+	return schema, nil
+}
+
+// Parquet writes log records to Parquet files, locally or in S3 compatible storage,
+// so archived logs can be queried directly by tools such as Athena and DuckDB.
+// Each file has time, level and msg columns plus a typed column for each configured
+// field. Rows are buffered and a file written when the buffer reaches the maximum
+// number of rows or size, when the flush interval elapses, and when the Logr is
+// flushed.
+type Parquet struct {
+	options ParquetOptions
+	schema  []parquetColumnSpec
+	client  *s3Client
+	batch   *batcher
+	keys    *keyTemplate
+}
+
+// NewParquetTarget creates a target capable of writing log records to Parquet files.
+func NewParquetTarget(options ParquetOptions) (*Parquet, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+	if options.KeyTemplate == "" {
+		options.KeyTemplate = ParquetDefaultKeyTemplate
+	}
+
+	schema, _ := newParquetSchema(options.Columns, options.RawColumn)
+	p := &Parquet{
+		options: options,
+		schema:  schema,
+		keys:    newKeyTemplate(options.KeyTemplate),
+	}
+	if options.S3 != nil {
+		p.client = newS3Client(options.S3.AWSCredentials, options.S3.Bucket, options.S3.Endpoint, options.S3.PathStyle, nil, S3DefaultMaxRetries)
+	}
+
+	limits := BatchOptions{
+		MaxBatchCount:       ParquetMaxRows,
+		MaxBatchBytes:       ParquetMaxBytes,
+		FlushIntervalMillis: ParquetDefaultFlushIntervalMillis,
+	}
+	batchOpts := options.BatchOptions
+	if batchOpts.MaxBatchCount <= 0 {
+		batchOpts.MaxBatchCount = ParquetDefaultMaxRows
+	}
+	if batchOpts.MaxBatchBytes <= 0 {
+		batchOpts.MaxBatchBytes = ParquetDefaultMaxBytes
+	}
+	p.batch = newBatcher(batchOpts.withDefaults(limits), p.send)
+	return p, nil
+}
+
+// Init is called once to initialize the target.
+func (p *Parquet) Init() error {
+	if p.options.Dir != "" {
+		return os.MkdirAll(p.options.Dir, 0750)
+	}
+	return nil
+}
+
+// Write buffers the log record as a row of the next file. The formatted
+// record is only used for the raw column, if configured.
+func (p *Parquet) Write(data []byte, rec *logr.LogRec) (int, error) {
+	row := p.encodeRow(data, rec)
+	if err := p.batch.add(row, p.keys.partition(rec.Time()), rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Flush writes any buffered rows.
+func (p *Parquet) Flush() error {
+	return p.batch.flush()
+}
+
+// Shutdown writes any buffered rows.
+func (p *Parquet) Shutdown() error {
+	err := p.batch.flush()
+	if p.client != nil {
+		p.client.close()
+	}
+	return err
+}
+
+// String returns a string representation of this target.
+func (p *Parquet) String() string {
+	if p.options.S3 != nil {
+		return fmt.Sprintf("ParquetTarget[s3://%s/%s]", p.options.S3.Bucket, p.options.S3.Prefix)
+	}
+	return fmt.Sprintf("ParquetTarget[%s]", p.options.Dir)
+}
+
+// send writes one file per partition, in the order partitions first appear.
+func (p *Parquet) send(items []batchItem) error {
+	order, groups := groupByKey(items)
+
+	var errs []string
+	for _, partition := range order {
+		rows := groups[partition]
+		columns, err := p.decodeRows(rows)
+		if err != nil {
+			return err
+		}
+		data, err := encodeParquet(columns, len(rows), !p.options.DisableCompression)
+		if err != nil {
+			return err
+		}
+		key := p.keys.objectKey(partition)
+		if err := p.put(key, data); err != nil {
+			errs = append(errs, fmt.Sprintf("%s could not write %d records to %s: %v", p, len(rows), key, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (p *Parquet) put(key string, data []byte) error {
+	if p.client != nil {
+		return p.client.put(p.options.S3.Prefix+key, data, "application/vnd.apache.parquet")
+	}
+	path := filepath.Join(p.options.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// encodeRow encodes the column values of a log record for buffering. Each value
+// is preceded by a byte indicating whether it is present.
+func (p *Parquet) encodeRow(data []byte, rec *logr.LogRec) []byte {
+	var buf bytes.Buffer
+	var b [binary.MaxVarintLen64]byte
+
+	writeBytes := func(s []byte) {
+		buf.WriteByte(1)
+		n := binary.PutUvarint(b[:], uint64(len(s)))
+		buf.Write(b[:n])
+		buf.Write(s)
+	}
+	writeUint := func(n uint64) {
+		buf.WriteByte(1)
+		binary.LittleEndian.PutUint64(b[:8], n)
+		buf.Write(b[:8])
+	}
+
+	fields := rec.Fields()
+	for _, col := range p.schema {
+		switch col.kind {
+		case parquetTimeKind:
+			writeUint(uint64(timeMillis(rec.Time())))
+		case parquetLevelKind:
+			writeBytes([]byte(rec.Level().Name))
+		case parquetMsgKind:
+			writeBytes([]byte(rec.Msg()))
+		case parquetRawKind:
+			writeBytes(bytes.TrimRight(data, "\n"))
+		case parquetFieldKind:
+			f, ok := findField(fields, col.field)
+			if !ok {
+				buf.WriteByte(0)
+				continue
+			}
+			switch col.typ {
+			case parquetByteArray:
+				if f.Type == logr.StringType {
+					writeBytes([]byte(f.String))
+					continue
+				}
+				var sb bytes.Buffer
+				if err := f.ValueString(&sb, nil); err != nil {
+					buf.WriteByte(0)
+					continue
+				}
+				writeBytes(sb.Bytes())
+			case parquetInt64:
+				if n, ok := fieldInt64(f, col.converted == parquetTimestampMillis); ok {
+					writeUint(uint64(n))
+				} else {
+					buf.WriteByte(0)
+				}
+			case parquetDouble:
+				if v, ok := fieldFloat64(f); ok {
+					writeUint(math.Float64bits(v))
+				} else {
+					buf.WriteByte(0)
+				}
+			case parquetBoolean:
+				if f.Type == logr.BoolType {
+					buf.WriteByte(1)
+					buf.WriteByte(byte(f.Integer))
+				} else {
+					buf.WriteByte(0)
+				}
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeRows converts buffered rows into column data.
+func (p *Parquet) decodeRows(rows []batchItem) ([]*parquetColumnData, error) {
+	columns := make([]*parquetColumnData, len(p.schema))
+	for i, col := range p.schema {
+		columns[i] = &parquetColumnData{
+			name:      col.name,
+			typ:       col.typ,
+			converted: col.converted,
+			optional:  col.kind == parquetFieldKind,
+		}
+	}
+
+	errCorrupt := errors.New("corrupt buffered row")
+	for _, row := range rows {
+		data := row.data
+		for _, col := range columns {
+			if len(data) == 0 {
+				return nil, errCorrupt
+			}
+			present := data[0] == 1
+			data = data[1:]
+			if !present {
+				col.appendNull()
+				continue
+			}
+			col.present = append(col.present, true)
+
+			switch col.typ {
+			case parquetByteArray:
+				n, l := binary.Uvarint(data)
+				if l <= 0 || uint64(len(data)-l) < n {
+					return nil, errCorrupt
+				}
+				col.strings = append(col.strings, data[l:l+int(n)])
+				data = data[l+int(n):]
+			case parquetInt64, parquetDouble:
+				if len(data) < 8 {
+					return nil, errCorrupt
+				}
+				v := binary.LittleEndian.Uint64(data)
+				if col.typ == parquetInt64 {
+					col.ints = append(col.ints, int64(v))
+				} else {
+					col.floats = append(col.floats, math.Float64frombits(v))
+				}
+				data = data[8:]
+			case parquetBoolean:
+				if len(data) < 1 {
+					return nil, errCorrupt
+				}
+				col.bools = append(col.bools, data[0] != 0)
+				data = data[1:]
+			}
+		}
+	}
+	return columns, nil
+}
+
+func findField(fields []logr.Field, key string) (logr.Field, bool) {
+	for _, f := range fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return logr.Field{}, false
+}
+
+func timeMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// fieldInt64 returns the value of an integer field, or a time field in Unix
+// milliseconds when timestamp is true.
+func fieldInt64(f logr.Field, timestamp bool) (int64, bool) {
+	switch f.Type {
+	case logr.TimestampMillisType:
+		return f.Integer, true
+	case logr.TimeType:
+		if t, ok := f.Interface.(time.Time); ok && timestamp {
+			return timeMillis(t), true
+		}
+	case logr.Int64Type, logr.Int32Type, logr.IntType, logr.Uint64Type, logr.Uint32Type, logr.UintType, logr.DurationType:
+		if !timestamp {
+			return f.Integer, true
+		}
+	}
+	return 0, false
+}
+
+func fieldFloat64(f logr.Field) (float64, bool) {
+	switch f.Type {
+	case logr.Float64Type, logr.Float32Type:
+		return f.Float, true
+	case logr.Uint64Type, logr.Uint32Type, logr.UintType:
+		return float64(uint64(f.Integer)), true
+	case logr.Int64Type, logr.Int32Type, logr.IntType, logr.DurationType:
+		return float64(f.Integer), true
+	}
+	return 0, false
+}
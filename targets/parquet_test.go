@@ -0,0 +1,214 @@
+package targets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftStructValue is a decoded Thrift compact protocol struct, keyed by field id.
+type thriftStructValue map[int16]interface{}
+
+type thriftReader struct {
+	r *bytes.Reader
+}
+
+func (tr thriftReader) varint() int64 {
+	u, err := binary.ReadUvarint(tr.r)
+	if err != nil {
+		panic(err)
+	}
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (tr thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1:
+		return true
+	case 2:
+		return false
+	case thriftI32, thriftI64:
+		return tr.varint()
+	case thriftBinary:
+		n, _ := binary.ReadUvarint(tr.r)
+		b := make([]byte, n)
+		_, _ = tr.r.Read(b)
+		return string(b)
+	case thriftList:
+		hdr, _ := tr.r.ReadByte()
+		size := int(hdr >> 4)
+		if size == 15 {
+			n, _ := binary.ReadUvarint(tr.r)
+			size = int(n)
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = tr.value(hdr & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		s := thriftStructValue{}
+		var last int16
+		for {
+			hdr, _ := tr.r.ReadByte()
+			if hdr == 0 {
+				return s
+			}
+			id := last + int16(hdr>>4)
+			if hdr>>4 == 0 {
+				id = int16(tr.varint())
+			}
+			s[id] = tr.value(hdr & 0x0f)
+			last = id
+		}
+	}
+	panic("unsupported thrift type")
+}
+
+type parquetFile struct {
+	meta    thriftStructValue
+	columns map[string][]interface{}
+}
+
+// readParquet decodes files written by encodeParquet.
+func readParquet(t *testing.T, data []byte) parquetFile {
+	require.True(t, bytes.HasPrefix(data, parquetMagic))
+	require.True(t, bytes.HasSuffix(data, parquetMagic))
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLen : len(data)-8]
+	meta := thriftReader{bytes.NewReader(footer)}.value(thriftStruct).(thriftStructValue)
+
+	numRows := int(meta[3].(int64))
+	schema := meta[2].([]interface{})
+	pf := parquetFile{meta: meta, columns: map[string][]interface{}{}}
+
+	chunks := meta[4].([]interface{})[0].(thriftStructValue)[1].([]interface{})
+	for i, c := range chunks {
+		elem := schema[i+1].(thriftStructValue)
+		name := elem[4].(string)
+		optional := elem[3].(int64) == int64(parquetOptional)
+		colMeta := c.(thriftStructValue)[3].(thriftStructValue)
+
+		r := bytes.NewReader(data[colMeta[9].(int64):])
+		hdr := thriftReader{r}.value(thriftStruct).(thriftStructValue)
+		page := make([]byte, hdr[3].(int64))
+		_, _ = r.Read(page)
+		if colMeta[4].(int64) == int64(parquetGzip) {
+			zr, err := gzip.NewReader(bytes.NewReader(page))
+			require.NoError(t, err)
+			page, err = ioutil.ReadAll(zr)
+			require.NoError(t, err)
+		}
+		require.Len(t, page, int(hdr[2].(int64)))
+
+		present := make([]bool, numRows)
+		if optional {
+			n := binary.LittleEndian.Uint32(page)
+			levels := bytes.NewReader(page[4 : 4+n])
+			for row := 0; levels.Len() > 0; {
+				run, _ := binary.ReadUvarint(levels)
+				v, _ := levels.ReadByte()
+				for j := 0; j < int(run>>1); j++ {
+					present[row] = v == 1
+					row++
+				}
+			}
+			page = page[4+n:]
+		} else {
+			for j := range present {
+				present[j] = true
+			}
+		}
+
+		var values []interface{}
+		bit := 0
+		for _, p := range present {
+			if !p {
+				values = append(values, nil)
+				continue
+			}
+			switch elem[1].(int64) {
+			case int64(parquetByteArray):
+				n := binary.LittleEndian.Uint32(page)
+				values = append(values, string(page[4:4+n]))
+				page = page[4+n:]
+			case int64(parquetInt64):
+				values = append(values, int64(binary.LittleEndian.Uint64(page)))
+				page = page[8:]
+			case int64(parquetDouble):
+				values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(page)))
+				page = page[8:]
+			case int64(parquetBoolean):
+				values = append(values, page[bit/8]&(1<<(uint(bit)%8)) != 0)
+				bit++
+			}
+		}
+		pf.columns[name] = values
+	}
+	return pf
+}
+
+func TestParquetTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "parquet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2021, 3, 4, 10, 59, 0, 0, time.UTC)
+	lgr, err := logr.New(logr.Clock(func() time.Time { return now }), logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	p, err := NewParquetTarget(ParquetOptions{
+		Dir: dir,
+		Columns: []ParquetColumn{
+			{Name: "user", Type: "string"},
+			{Name: "status", Type: "int64"},
+			{Name: "latency_ms", Field: "latency", Type: "double"},
+			{Name: "cached", Type: "bool"},
+		},
+		RawColumn:   "raw",
+		KeyTemplate: "dt={year}-{month}-{day}/{seq}.parquet",
+	})
+	require.NoError(t, err)
+	err = lgr.AddTarget(p, "parquet_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger().With(logr.String("user", "bob"))
+	logger.Info("request", logr.Int("status", 200), logr.Float64("latency", 1.5), logr.Bool("cached", true))
+	logger.Warn("slow request", logr.Int("latency", 900), logr.String("status", "n/a"))
+	require.NoError(t, lgr.Shutdown())
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "dt=2021-03-04", "000001.parquet"))
+	require.NoError(t, err)
+	pf := readParquet(t, data)
+
+	assert.Equal(t, int64(2), pf.meta[3])
+	millis := now.UnixNano() / int64(time.Millisecond)
+	assert.Equal(t, []interface{}{millis, millis}, pf.columns["time"])
+	assert.Equal(t, []interface{}{"info", "warn"}, pf.columns["level"])
+	assert.Equal(t, []interface{}{"request", "slow request"}, pf.columns["msg"])
+	assert.Equal(t, []interface{}{"bob", "bob"}, pf.columns["user"])
+	assert.Equal(t, []interface{}{int64(200), nil}, pf.columns["status"])
+	assert.Equal(t, []interface{}{1.5, 900.0}, pf.columns["latency_ms"])
+	assert.Equal(t, []interface{}{true, nil}, pf.columns["cached"])
+	assert.Contains(t, pf.columns["raw"][1], `"latency":900`)
+}
+
+func TestParquetOptionsCheckValid(t *testing.T) {
+	assert.Error(t, ParquetOptions{}.CheckValid(), "missing destination")
+	assert.Error(t, ParquetOptions{Dir: "x", Columns: []ParquetColumn{{Name: "msg", Type: "string"}}}.CheckValid(), "duplicate column")
+	assert.Error(t, ParquetOptions{Dir: "x", Columns: []ParquetColumn{{Name: "n", Type: "int"}}}.CheckValid(), "invalid type")
+	assert.NoError(t, ParquetOptions{Dir: "x", Columns: []ParquetColumn{{Name: "n", Type: "int64"}}}.CheckValid())
+}
@@ -0,0 +1,329 @@
+package targets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"math"
+)
+
+// This file contains a minimal Parquet file encoder: a single row group per file,
+// one PLAIN encoded data page per column, and optional gzip compression. See
+// https://github.com/apache/parquet-format for the format specification.
+
+var parquetMagic = []byte("PAR1")
+
+// Parquet physical types.
+const (
+	parquetBoolean   int32 = 0
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+)
+
+// Parquet converted types.
+const (
+	parquetNoConvertedType int32 = -1
+	parquetUTF8            int32 = 0
+	parquetTimestampMillis int32 = 9
+)
+
+// Parquet enum values used by the encoder.
+const (
+	parquetRequired     int32 = 0
+	parquetOptional     int32 = 1
+	parquetPlain        int32 = 0
+	parquetRLE          int32 = 3
+	parquetUncompressed int32 = 0
+	parquetGzip         int32 = 2
+	parquetDataPage     int32 = 0
+)
+
+// parquetColumnData holds the values of one column of a row group. Null values
+// are absent from the values slice and marked false in present.
+type parquetColumnData struct {
+	name      string
+	typ       int32
+	converted int32
+	optional  bool
+
+	present []bool
+	strings [][]byte
+	ints    []int64
+	floats  []float64
+	bools   []bool
+}
+
+func (c *parquetColumnData) appendNull() {
+	c.present = append(c.present, false)
+}
+
+// encodeValues returns the PLAIN encoding of the non-null values.
+func (c *parquetColumnData) encodeValues(buf *bytes.Buffer) {
+	var b [8]byte
+	switch c.typ {
+	case parquetByteArray:
+		for _, s := range c.strings {
+			binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+			buf.Write(b[:4])
+			buf.Write(s)
+		}
+	case parquetInt64:
+		for _, n := range c.ints {
+			binary.LittleEndian.PutUint64(b[:], uint64(n))
+			buf.Write(b[:])
+		}
+	case parquetDouble:
+		for _, f := range c.floats {
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+			buf.Write(b[:])
+		}
+	case parquetBoolean:
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				packed[i/8] |= 1 << (uint(i) % 8)
+			}
+		}
+		buf.Write(packed)
+	}
+}
+
+// encodeLevels writes definition levels, with a bit width of one, using the RLE
+// run form of the RLE/bit-packing hybrid encoding, prefixed by their length.
+func encodeLevels(buf *bytes.Buffer, present []bool) {
+	var runs bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	for i := 0; i < len(present); {
+		j := i
+		for j < len(present) && present[j] == present[i] {
+			j++
+		}
+		n := binary.PutUvarint(tmp[:], uint64(j-i)<<1)
+		runs.Write(tmp[:n])
+		if present[i] {
+			runs.WriteByte(1)
+		} else {
+			runs.WriteByte(0)
+		}
+		i = j
+	}
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(runs.Len()))
+	buf.Write(length[:])
+	buf.Write(runs.Bytes())
+}
+
+// encodeParquet returns a Parquet file containing the columns as one row group.
+func encodeParquet(columns []*parquetColumnData, numRows int, compress bool) ([]byte, error) {
+	codec := parquetUncompressed
+	if compress {
+		codec = parquetGzip
+	}
+
+	type chunkMeta struct {
+		offset       int64
+		uncompressed int64
+		compressed   int64
+	}
+	chunks := make([]chunkMeta, len(columns))
+
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	for i, col := range columns {
+		var page bytes.Buffer
+		if col.optional {
+			encodeLevels(&page, col.present)
+		}
+		col.encodeValues(&page)
+
+		data := page.Bytes()
+		if compress {
+			var zbuf bytes.Buffer
+			zw := gzip.NewWriter(&zbuf)
+			if _, err := zw.Write(data); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			data = zbuf.Bytes()
+		}
+
+		var hdr thriftWriter
+		hdr.begin()
+		hdr.i32Field(1, parquetDataPage)
+		hdr.i32Field(2, int32(page.Len()))
+		hdr.i32Field(3, int32(len(data)))
+		hdr.structField(5) // DataPageHeader
+		hdr.i32Field(1, int32(numRows))
+		hdr.i32Field(2, parquetPlain)
+		hdr.i32Field(3, parquetRLE)
+		hdr.i32Field(4, parquetRLE)
+		hdr.end()
+		hdr.end()
+
+		chunks[i] = chunkMeta{
+			offset:       int64(file.Len()),
+			uncompressed: int64(hdr.buf.Len() + page.Len()),
+			compressed:   int64(hdr.buf.Len() + len(data)),
+		}
+		file.Write(hdr.buf.Bytes())
+		file.Write(data)
+	}
+
+	var meta thriftWriter
+	meta.begin()
+	meta.i32Field(1, 1) // version
+
+	meta.listField(2, thriftStruct, len(columns)+1)
+	meta.begin() // root schema element
+	meta.stringField(4, "schema")
+	meta.i32Field(5, int32(len(columns)))
+	meta.end()
+	for _, col := range columns {
+		meta.begin()
+		meta.i32Field(1, col.typ)
+		repetition := parquetRequired
+		if col.optional {
+			repetition = parquetOptional
+		}
+		meta.i32Field(3, repetition)
+		meta.stringField(4, col.name)
+		if col.converted != parquetNoConvertedType {
+			meta.i32Field(6, col.converted)
+		}
+		meta.end()
+	}
+
+	meta.i64Field(3, int64(numRows))
+
+	var totalSize int64
+	for _, c := range chunks {
+		totalSize += c.uncompressed
+	}
+	meta.listField(4, thriftStruct, 1)
+	meta.begin() // RowGroup
+	meta.listField(1, thriftStruct, len(columns))
+	for i, col := range columns {
+		meta.begin() // ColumnChunk
+		meta.i64Field(2, chunks[i].offset)
+		meta.structField(3) // ColumnMetaData
+		meta.i32Field(1, col.typ)
+		meta.listField(2, thriftI32, 2)
+		meta.i32(parquetPlain)
+		meta.i32(parquetRLE)
+		meta.listField(3, thriftBinary, 1)
+		meta.string(col.name)
+		meta.i32Field(4, codec)
+		meta.i64Field(5, int64(numRows))
+		meta.i64Field(6, chunks[i].uncompressed)
+		meta.i64Field(7, chunks[i].compressed)
+		meta.i64Field(9, chunks[i].offset)
+		meta.end()
+		meta.end()
+	}
+	meta.i64Field(2, totalSize)
+	meta.i64Field(3, int64(numRows))
+	meta.end()
+
+	meta.stringField(6, "logr")
+	meta.end()
+
+	file.Write(meta.buf.Bytes())
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.buf.Len()))
+	file.Write(length[:])
+	file.Write(parquetMagic)
+	return file.Bytes(), nil
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftStruct byte = 12
+)
+
+// thriftWriter encodes structs using the Thrift compact protocol, as used for
+// Parquet metadata.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // id of the last field written for each open struct
+}
+
+// begin starts a struct; top level structs and list elements are started with
+// begin, struct fields with structField.
+func (w *thriftWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+// end writes the stop field and closes the innermost struct.
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	top := len(w.last) - 1
+	if delta := id - w.last[top]; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	w.last[top] = id
+}
+
+func (w *thriftWriter) varint(n int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(tmp[:], uint64((n<<1)^(n>>63)))
+	w.buf.Write(tmp[:l])
+}
+
+func (w *thriftWriter) i32(n int32) {
+	w.varint(int64(n))
+}
+
+func (w *thriftWriter) string(s string) {
+	var tmp [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(tmp[:], uint64(len(s)))
+	w.buf.Write(tmp[:l])
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) i32Field(id int16, n int32) {
+	w.field(id, thriftI32)
+	w.i32(n)
+}
+
+func (w *thriftWriter) i64Field(id int16, n int64) {
+	w.field(id, thriftI64)
+	w.varint(n)
+}
+
+func (w *thriftWriter) stringField(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.string(s)
+}
+
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+// listField writes the header of a list field; the caller then writes size elements.
+func (w *thriftWriter) listField(id int16, elemType byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elemType)
+	var tmp [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(tmp[:], uint64(size))
+	w.buf.Write(tmp[:l])
+}
@@ -0,0 +1,342 @@
+package targets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// Limits imposed by the Pub/Sub publish API. The byte limit allows for base64
+// encoding of the message data within the 10MB request limit.
+const (
+	PubSubMaxMessagesPerRequest = 1000
+	PubSubMaxRequestBytes       = 7 * 1024 * 1024
+
+	PubSubDefaultEndpoint            = "https://pubsub.googleapis.com"
+	PubSubDefaultMaxRetries          = 5
+	PubSubDefaultFlushIntervalMillis = 1000
+	PubSubRequestTimeoutSecs         = 30
+
+	pubsubScope = "https://www.googleapis.com/auth/pubsub"
+)
+
+// Flow control behaviors for when the outstanding message limits are exceeded.
+const (
+	FlowControlBlock = "block"
+	FlowControlDrop  = "drop"
+)
+
+// PubSubFlowControl limits the number and size of messages that are buffered
+// or being published at once.
+type PubSubFlowControl struct {
+	// MaxOutstandingMessages is the maximum number of unpublished messages. Zero means no limit.
+	MaxOutstandingMessages int `json:"max_outstanding_messages,omitempty"`
+
+	// MaxOutstandingBytes is the maximum total size of unpublished messages. Zero means no limit.
+	MaxOutstandingBytes int `json:"max_outstanding_bytes,omitempty"`
+
+	// LimitExceededBehavior is one of "block" (the default), which waits for buffered
+	// messages to be published, or "drop", which discards the record with an error.
+	LimitExceededBehavior string `json:"limit_exceeded_behavior,omitempty"`
+}
+
+// PubSubOptions provides parameters for the Pub/Sub target.
+type PubSubOptions struct {
+	GCPCredentials
+
+	// Project is the Google Cloud project ID containing the topic.
+	Project string `json:"project"`
+
+	// Topic is the name of the topic to publish to.
+	Topic string `json:"topic"`
+
+	// Endpoint overrides the service endpoint. When using ordering keys a regional
+	// endpoint, e.g. "https://us-east1-pubsub.googleapis.com", is recommended.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// OrderingKey is a template for the message ordering key, e.g. "{user_id}".
+	// See `fieldTemplate`. Messages with the same ordering key are delivered in order
+	// to subscriptions with message ordering enabled. Empty means no ordering key.
+	OrderingKey string `json:"ordering_key,omitempty"`
+
+	// MaxRetries is the maximum number of times a batch is retried when publishing
+	// fails with a transient error. Defaults to PubSubDefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between retries. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	FlowControl PubSubFlowControl `json:"flow_control,omitempty"`
+
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (po PubSubOptions) CheckValid() error {
+	if po.Project == "" {
+		return errors.New("missing project")
+	}
+	if po.Topic == "" {
+		return errors.New("missing topic")
+	}
+	if po.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	fc := po.FlowControl
+	if fc.MaxOutstandingMessages < 0 || fc.MaxOutstandingBytes < 0 {
+		return errors.New("flow control limits cannot be negative")
+	}
+	switch fc.LimitExceededBehavior {
+	case "", FlowControlBlock, FlowControlDrop:
+	default:
+		return fmt.Errorf("invalid limit_exceeded_behavior %q", fc.LimitExceededBehavior)
+	}
+	if _, err := parseFieldTemplate(po.OrderingKey); err != nil {
+		return fmt.Errorf("invalid ordering_key: %w", err)
+	}
+	if po.Backoff != nil {
+		return po.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// PubSub publishes log records to a Google Cloud Pub/Sub topic.
+type PubSub struct {
+	options  PubSubOptions
+	url      string
+	keyTmpl  *fieldTemplate
+	backoff  Backoff
+	client   *http.Client
+	tokens   *gcpTokenSource
+	batch    *batcher
+	flow     flowController
+	shutdown chan struct{}
+
+	shutdownOnce sync.Once
+}
+
+// NewPubSubTarget creates a target capable of publishing log records to Pub/Sub.
+func NewPubSubTarget(options PubSubOptions) (*PubSub, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	ps := &PubSub{
+		options:  options,
+		backoff:  DefaultBackoff(),
+		client:   &http.Client{Timeout: time.Second * PubSubRequestTimeoutSecs},
+		shutdown: make(chan struct{}),
+	}
+	ps.keyTmpl, _ = parseFieldTemplate(options.OrderingKey)
+	if options.Backoff != nil {
+		ps.backoff = options.Backoff
+	}
+	if ps.options.MaxRetries == 0 {
+		ps.options.MaxRetries = PubSubDefaultMaxRetries
+	}
+	ps.flow = flowController{
+		maxCount: options.FlowControl.MaxOutstandingMessages,
+		maxBytes: options.FlowControl.MaxOutstandingBytes,
+	}
+
+	endpoint := options.Endpoint
+	if endpoint == "" {
+		endpoint = PubSubDefaultEndpoint
+	}
+	ps.url = fmt.Sprintf("%s/v1/projects/%s/topics/%s:publish", endpoint,
+		url.PathEscape(options.Project), url.PathEscape(options.Topic))
+
+	var err error
+	if ps.tokens, err = newGCPTokenSource(options.GCPCredentials, pubsubScope, ps.client); err != nil {
+		return nil, err
+	}
+
+	ps.batch = newBatcher(options.BatchOptions.withDefaults(BatchOptions{
+		MaxBatchCount:       PubSubMaxMessagesPerRequest,
+		MaxBatchBytes:       PubSubMaxRequestBytes,
+		FlushIntervalMillis: PubSubDefaultFlushIntervalMillis,
+	}), ps.send)
+	return ps, nil
+}
+
+// Init is called once to initialize the target.
+func (ps *PubSub) Init() error {
+	return nil
+}
+
+// Write buffers the formatted log record to be published with the next batch.
+func (ps *PubSub) Write(p []byte, rec *logr.LogRec) (int, error) {
+	if len(p) > PubSubMaxRequestBytes {
+		return 0, fmt.Errorf("log record of %d bytes exceeds %s limit of %d bytes", len(p), ps, PubSubMaxRequestBytes)
+	}
+
+	if !ps.flow.acquire(len(p)) {
+		if ps.options.FlowControl.LimitExceededBehavior == FlowControlDrop {
+			return 0, fmt.Errorf("%s flow control limit exceeded; log record dropped", ps)
+		}
+		// publish what is buffered to make room; once flushed nothing is outstanding.
+		if err := ps.batch.flush(); err != nil {
+			rec.Logger().Logr().ReportError(err)
+		}
+		ps.flow.forceAcquire(len(p))
+	}
+
+	key := ps.keyTmpl.execute(rec)
+	if err := ps.batch.add(p, key, rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush publishes any buffered log records.
+func (ps *PubSub) Flush() error {
+	return ps.batch.flush()
+}
+
+// Shutdown publishes any buffered log records.
+func (ps *PubSub) Shutdown() error {
+	err := ps.batch.flush()
+	ps.shutdownOnce.Do(func() { close(ps.shutdown) })
+	return err
+}
+
+// String returns a string representation of this target.
+func (ps *PubSub) String() string {
+	return fmt.Sprintf("PubSubTarget[%s/%s]", ps.options.Project, ps.options.Topic)
+}
+
+type pubsubMessage struct {
+	Data        []byte `json:"data"` // base64 encoded by encoding/json
+	OrderingKey string `json:"orderingKey,omitempty"`
+}
+
+type pubsubError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// send publishes a batch. The batch is retried as a whole since the publish API
+// is all or nothing.
+func (ps *PubSub) send(items []batchItem) error {
+	var size int
+	msgs := make([]pubsubMessage, 0, len(items))
+	for _, item := range items {
+		msgs = append(msgs, pubsubMessage{Data: item.data, OrderingKey: item.key})
+		size += len(item.data)
+	}
+	defer ps.flow.release(len(items), size)
+
+	body, err := json.Marshal(struct {
+		Messages []pubsubMessage `json:"messages"`
+	}{Messages: msgs})
+	if err != nil {
+		return err
+	}
+
+	err = retry(ps.backoff, ps.options.MaxRetries, ps.shutdown, func() (bool, error) {
+		return ps.publish(body)
+	})
+	if err != nil {
+		return fmt.Errorf("%s could not publish %d messages: %w", ps, len(items), err)
+	}
+	return nil
+}
+
+func (ps *PubSub) publish(body []byte) (retryable bool, err error) {
+	token, err := ps.tokens.Token()
+	if err != nil {
+		return true, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, ps.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := ps.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+
+	var pe pubsubError
+	_ = json.Unmarshal(respBody, &pe)
+	err = fmt.Errorf("publish failed with status %d: %s %s", resp.StatusCode, pe.Error.Status, pe.Error.Message)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		ps.tokens.Invalidate()
+		return true, err
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, err
+	}
+	switch pe.Error.Status {
+	case "UNAVAILABLE", "RESOURCE_EXHAUSTED", "DEADLINE_EXCEEDED", "ABORTED", "INTERNAL":
+		return true, err
+	}
+	return false, err
+}
+
+// flowController tracks the number and size of outstanding messages.
+type flowController struct {
+	maxCount int
+	maxBytes int
+
+	mux   sync.Mutex
+	count int
+	bytes int
+}
+
+// acquire reserves room for a message, returning false if that would exceed the limits.
+// A message is always accepted when nothing is outstanding, so that a single message
+// larger than the byte limit cannot stall the target.
+func (fc *flowController) acquire(size int) bool {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	if fc.count > 0 {
+		if fc.maxCount > 0 && fc.count+1 > fc.maxCount {
+			return false
+		}
+		if fc.maxBytes > 0 && fc.bytes+size > fc.maxBytes {
+			return false
+		}
+	}
+	fc.count++
+	fc.bytes += size
+	return true
+}
+
+// forceAcquire reserves room for a message regardless of limits.
+func (fc *flowController) forceAcquire(size int) {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+	fc.count++
+	fc.bytes += size
+}
+
+func (fc *flowController) release(count int, size int) {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+	fc.count -= count
+	fc.bytes -= size
+}
@@ -0,0 +1,138 @@
+package targets
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pubsubServer struct {
+	mux         sync.Mutex
+	tokens      int
+	auth        []string
+	messages    []pubsubMessage
+	unavailable int // number of publish requests to fail with 503
+}
+
+func (ps *pubsubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	if r.URL.Path == "/token" {
+		_ = r.ParseForm()
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.Form.Get("assertion"), ".") != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ps.tokens++
+		fmt.Fprintf(w, `{"access_token":"token%d","expires_in":3600,"token_type":"Bearer"}`, ps.tokens)
+		return
+	}
+
+	if r.URL.Path != "/v1/projects/proj/topics/logs:publish" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	ps.auth = append(ps.auth, r.Header.Get("Authorization"))
+	if ps.unavailable > 0 {
+		ps.unavailable--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"try again","status":"UNAVAILABLE"}}`)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	var req struct {
+		Messages []pubsubMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ps.messages = append(ps.messages, req.Messages...)
+	fmt.Fprint(w, `{"messageIds":["1"]}`)
+}
+
+func testServiceAccount(t *testing.T, tokenURI string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	data, err := json.Marshal(gcpServiceAccount{
+		ClientEmail: "logr@proj.iam.gserviceaccount.com",
+		PrivateKey:  string(pemKey),
+		TokenURI:    tokenURI,
+	})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestPubSubTarget(t *testing.T) {
+	pss := &pubsubServer{unavailable: 1}
+	server := httptest.NewServer(pss)
+	defer server.Close()
+
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	ps, err := NewPubSubTarget(PubSubOptions{
+		GCPCredentials: GCPCredentials{CredentialsJSON: testServiceAccount(t, server.URL+"/token")},
+		Project:        "proj",
+		Topic:          "logs",
+		Endpoint:       server.URL,
+		OrderingKey:    "{user}",
+		Backoff:        &ExponentialBackoff{InitialMillis: 1, MaxMillis: 5},
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(ps, "pubsub_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	for i := 0; i < 4; i++ {
+		logger.Info("msg", logr.String("user", fmt.Sprintf("u%d", i%2)))
+	}
+	require.NoError(t, lgr.Shutdown())
+
+	pss.mux.Lock()
+	defer pss.mux.Unlock()
+
+	assert.Equal(t, 1, pss.tokens, "token should be cached")
+	assert.Equal(t, []string{"Bearer token1", "Bearer token1"}, pss.auth, "publish should be retried once")
+	require.Len(t, pss.messages, 4)
+	for i, msg := range pss.messages {
+		assert.Equal(t, fmt.Sprintf("u%d", i%2), msg.OrderingKey)
+		assert.Contains(t, string(msg.Data), "msg")
+	}
+}
+
+func TestFlowController(t *testing.T) {
+	fc := flowController{maxCount: 2, maxBytes: 100}
+
+	assert.True(t, fc.acquire(500), "first message always fits")
+	assert.False(t, fc.acquire(1))
+	fc.release(1, 500)
+
+	assert.True(t, fc.acquire(60))
+	assert.False(t, fc.acquire(60), "byte limit")
+	assert.True(t, fc.acquire(40))
+	assert.False(t, fc.acquire(0), "count limit")
+}
@@ -0,0 +1,373 @@
+package targets
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	RedisDefaultAddr                = "localhost:6379"
+	RedisDefaultField               = "log"
+	RedisDefaultMaxBatchCount       = 1000
+	RedisDefaultMaxBatchBytes       = 4 * 1024 * 1024
+	RedisDefaultFlushIntervalMillis = 500
+	RedisDefaultMaxRetries          = 5
+)
+
+// RedisOptions provides parameters for the Redis Streams target.
+type RedisOptions struct {
+	Addr     string `json:"addr,omitempty"` // defaults to RedisDefaultAddr
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	DB       int    `json:"db,omitempty"`
+
+	TLS      bool   `json:"tls,omitempty"`
+	Cert     string `json:"cert,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+
+	// Stream is a template for the stream key, e.g. "logs" or "logs:{level}". See `fieldTemplate`.
+	Stream string `json:"stream"`
+
+	// Field is the name of the entry field holding the formatted record. Defaults
+	// to RedisDefaultField. Each entry also has a "level" field.
+	Field string `json:"field,omitempty"`
+
+	// MaxLen, when greater than zero, trims the stream to approximately this many
+	// entries on each add. Trimming is approximate (MAXLEN ~) for efficiency unless
+	// ExactTrim is true.
+	MaxLen    int64 `json:"max_len,omitempty"`
+	ExactTrim bool  `json:"exact_trim,omitempty"`
+
+	// MaxRetries is the maximum number of times a batch is resent after a connection
+	// error. Defaults to RedisDefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between reconnect attempts. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (ro RedisOptions) CheckValid() error {
+	if ro.Stream == "" {
+		return errors.New("missing stream")
+	}
+	if _, err := parseFieldTemplate(ro.Stream); err != nil {
+		return fmt.Errorf("invalid stream: %w", err)
+	}
+	if ro.DB < 0 {
+		return errors.New("db cannot be negative")
+	}
+	if ro.MaxLen < 0 {
+		return errors.New("max_len cannot be negative")
+	}
+	if ro.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if ro.Backoff != nil {
+		return ro.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// Redis adds log records to a Redis stream using XADD, trimming the stream to a
+// maximum length. Batches are pipelined over a single connection.
+type Redis struct {
+	options  RedisOptions
+	stream   *fieldTemplate
+	backoff  Backoff
+	batch    *batcher
+	shutdown chan struct{}
+
+	mux  sync.Mutex // protects conn, held while sending a batch
+	conn net.Conn
+	rd   *bufio.Reader
+
+	shutdownOnce sync.Once
+}
+
+// NewRedisTarget creates a target capable of adding log records to a Redis stream.
+func NewRedisTarget(options RedisOptions) (*Redis, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+
+	r := &Redis{
+		options:  options,
+		backoff:  DefaultBackoff(),
+		shutdown: make(chan struct{}),
+	}
+	r.stream, _ = parseFieldTemplate(options.Stream)
+	if options.Backoff != nil {
+		r.backoff = options.Backoff
+	}
+	if r.options.Addr == "" {
+		r.options.Addr = RedisDefaultAddr
+	}
+	if r.options.Field == "" {
+		r.options.Field = RedisDefaultField
+	}
+	if r.options.MaxRetries == 0 {
+		r.options.MaxRetries = RedisDefaultMaxRetries
+	}
+
+	r.batch = newBatcher(options.BatchOptions.withDefaults(BatchOptions{
+		MaxBatchCount:       RedisDefaultMaxBatchCount,
+		MaxBatchBytes:       RedisDefaultMaxBatchBytes,
+		FlushIntervalMillis: RedisDefaultFlushIntervalMillis,
+	}), r.send)
+	return r, nil
+}
+
+// Init is called once to initialize the target.
+func (r *Redis) Init() error {
+	return nil
+}
+
+// Write encodes an XADD command for the log record and buffers it to be sent with
+// the next batch.
+func (r *Redis) Write(p []byte, rec *logr.LogRec) (int, error) {
+	args := make([][]byte, 0, 10)
+	args = append(args, []byte("XADD"), []byte(r.stream.execute(rec)))
+	if r.options.MaxLen > 0 {
+		args = append(args, []byte("MAXLEN"))
+		if !r.options.ExactTrim {
+			args = append(args, []byte("~"))
+		}
+		args = append(args, strconv.AppendInt(nil, r.options.MaxLen, 10))
+	}
+	args = append(args, []byte("*"), []byte("level"), []byte(rec.Level().Name), []byte(r.options.Field), p)
+
+	cmd := appendRESPCommand(nil, args...)
+
+	if err := r.batch.add(cmd, "", rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends any buffered log records.
+func (r *Redis) Flush() error {
+	return r.batch.flush()
+}
+
+// Shutdown sends any buffered log records and closes the connection.
+func (r *Redis) Shutdown() error {
+	err := r.batch.flush()
+	r.shutdownOnce.Do(func() { close(r.shutdown) })
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.closeConn()
+	return err
+}
+
+// String returns a string representation of this target.
+func (r *Redis) String() string {
+	return fmt.Sprintf("RedisTarget[%s]", r.options.Addr)
+}
+
+// send pipelines the XADD commands and reads the replies. Connection errors cause the
+// whole batch to be resent, which may duplicate entries; command errors are not retried.
+func (r *Redis) send(items []batchItem) error {
+	var size int
+	for _, item := range items {
+		size += len(item.data)
+	}
+	cmds := make([]byte, 0, size)
+	for _, item := range items {
+		cmds = append(cmds, item.data...)
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var cmdErr error
+	err := retry(r.backoff, r.options.MaxRetries, r.shutdown, func() (bool, error) {
+		var err error
+		cmdErr, err = r.pipeline(cmds, len(items))
+		if err != nil {
+			r.closeConn()
+			return true, err
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s could not send %d records: %w", r, len(items), err)
+	}
+	if cmdErr != nil {
+		return fmt.Errorf("%s XADD failed: %w", r, cmdErr)
+	}
+	return nil
+}
+
+// pipeline writes the commands and reads count replies, returning the first command
+// error, if any, and any connection error. Must be called with mux held.
+func (r *Redis) pipeline(cmds []byte, count int) (cmdErr error, err error) {
+	if r.conn == nil {
+		if err = r.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	_ = r.conn.SetDeadline(time.Now().Add(time.Second * WriteTimeoutSecs))
+	if _, err = r.conn.Write(cmds); err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		if _, err = readRESPReply(r.rd); err != nil {
+			var re redisError
+			if !errors.As(err, &re) {
+				return nil, err
+			}
+			if cmdErr == nil {
+				cmdErr = err
+			}
+		}
+	}
+	return cmdErr, nil
+}
+
+// connect dials the server, authenticates and selects the database.
+// Must be called with mux held.
+func (r *Redis) connect() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*DialTimeoutSecs)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", r.options.Addr)
+	if err != nil {
+		return err
+	}
+	if r.options.TLS {
+		host, _, _ := net.SplitHostPort(r.options.Addr)
+		tlsconfig := &tls.Config{ServerName: host, InsecureSkipVerify: r.options.Insecure}
+		if r.options.Cert != "" {
+			pool, errPool := GetCertPool(r.options.Cert)
+			if errPool != nil {
+				conn.Close()
+				return errPool
+			}
+			tlsconfig.RootCAs = pool
+		}
+		conn = tls.Client(conn, tlsconfig)
+	}
+	r.conn = conn
+	r.rd = bufio.NewReader(conn)
+
+	var setup [][][]byte
+	if r.options.Password != "" {
+		if r.options.Username != "" {
+			setup = append(setup, [][]byte{[]byte("AUTH"), []byte(r.options.Username), []byte(r.options.Password)})
+		} else {
+			setup = append(setup, [][]byte{[]byte("AUTH"), []byte(r.options.Password)})
+		}
+	}
+	if r.options.DB != 0 {
+		setup = append(setup, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(r.options.DB))})
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(time.Second * DialTimeoutSecs))
+	for _, args := range setup {
+		if _, err = conn.Write(appendRESPCommand(nil, args...)); err == nil {
+			_, err = readRESPReply(r.rd)
+		}
+		if err != nil {
+			r.closeConn()
+			return fmt.Errorf("%s failed: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// closeConn must be called with mux held.
+func (r *Redis) closeConn() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+		r.rd = nil
+	}
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// appendRESPCommand appends the command args encoded as a RESP array of bulk strings.
+func appendRESPCommand(b []byte, args ...[]byte) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, '\r', '\n')
+	for _, arg := range args {
+		b = appendRESPBulk(b, arg)
+	}
+	return b
+}
+
+func appendRESPBulk(b []byte, p []byte) []byte {
+	b = append(b, '$')
+	b = strconv.AppendInt(b, int64(len(p)), 10)
+	b = append(b, '\r', '\n')
+	b = append(b, p...)
+	return append(b, '\r', '\n')
+}
+
+// readRESPReply reads one reply, returning its value as a string for simple,
+// integer and bulk replies. Error replies are returned as a `redisError`.
+func readRESPReply(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return "", errors.New("invalid RESP reply")
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+', ':':
+		return payload, nil
+	case '-':
+		return "", redisError(payload)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return "", err
+		}
+		if n < 0 {
+			return "", nil // nil bulk
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(rd, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i < n; i++ {
+			if _, err = readRESPReply(rd); err != nil {
+				var re redisError
+				if !errors.As(err, &re) {
+					return "", err
+				}
+			}
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("invalid RESP reply type %q", kind)
+}
@@ -0,0 +1,132 @@
+package targets
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redisServer is a minimal server that records commands and replies OK.
+type redisServer struct {
+	listener net.Listener
+
+	mux      sync.Mutex
+	commands [][]string
+}
+
+func (rs *redisServer) serve() {
+	for {
+		conn, err := rs.listener.Accept()
+		if err != nil {
+			return
+		}
+		go rs.handle(conn)
+	}
+}
+
+func (rs *redisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		cmd, err := readRESPCommand(rd)
+		if err != nil {
+			return
+		}
+		rs.mux.Lock()
+		rs.commands = append(rs.commands, cmd)
+		rs.mux.Unlock()
+
+		reply := "+OK\r\n"
+		if cmd[0] == "XADD" {
+			if cmd[1] == "bad" {
+				reply = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+			} else {
+				reply = "$15\r\n1700000000000-0\r\n"
+			}
+		}
+		if _, err = conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readRESPCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if line, err = rd.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func TestRedisTarget(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	rs := &redisServer{listener: l}
+	go rs.serve()
+	defer l.Close()
+
+	var errs []error
+	var errMux sync.Mutex
+	lgr, err := logr.New(logr.OnLoggerError(func(err error) {
+		errMux.Lock()
+		errs = append(errs, err)
+		errMux.Unlock()
+	}))
+	require.NoError(t, err)
+
+	r, err := NewRedisTarget(RedisOptions{
+		Addr:     l.Addr().String(),
+		Password: "pw",
+		DB:       2,
+		Stream:   "{stream}",
+		MaxLen:   1000,
+	})
+	require.NoError(t, err)
+
+	err = lgr.AddTarget(r, "redis_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true, Delim: " "}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("one", logr.String("stream", "logs:app"))
+	logger.Info("two", logr.String("stream", "bad"))
+	logger.Error("three", logr.String("stream", "logs:app"))
+	require.NoError(t, lgr.Shutdown())
+
+	rs.mux.Lock()
+	defer rs.mux.Unlock()
+	require.Len(t, rs.commands, 5)
+	assert.Equal(t, []string{"AUTH", "pw"}, rs.commands[0])
+	assert.Equal(t, []string{"SELECT", "2"}, rs.commands[1])
+
+	xadd := rs.commands[2]
+	assert.Equal(t, []string{"XADD", "logs:app", "MAXLEN", "~", "1000", "*", "level", "info", "log"}, xadd[:9])
+	assert.Contains(t, xadd[9], "one")
+	assert.Equal(t, "error", rs.commands[4][7])
+
+	errMux.Lock()
+	defer errMux.Unlock()
+	require.Len(t, errs, 1, "command errors should be reported, not retried")
+	assert.Contains(t, errs[0].Error(), "WRONGTYPE")
+}
@@ -0,0 +1,379 @@
+package targets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	S3DefaultKeyTemplate         = "{year}/{month}/{day}/{hour}/{host}-{timestamp}-{seq}.ndjson.gz"
+	S3DefaultMaxObjectBytes      = 64 * 1024 * 1024
+	S3MaxObjectBytes             = 5 * 1024 * 1024 * 1024 // single PUT limit
+	S3DefaultFlushIntervalMillis = 5 * 60 * 1000
+	S3DefaultMaxRetries          = 5
+	S3RequestTimeoutSecs         = 300
+)
+
+// S3Options provides parameters for the S3 target.
+type S3Options struct {
+	AWSCredentials
+
+	// Bucket is the name of the bucket objects are uploaded to.
+	Bucket string `json:"bucket"`
+
+	// Endpoint overrides the regional endpoint, e.g. for S3 compatible storage such
+	// as MinIO or Cloudflare R2.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// PathStyle, when true, addresses the bucket in the URL path rather than the host
+	// name, as required by most S3 compatible storage.
+	PathStyle bool `json:"path_style,omitempty"`
+
+	// KeyTemplate determines each object's key. The placeholders {year}, {month}, {day},
+	// {hour} and {minute} expand to the UTC time of the log records in the object,
+	// which are split into separate objects as needed, allowing date and hour
+	// partitioning. {host} expands to the host name, {timestamp} to the upload time in
+	// Unix milliseconds and {seq} to a sequence number unique to the target. {seq} is
+	// required so that keys are unique. Defaults to S3DefaultKeyTemplate.
+	KeyTemplate string `json:"key_template,omitempty"`
+
+	// DisableCompression, when true, uploads objects uncompressed rather than gzipped.
+	DisableCompression bool `json:"disable_compression,omitempty"`
+
+	// MaxRetries is the maximum number of times an upload is retried after a transient
+	// failure. Defaults to S3DefaultMaxRetries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// Backoff determines the delay between retries. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
+
+	// BatchOptions determine when objects are uploaded. MaxBatchBytes is the maximum
+	// uncompressed size of an object, defaulting to S3DefaultMaxObjectBytes, and
+	// FlushIntervalMillis the maximum time a record is buffered, defaulting to
+	// S3DefaultFlushIntervalMillis.
+	BatchOptions
+}
+
+// CheckValid returns an error if the options are invalid.
+func (so S3Options) CheckValid() error {
+	if so.Bucket == "" {
+		return errors.New("missing bucket")
+	}
+	creds := so.AWSCredentials.withEnv()
+	if creds.Region == "" {
+		return errors.New("missing region")
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return errors.New("missing AWS credentials")
+	}
+	if so.KeyTemplate != "" {
+		if err := checkKeyTemplate(so.KeyTemplate); err != nil {
+			return fmt.Errorf("invalid key_template: %w", err)
+		}
+	}
+	if so.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if so.Backoff != nil {
+		return so.Backoff.CheckValid()
+	}
+	return nil
+}
+
+// S3 archives log records as NDJSON objects in S3 or S3 compatible storage, serving
+// as cheap long-term storage alongside hot targets. Records are buffered and uploaded
+// as gzip compressed objects when the buffer reaches the maximum object size, when
+// the flush interval elapses, and when the Logr is flushed. Use a formatter that
+// outputs one line per record, such as `formatters.JSON`.
+type S3 struct {
+	options S3Options
+	client  *s3Client
+	batch   *batcher
+	keys    *keyTemplate
+}
+
+// NewS3Target creates a target capable of archiving log records to S3.
+func NewS3Target(options S3Options) (*S3, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+	if options.KeyTemplate == "" {
+		options.KeyTemplate = S3DefaultKeyTemplate
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = S3DefaultMaxRetries
+	}
+
+	s := &S3{
+		options: options,
+		client:  newS3Client(options.AWSCredentials, options.Bucket, options.Endpoint, options.PathStyle, options.Backoff, options.MaxRetries),
+	}
+	s.keys = newKeyTemplate(options.KeyTemplate)
+
+	limits := BatchOptions{
+		MaxBatchCount:       S3MaxObjectBytes, // limited by size only
+		MaxBatchBytes:       S3MaxObjectBytes,
+		FlushIntervalMillis: S3DefaultFlushIntervalMillis,
+	}
+	batchOpts := options.BatchOptions
+	if batchOpts.MaxBatchBytes <= 0 {
+		batchOpts.MaxBatchBytes = S3DefaultMaxObjectBytes
+	}
+	s.batch = newBatcher(batchOpts.withDefaults(limits), s.send)
+	return s, nil
+}
+
+// Init is called once to initialize the target.
+func (s *S3) Init() error {
+	return nil
+}
+
+// Write buffers the formatted log record to be uploaded with the next object.
+func (s *S3) Write(p []byte, rec *logr.LogRec) (int, error) {
+	if err := s.batch.add(p, s.keys.partition(rec.Time()), rec.Logger().Logr().ReportError); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush uploads any buffered log records.
+func (s *S3) Flush() error {
+	return s.batch.flush()
+}
+
+// Shutdown uploads any buffered log records.
+func (s *S3) Shutdown() error {
+	err := s.batch.flush()
+	s.client.close()
+	return err
+}
+
+// String returns a string representation of this target.
+func (s *S3) String() string {
+	return fmt.Sprintf("S3Target[%s]", s.options.Bucket)
+}
+
+// send uploads one object per partition, in the order partitions first appear.
+func (s *S3) send(items []batchItem) error {
+	order, groups := groupByKey(items)
+
+	var errs []string
+	for _, partition := range order {
+		body, err := s.encode(groups[partition])
+		if err != nil {
+			return err
+		}
+		contentType := "application/x-ndjson"
+		if !s.options.DisableCompression {
+			contentType = "application/gzip"
+		}
+		key := s.keys.objectKey(partition)
+		if err := s.client.put(key, body, contentType); err != nil {
+			errs = append(errs, fmt.Sprintf("%s could not upload %d records to %s: %v", s, len(groups[partition]), key, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// encode concatenates the records, one per line, compressing unless disabled.
+func (s *S3) encode(items []batchItem) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if !s.options.DisableCompression {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+	for _, item := range items {
+		if _, err := w.Write(item.data); err != nil {
+			return nil, err
+		}
+		if len(item.data) > 0 && item.data[len(item.data)-1] != '\n' {
+			if _, err := w.Write([]byte{'\n'}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// S3BlobStoreOptions provides parameters for `S3BlobStore`.
+type S3BlobStoreOptions struct {
+	AWSCredentials
+
+	// Bucket is the name of the bucket values are stored in.
+	Bucket string `json:"bucket"`
+
+	// Endpoint and PathStyle are as for `S3Options`.
+	Endpoint  string `json:"endpoint,omitempty"`
+	PathStyle bool   `json:"path_style,omitempty"`
+
+	// Prefix is prepended to each object key, e.g. "blobs/".
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// S3BlobStore is a `logr.BlobStore` that saves each field value offloaded via
+// `logr.OffloadFields` to an S3 object named by the SHA-256 hash of the value.
+// References are "s3://" URLs.
+type S3BlobStore struct {
+	options S3BlobStoreOptions
+	client  *s3Client
+}
+
+// NewS3BlobStore creates a blob store saving values to S3.
+func NewS3BlobStore(options S3BlobStoreOptions) (*S3BlobStore, error) {
+	err := S3Options{AWSCredentials: options.AWSCredentials, Bucket: options.Bucket}.CheckValid()
+	if err != nil {
+		return nil, err
+	}
+	return &S3BlobStore{
+		options: options,
+		client:  newS3Client(options.AWSCredentials, options.Bucket, options.Endpoint, options.PathStyle, nil, S3DefaultMaxRetries),
+	}, nil
+}
+
+// Store uploads the value and returns its "s3://" URL.
+func (bs *S3BlobStore) Store(key string, value []byte) (string, error) {
+	sum := sha256.Sum256(value)
+	objKey := bs.options.Prefix + hex.EncodeToString(sum[:])
+	if err := bs.client.put(objKey, value, "application/octet-stream"); err != nil {
+		return "", err
+	}
+	return "s3://" + bs.options.Bucket + "/" + objKey, nil
+}
+
+// s3Client uploads objects to one bucket.
+type s3Client struct {
+	creds      AWSCredentials
+	bucket     string
+	endpoint   *url.URL
+	pathStyle  bool
+	client     *http.Client
+	backoff    Backoff
+	maxRetries int
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+func newS3Client(creds AWSCredentials, bucket string, endpoint string, pathStyle bool, backoff *ExponentialBackoff, maxRetries int) *s3Client {
+	c := &s3Client{
+		creds:      creds.withEnv(),
+		bucket:     bucket,
+		pathStyle:  pathStyle,
+		client:     &http.Client{Timeout: time.Second * S3RequestTimeoutSecs},
+		backoff:    DefaultBackoff(),
+		maxRetries: maxRetries,
+		shutdown:   make(chan struct{}),
+	}
+	if backoff != nil {
+		c.backoff = backoff
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.creds.Region)
+	}
+	c.endpoint, _ = url.Parse(endpoint)
+	if c.endpoint == nil {
+		c.endpoint = &url.URL{Scheme: "https", Host: endpoint}
+	}
+	return c
+}
+
+// objectURL returns the URL of the object with the key.
+func (c *s3Client) objectURL(key string) *url.URL {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	escaped := strings.Join(segments, "/")
+
+	u := *c.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if c.pathStyle {
+		u.Path = base + "/" + c.bucket + "/" + key
+		u.RawPath = base + "/" + url.PathEscape(c.bucket) + "/" + escaped
+	} else {
+		u.Host = c.bucket + "." + u.Host
+		u.Path = base + "/" + key
+		u.RawPath = base + "/" + escaped
+	}
+	return &u
+}
+
+// put uploads an object, retrying transient failures.
+func (c *s3Client) put(key string, body []byte, contentType string) error {
+	return retry(c.backoff, c.maxRetries, c.shutdown, func() (bool, error) {
+		return c.putOnce(key, body, contentType)
+	})
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (c *s3Client) putOnce(key string, body []byte, contentType string) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPut, c.objectURL(key).String(), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	signAWSv4(req, body, c.creds, "s3", time.Now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var se s3Error
+		_ = xml.Unmarshal(respBody, &se)
+		err = fmt.Errorf("upload failed with status %d: %s %s", resp.StatusCode, se.Code, se.Message)
+		if ra := parseRetryAfter(resp.Header.Get("Retry-After")); ra > 0 {
+			err = retryAfterError{err: err, delay: ra}
+		}
+		return isRetryableS3Error(resp.StatusCode, se.Code), err
+	}
+	return false, nil
+}
+
+func (c *s3Client) close() {
+	c.shutdownOnce.Do(func() { close(c.shutdown) })
+}
+
+func isRetryableS3Error(status int, code string) bool {
+	switch code {
+	case "SlowDown", "RequestTimeout", "InternalError", "ServiceUnavailable":
+		return true
+	}
+	return isRetryableAWSError(status, code)
+}
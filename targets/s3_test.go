@@ -0,0 +1,107 @@
+package targets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type s3Server struct {
+	mux      sync.Mutex
+	objects  map[string][]byte
+	headers  []http.Header
+	throttle int // number of leading requests to reject
+}
+
+func (ss *s3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+	if ss.throttle > 0 {
+		ss.throttle--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`))
+		return
+	}
+	if ss.objects == nil {
+		ss.objects = make(map[string][]byte)
+	}
+	ss.objects[r.URL.Path] = body
+	ss.headers = append(ss.headers, r.Header.Clone())
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	out, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestS3Target(t *testing.T) {
+	ss := &s3Server{throttle: 1}
+	server := httptest.NewServer(ss)
+	defer server.Close()
+
+	var mux sync.Mutex
+	now := time.Date(2021, 3, 4, 10, 59, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mux.Lock()
+		defer mux.Unlock()
+		return now
+	}
+	lgr, err := logr.New(logr.Clock(clock), logr.OnLoggerError(func(err error) {
+		t.Error("OnLoggerError", err)
+	}))
+	require.NoError(t, err)
+
+	s3, err := NewS3Target(S3Options{
+		AWSCredentials: AWSCredentials{Region: "us-east-1", AccessKeyID: "id", SecretAccessKey: "secret"},
+		Bucket:         "archive",
+		Endpoint:       server.URL,
+		PathStyle:      true,
+		KeyTemplate:    "logs/{year}-{month}-{day}/{hour}/{seq}.ndjson.gz",
+		Backoff:        &ExponentialBackoff{InitialMillis: 1, MaxMillis: 5},
+	})
+	require.NoError(t, err)
+	err = lgr.AddTarget(s3, "s3_test", &logr.StdFilter{Lvl: logr.Info}, &formatters.JSON{DisableTimestamp: true}, 1000)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("one")
+	logger.Info("two")
+	mux.Lock()
+	now = now.Add(2 * time.Minute)
+	mux.Unlock()
+	logger.Info("three")
+	require.NoError(t, lgr.Shutdown())
+
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+	require.Len(t, ss.objects, 2)
+	assert.Equal(t, `{"level":"info","msg":"one"}`+"\n"+`{"level":"info","msg":"two"}`+"\n", gunzip(t, ss.objects["/archive/logs/2021-03-04/10/000001.ndjson.gz"]))
+	assert.Equal(t, `{"level":"info","msg":"three"}`+"\n", gunzip(t, ss.objects["/archive/logs/2021-03-04/11/000002.ndjson.gz"]))
+
+	for _, h := range ss.headers {
+		assert.Equal(t, "application/gzip", h.Get("Content-Type"))
+		assert.NotEmpty(t, h.Get("X-Amz-Content-Sha256"))
+		assert.Contains(t, h.Get("Authorization"), "/us-east-1/s3/aws4_request")
+	}
+}
+
+func TestCheckKeyTemplate(t *testing.T) {
+	assert.NoError(t, checkKeyTemplate(S3DefaultKeyTemplate))
+	assert.Error(t, checkKeyTemplate("{year}/{timestamp}.gz"), "missing {seq}")
+	assert.Error(t, checkKeyTemplate("{year}/{tenant}-{seq}.gz"), "unknown placeholder")
+}
@@ -0,0 +1,292 @@
+package targets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	// SSEDefaultBufferSize is the default number of records buffered per connection.
+	SSEDefaultBufferSize = 100
+
+	// SSEDefaultHeartbeatMillis is the default interval between keep-alive comments.
+	SSEDefaultHeartbeatMillis = 15000
+)
+
+// SSEOptions provides parameters for the SSE target.
+type SSEOptions struct {
+	// BufferSize is the number of records buffered for each connection. Records
+	// are dropped for connections that fall further behind, so a slow browser
+	// never blocks the target. Defaults to SSEDefaultBufferSize.
+	BufferSize int `json:"buffer_size,omitempty"`
+
+	// HeartbeatMillis is the interval between keep-alive comments, which stop idle
+	// connections being closed by proxies. Defaults to SSEDefaultHeartbeatMillis.
+	HeartbeatMillis int64 `json:"heartbeat_millis,omitempty"`
+
+	// MaxClients is the maximum number of concurrent connections, or zero for no limit.
+	MaxClients int `json:"max_clients,omitempty"`
+}
+
+// CheckValid returns an error if the options are invalid.
+func (so SSEOptions) CheckValid() error {
+	if so.BufferSize < 0 {
+		return errors.New("buffer size cannot be negative")
+	}
+	if so.HeartbeatMillis < 0 {
+		return errors.New("heartbeat cannot be negative")
+	}
+	if so.MaxClients < 0 {
+		return errors.New("max clients cannot be negative")
+	}
+	return nil
+}
+
+// SSE is a target that is also an `http.Handler`, streaming formatted log records
+// to browsers using Server-Sent Events. This allows live log views in web admin
+// panels using only the browser's `EventSource` API.
+//
+// Each connection can narrow the records it receives via query parameters:
+// `level` sets the minimum standard level (e.g. `?level=warn`) and `filter` takes
+// an expression as supported by `logr.NewExprFilter`. The target's own filter
+// determines which records are available to connections at all.
+//
+// The handler should be protected by the application's authentication, as log
+// records often contain sensitive data.
+type SSE struct {
+	options SSEOptions
+
+	mux      sync.RWMutex
+	clients  map[*sseClient]struct{}
+	seq      uint64 // only accessed by Write
+	shutdown chan struct{}
+	once     sync.Once
+}
+
+type sseClient struct {
+	filter  logr.Filter
+	events  chan []byte
+	dropped uint64
+}
+
+// NewSSETarget creates a target capable of streaming log records to browsers.
+// Register it with an `http.ServeMux` and add it to a Logr like any other target.
+func NewSSETarget(options SSEOptions) (*SSE, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+	s := &SSE{
+		options:  options,
+		clients:  make(map[*sseClient]struct{}),
+		shutdown: make(chan struct{}),
+	}
+	if s.options.BufferSize == 0 {
+		s.options.BufferSize = SSEDefaultBufferSize
+	}
+	if s.options.HeartbeatMillis == 0 {
+		s.options.HeartbeatMillis = SSEDefaultHeartbeatMillis
+	}
+	return s, nil
+}
+
+// Init is called once to initialize the target.
+func (s *SSE) Init() error {
+	return nil
+}
+
+// Write sends the log record to every connection whose filter accepts it.
+// Connections with a full buffer miss the record.
+func (s *SSE) Write(p []byte, rec *logr.LogRec) (int, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if len(s.clients) == 0 {
+		return len(p), nil
+	}
+
+	s.seq++
+	event := encodeSSEEvent(s.seq, p)
+	for c := range s.clients {
+		if !sseAccepts(c.filter, rec) {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			atomic.AddUint64(&c.dropped, 1)
+		}
+	}
+	return len(p), nil
+}
+
+// ServeHTTP streams log records to the client until the client disconnects or
+// the target is shut down.
+func (s *SSE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := sseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c := &sseClient{filter: filter, events: make(chan []byte, s.options.BufferSize)}
+	if !s.addClient(c) {
+		http.Error(w, "too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.removeClient(c)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // disable proxy buffering, e.g. nginx
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(time.Duration(s.options.HeartbeatMillis) * time.Millisecond)
+	defer heartbeat.Stop()
+
+	var reported uint64
+	for {
+		select {
+		case event := <-c.events:
+			if dropped := atomic.LoadUint64(&c.dropped); dropped != reported {
+				fmt.Fprintf(w, ": %d records dropped\n\n", dropped-reported)
+				reported = dropped
+			}
+			if _, err := w.Write(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// Shutdown disconnects all clients.
+func (s *SSE) Shutdown() error {
+	s.once.Do(func() { close(s.shutdown) })
+	return nil
+}
+
+// String returns a string representation of this target.
+func (s *SSE) String() string {
+	return "SSETarget"
+}
+
+// Clients returns the number of connected clients.
+func (s *SSE) Clients() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return len(s.clients)
+}
+
+func (s *SSE) addClient(c *sseClient) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	select {
+	case <-s.shutdown:
+		return false
+	default:
+	}
+	if s.options.MaxClients > 0 && len(s.clients) >= s.options.MaxClients {
+		return false
+	}
+	s.clients[c] = struct{}{}
+	return true
+}
+
+func (s *SSE) removeClient(c *sseClient) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.clients, c)
+}
+
+// sseFilter creates the filter for a connection from the `level` and `filter`
+// query parameters, or returns nil if neither is provided.
+func sseFilter(r *http.Request) (logr.Filter, error) {
+	query := r.URL.Query()
+	var exprs []string
+	if name := query.Get("level"); name != "" {
+		if _, ok := logr.GetLevelMapper().Name(name); !ok {
+			return nil, fmt.Errorf("unknown level %q", name)
+		}
+		exprs = append(exprs, "level >= "+strings.ToLower(name))
+	}
+	if expr := query.Get("filter"); expr != "" {
+		exprs = append(exprs, "("+expr+")")
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	filter, err := logr.NewExprFilter(strings.Join(exprs, " && "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return filter, nil
+}
+
+func sseAccepts(filter logr.Filter, rec *logr.LogRec) bool {
+	if filter == nil {
+		return true
+	}
+	if _, enabled := filter.GetEnabledLevel(rec.Level()); !enabled {
+		return false
+	}
+	if rf, ok := filter.(logr.RecordFilter); ok {
+		return rf.IsRecordEnabled(rec)
+	}
+	return true
+}
+
+// encodeSSEEvent encodes a formatted record as an event, using a `data` line for
+// each line of the record as required by the SSE format.
+func encodeSSEEvent(id uint64, p []byte) []byte {
+	p = bytes.TrimRight(p, "\r\n")
+
+	var buf bytes.Buffer
+	buf.Grow(len(p) + 32)
+	buf.WriteString("id: ")
+	buf.WriteString(strconv.FormatUint(id, 10))
+	buf.WriteByte('\n')
+	for {
+		i := bytes.IndexByte(p, '\n')
+		line := p
+		if i >= 0 {
+			line = p[:i]
+		}
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+		if i < 0 {
+			break
+		}
+		p = p[i+1:]
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
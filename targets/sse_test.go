@@ -0,0 +1,77 @@
+package targets
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeSSEEvent(t *testing.T) {
+	assert.Equal(t, "id: 1\ndata: hello\n\n", string(encodeSSEEvent(1, []byte("hello\n"))))
+	assert.Equal(t, "id: 2\ndata: line1\ndata: line2\n\n", string(encodeSSEEvent(2, []byte("line1\r\nline2"))))
+}
+
+func TestSSETarget(t *testing.T) {
+	sse, err := NewSSETarget(SSEOptions{})
+	require.NoError(t, err)
+	server := httptest.NewServer(sse)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?level=bogus")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	connect := func(query string) *bufio.Reader {
+		resp, err := http.Get(server.URL + query)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return bufio.NewReader(resp.Body)
+	}
+	all := connect("")
+	errs := connect("?level=error")
+	auth := connect("?filter=" + `fields.subsystem%20%3D%3D%20%22auth%22`)
+	require.Eventually(t, func() bool { return sse.Clients() == 3 }, time.Second, time.Millisecond*10)
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	formatter := &formatters.Plain{DisableTimestamp: true, Delim: " "}
+	err = lgr.AddTarget(sse, "sse_test", &logr.StdFilter{Lvl: logr.Debug}, formatter, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Debug("starting")
+	logger.Error("login failed", logr.String("subsystem", "auth"))
+	require.NoError(t, lgr.Flush())
+
+	readData := func(r *bufio.Reader, n int) []string {
+		var data []string
+		for len(data) < n {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			if strings.HasPrefix(line, "data: ") {
+				data = append(data, strings.TrimSpace(strings.TrimPrefix(line, "data: ")))
+			}
+		}
+		return data
+	}
+
+	got := readData(all, 2)
+	assert.Contains(t, got[0], "starting")
+	assert.Contains(t, got[1], "login failed")
+	assert.Contains(t, readData(errs, 1)[0], "login failed")
+	assert.Contains(t, readData(auth, 1)[0], "login failed")
+
+	require.NoError(t, lgr.Shutdown())
+	require.Eventually(t, func() bool { return sse.Clients() == 0 }, time.Second, time.Millisecond*10)
+}
@@ -14,8 +14,9 @@ import (
 
 // Syslog outputs log records to local or remote syslog.
 type Syslog struct {
-	params *SyslogOptions
-	writer *syslog.Writer
+	params  *SyslogOptions
+	writer  *syslog.Writer
+	rfc5424 *syslogRFC5424
 }
 
 // SyslogOptions provides parameters for dialing a syslog daemon.
@@ -27,6 +28,24 @@ type SyslogOptions struct {
 	Cert     string `json:"cert"`
 	Insecure bool   `json:"insecure"`
 	Tag      string `json:"tag"`
+
+	// Facility is the syslog facility name, e.g. "user", "daemon" or "local0".
+	// Defaults to "kern" for compatibility with earlier versions.
+	Facility string `json:"facility,omitempty"`
+
+	// Format is the message format: "rfc3164", "rfc5424", or empty for the
+	// original format, which is a mix of both for maximum compatibility.
+	Format string `json:"format,omitempty"`
+
+	// AppName is the RFC5424 APP-NAME. Defaults to Tag, or the executable name.
+	AppName string `json:"app_name,omitempty"`
+
+	// MsgID is a template for the RFC5424 MSGID, where placeholders such as "{event}"
+	// are replaced with the value of the field with that key.
+	MsgID string `json:"msgid,omitempty"`
+
+	// StructuredData lists RFC5424 structured data elements populated from fields.
+	StructuredData []SyslogSDElement `json:"structured_data,omitempty"`
 }
 
 func (so SyslogOptions) CheckValid() error {
@@ -36,7 +55,12 @@ func (so SyslogOptions) CheckValid() error {
 	if so.Port == 0 {
 		return errors.New("missing port")
 	}
-	return nil
+	if so.Facility != "" {
+		if _, err := syslogFacilityCode(so.Facility); err != nil {
+			return err
+		}
+	}
+	return checkSyslogRFC5424(so.Format, so.MsgID, so.StructuredData)
 }
 
 // NewSyslogTarget creates a target capable of outputting log records to remote or local syslog, with or without TLS.
@@ -79,15 +103,53 @@ func (s *Syslog) Init() error {
 		network = ""
 	}
 
+	var facility int
+	if s.params.Facility != "" {
+		var err error
+		if facility, err = syslogFacilityCode(s.params.Facility); err != nil {
+			return err
+		}
+	}
+	priority := syslog.Priority(facility<<3) | syslog.LOG_INFO
+
+	if s.params.Format == SyslogFormatRFC5424 {
+		appName := s.params.AppName
+		if appName == "" {
+			appName = s.params.Tag
+		}
+		var err error
+		if s.rfc5424, err = newSyslogRFC5424(appName, s.params.MsgID, s.params.StructuredData); err != nil {
+			return err
+		}
+	}
+
 	var err error
-	s.writer, err = syslog.DialWithTLSConfig(network, raddr, syslog.LOG_INFO, s.params.Tag, config)
-	return err
+	s.writer, err = syslog.DialWithTLSConfig(network, raddr, priority, s.params.Tag, config)
+	if err != nil {
+		return err
+	}
+
+	switch s.params.Format {
+	case SyslogFormatRFC3164:
+		s.writer.SetFormatter(syslog.RFC3164Formatter)
+	case SyslogFormatRFC5424:
+		// the message header is built by Write since it depends on the log record.
+		s.writer.SetFormatter(func(p syslog.Priority, hostname, tag, content string) string {
+			return fmt.Sprintf("<%d>%s", p, content)
+		})
+	}
+	return nil
 }
 
 // Write outputs bytes to this file target.
 func (s *Syslog) Write(p []byte, rec *logr.LogRec) (int, error) {
-	txt := string(p)
-	n := len(txt)
+	n := len(p)
+	var txt string
+	if s.rfc5424 != nil {
+		txt = s.rfc5424.format(p, rec)
+	} else {
+		txt = string(p)
+	}
 	var err error
 
 	switch rec.Level() {
@@ -0,0 +1,229 @@
+package targets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// Syslog message formats. See `SyslogOptions.Format`.
+const (
+	SyslogFormatRFC3164 = "rfc3164"
+	SyslogFormatRFC5424 = "rfc5424"
+)
+
+// RFC5424 field length limits.
+const (
+	syslogAppNameMax = 48
+	syslogMsgIDMax   = 32
+	syslogSDNameMax  = 32
+	syslogNilValue   = "-"
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// SyslogSDElement configures an RFC5424 structured data element populated from
+// log record fields, e.g. `[meta@32473 user_id="42" request_id="abc"]`.
+type SyslogSDElement struct {
+	// ID is the SD-ID, e.g. "meta@32473". IDs without an "@" are reserved for
+	// elements registered with IANA.
+	ID string `json:"id"`
+
+	// Fields lists the keys of the fields included as parameters. When empty, all
+	// fields are included. The element is omitted for records without any of the fields.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// syslogFacilityCode returns the facility code for a facility name such as "local0".
+func syslogFacilityCode(name string) (int, error) {
+	code, ok := syslogFacilities[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("invalid facility %q", name)
+	}
+	return code, nil
+}
+
+// checkSyslogRFC5424 returns an error if the format or RFC5424 specific options are invalid.
+func checkSyslogRFC5424(format string, msgID string, sd []SyslogSDElement) error {
+	switch format {
+	case "", SyslogFormatRFC3164, SyslogFormatRFC5424:
+	default:
+		return fmt.Errorf("invalid format %q", format)
+	}
+	if format != SyslogFormatRFC5424 && (msgID != "" || len(sd) > 0) {
+		return errors.New("msgid and structured data require the rfc5424 format")
+	}
+	if _, err := parseFieldTemplate(msgID); err != nil {
+		return fmt.Errorf("invalid msgid: %w", err)
+	}
+	for _, elem := range sd {
+		if elem.ID == "" {
+			return errors.New("structured data element missing id")
+		}
+		if len(elem.ID) > syslogSDNameMax || sdName(elem.ID) != elem.ID {
+			return fmt.Errorf("invalid structured data id %q", elem.ID)
+		}
+	}
+	return nil
+}
+
+// syslogRFC5424 builds RFC5424 messages, excluding the PRI part which is
+// added by the syslog writer.
+type syslogRFC5424 struct {
+	hostname string
+	appName  string
+	procID   string
+	msgID    *fieldTemplate
+	sd       []SyslogSDElement
+}
+
+func newSyslogRFC5424(appName string, msgID string, sd []SyslogSDElement) (*syslogRFC5424, error) {
+	tmpl, err := parseFieldTemplate(msgID)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	return &syslogRFC5424{
+		hostname: headerValue(hostname, 255),
+		appName:  headerValue(appName, syslogAppNameMax),
+		procID:   strconv.Itoa(os.Getpid()),
+		msgID:    tmpl,
+		sd:       sd,
+	}, nil
+}
+
+// format returns `VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG`.
+func (f *syslogRFC5424) format(p []byte, rec *logr.LogRec) string {
+	var sb strings.Builder
+	sb.Grow(len(p) + 128)
+	sb.WriteString("1 ")
+	sb.WriteString(rec.Time().Format("2006-01-02T15:04:05.000000Z07:00"))
+	sb.WriteByte(' ')
+	sb.WriteString(f.hostname)
+	sb.WriteByte(' ')
+	sb.WriteString(f.appName)
+	sb.WriteByte(' ')
+	sb.WriteString(f.procID)
+	sb.WriteByte(' ')
+	sb.WriteString(headerValue(f.msgID.execute(rec), syslogMsgIDMax))
+	sb.WriteByte(' ')
+	if !f.writeSD(&sb, rec) {
+		sb.WriteString(syslogNilValue)
+	}
+	if len(p) > 0 {
+		sb.WriteByte(' ')
+		sb.Write(p)
+	}
+	return sb.String()
+}
+
+// writeSD writes the structured data elements, returning false if there are none.
+func (f *syslogRFC5424) writeSD(sb *strings.Builder, rec *logr.LogRec) bool {
+	fields := rec.Fields()
+	wrote := false
+	for _, elem := range f.sd {
+		params := 0
+		writeParam := func(field logr.Field) {
+			if params == 0 {
+				sb.WriteByte('[')
+				sb.WriteString(elem.ID)
+			}
+			params++
+			sb.WriteByte(' ')
+			sb.WriteString(sdName(field.Key))
+			sb.WriteString(`="`)
+			var val strings.Builder
+			_ = field.ValueString(&val, neverQuote)
+			writeSDValue(sb, val.String())
+			sb.WriteByte('"')
+		}
+
+		if len(elem.Fields) == 0 {
+			for _, field := range fields {
+				writeParam(field)
+			}
+		} else {
+			for _, key := range elem.Fields {
+				for i := len(fields) - 1; i >= 0; i-- {
+					if fields[i].Key == key {
+						writeParam(fields[i])
+						break
+					}
+				}
+			}
+		}
+		if params > 0 {
+			sb.WriteByte(']')
+			wrote = true
+		}
+	}
+	return wrote
+}
+
+// headerValue converts s to a valid RFC5424 header value: printable US-ASCII
+// without spaces, truncated to max characters, or the nil value if empty.
+func headerValue(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return syslogNilValue
+	}
+	return s
+}
+
+// sdName converts s to a valid SD-NAME, which excludes '=', ']' and '"'.
+func sdName(s string) string {
+	s = headerValue(s, syslogSDNameMax)
+	return strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// writeSDValue writes a PARAM-VALUE, escaping '"', '\' and ']'.
+func writeSDValue(sb *strings.Builder, s string) {
+	for _, r := range s {
+		if r == '"' || r == '\\' || r == ']' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+}
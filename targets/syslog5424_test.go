@@ -0,0 +1,64 @@
+package targets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc5424Capture is a target that records the RFC5424 message for each log record.
+type rfc5424Capture struct {
+	f    *syslogRFC5424
+	msgs []string
+}
+
+func (c *rfc5424Capture) Init() error     { return nil }
+func (c *rfc5424Capture) Shutdown() error { return nil }
+func (c *rfc5424Capture) Write(p []byte, rec *logr.LogRec) (int, error) {
+	c.msgs = append(c.msgs, c.f.format([]byte(rec.Msg()), rec))
+	return len(p), nil
+}
+
+func TestSyslogRFC5424(t *testing.T) {
+	sd := []SyslogSDElement{
+		{ID: "req@32473", Fields: []string{"request_id", "missing"}},
+		{ID: "none@32473", Fields: []string{"missing"}},
+	}
+	f, err := newSyslogRFC5424("my app", "{event}", sd)
+	require.NoError(t, err)
+	f.hostname = "host1"
+	f.procID = "123"
+
+	lgr, err := logr.New()
+	require.NoError(t, err)
+	capture := &rfc5424Capture{f: f}
+	err = lgr.AddTarget(capture, "capture", &logr.StdFilter{Lvl: logr.Info}, nil, 10)
+	require.NoError(t, err)
+
+	ts := time.Date(2026, 3, 4, 5, 6, 7, 8000, time.UTC)
+	logger := lgr.NewLogger()
+	logger.LogWithTime(ts, logr.Info, "user logged in", logr.String("event", "login"), logr.String("request_id", `a"b]c\d`))
+	logger.LogWithTime(ts, logr.Info, "hello")
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, []string{
+		`1 2026-03-04T05:06:07.000008Z host1 my_app 123 login [req@32473 request_id="a\"b\]c\\d"] user logged in`,
+		"1 2026-03-04T05:06:07.000008Z host1 my_app 123 - - hello",
+	}, capture.msgs)
+}
+
+func TestCheckSyslogRFC5424(t *testing.T) {
+	assert.NoError(t, checkSyslogRFC5424("", "", nil))
+	assert.NoError(t, checkSyslogRFC5424(SyslogFormatRFC5424, "{event}", []SyslogSDElement{{ID: "meta@1"}}))
+	assert.Error(t, checkSyslogRFC5424("rfc9999", "", nil))
+	assert.Error(t, checkSyslogRFC5424(SyslogFormatRFC3164, "{event}", nil))
+	assert.Error(t, checkSyslogRFC5424(SyslogFormatRFC5424, "", []SyslogSDElement{{ID: "bad id"}}))
+
+	_, err := syslogFacilityCode("LOCAL3")
+	assert.NoError(t, err)
+	_, err = syslogFacilityCode("local9")
+	assert.Error(t, err)
+}
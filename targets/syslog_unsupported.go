@@ -28,6 +28,24 @@ type SyslogOptions struct {
 	Cert     string `json:"cert"`
 	Insecure bool   `json:"insecure"`
 	Tag      string `json:"tag"`
+
+	// Facility is the syslog facility name, e.g. "user", "daemon" or "local0".
+	// Defaults to "kern" for compatibility with earlier versions.
+	Facility string `json:"facility,omitempty"`
+
+	// Format is the message format: "rfc3164", "rfc5424", or empty for the
+	// original format, which is a mix of both for maximum compatibility.
+	Format string `json:"format,omitempty"`
+
+	// AppName is the RFC5424 APP-NAME. Defaults to Tag, or the executable name.
+	AppName string `json:"app_name,omitempty"`
+
+	// MsgID is a template for the RFC5424 MSGID, where placeholders such as "{event}"
+	// are replaced with the value of the field with that key.
+	MsgID string `json:"msgid,omitempty"`
+
+	// StructuredData lists RFC5424 structured data elements populated from fields.
+	StructuredData []SyslogSDElement `json:"structured_data,omitempty"`
 }
 
 func (so SyslogOptions) CheckValid() error {
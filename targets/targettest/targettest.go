@@ -0,0 +1,352 @@
+// Package targettest implements support for testing implementations of
+// logr.Target, including targets provided by third parties.
+package targettest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/wiggin77/merror"
+)
+
+// Factory creates the target being tested. The target must write the formatted
+// log records it receives to w, unmodified; targets writing to a network or
+// other destination can be pointed at a local server that copies to w.
+type Factory func(w io.Writer) (logr.Target, error)
+
+const (
+	// Goroutines is the number of goroutines concurrently logging.
+	Goroutines = 10
+
+	// Records is the number of records logged by each goroutine.
+	Records = 100
+
+	// SlowWriteDelay is the time each write takes during the slow writes scenario.
+	SlowWriteDelay = time.Millisecond * 2
+
+	// Timeout bounds how long each flush or shutdown may take.
+	Timeout = time.Second * 10
+)
+
+// TestTarget tests a logr.Target implementation by hosting it in a Logr and
+// running the following scenarios, each against a new target:
+//   - concurrent: many goroutines log while the Logr is flushed concurrently;
+//     every record must be written exactly once by the time `Flush` returns.
+//   - writer errors: every write to w fails for a time; the target must not
+//     panic, must report the failures or eventually write the records, and
+//     must write records logged once w recovers.
+//   - slow writes: each write to w is delayed; `Shutdown` must drain the
+//     queue and write every record before returning.
+//
+// Panics in the target are reported as errors. Run with the race detector
+// enabled to catch unsynchronized access.
+//
+// TestTarget returns an error describing all failures, or nil if none.
+// Typical usage inside a test is:
+//
+//	factory := func(w io.Writer) (logr.Target, error) {
+//		return NewMyTarget(w), nil
+//	}
+//	if err := targettest.TestTarget(factory); err != nil {
+//		t.Fatal(err)
+//	}
+func TestTarget(factory Factory) error {
+	if factory == nil {
+		return errors.New("factory is nil")
+	}
+
+	errs := merror.New()
+	for _, sc := range []struct {
+		name string
+		run  func(Factory) []error
+	}{
+		{"concurrent", testConcurrent},
+		{"writer errors", testWriterErrors},
+		{"slow writes", testSlowWrites},
+	} {
+		for _, err := range sc.run(factory) {
+			errs.Append(fmt.Errorf("%s: %w", sc.name, err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func testConcurrent(factory Factory) []error {
+	w := &faultWriter{}
+	h, err := newHarness(factory, w)
+	if err != nil {
+		return []error{err}
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < Goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < Records; i++ {
+				h.logger.Info(recMsg("c", g, i))
+			}
+		}(g)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for flushing := true; flushing; {
+		select {
+		case <-done:
+			flushing = false
+		default:
+			if err := h.flush(); err != nil {
+				h.fail(err)
+			}
+		}
+	}
+
+	if err := h.flush(); err != nil {
+		h.fail(err)
+	}
+	out := w.String()
+	for g := 0; g < Goroutines; g++ {
+		for i := 0; i < Records; i++ {
+			if n := strings.Count(out, recMsg("c", g, i)); n != 1 {
+				h.fail(fmt.Errorf("record %s written %d times after flush", recMsg("c", g, i), n))
+			}
+		}
+	}
+	h.shutdown()
+	return h.result()
+}
+
+func testWriterErrors(factory Factory) []error {
+	w := &faultWriter{}
+	h, err := newHarness(factory, w)
+	if err != nil {
+		return []error{err}
+	}
+
+	w.setFail(true)
+	for i := 0; i < Records; i++ {
+		h.logger.Info(recMsg("e", 0, i))
+	}
+	if err := h.flush(); err != nil {
+		h.fail(err)
+	}
+
+	w.setFail(false)
+	for i := 0; i < Records; i++ {
+		h.logger.Info(recMsg("e", 1, i))
+	}
+	if err := h.flush(); err != nil {
+		h.fail(err)
+	}
+
+	out := w.String()
+	if h.reported() == 0 && !strings.Contains(out, recMsg("e", 0, 0)) {
+		h.fail(errors.New("records lost without an error being reported"))
+	}
+	for i := 0; i < Records; i++ {
+		if !strings.Contains(out, recMsg("e", 1, i)) {
+			h.fail(fmt.Errorf("record %s not written after writer recovered", recMsg("e", 1, i)))
+		}
+	}
+	h.shutdown()
+	return h.result()
+}
+
+func testSlowWrites(factory Factory) []error {
+	w := &faultWriter{delay: SlowWriteDelay}
+	h, err := newHarness(factory, w)
+	if err != nil {
+		return []error{err}
+	}
+
+	for i := 0; i < Records; i++ {
+		h.logger.Info(recMsg("s", 0, i))
+	}
+	h.shutdown()
+
+	out := w.String()
+	for i := 0; i < Records; i++ {
+		if !strings.Contains(out, recMsg("s", 0, i)) {
+			h.fail(fmt.Errorf("record %s not written by shutdown", recMsg("s", 0, i)))
+		}
+	}
+	return h.result()
+}
+
+// recMsg returns a message that is unique per scenario, goroutine and record,
+// and is not a substring of any other.
+func recMsg(scenario string, g int, i int) string {
+	return fmt.Sprintf("<%s:%d:%d>", scenario, g, i)
+}
+
+type harness struct {
+	lgr    *logr.Logr
+	logger logr.Logger
+
+	mux      sync.Mutex
+	errs     *merror.MError
+	reportsN int
+}
+
+func newHarness(factory Factory, w io.Writer) (*harness, error) {
+	target, err := factory(w)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, errors.New("factory returned nil target")
+	}
+
+	h := &harness{errs: merror.New()}
+	h.lgr, err = logr.New(
+		logr.MaxQueueSize(Goroutines*Records),
+		logr.OnLoggerError(h.report),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &logr.StdFilter{Lvl: logr.Info}
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	err = h.lgr.AddTarget(&guardTarget{target: target, h: h}, "targettest", filter, formatter, Goroutines*Records)
+	if err != nil {
+		return nil, err
+	}
+	h.logger = h.lgr.NewLogger()
+	return h, nil
+}
+
+func (h *harness) report(err error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.reportsN++
+}
+
+func (h *harness) reported() int {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.reportsN
+}
+
+func (h *harness) fail(err error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.errs.Append(err)
+}
+
+func (h *harness) flush() error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	if err := h.lgr.FlushWithTimeout(ctx); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+	return nil
+}
+
+func (h *harness) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	if err := h.lgr.ShutdownWithTimeout(ctx); err != nil {
+		h.fail(fmt.Errorf("shutdown: %w", err))
+	}
+}
+
+func (h *harness) result() []error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.errs.Errors()
+}
+
+// guardTarget wraps the target being tested, converting panics to errors. Panics
+// during writes and flushes are recorded as failures since the resulting errors
+// are otherwise only reported via `OnLoggerError`.
+type guardTarget struct {
+	target logr.Target
+	h      *harness
+}
+
+func (g *guardTarget) Init() (err error) {
+	defer g.recover("Init", &err, false)
+	return g.target.Init()
+}
+
+func (g *guardTarget) Write(p []byte, rec *logr.LogRec) (n int, err error) {
+	defer g.recover("Write", &err, true)
+	return g.target.Write(p, rec)
+}
+
+func (g *guardTarget) WriteContext(ctx context.Context, p []byte, rec *logr.LogRec) (n int, err error) {
+	tc, ok := g.target.(logr.TargetWithContext)
+	if !ok {
+		return g.Write(p, rec)
+	}
+	defer g.recover("WriteContext", &err, true)
+	return tc.WriteContext(ctx, p, rec)
+}
+
+func (g *guardTarget) Flush() (err error) {
+	tf, ok := g.target.(logr.TargetFlusher)
+	if !ok {
+		return nil
+	}
+	defer g.recover("Flush", &err, true)
+	return tf.Flush()
+}
+
+func (g *guardTarget) Shutdown() (err error) {
+	defer g.recover("Shutdown", &err, false)
+	return g.target.Shutdown()
+}
+
+func (g *guardTarget) recover(method string, err *error, fail bool) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%s panic: %v", method, r)
+		if fail {
+			g.h.fail(*err)
+		}
+	}
+}
+
+// faultWriter collects output, optionally failing or delaying writes.
+type faultWriter struct {
+	mux   sync.Mutex
+	buf   bytes.Buffer
+	fail  bool
+	delay time.Duration
+}
+
+func (w *faultWriter) Write(p []byte) (int, error) {
+	if w.delay > 0 {
+		time.Sleep(w.delay)
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.fail {
+		return 0, errors.New("targettest: injected write error")
+	}
+	return w.buf.Write(p)
+}
+
+func (w *faultWriter) setFail(fail bool) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.fail = fail
+}
+
+func (w *faultWriter) String() string {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.buf.String()
+}
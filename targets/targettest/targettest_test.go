@@ -0,0 +1,84 @@
+package targettest_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/targets/targettest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterTarget(t *testing.T) {
+	err := targettest.TestTarget(func(w io.Writer) (logr.Target, error) {
+		return targets.NewWriterTarget(w), nil
+	})
+	assert.NoError(t, err)
+}
+
+// batchTarget buffers records until flushed or shut down.
+type batchTarget struct {
+	out io.Writer
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *batchTarget) Init() error { return nil }
+
+func (b *batchTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *batchTarget) Flush() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if _, err := b.out.Write(b.buf.Bytes()); err != nil {
+		return err
+	}
+	b.buf.Reset()
+	return nil
+}
+
+func (b *batchTarget) Shutdown() error { return b.Flush() }
+
+func TestBatchTarget(t *testing.T) {
+	err := targettest.TestTarget(func(w io.Writer) (logr.Target, error) {
+		return &batchTarget{out: w}, nil
+	})
+	assert.NoError(t, err)
+}
+
+// lossyTarget drops write errors and panics on shutdown.
+type lossyTarget struct {
+	out io.Writer
+}
+
+func (l *lossyTarget) Init() error { return nil }
+
+func (l *lossyTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	_, _ = l.out.Write(p)
+	return len(p), nil
+}
+
+func (l *lossyTarget) Shutdown() error { panic("boom") }
+
+func TestLossyTarget(t *testing.T) {
+	err := targettest.TestTarget(func(w io.Writer) (logr.Target, error) {
+		return &lossyTarget{out: w}, nil
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "writer errors: records lost without an error being reported")
+		assert.Contains(t, err.Error(), "Shutdown panic: boom")
+	}
+
+	err = targettest.TestTarget(func(w io.Writer) (logr.Target, error) {
+		return nil, errors.New("cannot create")
+	})
+	assert.Error(t, err)
+}
@@ -27,6 +27,8 @@ type Tcp struct {
 	options *TcpOptions
 	addy    string
 
+	backoff Backoff
+
 	mutex    sync.Mutex
 	conn     net.Conn
 	monitor  chan struct{}
@@ -41,6 +43,9 @@ type TcpOptions struct {
 	TLS      bool   `json:"tls"`
 	Cert     string `json:"cert"`
 	Insecure bool   `json:"insecure"`
+
+	// Backoff determines the delay between reconnect attempts. Defaults to `DefaultBackoff`.
+	Backoff *ExponentialBackoff `json:"backoff,omitempty"`
 }
 
 func (to TcpOptions) CheckValid() error {
@@ -50,6 +55,9 @@ func (to TcpOptions) CheckValid() error {
 	if to.Port == 0 {
 		return errors.New("missing port")
 	}
+	if to.Backoff != nil {
+		return to.Backoff.CheckValid()
+	}
 	return nil
 }
 
@@ -61,6 +69,11 @@ func NewTcpTarget(options *TcpOptions) *Tcp {
 		monitor:  make(chan struct{}),
 		shutdown: make(chan struct{}),
 	}
+	if options.Backoff != nil {
+		tcp.backoff = options.Backoff
+	} else {
+		tcp.backoff = DefaultBackoff()
+	}
 	return tcp
 }
 
@@ -71,7 +84,7 @@ func (tcp *Tcp) Init() error {
 
 // getConn provides a net.Conn.  If a connection already exists, it is returned immediately,
 // otherwise this method blocks until a new connection is created, timeout or shutdown.
-func (tcp *Tcp) getConn(reporter func(err interface{})) (net.Conn, error) {
+func (tcp *Tcp) getConn(ctx context.Context, reporter func(err interface{})) (net.Conn, error) {
 	tcp.mutex.Lock()
 	defer tcp.mutex.Unlock()
 
@@ -84,8 +97,8 @@ func (tcp *Tcp) getConn(reporter func(err interface{})) (net.Conn, error) {
 		err  error
 	}
 
-	connChan := make(chan result)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*DialTimeoutSecs)
+	connChan := make(chan result, 1) // buffered so the dialer never blocks if abandoned
+	ctx, cancel := context.WithTimeout(ctx, time.Second*DialTimeoutSecs)
 	defer cancel()
 
 	go func(ctx context.Context, ch chan result) {
@@ -103,6 +116,8 @@ func (tcp *Tcp) getConn(reporter func(err interface{})) (net.Conn, error) {
 	select {
 	case <-tcp.shutdown:
 		return nil, errors.New("shutdown")
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	case res := <-connChan:
 		return res.conn, res.err
 	}
@@ -164,25 +179,37 @@ func (tcp *Tcp) Shutdown() error {
 // Write converts the log record to bytes, via the Formatter, and outputs to the socket.
 // Called by dedicated target goroutine and will block until success or shutdown.
 func (tcp *Tcp) Write(p []byte, rec *logr.LogRec) (int, error) {
-	try := 1
-	backoff := RetryBackoffMillis
+	return tcp.WriteContext(context.Background(), p, rec)
+}
+
+// WriteContext is like Write but gives up once ctx is done, returning the context error.
+// Called by dedicated target goroutine and will block until success, shutdown or ctx done.
+func (tcp *Tcp) WriteContext(ctx context.Context, p []byte, rec *logr.LogRec) (int, error) {
+	attempt := 1
 	for {
 		select {
 		case <-tcp.shutdown:
 			return 0, nil
+		case <-ctx.Done():
+			return 0, fmt.Errorf("log target %s write aborted: %w", tcp.String(), ctx.Err())
 		default:
 		}
 
 		reporter := rec.Logger().Logr().ReportError
 
-		conn, err := tcp.getConn(reporter)
+		conn, err := tcp.getConn(ctx, reporter)
 		if err != nil {
 			reporter(fmt.Errorf("log target %s connection error: %w", tcp.String(), err))
-			backoff = tcp.sleep(backoff)
+			tcp.sleep(ctx, attempt)
+			attempt++
 			continue
 		}
 
-		err = conn.SetWriteDeadline(time.Now().Add(time.Second * WriteTimeoutSecs))
+		deadline := time.Now().Add(time.Second * WriteTimeoutSecs)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		err = conn.SetWriteDeadline(deadline)
 		if err != nil {
 			reporter(fmt.Errorf("log target %s set write deadline error: %w", tcp.String(), err))
 		}
@@ -196,8 +223,8 @@ func (tcp *Tcp) Write(p []byte, rec *logr.LogRec) (int, error) {
 
 		_ = tcp.close()
 
-		backoff = tcp.sleep(backoff)
-		try++
+		tcp.sleep(ctx, attempt)
+		attempt++
 	}
 }
 
@@ -237,15 +264,25 @@ func (tcp *Tcp) String() string {
 	return fmt.Sprintf("TcpTarget[%s:%d]", tcp.options.IP, tcp.options.Port)
 }
 
-func (tcp *Tcp) sleep(backoff int64) int64 {
-	select {
-	case <-tcp.shutdown:
-	case <-time.After(time.Millisecond * time.Duration(backoff)):
+// SetBackoff sets the policy used to determine the delay between reconnect attempts.
+// Nil restores `DefaultBackoff`.
+func (tcp *Tcp) SetBackoff(backoff Backoff) {
+	if eb, ok := backoff.(*ExponentialBackoff); backoff == nil || (ok && eb == nil) {
+		backoff = DefaultBackoff()
 	}
+	tcp.mutex.Lock()
+	defer tcp.mutex.Unlock()
+	tcp.backoff = backoff
+}
 
-	nextBackoff := backoff + (backoff >> 1)
-	if nextBackoff > MaxRetryBackoffMillis {
-		nextBackoff = MaxRetryBackoffMillis
+func (tcp *Tcp) sleep(ctx context.Context, attempt int) {
+	tcp.mutex.Lock()
+	backoff := tcp.backoff
+	tcp.mutex.Unlock()
+
+	select {
+	case <-tcp.shutdown:
+	case <-ctx.Done():
+	case <-time.After(backoff.Delay(attempt)):
 	}
-	return nextBackoff
 }
@@ -4,7 +4,9 @@
 package targets
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/mattermost/logr/v2"
 	"github.com/mattermost/logr/v2/formatters"
@@ -63,3 +65,16 @@ func TestNewTcpTarget(t *testing.T) {
 		}
 	})
 }
+
+func TestTcpSetBackoffNil(t *testing.T) {
+	tcp := NewTcpTarget(&TcpOptions{IP: Server, Port: TestPort})
+	var nilBackoff *ExponentialBackoff
+	for _, b := range []Backoff{nil, nilBackoff} {
+		tcp.SetBackoff(b)
+
+		// must not panic computing the reconnect delay.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		tcp.sleep(ctx, 1)
+		cancel()
+	}
+}
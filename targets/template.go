@@ -0,0 +1,90 @@
+package targets
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// fieldTemplate expands placeholders such as "{user_id}" with values from a log
+// record. The placeholders "{level}" and "{msg}" expand to the record's level name
+// and message; any other name expands to the value of the field with that key, or
+// an empty string if the record has no such field.
+type fieldTemplate struct {
+	parts []templatePart
+}
+
+type templatePart struct {
+	literal string
+	name    string // placeholder name; empty for literals
+}
+
+func parseFieldTemplate(s string) (*fieldTemplate, error) {
+	tmpl := &fieldTemplate{}
+	for len(s) > 0 {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			tmpl.parts = append(tmpl.parts, templatePart{literal: s})
+			break
+		}
+		if start > 0 {
+			tmpl.parts = append(tmpl.parts, templatePart{literal: s[:start]})
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return nil, errors.New("template missing closing brace")
+		}
+		name := s[start+1 : start+end]
+		if name == "" {
+			return nil, errors.New("template has empty placeholder")
+		}
+		tmpl.parts = append(tmpl.parts, templatePart{name: name})
+		s = s[start+end+1:]
+	}
+	return tmpl, nil
+}
+
+// isEmpty returns true if the template expands to an empty string for all records.
+func (t *fieldTemplate) isEmpty() bool {
+	return t == nil || len(t.parts) == 0
+}
+
+// execute expands the template for the log record.
+func (t *fieldTemplate) execute(rec *logr.LogRec) string {
+	if t.isEmpty() {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range t.parts {
+		switch part.name {
+		case "":
+			sb.WriteString(part.literal)
+		case "level":
+			sb.WriteString(rec.Level().Name)
+		case "msg":
+			sb.WriteString(rec.Msg())
+		default:
+			sb.WriteString(fieldValue(rec, part.name))
+		}
+	}
+	return sb.String()
+}
+
+// fieldValue returns the string value of the named field, or empty string if
+// the record does not contain the field.
+func fieldValue(rec *logr.LogRec, key string) string {
+	fields := rec.Fields()
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == key {
+			var sb strings.Builder
+			_ = fields[i].ValueString(&sb, neverQuote)
+			return sb.String()
+		}
+	}
+	return ""
+}
+
+func neverQuote(string) bool {
+	return false
+}
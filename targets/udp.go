@@ -0,0 +1,170 @@
+package targets
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mattermost/logr/v2"
+)
+
+const (
+	// UDPDefaultMTU is the largest UDP payload that fits in a standard 1500 byte
+	// Ethernet frame over IPv4 without fragmentation.
+	UDPDefaultMTU = 1472
+
+	// UDPMaxMTU is the largest possible UDP payload.
+	UDPMaxMTU = 65507
+)
+
+// Oversize policies for records larger than the MTU.
+const (
+	OversizeTruncate = "truncate"
+	OversizeSplit    = "split"
+	OversizeDrop     = "drop"
+)
+
+// UDPOptions provides parameters for the UDP target.
+type UDPOptions struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+
+	// MTU is the maximum size of each datagram. Defaults to UDPDefaultMTU.
+	MTU int `json:"mtu,omitempty"`
+
+	// Oversize determines what happens to records larger than the MTU: "truncate"
+	// (the default) sends the first MTU bytes, "split" sends the record as multiple
+	// datagrams, and "drop" discards the record with an error.
+	Oversize string `json:"oversize,omitempty"`
+}
+
+// CheckValid returns an error if the options are invalid.
+func (uo UDPOptions) CheckValid() error {
+	if uo.Host == "" {
+		return errors.New("missing host")
+	}
+	if uo.Port <= 0 || uo.Port > 65535 {
+		return errors.New("missing or invalid port")
+	}
+	if uo.MTU < 0 || uo.MTU > UDPMaxMTU {
+		return fmt.Errorf("mtu must be between 1 and %d", UDPMaxMTU)
+	}
+	switch uo.Oversize {
+	case "", OversizeTruncate, OversizeSplit, OversizeDrop:
+	default:
+		return fmt.Errorf("invalid oversize policy %q", uo.Oversize)
+	}
+	return nil
+}
+
+// UDP outputs log records as datagrams, for fire-and-forget shipping to collectors
+// such as syslog or statsd style servers. Delivery is not guaranteed.
+type UDP struct {
+	options UDPOptions
+	addr    string
+
+	mux  sync.Mutex
+	conn net.Conn
+}
+
+// NewUDPTarget creates a target capable of outputting log records as UDP datagrams.
+func NewUDPTarget(options UDPOptions) (*UDP, error) {
+	if err := options.CheckValid(); err != nil {
+		return nil, err
+	}
+	u := &UDP{
+		options: options,
+		addr:    net.JoinHostPort(options.Host, strconv.Itoa(options.Port)),
+	}
+	if u.options.MTU == 0 {
+		u.options.MTU = UDPDefaultMTU
+	}
+	if u.options.Oversize == "" {
+		u.options.Oversize = OversizeTruncate
+	}
+	return u, nil
+}
+
+// Init is called once to initialize the target.
+func (u *UDP) Init() error {
+	return nil
+}
+
+// Write sends the formatted log record, applying the oversize policy if it is
+// larger than the MTU.
+func (u *UDP) Write(p []byte, rec *logr.LogRec) (int, error) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	if u.conn == nil {
+		conn, err := net.Dial("udp", u.addr)
+		if err != nil {
+			return 0, fmt.Errorf("log target %s dial error: %w", u, err)
+		}
+		u.conn = conn
+	}
+
+	var datagrams [][]byte
+	switch {
+	case len(p) <= u.options.MTU:
+		datagrams = [][]byte{p}
+	case u.options.Oversize == OversizeDrop:
+		return 0, fmt.Errorf("log target %s dropped record of %d bytes exceeding mtu %d", u, len(p), u.options.MTU)
+	case u.options.Oversize == OversizeSplit:
+		datagrams = splitUTF8(p, u.options.MTU)
+	default:
+		datagrams = [][]byte{splitUTF8(p, u.options.MTU)[0]}
+	}
+
+	var count int
+	for _, d := range datagrams {
+		n, err := u.conn.Write(d)
+		count += n
+		if err != nil {
+			// the socket may have been invalidated, e.g. by an ICMP unreachable; redial next time.
+			u.conn.Close()
+			u.conn = nil
+			return count, fmt.Errorf("log target %s write error: %w", u, err)
+		}
+	}
+	return len(p), nil
+}
+
+// splitUTF8 splits p into chunks of at most size bytes without splitting
+// multi-byte UTF-8 characters, where possible.
+func splitUTF8(p []byte, size int) [][]byte {
+	var chunks [][]byte
+	for len(p) > size {
+		n := size
+		// back up to the start of a rune, unless there is none in range.
+		for n > size-utf8.UTFMax && n > 0 && !utf8.RuneStart(p[n]) {
+			n--
+		}
+		if n == 0 || !utf8.RuneStart(p[n]) {
+			n = size
+		}
+		chunks = append(chunks, p[:n])
+		p = p[n:]
+	}
+	return append(chunks, p)
+}
+
+// Shutdown closes the socket.
+func (u *UDP) Shutdown() error {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	if u.conn != nil {
+		err := u.conn.Close()
+		u.conn = nil
+		return err
+	}
+	return nil
+}
+
+// String returns a string representation of this target.
+func (u *UDP) String() string {
+	return fmt.Sprintf("UDPTarget[%s]", u.addr)
+}
@@ -0,0 +1,77 @@
+package targets
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitUTF8(t *testing.T) {
+	chunks := splitUTF8([]byte("abcdef"), 4)
+	assert.Equal(t, [][]byte{[]byte("abcd"), []byte("ef")}, chunks)
+
+	// "é" is two bytes and must not be split.
+	chunks = splitUTF8([]byte("abcé"), 4)
+	assert.Equal(t, [][]byte{[]byte("abc"), []byte("é")}, chunks)
+
+	assert.Equal(t, [][]byte{[]byte("ab")}, splitUTF8([]byte("ab"), 4))
+}
+
+func TestUDPTarget(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+
+	read := func() []string {
+		var got []string
+		buf := make([]byte, UDPMaxMTU)
+		for {
+			_ = pc.SetReadDeadline(time.Now().Add(time.Millisecond * 200))
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				return got
+			}
+			got = append(got, string(buf[:n]))
+		}
+	}
+
+	tests := []struct {
+		oversize string
+		want     []string
+	}{
+		{OversizeTruncate, []string{"short", strings.Repeat("x", 16)}},
+		{OversizeSplit, []string{"short", strings.Repeat("x", 16), strings.Repeat("x", 14)}},
+		{OversizeDrop, []string{"short"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.oversize, func(t *testing.T) {
+			lgr, err := logr.New()
+			require.NoError(t, err)
+
+			u, err := NewUDPTarget(UDPOptions{Host: "127.0.0.1", Port: port, MTU: 16, Oversize: tt.oversize})
+			require.NoError(t, err)
+			formatter := &formatters.Plain{DisableTimestamp: true, DisableLevel: true, LineEnd: " "}
+			err = lgr.AddTarget(u, "udp_test", &logr.StdFilter{Lvl: logr.Info}, formatter, 100)
+			require.NoError(t, err)
+
+			logger := lgr.NewLogger()
+			logger.Info("short")
+			logger.Info(strings.Repeat("x", 30))
+			require.NoError(t, lgr.Shutdown())
+
+			got := read()
+			for i := range got {
+				got[i] = strings.TrimSpace(got[i])
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/mattermost/logr/v2/formatters"
 	"github.com/mattermost/logr/v2/targets"
 	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func ExampleWriter() {
@@ -83,3 +86,46 @@ func writer(t *testing.T, formatter logr.Formatter) {
 		t.Errorf("wrong level(s) enabled")
 	}
 }
+
+// gatedWriter blocks the first write until released and counts writes.
+type gatedWriter struct {
+	test.Buffer
+	blocked chan struct{}
+	release chan struct{}
+	writes  int32
+}
+
+func (gw *gatedWriter) Write(p []byte) (int, error) {
+	if atomic.AddInt32(&gw.writes, 1) == 1 {
+		close(gw.blocked)
+		<-gw.release
+	}
+	return gw.Buffer.Write(p)
+}
+
+func TestWriterBatch(t *testing.T) {
+	lgr, err := logr.New()
+	require.NoError(t, err)
+
+	out := &gatedWriter{blocked: make(chan struct{}), release: make(chan struct{})}
+	target := targets.NewWriterTargetWithOptions(out, targets.WriterOptions{MaxBatchRecords: 4})
+	err = lgr.AddTarget(target, "batch", &logr.StdFilter{Lvl: logr.Info}, &formatters.Plain{DisableTimestamp: true}, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	logger.Info("first")
+	<-out.blocked
+
+	var expected strings.Builder
+	expected.WriteString("info first \n")
+	for i := 0; i < 6; i++ {
+		logger.Info("queued", logr.Int("i", i))
+		fmt.Fprintf(&expected, "info queued i=%d\n", i)
+	}
+	close(out.release)
+	require.NoError(t, lgr.Shutdown())
+
+	assert.Equal(t, expected.String(), out.String())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&out.writes)) // 1 + batches of 4 and 2
+	assert.Equal(t, uint64(7), lgr.StatsSnapshot().Targets[0].Logged)
+}
@@ -139,3 +139,54 @@ func BenchmarkLogger(b *testing.B) {
 	err := lgr.Shutdown()
 	require.NoError(b, err)
 }
+
+// Logger avoids compiler optimization.
+var Logger logr.Logger
+
+// BenchmarkLoggerWith measures deriving a request scoped Logger from a Logger
+// that already has many fields, as is typical when a Logger is created per HTTP request.
+func BenchmarkLoggerWith(b *testing.B) {
+	lgr, _ := logr.New()
+	logger := newServiceLogger(lgr)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Logger = logger.With(logr.String("request_id", "d5e1c2a9"), logr.String("path", "/api/v4/users"))
+	}
+	b.StopTimer()
+	err := lgr.Shutdown()
+	require.NoError(b, err)
+}
+
+// BenchmarkLoggerWithPerRequest measures deriving a request scoped Logger and
+// logging once with it, with the record being filtered out.
+func BenchmarkLoggerWithPerRequest(b *testing.B) {
+	lgr, _ := logr.New()
+	filter := &logr.StdFilter{Lvl: logr.Warn}
+	formatter := &formatters.Plain{Delim: " | "}
+	target := targets.NewWriterTarget(ioutil.Discard)
+	err := lgr.AddTarget(target, "benchmarkTest", filter, formatter, 1000)
+	require.NoError(b, err)
+
+	logger := newServiceLogger(lgr)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reqLogger := logger.With(logr.String("request_id", "d5e1c2a9"))
+		reqLogger = reqLogger.With(logr.String("user_id", "wiggin"))
+		reqLogger.Debug("request handled", logr.Int("status", 200))
+	}
+	b.StopTimer()
+	err = lgr.Shutdown()
+	require.NoError(b, err)
+}
+
+func newServiceLogger(lgr *logr.Logr) logr.Logger {
+	fields := make([]logr.Field, 0, 10)
+	for i := 0; i < cap(fields); i++ {
+		fields = append(fields, logr.String("service_field_"+strconv.Itoa(i), "value"))
+	}
+	return lgr.NewLogger().With(fields...)
+}
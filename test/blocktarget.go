@@ -0,0 +1,59 @@
+package test
+
+import (
+	"io"
+	"sync"
+
+	"github.com/mattermost/logr/v2"
+)
+
+// BlockingTarget outputs log records to any `io.Writer` but blocks writes until
+// `Unblock` is called, allowing tests to fill target queues deterministically.
+type BlockingTarget struct {
+	out     io.Writer
+	mux     sync.Mutex
+	blocked chan struct{} // signaled when first write is blocked
+	unblock chan struct{}
+	once    sync.Once
+}
+
+// NewBlockingTarget creates a new BlockingTarget.
+func NewBlockingTarget(out io.Writer) *BlockingTarget {
+	return &BlockingTarget{
+		out:     out,
+		blocked: make(chan struct{}),
+		unblock: make(chan struct{}),
+	}
+}
+
+func (bt *BlockingTarget) Init() error {
+	return nil
+}
+
+// Write blocks until `Unblock` is called.
+func (bt *BlockingTarget) Write(p []byte, rec *logr.LogRec) (int, error) {
+	select {
+	case <-bt.unblock:
+	default:
+		bt.once.Do(func() { close(bt.blocked) })
+		<-bt.unblock
+	}
+
+	bt.mux.Lock()
+	defer bt.mux.Unlock()
+	return bt.out.Write(p)
+}
+
+// Blocked returns a channel that is closed once a write is blocked.
+func (bt *BlockingTarget) Blocked() <-chan struct{} {
+	return bt.blocked
+}
+
+// Unblock allows all current and future writes to proceed.
+func (bt *BlockingTarget) Unblock() {
+	close(bt.unblock)
+}
+
+func (bt *BlockingTarget) Shutdown() error {
+	return nil
+}
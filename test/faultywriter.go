@@ -0,0 +1,130 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// ErrInjected is returned by `FaultyWriter` for writes that are made to fail.
+var ErrInjected = errors.New("injected write error")
+
+// FaultyWriter wraps an `io.Writer`, injecting write errors, delays and panics so
+// that retry and overflow paths can be exercised deterministically. Faults can be
+// changed at any time, including while writes are in progress.
+type FaultyWriter struct {
+	out io.Writer
+
+	mux       sync.Mutex
+	writes    int
+	failures  int
+	failEvery int
+	failNext  int
+	panicNext bool
+	delay     time.Duration
+	release   chan struct{} // closed to release writes blocked by the current delay
+}
+
+// NewFaultyWriter creates a new FaultyWriter writing to out.
+func NewFaultyWriter(out io.Writer) *FaultyWriter {
+	if out == nil {
+		out = ioutil.Discard
+	}
+	return &FaultyWriter{
+		out:     out,
+		release: make(chan struct{}),
+	}
+}
+
+// FailEvery makes every nth write fail with `ErrInjected`, counting from the
+// first write. Zero disables.
+func (fw *FaultyWriter) FailEvery(n int) {
+	fw.mux.Lock()
+	defer fw.mux.Unlock()
+	fw.failEvery = n
+}
+
+// FailNext makes the next n writes fail with `ErrInjected`.
+func (fw *FaultyWriter) FailNext(n int) {
+	fw.mux.Lock()
+	defer fw.mux.Unlock()
+	fw.failNext = n
+}
+
+// PanicNext makes the next write panic.
+func (fw *FaultyWriter) PanicNext() {
+	fw.mux.Lock()
+	defer fw.mux.Unlock()
+	fw.panicNext = true
+}
+
+// BlockFor makes each write block for d before proceeding. Writes already blocked
+// are released, so `BlockFor(0)` unblocks everything; use a large duration to block
+// until released.
+func (fw *FaultyWriter) BlockFor(d time.Duration) {
+	fw.mux.Lock()
+	defer fw.mux.Unlock()
+	fw.delay = d
+	close(fw.release)
+	fw.release = make(chan struct{})
+}
+
+// Writes returns the number of writes attempted, including those that failed,
+// panicked or are blocked.
+func (fw *FaultyWriter) Writes() int {
+	fw.mux.Lock()
+	defer fw.mux.Unlock()
+	return fw.writes
+}
+
+// Failures returns the number of writes that failed with `ErrInjected`.
+func (fw *FaultyWriter) Failures() int {
+	fw.mux.Lock()
+	defer fw.mux.Unlock()
+	return fw.failures
+}
+
+// Write applies any faults then writes to the wrapped writer.
+func (fw *FaultyWriter) Write(p []byte) (int, error) {
+	fw.mux.Lock()
+	fw.writes++
+	delay, release := fw.delay, fw.release
+
+	var fail, panicNow bool
+	switch {
+	case fw.panicNext:
+		fw.panicNext = false
+		panicNow = true
+	case fw.failNext > 0:
+		fw.failNext--
+		fail = true
+	case fw.failEvery > 0 && fw.writes%fw.failEvery == 0:
+		fail = true
+	}
+	if fail {
+		fw.failures++
+	}
+	fw.mux.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-release:
+			timer.Stop()
+		}
+	}
+
+	if panicNow {
+		panic("FaultyWriter: injected panic")
+	}
+	if fail {
+		return 0, ErrInjected
+	}
+
+	fw.mux.Lock()
+	defer fw.mux.Unlock()
+	return fw.out.Write(p)
+}
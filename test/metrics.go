@@ -1,6 +1,8 @@
 package test
 
 import (
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/mattermost/logr/v2"
@@ -12,6 +14,13 @@ type TestMetrics struct {
 	Errors    float64
 	Dropped   float64
 	Blocked   float64
+
+	FlushDuration  float64
+	FlushedRecords float64
+	ShutdownDrain  float64
+	Abandoned      float64
+	Stale          float64
+	FormattedBytes float64
 }
 
 type TestMetricsCollector struct {
@@ -20,6 +29,24 @@ type TestMetricsCollector struct {
 	errorCounters   map[string]*TestCounter
 	droppedCounters map[string]*TestCounter
 	blockedCounters map[string]*TestCounter
+	shedCounters    map[string]*TestCounter
+
+	flushDurationGauges  map[string]*TestGauge
+	flushedRecordsGauges map[string]*TestGauge
+	shutdownDrainGauges  map[string]*TestGauge
+	abandonedCounters    map[string]*TestCounter
+	staleCounters        map[string]*TestCounter
+	formattedBytes       map[string]*TestCounter
+
+	volumeRecordsCounters map[string]*TestCounter
+	volumeBytesCounters   map[string]*TestCounter
+
+	schemaViolationCounters map[string]*TestCounter
+
+	logMetricCounters   map[string]*TestCounter
+	logMetricHistograms map[string]*TestHistogram
+
+	burstCounters map[string]*TestCounter
 }
 
 func NewTestMetricsCollector() *TestMetricsCollector {
@@ -29,6 +56,24 @@ func NewTestMetricsCollector() *TestMetricsCollector {
 		errorCounters:   make(map[string]*TestCounter),
 		droppedCounters: make(map[string]*TestCounter),
 		blockedCounters: make(map[string]*TestCounter),
+		shedCounters:    make(map[string]*TestCounter),
+
+		flushDurationGauges:  make(map[string]*TestGauge),
+		flushedRecordsGauges: make(map[string]*TestGauge),
+		shutdownDrainGauges:  make(map[string]*TestGauge),
+		abandonedCounters:    make(map[string]*TestCounter),
+		staleCounters:        make(map[string]*TestCounter),
+		formattedBytes:       make(map[string]*TestCounter),
+
+		volumeRecordsCounters: make(map[string]*TestCounter),
+		volumeBytesCounters:   make(map[string]*TestCounter),
+
+		schemaViolationCounters: make(map[string]*TestCounter),
+
+		logMetricCounters:   make(map[string]*TestCounter),
+		logMetricHistograms: make(map[string]*TestHistogram),
+
+		burstCounters: make(map[string]*TestCounter),
 	}
 }
 
@@ -39,7 +84,46 @@ func (c *TestMetricsCollector) Get(target string) TestMetrics {
 		Errors:    c.errorCounters[target].get(),
 		Dropped:   c.droppedCounters[target].get(),
 		Blocked:   c.blockedCounters[target].get(),
+
+		FlushDuration:  c.flushDurationGauges[target].get(),
+		FlushedRecords: c.flushedRecordsGauges[target].get(),
+		ShutdownDrain:  c.shutdownDrainGauges[target].get(),
+		Abandoned:      c.abandonedCounters[target].get(),
+		Stale:          c.staleCounters[target].get(),
+		FormattedBytes: c.formattedBytes[target].get(),
+	}
+}
+
+// GetShed returns the number of records shed for the target and level.
+func (c *TestMetricsCollector) GetShed(target string, level string) float64 {
+	return c.shedCounters[target+"/"+level].get()
+}
+
+// GetVolume returns the number of records and bytes counted for the volume
+// accounting key.
+func (c *TestMetricsCollector) GetVolume(key string) (records float64, bytes float64) {
+	return c.volumeRecordsCounters[key].get(), c.volumeBytesCounters[key].get()
+}
+
+// GetSchemaViolations returns the number of schema violations counted for the field key.
+func (c *TestMetricsCollector) GetSchemaViolations(key string) float64 {
+	return c.schemaViolationCounters[key].get()
+}
+
+// GetLogMetric returns the value of the counter, or the sum of the observations of
+// the histogram, derived from log records for the metric name and label values,
+// given as alternating keys and values sorted by key.
+func (c *TestMetricsCollector) GetLogMetric(name string, labels ...string) float64 {
+	key := logMetricKey(name, labels...)
+	if h, ok := c.logMetricHistograms[key]; ok {
+		return h.get()
 	}
+	return c.logMetricCounters[key].get()
+}
+
+// GetBursts returns the number of bursts detected for the level name.
+func (c *TestMetricsCollector) GetBursts(level string) float64 {
+	return c.burstCounters[level].get()
 }
 
 func (c *TestMetricsCollector) QueueSizeGauge(target string) (logr.Gauge, error) {
@@ -146,3 +230,123 @@ func (c *TestCounter) Add(val float64) {
 	defer c.mux.Unlock()
 	c.val += val
 }
+
+func (c *TestMetricsCollector) ShedCounter(target string, level string) (logr.Counter, error) {
+	counter, ok := c.shedCounters[target+"/"+level]
+	if !ok {
+		counter = &TestCounter{}
+		c.shedCounters[target+"/"+level] = counter
+	}
+	return counter, nil
+}
+
+func (c *TestMetricsCollector) FlushDurationGauge(target string) (logr.Gauge, error) {
+	return getGauge(c.flushDurationGauges, target), nil
+}
+
+func (c *TestMetricsCollector) FlushedRecordsGauge(target string) (logr.Gauge, error) {
+	return getGauge(c.flushedRecordsGauges, target), nil
+}
+
+func (c *TestMetricsCollector) ShutdownDrainGauge(target string) (logr.Gauge, error) {
+	return getGauge(c.shutdownDrainGauges, target), nil
+}
+
+func (c *TestMetricsCollector) AbandonedCounter(target string) (logr.Counter, error) {
+	return getCounter(c.abandonedCounters, target), nil
+}
+
+func (c *TestMetricsCollector) StaleCounter(target string) (logr.Counter, error) {
+	return getCounter(c.staleCounters, target), nil
+}
+
+func (c *TestMetricsCollector) FormattedBytesCounter(target string) (logr.Counter, error) {
+	return getCounter(c.formattedBytes, target), nil
+}
+
+func (c *TestMetricsCollector) VolumeRecordsCounter(key string) (logr.Counter, error) {
+	return getCounter(c.volumeRecordsCounters, key), nil
+}
+
+func (c *TestMetricsCollector) VolumeBytesCounter(key string) (logr.Counter, error) {
+	return getCounter(c.volumeBytesCounters, key), nil
+}
+
+func (c *TestMetricsCollector) SchemaViolationCounter(key string) (logr.Counter, error) {
+	return getCounter(c.schemaViolationCounters, key), nil
+}
+
+func (c *TestMetricsCollector) LogMetricCounter(name string, labels map[string]string) (logr.Counter, error) {
+	return getCounter(c.logMetricCounters, logMetricKey(name, labelPairs(labels)...)), nil
+}
+
+func (c *TestMetricsCollector) LogMetricHistogram(name string, labels map[string]string) (logr.Histogram, error) {
+	key := logMetricKey(name, labelPairs(labels)...)
+	h, ok := c.logMetricHistograms[key]
+	if !ok {
+		h = &TestHistogram{}
+		c.logMetricHistograms[key] = h
+	}
+	return h, nil
+}
+
+func (c *TestMetricsCollector) BurstCounter(level string) (logr.Counter, error) {
+	return getCounter(c.burstCounters, level), nil
+}
+
+// TestHistogram sums its observations.
+type TestHistogram struct {
+	mux sync.Mutex
+	sum float64
+}
+
+func (h *TestHistogram) get() float64 {
+	if h == nil {
+		return 0
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.sum
+}
+
+func (h *TestHistogram) Observe(val float64) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.sum += val
+}
+
+// labelPairs returns the labels as alternating keys and values, sorted by key.
+func labelPairs(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(labels)*2)
+	for _, k := range keys {
+		pairs = append(pairs, k, labels[k])
+	}
+	return pairs
+}
+
+func logMetricKey(name string, labels ...string) string {
+	return name + "{" + strings.Join(labels, ",") + "}"
+}
+
+func getCounter(counters map[string]*TestCounter, key string) *TestCounter {
+	counter, ok := counters[key]
+	if !ok {
+		counter = &TestCounter{}
+		counters[key] = counter
+	}
+	return counter
+}
+
+func getGauge(gauges map[string]*TestGauge, target string) *TestGauge {
+	gauge, ok := gauges[target]
+	if !ok {
+		gauge = &TestGauge{}
+		gauges[target] = gauge
+	}
+	return gauge
+}
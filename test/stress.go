@@ -0,0 +1,196 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/wiggin77/merror"
+)
+
+const (
+	// DefaultStressGoroutines is the default number of logging goroutines used by `Stress`.
+	DefaultStressGoroutines = 200
+	// DefaultStressLoops is the default number of loops per goroutine used by `Stress`.
+	DefaultStressLoops = 50
+	// DefaultStressFlushes is the default number of flushes performed by `Stress`.
+	DefaultStressFlushes = 20
+	// DefaultStressTargetChurn is the default number of targets added and removed by `Stress`.
+	DefaultStressTargetChurn = 10
+)
+
+// StressCfg is configuration for the `Stress` harness.
+type StressCfg struct {
+	// NewTarget creates the target being stressed. It is called once for the target
+	// present for the whole run, and again for each target added and removed during
+	// the run. Required.
+	NewTarget func() (logr.Target, error)
+	// Filter used for all targets. Defaults to Trace, with stack traces for Error.
+	Filter logr.Filter
+	// Formatter used for all targets. Defaults to `formatters.Plain`.
+	Formatter logr.Formatter
+	// QueueSize is the maximum queue size of each target. Defaults to 1000.
+	QueueSize int
+	// Goroutines is number of logging goroutines to start.
+	Goroutines int
+	// Loops is number of loops per goroutine.
+	Loops int
+	// Flushes is number of times the Logr is flushed while logging.
+	Flushes int
+	// TargetChurn is number of times a target is added then removed while logging.
+	TargetChurn int
+	// Options are used to create the Logr. Any `OnLoggerError` option is replaced.
+	Options []logr.Option
+}
+
+// Stress hammers a new Logr from many goroutines, each logging through chains of
+// `Logger.With` calls at a mix of levels, while concurrently flushing the Logr and
+// adding and removing targets. Once half the records are logged the Logr is shut
+// down while the goroutines continue logging.
+//
+// Run under `go test -race` to validate a custom target's synchronization. An error
+// is returned for any failure to add, remove, flush or shut down, and for any error
+// reported by a target.
+func Stress(cfg StressCfg) error {
+	if cfg.NewTarget == nil {
+		return errors.New("NewTarget is required")
+	}
+	applyStressDefaults(&cfg)
+
+	var mux sync.Mutex
+	errs := merror.New()
+	fail := func(err error) {
+		mux.Lock()
+		defer mux.Unlock()
+		errs.Append(err)
+	}
+
+	lgr, err := logr.New(append(cfg.Options, logr.OnLoggerError(fail))...)
+	if err != nil {
+		return err
+	}
+	addTarget := func(name string) error {
+		target, err := cfg.NewTarget()
+		if err != nil {
+			return err
+		}
+		return lgr.AddTarget(target, name, cfg.Filter, cfg.Formatter, cfg.QueueSize)
+	}
+	if err := addTarget("stress"); err != nil {
+		return err
+	}
+
+	total := int64(cfg.Goroutines * cfg.Loops)
+	var logged int64
+	halfway := make(chan struct{})
+	var halfwayOnce sync.Once
+
+	var wgLog sync.WaitGroup
+	for g := 0; g < cfg.Goroutines; g++ {
+		wgLog.Add(1)
+		go func(g int) {
+			defer wgLog.Done()
+			logger := lgr.NewLogger().With(logr.Int("goroutine", g))
+			for i := 0; i < cfg.Loops; i++ {
+				stressLog(logger, g, i)
+				if atomic.AddInt64(&logged, 1) >= total/2 {
+					halfwayOnce.Do(func() { close(halfway) })
+				}
+			}
+		}(g)
+	}
+
+	// flushes and target churn stop before shutdown since both fail afterwards.
+	stop := make(chan struct{})
+	var wgOps sync.WaitGroup
+	wgOps.Add(2)
+	go func() {
+		defer wgOps.Done()
+		for i := 0; i < cfg.Flushes && !isClosed(stop); i++ {
+			if err := lgr.Flush(); err != nil {
+				fail(fmt.Errorf("flush: %w", err))
+			}
+		}
+	}()
+	go func() {
+		defer wgOps.Done()
+		for i := 0; i < cfg.TargetChurn && !isClosed(stop); i++ {
+			name := fmt.Sprintf("stress-churn-%d", i)
+			if err := addTarget(name); err != nil {
+				fail(fmt.Errorf("add target: %w", err))
+				continue
+			}
+			time.Sleep(time.Millisecond)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			err := lgr.RemoveTargets(ctx, func(ti logr.TargetInfo) bool {
+				return ti.Name == name
+			})
+			cancel()
+			if err != nil {
+				fail(fmt.Errorf("remove target: %w", err))
+			}
+		}
+	}()
+
+	<-halfway
+	close(stop)
+	wgOps.Wait()
+
+	if err := lgr.Shutdown(); err != nil {
+		fail(fmt.Errorf("shutdown: %w", err))
+	}
+	wgLog.Wait()
+
+	mux.Lock()
+	defer mux.Unlock()
+	return errs.ErrorOrNil()
+}
+
+func applyStressDefaults(cfg *StressCfg) {
+	if cfg.Filter == nil {
+		cfg.Filter = &logr.StdFilter{Lvl: logr.Trace, Stacktrace: logr.Error}
+	}
+	if cfg.Formatter == nil {
+		cfg.Formatter = &formatters.Plain{}
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Goroutines <= 0 {
+		cfg.Goroutines = DefaultStressGoroutines
+	}
+	if cfg.Loops <= 0 {
+		cfg.Loops = DefaultStressLoops
+	}
+	if cfg.Flushes <= 0 {
+		cfg.Flushes = DefaultStressFlushes
+	}
+	if cfg.TargetChurn <= 0 {
+		cfg.TargetChurn = DefaultStressTargetChurn
+	}
+}
+
+var stressLevels = []logr.Level{logr.Trace, logr.Debug, logr.Info, logr.Warn, logr.Error}
+
+// stressLog logs one record through a chain of `With` calls whose depth varies.
+func stressLog(logger logr.Logger, g int, i int) {
+	l := logger.With(logr.Int("loop", i))
+	for d := 0; d < i%4; d++ {
+		l = l.With(logr.String(fmt.Sprintf("depth%d", d), StringRnd(8)))
+	}
+	l.Log(stressLevels[(g+i)%len(stressLevels)], "stress test", logr.Int("n", g*i), logr.Bool("even", i%2 == 0))
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
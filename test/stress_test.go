@@ -0,0 +1,27 @@
+package test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStress(t *testing.T) {
+	cfg := StressCfg{
+		NewTarget: func() (logr.Target, error) {
+			return targets.NewWriterTarget(ioutil.Discard), nil
+		},
+	}
+	assert.NoError(t, Stress(cfg))
+
+	cfg.NewTarget = func() (logr.Target, error) {
+		return targets.NewWriterTarget(NewFaultyWriter(nil)), nil
+	}
+	cfg.Goroutines = 10
+	assert.NoError(t, Stress(cfg))
+
+	assert.Error(t, Stress(StressCfg{}))
+}
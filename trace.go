@@ -0,0 +1,79 @@
+package logr
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PipelinePhase identifies a stage of the logging pipeline.
+type PipelinePhase uint8
+
+const (
+	// PhaseEnqueue is the time spent adding a record to the Logr queue, which
+	// is non-zero when the queue is full. This is measured on the calling goroutine.
+	PhaseEnqueue PipelinePhase = iota + 1
+	// PhaseDequeue is the time a record waited in the Logr queue.
+	PhaseDequeue
+	// PhaseTargetDequeue is the time a record waited in a target queue.
+	PhaseTargetDequeue
+	// PhaseFormat is the time a target's formatter took to format a record.
+	PhaseFormat
+	// PhaseWrite is the time a target took to write a formatted record.
+	PhaseWrite
+)
+
+// String returns the name of the phase.
+func (p PipelinePhase) String() string {
+	switch p {
+	case PhaseEnqueue:
+		return "enqueue"
+	case PhaseDequeue:
+		return "dequeue"
+	case PhaseTargetDequeue:
+		return "target_dequeue"
+	case PhaseFormat:
+		return "format"
+	case PhaseWrite:
+		return "write"
+	}
+	return "unknown"
+}
+
+// PipelineEvent reports how long a sampled log record spent in one phase of
+// the logging pipeline. See `PipelineTracing` option.
+type PipelineEvent struct {
+	// ID identifies the sampled record so events for the same record can be correlated.
+	ID uint64
+	// Phase is the pipeline phase being reported.
+	Phase PipelinePhase
+	// Target is the name of the target for target specific phases, otherwise empty.
+	Target string
+	// Level is the level of the sampled record.
+	Level Level
+	// Duration is the time spent in the phase.
+	Duration time.Duration
+}
+
+// sampleRec marks the record as traced if it is selected by the sample rate.
+func (lgr *Logr) sampleRec(rec *LogRec) bool {
+	n := atomic.AddUint64(&lgr.stats.sampled, 1)
+	if n%lgr.options.traceSampleEvery != 0 {
+		return false
+	}
+	rec.traceID = n
+	return true
+}
+
+// tracePhase reports a pipeline event for the record if it is traced.
+func (lgr *Logr) tracePhase(rec *LogRec, phase PipelinePhase, target string, d time.Duration) {
+	if rec.traceID == 0 || lgr.options.traceHook == nil {
+		return
+	}
+	lgr.options.traceHook(PipelineEvent{
+		ID:       rec.traceID,
+		Phase:    phase,
+		Target:   target,
+		Level:    rec.level,
+		Duration: d,
+	})
+}
@@ -0,0 +1,68 @@
+package logr_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineTracing(t *testing.T) {
+	var mux sync.Mutex
+	phases := make(map[uint64][]string)
+	hook := func(ev logr.PipelineEvent) {
+		mux.Lock()
+		defer mux.Unlock()
+		phases[ev.ID] = append(phases[ev.ID], ev.Phase.String()+"/"+ev.Target)
+		assert.GreaterOrEqual(t, int64(ev.Duration), int64(0))
+	}
+
+	lgr, err := logr.New(logr.PipelineTracing(3, hook))
+	require.NoError(t, err)
+
+	for _, name := range []string{"t1", "t2"} {
+		err = lgr.AddTarget(targets.NewWriterTarget(&test.Buffer{}), name, &logr.StdFilter{Lvl: logr.Info},
+			&formatters.Plain{}, 100)
+		require.NoError(t, err)
+	}
+
+	logger := lgr.NewLogger()
+	for i := 0; i < 7; i++ {
+		logger.Info("traced every third")
+	}
+	err = lgr.Shutdown()
+	require.NoError(t, err)
+
+	mux.Lock()
+	defer mux.Unlock()
+	require.Len(t, phases, 2)
+	for _, id := range []uint64{3, 6} {
+		events := phases[id]
+		require.Len(t, events, 8)
+		assert.Equal(t, []string{"dequeue/"}, filterEvents(events, "dequeue/"))
+		assert.Equal(t, []string{"enqueue/"}, filterEvents(events, "enqueue/"))
+		for _, target := range []string{"t1", "t2"} {
+			assert.Len(t, filterEvents(events, "target_dequeue/"+target), 1)
+			assert.Len(t, filterEvents(events, "format/"+target), 1)
+			assert.Len(t, filterEvents(events, "write/"+target), 1)
+		}
+	}
+
+	_, err = logr.New(logr.PipelineTracing(0, hook))
+	assert.Error(t, err)
+}
+
+func filterEvents(events []string, s string) []string {
+	var out []string
+	for _, e := range events {
+		if e == s {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package logr
+
+import (
+	"errors"
+	"sync"
+)
+
+// VolumeCollector is optionally implemented by a `MetricsCollector` to count log
+// records and bytes per value of the field designated via `VolumeAccounting`.
+type VolumeCollector interface {
+	// VolumeRecordsCounter returns a Counter that will be incremented for each log
+	// record output to at least one target with the specified field value.
+	VolumeRecordsCounter(key string) (Counter, error)
+	// VolumeBytesCounter returns a Counter that will be incremented by the number of
+	// formatted bytes written by any target for log records with the specified field
+	// value.
+	VolumeBytesCounter(key string) (Counter, error)
+}
+
+// Volume is the number of log records, and the formatted bytes written for them
+// summed across all targets, for one value of the field designated via
+// `VolumeAccounting`.
+type Volume struct {
+	Records uint64 `json:"records"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// VolumeAccounting enables aggregation of log record and byte counts by the value of
+// the field with the specified key, for example "tenant_id", for quota enforcement
+// and chargeback in multi-tenant services. Log records without the field are counted
+// under the empty string. Counts are available via `Logr.VolumeSnapshot`, and via the
+// `MetricsCollector` if it implements `VolumeCollector`.
+//
+// One set of counts is kept per distinct field value so the field should have
+// bounded cardinality.
+func VolumeAccounting(key string) Option {
+	return func(l *Logr) error {
+		if key == "" {
+			return errors.New("volume accounting key cannot be empty")
+		}
+		l.options.volumeKey = key
+		return nil
+	}
+}
+
+// VolumeSnapshot returns the log record and byte counts per value of the field
+// designated via `VolumeAccounting`, or nil if volume accounting is not enabled.
+func (lgr *Logr) VolumeSnapshot() map[string]Volume {
+	if lgr.volume == nil {
+		return nil
+	}
+	return lgr.volume.snapshot()
+}
+
+type volumeCounts struct {
+	records      uint64
+	bytes        uint64
+	collected    bool // counters requested from the current collector
+	recordsCount Counter
+	bytesCount   Counter
+}
+
+type volumeAccounting struct {
+	key string
+
+	mux       sync.Mutex
+	counts    map[string]*volumeCounts
+	collector VolumeCollector
+}
+
+func newVolumeAccounting(key string) *volumeAccounting {
+	return &volumeAccounting{key: key, counts: make(map[string]*volumeCounts)}
+}
+
+// keyOf returns the value of the designated field for a prepped record.
+func (va *volumeAccounting) keyOf(rec *LogRec) string {
+	for _, fld := range rec.fieldsAll {
+		if fld.Key == va.key {
+			return fieldValue(fld).str
+		}
+	}
+	return ""
+}
+
+// setCollector replaces the metrics collector, discarding counters from any
+// previous collector.
+func (va *volumeAccounting) setCollector(collector MetricsCollector) {
+	va.mux.Lock()
+	defer va.mux.Unlock()
+
+	va.collector, _ = collector.(VolumeCollector)
+	for _, vc := range va.counts {
+		vc.collected, vc.recordsCount, vc.bytesCount = false, nil, nil
+	}
+}
+
+func (va *volumeAccounting) addRecord(key string) {
+	va.mux.Lock()
+	defer va.mux.Unlock()
+
+	vc := va.get(key)
+	vc.records++
+	if vc.recordsCount != nil {
+		vc.recordsCount.Inc()
+	}
+}
+
+func (va *volumeAccounting) addBytes(key string, n int) {
+	va.mux.Lock()
+	defer va.mux.Unlock()
+
+	vc := va.get(key)
+	vc.bytes += uint64(n)
+	if vc.bytesCount != nil {
+		vc.bytesCount.Add(float64(n))
+	}
+}
+
+// get returns the counts for a key, creating them if needed. Must be called
+// with the mutex held.
+func (va *volumeAccounting) get(key string) *volumeCounts {
+	vc, ok := va.counts[key]
+	if !ok {
+		vc = &volumeCounts{}
+		va.counts[key] = vc
+	}
+	if va.collector != nil && !vc.collected {
+		vc.collected = true
+		var err error
+		if vc.recordsCount, err = va.collector.VolumeRecordsCounter(key); err != nil {
+			vc.recordsCount = nil
+		}
+		if vc.bytesCount, err = va.collector.VolumeBytesCounter(key); err != nil {
+			vc.bytesCount = nil
+		}
+	}
+	return vc
+}
+
+func (va *volumeAccounting) snapshot() map[string]Volume {
+	va.mux.Lock()
+	defer va.mux.Unlock()
+
+	m := make(map[string]Volume, len(va.counts))
+	for key, vc := range va.counts {
+		m[key] = Volume{Records: vc.records, Bytes: vc.bytes}
+	}
+	return m
+}
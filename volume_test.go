@@ -0,0 +1,58 @@
+package logr_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/logr/v2"
+	"github.com/mattermost/logr/v2/formatters"
+	"github.com/mattermost/logr/v2/targets"
+	"github.com/mattermost/logr/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVolumeAccounting(t *testing.T) {
+	_, err := logr.New(logr.VolumeAccounting(""))
+	assert.Error(t, err)
+
+	collector := test.NewTestMetricsCollector()
+	lgr, err := logr.New(logr.VolumeAccounting("tenant_id"), logr.SetMetricsCollector(collector, 1000))
+	require.NoError(t, err)
+
+	formatter := &formatters.Plain{DisableTimestamp: true}
+	buf1 := &test.Buffer{}
+	buf2 := &test.Buffer{}
+	err = lgr.AddTarget(targets.NewWriterTarget(buf1), "all", &logr.StdFilter{Lvl: logr.Debug}, formatter, 100)
+	require.NoError(t, err)
+	err = lgr.AddTarget(targets.NewWriterTarget(buf2), "errors", &logr.StdFilter{Lvl: logr.Error}, formatter, 100)
+	require.NoError(t, err)
+
+	logger := lgr.NewLogger()
+	acme := logger.With(logr.String("tenant_id", "acme"))
+	acme.Info("one")
+	acme.Error("two")
+	logger.Info("three", logr.Int("tenant_id", 42))
+	logger.Info("no tenant")
+	logger.Trace("filtered", logr.String("tenant_id", "acme"))
+	require.NoError(t, lgr.Flush())
+
+	volume := lgr.VolumeSnapshot()
+	require.Len(t, volume, 3)
+	assert.Equal(t, uint64(2), volume["acme"].Records)
+	assert.Equal(t, uint64(1), volume["42"].Records)
+	assert.Equal(t, uint64(1), volume[""].Records)
+
+	// bytes are summed across targets.
+	assert.Equal(t, uint64(len(buf1.Bytes())+len(buf2.Bytes())), volume["acme"].Bytes+volume["42"].Bytes+volume[""].Bytes)
+	assert.Greater(t, volume["acme"].Bytes, volume["42"].Bytes*2)
+
+	records, bytes := collector.GetVolume("acme")
+	assert.Equal(t, float64(2), records)
+	assert.Equal(t, float64(volume["acme"].Bytes), bytes)
+
+	require.NoError(t, lgr.Shutdown())
+
+	lgr, err = logr.New()
+	require.NoError(t, err)
+	assert.Nil(t, lgr.VolumeSnapshot())
+}
@@ -0,0 +1,97 @@
+package logr
+
+import "fmt"
+
+// TargetBatchWriter is implemented by targets that can output several formatted
+// records in one call, such as a single `writev` or a single write of the
+// concatenated records, to cut the number of syscalls at high rates. When
+// implemented, records already queued are dequeued together, up to `MaxBatchSize`,
+// and passed to `WriteBatch` instead of calling `Write` for each. Records are
+// never split across batches.
+type TargetBatchWriter interface {
+	// MaxBatchSize returns the maximum number of records per batch. Batching is
+	// disabled if less than 2. Called once when the target is added.
+	MaxBatchSize() int
+
+	// WriteBatch outputs the formatted records, in order, to this target's destination.
+	// p and recs are only valid for the duration of the call.
+	WriteBatch(p [][]byte, recs []*LogRec) (int, error)
+}
+
+// writeBatch writes first along with any records already queued behind it, up to
+// the target's batch size. A flush record ends the batch and is then processed.
+// The queue in use when done is returned.
+func (h *TargetHost) writeBatch(in chan *LogRec, first *LogRec) chan *LogRec {
+	batch := append(h.batch[:0], first)
+	var flushRec *LogRec
+gather:
+	for len(batch) < h.maxBatch {
+		select {
+		case rec, ok := <-in:
+			switch {
+			case !ok:
+				in = h.queue() // replaced by setQueueSize
+			case rec.flush != nil:
+				flushRec = rec
+				break gather
+			default:
+				h.releaseBytes(rec)
+				batch = append(batch, rec)
+			}
+		default:
+			break gather
+		}
+	}
+
+	h.writeRecs(batch)
+	for i := range batch {
+		batch[i] = nil
+	}
+	h.batch = batch
+
+	if flushRec != nil {
+		in = h.flush(in, flushRec)
+	}
+	return in
+}
+
+// writeRecs formats the records and writes them to the target as one batch.
+func (h *TargetHost) writeRecs(recs []*LogRec) {
+	formatted := make([]formattedRec, 0, len(recs))
+	for _, rec := range recs {
+		if h.isStale(rec) {
+			h.incStaleCounter()
+			continue
+		}
+		f, err := h.formatRec(rec)
+		if err != nil {
+			h.incErrorCounter()
+			rec.Logger().Logr().ReportError(err)
+			continue
+		}
+		formatted = append(formatted, f)
+	}
+	if len(formatted) == 0 {
+		return
+	}
+
+	p := make([][]byte, len(formatted))
+	out := make([]*LogRec, len(formatted))
+	for i, f := range formatted {
+		p[i] = f.buf.Bytes()
+		out[i] = f.rec
+	}
+	_, err := h.batchWriter.WriteBatch(p, out)
+
+	for _, f := range formatted {
+		h.wrote(f, err)
+		if err != nil {
+			h.incErrorCounter()
+		} else {
+			h.incLoggedCounter()
+		}
+	}
+	if err != nil {
+		recs[0].Logger().Logr().ReportError(fmt.Errorf("batch of %d records failed for target %s: %w", len(formatted), h.name, err))
+	}
+}